@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/remote"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+	"github.com/spf13/cobra"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Forward windows and frames to a FocusStreamer server",
+	Long: `Run as a lightweight capture agent. The agent watches this desktop's
+windows and sends them to a server started with --backend remote, which does
+the allowlisting, standby, overlays and streaming. Only windows the server
+asks for are captured and sent.
+
+The server only listens on localhost, so connect through an SSH tunnel or
+the reverse proxy viewers use. From another machine the agent needs a link
+token with the ingest scope, created on the server with
+POST /api/tokens {"scope": "ingest"}.`,
+	Example: `  # On the server
+  focusstreamer serve --backend remote
+
+  # On the desktop, through an SSH tunnel to the server
+  ssh -N -L 8080:localhost:8080 nas &
+  focusstreamer agent --server ws://localhost:8080
+
+  # Through a reverse proxy, with an ingest token
+  focusstreamer agent --server wss://stream.example.com --token "$TOKEN"`,
+	RunE: runAgent,
+}
+
+var (
+	agentServer  string
+	agentToken   string
+	agentQuality int
+)
+
+func init() {
+	rootCmd.AddCommand(agentCmd)
+
+	agentCmd.Flags().StringVarP(&agentServer, "server", "s", "", "server URL, e.g. ws://localhost:8080 (required)")
+	agentCmd.Flags().StringVarP(&agentToken, "token", "t", "", "link token with the ingest scope (default $FOCUSSTREAMER_AGENT_TOKEN)")
+	agentCmd.Flags().IntVarP(&agentQuality, "quality", "q", 85, "JPEG quality of forwarded frames (1-100)")
+	agentCmd.MarkFlagRequired("server")
+}
+
+func runAgent(cmd *cobra.Command, args []string) error {
+	configMgr, err := config.NewManager(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	backendName := GetBackend(configMgr.Get())
+	if backendName == window.BackendRemote {
+		return fmt.Errorf("the agent needs a local window backend, not %s", backendName)
+	}
+
+	token := agentToken
+	if token == "" {
+		token = os.Getenv("FOCUSSTREAMER_AGENT_TOKEN")
+	}
+
+	windowMgr, err := window.NewManagerWithBackend(configMgr, backendName)
+	if err != nil {
+		return fmt.Errorf("failed to initialize window manager: %w", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	defer windowMgr.Stop()
+	if err := windowMgr.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start window manager: %w", err)
+	}
+
+	agent, err := remote.NewAgent(windowMgr, remote.AgentOptions{
+		Server:  agentServer,
+		Token:   token,
+		Quality: agentQuality,
+		Backend: backendName,
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.WithComponent("agent").Info().Str("server", agentServer).Str("backend", backendName).Msg("Starting capture agent")
+	return agent.Run(ctx)
+}
@@ -0,0 +1,167 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/hwjpeg"
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+	"github.com/spf13/cobra"
+)
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure the frame rates this machine can stream",
+	Long: `Time the per-frame work of the stream at common resolutions: converting a
+captured BGRA frame, scaling it to the stream size and encoding it as JPEG
+(in software, and on the hardware encoder if one is found). The last column
+is the frame rate the CPU could sustain doing only that work; capture and
+overlays come on top, so stream at well below it.
+
+Use it to pick virtual_display.width/height/fps on small boards.`,
+	Example: `  # Benchmark with default settings
+  focusstreamer bench
+
+  # Lower JPEG quality, more iterations
+  focusstreamer bench --quality 70 --frames 50
+
+  # Benchmark the Raspberry Pi 4's hardware encoder (the Pi 5 has none)
+  focusstreamer bench --hardware-jpeg /dev/video31`,
+	RunE: runBench,
+}
+
+var (
+	benchFrames       int
+	benchQuality      int
+	benchHardwareJPEG string
+)
+
+// benchSizes are the stream resolutions benchmarked
+var benchSizes = []image.Point{{640, 360}, {1280, 720}, {1920, 1080}}
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+
+	benchCmd.Flags().IntVarP(&benchFrames, "frames", "n", 20, "frames timed per measurement")
+	benchCmd.Flags().IntVarP(&benchQuality, "quality", "q", output.DefaultJPEGQuality, "JPEG quality (1-100)")
+	benchCmd.Flags().StringVar(&benchHardwareJPEG, "hardware-jpeg", hwjpeg.DeviceAuto, "hardware JPEG encoder: off, auto or a device path")
+}
+
+func runBench(cmd *cobra.Command, args []string) error {
+	if benchFrames < 1 {
+		return fmt.Errorf("--frames must be at least 1")
+	}
+	if benchQuality < 1 || benchQuality > 100 {
+		return fmt.Errorf("--quality must be 1-100")
+	}
+	if err := hwjpeg.ValidateDevice(benchHardwareJPEG); err != nil {
+		return fmt.Errorf("invalid --hardware-jpeg: %w", err)
+	}
+
+	var encoder *hwjpeg.Encoder
+	if benchHardwareJPEG != hwjpeg.DeviceOff {
+		var err error
+		if encoder, err = hwjpeg.Open(benchHardwareJPEG); err != nil {
+			fmt.Printf("Hardware JPEG: unavailable (%v)\n", err)
+		} else {
+			defer encoder.Close()
+			fmt.Printf("Hardware JPEG: %s\n", encoder.Device())
+		}
+	}
+	fmt.Printf("CPU: %s/%s, %d cores; JPEG quality %d; %d frames each\n\n",
+		runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), benchQuality, benchFrames)
+
+	// Captures arrive at desktop size and are scaled to the stream size
+	source := benchFrame(1920, 1080)
+	raw := make([]byte, len(source.Pix))
+	pixconv.SwapRedBlue(raw, source.Pix, false)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOLUTION\tSTEP\tMS/FRAME\tMAX FPS")
+	for _, size := range benchSizes {
+		name := fmt.Sprintf("%dx%d", size.X, size.Y)
+		frame := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+		var buf bytes.Buffer
+
+		convert := benchTime(func() {
+			pixconv.BGRAToRGBA(source, raw)
+		})
+		scale := benchTime(func() {
+			pixconv.ScaleNearest(frame, frame.Bounds(), source, source.Bounds())
+		})
+		software := benchTime(func() {
+			buf.Reset()
+			jpeg.Encode(&buf, frame, &jpeg.Options{Quality: benchQuality})
+		})
+		benchRow(w, name, "convert 1080p capture", convert)
+		benchRow(w, name, "scale", scale)
+		benchRow(w, name, "software JPEG", software)
+		benchRow(w, name, "total (software JPEG)", convert+scale+software)
+
+		if encoder != nil {
+			var encodeErr error
+			hardware := benchTime(func() {
+				buf.Reset()
+				if err := encoder.Encode(&buf, frame, benchQuality); err != nil {
+					encodeErr = err
+				}
+			})
+			if encodeErr != nil {
+				fmt.Fprintf(w, "%s\thardware JPEG\tfailed: %v\t\n", name, encodeErr)
+				continue
+			}
+			benchRow(w, name, "hardware JPEG", hardware)
+			benchRow(w, name, "total (hardware JPEG)", convert+scale+hardware)
+		}
+	}
+	return w.Flush()
+}
+
+// benchTime returns the mean duration of fn over benchFrames runs, after
+// one warm-up run
+func benchTime(fn func()) time.Duration {
+	fn()
+	start := time.Now()
+	for i := 0; i < benchFrames; i++ {
+		fn()
+	}
+	return time.Since(start) / time.Duration(benchFrames)
+}
+
+// benchRow prints one timing
+func benchRow(w *tabwriter.Writer, resolution, step string, d time.Duration) {
+	ms := float64(d) / float64(time.Millisecond)
+	fps := 0.0
+	if d > 0 {
+		fps = float64(time.Second) / float64(d)
+	}
+	fmt.Fprintf(w, "%s\t%s\t%.2f\t%.1f\n", resolution, step, ms, fps)
+}
+
+// benchFrame draws a frame that compresses like a desktop window: flat
+// panels with lines of fine, text-like detail
+func benchFrame(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			r, g, b := byte(30), byte(30), byte(40) // Editor background
+			if x < width/5 {
+				r, g, b = 45, 45, 55 // Sidebar
+			}
+			// Text lines: 16px line height, glyph-like on/off runs
+			if y%16 >= 3 && y%16 <= 12 && (x*7+y*3)%11 < 5 && (x/48+y/16)%4 != 0 {
+				r, g, b = 212, 212, byte(160+(x*13)%90)
+			}
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = r, g, b, 0xff
+		}
+	}
+	return img
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/api"
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var browserHostCmd = &cobra.Command{
+	Use:   "browser-host [origin]",
+	Short: "Run the browser extension native messaging host",
+	Long: `Run the native messaging host used by the FocusStreamer browser extension.
+
+The browser starts this command itself and talks to it over stdin/stdout.
+It relays the active tab URL to a running FocusStreamer server so windows
+can be allowlisted per domain. Use --install once to register the host
+with Chrome, Chromium, and Brave.`,
+	Example: `  # Register the host for an extension (run once)
+  focusstreamer browser-host --install --extension-id abcdefghijklmnopabcdefghijklmnop
+
+  # Relay to a server on a non-default port
+  focusstreamer browser-host --server http://127.0.0.1:9090`,
+	// Browsers pass the calling extension's origin as an argument
+	Args: cobra.ArbitraryArgs,
+	RunE: runBrowserHost,
+}
+
+var (
+	browserHostServer      string
+	browserHostInstall     bool
+	browserHostExtensionID string
+)
+
+// nativeHostManifestDirs are the per-user native messaging host directories,
+// relative to the XDG config directory
+var nativeHostManifestDirs = []string{
+	"google-chrome",
+	"chromium",
+	"BraveSoftware/Brave-Browser",
+}
+
+func init() {
+	rootCmd.AddCommand(browserHostCmd)
+
+	browserHostCmd.Flags().StringVar(&browserHostServer, "server", "", "FocusStreamer server URL (default http://127.0.0.1:<server_port>)")
+	browserHostCmd.Flags().BoolVar(&browserHostInstall, "install", false, "register the native messaging host with installed browsers")
+	browserHostCmd.Flags().StringVar(&browserHostExtensionID, "extension-id", "", "extension ID allowed to use the host (required with --install)")
+}
+
+func runBrowserHost(cmd *cobra.Command, args []string) error {
+	if browserHostInstall {
+		return installBrowserHost()
+	}
+
+	// stdout carries the native messaging protocol, so logs go to stderr
+	logger.InitWithOutput(viper.GetString("log_level"), false, os.Stderr)
+
+	server := browserHostServer
+	if server == "" {
+		configMgr, err := config.NewManager(GetConfigFile())
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		server = fmt.Sprintf("http://127.0.0.1:%d", configMgr.Get().ServerPort)
+	}
+
+	return api.RunNativeHost(os.Stdin, os.Stdout, server)
+}
+
+// installBrowserHost writes a wrapper script (manifests cannot pass
+// arguments) and a host manifest for each installed browser
+func installBrowserHost() error {
+	if browserHostExtensionID == "" {
+		return fmt.Errorf("--extension-id is required with --install")
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate focusstreamer executable: %w", err)
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to locate config directory: %w", err)
+	}
+
+	wrapperDir := filepath.Join(configDir, "focusstreamer")
+	if err := os.MkdirAll(wrapperDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", wrapperDir, err)
+	}
+
+	wrapperArgs := ""
+	if browserHostServer != "" {
+		wrapperArgs = fmt.Sprintf(" --server %q", browserHostServer)
+	}
+	if cfgFile != "" {
+		wrapperArgs += fmt.Sprintf(" --config %q", cfgFile)
+	}
+	wrapperPath := filepath.Join(wrapperDir, "browser-host")
+	wrapper := fmt.Sprintf("#!/bin/sh\nexec %q browser-host%s \"$@\"\n", executable, wrapperArgs)
+	if err := os.WriteFile(wrapperPath, []byte(wrapper), 0755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", wrapperPath, err)
+	}
+
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"name":            api.NativeHostName,
+		"description":     "FocusStreamer browser tab reporter",
+		"path":            wrapperPath,
+		"type":            "stdio",
+		"allowed_origins": []string{fmt.Sprintf("chrome-extension://%s/", browserHostExtensionID)},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	installed := 0
+	for _, dir := range nativeHostManifestDirs {
+		browserDir := filepath.Join(configDir, dir)
+		if _, err := os.Stat(browserDir); err != nil {
+			continue
+		}
+
+		hostsDir := filepath.Join(browserDir, "NativeMessagingHosts")
+		if err := os.MkdirAll(hostsDir, 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", hostsDir, err)
+		}
+		manifestPath := filepath.Join(hostsDir, api.NativeHostName+".json")
+		if err := os.WriteFile(manifestPath, manifest, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", manifestPath, err)
+		}
+		fmt.Printf("✓ Installed native messaging host manifest: %s\n", manifestPath)
+		installed++
+	}
+
+	if installed == 0 {
+		return fmt.Errorf("no supported browser config directory found under %s", configDir)
+	}
+	return nil
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/hwjpeg"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
 )
@@ -61,6 +65,23 @@ var configPathCmd = &cobra.Command{
 	RunE:  runConfigPath,
 }
 
+var configPresetCmd = &cobra.Command{
+	Use:   "preset [NAME]",
+	Short: "Apply a configuration preset",
+	Long: `Apply a built-in preset of stream and capture settings, or list the
+presets when no name is given. Allowlists, profiles and overlays are kept.`,
+	Example: `  # List presets
+  focusstreamer config preset
+
+  # Tune for a Raspberry Pi or similar board
+  focusstreamer config preset low-power
+
+  # Undo it
+  focusstreamer config preset default`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runConfigPreset,
+}
+
 var formatFlag string
 
 func init() {
@@ -69,6 +90,7 @@ func init() {
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configPathCmd)
+	configCmd.AddCommand(configPresetCmd)
 
 	configShowCmd.Flags().StringVarP(&formatFlag, "format", "f", "yaml", "output format (yaml or json)")
 }
@@ -120,6 +142,18 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("invalid log level: %s (use: debug, info, warn, error)", value)
 		}
 		cfg.LogLevel = value
+	case "backend":
+		valid := false
+		for _, name := range window.BackendNames {
+			if value == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid backend: %s (use: %s)", value, strings.Join(window.BackendNames, ", "))
+		}
+		cfg.Backend = value
 	case "virtual_display.width":
 		var num int
 		if _, err := fmt.Sscanf(value, "%d", &num); err != nil {
@@ -156,14 +190,328 @@ func runConfigSet(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
 		}
 		cfg.Overlay.Enabled = enabled
+	case "overlay.hide_standby_stats":
+		var hide bool
+		if _, err := fmt.Sscanf(value, "%t", &hide); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Overlay.HideStandbyStats = hide
+	case "overlay.allow_commands":
+		var allow bool
+		if _, err := fmt.Sscanf(value, "%t", &allow); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Overlay.AllowCommands = allow
+	case "capture.color_management.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Capture.ColorManagement.Enabled = enabled
+	case "capture.color_management.source_color_space":
+		validSpaces := map[string]bool{"srgb": true, "display-p3": true, "bt2020": true, "adobe-rgb": true}
+		if !validSpaces[value] {
+			return fmt.Errorf("invalid color space: %s (use: srgb, display-p3, bt2020, adobe-rgb)", value)
+		}
+		cfg.Capture.ColorManagement.SourceColorSpace = value
+	case "capture.color_management.icc_profile":
+		cfg.Capture.ColorManagement.ICCProfile = value
+	case "capture.watchdog.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Capture.Watchdog.Enabled = enabled
+	case "capture.watchdog.stall_seconds":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num <= 0 {
+			return fmt.Errorf("invalid number of seconds: %s", value)
+		}
+		cfg.Capture.Watchdog.StallSeconds = num
+	case "capture.scale_at_source":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Capture.ScaleAtSource = enabled
+	case "capture.multi_monitor":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Capture.MultiMonitor = enabled
+	case "capture.pipeline_template":
+		if err := config.ValidatePipelineTemplate(value); err != nil {
+			return fmt.Errorf("invalid pipeline template: %w", err)
+		}
+		cfg.Capture.PipelineTemplate = value
+	case "capture.fallback_ttl_seconds":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num < 0 {
+			return fmt.Errorf("invalid number of seconds: %s", value)
+		}
+		cfg.Capture.FallbackTTLSeconds = num
+	case "suppress_notifications":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.SuppressNotifications = enabled
+	case "notify_new_shares":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.NotifyNewShares = enabled
+	case "on_air.command":
+		cfg.OnAir.Command = value
+	case "on_air.url":
+		cfg.OnAir.URL = value
+	case "stream_access.public_url":
+		cfg.StreamAccess.PublicURL = value
+	case "on_air.debounce_ms":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num < 0 {
+			return fmt.Errorf("invalid number of milliseconds: %s", value)
+		}
+		cfg.OnAir.DebounceMs = num
+	case "bandwidth.max_mbps":
+		var mbps float64
+		if _, err := fmt.Sscanf(value, "%g", &mbps); err != nil || mbps < 0 {
+			return fmt.Errorf("invalid Mbps: %s (use 0 for unlimited)", value)
+		}
+		cfg.Bandwidth.MaxMbps = mbps
+	case "bandwidth.client_max_mbps":
+		var mbps float64
+		if _, err := fmt.Sscanf(value, "%g", &mbps); err != nil || mbps < 0 {
+			return fmt.Errorf("invalid Mbps: %s (use 0 for unlimited)", value)
+		}
+		cfg.Bandwidth.ClientMaxMbps = mbps
+	case "api.log_requests":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.API.LogRequests = enabled
+	case "api.rate_limit":
+		var rate float64
+		if _, err := fmt.Sscanf(value, "%g", &rate); err != nil || rate < 0 {
+			return fmt.Errorf("invalid requests per second: %s (use 0 for unlimited)", value)
+		}
+		cfg.API.RateLimit = rate
+	case "api.rate_burst":
+		var burst int
+		if _, err := fmt.Sscanf(value, "%d", &burst); err != nil || burst < 0 {
+			return fmt.Errorf("invalid burst: %s", value)
+		}
+		cfg.API.RateBurst = burst
+	case "api.graphql":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.API.GraphQL = enabled
+	case "allowlist_approval.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		if enabled && cfg.AllowlistApproval.SecretSHA256 == "" {
+			return fmt.Errorf("set allowlist_approval.secret first; the approver needs it to approve entries")
+		}
+		cfg.AllowlistApproval.Enabled = enabled
+	case "allowlist_approval.secret":
+		if value == "" {
+			if cfg.AllowlistApproval.Enabled {
+				return fmt.Errorf("turn off allowlist_approval.enabled before removing the secret")
+			}
+			cfg.AllowlistApproval.SecretSHA256 = ""
+		} else {
+			cfg.AllowlistApproval.SecretSHA256 = config.HashApprovalSecret(value)
+		}
+	case "allowlist_approval.webhook_url":
+		cfg.AllowlistApproval.WebhookURL = value
+	case "focus_stats.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.FocusStats.Enabled = enabled
+	case "activitywatch.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.ActivityWatch.Enabled = enabled
+	case "activitywatch.url":
+		cfg.ActivityWatch.URL = value
+	case "activitywatch.window_bucket":
+		cfg.ActivityWatch.WindowBucket = value
+	case "activitywatch.stream_bucket":
+		cfg.ActivityWatch.StreamBucket = value
+	case "focus_stats.retention_days":
+		var days int
+		if _, err := fmt.Sscanf(value, "%d", &days); err != nil || days < 0 {
+			return fmt.Errorf("invalid number of days: %s (use 0 to keep all days)", value)
+		}
+		cfg.FocusStats.RetentionDays = days
+	case "pii_guard.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.PIIGuard.Enabled = enabled
+	case "pii_guard.interval_seconds":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num <= 0 {
+			return fmt.Errorf("invalid number of seconds: %s", value)
+		}
+		cfg.PIIGuard.IntervalSeconds = num
+	case "pii_guard.max_width":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num <= 0 {
+			return fmt.Errorf("invalid width: %s", value)
+		}
+		cfg.PIIGuard.MaxWidth = num
+	case "pii_guard.tesseract_path":
+		cfg.PIIGuard.TesseractPath = value
+	case "placeholder.message":
+		cfg.Placeholder.Message = value
+	case "placeholder.background", "placeholder.target_color", "placeholder.accent_color", "placeholder.text_color":
+		if _, err := config.ParseHexColor(value); err != nil {
+			return fmt.Errorf("invalid color: %s (use #rrggbb or #rgb)", value)
+		}
+		switch key {
+		case "placeholder.background":
+			cfg.Placeholder.Background = value
+		case "placeholder.target_color":
+			cfg.Placeholder.TargetColor = value
+		case "placeholder.accent_color":
+			cfg.Placeholder.AccentColor = value
+		case "placeholder.text_color":
+			cfg.Placeholder.TextColor = value
+		}
+	case "placeholder.hide_target":
+		var hide bool
+		if _, err := fmt.Sscanf(value, "%t", &hide); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Placeholder.HideTarget = hide
+	case "placeholder.show_reason":
+		var show bool
+		if _, err := fmt.Sscanf(value, "%t", &show); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.Placeholder.ShowReason = show
+	case "placeholder.next_stream":
+		if value != "" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return fmt.Errorf("invalid time: %s (use RFC 3339, e.g. 2025-06-01T14:00:00+02:00, or \"\" to clear)", value)
+			}
+		}
+		cfg.Placeholder.NextStream = value
+	case "stream_filters.brightness", "stream_filters.contrast", "stream_filters.gamma", "stream_filters.saturation", "stream_filters.sharpen":
+		var num float64
+		if _, err := fmt.Sscanf(value, "%g", &num); err != nil {
+			return fmt.Errorf("invalid number: %s", value)
+		}
+		switch key {
+		case "stream_filters.brightness":
+			cfg.StreamFilters.Brightness = num
+		case "stream_filters.contrast":
+			cfg.StreamFilters.Contrast = num
+		case "stream_filters.gamma":
+			cfg.StreamFilters.Gamma = num
+		case "stream_filters.saturation":
+			cfg.StreamFilters.Saturation = num
+		case "stream_filters.sharpen":
+			cfg.StreamFilters.Sharpen = num
+		}
+		if err := cfg.StreamFilters.Validate(); err != nil {
+			return err
+		}
+	case "pointer_highlight.enabled", "pointer_highlight.click_ripples":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		if key == "pointer_highlight.enabled" {
+			cfg.PointerHighlight.Enabled = enabled
+		} else {
+			cfg.PointerHighlight.ClickRipples = enabled
+		}
+	case "pointer_highlight.radius":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num < 4 || num > 100 {
+			return fmt.Errorf("invalid radius: %s (use 4-100)", value)
+		}
+		cfg.PointerHighlight.Radius = num
+	case "pointer_highlight.color":
+		if _, err := config.ParseHexColor(value); err != nil {
+			return fmt.Errorf("invalid color: %s (use #rrggbb or #rgb)", value)
+		}
+		cfg.PointerHighlight.Color = value
+	case "low_power.enabled":
+		var enabled bool
+		if _, err := fmt.Sscanf(value, "%t", &enabled); err != nil {
+			return fmt.Errorf("invalid boolean: %s (use: true or false)", value)
+		}
+		cfg.LowPower.Enabled = enabled
+	case "low_power.max_fps":
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num < 0 || num > 60 {
+			return fmt.Errorf("invalid FPS: %s (use 0-60, 0 for no cap)", value)
+		}
+		cfg.LowPower.MaxFPS = num
+	case "low_power.hardware_jpeg":
+		if err := hwjpeg.ValidateDevice(value); err != nil {
+			return fmt.Errorf("invalid hardware JPEG device: %w", err)
+		}
+		cfg.LowPower.HardwareJPEG = value
 	default:
-		return fmt.Errorf("unknown configuration key: %s", key)
+		if class, ok := strings.CutPrefix(key, "capture.routes."); ok && class != "" {
+			if value != "auto" && !config.IsValidCapturer(value) {
+				return fmt.Errorf("invalid capturer: %s (use x11, pipewire, a registered capturer, or auto to remove)", value)
+			}
+			routes := make(map[string]string, len(cfg.Capture.Routes)+1)
+			for k, v := range cfg.Capture.Routes {
+				routes[k] = v
+			}
+			if value == "auto" {
+				delete(routes, class)
+			} else {
+				routes[class] = value
+			}
+			cfg.Capture.Routes = routes
+			break
+		}
+		class, ok := strings.CutPrefix(key, "capture.app_fps.")
+		if !ok || class == "" {
+			return fmt.Errorf("unknown configuration key: %s", key)
+		}
+		var num int
+		if _, err := fmt.Sscanf(value, "%d", &num); err != nil || num < 0 || num > 60 {
+			return fmt.Errorf("invalid FPS: %s (use 1-60, 0 to remove)", value)
+		}
+		appFPS := make(map[string]int, len(cfg.Capture.AppFPS)+1)
+		for k, v := range cfg.Capture.AppFPS {
+			appFPS[k] = v
+		}
+		if num == 0 {
+			delete(appFPS, class)
+		} else {
+			appFPS[class] = num
+		}
+		cfg.Capture.AppFPS = appFPS
 	}
 
 	if err := configMgr.Update(cfg); err != nil {
 		return fmt.Errorf("failed to save config: %w", err)
 	}
 
+	if key == "allowlist_approval.secret" && value != "" {
+		value = "(stored as SHA-256)"
+	}
 	fmt.Printf("✅ Configuration updated: %s = %s\n", key, value)
 	return nil
 }
@@ -184,6 +532,8 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 		value = cfg.ServerPort
 	case "log_level":
 		value = cfg.LogLevel
+	case "backend":
+		value = cfg.Backend
 	case "virtual_display.width":
 		value = cfg.VirtualDisplay.Width
 	case "virtual_display.height":
@@ -196,12 +546,150 @@ func runConfigGet(cmd *cobra.Command, args []string) error {
 		value = cfg.VirtualDisplay.Enabled
 	case "overlay.enabled":
 		value = cfg.Overlay.Enabled
+	case "overlay.hide_standby_stats":
+		value = cfg.Overlay.HideStandbyStats
+	case "overlay.allow_commands":
+		value = cfg.Overlay.AllowCommands
+	case "capture.color_management.enabled":
+		value = cfg.Capture.ColorManagement.Enabled
+	case "capture.color_management.source_color_space":
+		value = cfg.Capture.ColorManagement.SourceColorSpace
+	case "capture.color_management.icc_profile":
+		value = cfg.Capture.ColorManagement.ICCProfile
+	case "capture.watchdog.enabled":
+		value = cfg.Capture.Watchdog.Enabled
+	case "capture.watchdog.stall_seconds":
+		value = cfg.Capture.Watchdog.StallSeconds
+	case "capture.scale_at_source":
+		value = cfg.Capture.ScaleAtSource
+	case "capture.multi_monitor":
+		value = cfg.Capture.MultiMonitor
+	case "capture.pipeline_template":
+		if cfg.Capture.PipelineTemplate == "" {
+			value = config.DefaultPipelineTemplate
+		} else {
+			value = cfg.Capture.PipelineTemplate
+		}
+	case "capture.fallback_ttl_seconds":
+		value = cfg.Capture.FallbackTTLSeconds
+	case "suppress_notifications":
+		value = cfg.SuppressNotifications
+	case "notify_new_shares":
+		value = cfg.NotifyNewShares
+	case "on_air.command":
+		value = cfg.OnAir.Command
+	case "on_air.url":
+		value = cfg.OnAir.URL
+	case "stream_access.public_url":
+		value = cfg.StreamAccess.PublicURL
+	case "on_air.debounce_ms":
+		value = cfg.OnAir.DebounceMs
+	case "bandwidth.max_mbps":
+		value = cfg.Bandwidth.MaxMbps
+	case "bandwidth.client_max_mbps":
+		value = cfg.Bandwidth.ClientMaxMbps
+	case "api.log_requests":
+		value = cfg.API.LogRequests
+	case "api.rate_limit":
+		value = cfg.API.RateLimit
+	case "api.rate_burst":
+		value = cfg.API.RateBurst
+	case "api.graphql":
+		value = cfg.API.GraphQL
+	case "allowlist_approval.enabled":
+		value = cfg.AllowlistApproval.Enabled
+	case "allowlist_approval.secret_sha256":
+		value = cfg.AllowlistApproval.SecretSHA256
+	case "allowlist_approval.webhook_url":
+		value = cfg.AllowlistApproval.WebhookURL
+	case "focus_stats.enabled":
+		value = cfg.FocusStats.Enabled
+	case "focus_stats.retention_days":
+		value = cfg.FocusStats.RetentionDays
+	case "activitywatch.enabled":
+		value = cfg.ActivityWatch.Enabled
+	case "activitywatch.url":
+		value = cfg.ActivityWatch.URL
+	case "activitywatch.window_bucket":
+		value = cfg.ActivityWatch.WindowBucket
+	case "activitywatch.stream_bucket":
+		value = cfg.ActivityWatch.StreamBucket
+	case "pii_guard.enabled":
+		value = cfg.PIIGuard.Enabled
+	case "pii_guard.interval_seconds":
+		value = cfg.PIIGuard.IntervalSeconds
+	case "pii_guard.max_width":
+		value = cfg.PIIGuard.MaxWidth
+	case "pii_guard.tesseract_path":
+		value = cfg.PIIGuard.TesseractPath
+	case "pii_guard.patterns":
+		value = cfg.PIIGuard.Patterns
+	case "placeholder.message":
+		value = cfg.Placeholder.Message
+	case "placeholder.background":
+		value = cfg.Placeholder.Background
+	case "placeholder.target_color":
+		value = cfg.Placeholder.TargetColor
+	case "placeholder.accent_color":
+		value = cfg.Placeholder.AccentColor
+	case "placeholder.text_color":
+		value = cfg.Placeholder.TextColor
+	case "placeholder.hide_target":
+		value = cfg.Placeholder.HideTarget
+	case "placeholder.show_reason":
+		value = cfg.Placeholder.ShowReason
+	case "placeholder.next_stream":
+		value = cfg.Placeholder.NextStream
+	case "stream_filters.brightness":
+		value = cfg.StreamFilters.Brightness
+	case "stream_filters.contrast":
+		value = cfg.StreamFilters.Contrast
+	case "stream_filters.gamma":
+		value = cfg.StreamFilters.Gamma
+	case "stream_filters.saturation":
+		value = cfg.StreamFilters.Saturation
+	case "stream_filters.sharpen":
+		value = cfg.StreamFilters.Sharpen
+	case "pointer_highlight.enabled":
+		value = cfg.PointerHighlight.Enabled
+	case "pointer_highlight.radius":
+		value = cfg.PointerHighlight.Radius
+	case "pointer_highlight.color":
+		value = cfg.PointerHighlight.Color
+	case "pointer_highlight.click_ripples":
+		value = cfg.PointerHighlight.ClickRipples
+	case "low_power.enabled":
+		value = cfg.LowPower.Enabled
+	case "low_power.max_fps":
+		value = cfg.LowPower.MaxFPS
+	case "low_power.hardware_jpeg":
+		value = cfg.LowPower.HardwareJPEG
 	case "allowed_apps":
 		value = cfg.AllowlistedApps
 	case "allowlist_patterns":
 		value = cfg.AllowlistPatterns
+	case "capture.app_fps":
+		value = cfg.Capture.AppFPS
+	case "capture.routes":
+		value = cfg.Capture.Routes
 	default:
-		return fmt.Errorf("unknown configuration key: %s", key)
+		if class, ok := strings.CutPrefix(key, "capture.routes."); ok && class != "" {
+			capturer, ok := cfg.CaptureRoute(class)
+			if !ok {
+				capturer = "auto"
+			}
+			value = capturer
+			break
+		}
+		class, ok := strings.CutPrefix(key, "capture.app_fps.")
+		if !ok || class == "" {
+			return fmt.Errorf("unknown configuration key: %s", key)
+		}
+		fps, ok := cfg.AppStreamFPS(class)
+		if !ok {
+			fps = cfg.StreamFPS()
+		}
+		value = fps
 	}
 
 	fmt.Println(value)
@@ -217,3 +705,23 @@ func runConfigPath(cmd *cobra.Command, args []string) error {
 	fmt.Println(configMgr.GetConfigPath())
 	return nil
 }
+
+func runConfigPreset(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		for _, preset := range config.Presets {
+			fmt.Printf("%-10s %s\n", preset.Name, preset.Description)
+		}
+		return nil
+	}
+
+	configMgr, err := config.NewManager(GetConfigFile())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := configMgr.ApplyPreset(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Applied preset: %s (restart the server to apply)\n", args[0])
+	return nil
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/integrations/krunner"
+	"github.com/spf13/cobra"
+)
+
+var installKRunnerCmd = &cobra.Command{
+	Use:   "install-krunner",
+	Short: "Register the KRunner plugin",
+	Long: `Register FocusStreamer as a KRunner plugin for the current user.
+
+While the server is running, type in KRunner:
+
+  fs standby          toggle standby
+  fs allow firefox    allowlist a running application
+  fs profile work     switch profile`,
+	RunE: runInstallKRunner,
+}
+
+func init() {
+	rootCmd.AddCommand(installKRunnerCmd)
+}
+
+func runInstallKRunner(cmd *cobra.Command, args []string) error {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+		dataDir = filepath.Join(homeDir, ".local", "share")
+	}
+
+	pluginDir := filepath.Join(dataDir, "krunner", "dbusplugins")
+	if err := os.MkdirAll(pluginDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", pluginDir, err)
+	}
+
+	path := filepath.Join(pluginDir, "focusstreamer.desktop")
+	if err := os.WriteFile(path, []byte(krunner.DesktopFile()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	fmt.Printf("✓ Installed KRunner plugin: %s\n", path)
+	fmt.Println("Restart KRunner to load it: kquitapp6 krunner (or kquitapp5 krunner on Plasma 5)")
+	return nil
+}
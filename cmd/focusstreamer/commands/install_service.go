@@ -0,0 +1,196 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var installServiceCmd = &cobra.Command{
+	Use:   "install-service",
+	Short: "Install a systemd user service",
+	Long: `Install FocusStreamer as a systemd user service.
+
+Writes focusstreamer.service and focusstreamer.socket to
+~/.config/systemd/user. With the socket enabled, systemd listens on the
+server port and starts FocusStreamer on the first HTTP request; the socket
+stays open while the service restarts, so clients aren't refused.
+
+The service needs the graphical session's environment (DISPLAY or
+WAYLAND_DISPLAY). Most desktops import it into the systemd user manager;
+otherwise run:
+
+  systemctl --user import-environment DISPLAY WAYLAND_DISPLAY XAUTHORITY`,
+	Example: `  # Install and start on first request
+  focusstreamer install-service --enable
+
+  # Start with the desktop session instead of on demand
+  focusstreamer install-service --no-socket --enable
+
+  # Show the units without writing them
+  focusstreamer install-service --print`,
+	RunE: runInstallService,
+}
+
+var (
+	installServiceNoSocket bool
+	installServiceEnable   bool
+	installServicePrint    bool
+)
+
+const (
+	serviceUnitName = "focusstreamer.service"
+	socketUnitName  = "focusstreamer.socket"
+)
+
+func init() {
+	rootCmd.AddCommand(installServiceCmd)
+
+	installServiceCmd.Flags().BoolVar(&installServiceNoSocket, "no-socket", false, "start with the graphical session instead of on the first request")
+	installServiceCmd.Flags().BoolVar(&installServiceEnable, "enable", false, "reload systemd and enable the units now")
+	installServiceCmd.Flags().BoolVar(&installServicePrint, "print", false, "print the units instead of writing them")
+}
+
+func runInstallService(cmd *cobra.Command, args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate focusstreamer executable: %w", err)
+	}
+
+	port := viper.GetInt("server_port")
+	if port == 0 {
+		configMgr, err := config.NewManager(GetConfigFile())
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		port = configMgr.Get().ServerPort
+	}
+
+	units := map[string]string{
+		serviceUnitName: serviceUnit(executable, !installServiceNoSocket),
+	}
+	if !installServiceNoSocket {
+		units[socketUnitName] = socketUnit(port)
+	}
+
+	if installServicePrint {
+		for _, name := range []string{serviceUnitName, socketUnitName} {
+			if unit, ok := units[name]; ok {
+				fmt.Printf("# %s\n%s\n", name, unit)
+			}
+		}
+		return nil
+	}
+
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	unitDir := filepath.Join(configDir, "systemd", "user")
+	if err := os.MkdirAll(unitDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", unitDir, err)
+	}
+
+	for name, unit := range units {
+		path := filepath.Join(unitDir, name)
+		if err := os.WriteFile(path, []byte(unit), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Printf("✓ Installed %s\n", path)
+	}
+
+	// Switching to --no-socket leaves an old socket unit that would also
+	// start the service
+	if installServiceNoSocket {
+		oldSocket := filepath.Join(unitDir, socketUnitName)
+		if _, err := os.Stat(oldSocket); err == nil {
+			fmt.Printf("Note: %s is still installed; run 'systemctl --user disable --now %s' and delete it\n", oldSocket, socketUnitName)
+		}
+	}
+
+	enable := []string{"enable", "--now", socketUnitName}
+	if installServiceNoSocket {
+		enable = []string{"enable", "--now", serviceUnitName}
+	}
+
+	if !installServiceEnable {
+		fmt.Println()
+		fmt.Println("To start it:")
+		fmt.Println("  systemctl --user daemon-reload")
+		fmt.Printf("  systemctl --user %s\n", strings.Join(enable, " "))
+		return nil
+	}
+
+	for _, systemctlArgs := range [][]string{{"daemon-reload"}, enable} {
+		systemctl := exec.Command("systemctl", append([]string{"--user"}, systemctlArgs...)...)
+		systemctl.Stdout = os.Stdout
+		systemctl.Stderr = os.Stderr
+		if err := systemctl.Run(); err != nil {
+			return fmt.Errorf("systemctl --user %s failed: %w", strings.Join(systemctlArgs, " "), err)
+		}
+	}
+	if installServiceNoSocket {
+		fmt.Printf("✓ FocusStreamer is running on http://localhost:%d\n", port)
+	} else {
+		fmt.Printf("✓ FocusStreamer will start on the first request to http://localhost:%d\n", port)
+	}
+	return nil
+}
+
+// serviceUnit returns the service unit running `focusstreamer serve`
+func serviceUnit(executable string, socketActivated bool) string {
+	execStart := systemdQuote(executable) + " serve"
+	if cfgFile != "" {
+		execStart += " --config " + systemdQuote(cfgFile)
+	}
+	if viper.GetInt("server_port") != 0 {
+		execStart += fmt.Sprintf(" --port %d", viper.GetInt("server_port"))
+	}
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	b.WriteString("Description=FocusStreamer virtual display for screen sharing\n")
+	b.WriteString("Documentation=https://github.com/bryanchriswhite/FocusStreamer\n")
+	b.WriteString("After=graphical-session.target\n")
+	b.WriteString("PartOf=graphical-session.target\n")
+	if socketActivated {
+		b.WriteString("Requires=" + socketUnitName + "\n")
+	}
+	b.WriteString("\n[Service]\n")
+	b.WriteString("Type=simple\n")
+	b.WriteString("ExecStart=" + execStart + "\n")
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=2\n")
+	if !socketActivated {
+		b.WriteString("\n[Install]\n")
+		b.WriteString("WantedBy=graphical-session.target\n")
+	}
+	return b.String()
+}
+
+// socketUnit returns the socket unit listening on the server port
+func socketUnit(port int) string {
+	return fmt.Sprintf(`[Unit]
+Description=FocusStreamer HTTP socket
+
+[Socket]
+ListenStream=127.0.0.1:%d
+
+[Install]
+WantedBy=sockets.target
+`, port)
+}
+
+// systemdQuote quotes a path for an Exec line if it contains spaces
+func systemdQuote(s string) string {
+	if !strings.ContainsAny(s, " \t\"\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
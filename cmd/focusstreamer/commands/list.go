@@ -54,7 +54,8 @@ func runList(cmd *cobra.Command, args []string) error {
 	}
 
 	// Initialize window manager
-	windowMgr, err := window.NewManager(configMgr)
+	cfg := configMgr.Get()
+	windowMgr, err := window.NewManagerWithBackend(configMgr, GetBackend(cfg))
 	if err != nil {
 		return fmt.Errorf("failed to connect to X11: %w", err)
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -35,10 +36,12 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.config/focusstreamer/config.yaml)")
 	rootCmd.PersistentFlags().Int("port", 0, "server port (default is 8080)")
 	rootCmd.PersistentFlags().String("log-level", "", "log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().StringP("backend", "b", "", "window backend: auto, x11, kwin, hyprland, windows, synthetic, or remote (default from config, else auto)")
 
 	// Bind flags to viper
 	viper.BindPFlag("server_port", rootCmd.PersistentFlags().Lookup("port"))
 	viper.BindPFlag("log_level", rootCmd.PersistentFlags().Lookup("log-level"))
+	viper.BindPFlag("backend", rootCmd.PersistentFlags().Lookup("backend"))
 }
 
 func initConfig() {
@@ -59,3 +62,12 @@ func Execute() {
 func GetConfigFile() string {
 	return cfgFile
 }
+
+// GetBackend returns the window backend selected with --backend, falling
+// back to the configured backend
+func GetBackend(cfg *config.Config) string {
+	if backend := viper.GetString("backend"); backend != "" {
+		return backend
+	}
+	return cfg.Backend
+}
@@ -1,19 +1,34 @@
 package commands
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"runtime"
 	"syscall"
 
 	"github.com/bryanchriswhite/FocusStreamer/internal/api"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/dnd"
+	"github.com/bryanchriswhite/FocusStreamer/internal/eventhub"
+	"github.com/bryanchriswhite/FocusStreamer/internal/focusstats"
+	"github.com/bryanchriswhite/FocusStreamer/internal/hwjpeg"
+	"github.com/bryanchriswhite/FocusStreamer/internal/integrations/activitywatch"
+	"github.com/bryanchriswhite/FocusStreamer/internal/integrations/dbusservice"
+	"github.com/bryanchriswhite/FocusStreamer/internal/integrations/krunner"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/onair"
 	"github.com/bryanchriswhite/FocusStreamer/internal/output"
 	"github.com/bryanchriswhite/FocusStreamer/internal/overlay"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pii"
+	"github.com/bryanchriswhite/FocusStreamer/internal/session"
+	"github.com/bryanchriswhite/FocusStreamer/internal/sharealert"
 	"github.com/bryanchriswhite/FocusStreamer/internal/window"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/sync/errgroup"
 )
 
 var serveCmd = &cobra.Command{
@@ -33,12 +48,24 @@ and viewing the currently focused window.`,
   focusstreamer serve --config /path/to/config.yaml
 
   # Start with debug logging
-  focusstreamer serve --log-level debug`,
+  focusstreamer serve --log-level debug
+
+  # Demo mode with fake windows and generated frames
+  focusstreamer serve --backend synthetic
+
+  # Serve the web UI and viewer pages from a checkout while developing
+  focusstreamer serve --assets-dir .`,
 	RunE: runServe,
 }
 
+// serveAssetsDir is a source checkout to serve web assets from instead of
+// the copies embedded in the binary
+var serveAssetsDir string
+
 func init() {
 	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveAssetsDir, "assets-dir", "", "serve web/dist and the viewer page templates from this source checkout instead of the embedded copies (re-read on every request, for development)")
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
@@ -84,9 +111,15 @@ func runServe(cmd *cobra.Command, args []string) error {
 		logger.WithComponent("config").Info().Int("count", removed).Msg("Cleaned up broken placeholder image paths")
 	}
 
+	// Everything below runs until interrupted; cancelling ctx stops the
+	// background work, and the deferred Stops tear down in reverse order
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize window manager
-	logger.WithComponent("init").Info().Msg("Connecting to X11 server")
-	windowMgr, err := window.NewManager(configMgr)
+	backendName := GetBackend(cfg)
+	logger.WithComponent("init").Info().Str("backend", backendName).Msg("Initializing window backend")
+	windowMgr, err := window.NewManagerWithBackend(configMgr, backendName)
 	if err != nil {
 		return fmt.Errorf("failed to initialize window manager: %w", err)
 	}
@@ -94,7 +127,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 
 	// Start window monitoring
 	logger.WithComponent("serve").Info().Msg("Starting window focus monitoring...")
-	if err := windowMgr.Start(); err != nil {
+	if err := windowMgr.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start window manager: %w", err)
 	}
 
@@ -102,6 +135,9 @@ func runServe(cmd *cobra.Command, args []string) error {
 	logger.WithComponent("serve").Info().Msg("Initializing overlay system...")
 	overlayMgr := overlay.NewManager()
 	overlayMgr.SetEnabled(cfg.Overlay.Enabled)
+	overlayMgr.SetAllowCommands(cfg.Overlay.AllowCommands)
+	overlayMgr.SetCommands(cfg.Overlay.Commands)
+	overlayMgr.SetDisabledGroups(cfg.Overlay.DisabledGroups)
 
 	// Load overlay widgets from config
 	if len(cfg.Overlay.Widgets) > 0 {
@@ -120,47 +156,209 @@ func runServe(cmd *cobra.Command, args []string) error {
 	mjpegOut := output.NewMJPEGOutput(output.Config{
 		Width:  cfg.VirtualDisplay.Width,
 		Height: cfg.VirtualDisplay.Height,
-		FPS:    cfg.VirtualDisplay.FPS,
+		FPS:    cfg.StreamFPS(),
+
+		MaxBitrate:       cfg.Bandwidth.MaxBitrate(),
+		ClientMaxBitrate: cfg.Bandwidth.ClientMaxBitrate(),
+
+		Watermark:        cfg.Watermark.Enabled,
+		WatermarkOpacity: cfg.Watermark.Opacity,
 	})
 	if err := mjpegOut.Start(); err != nil {
 		return fmt.Errorf("failed to start MJPEG output: %w", err)
 	}
 	defer mjpegOut.Stop()
 
-	// Set MJPEG output and overlay manager on window manager
-	windowMgr.SetOutput(mjpegOut)
+	// Low-power mode encodes JPEG on a hardware encoder when there is one
+	if cfg.LowPower.Enabled && cfg.LowPower.HardwareJPEG != hwjpeg.DeviceOff {
+		encoder, err := hwjpeg.Open(cfg.LowPower.HardwareJPEG)
+		if err != nil {
+			logger.WithComponent("serve").Info().Err(err).Msg("Hardware JPEG encoder unavailable, encoding in software")
+		} else {
+			defer encoder.Close()
+			mjpegOut.SetJPEGEncoder(encoder)
+			logger.WithComponent("serve").Info().Str("device", encoder.Device()).Msg("Encoding JPEG on hardware encoder")
+		}
+	} else if !cfg.LowPower.Enabled && runtime.GOARCH == "arm64" {
+		logger.WithComponent("serve").Info().Msg("Running on arm64; on a small board, try 'focusstreamer config preset low-power'")
+	}
+
+	// Frames fan out to every registered output; MJPEG is always one
+	outputs := output.NewMultiplexer()
+	if err := outputs.Add("mjpeg", mjpegOut, 0); err != nil {
+		return fmt.Errorf("failed to register MJPEG output: %w", err)
+	}
+	if err := outputs.Start(); err != nil {
+		return fmt.Errorf("failed to start outputs: %w", err)
+	}
+	defer outputs.Stop()
+
+	// Set outputs and overlay manager on window manager
+	windowMgr.SetOutput(outputs)
 	windowMgr.SetOverlayManager(overlayMgr)
+	overlayMgr.SetStatsReporter(mjpegOut)
+
+	// Optional OCR guard for sensitive on-screen text
+	if cfg.PIIGuard.Enabled {
+		guard, err := pii.NewGuard(cfg.PIIGuard)
+		if err != nil {
+			logger.WithComponent("serve").Warn().Err(err).Msg("PII guard disabled")
+		} else {
+			windowMgr.SetPIIGuard(guard)
+		}
+	}
+
+	// Alert when an application is streamed for the first time (optional),
+	// set before streaming starts so the first window is announced too
+	if cfg.NotifyNewShares {
+		alerts, err := sharealert.NewNotifier(func() { windowMgr.SetForceStandby(true) })
+		if err != nil {
+			logger.WithComponent("serve").Warn().Err(err).Msg("Share alerts disabled")
+		} else {
+			defer alerts.Close()
+			windowMgr.SetOnNewClassSharedCallback(alerts.Shared)
+			defer windowMgr.SetOnNewClassSharedCallback(nil)
+		}
+	}
+
+	// Typed events (pkg/events) for /api/events/ws and D-Bus, set before
+	// streaming starts so the first focus and standby events are sent
+	eventHub := eventhub.New()
+	windowMgr.SetOnEventCallback(eventHub.Publish)
+	defer windowMgr.SetOnEventCallback(nil)
+	configMgr.SetOnSaveCallback(func() { eventHub.Publish(eventhub.ConfigUpdated(configMgr)) })
+	defer configMgr.SetOnSaveCallback(nil)
 
 	// Start streaming
-	if err := windowMgr.StartStreaming(cfg.VirtualDisplay.FPS); err != nil {
+	if err := windowMgr.StartStreaming(cfg.StreamFPS()); err != nil {
 		return fmt.Errorf("failed to start streaming: %w", err)
 	}
 	defer windowMgr.StopStreaming()
 
+	// Announce on-air transitions (D-Bus signal and hooks)
+	indicator := onair.NewIndicator(cfg.OnAir)
+	defer indicator.Close()
+	windowMgr.SetOnAirCallback(indicator.Set)
+
+	// Enable Do Not Disturb while the stream has viewers
+	suppressor := dnd.NewSuppressor(cfg.SuppressNotifications)
+	defer suppressor.Close()
+	mjpegOut.SetOnClientsChanged(func(count int) {
+		windowMgr.SetClientCount(count)
+		suppressor.SetLive(count > 0 && windowMgr.GetHealthStatus().StreamRunning)
+	})
+
 	logger.WithComponent("serve").Info().Msgf("MJPEG stream initialized (%dx%d @ %d FPS)",
-		cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height, cfg.VirtualDisplay.FPS)
+		cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height, cfg.StreamFPS())
 
 	// Initialize API server
 	logger.WithComponent("serve").Info().Msg("Initializing HTTP server...")
 	server := api.NewServer(windowMgr, configMgr, nil, mjpegOut, overlayMgr)
+	server.SetNotificationSuppressor(suppressor)
+	server.SetOutputs(outputs)
+	server.SetEvents(eventHub)
+
+	// Count how long each application has focus (optional)
+	var focusStats *focusstats.Store
+	if cfg.FocusStats.Enabled {
+		focusStats, err = focusstats.Open(filepath.Join(configMgr.GetConfigDir(), focusstats.FileName), cfg.FocusStats.RetentionDays)
+		if err != nil {
+			logger.WithComponent("serve").Warn().Err(err).Msg("Focus statistics disabled")
+		} else {
+			server.SetFocusStats(focusStats)
+		}
+	}
+
+	if serveAssetsDir != "" {
+		webDir := filepath.Join(serveAssetsDir, "web", "dist")
+		templateDir := filepath.Join(serveAssetsDir, "internal", "output", "templates")
+		server.SetWebDir(webDir)
+		mjpegOut.SetTemplateDir(templateDir)
+		logger.WithComponent("serve").Info().
+			Str("web_dir", webDir).
+			Str("template_dir", templateDir).
+			Msg("Serving web assets from disk")
+	}
+
+	// Serve additional sessions from the config under /u/<name>/. Workers
+	// serve only their own session.
+	primary := session.Info{
+		Name:           session.PrimaryName,
+		Display:        os.Getenv("DISPLAY"),
+		WaylandDisplay: os.Getenv("WAYLAND_DISPLAY"),
+		Backend:        backendName,
+		Port:           cfg.ServerPort,
+	}
+	sessionConfigs := cfg.Sessions
+	if name := os.Getenv(session.EnvSessionName); name != "" {
+		primary.Name = name
+		sessionConfigs = nil
+	}
+	sessionMgr, err := session.NewManager(configMgr, primary, sessionConfigs)
+	if err != nil {
+		return fmt.Errorf("failed to initialize sessions: %w", err)
+	}
+	server.SetSessions(sessionMgr)
+	sessionMgr.Start()
+	defer sessionMgr.Stop()
+	if len(sessionConfigs) > 0 {
+		logger.WithComponent("serve").Info().Msgf("Serving %d additional sessions, see http://localhost:%d/api/sessions", len(sessionConfigs), cfg.ServerPort)
+	}
 
 	// Set up profile change callback to notify window manager
 	server.SetOnProfileChange(func(profileID string) {
 		windowMgr.OnProfileChanged(profileID)
 	})
 
-	// Start server in a goroutine
-	go func() {
+	// Control over D-Bus for desktop shortcuts and scripts (optional)
+	busService, err := dbusservice.NewService(windowMgr, configMgr)
+	if err != nil {
+		logger.WithComponent("serve").Warn().Err(err).Msg("D-Bus service disabled")
+	} else {
+		defer busService.Close()
+		busService.SetOnProfileChange(windowMgr.OnProfileChanged)
+		busService.ForwardEvents(eventHub)
+
+		if _, err := krunner.Export(busService, windowMgr, configMgr, server.Approvals()); err != nil {
+			logger.WithComponent("serve").Warn().Err(err).Msg("KRunner runner disabled")
+		}
+	}
+
+	// The server and startup actions run until interrupted; if the server
+	// fails, everything shuts down and serve returns its error
+	group, groupCtx := errgroup.WithContext(ctx)
+
+	// Restore the configured profile, zoom and window; waiting for the window
+	// mustn't hold up the server
+	group.Go(func() error {
+		windowMgr.RunStartupActions(groupCtx, cfg.Startup)
+		return nil
+	})
+
+	if focusStats != nil {
+		group.Go(func() error {
+			focusstats.Track(groupCtx, windowMgr, focusStats)
+			return nil
+		})
+	}
+
+	// Forward focus and stream segments to ActivityWatch (optional)
+	if cfg.ActivityWatch.Enabled {
+		exporter := activitywatch.NewExporter(cfg.ActivityWatch, windowMgr)
+		group.Go(func() error {
+			exporter.Run(groupCtx)
+			return nil
+		})
+	}
+
+	group.Go(func() error {
 		logger.WithComponent("serve").Info().Msgf("Server starting on http://localhost:%d", cfg.ServerPort)
 		logger.WithComponent("serve").Info().Msgf("Open http://localhost:%d in your browser to configure", cfg.ServerPort)
-		if err := server.Start(cfg.ServerPort); err != nil {
-			logger.WithComponent("serve").Fatal().Msgf("Server error: %v", err)
+		if err := server.Start(groupCtx, cfg.ServerPort); err != nil {
+			return fmt.Errorf("server error: %w", err)
 		}
-	}()
-
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+		return nil
+	})
 
 	fmt.Println()
 	logger.WithComponent("serve").Info().Msg("✅ FocusStreamer is running!")
@@ -169,13 +367,16 @@ func runServe(cmd *cobra.Command, args []string) error {
 	logger.WithComponent("serve").Info().Msgf("   - Stream Viewer: http://localhost:%d/view (open this in browser and share the tab in Discord!)", cfg.ServerPort)
 	logger.WithComponent("serve").Info().Msgf("   - Raw MJPEG Feed: http://localhost:%d/stream", cfg.ServerPort)
 	logger.WithComponent("serve").Info().Msgf("   - Stream Stats: http://localhost:%d/stats", cfg.ServerPort)
+	if focusStats != nil {
+		logger.WithComponent("serve").Info().Msgf("   - Focus Report: http://localhost:%d/report", cfg.ServerPort)
+	}
 	logger.WithComponent("serve").Info().Msgf("   - Overlay API: http://localhost:%d/api/overlay/types", cfg.ServerPort)
 	logger.WithComponent("serve").Info().Msg("   - Press Ctrl+C to stop")
 	fmt.Println()
 
-	<-sigChan
+	<-groupCtx.Done()
 
 	fmt.Println()
 	logger.WithComponent("serve").Info().Msg("Shutting down gracefully...")
-	return nil
+	return group.Wait()
 }
@@ -2,7 +2,6 @@ package commands
 
 import (
 	"fmt"
-	"regexp"
 
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
 	"github.com/spf13/cobra"
@@ -61,8 +60,8 @@ func runTitlePatternAdd(cmd *cobra.Command, args []string) error {
 	pattern := args[0]
 
 	// Validate regex
-	if _, err := regexp.Compile(pattern); err != nil {
-		return fmt.Errorf("invalid regex pattern: %w", err)
+	if _, err := config.CompilePattern(pattern); err != nil {
+		return err
 	}
 
 	configMgr, err := config.NewManager(GetConfigFile())
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -26,6 +27,9 @@ func main() {
 	cfg := configMgr.Get()
 	log.Printf("Configuration loaded from: %s", configMgr.GetConfigPath())
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Initialize window manager
 	log.Println("Connecting to X11 server...")
 	windowMgr, err := window.NewManager(configMgr)
@@ -36,7 +40,7 @@ func main() {
 
 	// Start window monitoring
 	log.Println("Starting window focus monitoring...")
-	if err := windowMgr.Start(); err != nil {
+	if err := windowMgr.Start(ctx); err != nil {
 		log.Fatalf("Failed to start window manager: %v", err)
 	}
 
@@ -45,7 +49,13 @@ func main() {
 	mjpegOut := output.NewMJPEGOutput(output.Config{
 		Width:  cfg.VirtualDisplay.Width,
 		Height: cfg.VirtualDisplay.Height,
-		FPS:    cfg.VirtualDisplay.FPS,
+		FPS:    cfg.StreamFPS(),
+
+		MaxBitrate:       cfg.Bandwidth.MaxBitrate(),
+		ClientMaxBitrate: cfg.Bandwidth.ClientMaxBitrate(),
+
+		Watermark:        cfg.Watermark.Enabled,
+		WatermarkOpacity: cfg.Watermark.Opacity,
 	})
 	if err := mjpegOut.Start(); err != nil {
 		log.Fatalf("Failed to start MJPEG output: %v", err)
@@ -54,7 +64,7 @@ func main() {
 
 	// Set MJPEG output on window manager and start streaming
 	windowMgr.SetOutput(mjpegOut)
-	if err := windowMgr.StartStreaming(cfg.VirtualDisplay.FPS); err != nil {
+	if err := windowMgr.StartStreaming(cfg.StreamFPS()); err != nil {
 		log.Fatalf("Failed to start streaming: %v", err)
 	}
 	defer windowMgr.StopStreaming()
@@ -67,15 +77,11 @@ func main() {
 	go func() {
 		log.Printf("Server starting on http://localhost:%d", cfg.ServerPort)
 		log.Printf("Open http://localhost:%d in your browser to configure", cfg.ServerPort)
-		if err := server.Start(cfg.ServerPort); err != nil {
+		if err := server.Start(ctx, cfg.ServerPort); err != nil {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
 
-	// Wait for interrupt signal
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-
 	fmt.Println()
 	log.Println("✅ FocusStreamer is running!")
 	log.Printf("   - Web UI: http://localhost:%d", cfg.ServerPort)
@@ -83,7 +89,7 @@ func main() {
 	log.Println("   - Press Ctrl+C to stop")
 	fmt.Println()
 
-	<-sigChan
+	<-ctx.Done()
 
 	fmt.Println()
 	log.Println("Shutting down gracefully...")
@@ -1,6 +1,17 @@
 const API_BASE = 'http://127.0.0.1:8080/api'
 const CONTEXT_ENDPOINT = `${API_BASE}/browser/active`
 const WINDOW_ENDPOINT = `${API_BASE}/window/current`
+const SOCKET_ENDPOINT = 'ws://127.0.0.1:8080/api/browser/ws'
+const NATIVE_HOST = 'org.focusstreamer.browser_host'
+const RECONNECT_DELAY_MS = 5000
+
+// Live transport to FocusStreamer: the native messaging host if installed,
+// otherwise a WebSocket. Both carry the same JSON messages; while connected
+// the server keeps our tab context fresh. HTTP POST is the last resort.
+let nativePort = null
+let socket = null
+let reconnectTimer = null
+let lastStatus = null
 
 const storageKeys = {
   browserWindowClass: 'browserWindowClass'
@@ -51,6 +62,78 @@ const fetchWindowClass = async () => {
   return null
 }
 
+const handleStatus = (message) => {
+  if (message?.type === 'status') {
+    lastStatus = message
+  }
+}
+
+const scheduleReconnect = () => {
+  if (reconnectTimer) {
+    return
+  }
+  reconnectTimer = setTimeout(() => {
+    reconnectTimer = null
+    connect()
+  }, RECONNECT_DELAY_MS)
+}
+
+const connectSocket = () => {
+  try {
+    socket = new WebSocket(SOCKET_ENDPOINT)
+  } catch (err) {
+    socket = null
+    scheduleReconnect()
+    return
+  }
+  socket.onopen = () => updateContext()
+  socket.onmessage = (event) => {
+    try {
+      handleStatus(JSON.parse(event.data))
+    } catch (err) {
+      // Ignore malformed messages
+    }
+  }
+  socket.onclose = () => {
+    socket = null
+    scheduleReconnect()
+  }
+}
+
+const connect = () => {
+  if (nativePort || socket) {
+    return
+  }
+
+  if (chrome.runtime.connectNative) {
+    const port = chrome.runtime.connectNative(NATIVE_HOST)
+    nativePort = port
+    port.onMessage.addListener(handleStatus)
+    port.onDisconnect.addListener(() => {
+      // Host not installed or server unreachable: use the WebSocket instead
+      void chrome.runtime.lastError
+      nativePort = null
+      connectSocket()
+    })
+    updateContext()
+    return
+  }
+
+  connectSocket()
+}
+
+const sendLive = (message) => {
+  if (nativePort) {
+    nativePort.postMessage(message)
+    return true
+  }
+  if (socket && socket.readyState === WebSocket.OPEN) {
+    socket.send(JSON.stringify(message))
+    return true
+  }
+  return false
+}
+
 const postBrowserContext = async (tab) => {
   if (!tab || !tab.url) {
     return
@@ -62,6 +145,16 @@ const postBrowserContext = async (tab) => {
     return
   }
 
+  const message = {
+    type: 'tab',
+    window_class: windowClass,
+    url: tab.url,
+    title: tab.title || ''
+  }
+  if (sendLive(message)) {
+    return
+  }
+
   try {
     await fetch(CONTEXT_ENDPOINT, {
       method: 'POST',
@@ -101,19 +194,26 @@ const ensureOffscreen = async () => {
 
 chrome.runtime.onInstalled.addListener(() => {
   ensureOffscreen()
+  connect()
 })
 
 chrome.runtime.onStartup.addListener(() => {
   ensureOffscreen()
+  connect()
 })
 
-chrome.runtime.onMessage.addListener((message) => {
+chrome.runtime.onMessage.addListener((message, sender, sendResponse) => {
   if (message?.type === 'heartbeat') {
+    // Heartbeats also revive the live connection after the worker restarts
+    connect()
     updateContext()
   }
   if (message?.type === 'refresh') {
     updateContext()
   }
+  if (message?.type === 'status') {
+    sendResponse(lastStatus)
+  }
 })
 
 chrome.tabs.onActivated.addListener(() => {
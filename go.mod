@@ -10,16 +10,22 @@ require (
 	github.com/spf13/viper v1.21.0
 )
 
-require golang.org/x/image v0.33.0
+require (
+	github.com/godbus/dbus/v5 v5.2.0
+	github.com/graph-gophers/graphql-go v1.5.0
+	github.com/rs/zerolog v1.34.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
+	github.com/tinyzimmer/go-gst v0.2.33
+	golang.org/x/image v0.33.0
+	golang.org/x/sync v0.18.0
+	google.golang.org/protobuf v1.36.9
+)
 
 require (
-	github.com/godbus/dbus/v5 v5.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	github.com/mattn/go-pointer v0.0.1 // indirect
-	github.com/rs/zerolog v1.34.0 // indirect
 	github.com/tinyzimmer/go-glib v0.0.25 // indirect
-	github.com/tinyzimmer/go-gst v0.2.33 // indirect
 )
 
 require (
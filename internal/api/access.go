@@ -0,0 +1,488 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/remote"
+	"github.com/bryanchriswhite/FocusStreamer/internal/session"
+)
+
+// Link token scopes. A viewer token also opens the view pages and reads
+// state through the API without changing anything, for an accountability
+// partner; a control token grants both. An ingest token lets a capture
+// agent or an external source feed the stream.
+const (
+	tokenScopeView    = "view"
+	tokenScopeViewer  = "viewer"
+	tokenScopeControl = "control"
+	tokenScopeIngest  = "ingest"
+)
+
+const (
+	// tokenKeyFile holds the HMAC key signing link tokens, in the config dir
+	tokenKeyFile = "stream-token.key"
+
+	// tokenCookie carries a link token to the pages and stream it links to
+	tokenCookie = "focusstreamer_token"
+
+	defaultTokenTTL = 2 * time.Hour
+	maxTokenTTL     = 30 * 24 * time.Hour
+)
+
+// tokenClaims is the signed payload of a link token
+type tokenClaims struct {
+	Expires int64  `json:"exp"`
+	Scope   string `json:"scope"`
+	Label   string `json:"label,omitempty"`
+}
+
+// allows reports whether the claims grant scope
+func (c tokenClaims) allows(scope string) bool {
+	switch c.Scope {
+	case tokenScopeControl:
+		return scope != tokenScopeIngest
+	case tokenScopeViewer:
+		return scope == tokenScopeViewer || scope == tokenScopeView
+	}
+	return c.Scope == scope
+}
+
+// streamAccess issues and verifies link tokens. The key is created on first
+// use, so it only exists once tokens are used.
+type streamAccess struct {
+	keyPath string
+	mu      sync.Mutex
+	key     []byte
+}
+
+func newStreamAccess(configMgr *config.Manager) *streamAccess {
+	return &streamAccess{keyPath: filepath.Join(configMgr.GetConfigDir(), tokenKeyFile)}
+}
+
+// signingKey loads the key, creating it if needed
+func (a *streamAccess) signingKey() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.key != nil {
+		return a.key, nil
+	}
+	key, err := os.ReadFile(a.keyPath)
+	if err == nil && len(key) >= 32 {
+		a.key = key
+		return key, nil
+	}
+	return a.rotateLocked()
+}
+
+// Rotate replaces the key, revoking every token issued so far
+func (a *streamAccess) Rotate() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err := a.rotateLocked()
+	return err
+}
+
+func (a *streamAccess) rotateLocked() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate token key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(a.keyPath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(a.keyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write token key: %w", err)
+	}
+	a.key = key
+	return key, nil
+}
+
+// Issue returns a token granting scope until ttl from now
+func (a *streamAccess) Issue(scope, label string, ttl time.Duration) (string, time.Time, error) {
+	key, err := a.signingKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(ttl).Truncate(time.Second)
+	payload, err := json.Marshal(tokenClaims{Expires: expires.Unix(), Scope: scope, Label: label})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + sign(key, encoded), expires, nil
+}
+
+// Verify checks a token's signature and expiry and returns its claims
+func (a *streamAccess) Verify(token string) (tokenClaims, error) {
+	var claims tokenClaims
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return claims, fmt.Errorf("malformed token")
+	}
+	key, err := a.signingKey()
+	if err != nil {
+		return claims, err
+	}
+	if !hmac.Equal([]byte(signature), []byte(sign(key, encoded))) {
+		return claims, fmt.Errorf("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return claims, fmt.Errorf("malformed token")
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, fmt.Errorf("malformed token")
+	}
+	if time.Now().Unix() >= claims.Expires {
+		return claims, fmt.Errorf("token expired")
+	}
+	return claims, nil
+}
+
+func sign(key []byte, encoded string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// viewerAPIPaths are the API reads a viewer token allows: the current
+// window, standby and other stream state, and the timeline. Other reads,
+// like window lists, screenshots and the config, show more than the stream
+// does and need a control token.
+var viewerAPIPaths = map[string]bool{
+	"/api/health":                         true,
+	"/api/window/current":                 true,
+	"/api/window/allowlist-status":        true,
+	"/api/window/stream":                  true,
+	"/api/window/events":                  true,
+	"/api/applications/allowlisted":       true,
+	"/api/applications/allowlist/pending": true,
+	"/api/profiles/active":                true,
+	"/api/stream/panic":                   true,
+	"/api/stream/allowlist-bypass":        true,
+	"/api/stream/on-air":                  true,
+	"/api/stream/clients":                 true,
+	"/api/stream/pii-guard":               true,
+	"/api/stream/zoom":                    true,
+	"/api/stream/filters":                 true,
+	"/api/stream/source":                  true,
+	"/api/stream/thumbnail":               true,
+	"/api/stream/thumbnail/ws":            true,
+	"/api/stream/snapshot":                true,
+	"/api/scenes":                         true,
+	"/api/outputs":                        true,
+	"/api/annotations":                    true,
+	"/api/backend":                        true,
+	"/api/stats/focus":                    true,
+}
+
+// streamPathScope returns the token scope a stream page or API request
+// needs, including those of other sessions under /u/<session>/
+func streamPathScope(method, path string) (string, bool) {
+	if rest, ok := strings.CutPrefix(path, session.PathPrefix); ok {
+		path = "/"
+		if i := strings.Index(rest, "/"); i >= 0 {
+			path = rest[i:]
+		}
+	}
+
+	switch path {
+	case "/", "/embed", "/stream", "/timeline", "/api/timeline":
+		return tokenScopeView, true
+	case "/report":
+		// Focus time covers applications that were never streamed
+		return tokenScopeViewer, true
+	case "/control":
+		return tokenScopeControl, true
+	case remote.AgentPath:
+		return tokenScopeIngest, true
+	}
+	if strings.HasPrefix(path, ingestPathPrefix) {
+		return tokenScopeIngest, true
+	}
+	if strings.HasPrefix(path, "/api/") {
+		return apiScope(method, path)
+	}
+	return "", false
+}
+
+// apiScope returns the token scope an API request needs: view for the
+// reads the view pages make, viewer for other state, control for the rest
+func apiScope(method, path string) (string, bool) {
+	if method != http.MethodGet && method != http.MethodHead {
+		// Approvers authenticate with the approver secret instead
+		if strings.HasPrefix(path, "/api/applications/allowlist/pending/") {
+			return "", false
+		}
+		return tokenScopeControl, true
+	}
+	switch {
+	case path == "/api/stream/standby" || path == "/api/stream/geometry":
+		return tokenScopeView, true
+	case viewerAPIPaths[path]:
+		return tokenScopeViewer, true
+	}
+	return tokenScopeControl, true
+}
+
+// clientAddr returns the address a request came from. Behind a reverse proxy
+// or tunnel on this machine it is the last X-Forwarded-For hop, the one the
+// proxy added; forwarded reports whether that was used.
+func clientAddr(r *http.Request) (addr netip.Addr, forwarded bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, _ = netip.ParseAddr(host)
+	addr = addr.Unmap()
+
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" && addr.IsLoopback() {
+		hops := strings.Split(xff, ",")
+		if hop, err := netip.ParseAddr(strings.TrimSpace(hops[len(hops)-1])); err == nil {
+			return hop.Unmap(), true
+		}
+	}
+	return addr, false
+}
+
+// cidrsContain reports whether addr is in any of the CIDR ranges
+func cidrsContain(cidrs []string, addr netip.Addr) bool {
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin refuses requests that change state from pages on other sites.
+// Form posts and text/plain bodies skip the CORS preflight, so without it
+// any page open in the browser could drive the API of this machine. Browsers
+// send Origin with every such request; clients that aren't browsers don't
+// send one and are unaffected.
+func checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			host := r.Host
+			if _, forwarded := clientAddr(r); forwarded && r.Header.Get("X-Forwarded-Host") != "" {
+				host = r.Header.Get("X-Forwarded-Host")
+			}
+			if origin := r.Header.Get("Origin"); origin != "" && !trustedOrigin(origin, host) {
+				logger.WithComponent("stream-access").Warn().
+					Str("origin", origin).
+					Str("path", r.URL.Path).
+					Msg("Refused cross-site request")
+				writeError(w, http.StatusForbidden, codeForbidden, "Cross-site requests can't change anything")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// trustedOrigin reports whether pages from origin may change state: pages
+// served by host itself (as the browser addressed it, through a proxy), pages on this machine (such as the frontend dev
+// server) and the browser extension
+func trustedOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "chrome-extension", "moz-extension":
+		return true
+	case "http", "https":
+	default:
+		// Including "null", sent by sandboxed frames and file:// pages
+		return false
+	}
+	if strings.EqualFold(u.Host, host) || u.Hostname() == "localhost" {
+		return true
+	}
+	addr, err := netip.ParseAddr(u.Hostname())
+	return err == nil && addr.IsLoopback()
+}
+
+// checkStreamAccess enforces stream_access on the stream pages and the API.
+// Requests from this machine (not forwarded by a proxy) are always allowed,
+// so the local control page keeps working.
+func (s *Server) checkStreamAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scope, protected := streamPathScope(r.Method, r.URL.Path)
+		if !protected {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		addr, forwarded := clientAddr(r)
+		if !forwarded && addr.IsLoopback() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rules := s.configMgr.Get().StreamAccess
+		log := logger.WithComponent("stream-access")
+		if len(rules.AllowedCIDRs) > 0 && !cidrsContain(rules.AllowedCIDRs, addr) {
+			log.Warn().Str("addr", addr.String()).Str("path", r.URL.Path).Msg("Refused stream request from outside allowed ranges")
+			writeError(w, http.StatusForbidden, codeForbidden, "Forbidden")
+			return
+		}
+		// Frames from other machines always need a token, since they end up
+		// on the stream
+		if !rules.RequireToken && scope != tokenScopeIngest {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := r.URL.Query().Get("token")
+		fromQuery := token != ""
+		if !fromQuery {
+			if cookie, err := r.Cookie(tokenCookie); err == nil {
+				token = cookie.Value
+			}
+		}
+		if token == "" {
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "This stream needs a link token")
+			return
+		}
+
+		claims, err := s.access.Verify(token)
+		if err != nil {
+			log.Info().Err(err).Str("addr", addr.String()).Msg("Rejected link token")
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "Invalid link token: "+err.Error())
+			return
+		}
+		if !claims.allows(scope) {
+			writeError(w, http.StatusForbidden, codeForbidden, "This link doesn't grant "+scope+" access")
+			return
+		}
+
+		// Links between pages and the stream carry the token in a cookie
+		if fromQuery {
+			http.SetCookie(w, &http.Cookie{
+				Name:     tokenCookie,
+				Value:    token,
+				Path:     "/",
+				Expires:  time.Unix(claims.Expires, 0),
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+
+		// End the stream when the token expires
+		ctx, cancel := context.WithDeadline(r.Context(), time.Unix(claims.Expires, 0))
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// handleCreateToken issues a link token, e.g. a 2-hour view link:
+// {"scope": "view", "ttl_minutes": 120, "label": "alice"}
+func (s *Server) handleCreateToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Scope      string `json:"scope"`
+		TTLMinutes int    `json:"ttl_minutes"`
+		Label      string `json:"label"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+	}
+
+	if req.Scope == "" {
+		req.Scope = tokenScopeView
+	}
+	if req.Scope != tokenScopeView && req.Scope != tokenScopeViewer && req.Scope != tokenScopeControl && req.Scope != tokenScopeIngest {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "scope must be view, viewer, control or ingest")
+		return
+	}
+	ttl, err := tokenTTL(req.TTLMinutes)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	token, expires, err := s.access.Issue(req.Scope, req.Label, ttl)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	logger.WithComponent("stream-access").Info().
+		Str("scope", req.Scope).
+		Str("label", req.Label).
+		Time("expires_at", expires).
+		Msg("Link token issued")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":      token,
+		"scope":      req.Scope,
+		"label":      req.Label,
+		"expires_at": expires,
+		"path":       tokenPage(req.Scope) + "?token=" + token,
+	})
+}
+
+// tokenTTL returns the lifetime of a token requested for minutes, 0 for
+// the default
+func tokenTTL(minutes int) (time.Duration, error) {
+	ttl := time.Duration(minutes) * time.Minute
+	if ttl == 0 {
+		ttl = defaultTokenTTL
+	}
+	if ttl < 0 || ttl > maxTokenTTL {
+		return 0, fmt.Errorf("ttl_minutes must be between 1 and %d", int(maxTokenTTL.Minutes()))
+	}
+	return ttl, nil
+}
+
+// tokenPage returns the page a token of scope is shared for
+func tokenPage(scope string) string {
+	switch scope {
+	case tokenScopeControl:
+		return "/control"
+	case tokenScopeIngest:
+		return remote.AgentPath
+	}
+	return "/"
+}
+
+// handleRevokeTokens replaces the signing key, revoking every link token
+func (s *Server) handleRevokeTokens(w http.ResponseWriter, r *http.Request) {
+	if err := s.access.Rotate(); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	logger.WithComponent("stream-access").Info().Msg("All link tokens revoked")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+}
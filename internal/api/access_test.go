@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		host       string
+		remote     string
+		forwarded  string // X-Forwarded-Host
+		wantStatus int
+	}{
+		{"no origin", http.MethodPost, "", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusOK},
+		{"same origin", http.MethodPost, "http://127.0.0.1:8080", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusOK},
+		{"lan same origin", http.MethodPut, "http://192.168.1.20:8080", "192.168.1.20:8080", "192.168.1.30:5000", "", http.StatusOK},
+		{"dev server", http.MethodPost, "http://localhost:5173", "localhost:8080", "127.0.0.1:5000", "", http.StatusOK},
+		{"loopback ipv6", http.MethodDelete, "http://[::1]:5173", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusOK},
+		{"extension", http.MethodPost, "chrome-extension://abcdefgh", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusOK},
+		{"proxied same origin", http.MethodPost, "https://stream.example.com", "127.0.0.1:8080", "127.0.0.1:5000", "stream.example.com", http.StatusOK},
+		{"cross site", http.MethodPost, "https://evil.example", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusForbidden},
+		{"cross site put", http.MethodPut, "https://evil.example", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusForbidden},
+		{"null origin", http.MethodPost, "null", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusForbidden},
+		{"forwarded host from outside ignored", http.MethodPost, "https://evil.example", "192.168.1.20:8080", "192.168.1.30:5000", "evil.example", http.StatusForbidden},
+		{"cross site read", http.MethodGet, "https://evil.example", "127.0.0.1:8080", "127.0.0.1:5000", "", http.StatusOK},
+	}
+	handler := checkOrigin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/overlay/instances", nil)
+			req.Host = tt.host
+			req.RemoteAddr = tt.remote
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-Host", tt.forwarded)
+				req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
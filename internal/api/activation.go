@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket
+// activation (SD_LISTEN_FDS_START)
+const listenFDsStart = 3
+
+// activationListener returns the listening socket passed by systemd socket
+// activation, or nil if the process wasn't socket activated. The activation
+// variables are cleared so they don't leak into child processes.
+func activationListener() (net.Listener, error) {
+	pid, _ := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	fds, _ := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid != os.Getpid() || fds < 1 {
+		return nil, nil
+	}
+	if fds > 1 {
+		return nil, fmt.Errorf("expected one socket from systemd, got %d", fds)
+	}
+
+	file := os.NewFile(listenFDsStart, "systemd-socket")
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use socket from systemd: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// handleAgentSocket accepts a capture agent (focusstreamer agent). Only a
+// server started with the remote backend takes agents.
+func (s *Server) handleAgentSocket(w http.ResponseWriter, r *http.Request) {
+	hub := s.windowMgr.RemoteHub()
+	if hub == nil {
+		writeError(w, http.StatusConflict, codeConflict, "This server doesn't take capture agents (start it with --backend remote)")
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.WithComponent("remote").Warn().Err(err).Msg("Agent WebSocket upgrade failed")
+		return
+	}
+	hub.ServeAgent(conn)
+}
+
+// handleGetAgent reports the connected capture agent
+func (s *Server) handleGetAgent(w http.ResponseWriter, r *http.Request) {
+	hub := s.windowMgr.RemoteHub()
+	if hub == nil {
+		writeError(w, http.StatusConflict, codeConflict, "This server doesn't take capture agents (start it with --backend remote)")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hub.Status())
+}
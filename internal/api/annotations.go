@@ -0,0 +1,73 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+)
+
+// handleGetAnnotations returns the caption on the stream, the queued ones and
+// those posted this session
+func (s *Server) handleGetAnnotations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.windowMgr.GetCaptions())
+}
+
+// handleAddAnnotation queues a caption for the stream, e.g.
+// {"text": "back in 5", "duration_seconds": 10}
+func (s *Server) handleAddAnnotation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Text            string  `json:"text"`
+		DurationSeconds float64 `json:"duration_seconds"` // Optional, 5 by default
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+
+	addr, _ := clientAddr(r)
+	duration := time.Duration(req.DurationSeconds * float64(time.Second))
+	caption, err := s.windowMgr.AddCaption(req.Text, duration, addr.String())
+	if errors.Is(err, window.ErrCaptionQueueFull) {
+		writeError(w, http.StatusTooManyRequests, codeRateLimited, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(caption)
+}
+
+// handleClearAnnotations takes the current and queued captions off the stream
+func (s *Server) handleClearAnnotations(w http.ResponseWriter, r *http.Request) {
+	s.windowMgr.ClearCaptions()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDrawAnnotation draws a stroke over the stream, e.g.
+// {"points": [{"x": 0.2, "y": 0.4}, {"x": 0.3, "y": 0.45}], "color": "#ff3b30"}
+func (s *Server) handleDrawAnnotation(w http.ResponseWriter, r *http.Request) {
+	var stroke window.Stroke
+	if err := json.NewDecoder(r.Body).Decode(&stroke); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+	if err := s.windowMgr.AddStroke(stroke); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleClearDrawing removes every stroke from the stream
+func (s *Server) handleClearDrawing(w http.ResponseWriter, r *http.Request) {
+	s.windowMgr.ClearStrokes()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/approval"
+	"github.com/gorilla/mux"
+)
+
+// submitForApproval queues an allowlist addition made through the API and
+// answers 202 Accepted with the pending entry
+func (s *Server) submitForApproval(w http.ResponseWriter, entry approval.Entry) {
+	entry.Source = "api"
+	pending, err := s.approvals.Submit(entry)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":  "pending",
+		"pending": pending,
+	})
+}
+
+// handleGetPendingAllowlist lists the allowlist entries waiting for approval
+func (s *Server) handleGetPendingAllowlist(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"enabled": s.approvals.Enabled(),
+		"pending": s.approvals.Pending(),
+	})
+}
+
+// handleApproveAllowlistEntry adds a pending entry to the allowlist. It
+// needs the approver secret as a bearer token.
+func (s *Server) handleApproveAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	s.decideAllowlistEntry(w, r, s.approvals.Approve)
+}
+
+// handleRejectAllowlistEntry drops a pending entry. It needs the approver
+// secret as a bearer token.
+func (s *Server) handleRejectAllowlistEntry(w http.ResponseWriter, r *http.Request) {
+	s.decideAllowlistEntry(w, r, s.approvals.Reject)
+}
+
+func (s *Server) decideAllowlistEntry(w http.ResponseWriter, r *http.Request, decide func(id string) (approval.Entry, error)) {
+	secret, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !s.approvals.Authorize(secret) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="allowlist approval"`)
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "Approving allowlist entries needs the approver secret as a bearer token")
+		return
+	}
+
+	entry, err := decide(mux.Vars(r)["id"])
+	if errors.Is(err, approval.ErrNotFound) {
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entry)
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/overlay"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+)
+
+// TestWideningWaitsForApproval checks the changes that widen what is
+// streamed are held until approved while approval is on, and that the
+// narrowing side of each still applies at once
+func TestWideningWaitsForApproval(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		held    func(s *Server) bool // Reports whether the change is still unapplied
+		applied func(s *Server) bool // Reports whether the change took effect after approval
+	}{
+		{
+			name:    "allowlist bypass on",
+			method:  http.MethodPost,
+			path:    "/api/stream/allowlist-bypass",
+			held:    func(s *Server) bool { return !s.windowMgr.GetAllowlistBypass() },
+			applied: func(s *Server) bool { return s.windowMgr.GetAllowlistBypass() },
+		},
+		{
+			name:    "browser allowed",
+			method:  http.MethodPost,
+			path:    "/api/browser/allowlist",
+			body:    `{"window_class": "Firefox", "allowed": true}`,
+			held:    func(s *Server) bool { return s.configMgr.IsBrowserBlocked("firefox") },
+			applied: func(s *Server) bool { return !s.configMgr.IsBrowserBlocked("firefox") },
+		},
+		{
+			name:    "external source",
+			method:  http.MethodPut,
+			path:    "/api/stream/source",
+			body:    `{"external": "phone"}`,
+			held:    func(s *Server) bool { return s.windowMgr.SelectedExternalSource() == "" },
+			applied: func(s *Server) bool { return s.windowMgr.SelectedExternalSource() == "phone" },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newApprovalTestServer(t)
+			if code, body := serve(s, tt.method, tt.path, tt.body); code != http.StatusAccepted {
+				t.Fatalf("status = %d (%s), want 202", code, body)
+			}
+			if !tt.held(s) {
+				t.Fatal("change applied before approval")
+			}
+
+			pending := s.approvals.Pending()
+			if len(pending) != 1 {
+				t.Fatalf("%d entries pending, want 1", len(pending))
+			}
+			// Asking again doesn't apply it either
+			serve(s, tt.method, tt.path, tt.body)
+			if !tt.held(s) || len(s.approvals.Pending()) != 1 {
+				t.Fatal("repeated request applied or queued the change again")
+			}
+
+			if _, err := s.approvals.Approve(pending[0].ID); err != nil {
+				t.Fatal(err)
+			}
+			if !tt.applied(s) {
+				t.Error("change not applied after approval")
+			}
+		})
+	}
+}
+
+func TestNarrowingSkipsApproval(t *testing.T) {
+	s := newApprovalTestServer(t)
+	s.windowMgr.SetAllowlistBypass(true)
+	if err := s.windowMgr.SelectExternalSource("phone"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, req := range []struct{ method, path, body string }{
+		{http.MethodPost, "/api/stream/allowlist-bypass", ""},
+		{http.MethodPost, "/api/browser/allowlist", `{"window_class": "chromium", "allowed": false}`},
+		{http.MethodPut, "/api/stream/source", `{"external": ""}`},
+	} {
+		if code, body := serve(s, req.method, req.path, req.body); code != http.StatusOK {
+			t.Errorf("%s %s: status = %d (%s), want 200", req.method, req.path, code, body)
+		}
+	}
+	if s.windowMgr.GetAllowlistBypass() || s.windowMgr.SelectedExternalSource() != "" || !s.configMgr.IsBrowserBlocked("chromium") {
+		t.Error("narrowing changes were not applied at once")
+	}
+	if pending := s.approvals.Pending(); len(pending) != 0 {
+		t.Errorf("%d entries pending, want none", len(pending))
+	}
+}
+
+// newApprovalTestServer returns a server over the synthetic window backend
+// with allowlist approval on and firefox blocked
+func newApprovalTestServer(t *testing.T) *Server {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	configMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := configMgr.SetBrowserBlocked("firefox", true); err != nil {
+		t.Fatal(err)
+	}
+	cfg := *configMgr.Get()
+	cfg.AllowlistApproval = config.AllowlistApprovalConfig{Enabled: true, SecretSHA256: config.HashApprovalSecret("secret")}
+	if err := configMgr.Update(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	windowMgr, err := window.NewManagerWithBackend(configMgr, window.BackendSynthetic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(windowMgr, configMgr, nil, nil, overlay.NewManager())
+}
+
+// serve runs a request from this machine through the server's handler
+func serve(s *Server, method, path, body string) (int, string) {
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec.Code, rec.Body.String()
+}
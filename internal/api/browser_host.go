@@ -0,0 +1,297 @@
+package api
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/websocket"
+)
+
+// Browser extension protocol. The extension sends BrowserMessage values and
+// receives BrowserStatusMessage values, either over the /api/browser/ws
+// WebSocket or through the native messaging host (which relays the same
+// JSON messages to the WebSocket).
+const (
+	BrowserMessageTab    = "tab"    // Active tab changed (window_class, url, title)
+	BrowserMessagePing   = "ping"   // Keepalive; answered with a status message
+	BrowserMessageStatus = "status" // Server -> extension allowlist status
+
+	// NativeHostName is the native messaging host name registered with the browser
+	NativeHostName = "org.focusstreamer.browser_host"
+
+	// nativeMessageMaxSize caps messages read from the browser (Chrome limits
+	// host-to-browser messages to 1 MB; tab updates are far smaller)
+	nativeMessageMaxSize = 1024 * 1024
+)
+
+// BrowserMessage is sent by the browser extension
+type BrowserMessage struct {
+	Type        string `json:"type"`
+	WindowClass string `json:"window_class,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Title       string `json:"title,omitempty"`
+}
+
+// BrowserStatusMessage reports how FocusStreamer treats the reported tab
+type BrowserStatusMessage struct {
+	Type            string                 `json:"type"`
+	WindowClass     string                 `json:"window_class"`
+	URL             string                 `json:"url,omitempty"`
+	Domain          string                 `json:"domain,omitempty"`
+	URLAllowlisted  bool                   `json:"url_allowlisted"`
+	Blocked         bool                   `json:"blocked"`
+	Focused         bool                   `json:"focused"`
+	Allowlisted     bool                   `json:"allowlisted"`
+	AllowlistSource config.AllowlistSource `json:"allowlist_source"`
+}
+
+// handleBrowserSocket serves the browser extension WebSocket. While the
+// socket is open the reported tab context stays fresh, and a status message
+// is pushed after every tab update and focus change.
+func (s *Server) handleBrowserSocket(w http.ResponseWriter, r *http.Request) {
+	log := logger.WithComponent("browser")
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn().Err(err).Msg("Browser WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	send := func(msg BrowserStatusMessage) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(msg)
+	}
+
+	// windowClass is set by the first tab message and guarded by classMu
+	// since the focus goroutine reads it
+	var (
+		classMu     sync.Mutex
+		windowClass string
+	)
+	getClass := func() string {
+		classMu.Lock()
+		defer classMu.Unlock()
+		return windowClass
+	}
+	defer func() {
+		if class := getClass(); class != "" {
+			s.windowMgr.SetBrowserContextLive(class, false)
+		}
+	}()
+
+	// Push status whenever focus changes so the extension UI stays current
+	updates := s.windowMgr.Subscribe()
+	defer s.windowMgr.Unsubscribe(updates)
+	go func() {
+		for range updates {
+			class := getClass()
+			if class == "" {
+				continue
+			}
+			if err := send(s.browserStatus(class)); err != nil {
+				return
+			}
+		}
+	}()
+
+	log.Info().Str("remote", r.RemoteAddr).Msg("Browser extension connected")
+
+	for {
+		var msg BrowserMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Debug().Err(err).Msg("Browser WebSocket read failed")
+			}
+			log.Info().Str("window_class", getClass()).Msg("Browser extension disconnected")
+			return
+		}
+
+		switch msg.Type {
+		case BrowserMessageTab:
+			if msg.WindowClass == "" || msg.URL == "" {
+				log.Debug().Msg("Ignoring tab message without window_class or url")
+				continue
+			}
+
+			class := strings.ToLower(msg.WindowClass)
+			classMu.Lock()
+			previous := windowClass
+			windowClass = class
+			classMu.Unlock()
+			if previous != class {
+				if previous != "" {
+					s.windowMgr.SetBrowserContextLive(previous, false)
+				}
+				s.windowMgr.SetBrowserContextLive(class, true)
+			}
+
+			s.windowMgr.UpdateBrowserContext(class, msg.URL, msg.Title)
+		case BrowserMessagePing:
+		default:
+			log.Debug().Str("type", msg.Type).Msg("Ignoring unknown browser message")
+			continue
+		}
+
+		if class := getClass(); class != "" {
+			if err := send(s.browserStatus(class)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// browserStatus builds the status message for a browser window class
+func (s *Server) browserStatus(windowClass string) BrowserStatusMessage {
+	status := BrowserStatusMessage{
+		Type:        BrowserMessageStatus,
+		WindowClass: windowClass,
+		Blocked:     s.configMgr.IsBrowserBlocked(windowClass),
+	}
+
+	if ctx, ok := s.windowMgr.GetBrowserContext(windowClass); ok {
+		status.URL = ctx.URL
+		status.URLAllowlisted = s.windowMgr.IsURLAllowlisted(ctx.URL)
+		if parsed, err := url.Parse(ctx.URL); err == nil {
+			status.Domain = parsed.Hostname()
+		}
+	}
+
+	if current := s.windowMgr.GetCurrentWindow(); current != nil && strings.EqualFold(current.Class, windowClass) {
+		status.Focused = true
+		status.AllowlistSource = s.windowMgr.GetWindowAllowlistSource(current)
+		status.Allowlisted = status.AllowlistSource != config.AllowlistSourceNone
+	}
+
+	return status
+}
+
+// RunNativeHost runs a browser native messaging host on in/out (the
+// browser's pipes), relaying messages to the FocusStreamer WebSocket at
+// serverURL (e.g. http://127.0.0.1:8080). It returns when the browser
+// closes the pipe or the server connection drops.
+func RunNativeHost(in io.Reader, out io.Writer, serverURL string) error {
+	log := logger.WithComponent("native-host")
+
+	wsURL, err := browserSocketURL(serverURL)
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to FocusStreamer at %s: %w", wsURL, err)
+	}
+	defer conn.Close()
+
+	log.Info().Str("server", wsURL).Msg("Native messaging host connected")
+
+	// Server -> browser
+	serverErr := make(chan error, 1)
+	go func() {
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				serverErr <- fmt.Errorf("server connection closed: %w", err)
+				return
+			}
+			if err := writeNativeMessage(out, data); err != nil {
+				serverErr <- err
+				return
+			}
+		}
+	}()
+
+	// Browser -> server
+	browserErr := make(chan error, 1)
+	go func() {
+		for {
+			data, err := readNativeMessage(in)
+			if err != nil {
+				browserErr <- err
+				return
+			}
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				browserErr <- fmt.Errorf("failed to forward message to server: %w", err)
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
+		return err
+	case err := <-browserErr:
+		if err == io.EOF {
+			// Browser closed the port
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			return nil
+		}
+		return err
+	}
+}
+
+// browserSocketURL converts a server base URL to the browser WebSocket URL
+func browserSocketURL(serverURL string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid server URL %q", serverURL)
+	}
+
+	switch u.Scheme {
+	case "http", "ws", "":
+		u.Scheme = "ws"
+	case "https", "wss":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported server URL scheme %q", u.Scheme)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/api/browser/ws"
+	return u.String(), nil
+}
+
+// readNativeMessage reads one native messaging frame: a 32-bit length in
+// native byte order (little-endian on all supported platforms) followed by
+// that many bytes of UTF-8 JSON
+func readNativeMessage(r io.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if length > nativeMessageMaxSize {
+		return nil, fmt.Errorf("native message too large: %d bytes", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read native message: %w", err)
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("native message is not valid JSON")
+	}
+	return data, nil
+}
+
+// writeNativeMessage writes one native messaging frame
+func writeNativeMessage(w io.Writer, data []byte) error {
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(data))); err != nil {
+		return fmt.Errorf("failed to write native message length: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write native message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/websocket"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// eventJSON encodes events for ?format=json with the .proto field names,
+// like the rest of the API's snake_case JSON, and with false and zero
+// fields included rather than left out
+var eventJSON = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// handleEventsSocket sends each focus, standby, zoom, config and capture
+// error event as a focusstreamer.events.v1.Event (pkg/events/events.proto):
+// one binary message per serialized event, or one text message of its
+// protobuf JSON with ?format=json. Clients offering the
+// graphql-transport-ws subprotocol get GraphQL subscriptions instead.
+func (s *Server) handleEventsSocket(w http.ResponseWriter, r *http.Request) {
+	if wantsGraphQL(r) {
+		s.handleGraphQLSocket(w, r)
+		return
+	}
+	if s.events == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Events are not available")
+		return
+	}
+
+	messageType := websocket.BinaryMessage
+	marshal := proto.Marshal
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "proto":
+	case "json":
+		messageType = websocket.TextMessage
+		marshal = eventJSON.Marshal
+	default:
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "format must be proto or json")
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.WithComponent("api").Debug().Err(err).Msg("Events WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	events := s.events.Subscribe()
+	defer s.events.Unsubscribe(events)
+
+	// Nothing is read from the client; reading notices it going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case event := <-events:
+			data, err := marshal(event)
+			if err != nil {
+				logger.WithComponent("api").Debug().Err(err).Msg("Failed to encode event")
+				continue
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}
+}
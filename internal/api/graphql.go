@@ -0,0 +1,545 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// /api/graphql answers GraphQL queries over the window, config, overlay and
+// output managers, for dashboards that are GraphQL-first, and
+// /api/events/ws runs focus and standby subscriptions (graphql_socket.go).
+// Both are off unless api.graphql is set. The typed schema is
+// schema.graphql, with introspection; resolvers read the same managers as
+// the REST handlers. See docs/graphql-api.md.
+
+//go:embed schema.graphql
+var graphqlSchemaSDL string
+
+// maxGraphQLRequestSize bounds POST /api/graphql bodies
+const maxGraphQLRequestSize = 64 << 10
+
+// graphqlMaxDepth bounds how deeply a query may nest selections; the
+// schema itself is five levels deep, introspection a few more
+const graphqlMaxDepth = 12
+
+// graphqlRequest is a POST /api/graphql body, and the payload of a
+// subscribe message on /api/events/ws
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlError is a resolver error with a REST error code in
+// extensions.code
+type graphqlError struct {
+	code    string
+	message string
+}
+
+func (e *graphqlError) Error() string {
+	return e.message
+}
+
+// Extensions adds the code to the error in the response
+func (e *graphqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+// newGraphQLSchema parses schema.graphql over the server's managers
+func newGraphQLSchema(s *Server) *graphql.Schema {
+	return graphql.MustParseSchema(graphqlSchemaSDL, &graphqlRoot{s: s},
+		graphql.UseFieldResolvers(),
+		graphql.UseStringDescriptions(),
+		graphql.MaxDepth(graphqlMaxDepth),
+	)
+}
+
+// handleGraphQL runs a GraphQL query, e.g.
+// {"query": "{ currentWindow { class title } stream { standby onAir } }"}
+func (s *Server) handleGraphQL(w http.ResponseWriter, r *http.Request) {
+	if !s.configMgr.Get().API.GraphQL {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "GraphQL is off (api.graphql)")
+		return
+	}
+
+	var req graphqlRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+	if strings.TrimSpace(req.Query) == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "query is required")
+		return
+	}
+
+	response := s.graphql.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+	status := http.StatusOK
+	if response.Data == nil {
+		// Nothing ran: the query didn't parse or validate
+		status = http.StatusBadRequest
+		addGraphQLCodes(response.Errors, codeInvalidRequest)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// addGraphQLCodes sets extensions.code on errors that have none, such as
+// the library's syntax and validation errors
+func addGraphQLCodes(errs []*gqlerrors.QueryError, code string) {
+	for _, err := range errs {
+		if err.Extensions == nil {
+			err.Extensions = map[string]interface{}{"code": code}
+		}
+	}
+}
+
+// graphqlRoot resolves the Query and Subscription fields
+type graphqlRoot struct {
+	s *Server
+}
+
+func (r *graphqlRoot) CurrentWindow() *graphqlWindow {
+	info := r.s.windowMgr.GetCurrentWindow()
+	if info == nil {
+		return nil
+	}
+	return newGraphQLWindow(info)
+}
+
+func (r *graphqlRoot) Windows(args struct{ Query *string }) ([]*graphqlWindowMatch, error) {
+	var query string
+	if args.Query != nil {
+		query = *args.Query
+	}
+	matches, err := r.s.windowMgr.SearchWindows(query)
+	if err != nil {
+		return nil, &graphqlError{codeInternal, err.Error()}
+	}
+	windows := make([]*graphqlWindowMatch, 0, len(matches))
+	for i := range matches {
+		match := &matches[i]
+		windows = append(windows, &graphqlWindowMatch{
+			Window:           newGraphQLWindow(&match.WindowInfo),
+			Score:            match.Score,
+			Capturable:       match.Capturable,
+			Minimized:        match.Minimized,
+			OnCurrentDesktop: match.OnCurrentDesktop,
+			Allowlisted:      match.Allowlisted,
+			AllowlistSource:  graphqlEnum(string(match.AllowlistSource)),
+			Shared:           match.Shared,
+			SelfExcluded:     match.SelfExcluded,
+		})
+	}
+	return windows, nil
+}
+
+func (r *graphqlRoot) Applications(args struct{ Allowlisted *bool }) ([]*graphqlApplication, error) {
+	apps, err := r.s.windowMgr.GetApplications()
+	if err != nil {
+		return nil, &graphqlError{codeInternal, err.Error()}
+	}
+	matching := make([]*graphqlApplication, 0, len(apps))
+	for _, app := range apps {
+		if args.Allowlisted != nil && app.Allowlisted != *args.Allowlisted {
+			continue
+		}
+		desktops := make([]int32, len(app.Desktops))
+		for i, desktop := range app.Desktops {
+			desktops[i] = int32(desktop)
+		}
+		matching = append(matching, &graphqlApplication{
+			ID:              graphql.ID(app.ID),
+			Name:            app.Name,
+			WindowClass:     app.WindowClass,
+			PID:             int32(app.PID),
+			Allowlisted:     app.Allowlisted,
+			AllowlistSource: graphqlEnum(string(app.AllowlistSource)),
+			Windows:         int32(app.Windows),
+			Desktops:        desktops,
+		})
+	}
+	return matching, nil
+}
+
+func (r *graphqlRoot) Config() *graphqlConfig {
+	cfg := r.s.configMgr.Get()
+	result := &graphqlConfig{
+		Version:    int32(cfg.Version),
+		Backend:    cfg.Backend,
+		ServerPort: int32(cfg.ServerPort),
+		LogLevel:   cfg.LogLevel,
+		VirtualDisplay: graphqlVirtualDisplay{
+			Enabled:   cfg.VirtualDisplay.Enabled,
+			Width:     int32(cfg.VirtualDisplay.Width),
+			Height:    int32(cfg.VirtualDisplay.Height),
+			RefreshHz: int32(cfg.VirtualDisplay.RefreshHz),
+			FPS:       int32(cfg.VirtualDisplay.FPS),
+		},
+		ActiveProfileID: graphql.ID(cfg.ActiveProfileID),
+		Profiles:        make([]*graphqlProfile, 0, len(cfg.Profiles)),
+	}
+	for i := range cfg.Profiles {
+		profile := newGraphQLProfile(&cfg.Profiles[i])
+		result.Profiles = append(result.Profiles, profile)
+		if cfg.Profiles[i].ID == cfg.ActiveProfileID {
+			result.ActiveProfile = profile
+		}
+	}
+	return result
+}
+
+func (r *graphqlRoot) Stream() *graphqlStream {
+	health := r.s.windowMgr.GetHealthStatus()
+	reason := r.s.windowMgr.GetStandbyReason()
+	zoom := r.s.windowMgr.GetZoomState()
+	return &graphqlStream{
+		Standby:         reason != window.StandbyNone,
+		StandbyReason:   graphqlEnum(string(reason)),
+		Caption:         reason.Caption(),
+		ForcedStandby:   r.s.windowMgr.GetForceStandby(),
+		DesktopBlocked:  r.s.windowMgr.IsDesktopBlocked(),
+		Panicked:        r.s.windowMgr.IsPanicked(),
+		AllowlistBypass: r.s.windowMgr.GetAllowlistBypass(),
+		OnAir:           health.OnAir,
+		Clients:         int32(health.Clients),
+		Zoom:            graphqlZoom{Scale: zoom.Scale, OffsetX: zoom.OffsetX, OffsetY: zoom.OffsetY},
+	}
+}
+
+func (r *graphqlRoot) Overlay() *graphqlOverlay {
+	widgets := r.s.overlayMgr.GetAllWidgets()
+	overlay := &graphqlOverlay{
+		Enabled: r.s.overlayMgr.IsEnabled(),
+		Widgets: make([]*graphqlWidget, 0, len(widgets)),
+	}
+	for _, widget := range widgets {
+		overlay.Widgets = append(overlay.Widgets, &graphqlWidget{
+			ID:      graphql.ID(widget.ID()),
+			Type:    widget.Type(),
+			Enabled: widget.IsEnabled(),
+			Config:  graphqlJSON{widget.GetConfig()},
+		})
+	}
+	return overlay
+}
+
+func (r *graphqlRoot) Outputs() (*[]*graphqlOutput, error) {
+	if r.s.outputs == nil {
+		return nil, &graphqlError{codeUnavailable, "Output multiplexer not available"}
+	}
+	sinks := r.s.outputs.Sinks()
+	outputs := make([]*graphqlOutput, 0, len(sinks))
+	for _, sink := range sinks {
+		outputs = append(outputs, &graphqlOutput{
+			Name:      sink.Name,
+			Type:      sink.Type,
+			Enabled:   sink.Enabled,
+			Running:   sink.Running,
+			MaxFPS:    int32(sink.MaxFPS),
+			Frames:    float64(sink.Frames),
+			Throttled: float64(sink.Throttled),
+			Dropped:   float64(sink.Dropped),
+			Errors:    float64(sink.Errors),
+			LastError: sink.LastError,
+		})
+	}
+	return &outputs, nil
+}
+
+func (r *graphqlRoot) FocusChanged(ctx context.Context) (<-chan *graphqlFocusChanged, error) {
+	return subscribeGraphQL(ctx, r.s, func(event *events.Event) (*graphqlFocusChanged, bool) {
+		focus := event.GetFocusChanged()
+		if focus == nil {
+			return nil, false
+		}
+		return &graphqlFocusChanged{
+			Time:   graphql.Time{Time: event.GetTime().AsTime()},
+			Window: newGraphQLEventWindow(focus.GetWindow()),
+		}, true
+	})
+}
+
+func (r *graphqlRoot) StandbyChanged(ctx context.Context) (<-chan *graphqlStandbyChanged, error) {
+	return subscribeGraphQL(ctx, r.s, func(event *events.Event) (*graphqlStandbyChanged, bool) {
+		standby := event.GetStandbyChanged()
+		if standby == nil {
+			return nil, false
+		}
+		return &graphqlStandbyChanged{
+			Time:         graphql.Time{Time: event.GetTime().AsTime()},
+			Standby:      standby.GetStandby(),
+			Reason:       strings.TrimPrefix(standby.GetReason().String(), "STANDBY_REASON_"),
+			SharedWindow: newGraphQLEventWindow(standby.GetSharedWindow()),
+		}, true
+	})
+}
+
+// subscribeGraphQL passes the events convert accepts, converted, to a
+// subscription until ctx is done
+func subscribeGraphQL[T any](ctx context.Context, s *Server, convert func(*events.Event) (T, bool)) (<-chan T, error) {
+	if s.events == nil {
+		return nil, &graphqlError{codeUnavailable, "Events are not available"}
+	}
+
+	source := s.events.Subscribe()
+	results := make(chan T)
+	go func() {
+		defer close(results)
+		defer s.events.Unsubscribe(source)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-source:
+				result, ok := convert(event)
+				if !ok {
+					continue
+				}
+				select {
+				case results <- result:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return results, nil
+}
+
+// graphqlEnum returns the enum value of a REST string value: upper case,
+// NONE when empty
+func graphqlEnum(value string) string {
+	if value == "" {
+		return "NONE"
+	}
+	return strings.ToUpper(value)
+}
+
+// graphqlJSON is the JSON scalar
+type graphqlJSON struct {
+	value interface{}
+}
+
+func (graphqlJSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *graphqlJSON) UnmarshalGraphQL(input interface{}) error {
+	j.value = input
+	return nil
+}
+
+func (j graphqlJSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.value)
+}
+
+// The types below are the schema's object types, resolved field by field
+// by name
+
+type graphqlWindow struct {
+	ID            graphql.ID
+	Class         string
+	Title         string
+	PID           int32
+	Focused       bool
+	Geometry      graphqlGeometry
+	NativeWayland bool
+	Desktop       int32
+}
+
+func newGraphQLWindow(info *config.WindowInfo) *graphqlWindow {
+	return &graphqlWindow{
+		ID:      graphql.ID(strconv.FormatUint(uint64(info.ID), 10)),
+		Class:   info.Class,
+		Title:   info.Title,
+		PID:     int32(info.PID),
+		Focused: info.Focused,
+		Geometry: graphqlGeometry{
+			X:      int32(info.Geometry.X),
+			Y:      int32(info.Geometry.Y),
+			Width:  int32(info.Geometry.Width),
+			Height: int32(info.Geometry.Height),
+		},
+		NativeWayland: info.IsNativeWayland,
+		Desktop:       int32(info.Desktop),
+	}
+}
+
+type graphqlGeometry struct {
+	X, Y, Width, Height int32
+}
+
+type graphqlWindowMatch struct {
+	Window           *graphqlWindow
+	Score            float64
+	Capturable       bool
+	Minimized        bool
+	OnCurrentDesktop bool
+	Allowlisted      bool
+	AllowlistSource  string
+	Shared           bool
+	SelfExcluded     bool
+}
+
+type graphqlApplication struct {
+	ID              graphql.ID
+	Name            string
+	WindowClass     string
+	PID             int32
+	Allowlisted     bool
+	AllowlistSource string
+	Windows         int32
+	Desktops        []int32
+}
+
+type graphqlConfig struct {
+	Version         int32
+	Backend         string
+	ServerPort      int32
+	LogLevel        string
+	VirtualDisplay  graphqlVirtualDisplay
+	ActiveProfileID graphql.ID
+	ActiveProfile   *graphqlProfile
+	Profiles        []*graphqlProfile
+}
+
+type graphqlVirtualDisplay struct {
+	Enabled                       bool
+	Width, Height, RefreshHz, FPS int32
+}
+
+type graphqlProfile struct {
+	ID                     graphql.ID
+	Name                   string
+	AllowlistedApps        []string
+	AllowlistPatterns      []string
+	AllowlistTitlePatterns []string
+	AllowlistURLRules      []*graphqlURLRule
+	BrowserWindowClasses   []string
+	BrowserBlockedClasses  []string
+	PlaceholderImagePaths  []string
+}
+
+func newGraphQLProfile(profile *config.Profile) *graphqlProfile {
+	rules := make([]*graphqlURLRule, 0, len(profile.AllowlistURLRules))
+	for _, rule := range profile.AllowlistURLRules {
+		rules = append(rules, &graphqlURLRule{
+			ID:          graphql.ID(rule.ID),
+			Type:        string(rule.Type),
+			Pattern:     rule.Pattern,
+			Description: rule.Description,
+		})
+	}
+	return &graphqlProfile{
+		ID:                     graphql.ID(profile.ID),
+		Name:                   profile.Name,
+		AllowlistedApps:        nonNilStrings(profile.AllowlistedApps),
+		AllowlistPatterns:      nonNilStrings(profile.AllowlistPatterns),
+		AllowlistTitlePatterns: nonNilStrings(profile.AllowlistTitlePatterns),
+		AllowlistURLRules:      rules,
+		BrowserWindowClasses:   nonNilStrings(profile.BrowserWindowClasses),
+		BrowserBlockedClasses:  nonNilStrings(profile.BrowserBlockedClasses),
+		PlaceholderImagePaths:  nonNilStrings(profile.PlaceholderImagePaths),
+	}
+}
+
+// nonNilStrings returns an empty list for nil, which non-null list fields
+// can't be
+func nonNilStrings(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
+
+type graphqlURLRule struct {
+	ID          graphql.ID
+	Type        string
+	Pattern     string
+	Description string
+}
+
+type graphqlStream struct {
+	Standby         bool
+	StandbyReason   string
+	Caption         string
+	ForcedStandby   bool
+	DesktopBlocked  bool
+	Panicked        bool
+	AllowlistBypass bool
+	OnAir           bool
+	Clients         int32
+	Zoom            graphqlZoom
+}
+
+type graphqlZoom struct {
+	Scale, OffsetX, OffsetY float64
+}
+
+type graphqlOverlay struct {
+	Enabled bool
+	Widgets []*graphqlWidget
+}
+
+type graphqlWidget struct {
+	ID      graphql.ID
+	Type    string
+	Enabled bool
+	Config  graphqlJSON
+}
+
+type graphqlOutput struct {
+	Name      string
+	Type      string
+	Enabled   bool
+	Running   bool
+	MaxFPS    int32
+	Frames    float64
+	Throttled float64
+	Dropped   float64
+	Errors    float64
+	LastError string
+}
+
+type graphqlEventWindow struct {
+	ID    graphql.ID
+	Class string
+	Title string
+	PID   int32
+}
+
+func newGraphQLEventWindow(w *events.Window) *graphqlEventWindow {
+	if w == nil {
+		return nil
+	}
+	return &graphqlEventWindow{
+		ID:    graphql.ID(strconv.FormatUint(uint64(w.GetId()), 10)),
+		Class: w.GetClass(),
+		Title: w.GetTitle(),
+		PID:   w.GetPid(),
+	}
+}
+
+type graphqlFocusChanged struct {
+	Time   graphql.Time
+	Window *graphqlEventWindow
+}
+
+type graphqlStandbyChanged struct {
+	Time         graphql.Time
+	Standby      bool
+	Reason       string
+	SharedWindow *graphqlEventWindow
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// graphqlSubprotocol selects GraphQL over /api/events/ws, in the protocol
+// of the graphql-ws library (github.com/enisdenjo/graphql-ws), which Apollo
+// Client and urql speak
+const graphqlSubprotocol = "graphql-transport-ws"
+
+// graphqlInitTimeout is how long a client has to send connection_init
+const graphqlInitTimeout = 10 * time.Second
+
+// Close codes of graphql-transport-ws
+const (
+	graphqlCloseBadRequest   = 4400
+	graphqlCloseUnauthorized = 4401
+	graphqlCloseInitTimeout  = 4408
+	graphqlCloseDuplicateID  = 4409
+	graphqlCloseTooManyInits = 4429
+)
+
+// graphqlMessage is a graphql-transport-ws message
+type graphqlMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wantsGraphQL reports whether a WebSocket request offers the GraphQL
+// subprotocol
+func wantsGraphQL(r *http.Request) bool {
+	return slices.Contains(websocket.Subprotocols(r), graphqlSubprotocol)
+}
+
+// handleGraphQLSocket runs GraphQL operations, focusChanged and
+// standbyChanged subscriptions and also queries, over a graphql-transport-ws
+// WebSocket on /api/events/ws
+func (s *Server) handleGraphQLSocket(w http.ResponseWriter, r *http.Request) {
+	if !s.configMgr.Get().API.GraphQL {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "GraphQL is off (api.graphql)")
+		return
+	}
+
+	header := http.Header{"Sec-Websocket-Protocol": {graphqlSubprotocol}}
+	conn, err := s.upgrader.Upgrade(w, r, header)
+	if err != nil {
+		logger.WithComponent("api").Debug().Err(err).Msg("GraphQL WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	socket := &graphqlSocket{conn: conn, schema: s.graphql, operations: make(map[string]context.CancelFunc)}
+	socket.serve()
+}
+
+// graphqlSocket is one graphql-transport-ws connection
+type graphqlSocket struct {
+	conn    *websocket.Conn
+	schema  *graphql.Schema
+	writeMu sync.Mutex
+
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc // Running operations by ID
+	wg         sync.WaitGroup
+}
+
+// serve reads messages until the client goes away or breaks the protocol,
+// then stops the operations still running
+func (c *graphqlSocket) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		c.wg.Wait()
+	}()
+
+	acked := false
+	c.conn.SetReadDeadline(time.Now().Add(graphqlInitTimeout))
+	for {
+		var msg graphqlMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			var netErr net.Error
+			switch {
+			case errors.As(err, &netErr) && netErr.Timeout():
+				c.close(graphqlCloseInitTimeout, "Connection initialisation timeout")
+			case errors.As(err, new(*json.SyntaxError)), errors.As(err, new(*json.UnmarshalTypeError)):
+				c.close(graphqlCloseBadRequest, "Invalid message")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case "connection_init":
+			if acked {
+				c.close(graphqlCloseTooManyInits, "Too many initialisation requests")
+				return
+			}
+			acked = true
+			c.conn.SetReadDeadline(time.Time{})
+			c.send(graphqlMessage{Type: "connection_ack"})
+		case "ping":
+			c.send(graphqlMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !acked {
+				c.close(graphqlCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var req graphqlRequest
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(graphqlCloseBadRequest, "Invalid subscribe message")
+				return
+			}
+			if !c.start(ctx, msg.ID, req) {
+				c.close(graphqlCloseDuplicateID, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+		case "complete":
+			c.mu.Lock()
+			if stop, ok := c.operations[msg.ID]; ok {
+				stop()
+				delete(c.operations, msg.ID)
+			}
+			c.mu.Unlock()
+		default:
+			c.close(graphqlCloseBadRequest, "Unknown message type")
+			return
+		}
+	}
+}
+
+// start runs an operation until it ends or the client completes it. It
+// reports false if an operation with the ID is already running.
+func (c *graphqlSocket) start(ctx context.Context, id string, req graphqlRequest) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.operations[id]; ok {
+		return false
+	}
+	ctx, stop := context.WithCancel(ctx)
+	c.operations[id] = stop
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.run(ctx, id, req)
+
+		c.mu.Lock()
+		if ctx.Err() == nil {
+			delete(c.operations, id)
+		}
+		c.mu.Unlock()
+		stop()
+	}()
+	return true
+}
+
+// run sends an operation's results as next messages, then complete; an
+// operation that couldn't start gets an error message instead
+func (c *graphqlSocket) run(ctx context.Context, id string, req graphqlRequest) {
+	responses, err := c.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+	if err != nil {
+		c.sendErrors(id, []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)})
+		return
+	}
+
+	failed := false
+	// Read to the end: the schema's sender blocks until each is taken
+	for result := range responses {
+		response := result.(*graphql.Response)
+		if failed {
+			continue
+		}
+		if response.Data == nil && len(response.Errors) > 0 {
+			failed = true
+			c.sendErrors(id, response.Errors)
+			continue
+		}
+		payload, err := json.Marshal(response)
+		if err != nil {
+			continue
+		}
+		c.send(graphqlMessage{ID: id, Type: "next", Payload: payload})
+	}
+
+	// Nothing more is sent for operations the client completed
+	if !failed && ctx.Err() == nil {
+		c.send(graphqlMessage{ID: id, Type: "complete"})
+	}
+}
+
+// sendErrors ends an operation with an error message
+func (c *graphqlSocket) sendErrors(id string, errs []*gqlerrors.QueryError) {
+	addGraphQLCodes(errs, codeInvalidRequest)
+	payload, err := json.Marshal(errs)
+	if err != nil {
+		return
+	}
+	c.send(graphqlMessage{ID: id, Type: "error", Payload: payload})
+}
+
+// send writes a message; errors surface as the next read failing
+func (c *graphqlSocket) send(msg graphqlMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteJSON(msg)
+}
+
+// close ends the connection with a graphql-transport-ws close code
+func (c *graphqlSocket) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
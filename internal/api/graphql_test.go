@@ -0,0 +1,300 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/eventhub"
+	"github.com/bryanchriswhite/FocusStreamer/internal/overlay"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
+	"github.com/gorilla/websocket"
+)
+
+// newGraphQLTestServer returns a server over the synthetic window backend,
+// with api.graphql set as given
+func newGraphQLTestServer(t *testing.T, enabled bool) *Server {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	configMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := *configMgr.Get()
+	cfg.API.GraphQL = enabled
+	if err := configMgr.Update(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	windowMgr, err := window.NewManagerWithBackend(configMgr, window.BackendSynthetic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewServer(windowMgr, configMgr, nil, nil, overlay.NewManager())
+}
+
+// postGraphQL sends a request body to /api/graphql and decodes the response
+func postGraphQL(t *testing.T, s *Server, body string) (int, map[string]interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(body)))
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, response
+}
+
+func TestHandleGraphQL(t *testing.T) {
+	s := newGraphQLTestServer(t, true)
+	windows, err := s.windowMgr.SearchWindows("")
+	if err != nil || len(windows) == 0 {
+		t.Fatalf("synthetic backend has no windows: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		want       string // Expected response, compared as JSON
+	}{
+		{
+			name:       "selected fields",
+			body:       `{"query": "{ stream { zoom { scale } panicked forcedStandby } }"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"data": {"stream": {"zoom": {"scale": 1}, "panicked": false, "forcedStandby": false}}}`,
+		},
+		{
+			name:       "aliases and variables",
+			body:       `{"query": "query($q: String) { first: windows(query: $q) { window { class } } }", "variables": {"q": "` + windows[0].Title + `"}}`,
+			wantStatus: http.StatusOK,
+			want:       `{"data": {"first": [{"window": {"class": "` + windows[0].Class + `"}}]}}`,
+		},
+		{
+			name:       "fragments",
+			body:       `{"query": "{ config { ...Port } } fragment Port on Config { serverPort }"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"data": {"config": {"serverPort": 8080}}}`,
+		},
+		{
+			name:       "introspection",
+			body:       `{"query": "{ __type(name: \"Zoom\") { kind fields { name } } }"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"data": {"__type": {"kind": "OBJECT", "fields": [{"name": "scale"}, {"name": "offsetX"}, {"name": "offsetY"}]}}}`,
+		},
+		{
+			name:       "field error leaves the others",
+			body:       `{"query": "{ outputs { name } overlay { enabled widgets { id } } }"}`,
+			wantStatus: http.StatusOK,
+			want:       `{"errors": [{"message": "Output multiplexer not available", "path": ["outputs"], "extensions": {"code": "unavailable"}}], "data": {"outputs": null, "overlay": {"enabled": true, "widgets": []}}}`,
+		},
+		{
+			name:       "REST casing",
+			body:       `{"query": "{ stream { on_air } }"}`,
+			wantStatus: http.StatusBadRequest,
+			want:       `{"errors": [{"message": "Cannot query field \"on_air\" on type \"Stream\". Did you mean \"onAir\"?", "locations": [{"line": 1, "column": 12}], "extensions": {"code": "invalid_request"}}]}`,
+		},
+		{
+			name:       "unknown argument",
+			body:       `{"query": "{ windows(class: \"firefox\") { score } }"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "mutation",
+			body:       `{"query": "mutation { panic }"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty",
+			body:       `{"query": " "}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, got := postGraphQL(t, s, tt.body)
+			if status != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %v", status, tt.wantStatus, got)
+			}
+			if tt.want == "" {
+				return
+			}
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("response = %s\nwant %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestHandleGraphQLOff(t *testing.T) {
+	s := newGraphQLTestServer(t, false)
+	rec := httptest.NewRecorder()
+	s.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/graphql", strings.NewReader(`{"query": "{ config { version } }"}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+// dialGraphQL opens /api/events/ws with the GraphQL subprotocol
+func dialGraphQL(t *testing.T, s *Server) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+	server := httptest.NewServer(s.handler())
+	t.Cleanup(server.Close)
+	dialer := websocket.Dialer{Subprotocols: []string{graphqlSubprotocol}}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/events/ws", nil)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	}
+	return conn, resp, err
+}
+
+// readGraphQL reads the next message
+func readGraphQL(t *testing.T, conn *websocket.Conn) graphqlMessage {
+	t.Helper()
+	var msg graphqlMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestGraphQLSocket(t *testing.T) {
+	s := newGraphQLTestServer(t, true)
+	hub := eventhub.New()
+	s.SetEvents(hub)
+
+	conn, _, err := dialGraphQL(t, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.Subprotocol() != graphqlSubprotocol {
+		t.Fatalf("subprotocol = %q", conn.Subprotocol())
+	}
+	conn.WriteJSON(graphqlMessage{Type: "connection_init"})
+	if msg := readGraphQL(t, conn); msg.Type != "connection_ack" {
+		t.Fatalf("got %q, want connection_ack", msg.Type)
+	}
+
+	// A query answers once and completes
+	conn.WriteJSON(graphqlMessage{ID: "q", Type: "subscribe", Payload: json.RawMessage(`{"query": "{ stream { panicked } }"}`)})
+	if msg := readGraphQL(t, conn); msg.Type != "next" || msg.ID != "q" || string(msg.Payload) != `{"data":{"stream":{"panicked":false}}}` {
+		t.Fatalf("got %s %s %s", msg.Type, msg.ID, msg.Payload)
+	}
+	if msg := readGraphQL(t, conn); msg.Type != "complete" || msg.ID != "q" {
+		t.Fatalf("got %s %s, want complete", msg.Type, msg.ID)
+	}
+
+	// Subscriptions pass on matching events, and only those
+	conn.WriteJSON(graphqlMessage{ID: "s", Type: "subscribe", Payload: json.RawMessage(`{"query": "subscription { focusChanged { window { class } } }"}`)})
+	stop := publishUntilStopped(hub,
+		&events.Event{Payload: &events.Event_ZoomChanged{ZoomChanged: &events.ZoomChanged{Scale: 2}}},
+		&events.Event{Payload: &events.Event_FocusChanged{FocusChanged: &events.FocusChanged{Window: &events.Window{Class: "firefox"}}}},
+	)
+	msg := readGraphQL(t, conn)
+	stop()
+	if msg.Type != "next" || msg.ID != "s" || string(msg.Payload) != `{"data":{"focusChanged":{"window":{"class":"firefox"}}}}` {
+		t.Fatalf("got %s %s %s", msg.Type, msg.ID, msg.Payload)
+	}
+
+	// An invalid operation gets an error message
+	conn.WriteJSON(graphqlMessage{ID: "bad", Type: "subscribe", Payload: json.RawMessage(`{"query": "subscription { zoomChanged { scale } }"}`)})
+	if msg := readOperation(t, conn, "bad"); msg.Type != "error" || !strings.Contains(string(msg.Payload), "zoomChanged") {
+		t.Fatalf("got %s %s, want error", msg.Type, msg.Payload)
+	}
+
+	// A completed ID can be used again
+	conn.WriteJSON(graphqlMessage{ID: "s", Type: "complete"})
+	standby := graphqlMessage{ID: "s", Type: "subscribe", Payload: json.RawMessage(`{"query": "subscription { standbyChanged { reason } }"}`)}
+	conn.WriteJSON(standby)
+	stop = publishUntilStopped(hub, &events.Event{Payload: &events.Event_StandbyChanged{StandbyChanged: &events.StandbyChanged{
+		Standby: true,
+		Reason:  events.StandbyReason_STANDBY_REASON_NO_WINDOW,
+	}}})
+	for {
+		msg := readOperation(t, conn, "s")
+		if strings.Contains(string(msg.Payload), "focusChanged") {
+			continue // Sent before the first subscription was completed
+		}
+		if msg.Type != "next" || string(msg.Payload) != `{"data":{"standbyChanged":{"reason":"NO_WINDOW"}}}` {
+			t.Fatalf("got %s %s", msg.Type, msg.Payload)
+		}
+		break
+	}
+	stop()
+
+	// A running one can't
+	conn.WriteJSON(standby)
+	for {
+		_, _, err := conn.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, graphqlCloseDuplicateID) {
+			t.Fatalf("error = %v, want close %d", err, graphqlCloseDuplicateID)
+		}
+		break
+	}
+}
+
+// publishUntilStopped publishes the events every 10ms, since a subscription
+// joins the hub some time after the subscribe message
+func publishUntilStopped(hub *eventhub.Hub, published ...*events.Event) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				for _, event := range published {
+					hub.Publish(event)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// readOperation reads the next message for an operation ID, skipping
+// others
+func readOperation(t *testing.T, conn *websocket.Conn, id string) graphqlMessage {
+	t.Helper()
+	for {
+		if msg := readGraphQL(t, conn); msg.ID == id {
+			return msg
+		}
+	}
+}
+
+func TestGraphQLSocketNeedsInit(t *testing.T) {
+	s := newGraphQLTestServer(t, true)
+	conn, _, err := dialGraphQL(t, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.WriteJSON(graphqlMessage{ID: "1", Type: "subscribe", Payload: json.RawMessage(`{"query": "{ config { version } }"}`)})
+	if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, graphqlCloseUnauthorized) {
+		t.Errorf("error = %v, want close %d", err, graphqlCloseUnauthorized)
+	}
+}
+
+func TestGraphQLSocketOff(t *testing.T) {
+	s := newGraphQLTestServer(t, false)
+	if _, resp, err := dialGraphQL(t, s); err == nil || resp == nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("dial error %v, want 503", err)
+	}
+}
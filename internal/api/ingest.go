@@ -0,0 +1,199 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/approval"
+	"github.com/bryanchriswhite/FocusStreamer/internal/ingest"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// ingestPathPrefix is where other programs push frames; requests from
+	// other machines need an ingest token
+	ingestPathPrefix = "/api/ingest/"
+
+	// maxIngestFrameBytes bounds one pushed frame, enough for raw 4K RGBA
+	maxIngestFrameBytes = 64 << 20
+)
+
+// ingestFormat returns the frame format and size of a push request: the
+// format, width and height query parameters, else the Content-Type
+// (image/jpeg, image/png, or application/octet-stream for raw RGBA)
+func ingestFormat(r *http.Request, fallback string) (format string, width, height int, err error) {
+	query := r.URL.Query()
+	format = query.Get("format")
+	if format == "" {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch mediaType {
+		case "image/jpeg":
+			format = ingest.FormatJPEG
+		case "image/png":
+			format = ingest.FormatPNG
+		case "application/octet-stream":
+			format = ingest.FormatRGBA
+		default:
+			format = fallback
+		}
+	}
+	if format == ingest.FormatRGBA {
+		width, err = strconv.Atoi(query.Get("width"))
+		if err == nil {
+			height, err = strconv.Atoi(query.Get("height"))
+		}
+		if err != nil {
+			return "", 0, 0, fmt.Errorf("raw RGBA frames need width and height parameters")
+		}
+	}
+	return format, width, height, nil
+}
+
+// handleGetIngest lists the external sources and the one streamed
+func (s *Server) handleGetIngest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sources":  s.windowMgr.ExternalSources().Status(),
+		"selected": s.windowMgr.SelectedExternalSource(),
+	})
+}
+
+// handleIngestFrame takes one frame of an external source
+func (s *Server) handleIngestFrame(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !ingest.ValidName(name) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid source name (use 1-64 letters, digits, '.', '-' and '_')")
+		return
+	}
+
+	format, width, height, err := ingestFormat(r, "")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if format == "" {
+		writeError(w, http.StatusUnsupportedMediaType, codeUnsupportedMedia, "Unknown frame format: set Content-Type to image/jpeg, image/png or application/octet-stream (raw RGBA)")
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestFrameBytes))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, codeTooLarge, "Failed to read frame: "+err.Error())
+		return
+	}
+	img, err := ingest.Decode(format, data, width, height)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if err := s.windowMgr.ExternalSources().Push(name, img); err != nil {
+		writeError(w, http.StatusTooManyRequests, codeRateLimited, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteIngest forgets an external source
+func (s *Server) handleDeleteIngest(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !s.windowMgr.ExternalSources().Remove(name) {
+		writeError(w, http.StatusNotFound, codeNotFound, "Source not found")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleIngestSocket takes a stream of frames of an external source, one
+// binary message each, in the format given by the query (JPEG by default).
+// A frame that can't be used is answered with a text message holding the
+// error; the connection stays open.
+func (s *Server) handleIngestSocket(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !ingest.ValidName(name) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid source name (use 1-64 letters, digits, '.', '-' and '_')")
+		return
+	}
+	format, width, height, err := ingestFormat(r, ingest.FormatJPEG)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	log := logger.WithComponent("ingest")
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Warn().Err(err).Msg("Ingest WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(maxIngestFrameBytes)
+
+	log.Info().Str("source", name).Str("format", format).Msg("External source connected")
+	defer log.Info().Str("source", name).Msg("External source disconnected")
+
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+
+		img, err := ingest.Decode(format, data, width, height)
+		if err == nil {
+			err = s.windowMgr.ExternalSources().Push(name, img)
+		}
+		if err != nil {
+			if writeErr := conn.WriteJSON(map[string]string{"error": err.Error()}); writeErr != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleGetStreamSource reports the external source streamed in place of
+// the focused window, empty if none
+func (s *Server) handleGetStreamSource(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"external": s.windowMgr.SelectedExternalSource(),
+	})
+}
+
+// handleSetStreamSource streams an external source, e.g.
+// {"external": "phone"}, or the focused window again with {"external": ""}.
+// While approval is on, streaming a source waits for approval.
+func (s *Server) handleSetStreamSource(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		External string `json:"external"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+	if req.External != "" && s.approvals.Enabled() {
+		if !ingest.ValidName(req.External) {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("invalid source name %q", req.External))
+			return
+		}
+		s.submitForApproval(w, approval.Entry{Kind: approval.KindSource, Value: req.External})
+		return
+	}
+	if err := s.windowMgr.SelectExternalSource(req.External); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"external": req.External,
+	})
+}
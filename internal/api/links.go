@@ -0,0 +1,260 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/mux"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// shortLinkPrefix is where short links to tokenized stream pages live.
+	// Not covered by stream_access: the page a link leads to is.
+	shortLinkPrefix = "/s/"
+
+	// shortCodeBytes is the randomness in a short link code, 8 characters
+	// of lowercase base32
+	shortCodeBytes = 5
+
+	// maxShortLinks bounds the short links kept; the oldest is dropped
+	maxShortLinks = 256
+
+	// QR code size bounds, in pixels
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
+)
+
+type shortLink struct {
+	target  string
+	expires time.Time
+	created time.Time
+}
+
+// shortLinks maps short codes to stream page links with a link token,
+// which are too long to type or to scan reliably. They are kept in memory,
+// so they end with the server.
+type shortLinks struct {
+	mu    sync.Mutex
+	links map[string]shortLink
+}
+
+func newShortLinks() *shortLinks {
+	return &shortLinks{links: make(map[string]shortLink)}
+}
+
+// Add returns a new code for target, valid until expires
+func (l *shortLinks) Add(target string, expires time.Time) (string, error) {
+	buf := make([]byte, shortCodeBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate short link: %w", err)
+	}
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	var oldest string
+	for c, link := range l.links {
+		if now.After(link.expires) {
+			delete(l.links, c)
+		} else if oldest == "" || link.created.Before(l.links[oldest].created) {
+			oldest = c
+		}
+	}
+	if len(l.links) >= maxShortLinks {
+		delete(l.links, oldest)
+	}
+	l.links[code] = shortLink{target: target, expires: expires, created: now}
+	return code, nil
+}
+
+// Resolve returns the target of a code that hasn't expired
+func (l *shortLinks) Resolve(code string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	link, ok := l.links[code]
+	if !ok || time.Now().After(link.expires) {
+		return "", false
+	}
+	return link.target, true
+}
+
+// publicBaseURL returns the URL other devices reach this server at:
+// stream_access.public_url, else the host the request came in on (through
+// a reverse proxy, the one it forwarded)
+func (s *Server) publicBaseURL(r *http.Request) string {
+	if publicURL := s.configMgr.Get().StreamAccess.PublicURL; publicURL != "" {
+		return strings.TrimSuffix(publicURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host := r.Host
+	if _, forwarded := clientAddr(r); forwarded {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+		if fwdHost := r.Header.Get("X-Forwarded-Host"); fwdHost != "" {
+			host = fwdHost
+		}
+	}
+	return scheme + "://" + host
+}
+
+// streamLinkRequest describes a link to a stream page for another device
+type streamLinkRequest struct {
+	Page       string `json:"page"`        // "/", "/embed", "/timeline", "/report" or "/control" (default: "/", or "/control" for a control token)
+	Scope      string `json:"scope"`       // Link token to include: "", "view", "viewer" or "control" (default: view if stream_access.require_token)
+	TTLMinutes int    `json:"ttl_minutes"` // Lifetime of the token and short link
+	Label      string `json:"label"`       // Recorded in the token, e.g. who it was shown to
+}
+
+// streamLink is a link to a stream page
+type streamLink struct {
+	URL       string     `json:"url"`
+	Scope     string     `json:"scope,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// createStreamLink builds a link to a stream page for another device. With
+// a scope, it issues a link token and returns a short link to the page
+// with the token.
+func (s *Server) createStreamLink(r *http.Request, req streamLinkRequest) (streamLink, error) {
+	if req.Scope == "" && s.configMgr.Get().StreamAccess.RequireToken {
+		req.Scope = tokenScopeView
+	}
+	if req.Scope != "" && req.Scope != tokenScopeView && req.Scope != tokenScopeViewer && req.Scope != tokenScopeControl {
+		return streamLink{}, fmt.Errorf("scope must be view, viewer or control")
+	}
+	if req.Page == "" {
+		req.Page = tokenPage(req.Scope)
+	}
+	switch req.Page {
+	case "/", "/embed", "/timeline":
+	case "/report":
+		if req.Scope == tokenScopeView {
+			return streamLink{}, fmt.Errorf("page /report needs scope viewer or control")
+		}
+	case "/control":
+		if req.Scope != "" && req.Scope != tokenScopeControl {
+			return streamLink{}, fmt.Errorf("page /control needs scope control")
+		}
+	default:
+		return streamLink{}, fmt.Errorf("page must be /, /embed, /timeline, /report or /control")
+	}
+
+	base := s.publicBaseURL(r)
+	if req.Scope == "" {
+		return streamLink{URL: base + req.Page}, nil
+	}
+
+	ttl, err := tokenTTL(req.TTLMinutes)
+	if err != nil {
+		return streamLink{}, err
+	}
+	token, expires, err := s.access.Issue(req.Scope, req.Label, ttl)
+	if err != nil {
+		return streamLink{}, err
+	}
+	code, err := s.links.Add(req.Page+"?token="+url.QueryEscape(token), expires)
+	if err != nil {
+		return streamLink{}, err
+	}
+
+	logger.WithComponent("stream-access").Info().
+		Str("scope", req.Scope).
+		Str("label", req.Label).
+		Time("expires_at", expires).
+		Msg("Short link issued")
+	return streamLink{URL: base + shortLinkPrefix + code, Scope: req.Scope, ExpiresAt: &expires}, nil
+}
+
+// handleCreateStreamLink returns a link to a stream page for another
+// device, e.g. {"scope": "view", "ttl_minutes": 30}
+func (s *Server) handleCreateStreamLink(w http.ResponseWriter, r *http.Request) {
+	var req streamLinkRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+			return
+		}
+	}
+
+	link, err := s.createStreamLink(r, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(link)
+}
+
+// handleStreamQRCode renders a QR code PNG of a link to a stream page, to
+// open it on a phone or tablet. The query takes the fields of
+// streamLinkRequest plus size; the link is in the X-Stream-URL header.
+func (s *Server) handleStreamQRCode(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := streamLinkRequest{
+		Page:  query.Get("page"),
+		Scope: query.Get("scope"),
+		Label: query.Get("label"),
+	}
+	if ttl := query.Get("ttl_minutes"); ttl != "" {
+		minutes, err := strconv.Atoi(ttl)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid ttl_minutes")
+			return
+		}
+		req.TTLMinutes = minutes
+	}
+	size := defaultQRSize
+	if value := query.Get("size"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < minQRSize || n > maxQRSize {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("size must be between %d and %d", minQRSize, maxQRSize))
+			return
+		}
+		size = n
+	}
+
+	link, err := s.createStreamLink(r, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	png, err := qrcode.Encode(link.URL, qrcode.Medium, size)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to render QR code: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "no-store") // Each request may issue a new token
+	w.Header().Set("X-Stream-URL", link.URL)
+	w.Write(png)
+}
+
+// handleShortLink redirects a short link to its stream page
+func (s *Server) handleShortLink(w http.ResponseWriter, r *http.Request) {
+	target, ok := s.links.Resolve(mux.Vars(r)["code"])
+	if !ok {
+		writeError(w, http.StatusNotFound, codeNotFound, "This link has expired or doesn't exist")
+		return
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
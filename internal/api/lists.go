@@ -0,0 +1,163 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+)
+
+// maxListLimit caps the page size of list endpoints
+const maxListLimit = 500
+
+// listParams are the filtering, sorting and pagination query parameters
+// of the application and window lists, e.g.
+// ?allowlisted=true&desktop=2&sort=title&order=desc&limit=20&offset=40
+type listParams struct {
+	allowlisted *bool // Only allowlisted (true) or other (false) entries
+	desktop     *int  // Only entries on this virtual desktop (sticky windows are on all)
+	sort        string
+	desc        bool
+	limit       int // 0 means no limit
+	offset      int
+}
+
+// parseListParams reads list parameters from the query string, accepting
+// the given sort keys
+func parseListParams(r *http.Request, sortKeys ...string) (listParams, error) {
+	query := r.URL.Query()
+	var p listParams
+
+	if value := query.Get("allowlisted"); value != "" {
+		allowlisted, err := strconv.ParseBool(value)
+		if err != nil {
+			return listParams{}, fmt.Errorf("invalid allowlisted: %s (use true or false)", value)
+		}
+		p.allowlisted = &allowlisted
+	}
+
+	if value := query.Get("desktop"); value != "" {
+		desktop, err := strconv.Atoi(value)
+		if err != nil {
+			return listParams{}, fmt.Errorf("invalid desktop: %s", value)
+		}
+		p.desktop = &desktop
+	}
+
+	if p.sort = query.Get("sort"); p.sort != "" && !slices.Contains(sortKeys, p.sort) {
+		return listParams{}, fmt.Errorf("invalid sort: %s (use %s)", p.sort, strings.Join(sortKeys, ", "))
+	}
+
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		p.desc = true
+	default:
+		return listParams{}, fmt.Errorf("invalid order: %s (use asc or desc)", order)
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"limit", &p.limit}, {"offset", &p.offset}} {
+		value := query.Get(param.name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return listParams{}, fmt.Errorf("invalid %s: %s", param.name, value)
+		}
+		*param.value = n
+	}
+	p.limit = min(p.limit, maxListLimit)
+
+	return p, nil
+}
+
+// onDesktop reports whether a window on the given desktop passes the
+// desktop filter
+func (p listParams) onDesktop(desktop int) bool {
+	return p.desktop == nil || desktop == *p.desktop || desktop == -1
+}
+
+// page returns the requested page of items
+func page[T any](items []T, p listParams) []T {
+	if p.offset >= len(items) {
+		return items[:0]
+	}
+	items = items[p.offset:]
+	if p.limit > 0 && p.limit < len(items) {
+		items = items[:p.limit]
+	}
+	return items
+}
+
+// filterApplications applies the list filters and sort to applications,
+// which come sorted by name
+func filterApplications(apps []config.Application, p listParams) []config.Application {
+	filtered := make([]config.Application, 0, len(apps))
+	for _, app := range apps {
+		if p.allowlisted != nil && app.Allowlisted != *p.allowlisted {
+			continue
+		}
+		if p.desktop != nil && !slices.ContainsFunc(app.Desktops, p.onDesktop) {
+			continue
+		}
+		filtered = append(filtered, app)
+	}
+
+	switch p.sort {
+	case "class":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return strings.ToLower(filtered[i].WindowClass) < strings.ToLower(filtered[j].WindowClass)
+		})
+	case "windows":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return filtered[i].Windows > filtered[j].Windows
+		})
+	}
+	if p.desc {
+		slices.Reverse(filtered)
+	}
+	return filtered
+}
+
+// filterWindows applies the list filters and sort to windows, which come
+// ranked by SearchWindows
+func filterWindows(windows []window.WindowMatch, p listParams) []window.WindowMatch {
+	filtered := make([]window.WindowMatch, 0, len(windows))
+	for _, w := range windows {
+		if p.allowlisted != nil && w.Allowlisted != *p.allowlisted {
+			continue
+		}
+		if !p.onDesktop(w.Desktop) {
+			continue
+		}
+		filtered = append(filtered, w)
+	}
+
+	switch p.sort {
+	case "class":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return strings.ToLower(filtered[i].Class) < strings.ToLower(filtered[j].Class)
+		})
+	case "title":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return strings.ToLower(filtered[i].Title) < strings.ToLower(filtered[j].Title)
+		})
+	case "desktop":
+		sort.SliceStable(filtered, func(i, j int) bool {
+			return filtered[i].Desktop < filtered[j].Desktop
+		})
+	}
+	if p.desc {
+		slices.Reverse(filtered)
+	}
+	return filtered
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/mux"
+)
+
+// statusRecorder captures the status code and size of a response for the
+// request log. It passes flushing and hijacking through, so MJPEG streams
+// and websockets work behind it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not support hijacking")
+	}
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// logRequests logs each request once it is done: method, path, route
+// template, status, size, duration and client address. Requests are logged
+// at debug level (info with api.log_requests), and server errors as
+// warnings, except 503, which handlers answer while something isn't ready
+// yet (e.g. no thumbnail before the first frame). Streams and websockets
+// are logged when they close.
+func (s *Server) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		log := logger.WithComponent("http")
+		event := log.Debug()
+		switch {
+		case status >= http.StatusInternalServerError && status != http.StatusServiceUnavailable:
+			event = log.Warn()
+		case s.configMgr.Get().API.LogRequests:
+			event = log.Info()
+		}
+		if route := mux.CurrentRoute(r); route != nil {
+			if tmpl, err := route.GetPathTemplate(); err == nil {
+				event = event.Str("route", tmpl)
+			}
+		}
+		addr, _ := clientAddr(r)
+		event.
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", status).
+			Int64("bytes", rec.bytes).
+			Dur("duration", time.Since(start)).
+			Str("remote", addr.String()).
+			Msg("Request")
+	})
+}
+
+// Endpoint groups rate limited with api.rate_limit. Each client has its
+// own budget per group, so polling thumbnails doesn't use up screenshots.
+const (
+	rateGroupScreenshots  = "screenshots"
+	rateGroupThumbnail    = "thumbnail"
+	rateGroupApplications = "applications"
+)
+
+// rateBucketIdle is how long an unused client bucket is kept
+const rateBucketIdle = 10 * time.Minute
+
+// rateLimiter is a token bucket per client and endpoint group. Rate and
+// burst are passed on every call, so config edits apply at once.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[rateKey]*rateBucket
+	lastPrune time.Time
+}
+
+type rateKey struct {
+	group string
+	addr  netip.Addr
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{buckets: make(map[rateKey]*rateBucket)}
+}
+
+// allow takes a token from the client's bucket for the group. If there is
+// none, it returns false and how long until there will be.
+func (l *rateLimiter) allow(key rateKey, rate float64, burst int, now time.Time) (bool, time.Duration) {
+	burst = max(burst, 1)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastPrune) > rateBucketIdle {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) > rateBucketIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// rateLimited limits each client to api.rate_limit requests per second to
+// an expensive handler, answering 429 Too Many Requests with Retry-After
+// beyond that. A rate of 0 turns the limit off.
+func (s *Server) rateLimited(group string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.configMgr.Get().API
+		if cfg.RateLimit <= 0 {
+			next(w, r)
+			return
+		}
+
+		addr, _ := clientAddr(r)
+		ok, wait := s.limiter.allow(rateKey{group: group, addr: addr}, cfg.RateLimit, cfg.RateBurst, time.Now())
+		if !ok {
+			logger.WithComponent("http").Debug().
+				Str("group", group).
+				Str("remote", addr.String()).
+				Msg("Rate limited")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			writeError(w, http.StatusTooManyRequests, codeRateLimited, "Too many requests")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture/pipewire"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// handleGetPortalSession reports the screen share portal session's state
+func (s *Server) handleGetPortalSession(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.windowMgr.GetPortalSession())
+}
+
+// handlePortalSessionSocket pushes the portal session's state, then every
+// change to it, e.g. so a dashboard can tell the user to look for the share
+// dialog
+func (s *Server) handlePortalSessionSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.WithComponent("api").Debug().Err(err).Msg("Portal events WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	events := s.windowMgr.SubscribePortalSession()
+	defer s.windowMgr.UnsubscribePortalSession(events)
+
+	// Nothing is read from the client; reading notices it going away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(s.windowMgr.GetPortalSession()); err != nil {
+		return
+	}
+	for {
+		select {
+		case <-closed:
+			return
+		case event := <-events:
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleGetCaptureMonitors returns the monitor map windows are cropped by
+func (s *Server) handleGetCaptureMonitors(w http.ResponseWriter, r *http.Request) {
+	monitors := s.windowMgr.GetCaptureMonitors()
+	if monitors == nil {
+		monitors = []pipewire.Monitor{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"monitors": monitors,
+	})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error codes sent in the code member of problem responses. Clients branch
+// on these rather than on the status or the human-readable detail.
+const (
+	codeInvalidRequest   = "invalid_request"   // Malformed body or parameters
+	codeUnauthorized     = "unauthorized"      // Missing or invalid link token
+	codeForbidden        = "forbidden"         // Not allowed from this client or with this token
+	codeNotFound         = "not_found"         // No such profile, widget, token, ...
+	codeConflict         = "conflict"          // Clashes with the current state
+	codeTooLarge         = "too_large"         // Request body over the limit
+	codeUnsupportedMedia = "unsupported_media" // Content type or image format not accepted
+	codeRateLimited      = "rate_limited"      // Over api.rate_limit or a queue limit; see Retry-After
+	codeInternal         = "internal_error"    // Unexpected server-side failure
+	codeNotImplemented   = "not_implemented"   // Not supported by this build or backend
+	codeUnavailable      = "unavailable"       // Not ready yet or turned off, e.g. no frame streamed yet
+
+	codeWindowNotFound = "window_not_found" // No open window with that ID or class
+	codeInvalidPattern = "invalid_pattern"  // An allowlist or title pattern doesn't compile
+	codeCaptureFailed  = "capture_failed"   // Capturing a window or frame failed
+	codeInvalidConfig  = "invalid_config"   // The config change didn't validate
+
+	codeApprovalRequired = "approval_required" // Allowlist additions wait for a second party (allowlist_approval)
+)
+
+// problemContentType is the RFC 7807 media type of error responses
+const problemContentType = "application/problem+json"
+
+// problem is an RFC 7807 problem details body, with the machine-readable
+// error code as an extension member
+type problem struct {
+	Type   string `json:"type"`   // urn:focusstreamer:error:<code>
+	Title  string `json:"title"`  // Status text, e.g. "Not Found"
+	Status int    `json:"status"` // HTTP status, repeated for clients that lose it
+	Code   string `json:"code"`
+	Detail string `json:"detail,omitempty"` // What went wrong, for people
+}
+
+// writeError sends an error as application/problem+json, e.g.
+//
+//	{"type": "urn:focusstreamer:error:window_not_found", "title": "Not Found",
+//	 "status": 404, "code": "window_not_found", "detail": "window 42 not found"}
+//
+// It replaces http.Error throughout the API.
+func writeError(w http.ResponseWriter, status int, code, detail string) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(problem{
+		Type:   "urn:focusstreamer:error:" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	})
+}
+
+// problemHandler answers unmatched API routes with a problem response
+// instead of the router's plain-text one
+func problemHandler(status int, code string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, status, code, r.Method+" "+r.URL.Path+": "+http.StatusText(status))
+	})
+}
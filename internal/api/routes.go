@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// handleGetCaptureRoutes returns the capture.routes rules and the
+// per-window overrides, or with ?window_id=N how that window's capturer is
+// chosen
+func (s *Server) handleGetCaptureRoutes(w http.ResponseWriter, r *http.Request) {
+	if idParam := r.URL.Query().Get("window_id"); idParam != "" {
+		id, err := strconv.ParseUint(idParam, 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid window ID")
+			return
+		}
+		window, err := s.windowMgr.FindWindowByID(uint32(id))
+		if err != nil {
+			writeError(w, http.StatusNotFound, codeWindowNotFound, err.Error())
+			return
+		}
+		route, err := s.windowMgr.GetCaptureRoute(window)
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(route)
+		return
+	}
+
+	rules := s.configMgr.Get().Capture.Routes
+	if rules == nil {
+		rules = map[string]string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rules":     rules,
+		"overrides": s.windowMgr.GetCaptureRouteOverrides(),
+	})
+}
+
+// handleSetCaptureRoute sends a window to a capturer until it closes, e.g.
+// {"window_id": 62914563, "capturer": "x11"}; "auto" removes the override
+func (s *Server) handleSetCaptureRoute(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		WindowID uint32 `json:"window_id"`
+		Capturer string `json:"capturer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+	if req.Capturer == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "capturer is required (auto, x11, pipewire or a registered capturer)")
+		return
+	}
+
+	window, err := s.windowMgr.FindWindowByID(req.WindowID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, codeWindowNotFound, err.Error())
+		return
+	}
+	route, err := s.windowMgr.SetCaptureRoute(window, req.Capturer)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(route)
+}
@@ -1,27 +1,46 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"image/jpeg"
+	"hash/fnv"
 	"io"
+	"io/fs"
+	"net"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/bryanchriswhite/FocusStreamer/internal/approval"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/dnd"
+	"github.com/bryanchriswhite/FocusStreamer/internal/eventhub"
+	"github.com/bryanchriswhite/FocusStreamer/internal/focusstats"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/imgenc"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 	"github.com/bryanchriswhite/FocusStreamer/internal/output"
 	"github.com/bryanchriswhite/FocusStreamer/internal/overlay"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pii"
+	"github.com/bryanchriswhite/FocusStreamer/internal/session"
 	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+	"github.com/bryanchriswhite/FocusStreamer/web"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
+	graphql "github.com/graph-gophers/graphql-go"
 )
 
+// maxConfigImportSize bounds uploads to /api/config/import
+const maxConfigImportSize = 1 << 20
+
 // ProfileChangeCallback is called when the active profile changes
 type ProfileChangeCallback func(profileID string)
 
@@ -34,6 +53,17 @@ type Server struct {
 	overlayMgr              *overlay.Manager
 	upgrader                websocket.Upgrader
 	onProfileChangeCallback ProfileChangeCallback
+	suppressor              *dnd.Suppressor
+	webDir                  string // Serve the settings UI from disk instead of the embedded build
+	sessions                *session.Manager
+	access                  *streamAccess // Link tokens for the stream pages
+	links                   *shortLinks   // Short links to stream pages with a link token
+	outputs                 *output.Multiplexer
+	limiter                 *rateLimiter // Per-client budgets for expensive endpoints
+	approvals               *approval.Queue
+	focusStats              *focusstats.Store // Nil with focus_stats off
+	events                  *eventhub.Hub
+	graphql                 *graphql.Schema // Served with api.graphql
 }
 
 // NewServer creates a new API server
@@ -44,6 +74,10 @@ func NewServer(windowMgr *window.Manager, configMgr *config.Manager, displayMgr
 		configMgr:  configMgr,
 		mjpegOut:   mjpegOut,
 		overlayMgr: overlayMgr,
+		access:     newStreamAccess(configMgr),
+		links:      newShortLinks(),
+		limiter:    newRateLimiter(),
+		approvals:  approval.NewQueue(configMgr),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true // Allow all origins for development
@@ -51,10 +85,59 @@ func NewServer(windowMgr *window.Manager, configMgr *config.Manager, displayMgr
 		},
 	}
 
+	s.graphql = newGraphQLSchema(s)
+
+	s.approvals.OnApprove(approval.KindBypass, func(approval.Entry) error {
+		windowMgr.SetAllowlistBypass(true)
+		return nil
+	})
+	s.approvals.OnApprove(approval.KindSource, func(entry approval.Entry) error {
+		return windowMgr.SelectExternalSource(entry.Value)
+	})
+
 	s.setupRoutes()
 	return s
 }
 
+// SetNotificationSuppressor sets the Do Not Disturb suppressor reported in /api/health
+func (s *Server) SetNotificationSuppressor(suppressor *dnd.Suppressor) {
+	s.suppressor = suppressor
+}
+
+// SetOutputs sets the output multiplexer managed through /api/outputs
+func (s *Server) SetOutputs(outputs *output.Multiplexer) {
+	s.outputs = outputs
+}
+
+// SetFocusStats sets the store reported at /api/stats/focus
+func (s *Server) SetFocusStats(store *focusstats.Store) {
+	s.focusStats = store
+}
+
+// SetEvents sets the hub whose events are sent over /api/events/ws
+func (s *Server) SetEvents(hub *eventhub.Hub) {
+	s.events = hub
+}
+
+// SetSessions serves the sessions in /api/sessions and under /u/<name>/,
+// with this server handling the primary session
+func (s *Server) SetSessions(sessions *session.Manager) {
+	sessions.SetLocalHandler(s.router)
+	s.sessions = sessions
+}
+
+// SetWebDir serves the settings UI from a built web/dist directory on disk
+// instead of the copy embedded in the binary, so rebuilds show up on reload
+func (s *Server) SetWebDir(dir string) {
+	s.webDir = dir
+}
+
+// Approvals returns the queue of allowlist entries waiting for approval,
+// for other front ends adding to the allowlist
+func (s *Server) Approvals() *approval.Queue {
+	return s.approvals
+}
+
 // SetOnProfileChange sets the callback for profile changes
 func (s *Server) SetOnProfileChange(callback ProfileChangeCallback) {
 	s.onProfileChangeCallback = callback
@@ -62,29 +145,45 @@ func (s *Server) SetOnProfileChange(callback ProfileChangeCallback) {
 
 // setupRoutes configures the API routes
 func (s *Server) setupRoutes() {
+	s.router.Use(s.logRequests)
+
 	// API routes
 	api := s.router.PathPrefix("/api").Subrouter()
+	api.NotFoundHandler = problemHandler(http.StatusNotFound, codeNotFound)
 
 	// Application management
-	api.HandleFunc("/applications", s.handleGetApplications).Methods("GET")
+	api.HandleFunc("/applications", s.rateLimited(rateGroupApplications, s.handleGetApplications)).Methods("GET")
 	api.HandleFunc("/applications/allowlisted", s.handleGetAllowlisted).Methods("GET")
 	api.HandleFunc("/applications/allowlist", s.handleAddToAllowlist).Methods("POST")
 	api.HandleFunc("/applications/allowlist/{id}", s.handleRemoveFromAllowlist).Methods("DELETE")
+	api.HandleFunc("/applications/allowlist/pending", s.handleGetPendingAllowlist).Methods("GET")
+	api.HandleFunc("/applications/allowlist/pending/{id}/approve", s.handleApproveAllowlistEntry).Methods("POST")
+	api.HandleFunc("/applications/allowlist/pending/{id}/reject", s.handleRejectAllowlistEntry).Methods("POST")
+
+	// Individual windows, searchable with ?query=
+	api.HandleFunc("/windows", s.rateLimited(rateGroupApplications, s.handleSearchWindows)).Methods("GET")
+	api.HandleFunc("/windows/screenshots", s.rateLimited(rateGroupScreenshots, s.handleBatchScreenshots)).Methods("GET")
+	api.HandleFunc("/windows/{id}/activate", s.handleActivateWindow).Methods("POST")
 
 	// Window state
 	api.HandleFunc("/window/current", s.handleGetCurrentWindow).Methods("GET")
 	api.HandleFunc("/window/allowlist-status", s.handleGetAllowlistStatus).Methods("GET")
+	api.HandleFunc("/allowlist/test", s.handleTestAllowlist).Methods("POST")
 	api.HandleFunc("/window/stream", s.handleWindowStream)
-	api.HandleFunc("/window/{id}/screenshot", s.handleGetWindowScreenshot).Methods("GET")
+	api.HandleFunc("/window/events", s.handleWindowEvents).Methods("GET")
+	api.HandleFunc("/window/{id}/screenshot", s.rateLimited(rateGroupScreenshots, s.handleGetWindowScreenshot)).Methods("GET")
 
 	// Browser context
 	api.HandleFunc("/browser/active", s.handleBrowserActive).Methods("POST")
 	api.HandleFunc("/browser/allowlist", s.handleSetBrowserAllowlist).Methods("POST")
 	api.HandleFunc("/browser/status", s.handleBrowserStatus).Methods("GET")
+	api.HandleFunc("/browser/ws", s.handleBrowserSocket)
 
 	// Configuration
 	api.HandleFunc("/config", s.handleGetConfig).Methods("GET")
 	api.HandleFunc("/config", s.handleUpdateConfig).Methods("PUT")
+	api.HandleFunc("/config/export", s.handleExportConfig).Methods("GET")
+	api.HandleFunc("/config/import", s.handleImportConfig).Methods("POST")
 	api.HandleFunc("/config/patterns", s.handleAddPattern).Methods("POST")
 	api.HandleFunc("/config/patterns", s.handleRemovePattern).Methods("DELETE")
 	api.HandleFunc("/config/url-rules", s.handleAddURLRule).Methods("POST")
@@ -98,6 +197,8 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/config/placeholder-images", s.handleUploadPlaceholders).Methods("POST")
 	api.HandleFunc("/config/placeholder-images/{id}", s.handleGetPlaceholderByID).Methods("GET")
 	api.HandleFunc("/config/placeholder-images/{id}", s.handleDeletePlaceholderByID).Methods("DELETE")
+	api.HandleFunc("/config/placeholder-theme", s.handleGetPlaceholderTheme).Methods("GET")
+	api.HandleFunc("/config/placeholder-theme", s.handleSetPlaceholderTheme).Methods("PUT")
 
 	// Profile management
 	api.HandleFunc("/profiles", s.handleListProfiles).Methods("GET")
@@ -116,28 +217,108 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/overlay/instances/{id}", s.handleUpdateWidget).Methods("PUT")
 	api.HandleFunc("/overlay/instances/{id}", s.handleDeleteWidget).Methods("DELETE")
 	api.HandleFunc("/overlay/enabled", s.handleSetOverlayEnabled).Methods("PUT")
+	api.HandleFunc("/overlay/groups", s.handleGetWidgetGroups).Methods("GET")
+	api.HandleFunc("/overlay/groups/{group}", s.handleSetWidgetGroupEnabled).Methods("PUT")
 
 	// Stream control
 	api.HandleFunc("/stream/standby", s.handleGetStandby).Methods("GET")
 	api.HandleFunc("/stream/standby", s.handleToggleStandby).Methods("POST")
+	api.HandleFunc("/stream/panic", s.handleGetPanic).Methods("GET")
+	api.HandleFunc("/stream/panic", s.handlePanic).Methods("POST")
+	api.HandleFunc("/stream/panic/rearm", s.handleRearm).Methods("POST")
 	api.HandleFunc("/stream/allowlist-bypass", s.handleGetAllowlistBypass).Methods("GET")
 	api.HandleFunc("/stream/allowlist-bypass", s.handleToggleAllowlistBypass).Methods("POST")
+	api.HandleFunc("/stream/on-air", s.handleGetOnAir).Methods("GET")
+	api.HandleFunc("/stream/clients", s.handleGetStreamClients).Methods("GET")
+	api.HandleFunc("/stream/pii-guard", s.handleGetPIIGuard).Methods("GET")
+	api.HandleFunc("/stream/pii-guard/override", s.handleTogglePIIOverride).Methods("POST")
 	api.HandleFunc("/stream/placeholder/next", s.handleNextPlaceholder).Methods("POST")
 	api.HandleFunc("/stream/placeholder/prev", s.handlePrevPlaceholder).Methods("POST")
 	api.HandleFunc("/stream/zoom", s.handleGetZoom).Methods("GET")
 	api.HandleFunc("/stream/zoom", s.handleSetZoom).Methods("POST")
 	api.HandleFunc("/stream/zoom/reset", s.handleResetZoom).Methods("POST")
-	api.HandleFunc("/stream/thumbnail", s.handleThumbnail).Methods("GET")
+	api.HandleFunc("/stream/filters", s.handleGetStreamFilters).Methods("GET")
+	api.HandleFunc("/stream/filters", s.handleSetStreamFilters).Methods("PUT")
+	api.HandleFunc("/stream/source", s.handleGetStreamSource).Methods("GET")
+	api.HandleFunc("/stream/source", s.handleSetStreamSource).Methods("PUT")
+
+	// Scenes (named bundles of profile, window, zoom and overlays)
+	api.HandleFunc("/scenes", s.handleGetScenes).Methods("GET")
+	api.HandleFunc("/scenes/{name}/activate", s.handleActivateScene).Methods("POST")
+	api.HandleFunc("/stream/thumbnail", s.rateLimited(rateGroupThumbnail, s.handleThumbnail)).Methods("GET")
+	api.HandleFunc("/stream/geometry", s.handleGetStreamGeometry).Methods("GET")
+	api.HandleFunc("/stream/snapshot", s.handleStreamSnapshot).Methods("GET")
+	api.HandleFunc("/stream/thumbnail/ws", s.handleThumbnailSocket)
+
+	// Output sinks fed by the stream
+	api.HandleFunc("/outputs", s.handleGetOutputs).Methods("GET")
+	api.HandleFunc("/outputs/{name}", s.handleUpdateOutput).Methods("PUT")
+
+	// Expiring link tokens for the stream pages (see stream_access)
+	api.HandleFunc("/tokens", s.handleCreateToken).Methods("POST")
+	api.HandleFunc("/tokens", s.handleRevokeTokens).Methods("DELETE")
+	api.HandleFunc("/stream/link", s.handleCreateStreamLink).Methods("POST")
+	api.HandleFunc("/stream/qrcode", s.handleStreamQRCode).Methods("GET")
+	s.router.HandleFunc(shortLinkPrefix+"{code}", s.handleShortLink).Methods("GET")
+
+	// Capture agents feeding a server started with the remote backend
+	api.HandleFunc("/agent", s.handleGetAgent).Methods("GET")
+	api.HandleFunc("/agent/ws", s.handleAgentSocket)
+
+	// Screen share portal session (Wayland)
+	api.HandleFunc("/capture/portal", s.handleGetPortalSession).Methods("GET")
+	api.HandleFunc("/capture/portal/ws", s.handlePortalSessionSocket)
+	api.HandleFunc("/capture/monitors", s.handleGetCaptureMonitors).Methods("GET")
+	api.HandleFunc("/capture/route", s.handleGetCaptureRoutes).Methods("GET")
+	api.HandleFunc("/capture/route", s.handleSetCaptureRoute).Methods("POST")
+
+	// Frames pushed by other programs, shown as external sources
+	api.HandleFunc("/ingest", s.handleGetIngest).Methods("GET")
+	api.HandleFunc("/ingest/{name}", s.handleIngestFrame).Methods("POST")
+	api.HandleFunc("/ingest/{name}", s.handleDeleteIngest).Methods("DELETE")
+	api.HandleFunc("/ingest/{name}/ws", s.handleIngestSocket)
+
+	// Timed captions and telestrator strokes on the stream
+	api.HandleFunc("/annotations", s.handleGetAnnotations).Methods("GET")
+	api.HandleFunc("/annotations", s.handleAddAnnotation).Methods("POST")
+	api.HandleFunc("/annotations", s.handleClearAnnotations).Methods("DELETE")
+	api.HandleFunc("/annotations/draw", s.handleDrawAnnotation).Methods("POST")
+	api.HandleFunc("/annotations/draw", s.handleClearDrawing).Methods("DELETE")
+
+	// What the stream showed this session
+	api.HandleFunc("/timeline", s.handleGetTimeline).Methods("GET")
+
+	// Time each application had focus, per day or week
+	api.HandleFunc("/stats/focus", s.handleGetFocusStats).Methods("GET")
+
+	// Typed events (pkg/events) as protobuf or JSON, or GraphQL
+	// subscriptions with the graphql-transport-ws subprotocol
+	api.HandleFunc("/events/ws", s.handleEventsSocket).Methods("GET")
+
+	// GraphQL queries over windows, config, overlays and outputs (api.graphql)
+	api.HandleFunc("/graphql", s.handleGraphQL).Methods("POST")
 
 	// Health check
 	api.HandleFunc("/health", s.handleHealth).Methods("GET")
 
+	// Window backend and the features it supports
+	api.HandleFunc("/backend", s.handleGetBackend).Methods("GET")
+	api.HandleFunc("/backend/providers", s.handleGetProviders).Methods("GET")
+
+	// Sessions served by this daemon, each under /u/<name>/
+	api.HandleFunc("/sessions", s.handleGetSessions).Methods("GET")
+	s.router.PathPrefix(session.PathPrefix).HandlerFunc(s.handleSessionRequest)
+
 	// MJPEG stream endpoints (if MJPEG output is enabled)
 	if s.mjpegOut != nil {
 		s.router.HandleFunc("/", s.mjpegOut.GetViewerHandler())         // Clean HTML viewer (root)
 		s.router.HandleFunc("/control", s.mjpegOut.GetControlHandler()) // HTML viewer with controls
 		s.router.HandleFunc("/stream", s.mjpegOut.GetHTTPHandler())     // Raw MJPEG feed
+		s.router.HandleFunc("/embed", s.mjpegOut.GetEmbedHandler())     // Minimal iframe-friendly viewer
+		s.router.HandleFunc("/timeline", s.mjpegOut.GetTimelineHandler())
+		s.router.HandleFunc("/report", s.mjpegOut.GetReportHandler())
 		s.router.HandleFunc("/stats", s.mjpegOut.GetStatsHandler())
+		s.router.HandleFunc("/stats.json", s.mjpegOut.GetStatsJSONHandler())
 	}
 
 	// Serve static files (React app from web/dist) at /settings
@@ -146,50 +327,94 @@ func (s *Server) setupRoutes() {
 
 // createSettingsHandler creates a handler for serving the React settings app at /settings
 func (s *Server) createSettingsHandler() http.Handler {
-	// Get the web/dist directory path
-	webDistPath := filepath.Join("web", "dist")
-
-	// Get absolute path for better debugging
-	absPath, _ := filepath.Abs(webDistPath)
-	logger.WithComponent("overlay").Info().Msgf("Looking for web UI at: %s", absPath)
-
-	// Check if the directory exists
-	if _, err := os.Stat(webDistPath); os.IsNotExist(err) {
-		logger.WithComponent("overlay").Info().Msgf("Warning: web/dist directory not found at %s", absPath)
-		logger.WithComponent("overlay").Info().Msgf("Serving fallback HTML. To see the React UI, run from project root: cd /path/to/FocusStreamer && ./build/focusstreamer serve")
-		return http.HandlerFunc(s.handleFallbackIndex)
+	embedded, hasEmbedded := web.Dist()
+	if !hasEmbedded {
+		logger.WithComponent("api").Warn().Msg("Web UI not built into this binary (run make build); serving fallback page unless --web-dir is set")
 	}
 
-	logger.WithComponent("overlay").Info().Msgf("✅ Found web UI build at: %s", absPath)
-
-	// Create file server with /settings prefix stripped
-	fileServer := http.StripPrefix("/settings", http.FileServer(http.Dir(webDistPath)))
-
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webFS, ok := embedded, hasEmbedded
+		if s.webDir != "" {
+			webFS = os.DirFS(s.webDir)
+			_, err := fs.Stat(webFS, "index.html")
+			ok = err == nil
+		}
+		if !ok {
+			s.handleFallbackIndex(w, r)
+			return
+		}
+
 		// Strip /settings prefix to get the actual file path
-		filePath := strings.TrimPrefix(r.URL.Path, "/settings")
+		filePath := strings.TrimPrefix(path.Clean(r.URL.Path), "/settings")
+		filePath = strings.TrimPrefix(filePath, "/")
 		if filePath == "" {
-			filePath = "/"
+			filePath = "index.html"
 		}
 
-		// Check if the file exists
-		path := filepath.Join(webDistPath, filePath)
-		if _, err := os.Stat(path); os.IsNotExist(err) && !strings.HasPrefix(filePath, "/assets") {
-			// Serve index.html for SPA routing
-			http.ServeFile(w, r, filepath.Join(webDistPath, "index.html"))
-			return
+		// Serve index.html for SPA routes that aren't files
+		if _, err := fs.Stat(webFS, filePath); err != nil && !strings.HasPrefix(filePath, "assets/") {
+			filePath = "index.html"
 		}
 
-		// Serve the requested file
-		fileServer.ServeHTTP(w, r)
+		http.ServeFileFS(w, r, webFS, filePath)
 	})
 }
 
-// Start starts the HTTP server
-func (s *Server) Start(port int) error {
-	addr := fmt.Sprintf("127.0.0.1:%d", port)
-	logger.WithComponent("overlay").Info().Msgf("Starting server on http://%s\n", addr)
-	return http.ListenAndServe(addr, s.enableCORS(s.router))
+// shutdownTimeout is how long Start waits for requests in flight once its
+// context is done before closing their connections
+const shutdownTimeout = 5 * time.Second
+
+// Start serves HTTP until ctx is done, then shuts the server down and
+// returns nil. Under systemd socket activation it serves the socket it was
+// passed instead of listening on port, so the daemon can restart without
+// refusing connections.
+func (s *Server) Start(ctx context.Context, port int) error {
+	listener, err := activationListener()
+	if err != nil {
+		return err
+	}
+
+	// Requests inherit ctx, so streams and other long-lived handlers end
+	// when the server shuts down instead of holding it open
+	httpServer := &http.Server{
+		Handler:     s.handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	if listener != nil {
+		logger.WithComponent("overlay").Info().Msgf("Starting server on socket-activated %s", listener.Addr())
+	} else {
+		httpServer.Addr = fmt.Sprintf("127.0.0.1:%d", port)
+		logger.WithComponent("overlay").Info().Msgf("Starting server on http://%s\n", httpServer.Addr)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		if listener != nil {
+			errChan <- httpServer.Serve(listener)
+		} else {
+			errChan <- httpServer.ListenAndServe()
+		}
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.WithComponent("overlay").Warn().Err(err).Msg("Server didn't shut down in time, closing connections")
+		httpServer.Close()
+	}
+	return nil
+}
+
+// handler wraps the router with CORS headers, the cross-site check and
+// stream page access checks
+func (s *Server) handler() http.Handler {
+	return s.enableCORS(checkOrigin(s.checkStreamAccess(s.router)))
 }
 
 // enableCORS adds CORS headers
@@ -210,34 +435,176 @@ func (s *Server) enableCORS(next http.Handler) http.Handler {
 
 // HTTP Handlers
 
-func (s *Server) handleGetApplications(w http.ResponseWriter, r *http.Request) {
-	apps, err := s.windowMgr.GetApplications()
+// handleSearchWindows lists individual windows, fuzzy matched and ranked
+// against ?query= over titles and classes (see listParams for filtering,
+// sorting and pagination). total counts the windows on all pages.
+func (s *Server) handleSearchWindows(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+	params, err := parseListParams(r, "score", "class", "title", "desktop")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	windows, err := s.windowMgr.SearchWindows(query)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
+	windows = filterWindows(windows, params)
+	total := len(windows)
+	windows = page(windows, params)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(apps)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query":   query,
+		"windows": windows,
+		"count":   len(windows),
+		"total":   total,
+	})
+}
+
+// maxBatchScreenshots bounds the windows in one screenshot batch
+const maxBatchScreenshots = 64
+
+// handleBatchScreenshots returns downscaled screenshots of several windows
+// as base64 images in one response, e.g. ?ids=12,34&max_width=320
+func (s *Server) handleBatchScreenshots(w http.ResponseWriter, r *http.Request) {
+	var ids []uint32
+	for _, field := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("Invalid window ID: %s", field))
+			return
+		}
+		ids = append(ids, uint32(id))
+	}
+	if len(ids) == 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "ids is required")
+		return
+	}
+	if len(ids) > maxBatchScreenshots {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("At most %d windows per batch", maxBatchScreenshots))
+		return
+	}
+
+	maxWidth := 320
+	if value := r.URL.Query().Get("max_width"); value != "" {
+		width, err := strconv.Atoi(value)
+		if err != nil || width < 16 || width > 1920 {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "max_width must be 16-1920")
+			return
+		}
+		maxWidth = width
+	}
+
+	format, err := imgenc.Negotiate(r, imgenc.JPEG)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	shots, err := s.windowMgr.CaptureWindowScreenshots(ids, maxWidth, format)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeCaptureFailed, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"screenshots": shots,
+		"count":       len(shots),
+	})
+}
+
+// handleGetBackend returns the window backend's name and capabilities, so
+// clients can hide features it doesn't support
+func (s *Server) handleGetBackend(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.windowMgr.GetBackendInfo())
+}
+
+// handleGetProviders lists the built-in and registered window backends and
+// capturers
+func (s *Server) handleGetProviders(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.windowMgr.GetProviders())
+}
+
+// handleActivateWindow focuses and raises a window on the desktop, so the
+// shared window can be switched from another device
+func (s *Server) handleActivateWindow(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid window ID")
+		return
+	}
+
+	if !s.windowMgr.CanActivateWindows() {
+		writeError(w, http.StatusNotImplemented, codeNotImplemented, "Window activation is not supported by this backend")
+		return
+	}
+
+	window, err := s.windowMgr.FindWindowByID(uint32(id))
+	if err != nil {
+		writeError(w, http.StatusNotFound, codeWindowNotFound, err.Error())
+		return
+	}
+
+	if err := s.windowMgr.ActivateWindow(window.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"window": window,
+	})
+}
+
+// handleGetApplications lists applications with open windows (see
+// listParams for filtering, sorting and pagination). X-Total-Count counts
+// the applications on all pages.
+func (s *Server) handleGetApplications(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r, "name", "class", "windows")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	s.writeApplications(w, params)
 }
 
+// handleGetAllowlisted lists allowlisted applications with open windows,
+// taking the same parameters as handleGetApplications
 func (s *Server) handleGetAllowlisted(w http.ResponseWriter, r *http.Request) {
-	apps, err := s.windowMgr.GetApplications()
+	params, err := parseListParams(r, "name", "class", "windows")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
+	allowlisted := true
+	params.allowlisted = &allowlisted
+	s.writeApplications(w, params)
+}
 
-	// Filter allowlisted apps
-	allowlisted := make([]config.Application, 0)
-	for _, app := range apps {
-		if app.Allowlisted {
-			allowlisted = append(allowlisted, app)
-		}
+// writeApplications writes a page of the filtered application list
+func (s *Server) writeApplications(w http.ResponseWriter, params listParams) {
+	apps, err := s.windowMgr.GetApplications()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
 	}
+	apps = filterApplications(apps, params)
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(allowlisted)
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(apps)))
+	json.NewEncoder(w).Encode(page(apps, params))
 }
 
 func (s *Server) handleAddToAllowlist(w http.ResponseWriter, r *http.Request) {
@@ -247,7 +614,16 @@ func (s *Server) handleAddToAllowlist(w http.ResponseWriter, r *http.Request) {
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error decoding add allowlist request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if req.AppClass == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "app_class is required")
+		return
+	}
+
+	if s.approvals.Enabled() {
+		s.submitForApproval(w, approval.Entry{Kind: approval.KindApp, Value: req.AppClass})
 		return
 	}
 
@@ -255,7 +631,7 @@ func (s *Server) handleAddToAllowlist(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.configMgr.AddAllowlistedApp(req.AppClass); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error adding to allowlist: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -273,7 +649,7 @@ func (s *Server) handleRemoveFromAllowlist(w http.ResponseWriter, r *http.Reques
 
 	if err := s.configMgr.RemoveAllowlistedApp(appClass); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error removing from allowlist: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -286,7 +662,7 @@ func (s *Server) handleRemoveFromAllowlist(w http.ResponseWriter, r *http.Reques
 func (s *Server) handleGetCurrentWindow(w http.ResponseWriter, r *http.Request) {
 	currentWindow := s.windowMgr.GetCurrentWindow()
 	if currentWindow == nil {
-		http.Error(w, "No window focused", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "No window focused")
 		return
 	}
 
@@ -297,7 +673,7 @@ func (s *Server) handleGetCurrentWindow(w http.ResponseWriter, r *http.Request)
 func (s *Server) handleGetAllowlistStatus(w http.ResponseWriter, r *http.Request) {
 	currentWindow := s.windowMgr.GetCurrentWindow()
 	if currentWindow == nil {
-		http.Error(w, "No window focused", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "No window focused")
 		return
 	}
 
@@ -309,6 +685,29 @@ func (s *Server) handleGetAllowlistStatus(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// handleTestAllowlist reports which allowlist rule would match a window with
+// the given class and title, and whether it would be streamed
+func (s *Server) handleTestAllowlist(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Class string `json:"class"`
+		Title string `json:"title"`
+		URL   string `json:"url,omitempty"` // Active tab, for browser classes
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if req.Class == "" && req.Title == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "class or title is required")
+		return
+	}
+
+	match := s.windowMgr.TestAllowlist(req.Class, req.Title, req.URL)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(match)
+}
+
 func (s *Server) handleWindowStream(w http.ResponseWriter, r *http.Request) {
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -348,24 +747,30 @@ func (s *Server) handleGetWindowScreenshot(w http.ResponseWriter, r *http.Reques
 	window, err := s.windowMgr.FindWindowByClass(windowClass)
 	if err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Window not found: %v", err)
-		http.Error(w, "Window not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeWindowNotFound, "Window not found")
+		return
+	}
+
+	format, err := imgenc.Negotiate(r, imgenc.PNG)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
 	// Capture screenshot using window manager
-	pngData, err := s.windowMgr.CaptureWindowScreenshot(window.ID)
+	data, err := s.windowMgr.CaptureWindowScreenshotAs(window.ID, format)
 	if err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Failed to capture screenshot: %v", err)
-		http.Error(w, fmt.Sprintf("Failed to capture screenshot: %v", err), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeCaptureFailed, fmt.Sprintf("Failed to capture screenshot: %v", err))
 		return
 	}
 
-	logger.WithComponent("overlay").Info().Msgf("Successfully captured screenshot for %s (%d bytes)", windowClass, len(pngData))
+	logger.WithComponent("overlay").Info().Msgf("Successfully captured screenshot for %s (%d bytes)", windowClass, len(data))
 
-	// Return PNG image
-	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Type", imgenc.ContentType(format))
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	w.Write(pngData)
+	w.Header().Set("Vary", "Accept")
+	w.Write(data)
 }
 
 func (s *Server) handleBrowserActive(w http.ResponseWriter, r *http.Request) {
@@ -376,12 +781,12 @@ func (s *Server) handleBrowserActive(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.WindowClass == "" || req.URL == "" {
-		http.Error(w, "window_class and url are required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "window_class and url are required")
 		return
 	}
 
@@ -398,22 +803,28 @@ func (s *Server) handleSetBrowserAllowlist(w http.ResponseWriter, r *http.Reques
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.WindowClass == "" {
-		http.Error(w, "window_class is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "window_class is required")
+		return
+	}
+
+	// Blocking needs no approval; allowing lets the browser's URL rules match
+	if req.Allowed && s.approvals.Enabled() {
+		s.submitForApproval(w, approval.Entry{Kind: approval.KindBrowser, Value: req.WindowClass})
 		return
 	}
 
 	if err := s.configMgr.AddBrowserWindowClass(req.WindowClass); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
 	if err := s.configMgr.SetBrowserBlocked(req.WindowClass, !req.Allowed); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -434,13 +845,13 @@ func (s *Server) handleBrowserStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if windowClass == "" {
-		http.Error(w, "window_class is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "window_class is required")
 		return
 	}
 
 	ctx, ok, fresh := s.windowMgr.GetBrowserContextStatus(windowClass)
 	if !ok {
-		http.Error(w, "browser context not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "browser context not found")
 		return
 	}
 
@@ -473,12 +884,22 @@ func (s *Server) handleGetConfig(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 	var cfg config.Config
 	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	if err := cfg.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidConfig, err.Error())
+		return
+	}
+	cfg.KeepCommands(s.configMgr.Get())
+	if approval.Bypasses(s.configMgr.Get(), &cfg) {
+		writeError(w, http.StatusForbidden, codeApprovalRequired, "Allowlist additions and approval settings can't be changed by replacing the config while approval is on")
 		return
 	}
 
 	if err := s.configMgr.Update(&cfg); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -486,23 +907,171 @@ func (s *Server) handleUpdateConfig(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// handleGetSessions lists the sessions served by this daemon
+func (s *Server) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := []session.Info{}
+	if s.sessions != nil {
+		sessions = s.sessions.List()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": sessions,
+	})
+}
+
+// handleSessionRequest forwards /u/<name>/... to the process serving the session
+func (s *Server) handleSessionRequest(w http.ResponseWriter, r *http.Request) {
+	if s.sessions == nil {
+		writeError(w, http.StatusNotFound, codeNotFound, "sessions are not enabled")
+		return
+	}
+	s.sessions.ServeHTTP(w, r)
+}
+
+// handleExportConfig downloads the full config, including all profiles and
+// overlay widgets, as YAML
+func (s *Server) handleExportConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := s.configMgr.Export()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+		return
+	}
+
+	filename := "focusstreamer-config-" + time.Now().Format("20060102-150405") + ".yaml"
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(data)
+}
+
+// handleImportConfig replaces the config with an uploaded YAML export,
+// migrating older formats, and applies overlay and profile changes live
+func (s *Server) handleImportConfig(w http.ResponseWriter, r *http.Request) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxConfigImportSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("failed to read config: %v", err))
+		return
+	}
+
+	previous := s.configMgr.Get()
+	if s.approvals.Enabled() {
+		imported, _, err := s.configMgr.ParseImport(data)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidConfig, err.Error())
+			return
+		}
+		if approval.Bypasses(previous, imported) {
+			writeError(w, http.StatusForbidden, codeApprovalRequired, "Imports can't add allowlist entries or change approval settings while approval is on")
+			return
+		}
+	}
+	cfg, fromVersion, err := s.configMgr.Import(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidConfig, err.Error())
+		return
+	}
+
+	// Rebuild overlay widgets from the imported config
+	if s.overlayMgr != nil {
+		s.overlayMgr.Clear()
+		if err := s.overlayMgr.LoadFromConfig(cfg.Overlay.Widgets); err != nil {
+			logger.WithComponent("api").Warn().Err(err).Msg("Failed to load imported overlay widgets")
+		}
+		s.overlayMgr.SetEnabled(cfg.Overlay.Enabled)
+		s.overlayMgr.SetDisabledGroups(cfg.Overlay.DisabledGroups)
+	}
+
+	if s.onProfileChangeCallback != nil {
+		s.onProfileChangeCallback(cfg.ActiveProfileID)
+	}
+
+	// These are only read at startup
+	restartRequired := cfg.ServerPort != previous.ServerPort ||
+		cfg.Backend != previous.Backend ||
+		cfg.VirtualDisplay != previous.VirtualDisplay
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "success",
+		"from_version":     fromVersion,
+		"version":          config.CurrentVersion,
+		"migrated":         fromVersion < config.CurrentVersion,
+		"profiles":         len(cfg.Profiles),
+		"widgets":          len(cfg.Overlay.Widgets),
+		"restart_required": restartRequired,
+		"backup_dir":       s.configMgr.GetBackupDir(),
+	})
+}
+
+// handleAddPattern validates an allowlist pattern, adds it unless dry_run
+// is set, and reports the open windows it matches
 func (s *Server) handleAddPattern(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Pattern string `json:"pattern"`
+		DryRun  bool   `json:"dry_run,omitempty"` // Only report matches, don't save
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if req.Pattern == "" {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "pattern is required")
 		return
 	}
 
-	if err := s.configMgr.AddPattern(req.Pattern); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	re, err := config.CompilePattern(req.Pattern)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidPattern, err.Error())
 		return
 	}
 
+	matches, total, err := s.windowMgr.WindowsMatchingPattern(re)
+	if err != nil {
+		logger.WithComponent("api").Warn().Err(err).Msg("Pattern dry run failed")
+	}
+
+	// A pattern matching the empty string matches every window
+	var warning string
+	if re.MatchString("") {
+		warning = "pattern matches every window"
+	} else if total > 1 && len(matches) == total {
+		warning = "pattern matches every open window"
+	}
+
+	response := map[string]interface{}{
+		"status":        "success",
+		"saved":         !req.DryRun,
+		"matches":       matches,
+		"total_windows": total,
+	}
+	if warning != "" {
+		response["warning"] = warning
+	}
+
+	status := http.StatusOK
+	switch {
+	case req.DryRun:
+	case s.approvals.Enabled():
+		entry, err := s.approvals.Submit(approval.Entry{Kind: approval.KindPattern, Value: req.Pattern, Source: "api"})
+		if err != nil {
+			writeError(w, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+			return
+		}
+		response["status"] = "pending"
+		response["saved"] = false
+		response["pending"] = entry
+		status = http.StatusAccepted
+	default:
+		if err := s.configMgr.AddPattern(req.Pattern); err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
 }
 
 func (s *Server) handleRemovePattern(w http.ResponseWriter, r *http.Request) {
@@ -511,12 +1080,12 @@ func (s *Server) handleRemovePattern(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
 	if err := s.configMgr.RemovePattern(req.Pattern); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -533,7 +1102,7 @@ func (s *Server) handleAddURLRule(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
@@ -541,7 +1110,7 @@ func (s *Server) handleAddURLRule(w http.ResponseWriter, r *http.Request) {
 	if ruleID == "" {
 		generated, err := generateRuleID()
 		if err != nil {
-			http.Error(w, "failed to generate rule id", http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, codeInternal, "failed to generate rule id")
 			return
 		}
 		ruleID = generated
@@ -554,8 +1123,17 @@ func (s *Server) handleAddURLRule(w http.ResponseWriter, r *http.Request) {
 		Description: req.Description,
 	}
 
+	if s.approvals.Enabled() {
+		if err := rule.Validate(); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+		s.submitForApproval(w, approval.Entry{Kind: approval.KindURLRule, Value: rule.Pattern, URLRule: &rule})
+		return
+	}
+
 	if err := s.configMgr.AddURLRule(rule); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
@@ -567,12 +1145,12 @@ func (s *Server) handleRemoveURLRule(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	ruleID := vars["id"]
 	if ruleID == "" {
-		http.Error(w, "rule id is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "rule id is required")
 		return
 	}
 
 	if err := s.configMgr.RemoveURLRule(ruleID); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -584,13 +1162,13 @@ func (s *Server) handleGetPlaceholder(w http.ResponseWriter, r *http.Request) {
 	cfg := s.configMgr.Get()
 
 	if cfg.PlaceholderImagePath == "" {
-		http.Error(w, "No custom placeholder image set", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "No custom placeholder image set")
 		return
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(cfg.PlaceholderImagePath); os.IsNotExist(err) {
-		http.Error(w, "Placeholder image file not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "Placeholder image file not found")
 		return
 	}
 
@@ -619,7 +1197,7 @@ func (s *Server) handleUploadPlaceholder(w http.ResponseWriter, r *http.Request)
 	// Parse multipart form (10MB max)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		log.Error().Err(err).Msg("Failed to parse multipart form")
-		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Failed to parse form: "+err.Error())
 		return
 	}
 
@@ -627,7 +1205,7 @@ func (s *Server) handleUploadPlaceholder(w http.ResponseWriter, r *http.Request)
 	file, header, err := r.FormFile("image")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get image from form")
-		http.Error(w, "Failed to get image: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Failed to get image: "+err.Error())
 		return
 	}
 	defer file.Close()
@@ -635,7 +1213,7 @@ func (s *Server) handleUploadPlaceholder(w http.ResponseWriter, r *http.Request)
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" && ext != ".gif" {
-		http.Error(w, "Invalid image format. Supported: PNG, JPEG, GIF", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid image format. Supported: PNG, JPEG, GIF")
 		return
 	}
 
@@ -653,7 +1231,7 @@ func (s *Server) handleUploadPlaceholder(w http.ResponseWriter, r *http.Request)
 	destFile, err := os.Create(destPath)
 	if err != nil {
 		log.Error().Err(err).Str("path", destPath).Msg("Failed to create placeholder file")
-		http.Error(w, "Failed to save image: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save image: "+err.Error())
 		return
 	}
 	defer destFile.Close()
@@ -661,14 +1239,14 @@ func (s *Server) handleUploadPlaceholder(w http.ResponseWriter, r *http.Request)
 	// Copy file content
 	if _, err := io.Copy(destFile, file); err != nil {
 		log.Error().Err(err).Msg("Failed to copy image data")
-		http.Error(w, "Failed to save image: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save image: "+err.Error())
 		return
 	}
 
 	// Update config with new path
 	if err := s.configMgr.SetPlaceholderImage(destPath); err != nil {
 		log.Error().Err(err).Msg("Failed to update config")
-		http.Error(w, "Failed to save config: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save config: "+err.Error())
 		return
 	}
 
@@ -690,7 +1268,7 @@ func (s *Server) handleDeletePlaceholder(w http.ResponseWriter, r *http.Request)
 	if cfg.PlaceholderImagePath != "" {
 		if err := os.Remove(cfg.PlaceholderImagePath); err != nil && !os.IsNotExist(err) {
 			log.Error().Err(err).Str("path", cfg.PlaceholderImagePath).Msg("Failed to delete placeholder file")
-			http.Error(w, "Failed to delete image: "+err.Error(), http.StatusInternalServerError)
+			writeError(w, http.StatusInternalServerError, codeInternal, "Failed to delete image: "+err.Error())
 			return
 		}
 	}
@@ -698,7 +1276,7 @@ func (s *Server) handleDeletePlaceholder(w http.ResponseWriter, r *http.Request)
 	// Clear config
 	if err := s.configMgr.ClearPlaceholderImage(); err != nil {
 		log.Error().Err(err).Msg("Failed to update config")
-		http.Error(w, "Failed to save config: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save config: "+err.Error())
 		return
 	}
 
@@ -758,7 +1336,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	// Parse multipart form (10MB max)
 	if err := r.ParseMultipartForm(10 << 20); err != nil {
 		log.Error().Err(err).Msg("Failed to parse multipart form")
-		http.Error(w, "Failed to parse form: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Failed to parse form: "+err.Error())
 		return
 	}
 
@@ -766,7 +1344,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	file, header, err := r.FormFile("image")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get image from form")
-		http.Error(w, "Failed to get image: "+err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Failed to get image: "+err.Error())
 		return
 	}
 	defer file.Close()
@@ -774,7 +1352,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	// Validate file extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if ext != ".png" && ext != ".jpg" && ext != ".jpeg" && ext != ".gif" {
-		http.Error(w, "Invalid image format. Supported: PNG, JPEG, GIF", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid image format. Supported: PNG, JPEG, GIF")
 		return
 	}
 
@@ -783,7 +1361,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	placeholderDir := filepath.Join(configDir, "placeholders")
 	if err := os.MkdirAll(placeholderDir, 0755); err != nil {
 		log.Error().Err(err).Str("path", placeholderDir).Msg("Failed to create placeholders directory")
-		http.Error(w, "Failed to create directory: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to create directory: "+err.Error())
 		return
 	}
 
@@ -795,7 +1373,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	destFile, err := os.Create(destPath)
 	if err != nil {
 		log.Error().Err(err).Str("path", destPath).Msg("Failed to create placeholder file")
-		http.Error(w, "Failed to save image: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save image: "+err.Error())
 		return
 	}
 	defer destFile.Close()
@@ -804,7 +1382,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	if _, err := io.Copy(destFile, file); err != nil {
 		log.Error().Err(err).Msg("Failed to copy image data")
 		os.Remove(destPath) // Clean up on failure
-		http.Error(w, "Failed to save image: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save image: "+err.Error())
 		return
 	}
 
@@ -812,7 +1390,7 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	if err := s.configMgr.AddPlaceholderImage(destPath); err != nil {
 		log.Error().Err(err).Msg("Failed to update config")
 		os.Remove(destPath) // Clean up on failure
-		http.Error(w, "Failed to save config: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save config: "+err.Error())
 		return
 	}
 
@@ -826,6 +1404,68 @@ func (s *Server) handleUploadPlaceholders(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// handleGetPlaceholderTheme returns the default placeholder's theme
+func (s *Server) handleGetPlaceholderTheme(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.configMgr.Get().Placeholder)
+}
+
+// handleSetPlaceholderTheme updates the default placeholder's theme. Fields
+// left out of the body keep their current values; the stream picks up the
+// change on its next placeholder frame.
+func (s *Server) handleSetPlaceholderTheme(w http.ResponseWriter, r *http.Request) {
+	cfg := s.configMgr.Get()
+	theme := cfg.Placeholder
+	if err := json.NewDecoder(r.Body).Decode(&theme); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+	if err := theme.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidConfig, err.Error())
+		return
+	}
+
+	cfg.Placeholder = theme
+	if err := s.configMgr.Update(cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save config: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(theme)
+}
+
+// handleGetStreamFilters returns the picture adjustments applied to shared
+// windows
+func (s *Server) handleGetStreamFilters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.configMgr.Get().StreamFilters)
+}
+
+// handleSetStreamFilters updates the picture adjustments; fields left out
+// keep their values, e.g. {"gamma": 1.4}
+func (s *Server) handleSetStreamFilters(w http.ResponseWriter, r *http.Request) {
+	cfg := s.configMgr.Get()
+	filters := cfg.StreamFilters
+	if err := json.NewDecoder(r.Body).Decode(&filters); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+		return
+	}
+	if err := filters.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidConfig, err.Error())
+		return
+	}
+
+	cfg.StreamFilters = filters
+	if err := s.configMgr.Update(cfg); err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save config: "+err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(filters)
+}
+
 func (s *Server) handleGetPlaceholderByID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["id"]
@@ -841,13 +1481,13 @@ func (s *Server) handleGetPlaceholderByID(w http.ResponseWriter, r *http.Request
 	}
 
 	if targetPath == "" {
-		http.Error(w, "Image not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "Image not found")
 		return
 	}
 
 	// Check if file exists
 	if _, err := os.Stat(targetPath); os.IsNotExist(err) {
-		http.Error(w, "Image file not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "Image file not found")
 		return
 	}
 
@@ -886,7 +1526,7 @@ func (s *Server) handleDeletePlaceholderByID(w http.ResponseWriter, r *http.Requ
 	}
 
 	if targetPath == "" {
-		http.Error(w, "Image not found", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "Image not found")
 		return
 	}
 
@@ -896,7 +1536,7 @@ func (s *Server) handleDeletePlaceholderByID(w http.ResponseWriter, r *http.Requ
 	// Remove from active profile config first
 	if err := s.configMgr.RemovePlaceholderImage(targetPath); err != nil {
 		log.Error().Err(err).Msg("Failed to update config")
-		http.Error(w, "Failed to save config: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "Failed to save config: "+err.Error())
 		return
 	}
 
@@ -919,9 +1559,14 @@ func (s *Server) handleDeletePlaceholderByID(w http.ResponseWriter, r *http.Requ
 
 func (s *Server) handleGetStandby(w http.ResponseWriter, r *http.Request) {
 	enabled := s.windowMgr.GetForceStandby()
+	reason := s.windowMgr.GetStandbyReason()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"enabled": enabled,
+		"enabled":         enabled,
+		"desktop_blocked": s.windowMgr.IsDesktopBlocked(), // Standby forced by desktop rules
+		"panicked":        s.windowMgr.IsPanicked(),
+		"reason":          reason,           // Why the stream shows the placeholder, empty while streaming a window
+		"caption":         reason.Caption(), // What viewers are told, with placeholder.show_reason
 	})
 }
 
@@ -934,6 +1579,35 @@ func (s *Server) handleToggleStandby(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleGetPanic(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"panicked": s.windowMgr.IsPanicked(),
+	})
+}
+
+// handlePanic blanks the stream until handleRearm; repeated calls are
+// harmless, so a panic button can be mashed
+func (s *Server) handlePanic(w http.ResponseWriter, r *http.Request) {
+	changed := s.windowMgr.Panic()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"panicked": true,
+		"changed":  changed,
+		"status":   "success",
+	})
+}
+
+func (s *Server) handleRearm(w http.ResponseWriter, r *http.Request) {
+	changed := s.windowMgr.Rearm()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"panicked": false,
+		"changed":  changed,
+		"status":   "success",
+	})
+}
+
 func (s *Server) handleGetAllowlistBypass(w http.ResponseWriter, r *http.Request) {
 	enabled := s.windowMgr.GetAllowlistBypass()
 	w.Header().Set("Content-Type", "application/json")
@@ -942,7 +1616,14 @@ func (s *Server) handleGetAllowlistBypass(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// handleToggleAllowlistBypass toggles the allowlist bypass. While approval
+// is on, turning it on waits for approval; turning it off never does.
 func (s *Server) handleToggleAllowlistBypass(w http.ResponseWriter, r *http.Request) {
+	if !s.windowMgr.GetAllowlistBypass() && s.approvals.Enabled() {
+		s.submitForApproval(w, approval.Entry{Kind: approval.KindBypass, Value: "on"})
+		return
+	}
+
 	newState := s.windowMgr.ToggleAllowlistBypass()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -951,6 +1632,116 @@ func (s *Server) handleToggleAllowlistBypass(w http.ResponseWriter, r *http.Requ
 	})
 }
 
+func (s *Server) handleGetOnAir(w http.ResponseWriter, r *http.Request) {
+	health := s.windowMgr.GetHealthStatus()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"on_air":  health.OnAir,
+		"clients": health.Clients,
+	})
+}
+
+func (s *Server) handleGetStreamClients(w http.ResponseWriter, r *http.Request) {
+	if s.mjpegOut == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "MJPEG output not available")
+		return
+	}
+
+	clients := s.mjpegOut.GetClients()
+	var totalBitrate uint64
+	for _, client := range clients {
+		totalBitrate += client.BitrateBps
+	}
+	maxBitrate, clientMaxBitrate := s.mjpegOut.GetBandwidthLimits()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"clients":                clients,
+		"total_bitrate_bps":      totalBitrate,
+		"max_bitrate_bps":        maxBitrate,
+		"client_max_bitrate_bps": clientMaxBitrate,
+	})
+}
+
+func (s *Server) handleGetOutputs(w http.ResponseWriter, r *http.Request) {
+	if s.outputs == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Output multiplexer not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"outputs": s.outputs.Sinks(),
+	})
+}
+
+// handleUpdateOutput enables or disables an output and sets its FPS limit,
+// e.g. {"enabled": false} or {"max_fps": 5}. Changes last until restart.
+func (s *Server) handleUpdateOutput(w http.ResponseWriter, r *http.Request) {
+	if s.outputs == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Output multiplexer not available")
+		return
+	}
+
+	name := mux.Vars(r)["name"]
+	if _, exists := s.outputs.Sink(name); !exists {
+		writeError(w, http.StatusNotFound, codeNotFound, "Output not found")
+		return
+	}
+
+	var req struct {
+		Enabled *bool `json:"enabled"`
+		MaxFPS  *int  `json:"max_fps"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	if req.MaxFPS != nil {
+		if err := s.outputs.SetMaxFPS(name, *req.MaxFPS); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+			return
+		}
+	}
+	if req.Enabled != nil {
+		if err := s.outputs.SetEnabled(name, *req.Enabled); err != nil {
+			writeError(w, http.StatusNotFound, codeNotFound, err.Error())
+			return
+		}
+	}
+
+	status, _ := s.outputs.Sink(name)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+func (s *Server) handleGetPIIGuard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.piiGuardStatus())
+}
+
+func (s *Server) handleTogglePIIOverride(w http.ResponseWriter, r *http.Request) {
+	guard := s.windowMgr.GetPIIGuard()
+	if guard == nil {
+		writeError(w, http.StatusNotFound, codeNotFound, "PII guard is not enabled")
+		return
+	}
+
+	guard.SetOverride(!guard.Status().Override)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guard.Status())
+}
+
+// piiGuardStatus returns the guard status, or a disabled status if the
+// guard isn't running
+func (s *Server) piiGuardStatus() pii.Status {
+	if guard := s.windowMgr.GetPIIGuard(); guard != nil {
+		return guard.Status()
+	}
+	return pii.Status{Matches: []string{}}
+}
+
 func (s *Server) handleNextPlaceholder(w http.ResponseWriter, r *http.Request) {
 	s.windowMgr.CyclePlaceholder(1)
 	w.Header().Set("Content-Type", "application/json")
@@ -972,7 +1763,7 @@ func (s *Server) handleGetZoom(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleSetZoom(w http.ResponseWriter, r *http.Request) {
 	var req window.ZoomState
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
@@ -987,16 +1778,247 @@ func (s *Server) handleResetZoom(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(newState)
 }
 
+// handleGetScenes lists the configured scenes and the last activated one
+func (s *Server) handleGetScenes(w http.ResponseWriter, r *http.Request) {
+	scenes := s.configMgr.Get().Scenes
+	if scenes == nil {
+		scenes = []config.SceneConfig{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scenes": scenes,
+		"active": s.windowMgr.ActiveScene(),
+	})
+}
+
+// handleActivateScene switches to a scene, with an optional transition
+// overriding the scene's own, e.g. {"transition": "zoom", "duration_ms": 800}
+func (s *Server) handleActivateScene(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var req struct {
+		Transition string `json:"transition,omitempty"`
+		DurationMs int    `json:"duration_ms,omitempty"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
+			return
+		}
+	}
+	if req.DurationMs < 0 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "duration_ms must not be negative")
+		return
+	}
+
+	scene, err := s.windowMgr.ActivateScene(name, req.Transition, time.Duration(req.DurationMs)*time.Millisecond)
+	if err != nil {
+		status, code := http.StatusBadRequest, codeInvalidRequest
+		if _, found := s.configMgr.Get().FindScene(name); !found {
+			status, code = http.StatusNotFound, codeNotFound
+		}
+		writeError(w, status, code, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "success",
+		"scene":  scene,
+	})
+}
+
+// handleGetTimeline returns the stream's segments per shared application and
+// standby period since the server started
+func (s *Server) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.windowMgr.GetTimeline())
+}
+
+// handleGetFocusStats returns the focus time per application of the last
+// days or weeks, e.g. ?period=week&count=4 (default: the last 7 days)
+func (s *Server) handleGetFocusStats(w http.ResponseWriter, r *http.Request) {
+	if s.focusStats == nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "Focus statistics are off (focus_stats.enabled)")
+		return
+	}
+
+	query := r.URL.Query()
+	period := query.Get("period")
+	if period == "" {
+		period = focusstats.PeriodDay
+	}
+	count := 7
+	if period == focusstats.PeriodWeek {
+		count = 4
+	}
+	if c := query.Get("count"); c != "" {
+		n, err := strconv.Atoi(c)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid count")
+			return
+		}
+		count = n
+	}
+
+	report, err := s.focusStats.Report(period, count, time.Now())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
 func (s *Server) handleThumbnail(w http.ResponseWriter, r *http.Request) {
+	format, err := imgenc.Negotiate(r, imgenc.JPEG)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
 	thumb := s.windowMgr.GetThumbnail(200) // 200px wide thumbnail
 	if thumb == nil {
-		http.Error(w, "No frame available", http.StatusServiceUnavailable)
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "No frame available")
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Type", imgenc.ContentType(format))
 	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
-	jpeg.Encode(w, thumb, &jpeg.Options{Quality: 70})
+	w.Header().Set("Vary", "Accept")
+	imgenc.Encode(w, thumb, format, 70)
+}
+
+// handleGetStreamGeometry returns the size of the last stream frame and the
+// part of it showing content, without the black bars of a zoomed frame
+func (s *Server) handleGetStreamGeometry(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.windowMgr.GetStreamGeometry())
+}
+
+// snapshotTimeout is how long handleStreamSnapshot waits for a frame
+const snapshotTimeout = 3 * time.Second
+
+// handleStreamSnapshot returns the next stream frame at full size, with its
+// content rect ("x,y,width,height") in the X-Content-Rect header
+func (s *Server) handleStreamSnapshot(w http.ResponseWriter, r *http.Request) {
+	format, err := imgenc.Negotiate(r, imgenc.JPEG)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), snapshotTimeout)
+	defer cancel()
+	frame, geometry, err := s.windowMgr.Snapshot(ctx)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, codeUnavailable, "No frame available: "+err.Error())
+		return
+	}
+	defer framepool.Put(frame)
+
+	w.Header().Set("Content-Type", imgenc.ContentType(format))
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("X-Content-Rect", geometry.Content.String())
+	imgenc.Encode(w, frame, format, 90)
+}
+
+// thumbnailPushInterval is the fastest handleThumbnailSocket pushes
+// thumbnails to a client
+const thumbnailPushInterval = 500 * time.Millisecond
+
+// handleThumbnailSocket pushes the unzoomed minimap thumbnail over a
+// WebSocket as binary image messages (JPEG, or ?format=webp), only when it
+// changes and at most every thumbnailPushInterval. Clients pause pushes
+// while they don't show it by sending {"paused": true}.
+func (s *Server) handleThumbnailSocket(w http.ResponseWriter, r *http.Request) {
+	format, err := imgenc.Negotiate(r, imgenc.JPEG)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.WithComponent("api").Debug().Err(err).Msg("Thumbnail WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	// Read pause requests until the client goes away
+	paused := make(chan bool, 1)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			var msg struct {
+				Paused bool `json:"paused"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			select {
+			case <-paused:
+			default:
+			}
+			paused <- msg.Paused
+		}
+	}()
+
+	ticker := time.NewTicker(thumbnailPushInterval)
+	defer ticker.Stop()
+
+	// Keep frames coming while pushing, even with no stream viewers
+	release := s.windowMgr.AcquireFrames()
+	defer func() { release() }()
+
+	var lastHash uint64
+	isPaused := false
+	for {
+		select {
+		case <-closed:
+			return
+		case p := <-paused:
+			if p != isPaused {
+				isPaused = p
+				release()
+				if !isPaused {
+					release = s.windowMgr.AcquireFrames()
+				}
+			}
+			// Push at the next tick after resuming, even if unchanged
+			lastHash = 0
+			continue
+		case <-ticker.C:
+		}
+		if isPaused {
+			continue
+		}
+
+		thumb := s.windowMgr.GetThumbnail(200)
+		if thumb == nil {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write(thumb.Pix)
+		sum := h.Sum64()
+		if sum == lastHash {
+			continue
+		}
+		lastHash = sum
+
+		var buf bytes.Buffer
+		if err := imgenc.Encode(&buf, thumb, format, 70); err != nil {
+			logger.WithComponent("api").Debug().Err(err).Msg("Failed to encode thumbnail")
+			continue
+		}
+		conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		if err := conn.WriteMessage(websocket.BinaryMessage, buf.Bytes()); err != nil {
+			return
+		}
+	}
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -1019,12 +2041,24 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	if s.mjpegOut != nil && !s.mjpegOut.IsRunning() {
 		overallHealthy = false
 	}
+	if streamHealth.Watchdog != nil && streamHealth.Watchdog.Stalled {
+		overallHealthy = false
+	}
+	if !streamHealth.Connection.Connected {
+		overallHealthy = false
+	}
 
 	status := "healthy"
 	if !overallHealthy {
 		status = "degraded"
 	}
 
+	var dndStatus *dnd.Status
+	if s.suppressor != nil {
+		st := s.suppressor.Status()
+		dndStatus = &st
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"status":  status,
@@ -1034,8 +2068,28 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 			"healthy":              streamHealth.IsHealthy,
 			"last_frame_age":       streamHealth.FrameAge,
 			"consecutive_failures": streamHealth.ConsecutiveFailures,
+			"capture_failures":     streamHealth.CaptureFailures,
+			"frame_pool_hits":      streamHealth.FramePoolHits,
+			"frame_pool_misses":    streamHealth.FramePoolMisses,
+			"clients":              streamHealth.Clients,
+			"on_air":               streamHealth.OnAir,
+			"suspended":            streamHealth.Suspended,
+			"fps":                  streamHealth.FPS,
+			"throttle":             streamHealth.Throttle,
+			"pipeline":             streamHealth.Pipeline,
+			"compose":              streamHealth.Compose,
+		},
+		"window_backend": map[string]interface{}{
+			"name":       streamHealth.Backend,
+			"chain":      streamHealth.Backends,
+			"connection": streamHealth.Connection,
 		},
-		"mjpeg": mjpegStats,
+		"capture_watchdog": streamHealth.Watchdog,
+		"portal_session":   s.windowMgr.GetPortalSession(),
+		"capture_workers":  streamHealth.CaptureWorkers,
+		"pii_guard":        s.piiGuardStatus(),
+		"do_not_disturb":   dndStatus,
+		"mjpeg":            mjpegStats,
 	})
 }
 
@@ -1154,19 +2208,19 @@ func (s *Server) handleCreateWidget(w http.ResponseWriter, r *http.Request) {
 	var req map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error decoding create widget request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
 	widgetType, ok := req["type"].(string)
 	if !ok {
-		http.Error(w, "missing or invalid 'type' field", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "missing or invalid 'type' field")
 		return
 	}
 
 	widgetID, ok := req["id"].(string)
 	if !ok {
-		http.Error(w, "missing or invalid 'id' field", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "missing or invalid 'id' field")
 		return
 	}
 
@@ -1176,14 +2230,14 @@ func (s *Server) handleCreateWidget(w http.ResponseWriter, r *http.Request) {
 	widget, err := s.overlayMgr.CreateWidget(widgetType, widgetID, req)
 	if err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error creating widget: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
 	// Add to manager
 	if err := s.overlayMgr.AddWidget(widget); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error adding widget: %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -1206,7 +2260,7 @@ func (s *Server) handleUpdateWidget(w http.ResponseWriter, r *http.Request) {
 	var config map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error decoding update widget request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
@@ -1214,7 +2268,7 @@ func (s *Server) handleUpdateWidget(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.overlayMgr.UpdateWidget(widgetID, config); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error updating widget: %v", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
 		return
 	}
 
@@ -1227,7 +2281,7 @@ func (s *Server) handleUpdateWidget(w http.ResponseWriter, r *http.Request) {
 	// Get updated widget config
 	widget, exists := s.overlayMgr.GetWidget(widgetID)
 	if !exists {
-		http.Error(w, "widget not found after update", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, "widget not found after update")
 		return
 	}
 
@@ -1245,7 +2299,7 @@ func (s *Server) handleDeleteWidget(w http.ResponseWriter, r *http.Request) {
 
 	if err := s.overlayMgr.RemoveWidget(widgetID); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error removing widget: %v", err)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
 		return
 	}
 
@@ -1261,6 +2315,44 @@ func (s *Server) handleDeleteWidget(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// handleGetWidgetGroups lists the widget groups and whether each is shown
+func (s *Server) handleGetWidgetGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"groups": s.overlayMgr.GetGroups(),
+	})
+}
+
+// handleSetWidgetGroupEnabled shows or hides every widget in a group
+func (s *Server) handleSetWidgetGroupEnabled(w http.ResponseWriter, r *http.Request) {
+	group := mux.Vars(r)["group"]
+
+	var req struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+
+	s.overlayMgr.SetGroupEnabled(group, req.Enabled)
+
+	// Update config to persist
+	cfg := s.configMgr.Get()
+	cfg.Overlay.DisabledGroups = s.overlayMgr.DisabledGroups()
+	if err := s.configMgr.Update(cfg); err != nil {
+		logger.WithComponent("overlay").Info().Msgf("Error saving config: %v", err)
+		// Don't fail the request, the group is already toggled
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"group":   group,
+		"enabled": req.Enabled,
+		"status":  "success",
+	})
+}
+
 func (s *Server) handleSetOverlayEnabled(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Enabled bool `json:"enabled"`
@@ -1268,7 +2360,7 @@ func (s *Server) handleSetOverlayEnabled(w http.ResponseWriter, r *http.Request)
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		logger.WithComponent("overlay").Info().Msgf("Error decoding set overlay enabled request: %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
@@ -1327,18 +2419,18 @@ func (s *Server) handleCreateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "Profile name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Profile name is required")
 		return
 	}
 
 	profile, err := s.configMgr.CreateProfile(req.Name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error())
 		return
 	}
 
@@ -1350,7 +2442,7 @@ func (s *Server) handleCreateProfile(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleGetActiveProfile(w http.ResponseWriter, r *http.Request) {
 	profile := s.configMgr.GetActiveProfile()
 	if profile == nil {
-		http.Error(w, "No active profile", http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, "No active profile")
 		return
 	}
 
@@ -1364,17 +2456,17 @@ func (s *Server) handleSetActiveProfile(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.ProfileID == "" {
-		http.Error(w, "Profile ID is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Profile ID is required")
 		return
 	}
 
 	if err := s.configMgr.SetActiveProfile(req.ProfileID); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
 		return
 	}
 
@@ -1396,7 +2488,7 @@ func (s *Server) handleGetProfile(w http.ResponseWriter, r *http.Request) {
 
 	profile, err := s.configMgr.GetProfile(profileID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
 		return
 	}
 
@@ -1410,15 +2502,23 @@ func (s *Server) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
 
 	var profile config.Profile
 	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
 	// Ensure the ID matches the URL
 	profile.ID = profileID
 
+	if s.approvals.Enabled() {
+		before, _ := s.configMgr.GetProfile(profileID)
+		if approval.AddsEntries(before, &profile) {
+			writeError(w, http.StatusForbidden, codeApprovalRequired, "Allowlist additions need approval; add them through /api/applications/allowlist, /api/config/patterns or /api/config/url-rules")
+			return
+		}
+	}
+
 	if err := s.configMgr.UpdateProfile(&profile); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, http.StatusNotFound, codeNotFound, err.Error())
 		return
 	}
 
@@ -1431,7 +2531,7 @@ func (s *Server) handleDeleteProfile(w http.ResponseWriter, r *http.Request) {
 	profileID := vars["id"]
 
 	if err := s.configMgr.DeleteProfile(profileID); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
@@ -1448,18 +2548,18 @@ func (s *Server) handleDuplicateProfile(w http.ResponseWriter, r *http.Request)
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "Invalid JSON body")
 		return
 	}
 
 	if req.Name == "" {
-		http.Error(w, "New profile name is required", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "New profile name is required")
 		return
 	}
 
 	newProfile, err := s.configMgr.DuplicateProfile(profileID, req.Name)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
 		return
 	}
 
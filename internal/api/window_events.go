@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// sseKeepaliveInterval is how often handleWindowEvents writes a comment
+// while focus doesn't change, so proxies don't close the idle stream
+const sseKeepaliveInterval = 15 * time.Second
+
+// handleWindowEvents streams focus changes as Server-Sent Events, the same
+// window objects /api/window/stream sends over its WebSocket, for clients
+// behind proxies that block WebSockets and for scripts:
+//
+//	curl -N http://localhost:8080/api/window/events
+//
+// Each change is an event named "window" with the window as JSON data,
+// starting with the window focused when the client connects.
+func (s *Server) handleWindowEvents(w http.ResponseWriter, r *http.Request) {
+	log := logger.WithComponent("api")
+	rc := http.NewResponseController(w)
+
+	// Subscribe before sending the current window, so no change is lost
+	// between the two
+	updates := s.windowMgr.Subscribe()
+	defer s.windowMgr.Unsubscribe(updates)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Don't let nginx buffer events
+	w.WriteHeader(http.StatusOK)
+
+	send := func(data []byte) error {
+		if _, err := fmt.Fprintf(w, "event: window\ndata: %s\n\n", data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	// Tell EventSource to reconnect after 3 seconds if the stream drops
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		log.Debug().Err(err).Msg("Window events need a flushable response")
+		return
+	}
+
+	if current := s.windowMgr.GetCurrentWindow(); current != nil {
+		data, err := json.Marshal(current)
+		if err != nil || send(data) != nil {
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(sseKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case window, ok := <-updates:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(window)
+			if err != nil {
+				log.Debug().Err(err).Msg("Failed to encode window event")
+				continue
+			}
+			if err := send(data); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,371 @@
+// Package approval implements two-person approval of allowlist additions:
+// while allowlist_approval is enabled, applications, patterns and URL rules
+// added through the API or KRunner wait in a queue until a second party
+// approves them with the approver secret, optionally told about each one by
+// a webhook. So do the other changes that widen what is streamed: turning
+// on the allowlist bypass, allowing a browser and streaming an external
+// source. Pending entries are kept in memory, so they end with the daemon
+// without being applied.
+package approval
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// Kinds of allowlist entries
+const (
+	KindApp     = "app"      // Application class
+	KindPattern = "pattern"  // Class and title pattern
+	KindURLRule = "url_rule" // Browser URL rule
+	KindBrowser = "browser"  // Browser window class taken off the blocked list
+	KindBypass  = "bypass"   // Allowlist bypass turned on; applied through OnApprove
+	KindSource  = "source"   // External source streamed; applied through OnApprove
+)
+
+const (
+	// maxPending bounds the entries waiting for approval
+	maxPending = 100
+
+	webhookTimeout = 10 * time.Second
+)
+
+var (
+	// ErrNotFound is returned for an unknown or already decided entry
+	ErrNotFound = errors.New("no pending allowlist entry with that id")
+
+	// ErrQueueFull is returned when maxPending entries are waiting
+	ErrQueueFull = errors.New("too many allowlist entries waiting for approval")
+)
+
+// Entry is an allowlist addition waiting for approval
+type Entry struct {
+	ID          string          `json:"id"`
+	Kind        string          `json:"kind"`
+	Value       string          `json:"value"`              // App class or pattern; the rule's pattern for URL rules
+	URLRule     *config.UrlRule `json:"url_rule,omitempty"` // The rule, for URL rules
+	ProfileID   string          `json:"profile_id"`         // Profile the entry is added to once approved
+	Source      string          `json:"source"`             // Where it was requested: api or krunner
+	RequestedAt time.Time       `json:"requested_at"`
+}
+
+// Queue holds allowlist entries until they are approved or rejected
+type Queue struct {
+	configMgr *config.Manager
+	client    *http.Client
+
+	mu      sync.Mutex
+	pending []Entry // Oldest first
+
+	applyMu sync.Mutex // Keeps approvals from overwriting each other's profile update
+	actions map[string]func(Entry) error
+}
+
+// NewQueue creates an empty queue
+func NewQueue(configMgr *config.Manager) *Queue {
+	return &Queue{
+		configMgr: configMgr,
+		client:    &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// OnApprove sets how approved entries of a kind that changes live state
+// rather than the config (KindBypass, KindSource) are applied
+func (q *Queue) OnApprove(kind string, apply func(Entry) error) {
+	q.applyMu.Lock()
+	defer q.applyMu.Unlock()
+	if q.actions == nil {
+		q.actions = make(map[string]func(Entry) error)
+	}
+	q.actions[kind] = apply
+}
+
+// Enabled reports whether allowlist additions need approval
+func (q *Queue) Enabled() bool {
+	return q.configMgr.Get().AllowlistApproval.Enabled
+}
+
+// Submit queues an entry for the active profile and announces it to the
+// webhook. An entry already waiting with the same kind, value and profile is
+// returned instead of queueing it twice.
+func (q *Queue) Submit(entry Entry) (Entry, error) {
+	cfg := q.configMgr.Get()
+	entry.ProfileID = cfg.ActiveProfileID
+	if entry.Kind == KindApp || entry.Kind == KindBrowser {
+		entry.Value = strings.ToLower(entry.Value)
+	}
+
+	q.mu.Lock()
+	for _, pending := range q.pending {
+		if pending.Kind == entry.Kind && pending.Value == entry.Value && pending.ProfileID == entry.ProfileID {
+			q.mu.Unlock()
+			return pending, nil
+		}
+	}
+	if len(q.pending) >= maxPending {
+		q.mu.Unlock()
+		return Entry{}, ErrQueueFull
+	}
+	id, err := newID()
+	if err != nil {
+		q.mu.Unlock()
+		return Entry{}, err
+	}
+	entry.ID = id
+	entry.RequestedAt = time.Now()
+	q.pending = append(q.pending, entry)
+	q.mu.Unlock()
+
+	logger.WithComponent("approval").Info().
+		Str("id", entry.ID).
+		Str("kind", entry.Kind).
+		Str("value", entry.Value).
+		Str("profile_id", entry.ProfileID).
+		Str("source", entry.Source).
+		Msg("Allowlist entry waiting for approval")
+
+	if webhook := cfg.AllowlistApproval.WebhookURL; webhook != "" {
+		go q.announce(webhook, baseURL(cfg), entry)
+	}
+	return entry, nil
+}
+
+// Pending returns the entries waiting for approval, oldest first
+func (q *Queue) Pending() []Entry {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return slices.Clone(q.pending)
+}
+
+// Authorize reports whether secret is the configured approver secret
+func (q *Queue) Authorize(secret string) bool {
+	want := q.configMgr.Get().AllowlistApproval.SecretSHA256
+	if secret == "" || want == "" {
+		return false
+	}
+	got := config.HashApprovalSecret(secret)
+	return subtle.ConstantTimeCompare([]byte(got), []byte(strings.ToLower(want))) == 1
+}
+
+// Approve removes an entry from the queue and adds it to its profile's
+// allowlist
+func (q *Queue) Approve(id string) (Entry, error) {
+	entry, err := q.take(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := q.apply(entry); err != nil {
+		// Keep it pending so approving can be retried
+		q.mu.Lock()
+		q.pending = append(q.pending, entry)
+		q.mu.Unlock()
+		return Entry{}, fmt.Errorf("failed to apply allowlist entry: %w", err)
+	}
+	logger.WithComponent("approval").Info().
+		Str("id", entry.ID).
+		Str("kind", entry.Kind).
+		Str("value", entry.Value).
+		Msg("Allowlist entry approved")
+	return entry, nil
+}
+
+// Reject removes an entry from the queue without applying it
+func (q *Queue) Reject(id string) (Entry, error) {
+	entry, err := q.take(id)
+	if err != nil {
+		return Entry{}, err
+	}
+	logger.WithComponent("approval").Info().
+		Str("id", entry.ID).
+		Str("kind", entry.Kind).
+		Str("value", entry.Value).
+		Msg("Allowlist entry rejected")
+	return entry, nil
+}
+
+// take removes an entry from the queue
+func (q *Queue) take(id string) (Entry, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := slices.IndexFunc(q.pending, func(e Entry) bool { return e.ID == id })
+	if i < 0 {
+		return Entry{}, ErrNotFound
+	}
+	entry := q.pending[i]
+	q.pending = slices.Delete(q.pending, i, i+1)
+	return entry, nil
+}
+
+// apply adds an approved entry to the profile it was requested for, which
+// may no longer be the active one, or hands it to its OnApprove action
+func (q *Queue) apply(entry Entry) error {
+	q.applyMu.Lock()
+	defer q.applyMu.Unlock()
+
+	if action, ok := q.actions[entry.Kind]; ok {
+		return action(entry)
+	}
+
+	profile, err := q.configMgr.GetProfile(entry.ProfileID)
+	if err != nil {
+		return err
+	}
+
+	// The profile's slices are shared with the config; Clip makes append
+	// copy them
+	switch entry.Kind {
+	case KindApp:
+		if slices.Contains(profile.AllowlistedApps, entry.Value) {
+			return nil
+		}
+		profile.AllowlistedApps = append(slices.Clip(profile.AllowlistedApps), entry.Value)
+	case KindPattern:
+		if slices.Contains(profile.AllowlistPatterns, entry.Value) {
+			return nil
+		}
+		profile.AllowlistPatterns = append(slices.Clip(profile.AllowlistPatterns), entry.Value)
+	case KindURLRule:
+		if entry.URLRule == nil {
+			return fmt.Errorf("url rule entry without a rule")
+		}
+		if slices.ContainsFunc(profile.AllowlistURLRules, func(r config.UrlRule) bool { return r.ID == entry.URLRule.ID }) {
+			return nil
+		}
+		profile.AllowlistURLRules = append(slices.Clip(profile.AllowlistURLRules), *entry.URLRule)
+	case KindBrowser:
+		if !slices.Contains(profile.BrowserWindowClasses, entry.Value) {
+			profile.BrowserWindowClasses = append(slices.Clip(profile.BrowserWindowClasses), entry.Value)
+		}
+		profile.BrowserBlockedClasses = slices.DeleteFunc(slices.Clone(profile.BrowserBlockedClasses), func(class string) bool {
+			return class == entry.Value
+		})
+	default:
+		return fmt.Errorf("unknown entry kind: %s", entry.Kind)
+	}
+	return q.configMgr.UpdateProfile(profile)
+}
+
+// announce posts a pending entry to the webhook, e.g.
+//
+//	{"event": "allowlist_entry_pending", "entry": {...},
+//	 "approve_url": "https://.../api/applications/allowlist/pending/<id>/approve",
+//	 "reject_url": "https://.../api/applications/allowlist/pending/<id>/reject"}
+//
+// The approver POSTs to either link with the secret as a bearer token.
+func (q *Queue) announce(webhook, base string, entry Entry) {
+	entryURL := base + "/api/applications/allowlist/pending/" + entry.ID
+	body, err := json.Marshal(map[string]interface{}{
+		"event":       "allowlist_entry_pending",
+		"entry":       entry,
+		"approve_url": entryURL + "/approve",
+		"reject_url":  entryURL + "/reject",
+	})
+	if err != nil {
+		return
+	}
+
+	log := logger.WithComponent("approval")
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid approval webhook")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		log.Warn().Err(err).Str("id", entry.ID).Msg("Approval webhook failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Warn().Int("status", resp.StatusCode).Str("id", entry.ID).Msg("Approval webhook refused the entry")
+	}
+}
+
+// baseURL is where the approve and reject links point: the public URL when
+// one is configured, this machine otherwise
+func baseURL(cfg *config.Config) string {
+	if public := cfg.StreamAccess.PublicURL; public != "" {
+		return strings.TrimSuffix(public, "/")
+	}
+	return fmt.Sprintf("http://localhost:%d", cfg.ServerPort)
+}
+
+// newID returns a random entry ID
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate entry id: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// AddsEntries reports whether after allowlists anything before doesn't:
+// an application, pattern, title pattern or URL rule, or a browser taken
+// off the blocked list
+func AddsEntries(before, after *config.Profile) bool {
+	if before == nil {
+		before = &config.Profile{}
+	}
+	newIn := func(old, updated []string) bool {
+		for _, v := range updated {
+			if !slices.Contains(old, v) {
+				return true
+			}
+		}
+		return false
+	}
+	if newIn(before.AllowlistedApps, after.AllowlistedApps) ||
+		newIn(before.AllowlistPatterns, after.AllowlistPatterns) ||
+		newIn(before.AllowlistTitlePatterns, after.AllowlistTitlePatterns) {
+		return true
+	}
+	for _, rule := range after.AllowlistURLRules {
+		if !slices.Contains(before.AllowlistURLRules, rule) {
+			return true
+		}
+	}
+	return newIn(after.BrowserBlockedClasses, before.BrowserBlockedClasses)
+}
+
+// Bypasses reports whether replacing the config before with after would
+// get around approval while it is enabled: by turning it off, changing the
+// approver secret or webhook, or adding allowlist entries to any profile
+func Bypasses(before, after *config.Config) bool {
+	if !before.AllowlistApproval.Enabled {
+		return false
+	}
+	if after.AllowlistApproval != before.AllowlistApproval {
+		return true
+	}
+	for i := range after.Profiles {
+		var old *config.Profile
+		for j := range before.Profiles {
+			if before.Profiles[j].ID == after.Profiles[i].ID {
+				old = &before.Profiles[j]
+				break
+			}
+		}
+		if AddsEntries(old, &after.Profiles[i]) {
+			return true
+		}
+	}
+	return false
+}
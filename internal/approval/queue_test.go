@@ -0,0 +1,241 @@
+package approval
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// newTestQueue returns a queue over a config in a temporary directory, with
+// approval on and no webhook
+func newTestQueue(t *testing.T) (*Queue, *config.Manager) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	configMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := *configMgr.Get()
+	cfg.AllowlistApproval = config.AllowlistApprovalConfig{Enabled: true, SecretSHA256: config.HashApprovalSecret("secret")}
+	if err := configMgr.Update(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	return NewQueue(configMgr), configMgr
+}
+
+func activeProfile(t *testing.T, configMgr *config.Manager) *config.Profile {
+	t.Helper()
+	profile, err := configMgr.GetProfile(configMgr.Get().ActiveProfileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return profile
+}
+
+func TestQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		submit   []Entry
+		decide   func(q *Queue, submitted []Entry) error
+		wantErr  error
+		pending  int      // Entries left waiting
+		wantApps []string // Applications added to the active profile
+	}{
+		{
+			name:     "approve",
+			submit:   []Entry{{Kind: KindApp, Value: "Firefox"}},
+			decide:   func(q *Queue, e []Entry) error { _, err := q.Approve(e[0].ID); return err },
+			wantApps: []string{"firefox"},
+		},
+		{
+			name:   "reject",
+			submit: []Entry{{Kind: KindApp, Value: "firefox"}},
+			decide: func(q *Queue, e []Entry) error { _, err := q.Reject(e[0].ID); return err },
+		},
+		{
+			name:    "duplicate waits once",
+			submit:  []Entry{{Kind: KindApp, Value: "firefox"}, {Kind: KindApp, Value: "FIREFOX"}, {Kind: KindPattern, Value: "firefox"}},
+			pending: 2,
+		},
+		{
+			name:    "unknown id",
+			submit:  []Entry{{Kind: KindApp, Value: "firefox"}},
+			decide:  func(q *Queue, e []Entry) error { _, err := q.Approve("nope"); return err },
+			wantErr: ErrNotFound,
+			pending: 1,
+		},
+		{
+			name:   "decided twice",
+			submit: []Entry{{Kind: KindApp, Value: "firefox"}},
+			decide: func(q *Queue, e []Entry) error {
+				if _, err := q.Reject(e[0].ID); err != nil {
+					return err
+				}
+				_, err := q.Approve(e[0].ID)
+				return err
+			},
+			wantErr: ErrNotFound,
+		},
+		{
+			name:   "failed apply stays pending",
+			submit: []Entry{{Kind: KindURLRule, Value: "docs.example.com"}},
+			decide: func(q *Queue, e []Entry) error {
+				_, err := q.Approve(e[0].ID)
+				if err == nil {
+					return errors.New("URL rule without a rule was applied")
+				}
+				return nil
+			},
+			pending: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, configMgr := newTestQueue(t)
+			before := activeProfile(t, configMgr).AllowlistedApps
+
+			var submitted []Entry
+			for _, entry := range tt.submit {
+				entry, err := q.Submit(entry)
+				if err != nil {
+					t.Fatal(err)
+				}
+				submitted = append(submitted, entry)
+			}
+			if tt.decide != nil {
+				if err := tt.decide(q, submitted); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			}
+
+			if got := len(q.Pending()); got != tt.pending {
+				t.Errorf("%d entries pending, want %d", got, tt.pending)
+			}
+			added := slices.DeleteFunc(slices.Clone(activeProfile(t, configMgr).AllowlistedApps), func(app string) bool {
+				return slices.Contains(before, app)
+			})
+			if !slices.Equal(added, tt.wantApps) {
+				t.Errorf("added apps %v, want %v", added, tt.wantApps)
+			}
+		})
+	}
+}
+
+func TestQueueFull(t *testing.T) {
+	q, _ := newTestQueue(t)
+	for i := range maxPending {
+		if _, err := q.Submit(Entry{Kind: KindApp, Value: fmt.Sprintf("app-%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := q.Submit(Entry{Kind: KindApp, Value: "one-more"}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("error = %v, want ErrQueueFull", err)
+	}
+	// A duplicate of a waiting entry is still answered
+	if _, err := q.Submit(Entry{Kind: KindApp, Value: "app-0"}); err != nil {
+		t.Errorf("duplicate of a waiting entry: %v", err)
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	q, _ := newTestQueue(t)
+	for secret, want := range map[string]bool{"secret": true, "Secret": false, "": false, "secret ": false} {
+		if got := q.Authorize(secret); got != want {
+			t.Errorf("Authorize(%q) = %v, want %v", secret, got, want)
+		}
+	}
+}
+
+// TestConcurrentApprovals approves entries for the same profile from
+// several goroutines, as approvers and KRunner can, while others submit and
+// list; every approved entry must end up in the profile. Run with -race.
+func TestConcurrentApprovals(t *testing.T) {
+	q, configMgr := newTestQueue(t)
+	before := len(activeProfile(t, configMgr).AllowlistedApps)
+	const workers, perWorker = 4, 10
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWorker {
+				entry, err := q.Submit(Entry{Kind: KindApp, Value: fmt.Sprintf("app-%d-%d", w, i)})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if _, err := q.Approve(entry.ID); err != nil {
+					t.Error(err)
+				}
+				q.Pending()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(q.Pending()); got != 0 {
+		t.Errorf("%d entries still pending", got)
+	}
+	apps := activeProfile(t, configMgr).AllowlistedApps
+	if got := len(apps) - before; got != workers*perWorker {
+		t.Errorf("%d of %d approved apps in the profile", got, workers*perWorker)
+	}
+}
+
+func TestBypasses(t *testing.T) {
+	base := func() *config.Config {
+		return &config.Config{
+			AllowlistApproval: config.AllowlistApprovalConfig{Enabled: true, SecretSHA256: "abc"},
+			Profiles: []config.Profile{{
+				ID:                    "default",
+				AllowlistedApps:       []string{"code"},
+				AllowlistURLRules:     []config.UrlRule{{ID: "docs", Pattern: "docs.example.com"}},
+				BrowserBlockedClasses: []string{"firefox"},
+			}},
+		}
+	}
+	tests := []struct {
+		name   string
+		change func(before, after *config.Config)
+		want   bool
+	}{
+		{"unchanged", func(b, a *config.Config) {}, false},
+		{"approval off before", func(b, a *config.Config) {
+			b.AllowlistApproval.Enabled = false
+			a.Profiles[0].AllowlistedApps = []string{"code", "firefox"}
+		}, false},
+		{"turned off", func(b, a *config.Config) { a.AllowlistApproval.Enabled = false }, true},
+		{"secret changed", func(b, a *config.Config) { a.AllowlistApproval.SecretSHA256 = "def" }, true},
+		{"webhook changed", func(b, a *config.Config) { a.AllowlistApproval.WebhookURL = "http://example.com" }, true},
+		{"app added", func(b, a *config.Config) { a.Profiles[0].AllowlistedApps = []string{"code", "firefox"} }, true},
+		{"app removed", func(b, a *config.Config) { a.Profiles[0].AllowlistedApps = nil }, false},
+		{"title pattern added", func(b, a *config.Config) { a.Profiles[0].AllowlistTitlePatterns = []string{".*"} }, true},
+		{"url rule changed", func(b, a *config.Config) {
+			a.Profiles[0].AllowlistURLRules = []config.UrlRule{{ID: "docs", Pattern: ".*"}}
+		}, true},
+		{"new profile with apps", func(b, a *config.Config) {
+			a.Profiles = append(a.Profiles, config.Profile{ID: "work", AllowlistedApps: []string{"slack"}})
+		}, true},
+		{"new empty profile", func(b, a *config.Config) { a.Profiles = append(a.Profiles, config.Profile{ID: "work"}) }, false},
+		{"browser unblocked", func(b, a *config.Config) { a.Profiles[0].BrowserBlockedClasses = nil }, true},
+		{"browser blocked", func(b, a *config.Config) {
+			a.Profiles[0].BrowserBlockedClasses = []string{"firefox", "chromium"}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := base(), base()
+			tt.change(before, after)
+			if got := Bypasses(before, after); got != tt.want {
+				t.Errorf("Bypasses = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,348 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+)
+
+// Supported source color spaces for ColorConverter
+const (
+	ColorSpaceSRGB      = "srgb"
+	ColorSpaceDisplayP3 = "display-p3"
+	ColorSpaceBT2020    = "bt2020"
+	ColorSpaceAdobeRGB  = "adobe-rgb"
+)
+
+// encodeLUTSize is the resolution of the linear -> sRGB encoding table
+const encodeLUTSize = 4096
+
+// xyzD50ToSRGB converts D50-adapted XYZ (the ICC profile connection space)
+// to linear sRGB using the Bradford-adapted matrix
+var xyzD50ToSRGB = [9]float64{
+	3.1338561, -1.6168667, -0.4906146,
+	-0.9787684, 1.9161415, 0.0334540,
+	0.0719453, -0.2289914, 1.4052427,
+}
+
+// builtinColorSpaces maps well-known wide-gamut spaces to a linear-RGB to
+// linear-sRGB matrix and a transfer function
+var builtinColorSpaces = map[string]struct {
+	matrix [9]float64
+	decode func(float64) float64
+}{
+	ColorSpaceDisplayP3: {
+		matrix: [9]float64{
+			1.2249, -0.2247, 0,
+			-0.0420, 1.0419, 0,
+			-0.0197, -0.0786, 1.0979,
+		},
+		decode: srgbDecode,
+	},
+	ColorSpaceBT2020: {
+		matrix: [9]float64{
+			1.6605, -0.5876, -0.0728,
+			-0.1246, 1.1329, -0.0083,
+			-0.0182, -0.1006, 1.1187,
+		},
+		decode: func(v float64) float64 { return math.Pow(v, 2.4) },
+	},
+	ColorSpaceAdobeRGB: {
+		matrix: [9]float64{
+			1.3982, -0.3982, 0,
+			0, 1, 0,
+			0, -0.0429, 1.0429,
+		},
+		decode: func(v float64) float64 { return math.Pow(v, 563.0/256.0) },
+	},
+}
+
+// ColorConverter converts captured frames from a monitor/compositor color
+// space to sRGB before encoding. It is built once and applied per frame using
+// lookup tables, so the per-pixel cost is a 3x3 matrix multiply.
+type ColorConverter struct {
+	decode [3][256]float32
+	matrix [9]float32
+	encode [encodeLUTSize]uint8
+}
+
+// NewColorConverter builds a converter for a named source color space.
+// If iccPath is non-empty, the monitor ICC profile takes precedence.
+// Returns nil (no conversion needed) for sRGB sources without a profile.
+func NewColorConverter(sourceColorSpace, iccPath string) (*ColorConverter, error) {
+	if iccPath != "" {
+		return NewColorConverterFromICC(iccPath)
+	}
+
+	space := strings.ToLower(strings.TrimSpace(sourceColorSpace))
+	if space == "" || space == ColorSpaceSRGB {
+		return nil, nil
+	}
+
+	cs, ok := builtinColorSpaces[space]
+	if !ok {
+		return nil, fmt.Errorf("unsupported source color space: %s", sourceColorSpace)
+	}
+
+	c := &ColorConverter{}
+	for ch := 0; ch < 3; ch++ {
+		c.fillDecode(ch, cs.decode)
+	}
+	c.setMatrix(cs.matrix)
+	c.fillEncode()
+	return c, nil
+}
+
+// NewColorConverterFromICC builds a converter from a matrix/TRC ICC profile
+// (the kind produced by monitor calibration tools and EDID-derived profiles)
+func NewColorConverterFromICC(path string) (*ColorConverter, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICC profile: %w", err)
+	}
+
+	tags, err := parseICCTags(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Colorant XYZ values form the RGB -> XYZ(D50) matrix columns
+	var rgbToXYZ [9]float64
+	for col, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		xyz, err := parseICCXYZ(tags[sig])
+		if err != nil {
+			return nil, fmt.Errorf("ICC profile %s: %w", sig, err)
+		}
+		rgbToXYZ[col] = xyz[0]
+		rgbToXYZ[3+col] = xyz[1]
+		rgbToXYZ[6+col] = xyz[2]
+	}
+
+	c := &ColorConverter{}
+	for ch, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		curve, err := parseICCCurve(tags[sig])
+		if err != nil {
+			return nil, fmt.Errorf("ICC profile %s: %w", sig, err)
+		}
+		c.fillDecode(ch, curve)
+	}
+	c.setMatrix(multiply3x3(xyzD50ToSRGB, rgbToXYZ))
+	c.fillEncode()
+	return c, nil
+}
+
+// Apply converts the image to sRGB in place
+func (c *ColorConverter) Apply(img *image.RGBA) {
+	if c == nil || img == nil {
+		return
+	}
+
+	m := c.matrix
+	scale := float32(encodeLUTSize - 1)
+	bounds := img.Bounds()
+	width := bounds.Dx()
+
+	pixconv.ParallelRows(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			rowStart := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			row := img.Pix[rowStart : rowStart+width*4]
+			for i := 0; i < len(row); i += 4 {
+				r := c.decode[0][row[i]]
+				g := c.decode[1][row[i+1]]
+				b := c.decode[2][row[i+2]]
+
+				row[i] = c.encode[clampIndex((m[0]*r+m[1]*g+m[2]*b)*scale)]
+				row[i+1] = c.encode[clampIndex((m[3]*r+m[4]*g+m[5]*b)*scale)]
+				row[i+2] = c.encode[clampIndex((m[6]*r+m[7]*g+m[8]*b)*scale)]
+			}
+		}
+	})
+}
+
+// fillDecode populates the linearization table for one channel
+func (c *ColorConverter) fillDecode(ch int, decode func(float64) float64) {
+	for i := 0; i < 256; i++ {
+		c.decode[ch][i] = float32(decode(float64(i) / 255.0))
+	}
+}
+
+// setMatrix stores the linear conversion matrix
+func (c *ColorConverter) setMatrix(m [9]float64) {
+	for i, v := range m {
+		c.matrix[i] = float32(v)
+	}
+}
+
+// fillEncode populates the linear -> sRGB encoding table
+func (c *ColorConverter) fillEncode() {
+	for i := 0; i < encodeLUTSize; i++ {
+		v := srgbEncode(float64(i) / float64(encodeLUTSize-1))
+		c.encode[i] = uint8(math.Round(v * 255))
+	}
+}
+
+// clampIndex clamps a scaled linear value to a valid encode LUT index
+func clampIndex(v float32) int {
+	if v <= 0 {
+		return 0
+	}
+	if v >= encodeLUTSize-1 {
+		return encodeLUTSize - 1
+	}
+	return int(v + 0.5)
+}
+
+// srgbDecode applies the sRGB EOTF (encoded -> linear)
+func srgbDecode(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// srgbEncode applies the inverse sRGB EOTF (linear -> encoded)
+func srgbEncode(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// multiply3x3 returns a*b for row-major 3x3 matrices
+func multiply3x3(a, b [9]float64) [9]float64 {
+	var out [9]float64
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			out[r*3+c] = a[r*3]*b[c] + a[r*3+1]*b[3+c] + a[r*3+2]*b[6+c]
+		}
+	}
+	return out
+}
+
+// parseICCTags returns the raw tag data keyed by tag signature
+func parseICCTags(data []byte) (map[string][]byte, error) {
+	if len(data) < 132 || string(data[36:40]) != "acsp" {
+		return nil, fmt.Errorf("not a valid ICC profile")
+	}
+
+	count := int(binary.BigEndian.Uint32(data[128:132]))
+	tags := make(map[string][]byte, count)
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(data) {
+			return nil, fmt.Errorf("truncated ICC tag table")
+		}
+		sig := string(data[entry : entry+4])
+		offset := int(binary.BigEndian.Uint32(data[entry+4 : entry+8]))
+		size := int(binary.BigEndian.Uint32(data[entry+8 : entry+12]))
+		if offset < 0 || size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("ICC tag %s out of bounds", sig)
+		}
+		tags[sig] = data[offset : offset+size]
+	}
+	return tags, nil
+}
+
+// parseICCXYZ decodes an XYZType tag
+func parseICCXYZ(tag []byte) ([3]float64, error) {
+	var xyz [3]float64
+	if len(tag) < 20 || string(tag[0:4]) != "XYZ " {
+		return xyz, fmt.Errorf("missing or invalid XYZ tag (only matrix/TRC profiles are supported)")
+	}
+	for i := 0; i < 3; i++ {
+		xyz[i] = s15Fixed16(tag[8+i*4:])
+	}
+	return xyz, nil
+}
+
+// parseICCCurve decodes a curveType or parametricCurveType tag into a
+// transfer function (encoded -> linear)
+func parseICCCurve(tag []byte) (func(float64) float64, error) {
+	if len(tag) < 12 {
+		return nil, fmt.Errorf("missing or invalid TRC tag")
+	}
+
+	switch string(tag[0:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:12]))
+		if len(tag) < 12+n*2 {
+			return nil, fmt.Errorf("truncated curv tag")
+		}
+		switch n {
+		case 0:
+			return func(v float64) float64 { return v }, nil
+		case 1:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:14])) / 256.0
+			return func(v float64) float64 { return math.Pow(v, gamma) }, nil
+		}
+		table := make([]float64, n)
+		for i := range table {
+			table[i] = float64(binary.BigEndian.Uint16(tag[12+i*2:])) / 65535.0
+		}
+		return func(v float64) float64 {
+			pos := v * float64(n-1)
+			i := int(pos)
+			if i >= n-1 {
+				return table[n-1]
+			}
+			frac := pos - float64(i)
+			return table[i]*(1-frac) + table[i+1]*frac
+		}, nil
+
+	case "para":
+		funcType := int(binary.BigEndian.Uint16(tag[8:10]))
+		paramCounts := []int{1, 3, 4, 5, 7}
+		if funcType >= len(paramCounts) || len(tag) < 12+paramCounts[funcType]*4 {
+			return nil, fmt.Errorf("unsupported para tag")
+		}
+		p := make([]float64, 7)
+		for i := 0; i < paramCounts[funcType]; i++ {
+			p[i] = s15Fixed16(tag[12+i*4:])
+		}
+		g, a, b, cc, d, e, f := p[0], p[1], p[2], p[3], p[4], p[5], p[6]
+		switch funcType {
+		case 0:
+			return func(v float64) float64 { return math.Pow(v, g) }, nil
+		case 1:
+			return func(v float64) float64 {
+				if v >= -b/a {
+					return math.Pow(a*v+b, g)
+				}
+				return 0
+			}, nil
+		case 2:
+			return func(v float64) float64 {
+				if v >= -b/a {
+					return math.Pow(a*v+b, g) + cc
+				}
+				return cc
+			}, nil
+		case 3:
+			return func(v float64) float64 {
+				if v >= d {
+					return math.Pow(a*v+b, g)
+				}
+				return cc * v
+			}, nil
+		default:
+			return func(v float64) float64 {
+				if v >= d {
+					return math.Pow(a*v+b, g) + e
+				}
+				return cc*v + f
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unsupported TRC type %q", string(tag[0:4]))
+}
+
+// s15Fixed16 decodes an ICC s15Fixed16Number
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536.0
+}
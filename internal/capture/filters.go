@@ -0,0 +1,129 @@
+package capture
+
+import (
+	"image"
+	"math"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+)
+
+// ImageFilter applies the brightness, contrast, gamma and saturation
+// adjustments of config.StreamFiltersConfig (sharpening is Sharpen).
+// Brightness, contrast and gamma are folded into one lookup table per
+// channel value; saturation mixes each pixel with its luma.
+type ImageFilter struct {
+	lut        [256]uint8
+	saturation int32 // Fixed point, 256 is unchanged
+}
+
+// NewImageFilter builds a filter for the given adjustments, or returns nil
+// if they leave frames unchanged
+func NewImageFilter(cfg config.StreamFiltersConfig) *ImageFilter {
+	if cfg.IsNeutral() {
+		return nil
+	}
+
+	f := &ImageFilter{saturation: int32(math.Round(cfg.Saturation * 256))}
+	for i := range f.lut {
+		v := float64(i) / 255
+		if cfg.Gamma != 1 {
+			v = math.Pow(v, 1/cfg.Gamma)
+		}
+		v = (v-0.5)*cfg.Contrast + 0.5 + cfg.Brightness
+		f.lut[i] = uint8(math.Round(math.Max(0, math.Min(1, v)) * 255))
+	}
+	return f
+}
+
+// Apply adjusts an image in place
+func (f *ImageFilter) Apply(img *image.RGBA) {
+	if f == nil || img == nil {
+		return
+	}
+
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	saturate := f.saturation != 256
+
+	pixconv.ParallelRows(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			rowStart := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			row := img.Pix[rowStart : rowStart+width*4]
+			for i := 0; i < len(row); i += 4 {
+				r, g, b := int32(row[i]), int32(row[i+1]), int32(row[i+2])
+				if saturate {
+					// Rec. 601 luma
+					luma := (77*r + 150*g + 29*b) >> 8
+					r = clampByte(luma + ((r-luma)*f.saturation)>>8)
+					g = clampByte(luma + ((g-luma)*f.saturation)>>8)
+					b = clampByte(luma + ((b-luma)*f.saturation)>>8)
+				}
+				row[i] = f.lut[r]
+				row[i+1] = f.lut[g]
+				row[i+2] = f.lut[b]
+			}
+		}
+	})
+}
+
+// clampByte clamps a channel value to 0-255
+func clampByte(v int32) int32 {
+	return max(0, min(255, v))
+}
+
+const (
+	// MaxSharpen bounds the unsharp mask amount
+	MaxSharpen = 2
+
+	// sharpenThreshold leaves differences this small (flat areas, JPEG
+	// noise) alone so only edges such as glyph outlines are enhanced
+	sharpenThreshold = 2
+)
+
+// Sharpen writes src to dst through a 3x3 unsharp mask, which makes small
+// text crisper after scaling and before JPEG encoding. amount is how much of
+// the difference from the blurred picture is added back (0 to MaxSharpen).
+// dst and src must be the same size and must not overlap.
+func Sharpen(dst, src *image.RGBA, amount float64) {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width == 0 || height == 0 {
+		return
+	}
+	gain := int32(math.Round(min(amount, MaxSharpen) * 256))
+
+	// Byte offsets of each pixel's left and right neighbors, clamped at the
+	// edges
+	left := make([]int, width)
+	right := make([]int, width)
+	for x := range width {
+		left[x] = max(x-1, 0) * 4
+		right[x] = min(x+1, width-1) * 4
+	}
+
+	pixconv.ParallelRows(height, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			above := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+max(y-1, 0)):]
+			row := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+y):]
+			below := src.Pix[src.PixOffset(bounds.Min.X, bounds.Min.Y+min(y+1, height-1)):]
+			out := dst.Pix[dst.PixOffset(dst.Rect.Min.X, dst.Rect.Min.Y+y):]
+
+			for x := range width {
+				i, l, r := x*4, left[x], right[x]
+				for c := 0; c < 3; c++ {
+					sum := int32(above[l+c]) + int32(above[i+c]) + int32(above[r+c]) +
+						int32(row[l+c]) + int32(row[i+c]) + int32(row[r+c]) +
+						int32(below[l+c]) + int32(below[i+c]) + int32(below[r+c])
+					v := int32(row[i+c])
+					diff := v - (sum*7282)>>16 // sum / 9
+					if diff > sharpenThreshold || diff < -sharpenThreshold {
+						v = clampByte(v + (diff*gain)>>8)
+					}
+					out[i+c] = uint8(v)
+				}
+				out[i+3] = row[i+3]
+			}
+		}
+	})
+}
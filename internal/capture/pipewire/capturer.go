@@ -1,6 +1,7 @@
 package pipewire
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"sync"
@@ -12,14 +13,41 @@ import (
 // Capturer implements the capture.Capturer interface using PipeWire
 type Capturer struct {
 	portal   *Portal
-	pipeline *GStreamerSubprocess // Use subprocess instead of CGO-based pipeline
+	streams  []*monitorStream // One per shared monitor; the first is the primary
 	mu       sync.Mutex
 	started  bool
+	dmabuf   config.DMABufConfig // Zero-copy pipeline settings
+	multiple bool                // Share every monitor instead of one
+	template string              // gst-launch pipeline template for copy mode
+
+	// Cancelled by Stop, ending the session supervisor and abandoning any
+	// portal request it is waiting on
+	cancel context.CancelFunc
+
+	// Closed when the session supervisor has returned
+	done chan struct{}
+
+	// Closed when the portal session ended and could not be reopened
+	lost chan struct{}
 }
 
-// NewCapturer creates a new PipeWire capturer
-func NewCapturer() (*Capturer, error) {
-	return &Capturer{}, nil
+// monitorStream is the frame source of one shared monitor and how logical
+// geometry maps onto its frames
+type monitorStream struct {
+	node     PortalStream
+	pipeline frameSource  // In-process stream, or the gst-launch subprocess
+	mapping  scaleMapping // Logical-to-physical conversion for crops
+	mappedW  int          // Frame size the mapping was resolved for
+	mappedH  int
+	located  string // Where the mapping came from: geometryPortal, geometryOutput or geometryNone
+	output   string // Compositor output matched through KWin
+}
+
+// NewCapturer creates a new PipeWire capturer. With cfg.MultiMonitor, the
+// portal asks for every monitor and each window is cropped from the stream
+// of the monitor it is on; otherwise one monitor is shared.
+func NewCapturer(cfg config.CaptureConfig) (*Capturer, error) {
+	return &Capturer{dmabuf: cfg.DMABuf, multiple: cfg.MultiMonitor, template: cfg.PipelineTemplate}, nil
 }
 
 // Start initializes the PipeWire capture session
@@ -33,52 +61,191 @@ func (c *Capturer) Start() error {
 
 	log := logger.WithComponent("pipewire-capturer")
 
-	// Initialize portal
-	portal, err := NewPortal()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sessionEvents.publish(SessionStarting, "")
+	portal, streams, err := c.openSession(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create portal: %w", err)
+		cancel()
+		sessionEvents.publish(SessionClosed, err.Error())
+		return err
 	}
 	c.portal = portal
+	c.streams = streams
+	c.started = true
+	for _, s := range streams {
+		log.Info().
+			Uint32("node_id", s.node.NodeID).
+			Str("mode", s.pipeline.Mode()).
+			Float64("scale_x", s.mapping.scaleX).
+			Float64("scale_y", s.mapping.scaleY).
+			Msg("PipeWire capturer started")
+	}
+
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	c.lost = make(chan struct{})
+	go c.superviseSession(ctx, portal, c.lost, c.done)
+	sessionEvents.publish(SessionActive, "")
+	return nil
+}
+
+// openSession starts a portal screen share and consumes each of its
+// streams. Cancelling ctx abandons a pending portal request.
+func (c *Capturer) openSession(ctx context.Context) (*Portal, []*monitorStream, error) {
+	portal, err := NewPortal(c.multiple)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create portal: %w", err)
+	}
 
-	// Start screen sharing session
-	if err := portal.StartScreenShare(); err != nil {
+	if err := portal.StartScreenShare(ctx); err != nil {
 		portal.Close()
-		return fmt.Errorf("failed to start screen share: %w", err)
+		return nil, nil, fmt.Errorf("failed to start screen share: %w", err)
 	}
 
-	nodeID := portal.GetNodeID()
-	log.Info().Uint32("node_id", nodeID).Msg("Got PipeWire node ID")
+	var streams []*monitorStream
+	for _, node := range portal.Streams() {
+		logger.WithComponent("pipewire-capturer").Info().Uint32("node_id", node.NodeID).Msg("Got PipeWire node ID")
+
+		pipeline, err := c.startSource(portal, node.NodeID, c.dmabuf)
+		if err != nil {
+			stopStreams(streams)
+			portal.Close()
+			return nil, nil, err
+		}
+		s := &monitorStream{node: node, pipeline: pipeline}
+		s.mapping = s.resolveScaleMapping(claimedOutputs(streams, s))
+		s.mappedW, s.mappedH = pipeline.GetFrameSize()
+		streams = append(streams, s)
+	}
+	return portal, streams, nil
+}
+
+// stopStreams stops the frame sources of streams
+func stopStreams(streams []*monitorStream) {
+	for _, s := range streams {
+		s.pipeline.Stop()
+	}
+}
+
+// superviseSession reopens the portal session with the restore token when
+// the compositor closes it (e.g. across suspend/resume), so only the
+// PipeWire streams restart and no dialog pops up mid-stream. If it can't
+// be reopened, lost is closed and the capture router restart takes over.
+// done is closed when it returns.
+func (c *Capturer) superviseSession(ctx context.Context, portal *Portal, lost, done chan struct{}) {
+	log := logger.WithComponent("pipewire-capturer")
+	defer close(done)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-portal.Closed():
+		}
+
+		log.Info().Msg("Portal session closed, reopening it with the restore token")
+		sessionEvents.publish(SessionRestoring, "closed by the portal")
+
+		next, streams, err := c.openSession(ctx)
+		if ctx.Err() != nil {
+			// Stopped while reopening
+			if err == nil {
+				stopStreams(streams)
+				next.Close()
+			}
+			return
+		}
+		if err != nil {
+			log.Warn().Err(err).Msg("Failed to reopen the portal session")
+			sessionEvents.publish(SessionClosed, err.Error())
+			close(lost)
+			return
+		}
+
+		c.mu.Lock()
+		if ctx.Err() != nil {
+			c.mu.Unlock()
+			stopStreams(streams)
+			next.Close()
+			return
+		}
+		old, oldStreams := c.portal, c.streams
+		c.portal, c.streams = next, streams
+		c.mu.Unlock()
+
+		stopStreams(oldStreams)
+		old.Close()
+		portal = next
+
+		log.Info().Int("streams", len(streams)).Msg("Portal session reopened")
+		sessionEvents.publish(SessionActive, "")
+	}
+}
+
+// startSource starts consuming a portal stream. The in-process stream is
+// preferred when compiled in; DMA-BUF mode needs GStreamer's VA-API elements,
+// and the gst-launch subprocess is the fallback.
+func (c *Capturer) startSource(portal *Portal, nodeID uint32, dmabuf config.DMABufConfig) (frameSource, error) {
+	log := logger.WithComponent("pipewire-capturer")
+
+	if !dmabuf.Enabled {
+		native, err := c.startNative(portal, nodeID)
+		if err == nil {
+			return native, nil
+		}
+		log.Info().Err(err).Msg("In-process PipeWire capture unavailable, using GStreamer")
+	}
 
 	// Create and start GStreamer subprocess (avoids CGO crashes)
-	pipeline, err := NewGStreamerSubprocess(nodeID)
+	pipeline, err := NewGStreamerSubprocess(nodeID, dmabuf, c.template)
 	if err != nil {
-		portal.Close()
-		return fmt.Errorf("failed to create pipeline: %w", err)
+		return nil, fmt.Errorf("failed to create pipeline: %w", err)
 	}
-	c.pipeline = pipeline
-
 	if err := pipeline.Start(); err != nil {
-		portal.Close()
-		return fmt.Errorf("failed to start pipeline subprocess: %w", err)
+		return nil, fmt.Errorf("failed to start pipeline subprocess: %w", err)
 	}
+	return pipeline, nil
+}
 
-	c.started = true
-	log.Info().Msg("PipeWire capturer started")
+// startNative connects an in-process stream over the portal's PipeWire remote
+func (c *Capturer) startNative(portal *Portal, nodeID uint32) (*NativeStream, error) {
+	stream, err := NewNativeStream(nodeID)
+	if err != nil {
+		return nil, err
+	}
 
-	return nil
+	fd, err := portal.OpenPipeWireRemote()
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Connect(fd); err != nil {
+		return nil, err
+	}
+	return stream, nil
 }
 
-// Stop stops the PipeWire capture session
+// Stop stops the PipeWire capture session. It waits for the session
+// supervisor, so no reopened session outlives it.
 func (c *Capturer) Stop() error {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel, c.done, c.lost = nil, nil, nil
+	c.mu.Unlock()
+
+	// The supervisor takes c.mu to swap sessions, so wait for it unlocked
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	log := logger.WithComponent("pipewire-capturer")
 
-	if c.pipeline != nil {
-		c.pipeline.Stop()
-		c.pipeline = nil
-	}
+	stopStreams(c.streams)
+	c.streams = nil
 
 	if c.portal != nil {
 		c.portal.Close()
@@ -87,41 +254,45 @@ func (c *Capturer) Stop() error {
 
 	c.started = false
 	log.Info().Msg("PipeWire capturer stopped")
+	sessionEvents.publish(SessionClosed, "stopped")
 
 	return nil
 }
 
-// CaptureWindow captures a window by cropping the screen capture to window geometry
-func (c *Capturer) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
+// Disconnected returns a channel that is closed when the portal session
+// ended and could not be reopened with the restore token, or nil if the
+// capturer is not started
+func (c *Capturer) Disconnected() <-chan struct{} {
 	c.mu.Lock()
-	pipeline := c.pipeline
-	c.mu.Unlock()
-
-	if pipeline == nil || !pipeline.IsRunning() {
-		return nil, fmt.Errorf("pipeline not running")
-	}
+	defer c.mu.Unlock()
+	return c.lost
+}
 
+// CaptureWindow captures a window by cropping the screen capture of the
+// monitor it is on to window geometry
+func (c *Capturer) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
 	// Get the window geometry
 	geom := window.Geometry
 	if geom.Width <= 0 || geom.Height <= 0 {
 		// If no geometry, return full frame
-		return pipeline.GetLatestFrame(), nil
+		return c.GetFullScreen()
 	}
 
 	// Crop the screen capture to the window's position
 	return c.CaptureRegion(geom.X, geom.Y, geom.Width, geom.Height)
 }
 
-// CaptureRegion captures a specific region of the screen
+// CaptureRegion captures a specific region of the screen from the stream of
+// the monitor it overlaps most.
+// Coordinates are logical (as reported by the compositor) and are converted
+// to physical frame pixels using the output scale factor.
 func (c *Capturer) CaptureRegion(x, y, width, height int) (*image.RGBA, error) {
-	c.mu.Lock()
-	pipeline := c.pipeline
-	c.mu.Unlock()
-
+	pipeline, mapping := c.streamFor(image.Rect(x, y, x+width, y+height))
 	if pipeline == nil || !pipeline.IsRunning() {
 		return nil, fmt.Errorf("pipeline not running")
 	}
 
+	x, y, width, height = mapping.toPhysical(x, y, width, height)
 	cropped := pipeline.CropFrame(x, y, width, height)
 	if cropped == nil {
 		return nil, fmt.Errorf("no frame available")
@@ -130,6 +301,178 @@ func (c *Capturer) CaptureRegion(x, y, width, height int) (*image.RGBA, error) {
 	return cropped, nil
 }
 
+// ScaleFactor returns the physical-per-logical pixel ratio used for crops
+// from the primary stream
+func (c *Capturer) ScaleFactor() (float64, float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.streams) == 0 {
+		return identityMapping.scaleX, identityMapping.scaleY
+	}
+	mapping := c.streams[0].currentMapping(c.streams)
+	return mapping.scaleX, mapping.scaleY
+}
+
+// streamFor returns the frame source and scale mapping of the stream whose
+// monitor overlaps a logical rectangle most, or the primary stream if none
+// does
+func (c *Capturer) streamFor(rect image.Rectangle) (frameSource, scaleMapping) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.streams) == 0 {
+		return nil, identityMapping
+	}
+
+	best := c.streams[0]
+	bestMapping := best.currentMapping(c.streams)
+	if len(c.streams) == 1 {
+		return best.pipeline, bestMapping
+	}
+
+	bestArea := area(rect.Intersect(best.logicalBounds(bestMapping)))
+	for _, s := range c.streams[1:] {
+		mapping := s.currentMapping(c.streams)
+		if a := area(rect.Intersect(s.logicalBounds(mapping))); a > bestArea {
+			best, bestMapping, bestArea = s, mapping, a
+		}
+	}
+	return best.pipeline, bestMapping
+}
+
+// area returns the number of pixels in a rectangle
+func area(r image.Rectangle) int {
+	return r.Dx() * r.Dy()
+}
+
+// logicalBounds returns the monitor's area in logical coordinates
+func (s *monitorStream) logicalBounds(mapping scaleMapping) image.Rectangle {
+	if s.node.hasGeometry() {
+		return image.Rect(s.node.X, s.node.Y, s.node.X+s.node.Width, s.node.Y+s.node.Height)
+	}
+	w, h := s.pipeline.GetFrameSize()
+	return image.Rect(mapping.originX, mapping.originY,
+		mapping.originX+int(float64(w)/mapping.scaleX+0.5),
+		mapping.originY+int(float64(h)/mapping.scaleY+0.5))
+}
+
+// currentMapping returns the scale mapping for the stream's frames,
+// resolving it again if the frame size changed since (e.g. the shared
+// output changed resolution). all is every stream of the session. Must be
+// called with the capturer's mu held.
+func (s *monitorStream) currentMapping(all []*monitorStream) scaleMapping {
+	if w, h := s.pipeline.GetFrameSize(); w != s.mappedW || h != s.mappedH {
+		s.mapping = s.resolveScaleMapping(claimedOutputs(all, s))
+		s.mappedW, s.mappedH = w, h
+		logger.WithComponent("pipewire-capturer").Info().
+			Uint32("node_id", s.node.NodeID).
+			Int("width", w).
+			Int("height", h).
+			Float64("scale_x", s.mapping.scaleX).
+			Float64("scale_y", s.mapping.scaleY).
+			Msg("Frame size changed, updated crop mapping")
+	}
+	return s.mapping
+}
+
+// Mode returns how frames leave GStreamer (ModeCopy or ModeDMABuf), or ""
+// if the capturer is not started
+func (c *Capturer) Mode() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.streams) == 0 {
+		return ""
+	}
+	return c.streams[0].pipeline.Mode()
+}
+
+// Streams returns the number of shared monitors being captured
+func (c *Capturer) Streams() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.streams)
+}
+
+// resolveScaleMapping determines how logical window geometry maps onto the
+// physical PipeWire frame. On HiDPI Wayland setups KWin reports geometry in
+// logical pixels while the stream is delivered in physical pixels; in
+// DMA-BUF mode the frames are further downscaled from the stream. Outputs in
+// claimed belong to other streams of the session and aren't matched again.
+func (s *monitorStream) resolveScaleMapping(claimed map[string]bool) scaleMapping {
+	log := logger.WithComponent("pipewire-capturer")
+
+	frameWidth, frameHeight := s.pipeline.GetFrameSize()
+	srcWidth, srcHeight := s.pipeline.GetSourceSize()
+	s.located, s.output = geometryNone, ""
+	if frameWidth <= 0 || frameHeight <= 0 || srcWidth <= 0 || srcHeight <= 0 {
+		return identityMapping
+	}
+	// Frame pixels per stream pixel (1 unless DMA-BUF mode downscales)
+	downX := float64(frameWidth) / float64(srcWidth)
+	downY := float64(frameHeight) / float64(srcHeight)
+
+	// Preferred: the portal tells us the logical size of the shared output
+	if s.node.hasGeometry() {
+		log.Debug().Msg("Using portal stream geometry for scale factor")
+		s.located = geometryPortal
+		return scaleMapping{
+			originX: s.node.X,
+			originY: s.node.Y,
+			scaleX:  float64(frameWidth) / float64(s.node.Width),
+			scaleY:  float64(frameHeight) / float64(s.node.Height),
+		}
+	}
+
+	// Fallback: ask KWin for per-output scale factors and pick the output
+	// at the stream's position, if the portal sent one, or else the first
+	// unclaimed output whose physical size matches the stream
+	outputs, err := DiscoverOutputScales()
+	if err != nil {
+		log.Debug().Err(err).Msg("Output scale discovery failed, assuming scale 1.0")
+		return scaleMapping{scaleX: downX, scaleY: downY}
+	}
+	for _, o := range outputs {
+		physW := int(float64(o.Width)*o.Scale + 0.5)
+		physH := int(float64(o.Height)*o.Scale + 0.5)
+		matched := !claimed[o.Name] && abs(physW-srcWidth) <= 1 && abs(physH-srcHeight) <= 1
+		if s.node.Positioned {
+			matched = o.X == s.node.X && o.Y == s.node.Y
+		}
+		if matched {
+			log.Debug().Str("output", o.Name).Float64("scale", o.Scale).Msg("Matched output for scale factor")
+			s.located, s.output = geometryOutput, o.Name
+			return scaleMapping{
+				originX: o.X,
+				originY: o.Y,
+				scaleX:  o.Scale * downX,
+				scaleY:  o.Scale * downY,
+			}
+		}
+	}
+
+	return scaleMapping{scaleX: downX, scaleY: downY}
+}
+
+// claimedOutputs returns the outputs matched to streams other than s
+func claimedOutputs(all []*monitorStream, s *monitorStream) map[string]bool {
+	claimed := make(map[string]bool)
+	for _, other := range all {
+		if other != s && other.output != "" {
+			claimed[other.output] = true
+		}
+	}
+	return claimed
+}
+
+// abs returns the absolute value of an int
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
 // Name returns the capturer name
 func (c *Capturer) Name() string {
 	return "PipeWire"
@@ -138,7 +481,7 @@ func (c *Capturer) Name() string {
 // IsAvailable checks if PipeWire capture is available
 func (c *Capturer) IsAvailable() bool {
 	// Check if we can create a portal connection
-	conn, err := NewPortal()
+	conn, err := NewPortal(false)
 	if err != nil {
 		return false
 	}
@@ -153,10 +496,13 @@ func (c *Capturer) CanCapture(window *config.WindowInfo) bool {
 	return window.Geometry.Width > 0 && window.Geometry.Height > 0
 }
 
-// GetFullScreen returns the full screen capture without cropping
+// GetFullScreen returns the primary monitor's capture without cropping
 func (c *Capturer) GetFullScreen() (*image.RGBA, error) {
 	c.mu.Lock()
-	pipeline := c.pipeline
+	var pipeline frameSource
+	if len(c.streams) > 0 {
+		pipeline = c.streams[0].pipeline
+	}
 	c.mu.Unlock()
 
 	if pipeline == nil || !pipeline.IsRunning() {
@@ -0,0 +1,67 @@
+package pipewire
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// Capture modes reported by frameSource.Mode
+const (
+	ModeNative = "native" // In-process PipeWire stream, no GStreamer
+	ModeCopy   = "copy"   // Full-size RGBA piped out of GStreamer
+	ModeDMABuf = "dmabuf" // Frames converted on the GPU; RGBA piped out
+)
+
+// vaPostprocs are the VA-API postproc elements tried in order: the va plugin
+// from gst-plugins-bad first, then the older gstreamer-vaapi
+var vaPostprocs = []string{"vapostproc", "vaapipostproc"}
+
+// dmabufPipeline builds a gst-launch pipeline that negotiates DMA-BUF memory
+// with PipeWire and converts frames to RGBA with a VA-API postproc, so the
+// compositor's buffers are never mapped into system memory. Frames keep the
+// window's size unless cfg.PreviewScale asks for a downscale. Every frame
+// goes through sink, so the allowlist, standby, overlays and the rest of the
+// output path apply exactly as for the other capture modes.
+//
+// It returns the pipeline and the size of the RGBA frames it writes, or an
+// error if the VA-API elements aren't installed.
+func dmabufPipeline(nodeID uint32, width, height int, cfg config.DMABufConfig, sink string) (string, int, int, error) {
+	postproc := findElement(vaPostprocs)
+	if postproc == "" {
+		return "", 0, 0, fmt.Errorf("no VA-API postproc element (install the gstreamer va or vaapi plugin)")
+	}
+
+	scale := cfg.PreviewScale
+	if scale <= 0 {
+		scale = 1
+	}
+	// Keep the frames even-sized; some drivers reject odd surfaces
+	previewWidth := (width / scale) &^ 1
+	previewHeight := (height / scale) &^ 1
+
+	// Quoted because sh would otherwise parse the parentheses
+	pipeline := fmt.Sprintf(
+		"pipewiresrc path=%d do-timestamp=true always-copy=false ! "+
+			"'video/x-raw(memory:DMABuf)' ! "+
+			"queue leaky=downstream max-size-buffers=1 ! "+
+			"%s ! "+
+			"video/x-raw,format=RGBA,width=%d,height=%d ! "+
+			"%s",
+		nodeID, postproc, previewWidth, previewHeight, sink,
+	)
+
+	return pipeline, previewWidth, previewHeight, nil
+}
+
+// findElement returns the first GStreamer element in names that is
+// installed, or "" if none are
+func findElement(names []string) string {
+	for _, name := range names {
+		if exec.Command("gst-inspect-1.0", "--exists", name).Run() == nil {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package pipewire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GStreamer Data Protocol (gdppay) framing. Each packet is a fixed-size
+// header followed by its payload: a buffer's bytes, or the caps as a
+// NUL-terminated string whenever they're negotiated. Reading caps in-band
+// keeps the frame size in step with the stream when the source resolution
+// changes mid-session.
+const (
+	gdpHeaderLength = 62
+	gdpVersionMajor = 1
+
+	gdpPayloadBuffer = 1
+	gdpPayloadCaps   = 2
+)
+
+// gdpPacket is the part of a GDP header the frame reader needs
+type gdpPacket struct {
+	payloadType uint16
+	size        int
+}
+
+// readGDPHeader reads and decodes the next packet header
+func readGDPHeader(r io.Reader, header []byte) (gdpPacket, error) {
+	if _, err := io.ReadFull(r, header[:gdpHeaderLength]); err != nil {
+		return gdpPacket{}, err
+	}
+	if header[0] != gdpVersionMajor {
+		return gdpPacket{}, fmt.Errorf("unsupported GDP version %d.%d (stream out of sync?)", header[0], header[1])
+	}
+	return gdpPacket{
+		payloadType: binary.BigEndian.Uint16(header[4:6]),
+		size:        int(binary.BigEndian.Uint32(header[6:10])),
+	}, nil
+}
+
+// parseCapsSize extracts the frame size from a caps payload
+func parseCapsSize(payload []byte) (width, height int) {
+	caps := strings.TrimRight(string(payload), "\x00")
+	return extractIntFromCaps(caps, "width"), extractIntFromCaps(caps, "height")
+}
@@ -11,6 +11,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 )
 
@@ -18,21 +20,31 @@ import (
 // This avoids CGO issues by running gst-launch-1.0 as a separate process
 type GStreamerSubprocess struct {
 	nodeID      uint32
+	dmabuf      config.DMABufConfig
+	template    string // capture.pipeline_template, "" for the default
+	mode        string // ModeCopy or ModeDMABuf, set on Start
 	cmd         *exec.Cmd
 	stdout      io.ReadCloser
 	stderr      io.ReadCloser
 	mu          sync.RWMutex
 	latestFrame *image.RGBA
-	frameWidth  int
+	frameWidth  int // Size of the RGBA frames read from the subprocess
 	frameHeight int
+	framed      bool // Frames are gdppay-framed with in-band caps
+	srcWidth    int  // Size of the PipeWire stream (larger in DMA-BUF mode)
+	srcHeight   int
 	running     bool
 	stopChan    chan struct{}
 }
 
-// NewGStreamerSubprocess creates a new subprocess-based GStreamer pipeline
-func NewGStreamerSubprocess(nodeID uint32) (*GStreamerSubprocess, error) {
+// NewGStreamerSubprocess creates a new subprocess-based GStreamer pipeline.
+// With dmabuf enabled it tries the zero-copy pipeline first; otherwise the
+// stream is read by template (config.DefaultPipelineTemplate if empty).
+func NewGStreamerSubprocess(nodeID uint32, dmabuf config.DMABufConfig, template string) (*GStreamerSubprocess, error) {
 	return &GStreamerSubprocess{
 		nodeID:   nodeID,
+		dmabuf:   dmabuf,
+		template: template,
 		stopChan: make(chan struct{}),
 	}, nil
 }
@@ -54,22 +66,54 @@ func (g *GStreamerSubprocess) Start() error {
 		log.Warn().Err(err).Msg("Failed to probe video dimensions, using defaults")
 		width, height = 1920, 1080
 	}
+	g.srcWidth = width
+	g.srcHeight = height
 	g.frameWidth = width
 	g.frameHeight = height
 	log.Info().Int("width", width).Int("height", height).Msg("Video dimensions")
 
-	// Build the pipeline command
-	// Pipeline: pipewiresrc -> videoconvert -> scale -> RGBA format -> raw output to stdout
-	pipelineStr := fmt.Sprintf(
-		"pipewiresrc path=%d do-timestamp=true ! "+
-			"videoconvert ! "+
-			"videoscale ! "+
-			"video/x-raw,format=RGBA,width=%d,height=%d ! "+
-			"fdsink fd=1 sync=false",
-		g.nodeID, width, height,
-	)
+	// With gdppay each frame is length-prefixed and caps arrive in-band, so
+	// frames keep the source size as it changes. Without it, frames are
+	// scaled to the probed size to keep the raw byte stream in step.
+	g.framed = findElement([]string{"gdppay"}) != ""
+	sink := "fdsink fd=1 sync=false"
+	rawCaps := fmt.Sprintf("video/x-raw,format=RGBA,width=%d,height=%d", width, height)
+	if g.framed {
+		sink = "gdppay ! " + sink
+		rawCaps = "video/x-raw,format=RGBA"
+	} else {
+		log.Warn().Msg("gdppay not installed (gst-plugins-bad), resolution changes will be scaled to the initial size")
+	}
+
+	// Build the pipeline command from the template, by default:
+	// pipewiresrc -> videoconvert -> scale -> RGBA format -> output to stdout
+	g.mode = ModeCopy
+	template := g.template
+	if template == "" {
+		template = config.DefaultPipelineTemplate
+	} else if missing := missingElements(template); len(missing) > 0 {
+		log.Error().Strs("elements", missing).Msg("capture.pipeline_template uses elements that aren't installed, using the default pipeline")
+		template = config.DefaultPipelineTemplate
+	}
+	pipelineStr := expandPipelineTemplate(template, g.nodeID, width, height, rawCaps) + " ! " + sink
+
+	if g.dmabuf.Enabled {
+		dmabufStr, previewWidth, previewHeight, err := dmabufPipeline(g.nodeID, width, height, g.dmabuf, sink)
+		if err != nil {
+			log.Warn().Err(err).Msg("DMA-BUF capture unavailable, copying frames instead")
+		} else {
+			g.mode = ModeDMABuf
+			pipelineStr = dmabufStr
+			g.frameWidth = previewWidth
+			g.frameHeight = previewHeight
+			log.Info().
+				Int("preview_width", previewWidth).
+				Int("preview_height", previewHeight).
+				Msg("Using zero-copy DMA-BUF capture")
+		}
+	}
 
-	log.Debug().Str("pipeline", pipelineStr).Msg("Starting GStreamer subprocess")
+	log.Debug().Str("pipeline", pipelineStr).Str("mode", g.mode).Msg("Starting GStreamer subprocess")
 
 	// Use sh -c to properly parse the pipeline string with ! separators
 	g.cmd = exec.Command("sh", "-c", "gst-launch-1.0 -q "+pipelineStr)
@@ -107,6 +151,37 @@ func (g *GStreamerSubprocess) Start() error {
 	return nil
 }
 
+// expandPipelineTemplate fills in the placeholders of a pipeline template
+func expandPipelineTemplate(template string, nodeID uint32, width, height int, caps string) string {
+	return strings.NewReplacer(
+		"{node_id}", strconv.FormatUint(uint64(nodeID), 10),
+		"{width}", strconv.Itoa(width),
+		"{height}", strconv.Itoa(height),
+		"{caps}", caps,
+	).Replace(template)
+}
+
+// missingElements returns the elements of a pipeline template that aren't
+// installed. The first word of each link names an element unless it is
+// caps (media type/subtype), a placeholder or a pad reference ("t.").
+func missingElements(template string) []string {
+	var missing []string
+	for _, link := range strings.Split(template, "!") {
+		fields := strings.Fields(link)
+		if len(fields) == 0 {
+			continue
+		}
+		name := fields[0]
+		if strings.ContainsAny(name, "/{'\"") || strings.HasSuffix(name, ".") {
+			continue
+		}
+		if findElement([]string{name}) == "" {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
 // probeVideoDimensions runs a short pipeline to detect video dimensions
 func (g *GStreamerSubprocess) probeVideoDimensions() (int, int, error) {
 	log := logger.WithComponent("gstreamer-subprocess")
@@ -221,7 +296,11 @@ func (g *GStreamerSubprocess) readFrames() {
 	frameSize := width * height * 4 // RGBA = 4 bytes per pixel
 	reader := bufio.NewReaderSize(g.stdout, frameSize*2)
 
-	frameBuffer := make([]byte, frameSize)
+	if g.framed {
+		g.readFramedFrames(reader)
+		return
+	}
+
 	frameCount := 0
 
 	for {
@@ -232,9 +311,11 @@ func (g *GStreamerSubprocess) readFrames() {
 		default:
 		}
 
-		// Read exactly one frame
-		n, err := io.ReadFull(reader, frameBuffer)
+		// Read exactly one frame straight into a pooled image
+		img := framepool.Get(width, height)
+		n, err := io.ReadFull(reader, img.Pix)
 		if err != nil {
+			framepool.Put(img)
 			if err == io.EOF || err == io.ErrUnexpectedEOF {
 				log.Debug().Msg("EOF from GStreamer subprocess")
 				return
@@ -244,19 +325,120 @@ func (g *GStreamerSubprocess) readFrames() {
 			continue
 		}
 
-		// Create image from buffer
-		img := image.NewRGBA(image.Rect(0, 0, width, height))
-		copy(img.Pix, frameBuffer)
-
-		// Store latest frame
+		// Store latest frame and recycle the previous one. Readers copy
+		// out of latestFrame under the lock, so nothing else references it.
 		g.mu.Lock()
+		prev := g.latestFrame
 		g.latestFrame = img
 		g.mu.Unlock()
+		framepool.Put(prev)
 
 		frameCount++
 	}
 }
 
+// readFramedFrames reads gdppay packets, following caps changes so the frame
+// size always matches the buffers that follow them
+func (g *GStreamerSubprocess) readFramedFrames(reader *bufio.Reader) {
+	log := logger.WithComponent("gstreamer-subprocess")
+
+	header := make([]byte, gdpHeaderLength)
+	mismatches := 0
+
+	for {
+		select {
+		case <-g.stopChan:
+			log.Debug().Msg("Frame reader stopping")
+			return
+		default:
+		}
+
+		packet, err := readGDPHeader(reader, header)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				log.Debug().Msg("EOF from GStreamer subprocess")
+			} else {
+				log.Error().Err(err).Msg("Error reading frame header")
+			}
+			return
+		}
+
+		switch packet.payloadType {
+		case gdpPayloadCaps:
+			payload := make([]byte, packet.size)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				log.Debug().Err(err).Msg("EOF reading caps")
+				return
+			}
+			if width, height := parseCapsSize(payload); width > 0 && height > 0 {
+				g.setFrameSize(width, height)
+			}
+
+		case gdpPayloadBuffer:
+			g.mu.RLock()
+			width, height := g.frameWidth, g.frameHeight
+			g.mu.RUnlock()
+
+			if packet.size != width*height*4 {
+				// Caps not seen yet, or a padded stride; skip the buffer
+				mismatches++
+				if mismatches == 1 || mismatches%100 == 0 {
+					log.Warn().
+						Int("bytes", packet.size).
+						Int("width", width).
+						Int("height", height).
+						Int("count", mismatches).
+						Msg("Frame size doesn't match caps, dropping frame")
+				}
+				if _, err := reader.Discard(packet.size); err != nil {
+					return
+				}
+				continue
+			}
+
+			img := framepool.Get(width, height)
+			if _, err := io.ReadFull(reader, img.Pix); err != nil {
+				framepool.Put(img)
+				log.Debug().Msg("EOF from GStreamer subprocess")
+				return
+			}
+
+			g.mu.Lock()
+			prev := g.latestFrame
+			g.latestFrame = img
+			g.mu.Unlock()
+			framepool.Put(prev)
+
+		default:
+			// Events (stream-start, segment, ...) carry nothing we need
+			if _, err := reader.Discard(packet.size); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// setFrameSize records the frame size from newly negotiated caps. In copy
+// mode frames have the source size, so the source size follows.
+func (g *GStreamerSubprocess) setFrameSize(width, height int) {
+	g.mu.Lock()
+	changed := width != g.frameWidth || height != g.frameHeight
+	g.frameWidth = width
+	g.frameHeight = height
+	if g.mode == ModeCopy {
+		g.srcWidth = width
+		g.srcHeight = height
+	}
+	g.mu.Unlock()
+
+	if changed {
+		logger.WithComponent("gstreamer-subprocess").Info().
+			Int("width", width).
+			Int("height", height).
+			Msg("Capture resolution changed")
+	}
+}
+
 // logStderr logs any errors from the GStreamer subprocess
 func (g *GStreamerSubprocess) logStderr() {
 	log := logger.WithComponent("gstreamer-subprocess")
@@ -298,7 +480,8 @@ func (g *GStreamerSubprocess) Stop() error {
 	return nil
 }
 
-// GetLatestFrame returns the most recent captured frame
+// GetLatestFrame returns a copy of the most recent captured frame.
+// The caller owns the returned (pooled) frame.
 func (g *GStreamerSubprocess) GetLatestFrame() *image.RGBA {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
@@ -308,9 +491,7 @@ func (g *GStreamerSubprocess) GetLatestFrame() *image.RGBA {
 	}
 
 	// Return a copy to avoid race conditions
-	copyImg := image.NewRGBA(g.latestFrame.Bounds())
-	copy(copyImg.Pix, g.latestFrame.Pix)
-	return copyImg
+	return framepool.Clone(g.latestFrame)
 }
 
 // GetFrameSize returns the current frame dimensions
@@ -320,45 +501,29 @@ func (g *GStreamerSubprocess) GetFrameSize() (width, height int) {
 	return g.frameWidth, g.frameHeight
 }
 
-// CropFrame extracts a region from the current frame
-func (g *GStreamerSubprocess) CropFrame(x, y, width, height int) *image.RGBA {
+// GetSourceSize returns the size of the PipeWire stream, which differs from
+// the frame size when DMA-BUF mode downscales frames
+func (g *GStreamerSubprocess) GetSourceSize() (width, height int) {
 	g.mu.RLock()
-	frame := g.latestFrame
-	g.mu.RUnlock()
-
-	if frame == nil {
-		return nil
-	}
-
-	// Validate bounds
-	frameBounds := frame.Bounds()
-	if x < 0 {
-		x = 0
-	}
-	if y < 0 {
-		y = 0
-	}
-	if x+width > frameBounds.Dx() {
-		width = frameBounds.Dx() - x
-	}
-	if y+height > frameBounds.Dy() {
-		height = frameBounds.Dy() - y
-	}
-
-	if width <= 0 || height <= 0 {
-		return nil
-	}
+	defer g.mu.RUnlock()
+	return g.srcWidth, g.srcHeight
+}
 
-	// Create cropped image
-	cropped := image.NewRGBA(image.Rect(0, 0, width, height))
+// Mode returns ModeDMABuf if the zero-copy pipeline is running, else ModeCopy
+func (g *GStreamerSubprocess) Mode() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.mode
+}
 
-	for dy := 0; dy < height; dy++ {
-		srcStart := (y+dy)*frame.Stride + x*4
-		dstStart := dy * cropped.Stride
-		copy(cropped.Pix[dstStart:dstStart+width*4], frame.Pix[srcStart:srcStart+width*4])
-	}
+// CropFrame extracts a region from the current frame.
+// The caller owns the returned (pooled) frame.
+func (g *GStreamerSubprocess) CropFrame(x, y, width, height int) *image.RGBA {
+	// Hold the read lock while copying: the reader recycles replaced frames
+	g.mu.RLock()
+	defer g.mu.RUnlock()
 
-	return cropped
+	return cropFrame(g.latestFrame, x, y, width, height)
 }
 
 // IsRunning returns whether the subprocess is running
@@ -0,0 +1,55 @@
+package pipewire
+
+// Where a stream's place on the desktop came from
+const (
+	geometryPortal = "portal" // Position and size in the Start response
+	geometryOutput = "kwin"   // KWin output matched by position or size
+	geometryNone   = "none"   // Unknown; crops assume the desktop origin
+)
+
+// Monitor is a shared monitor in the monitor map: its PipeWire node, the
+// portal's metadata for it, and the logical desktop area it covers
+type Monitor struct {
+	NodeID     uint32  `json:"node_id"`
+	StreamID   string  `json:"stream_id,omitempty"`
+	MappingID  string  `json:"mapping_id,omitempty"`
+	SourceType string  `json:"source_type"`      // "monitor", "window", "virtual" or "unknown"
+	Output     string  `json:"output,omitempty"` // Compositor output, when matched through KWin
+	X          int     `json:"x"`
+	Y          int     `json:"y"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	ScaleX     float64 `json:"scale_x"` // Frame pixels per logical pixel
+	ScaleY     float64 `json:"scale_y"`
+	Geometry   string  `json:"geometry"` // "portal", "kwin" or "none"
+	Primary    bool    `json:"primary"`  // Used for full-screen captures and windows off every monitor
+}
+
+// Monitors returns the monitor map of the running session, primary first,
+// or nil if the capturer is not started
+func (c *Capturer) Monitors() []Monitor {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var monitors []Monitor
+	for i, s := range c.streams {
+		mapping := s.currentMapping(c.streams)
+		bounds := s.logicalBounds(mapping)
+		monitors = append(monitors, Monitor{
+			NodeID:     s.node.NodeID,
+			StreamID:   s.node.ID,
+			MappingID:  s.node.MappingID,
+			SourceType: sourceTypeName(s.node.SourceType),
+			Output:     s.output,
+			X:          bounds.Min.X,
+			Y:          bounds.Min.Y,
+			Width:      bounds.Dx(),
+			Height:     bounds.Dy(),
+			ScaleX:     mapping.scaleX,
+			ScaleY:     mapping.scaleY,
+			Geometry:   s.located,
+			Primary:    i == 0,
+		})
+	}
+	return monitors
+}
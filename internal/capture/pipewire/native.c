@@ -0,0 +1,188 @@
+//go:build pipewire && cgo
+
+#include <stdlib.h>
+#include <unistd.h>
+#include <spa/param/video/format-utils.h>
+#include <spa/param/buffers.h>
+#include <spa/pod/builder.h>
+
+#include "native.h"
+#include "_cgo_export.h"
+
+static void fs_on_state_changed(void *data, enum pw_stream_state old,
+		enum pw_stream_state state, const char *error) {
+	fs_stream *s = data;
+	fsNativeState(s->handle, state, (char *)error);
+}
+
+static void fs_on_param_changed(void *data, uint32_t id, const struct spa_pod *param) {
+	fs_stream *s = data;
+	uint8_t buffer[1024];
+	struct spa_pod_builder b = SPA_POD_BUILDER_INIT(buffer, sizeof(buffer));
+	const struct spa_pod *params[1];
+
+	if (param == NULL || id != SPA_PARAM_Format)
+		return;
+	if (spa_format_video_raw_parse(param, &s->format) < 0)
+		return;
+
+	switch (s->format.format) {
+	case SPA_VIDEO_FORMAT_BGRx:
+	case SPA_VIDEO_FORMAT_BGRA:
+		s->order = FS_ORDER_BGR;
+		break;
+	default:
+		s->order = FS_ORDER_RGB;
+	}
+	fsNativeFormat(s->handle, s->format.size.width, s->format.size.height);
+
+	// Ask for buffers mapped into our memory rather than DMA-BUFs
+	params[0] = spa_pod_builder_add_object(&b,
+		SPA_TYPE_OBJECT_ParamBuffers, SPA_PARAM_Buffers,
+		SPA_PARAM_BUFFERS_dataType, SPA_POD_CHOICE_FLAGS_Int((1 << SPA_DATA_MemPtr) | (1 << SPA_DATA_MemFd)));
+	pw_stream_update_params(s->stream, params, 1);
+}
+
+static void fs_on_process(void *data) {
+	fs_stream *s = data;
+	struct pw_buffer *b, *last = NULL;
+	struct spa_data *d;
+	int stride;
+
+	// Skip to the newest buffer; older ones are stale by now
+	while ((b = pw_stream_dequeue_buffer(s->stream)) != NULL) {
+		if (last != NULL)
+			pw_stream_queue_buffer(s->stream, last);
+		last = b;
+	}
+	if (last == NULL)
+		return;
+
+	d = &last->buffer->datas[0];
+	if (d->data != NULL && d->chunk->size > 0 && !(d->chunk->flags & SPA_CHUNK_FLAG_CORRUPTED)) {
+		stride = d->chunk->stride;
+		if (stride <= 0)
+			stride = s->format.size.width * 4;
+		fsNativeFrame(s->handle, SPA_PTROFF(d->data, d->chunk->offset, void), d->chunk->size,
+			s->format.size.width, s->format.size.height, stride, s->order);
+	}
+	pw_stream_queue_buffer(s->stream, last);
+}
+
+static const struct pw_stream_events fs_stream_events = {
+	PW_VERSION_STREAM_EVENTS,
+	.state_changed = fs_on_state_changed,
+	.param_changed = fs_on_param_changed,
+	.process = fs_on_process,
+};
+
+void fs_stream_destroy(fs_stream *s) {
+	if (s->loop != NULL)
+		pw_thread_loop_stop(s->loop);
+	if (s->stream != NULL) {
+		// Destroying emits a final state change; Go isn't listening anymore
+		spa_hook_remove(&s->stream_listener);
+		pw_stream_destroy(s->stream);
+	}
+	if (s->core != NULL)
+		pw_core_disconnect(s->core);
+	if (s->context != NULL)
+		pw_context_destroy(s->context);
+	if (s->loop != NULL)
+		pw_thread_loop_destroy(s->loop);
+	free(s);
+}
+
+// fs_stream_new connects to PipeWire over fd, which it takes ownership of,
+// and starts consuming node_id. On failure it sets *error and returns NULL.
+fs_stream *fs_stream_new(uintptr_t handle, int fd, uint32_t node_id, const char **error) {
+	fs_stream *s;
+	uint8_t buffer[1024];
+	struct spa_pod_builder b = SPA_POD_BUILDER_INIT(buffer, sizeof(buffer));
+	const struct spa_pod *params[1];
+	struct spa_rectangle def_size = SPA_RECTANGLE(1920, 1080);
+	struct spa_rectangle min_size = SPA_RECTANGLE(1, 1);
+	struct spa_rectangle max_size = SPA_RECTANGLE(16384, 16384);
+	struct spa_fraction def_rate = SPA_FRACTION(30, 1);
+	struct spa_fraction min_rate = SPA_FRACTION(0, 1);
+	struct spa_fraction max_rate = SPA_FRACTION(240, 1);
+
+	pw_init(NULL, NULL);
+
+	s = calloc(1, sizeof(*s));
+	if (s == NULL) {
+		close(fd);
+		*error = "out of memory";
+		return NULL;
+	}
+	s->handle = handle;
+
+	s->loop = pw_thread_loop_new("focusstreamer-capture", NULL);
+	if (s->loop == NULL) {
+		close(fd);
+		*error = "failed to create thread loop";
+		goto fail;
+	}
+	s->context = pw_context_new(pw_thread_loop_get_loop(s->loop), NULL, 0);
+	if (s->context == NULL) {
+		close(fd);
+		*error = "failed to create context";
+		goto fail;
+	}
+	if (pw_thread_loop_start(s->loop) < 0) {
+		close(fd);
+		*error = "failed to start thread loop";
+		goto fail;
+	}
+
+	pw_thread_loop_lock(s->loop);
+
+	// The core owns fd from here, even if connecting fails
+	s->core = pw_context_connect_fd(s->context, fd, NULL, 0);
+	if (s->core == NULL) {
+		*error = "failed to connect to PipeWire";
+		goto fail_locked;
+	}
+
+	s->stream = pw_stream_new(s->core, "FocusStreamer",
+		pw_properties_new(
+			PW_KEY_MEDIA_TYPE, "Video",
+			PW_KEY_MEDIA_CATEGORY, "Capture",
+			PW_KEY_MEDIA_ROLE, "Screen",
+			NULL));
+	if (s->stream == NULL) {
+		*error = "failed to create stream";
+		goto fail_locked;
+	}
+	pw_stream_add_listener(s->stream, &s->stream_listener, &fs_stream_events, s);
+
+	// Offer the packed RGB formats compositors share; size and rate are
+	// whatever the source negotiates, and may change mid-stream
+	params[0] = spa_pod_builder_add_object(&b,
+		SPA_TYPE_OBJECT_Format, SPA_PARAM_EnumFormat,
+		SPA_FORMAT_mediaType, SPA_POD_Id(SPA_MEDIA_TYPE_video),
+		SPA_FORMAT_mediaSubtype, SPA_POD_Id(SPA_MEDIA_SUBTYPE_raw),
+		SPA_FORMAT_VIDEO_format, SPA_POD_CHOICE_ENUM_Id(5,
+			SPA_VIDEO_FORMAT_BGRx,
+			SPA_VIDEO_FORMAT_BGRx,
+			SPA_VIDEO_FORMAT_BGRA,
+			SPA_VIDEO_FORMAT_RGBx,
+			SPA_VIDEO_FORMAT_RGBA),
+		SPA_FORMAT_VIDEO_size, SPA_POD_CHOICE_RANGE_Rectangle(&def_size, &min_size, &max_size),
+		SPA_FORMAT_VIDEO_framerate, SPA_POD_CHOICE_RANGE_Fraction(&def_rate, &min_rate, &max_rate));
+
+	if (pw_stream_connect(s->stream, PW_DIRECTION_INPUT, node_id,
+			PW_STREAM_FLAG_AUTOCONNECT | PW_STREAM_FLAG_MAP_BUFFERS, params, 1) < 0) {
+		*error = "failed to connect stream";
+		goto fail_locked;
+	}
+
+	pw_thread_loop_unlock(s->loop);
+	return s;
+
+fail_locked:
+	pw_thread_loop_unlock(s->loop);
+fail:
+	fs_stream_destroy(s);
+	return NULL;
+}
@@ -0,0 +1,236 @@
+//go:build pipewire && cgo
+
+package pipewire
+
+// #cgo pkg-config: libpipewire-0.3
+// #include "native.h"
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"runtime/cgo"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+)
+
+// negotiateTimeout bounds how long Connect waits for PipeWire to agree on
+// a video format
+const negotiateTimeout = 5 * time.Second
+
+// NativeStream consumes a portal PipeWire stream in-process through
+// libpipewire, with no GStreamer subprocess. Frames arrive in whatever size
+// the source negotiates, and a renegotiated size takes effect with the next
+// frame.
+type NativeStream struct {
+	nodeID uint32
+	handle cgo.Handle
+	stream *C.fs_stream
+
+	mu            sync.RWMutex
+	latestFrame   *image.RGBA
+	frameWidth    int
+	frameHeight   int
+	running       bool
+	negotiated    chan struct{} // Closed once the first format is known
+	negotiateOnce sync.Once
+}
+
+// NewNativeStream creates an in-process stream for a portal node
+func NewNativeStream(nodeID uint32) (*NativeStream, error) {
+	return &NativeStream{
+		nodeID:     nodeID,
+		negotiated: make(chan struct{}),
+	}, nil
+}
+
+// Connect starts consuming the stream over fd, a PipeWire connection from
+// Portal.OpenPipeWireRemote. The stream takes ownership of fd. Connect
+// returns once a video format has been negotiated.
+func (s *NativeStream) Connect(fd int) error {
+	log := logger.WithComponent("pipewire-native")
+
+	s.handle = cgo.NewHandle(s)
+
+	// Running before the stream exists, so an early error isn't overwritten
+	s.mu.Lock()
+	s.running = true
+	s.mu.Unlock()
+
+	var cerr *C.char
+	stream := C.fs_stream_new(C.uintptr_t(s.handle), C.int(fd), C.uint32_t(s.nodeID), &cerr)
+	if stream == nil {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+		s.handle.Delete()
+		return fmt.Errorf("failed to start PipeWire stream: %s", C.GoString(cerr))
+	}
+
+	s.mu.Lock()
+	s.stream = stream
+	s.mu.Unlock()
+
+	select {
+	case <-s.negotiated:
+	case <-time.After(negotiateTimeout):
+		s.Stop()
+		return fmt.Errorf("no video format negotiated within %s", negotiateTimeout)
+	}
+
+	width, height := s.GetFrameSize()
+	log.Info().
+		Uint32("node_id", s.nodeID).
+		Int("width", width).
+		Int("height", height).
+		Msg("In-process PipeWire stream started")
+	return nil
+}
+
+// Stop disconnects from PipeWire
+func (s *NativeStream) Stop() error {
+	s.mu.Lock()
+	stream := s.stream
+	s.stream = nil
+	s.running = false
+	s.mu.Unlock()
+
+	if stream == nil {
+		return nil
+	}
+
+	// Stopping the loop first guarantees no callback runs after the
+	// handle is deleted
+	C.fs_stream_destroy(stream)
+	s.handle.Delete()
+
+	s.mu.Lock()
+	framepool.Put(s.latestFrame)
+	s.latestFrame = nil
+	s.mu.Unlock()
+
+	logger.WithComponent("pipewire-native").Info().Msg("In-process PipeWire stream stopped")
+	return nil
+}
+
+// IsRunning returns whether the stream is connected
+func (s *NativeStream) IsRunning() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.running
+}
+
+// GetLatestFrame returns a copy of the most recent frame.
+// The caller owns the returned (pooled) frame.
+func (s *NativeStream) GetLatestFrame() *image.RGBA {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.latestFrame == nil {
+		return nil
+	}
+	return framepool.Clone(s.latestFrame)
+}
+
+// CropFrame extracts a region from the current frame.
+// The caller owns the returned (pooled) frame.
+func (s *NativeStream) CropFrame(x, y, width, height int) *image.RGBA {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return cropFrame(s.latestFrame, x, y, width, height)
+}
+
+// GetFrameSize returns the currently negotiated frame size
+func (s *NativeStream) GetFrameSize() (width, height int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.frameWidth, s.frameHeight
+}
+
+// GetSourceSize returns the stream size, the same as the frame size since
+// frames aren't scaled
+func (s *NativeStream) GetSourceSize() (width, height int) {
+	return s.GetFrameSize()
+}
+
+// Mode returns ModeNative
+func (s *NativeStream) Mode() string {
+	return ModeNative
+}
+
+// setFormat records a negotiated frame size
+func (s *NativeStream) setFormat(width, height int) {
+	s.mu.Lock()
+	changed := s.frameWidth != 0 && (s.frameWidth != width || s.frameHeight != height)
+	s.frameWidth = width
+	s.frameHeight = height
+	s.mu.Unlock()
+
+	if changed {
+		logger.WithComponent("pipewire-native").Info().
+			Int("width", width).
+			Int("height", height).
+			Msg("PipeWire stream size changed")
+	}
+	s.negotiateOnce.Do(func() { close(s.negotiated) })
+}
+
+// storeFrame converts a mapped PipeWire buffer to RGBA and makes it the
+// latest frame. data is only valid for the duration of the call.
+func (s *NativeStream) storeFrame(data []byte, width, height, stride int, bgr bool) {
+	if width <= 0 || height <= 0 || len(data) < stride*(height-1)+width*4 {
+		return
+	}
+
+	img := framepool.Get(width, height)
+	pixconv.StridedToRGBA(img, data, stride, bgr)
+
+	s.mu.Lock()
+	prev := s.latestFrame
+	s.latestFrame = img
+	s.mu.Unlock()
+	framepool.Put(prev)
+}
+
+// setState handles stream state changes, marking the stream stopped when
+// PipeWire reports an error or disconnects it
+func (s *NativeStream) setState(state int, errMsg string) {
+	log := logger.WithComponent("pipewire-native")
+
+	switch state {
+	case C.PW_STREAM_STATE_ERROR:
+		log.Error().Str("error", errMsg).Msg("PipeWire stream failed")
+	case C.PW_STREAM_STATE_UNCONNECTED:
+		log.Info().Msg("PipeWire stream disconnected")
+	default:
+		log.Debug().Int("state", state).Msg("PipeWire stream state changed")
+		return
+	}
+
+	s.mu.Lock()
+	s.running = false
+	s.mu.Unlock()
+}
+
+//export fsNativeFormat
+func fsNativeFormat(handle C.uintptr_t, width, height C.int) {
+	cgo.Handle(handle).Value().(*NativeStream).setFormat(int(width), int(height))
+}
+
+//export fsNativeFrame
+func fsNativeFrame(handle C.uintptr_t, data unsafe.Pointer, size, width, height, stride, order C.int) {
+	cgo.Handle(handle).Value().(*NativeStream).storeFrame(
+		unsafe.Slice((*byte)(data), int(size)),
+		int(width), int(height), int(stride), order == C.FS_ORDER_BGR,
+	)
+}
+
+//export fsNativeState
+func fsNativeState(handle C.uintptr_t, state C.int, errMsg *C.char) {
+	cgo.Handle(handle).Value().(*NativeStream).setState(int(state), C.GoString(errMsg))
+}
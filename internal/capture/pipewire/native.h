@@ -0,0 +1,28 @@
+//go:build pipewire && cgo
+
+#ifndef FOCUSSTREAMER_NATIVE_H
+#define FOCUSSTREAMER_NATIVE_H
+
+#include <stdint.h>
+#include <pipewire/pipewire.h>
+#include <spa/param/video/raw.h>
+
+// Channel order of the negotiated format, as reported to Go
+enum { FS_ORDER_RGB = 0, FS_ORDER_BGR = 1 };
+
+// fs_stream is an in-process PipeWire video stream (see native.go)
+typedef struct {
+	uintptr_t handle;
+	struct pw_thread_loop *loop;
+	struct pw_context *context;
+	struct pw_core *core;
+	struct pw_stream *stream;
+	struct spa_hook stream_listener;
+	struct spa_video_info_raw format;
+	int order;
+} fs_stream;
+
+fs_stream *fs_stream_new(uintptr_t handle, int fd, uint32_t node_id, const char **error);
+void fs_stream_destroy(fs_stream *s);
+
+#endif
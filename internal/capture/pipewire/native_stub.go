@@ -0,0 +1,25 @@
+//go:build !pipewire || !cgo
+
+package pipewire
+
+import (
+	"fmt"
+	"image"
+)
+
+// NativeStream is unavailable without the pipewire build tag
+type NativeStream struct{}
+
+// NewNativeStream reports that in-process PipeWire capture wasn't compiled in
+func NewNativeStream(nodeID uint32) (*NativeStream, error) {
+	return nil, fmt.Errorf("built without in-process PipeWire support (rebuild with -tags pipewire)")
+}
+
+func (s *NativeStream) Connect(fd int) error                          { return fmt.Errorf("not supported") }
+func (s *NativeStream) Stop() error                                   { return nil }
+func (s *NativeStream) IsRunning() bool                               { return false }
+func (s *NativeStream) GetLatestFrame() *image.RGBA                   { return nil }
+func (s *NativeStream) CropFrame(x, y, width, height int) *image.RGBA { return nil }
+func (s *NativeStream) GetFrameSize() (int, int)                      { return 0, 0 }
+func (s *NativeStream) GetSourceSize() (int, int)                     { return 0, 0 }
+func (s *NativeStream) Mode() string                                  { return ModeNative }
@@ -1,11 +1,14 @@
 package pipewire
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
@@ -20,6 +23,16 @@ type Portal struct {
 	mu            sync.Mutex
 	restoreToken  string
 	tokenPath     string
+
+	// Ask for every monitor instead of one
+	multiple bool
+
+	// Shared streams, one per selected monitor
+	streams []PortalStream
+
+	// Closed when the session ends or the session bus connection drops
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 // Portal D-Bus constants
@@ -28,8 +41,29 @@ const (
 	portalPath      = "/org/freedesktop/portal/desktop"
 	screenCastIface = "org.freedesktop.portal.ScreenCast"
 	requestIface    = "org.freedesktop.portal.Request"
+	sessionIface    = "org.freedesktop.portal.Session"
 )
 
+// PortalStream is a PipeWire stream of a shared monitor, with the metadata
+// the portal sent along in the Start response. Position and size are logical
+// (compositor) coordinates, zero if the portal did not provide them.
+type PortalStream struct {
+	NodeID     uint32
+	ID         string // Opaque stream id, stable across restored sessions
+	MappingID  string // Identifies the monitor to other portals, e.g. RemoteDesktop
+	SourceType uint32 // SourceTypeMonitor, SourceTypeWindow or SourceTypeVirtual; 0 if not reported
+	X          int
+	Y          int
+	Width      int
+	Height     int
+	Positioned bool // The portal reported the position
+}
+
+// hasGeometry reports whether the portal gave the stream's size
+func (s PortalStream) hasGeometry() bool {
+	return s.Width > 0 && s.Height > 0
+}
+
 // Source types for SelectSources
 const (
 	SourceTypeMonitor = 1 << 0
@@ -37,6 +71,20 @@ const (
 	SourceTypeVirtual = 1 << 2
 )
 
+// sourceTypeName names a stream's source type for logs and the API
+func sourceTypeName(sourceType uint32) string {
+	switch sourceType {
+	case SourceTypeMonitor:
+		return "monitor"
+	case SourceTypeWindow:
+		return "window"
+	case SourceTypeVirtual:
+		return "virtual"
+	default:
+		return "unknown"
+	}
+}
+
 // Cursor modes for SelectSources
 const (
 	CursorModeHidden   = 1 << 0
@@ -51,8 +99,22 @@ const (
 	PersistModeSession     = 2
 )
 
-// NewPortal creates a new portal client
-func NewPortal() (*Portal, error) {
+// errTokenRejected is returned when the portal refuses the stored restore
+// token, e.g. because the shared monitor is gone
+var errTokenRejected = errors.New("restore token rejected")
+
+// requestSeq numbers portal requests so a restarted session doesn't reuse
+// the handle tokens of the one before
+var requestSeq atomic.Uint32
+
+// requestToken returns a handle token unique to this process and request
+func requestToken(prefix string) string {
+	return fmt.Sprintf("%s%d_%d", prefix, os.Getpid(), requestSeq.Add(1))
+}
+
+// NewPortal creates a new portal client. With multiple, the user is asked
+// to share every monitor rather than one.
+func NewPortal(multiple bool) (*Portal, error) {
 	conn, err := dbus.ConnectSessionBus()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
@@ -68,6 +130,8 @@ func NewPortal() (*Portal, error) {
 	p := &Portal{
 		conn:      conn,
 		tokenPath: tokenPath,
+		multiple:  multiple,
+		closed:    make(chan struct{}),
 	}
 
 	// Try to load existing restore token
@@ -78,31 +142,68 @@ func NewPortal() (*Portal, error) {
 
 // Close closes the portal connection
 func (p *Portal) Close() error {
+	p.closeSession()
+	return p.conn.Close()
+}
+
+// closeSession closes the session, if one was created
+func (p *Portal) closeSession() {
 	if p.sessionHandle != "" {
-		// Close the session
 		p.conn.Object(portalService, p.sessionHandle).Call(
-			"org.freedesktop.portal.Session.Close", 0,
+			sessionIface+".Close", 0,
 		)
+		p.sessionHandle = ""
 	}
-	return p.conn.Close()
 }
 
-// GetNodeID returns the PipeWire node ID for screen capture
+// closeRequest dismisses a pending portal request, e.g. the screen share
+// dialog, after its caller stopped waiting for the response
+func (p *Portal) closeRequest(requestPath dbus.ObjectPath) {
+	if requestPath != "" {
+		p.conn.Object(portalService, requestPath).Call(requestIface+".Close", 0)
+	}
+}
+
+// GetNodeID returns the PipeWire node ID of the first shared stream
 func (p *Portal) GetNodeID() uint32 {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	return p.nodeID
 }
 
-// StartScreenShare initiates the screen sharing session
-func (p *Portal) StartScreenShare() error {
+// Streams returns the shared streams, one per monitor the user selected
+func (p *Portal) Streams() []PortalStream {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	return append([]PortalStream{}, p.streams...)
+}
 
+// StartScreenShare initiates the screen sharing session. With a stored
+// restore token the portal reopens the previous share without a dialog; if
+// it rejects the token, the token is discarded and the user is asked again.
+// Cancelling ctx abandons the pending portal request, closing its dialog.
+func (p *Portal) StartScreenShare(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	err := p.startScreenShare(ctx)
+	if err == nil || !errors.Is(err, errTokenRejected) {
+		return err
+	}
+
+	logger.WithComponent("portal").Warn().Err(err).Msg("Portal rejected the restore token, asking to share again")
+	sessionEvents.publish(SessionAuthorizing, "restore token rejected")
+	p.closeSession()
+	p.clearRestoreToken()
+	return p.startScreenShare(ctx)
+}
+
+// startScreenShare creates, configures and starts a session
+func (p *Portal) startScreenShare(ctx context.Context) error {
 	log := logger.WithComponent("portal")
 
 	// Create session
-	sessionHandle, err := p.createSession()
+	sessionHandle, err := p.createSession(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
@@ -110,34 +211,98 @@ func (p *Portal) StartScreenShare() error {
 	log.Debug().Str("session", string(sessionHandle)).Msg("Created portal session")
 
 	// Select sources
-	err = p.selectSources(sessionHandle)
+	err = p.selectSources(ctx, sessionHandle)
 	if err != nil {
 		return fmt.Errorf("failed to select sources: %w", err)
 	}
 	log.Debug().Msg("Selected sources")
 
 	// Start the session
-	nodeID, err := p.start(sessionHandle)
+	streams, err := p.start(ctx, sessionHandle)
 	if err != nil {
 		return fmt.Errorf("failed to start session: %w", err)
 	}
-	p.nodeID = nodeID
-	log.Info().Uint32("node_id", nodeID).Msg("Screen sharing started")
+	p.streams = streams
+	p.nodeID = streams[0].NodeID
+	log.Info().Uint32("node_id", p.nodeID).Int("streams", len(streams)).Msg("Screen sharing started")
+
+	go p.watchSession(sessionHandle)
 
 	return nil
 }
 
+// OpenPipeWireRemote returns a file descriptor for a PipeWire connection
+// that can see the session's streams. The caller owns the descriptor.
+func (p *Portal) OpenPipeWireRemote() (int, error) {
+	p.mu.Lock()
+	sessionHandle := p.sessionHandle
+	p.mu.Unlock()
+
+	if sessionHandle == "" {
+		return -1, fmt.Errorf("no screen share session")
+	}
+
+	var fd dbus.UnixFD
+	err := p.conn.Object(portalService, portalPath).Call(
+		screenCastIface+".OpenPipeWireRemote", 0,
+		sessionHandle, map[string]dbus.Variant{},
+	).Store(&fd)
+	if err != nil {
+		return -1, fmt.Errorf("OpenPipeWireRemote call failed: %w", err)
+	}
+	return int(fd), nil
+}
+
+// Closed returns a channel that is closed when the screen share session
+// ends: the compositor closed it (e.g. the shared monitor was unplugged or
+// the session resumed from suspend without it) or the session bus
+// connection was lost
+func (p *Portal) Closed() <-chan struct{} {
+	return p.closed
+}
+
+// watchSession waits for the session's Closed signal or a bus disconnect
+func (p *Portal) watchSession(sessionHandle dbus.ObjectPath) {
+	log := logger.WithComponent("portal")
+	defer p.closeOnce.Do(func() { close(p.closed) })
+
+	matchRule := fmt.Sprintf("type='signal',interface='%s',member='Closed',path='%s'", sessionIface, sessionHandle)
+	if err := p.conn.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, matchRule).Err; err != nil {
+		log.Warn().Err(err).Msg("Failed to add session match rule")
+	}
+
+	signals := make(chan *dbus.Signal, 10)
+	p.conn.Signal(signals)
+	defer p.conn.RemoveSignal(signals)
+
+	for {
+		select {
+		case <-p.conn.Context().Done():
+			log.Warn().Msg("Session bus connection lost")
+			return
+		case sig, ok := <-signals:
+			if !ok {
+				return
+			}
+			if sig.Path == sessionHandle && sig.Name == sessionIface+".Closed" {
+				log.Warn().Str("session", string(sessionHandle)).Msg("Screen share session closed by the portal")
+				return
+			}
+		}
+	}
+}
+
 // createSession creates a new portal session
-func (p *Portal) createSession() (dbus.ObjectPath, error) {
+func (p *Portal) createSession(ctx context.Context) (dbus.ObjectPath, error) {
 	log := logger.WithComponent("portal")
 	obj := p.conn.Object(portalService, portalPath)
 
 	// Generate a unique token for this request
-	token := fmt.Sprintf("focusstreamer%d", os.Getpid())
+	token := requestToken("focusstreamer")
 
 	options := map[string]dbus.Variant{
 		"handle_token":         dbus.MakeVariant(token),
-		"session_handle_token": dbus.MakeVariant(fmt.Sprintf("session%d", os.Getpid())),
+		"session_handle_token": dbus.MakeVariant(requestToken("session")),
 	}
 
 	// Set up response channel BEFORE making the call
@@ -153,7 +318,7 @@ func (p *Portal) createSession() (dbus.ObjectPath, error) {
 	defer p.conn.RemoveSignal(responseChan)
 
 	var requestPath dbus.ObjectPath
-	err := obj.Call(screenCastIface+".CreateSession", 0, options).Store(&requestPath)
+	err := obj.CallWithContext(ctx, screenCastIface+".CreateSession", 0, options).Store(&requestPath)
 	if err != nil {
 		return "", fmt.Errorf("CreateSession call failed: %w", err)
 	}
@@ -166,6 +331,9 @@ func (p *Portal) createSession() (dbus.ObjectPath, error) {
 		select {
 		case <-timeout:
 			return "", fmt.Errorf("timeout waiting for CreateSession response")
+		case <-ctx.Done():
+			p.closeRequest(requestPath)
+			return "", fmt.Errorf("CreateSession cancelled: %w", ctx.Err())
 		case sig := <-responseChan:
 			log.Debug().
 				Str("signal_path", string(sig.Path)).
@@ -202,16 +370,16 @@ func (p *Portal) createSession() (dbus.ObjectPath, error) {
 }
 
 // selectSources selects what to share (full screen)
-func (p *Portal) selectSources(sessionHandle dbus.ObjectPath) error {
+func (p *Portal) selectSources(ctx context.Context, sessionHandle dbus.ObjectPath) error {
 	log := logger.WithComponent("portal")
 	obj := p.conn.Object(portalService, portalPath)
 
-	token := fmt.Sprintf("select%d", os.Getpid())
+	token := requestToken("select")
 
 	options := map[string]dbus.Variant{
 		"handle_token": dbus.MakeVariant(token),
 		"types":        dbus.MakeVariant(uint32(SourceTypeMonitor)), // Capture monitors
-		"multiple":     dbus.MakeVariant(false),                     // Single source
+		"multiple":     dbus.MakeVariant(p.multiple),                // One source, or every monitor
 		"cursor_mode":  dbus.MakeVariant(uint32(CursorModeEmbedded)), // Embed cursor
 		"persist_mode": dbus.MakeVariant(uint32(PersistModeSession)), // Persist permission
 	}
@@ -234,7 +402,7 @@ func (p *Portal) selectSources(sessionHandle dbus.ObjectPath) error {
 	defer p.conn.RemoveSignal(responseChan)
 
 	var requestPath dbus.ObjectPath
-	err := obj.Call(screenCastIface+".SelectSources", 0, sessionHandle, options).Store(&requestPath)
+	err := obj.CallWithContext(ctx, screenCastIface+".SelectSources", 0, sessionHandle, options).Store(&requestPath)
 	if err != nil {
 		return fmt.Errorf("SelectSources call failed: %w", err)
 	}
@@ -243,10 +411,16 @@ func (p *Portal) selectSources(sessionHandle dbus.ObjectPath) error {
 
 	// Wait for response with timeout (user needs to select screen)
 	timeout := time.After(60 * time.Second)
+	dialog := time.After(dialogDelay)
 	for {
 		select {
 		case <-timeout:
 			return fmt.Errorf("timeout waiting for SelectSources response (user did not select screen)")
+		case <-ctx.Done():
+			p.closeRequest(requestPath)
+			return fmt.Errorf("SelectSources cancelled: %w", ctx.Err())
+		case <-dialog:
+			sessionEvents.publish(SessionAuthorizing, "waiting for the screen share dialog")
 		case sig := <-responseChan:
 			log.Debug().
 				Str("signal_path", string(sig.Path)).
@@ -259,6 +433,9 @@ func (p *Portal) selectSources(sessionHandle dbus.ObjectPath) error {
 				}
 
 				response := sig.Body[0].(uint32)
+				if response == 2 && p.restoreToken != "" {
+					return fmt.Errorf("source selection failed: %w (code %d)", errTokenRejected, response)
+				}
 				if response != 0 {
 					return fmt.Errorf("source selection denied (code %d)", response)
 				}
@@ -269,12 +446,12 @@ func (p *Portal) selectSources(sessionHandle dbus.ObjectPath) error {
 	}
 }
 
-// start starts the screen capture session
-func (p *Portal) start(sessionHandle dbus.ObjectPath) (uint32, error) {
+// start starts the screen capture session and returns its streams
+func (p *Portal) start(ctx context.Context, sessionHandle dbus.ObjectPath) ([]PortalStream, error) {
 	log := logger.WithComponent("portal")
 	obj := p.conn.Object(portalService, portalPath)
 
-	token := fmt.Sprintf("start%d", os.Getpid())
+	token := requestToken("start")
 
 	options := map[string]dbus.Variant{
 		"handle_token": dbus.MakeVariant(token),
@@ -293,19 +470,26 @@ func (p *Portal) start(sessionHandle dbus.ObjectPath) (uint32, error) {
 
 	// Start with empty parent window
 	var requestPath dbus.ObjectPath
-	err := obj.Call(screenCastIface+".Start", 0, sessionHandle, "", options).Store(&requestPath)
+	err := obj.CallWithContext(ctx, screenCastIface+".Start", 0, sessionHandle, "", options).Store(&requestPath)
 	if err != nil {
-		return 0, fmt.Errorf("Start call failed: %w", err)
+		return nil, fmt.Errorf("Start call failed: %w", err)
 	}
 
 	log.Info().Str("request_path", string(requestPath)).Msg("Waiting for Start response")
 
-	// Wait for response with timeout
+	// Wait for response with timeout. Some compositors show the source
+	// dialog here rather than in SelectSources.
 	timeout := time.After(30 * time.Second)
+	dialog := time.After(dialogDelay)
 	for {
 		select {
 		case <-timeout:
-			return 0, fmt.Errorf("timeout waiting for Start response")
+			return nil, fmt.Errorf("timeout waiting for Start response")
+		case <-ctx.Done():
+			p.closeRequest(requestPath)
+			return nil, fmt.Errorf("Start cancelled: %w", ctx.Err())
+		case <-dialog:
+			sessionEvents.publish(SessionAuthorizing, "waiting for the screen share dialog")
 		case sig := <-responseChan:
 			log.Debug().
 				Str("signal_path", string(sig.Path)).
@@ -314,14 +498,17 @@ func (p *Portal) start(sessionHandle dbus.ObjectPath) (uint32, error) {
 
 			if sig.Path == requestPath && sig.Name == requestIface+".Response" {
 				if len(sig.Body) < 2 {
-					return 0, fmt.Errorf("invalid response")
+					return nil, fmt.Errorf("invalid response")
 				}
 
 				response := sig.Body[0].(uint32)
 				results := sig.Body[1].(map[string]dbus.Variant)
 
+				if response == 2 && p.restoreToken != "" {
+					return nil, fmt.Errorf("start failed: %w (code %d)", errTokenRejected, response)
+				}
 				if response != 0 {
-					return 0, fmt.Errorf("start denied (code %d)", response)
+					return nil, fmt.Errorf("start denied (code %d)", response)
 				}
 
 				// Save restore token for future sessions
@@ -338,33 +525,100 @@ func (p *Portal) start(sessionHandle dbus.ObjectPath) (uint32, error) {
 					// streams is a(ua{sv}) - array of (node_id, properties)
 					log.Debug().Interface("streams_type", fmt.Sprintf("%T", streams.Value())).Msg("Parsing streams")
 
+					var entries [][]interface{}
 					switch v := streams.Value().(type) {
 					case [][]interface{}:
-						if len(v) > 0 && len(v[0]) > 0 {
-							if nodeID, ok := v[0][0].(uint32); ok {
-								return nodeID, nil
-							}
-						}
+						entries = v
 					case []interface{}:
 						// Sometimes it comes as []interface{} containing structs
-						if len(v) > 0 {
-							if stream, ok := v[0].([]interface{}); ok && len(stream) > 0 {
-								if nodeID, ok := stream[0].(uint32); ok {
-									return nodeID, nil
-								}
+						for _, e := range v {
+							if entry, ok := e.([]interface{}); ok {
+								entries = append(entries, entry)
 							}
 						}
 					default:
 						log.Warn().Str("type", fmt.Sprintf("%T", v)).Msg("Unknown streams format")
 					}
+
+					var parsed []PortalStream
+					for _, entry := range entries {
+						if len(entry) == 0 {
+							continue
+						}
+						nodeID, ok := entry[0].(uint32)
+						if !ok {
+							continue
+						}
+						stream := PortalStream{NodeID: nodeID}
+						if len(entry) > 1 {
+							if props, ok := entry[1].(map[string]dbus.Variant); ok {
+								parseStreamProperties(&stream, props)
+							}
+						}
+						parsed = append(parsed, stream)
+					}
+					if len(parsed) > 0 {
+						return parsed, nil
+					}
 				}
 
-				return 0, fmt.Errorf("no streams in response")
+				return nil, fmt.Errorf("no streams in response")
 			}
 		}
 	}
 }
 
+// parseStreamProperties extracts the logical position and size of a stream
+// from its portal properties. Both are (ii) structs in compositor
+// coordinates, which lets us derive the output scale factor later.
+func parseStreamProperties(stream *PortalStream, props map[string]dbus.Variant) {
+	log := logger.WithComponent("portal")
+
+	if pos, ok := props["position"]; ok {
+		if x, y, ok := parseIntPair(pos.Value()); ok {
+			stream.X, stream.Y = x, y
+			stream.Positioned = true
+		}
+	}
+	if size, ok := props["size"]; ok {
+		if w, h, ok := parseIntPair(size.Value()); ok {
+			stream.Width, stream.Height = w, h
+		}
+	}
+	if id, ok := props["id"].Value().(string); ok {
+		stream.ID = id
+	}
+	if id, ok := props["mapping_id"].Value().(string); ok {
+		stream.MappingID = id
+	}
+	if sourceType, ok := props["source_type"].Value().(uint32); ok {
+		stream.SourceType = sourceType
+	}
+
+	log.Debug().
+		Uint32("node_id", stream.NodeID).
+		Int("x", stream.X).
+		Int("y", stream.Y).
+		Int("width", stream.Width).
+		Int("height", stream.Height).
+		Str("source_type", sourceTypeName(stream.SourceType)).
+		Msg("Stream logical geometry")
+}
+
+// parseIntPair decodes a D-Bus (ii) struct value
+func parseIntPair(v interface{}) (int, int, bool) {
+	pair, ok := v.([]interface{})
+	if !ok || len(pair) != 2 {
+		return 0, 0, false
+	}
+	a, ok1 := pair[0].(int32)
+	b, ok2 := pair[1].(int32)
+	if !ok1 || !ok2 {
+		return 0, 0, false
+	}
+	return int(a), int(b), true
+}
+
 // loadRestoreToken loads the restore token from disk
 func (p *Portal) loadRestoreToken() {
 	data, err := os.ReadFile(p.tokenPath)
@@ -381,6 +635,14 @@ func (p *Portal) loadRestoreToken() {
 	p.restoreToken = token.Token
 }
 
+// clearRestoreToken forgets a restore token the portal no longer accepts
+func (p *Portal) clearRestoreToken() {
+	p.restoreToken = ""
+	if err := os.Remove(p.tokenPath); err != nil && !os.IsNotExist(err) {
+		logger.WithComponent("portal").Warn().Err(err).Msg("Failed to remove restore token")
+	}
+}
+
 // saveRestoreToken saves the restore token to disk
 func (p *Portal) saveRestoreToken() {
 	if p.restoreToken == "" {
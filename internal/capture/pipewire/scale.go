@@ -0,0 +1,127 @@
+package pipewire
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// OutputScale describes a compositor output in logical coordinates along with
+// its fractional scale factor
+type OutputScale struct {
+	Name   string  `json:"name"`
+	X      int     `json:"x"`
+	Y      int     `json:"y"`
+	Width  int     `json:"width"`  // Logical width
+	Height int     `json:"height"` // Logical height
+	Scale  float64 `json:"scale"`
+}
+
+// Contains reports whether the logical point (x, y) lies on this output
+func (o OutputScale) Contains(x, y int) bool {
+	return x >= o.X && x < o.X+o.Width && y >= o.Y && y < o.Y+o.Height
+}
+
+// outputScaleTimeout bounds the KWin query, which runs while capture starts
+const outputScaleTimeout = 2 * time.Second
+
+// DiscoverOutputScales asks KWin over D-Bus for the scale factor and logical
+// geometry of each enabled output
+func DiscoverOutputScales() ([]OutputScale, error) {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), outputScaleTimeout)
+	defer cancel()
+	var info string
+	if err := conn.Object("org.kde.KWin", "/KWin").CallWithContext(ctx, "org.kde.KWin.supportInformation", 0).Store(&info); err != nil {
+		return nil, fmt.Errorf("failed to query KWin support information: %w", err)
+	}
+
+	outputs := parseOutputScales(info)
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("no outputs in KWin support information")
+	}
+	return outputs, nil
+}
+
+// parseOutputScales reads the enabled outputs from the "Screen N:" blocks of
+// KWin's support information, where Geometry is "x,y,widthxheight" in
+// logical pixels. Outputs without a parsable geometry are skipped.
+func parseOutputScales(info string) []OutputScale {
+	var outputs []OutputScale
+	var current *OutputScale
+	var enabled, placed bool
+	flush := func() {
+		if current != nil && enabled && placed {
+			if current.Scale <= 0 {
+				current.Scale = 1.0
+			}
+			outputs = append(outputs, *current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(info, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "Screen ") && strings.HasSuffix(line, ":") {
+			flush()
+			current, enabled, placed = &OutputScale{}, true, false
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			// A blank line or a new section ends the block
+			if line == "" || !strings.HasPrefix(line, "-") {
+				flush()
+			}
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch key {
+		case "Name":
+			current.Name = value
+		case "Enabled":
+			enabled = value != "0" && value != "false"
+		case "Geometry":
+			if _, err := fmt.Sscanf(value, "%d,%d,%dx%d", &current.X, &current.Y, &current.Width, &current.Height); err == nil {
+				placed = current.Width > 0 && current.Height > 0
+			}
+		case "Scale":
+			if scale, err := strconv.ParseFloat(value, 64); err == nil {
+				current.Scale = scale
+			}
+		}
+	}
+	flush()
+	return outputs
+}
+
+// scaleMapping converts logical window geometry into physical frame pixels
+type scaleMapping struct {
+	originX int     // Logical X of the captured output
+	originY int     // Logical Y of the captured output
+	scaleX  float64 // Physical pixels per logical pixel (horizontal)
+	scaleY  float64 // Physical pixels per logical pixel (vertical)
+}
+
+// identityMapping is used when no scaling information is available
+var identityMapping = scaleMapping{scaleX: 1.0, scaleY: 1.0}
+
+// toPhysical converts a logical rectangle to physical frame coordinates
+func (m scaleMapping) toPhysical(x, y, width, height int) (int, int, int, int) {
+	px := int(float64(x-m.originX)*m.scaleX + 0.5)
+	py := int(float64(y-m.originY)*m.scaleY + 0.5)
+	pw := int(float64(width)*m.scaleX + 0.5)
+	ph := int(float64(height)*m.scaleY + 0.5)
+	return px, py, pw, ph
+}
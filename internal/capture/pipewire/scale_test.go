@@ -0,0 +1,75 @@
+package pipewire
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseOutputScales(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want []OutputScale
+	}{
+		{
+			name: "two outputs",
+			info: `Screens
+=======
+Active screen follows mouse:  yes
+Number of Screens: 2
+
+Screen 0:
+---------
+Name: eDP-1
+Enabled: 1
+Geometry: 0,0,1536x960
+Scale: 1.25
+Refresh Rate: 60001
+Adaptive Sync: incapable
+
+Screen 1:
+---------
+Name: DP-2
+Enabled: 1
+Geometry: 1536,0,2560x1440
+Scale: 1
+Refresh Rate: 143912
+Adaptive Sync: automatic
+
+Compositing
+===========
+`,
+			want: []OutputScale{
+				{Name: "eDP-1", X: 0, Y: 0, Width: 1536, Height: 960, Scale: 1.25},
+				{Name: "DP-2", X: 1536, Y: 0, Width: 2560, Height: 1440, Scale: 1},
+			},
+		},
+		{
+			name: "disabled output skipped",
+			info: "Screen 0:\n---------\nName: HDMI-A-1\nEnabled: 0\nGeometry: 0,0,1920x1080\nScale: 1\n\nScreen 1:\n---------\nName: DP-1\nEnabled: 1\nGeometry: 0,0,1280x720\nScale: 1.5\n",
+			want: []OutputScale{{Name: "DP-1", X: 0, Y: 0, Width: 1280, Height: 720, Scale: 1.5}},
+		},
+		{
+			name: "missing scale and enabled default",
+			info: "Screen 0:\n---------\nName: DP-1\nGeometry: -1920,0,1920x1080\n",
+			want: []OutputScale{{Name: "DP-1", X: -1920, Y: 0, Width: 1920, Height: 1080, Scale: 1}},
+		},
+		{
+			name: "unparsable geometry skipped",
+			info: "Screen 0:\n---------\nName: DP-1\nGeometry: unknown\nScale: 2\n",
+			want: nil,
+		},
+		{
+			name: "no screens",
+			info: "KWin Support Information:\nVersion\n=======\nKWin version: 6.2.4\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseOutputScales(tt.info); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOutputScales() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package pipewire
+
+import (
+	"sync"
+	"time"
+)
+
+// SessionState is a stage of the portal screen share session's lifecycle
+type SessionState string
+
+const (
+	SessionInactive    SessionState = "inactive"    // Never started
+	SessionStarting    SessionState = "starting"    // Opening the session, with the restore token if there is one
+	SessionAuthorizing SessionState = "authorizing" // Waiting on the portal's screen share dialog
+	SessionActive      SessionState = "active"      // Streaming
+	SessionRestoring   SessionState = "restoring"   // Closed by the portal, reopening with the restore token
+	SessionClosed      SessionState = "closed"      // Stopped, or could not be restored
+)
+
+// dialogDelay is how long a portal request may take before it is assumed
+// to be waiting on the user, i.e. the restore token wasn't accepted
+const dialogDelay = 2 * time.Second
+
+// SessionEvent reports a change of the portal session's state
+type SessionEvent struct {
+	State    SessionState `json:"state"`
+	Reason   string       `json:"reason,omitempty"`
+	Restores int          `json:"restores"` // Sessions reopened after the portal closed them
+	Time     time.Time    `json:"time"`
+}
+
+// sessionBroadcaster keeps the latest session state and fans changes out
+// to subscribers. There is one portal session per process, so it is
+// shared by every capturer.
+type sessionBroadcaster struct {
+	mu        sync.Mutex
+	current   SessionEvent
+	restoring bool
+	subs      map[chan SessionEvent]struct{}
+}
+
+var sessionEvents = &sessionBroadcaster{
+	current: SessionEvent{State: SessionInactive},
+	subs:    make(map[chan SessionEvent]struct{}),
+}
+
+// publish records a state change and sends it to subscribers, dropping it
+// for subscribers that aren't keeping up
+func (b *sessionBroadcaster) publish(state SessionState, reason string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch state {
+	case SessionRestoring:
+		b.restoring = true
+	case SessionActive:
+		if b.restoring {
+			b.current.Restores++
+		}
+		b.restoring = false
+	case SessionClosed:
+		b.restoring = false
+	}
+
+	b.current.State = state
+	b.current.Reason = reason
+	b.current.Time = time.Now()
+	for ch := range b.subs {
+		select {
+		case ch <- b.current:
+		default:
+		}
+	}
+}
+
+// CurrentSession returns the portal session's latest state
+func CurrentSession() SessionEvent {
+	sessionEvents.mu.Lock()
+	defer sessionEvents.mu.Unlock()
+	return sessionEvents.current
+}
+
+// SubscribeSession returns a channel receiving portal session state
+// changes. Call UnsubscribeSession when done.
+func SubscribeSession() chan SessionEvent {
+	ch := make(chan SessionEvent, 8)
+	sessionEvents.mu.Lock()
+	sessionEvents.subs[ch] = struct{}{}
+	sessionEvents.mu.Unlock()
+	return ch
+}
+
+// UnsubscribeSession stops and closes a channel from SubscribeSession
+func UnsubscribeSession(ch chan SessionEvent) {
+	sessionEvents.mu.Lock()
+	defer sessionEvents.mu.Unlock()
+	if _, ok := sessionEvents.subs[ch]; ok {
+		delete(sessionEvents.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,54 @@
+package pipewire
+
+import (
+	"image"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+)
+
+// frameSource delivers the frames of a PipeWire stream: the in-process
+// NativeStream or the gst-launch GStreamerSubprocess
+type frameSource interface {
+	Stop() error
+	IsRunning() bool
+
+	// GetLatestFrame and CropFrame return pooled copies owned by the caller
+	GetLatestFrame() *image.RGBA
+	CropFrame(x, y, width, height int) *image.RGBA
+
+	// GetFrameSize is the size of the frames delivered; GetSourceSize is
+	// the size of the stream, larger when frames are downscaled
+	GetFrameSize() (width, height int)
+	GetSourceSize() (width, height int)
+
+	// Mode reports how frames are delivered (ModeNative, ModeCopy or ModeDMABuf)
+	Mode() string
+}
+
+// cropFrame copies a region of frame, clamped to its bounds, into a pooled
+// image. It returns nil if frame is nil or the region is empty.
+func cropFrame(frame *image.RGBA, x, y, width, height int) *image.RGBA {
+	if frame == nil {
+		return nil
+	}
+
+	frameBounds := frame.Bounds()
+	if x < 0 {
+		x = 0
+	}
+	if y < 0 {
+		y = 0
+	}
+	if x+width > frameBounds.Dx() {
+		width = frameBounds.Dx() - x
+	}
+	if y+height > frameBounds.Dy() {
+		height = frameBounds.Dy() - y
+	}
+
+	if width <= 0 || height <= 0 {
+		return nil
+	}
+
+	return framepool.Clone(frame.SubImage(image.Rect(x, y, x+width, y+height)).(*image.RGBA))
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package capture
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// PlatformCapturer is unavailable outside Windows, where the X11 and
+// PipeWire capturers are used instead
+type PlatformCapturer struct{}
+
+// NewPlatformCapturer reports that this platform has no native capturer
+func NewPlatformCapturer() (*PlatformCapturer, error) {
+	return nil, fmt.Errorf("no platform capturer on this OS (use X11 or PipeWire)")
+}
+
+func (c *PlatformCapturer) Name() string                       { return "none" }
+func (c *PlatformCapturer) IsAvailable() bool                  { return false }
+func (c *PlatformCapturer) Start() error                       { return fmt.Errorf("not supported") }
+func (c *PlatformCapturer) Stop() error                        { return nil }
+func (c *PlatformCapturer) Disconnected() <-chan struct{}      { return nil }
+func (c *PlatformCapturer) CanCapture(*config.WindowInfo) bool { return false }
+func (c *PlatformCapturer) CaptureWindow(*config.WindowInfo) (*image.RGBA, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (c *PlatformCapturer) CaptureRegion(x, y, width, height int) (*image.RGBA, error) {
+	return nil, fmt.Errorf("not supported")
+}
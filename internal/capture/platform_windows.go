@@ -0,0 +1,400 @@
+package capture
+
+import (
+	"fmt"
+	"image"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+)
+
+// DXGI desktop duplication through raw COM calls, so Windows builds need no
+// cgo. Method indices are positions in each interface's vtable, counting
+// the inherited IUnknown (and IDXGIObject / ID3D11DeviceChild) methods.
+const (
+	methodQueryInterface = 0
+	methodRelease        = 2
+
+	dxgiDeviceGetAdapter       = 7
+	dxgiAdapterEnumOutputs     = 7
+	dxgiOutputGetDesc          = 7
+	dxgiOutput1DuplicateOutput = 22
+	duplAcquireNextFrame       = 8
+	duplReleaseFrame           = 14
+	d3dDeviceCreateTexture2D   = 5
+	d3dTexture2DGetDesc        = 10
+	d3dContextMap              = 14
+	d3dContextUnmap            = 15
+	d3dContextCopyResource     = 47
+
+	d3dDriverTypeHardware = 1
+	d3dSDKVersion         = 7
+	d3dUsageStaging       = 3
+	d3dCPUAccessRead      = 0x20000
+	d3dMapRead            = 1
+
+	dxgiErrorNotFound    = 0x887A0002
+	dxgiErrorAccessLost  = 0x887A0026
+	dxgiErrorWaitTimeout = 0x887A0027
+
+	// dxgiFrameTimeoutMs is how long a capture waits for a new desktop
+	// frame before reusing the last one. Duplication only delivers frames
+	// when the screen changes.
+	dxgiFrameTimeoutMs = 50
+)
+
+var (
+	d3d11                 = syscall.NewLazyDLL("d3d11.dll")
+	procD3D11CreateDevice = d3d11.NewProc("D3D11CreateDevice")
+
+	iidIDXGIDevice     = syscall.GUID{Data1: 0x54ec77fa, Data2: 0x1377, Data3: 0x44e6, Data4: [8]byte{0x8c, 0x32, 0x88, 0xfd, 0x5f, 0x44, 0xc8, 0x4c}}
+	iidIDXGIOutput1    = syscall.GUID{Data1: 0x00cddea8, Data2: 0x939b, Data3: 0x4b83, Data4: [8]byte{0xa3, 0x40, 0xa6, 0x85, 0x22, 0x66, 0x66, 0xcc}}
+	iidID3D11Texture2D = syscall.GUID{Data1: 0x6f15aaf2, Data2: 0xd208, Data3: 0x4e89, Data4: [8]byte{0x9a, 0xb4, 0x48, 0x95, 0x35, 0xd3, 0x4f, 0x9c}}
+)
+
+// comObject is a COM interface pointer: the object starts with its vtable
+type comObject struct {
+	vtbl *[64]uintptr
+}
+
+// call invokes a method of the object, returning its HRESULT
+//
+//go:uintptrescapes
+func (o *comObject) call(method int, args ...uintptr) uint32 {
+	r, _, _ := syscall.SyscallN(o.vtbl[method], append([]uintptr{uintptr(unsafe.Pointer(o))}, args...)...)
+	return uint32(r)
+}
+
+// release drops a reference, ignoring nil objects
+func (o *comObject) release() {
+	if o != nil {
+		o.call(methodRelease)
+	}
+}
+
+func hresultError(op string, hr uint32) error {
+	return fmt.Errorf("%s failed: HRESULT 0x%08X", op, hr)
+}
+
+// dxgiOutputDesc is DXGI_OUTPUT_DESC
+type dxgiOutputDesc struct {
+	DeviceName        [32]uint16
+	Left, Top         int32
+	Right, Bottom     int32
+	AttachedToDesktop int32
+	Rotation          uint32
+	Monitor           uintptr
+}
+
+// dxgiFrameInfo is DXGI_OUTDUPL_FRAME_INFO
+type dxgiFrameInfo struct {
+	LastPresentTime           int64
+	LastMouseUpdateTime       int64
+	AccumulatedFrames         uint32
+	RectsCoalesced            int32
+	ProtectedContentMaskedOut int32
+	PointerX, PointerY        int32
+	PointerVisible            int32
+	TotalMetadataBufferSize   uint32
+	PointerShapeBufferSize    uint32
+}
+
+// d3dTexture2DDesc is D3D11_TEXTURE2D_DESC
+type d3dTexture2DDesc struct {
+	Width, Height  uint32
+	MipLevels      uint32
+	ArraySize      uint32
+	Format         uint32
+	SampleCount    uint32
+	SampleQuality  uint32
+	Usage          uint32
+	BindFlags      uint32
+	CPUAccessFlags uint32
+	MiscFlags      uint32
+}
+
+// d3dMappedSubresource is D3D11_MAPPED_SUBRESOURCE
+type d3dMappedSubresource struct {
+	Data       unsafe.Pointer
+	RowPitch   uint32
+	DepthPitch uint32
+}
+
+// dxgiOutput duplicates one monitor. desktop keeps its last frame as
+// tightly packed BGRA, since duplication only reports changes.
+type dxgiOutput struct {
+	bounds      image.Rectangle // Desktop coordinates
+	duplication *comObject
+	staging     *comObject
+	desktop     []byte
+	hasFrame    bool
+}
+
+// PlatformCapturer captures windows on Windows with DXGI desktop
+// duplication, cropping the monitor under the window to its bounds. Like
+// PipeWire monitor capture, windows must be unobscured to be captured,
+// which holds for the focused window.
+type PlatformCapturer struct {
+	device  *comObject
+	context *comObject
+	outputs []*dxgiOutput
+	lost    chan struct{}
+	mu      sync.Mutex
+}
+
+// NewPlatformCapturer creates a DXGI desktop duplication capturer
+func NewPlatformCapturer() (*PlatformCapturer, error) {
+	if err := procD3D11CreateDevice.Find(); err != nil {
+		return nil, fmt.Errorf("Direct3D 11 not available: %w", err)
+	}
+	return &PlatformCapturer{lost: make(chan struct{})}, nil
+}
+
+// Name returns the capturer name
+func (c *PlatformCapturer) Name() string {
+	return "dxgi"
+}
+
+// IsAvailable returns true if DXGI duplication started
+func (c *PlatformCapturer) IsAvailable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.outputs) > 0
+}
+
+// Start creates the Direct3D device and duplicates every monitor
+func (c *PlatformCapturer) Start() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var featureLevel uint32
+	hr, _, _ := procD3D11CreateDevice.Call(
+		0, d3dDriverTypeHardware, 0, 0, 0, 0, d3dSDKVersion,
+		uintptr(unsafe.Pointer(&c.device)),
+		uintptr(unsafe.Pointer(&featureLevel)),
+		uintptr(unsafe.Pointer(&c.context)),
+	)
+	if uint32(hr) != 0 {
+		return hresultError("D3D11CreateDevice", uint32(hr))
+	}
+
+	var dxgiDevice, adapter *comObject
+	defer func() {
+		adapter.release()
+		dxgiDevice.release()
+	}()
+	if hr := c.device.call(methodQueryInterface, uintptr(unsafe.Pointer(&iidIDXGIDevice)), uintptr(unsafe.Pointer(&dxgiDevice))); hr != 0 {
+		c.releaseLocked()
+		return hresultError("QueryInterface(IDXGIDevice)", hr)
+	}
+	if hr := dxgiDevice.call(dxgiDeviceGetAdapter, uintptr(unsafe.Pointer(&adapter))); hr != 0 {
+		c.releaseLocked()
+		return hresultError("IDXGIDevice.GetAdapter", hr)
+	}
+
+	log := logger.WithComponent("dxgi-capturer")
+	for i := uintptr(0); ; i++ {
+		var output *comObject
+		hr := adapter.call(dxgiAdapterEnumOutputs, i, uintptr(unsafe.Pointer(&output)))
+		if hr == dxgiErrorNotFound {
+			break
+		}
+		if hr != 0 {
+			c.releaseLocked()
+			return hresultError("IDXGIAdapter.EnumOutputs", hr)
+		}
+
+		out, err := c.duplicate(output)
+		output.release()
+		if err != nil {
+			log.Warn().Err(err).Uint("output", uint(i)).Msg("Failed to duplicate output")
+			continue
+		}
+		c.outputs = append(c.outputs, out)
+		log.Info().Str("bounds", out.bounds.String()).Msg("Duplicating output")
+	}
+
+	if len(c.outputs) == 0 {
+		c.releaseLocked()
+		return fmt.Errorf("no outputs could be duplicated")
+	}
+	return nil
+}
+
+// duplicate starts desktop duplication of an output
+func (c *PlatformCapturer) duplicate(output *comObject) (*dxgiOutput, error) {
+	var desc dxgiOutputDesc
+	if hr := output.call(dxgiOutputGetDesc, uintptr(unsafe.Pointer(&desc))); hr != 0 {
+		return nil, hresultError("IDXGIOutput.GetDesc", hr)
+	}
+
+	var output1 *comObject
+	if hr := output.call(methodQueryInterface, uintptr(unsafe.Pointer(&iidIDXGIOutput1)), uintptr(unsafe.Pointer(&output1))); hr != 0 {
+		return nil, hresultError("QueryInterface(IDXGIOutput1)", hr)
+	}
+	defer output1.release()
+
+	out := &dxgiOutput{bounds: image.Rect(int(desc.Left), int(desc.Top), int(desc.Right), int(desc.Bottom))}
+	if hr := output1.call(dxgiOutput1DuplicateOutput, uintptr(unsafe.Pointer(c.device)), uintptr(unsafe.Pointer(&out.duplication))); hr != 0 {
+		return nil, hresultError("IDXGIOutput1.DuplicateOutput", hr)
+	}
+	return out, nil
+}
+
+// Stop releases the duplications and the device
+func (c *PlatformCapturer) Stop() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.releaseLocked()
+	return nil
+}
+
+func (c *PlatformCapturer) releaseLocked() {
+	for _, out := range c.outputs {
+		out.staging.release()
+		out.duplication.release()
+	}
+	c.outputs = nil
+	c.context.release()
+	c.context = nil
+	c.device.release()
+	c.device = nil
+}
+
+// Disconnected returns a channel that is closed when duplication is lost
+// for good (e.g. a mode change or the secure desktop), so the router
+// restarts the capturer
+func (c *PlatformCapturer) Disconnected() <-chan struct{} {
+	return c.lost
+}
+
+// CanCapture reports whether the window lies on a duplicated monitor
+func (c *PlatformCapturer) CanCapture(window *config.WindowInfo) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.outputFor(windowRect(window)) != nil
+}
+
+// CaptureWindow captures the window's bounds from its monitor
+func (c *PlatformCapturer) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
+	return c.CaptureRegion(window.Geometry.X, window.Geometry.Y, window.Geometry.Width, window.Geometry.Height)
+}
+
+// CaptureRegion captures a region in desktop coordinates, clipped to the
+// monitor showing most of it
+func (c *PlatformCapturer) CaptureRegion(x, y, width, height int) (*image.RGBA, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rect := image.Rect(x, y, x+width, y+height)
+	out := c.outputFor(rect)
+	if out == nil {
+		return nil, fmt.Errorf("region %v is not on a duplicated output", rect)
+	}
+	if err := c.updateDesktop(out); err != nil {
+		return nil, err
+	}
+	if !out.hasFrame {
+		return nil, fmt.Errorf("no desktop frame received yet")
+	}
+
+	rect = rect.Intersect(out.bounds)
+	img := framepool.Get(rect.Dx(), rect.Dy())
+	stride := out.bounds.Dx() * 4
+	offsetX, offsetY := rect.Min.X-out.bounds.Min.X, rect.Min.Y-out.bounds.Min.Y
+	pixconv.ParallelRows(rect.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			src := out.desktop[(offsetY+y)*stride+offsetX*4:]
+			pixconv.SwapRedBlue(img.Pix[y*img.Stride:y*img.Stride+rect.Dx()*4], src, false)
+		}
+	})
+	return img, nil
+}
+
+// outputFor returns the output showing most of a rect, or nil
+func (c *PlatformCapturer) outputFor(rect image.Rectangle) *dxgiOutput {
+	var best *dxgiOutput
+	bestArea := 0
+	for _, out := range c.outputs {
+		overlap := rect.Intersect(out.bounds)
+		if area := overlap.Dx() * overlap.Dy(); area > bestArea {
+			best, bestArea = out, area
+		}
+	}
+	return best
+}
+
+// updateDesktop copies the output's next frame into its desktop buffer,
+// keeping the last one if the screen hasn't changed
+func (c *PlatformCapturer) updateDesktop(out *dxgiOutput) error {
+	var info dxgiFrameInfo
+	var resource *comObject
+	hr := out.duplication.call(duplAcquireNextFrame, dxgiFrameTimeoutMs, uintptr(unsafe.Pointer(&info)), uintptr(unsafe.Pointer(&resource)))
+	switch hr {
+	case 0:
+	case dxgiErrorWaitTimeout:
+		return nil
+	case dxgiErrorAccessLost:
+		select {
+		case <-c.lost:
+		default:
+			close(c.lost)
+		}
+		return fmt.Errorf("desktop duplication access lost")
+	default:
+		return hresultError("IDXGIOutputDuplication.AcquireNextFrame", hr)
+	}
+	defer out.duplication.call(duplReleaseFrame)
+	defer resource.release()
+
+	var texture *comObject
+	if hr := resource.call(methodQueryInterface, uintptr(unsafe.Pointer(&iidID3D11Texture2D)), uintptr(unsafe.Pointer(&texture))); hr != 0 {
+		return hresultError("QueryInterface(ID3D11Texture2D)", hr)
+	}
+	defer texture.release()
+
+	if out.staging == nil {
+		var desc d3dTexture2DDesc
+		texture.call(d3dTexture2DGetDesc, uintptr(unsafe.Pointer(&desc)))
+		desc.MipLevels, desc.ArraySize = 1, 1
+		desc.Usage = d3dUsageStaging
+		desc.BindFlags = 0
+		desc.CPUAccessFlags = d3dCPUAccessRead
+		desc.MiscFlags = 0
+		if hr := c.device.call(d3dDeviceCreateTexture2D, uintptr(unsafe.Pointer(&desc)), 0, uintptr(unsafe.Pointer(&out.staging))); hr != 0 {
+			return hresultError("ID3D11Device.CreateTexture2D", hr)
+		}
+	}
+
+	c.context.call(d3dContextCopyResource, uintptr(unsafe.Pointer(out.staging)), uintptr(unsafe.Pointer(texture)))
+
+	var mapped d3dMappedSubresource
+	if hr := c.context.call(d3dContextMap, uintptr(unsafe.Pointer(out.staging)), 0, d3dMapRead, 0, uintptr(unsafe.Pointer(&mapped))); hr != 0 {
+		return hresultError("ID3D11DeviceContext.Map", hr)
+	}
+	defer c.context.call(d3dContextUnmap, uintptr(unsafe.Pointer(out.staging)), 0)
+
+	width, height := out.bounds.Dx(), out.bounds.Dy()
+	rowBytes := width * 4
+	if len(out.desktop) != rowBytes*height {
+		out.desktop = make([]byte, rowBytes*height)
+	}
+	pitch := int(mapped.RowPitch)
+	src := unsafe.Slice((*byte)(mapped.Data), pitch*height)
+	for y := 0; y < height; y++ {
+		copy(out.desktop[y*rowBytes:(y+1)*rowBytes], src[y*pitch:y*pitch+rowBytes])
+	}
+	out.hasFrame = true
+	return nil
+}
+
+// windowRect returns a window's bounds in desktop coordinates
+func windowRect(window *config.WindowInfo) image.Rectangle {
+	g := window.Geometry
+	return image.Rect(g.X, g.Y, g.X+g.Width, g.Y+g.Height)
+}
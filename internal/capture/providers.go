@@ -0,0 +1,153 @@
+package capture
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// CapturerDriver creates a capturer registered from outside this package,
+// e.g. for a proprietary compositor's screen capture API
+type CapturerDriver struct {
+	// New creates the capturer; the router starts it
+	New func() (Capturer, error)
+
+	// Description is a short human-readable summary for provider listings
+	Description string
+
+	// Priority orders registered capturers, highest first. The router asks
+	// them in that order, before the built-in ones, whether they can capture
+	// a window.
+	Priority int
+}
+
+// CapturerProvider describes a built-in or registered capturer
+type CapturerProvider struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Builtin     bool   `json:"builtin"`
+	Priority    int    `json:"priority"`
+	Active      bool   `json:"active"` // Started by the router
+}
+
+// builtinCapturers lists the capturers the router constructs itself
+var builtinCapturers = []CapturerProvider{
+	{Name: "x11", Description: "X11 and XWayland windows through XGetImage"},
+	{Name: "pipewire", Description: "Wayland windows through the ScreenCast portal"},
+	{Name: "dxgi", Description: "Windows desktop duplication"},
+	{Name: "synthetic", Description: "Procedurally drawn frames (demo mode)"},
+	{Name: "remote", Description: "Frames from a capture agent (agent mode)"},
+}
+
+var (
+	capturerDriversMu sync.RWMutex
+	capturerDrivers   = make(map[string]CapturerDriver)
+)
+
+// RegisterCapturer makes a capturer available to every router started
+// afterwards. It is meant to be called from the init function of the package
+// providing the capturer, like database/sql drivers, and panics if the name
+// is empty, taken or New is nil.
+func RegisterCapturer(name string, driver CapturerDriver) {
+	capturerDriversMu.Lock()
+	defer capturerDriversMu.Unlock()
+
+	if name == "" {
+		panic("capture: RegisterCapturer with an empty name")
+	}
+	if driver.New == nil {
+		panic("capture: RegisterCapturer " + name + " without a New function")
+	}
+	if _, dup := capturerDrivers[name]; dup {
+		panic("capture: RegisterCapturer called twice for " + name)
+	}
+	for _, builtin := range builtinCapturers {
+		if builtin.Name == name {
+			panic("capture: RegisterCapturer " + name + " shadows a built-in capturer")
+		}
+	}
+	capturerDrivers[name] = driver
+	config.RegisterCapturerName(name)
+}
+
+// namedCapturer is a registered capturer started by a router
+type namedCapturer struct {
+	name string
+	Capturer
+}
+
+// registeredCapturerNames returns the registered capturers' names, highest
+// priority first
+func registeredCapturerNames() []string {
+	capturerDriversMu.RLock()
+	defer capturerDriversMu.RUnlock()
+
+	names := make([]string, 0, len(capturerDrivers))
+	for name := range capturerDrivers {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := capturerDrivers[names[i]].Priority, capturerDrivers[names[j]].Priority
+		if pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// newRegisteredCapturer creates and starts a registered capturer
+func newRegisteredCapturer(name string) (Capturer, error) {
+	capturerDriversMu.RLock()
+	driver := capturerDrivers[name]
+	capturerDriversMu.RUnlock()
+
+	c, err := driver.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s capturer: %w", name, err)
+	}
+	if err := c.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s capturer: %w", name, err)
+	}
+	return c, nil
+}
+
+// Providers lists the built-in and registered capturers, marking the ones
+// this router started
+func (r *Router) Providers() []CapturerProvider {
+	r.mu.RLock()
+	active := map[string]bool{
+		"x11":       r.x11Capturer != nil,
+		"pipewire":  r.pipewireCapturer != nil,
+		"synthetic": r.synthetic != nil,
+		"remote":    r.remote != nil,
+	}
+	if r.platform != nil {
+		active[r.platform.Name()] = true
+	}
+	for _, c := range r.registered {
+		active[c.name] = true
+	}
+	r.mu.RUnlock()
+
+	providers := make([]CapturerProvider, 0, len(builtinCapturers))
+	for _, p := range builtinCapturers {
+		p.Builtin = true
+		p.Active = active[p.Name]
+		providers = append(providers, p)
+	}
+	for _, name := range registeredCapturerNames() {
+		capturerDriversMu.RLock()
+		driver := capturerDrivers[name]
+		capturerDriversMu.RUnlock()
+		providers = append(providers, CapturerProvider{
+			Name:        name,
+			Description: driver.Description,
+			Priority:    driver.Priority,
+			Active:      active[name],
+		})
+	}
+	return providers
+}
@@ -14,6 +14,13 @@ import (
 type Router struct {
 	x11Capturer      *X11Capturer
 	pipewireCapturer *pipewire.Capturer
+	platform         *PlatformCapturer    // Windows: replaces the X11 and PipeWire capturers
+	synthetic        *SyntheticCapturer   // Demo mode: replaces all real capturers
+	remote           Capturer             // Agent mode: frames from a capture agent, replaces all real capturers
+	registered       []namedCapturer      // Capturers from RegisterCapturer, highest priority first
+	pipewireConfig   config.CaptureConfig // DMA-BUF, monitor and pipeline settings for the PipeWire capturer
+	routeRules       func(class string) (string, bool)
+	overrides        map[uint32]string // Capturer by window ID, from SetWindowRoute
 	mu               sync.RWMutex
 	started          bool
 }
@@ -23,6 +30,106 @@ func NewRouter() (*Router, error) {
 	return &Router{}, nil
 }
 
+// NewSyntheticRouter creates a capture router that only uses the synthetic
+// capturer (demo mode, no display server required)
+func NewSyntheticRouter() *Router {
+	return &Router{synthetic: NewSyntheticCapturer()}
+}
+
+// NewRemoteRouter creates a capture router that only uses a capture agent's
+// frames (agent mode, see the remote package)
+func NewRemoteRouter(remote Capturer) *Router {
+	return &Router{remote: remote}
+}
+
+// SetPipeWireConfig configures the PipeWire capturer: the zero-copy
+// pipeline, sharing every monitor and the GStreamer pipeline template. It
+// takes effect the next time the capturers start.
+func (r *Router) SetPipeWireConfig(cfg config.CaptureConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pipewireConfig = cfg
+}
+
+// SetRouteRules sets the lookup of the capturer configured for a window
+// class (capture.routes). It is consulted on every capture, so rule changes
+// apply right away.
+func (r *Router) SetRouteRules(rules func(class string) (string, bool)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routeRules = rules
+}
+
+// SetWindowRoute sends a window to the named capturer, ahead of the route
+// rules. An empty name or "auto" removes the window's override.
+func (r *Router) SetWindowRoute(windowID uint32, capturer string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if capturer == "" || capturer == "auto" {
+		delete(r.overrides, windowID)
+		return nil
+	}
+	if !config.IsValidCapturer(capturer) {
+		return fmt.Errorf("unknown capturer %q (use auto, x11, pipewire or a registered capturer)", capturer)
+	}
+	if r.overrides == nil {
+		r.overrides = make(map[uint32]string)
+	}
+	r.overrides[windowID] = capturer
+	return nil
+}
+
+// WindowRoutes returns the per-window capturer overrides by window ID
+func (r *Router) WindowRoutes() map[uint32]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	routes := make(map[uint32]string, len(r.overrides))
+	for id, capturer := range r.overrides {
+		routes[id] = capturer
+	}
+	return routes
+}
+
+// Route describes how a window's capturer is chosen
+type Route struct {
+	WindowID  uint32 `json:"window_id"`
+	Class     string `json:"class"`
+	Requested string `json:"requested,omitempty"` // From the override or rule
+	Source    string `json:"source"`              // "override", "rule" or "auto"
+	Capturer  string `json:"capturer"`            // In use; differs from Requested while it can't capture the window
+}
+
+// Route returns the capturer requested for a window and the one in use
+func (r *Router) Route(window *config.WindowInfo) Route {
+	route := Route{WindowID: window.ID, Class: window.Class, Source: "auto"}
+	if requested, source, ok := r.requestedCapturer(window); ok {
+		route.Requested = requested
+		route.Source = source
+	}
+	route.Capturer = r.CapturerName(window)
+	return route
+}
+
+// requestedCapturer returns the capturer a window's override, or else its
+// class's route rule, asks for, and which of them asked
+func (r *Router) requestedCapturer(window *config.WindowInfo) (string, string, bool) {
+	r.mu.RLock()
+	capturer, ok := r.overrides[window.ID]
+	rules := r.routeRules
+	r.mu.RUnlock()
+
+	if ok {
+		return capturer, "override", true
+	}
+	if rules != nil {
+		if capturer, ok := rules(window.Class); ok {
+			return capturer, "rule", true
+		}
+	}
+	return "", "", false
+}
+
 // Start initializes the available capturers
 func (r *Router) Start() error {
 	r.mu.Lock()
@@ -34,6 +141,45 @@ func (r *Router) Start() error {
 
 	log := logger.WithComponent("capture-router")
 
+	if r.synthetic != nil {
+		if err := r.synthetic.Start(); err != nil {
+			return fmt.Errorf("failed to start synthetic capturer: %w", err)
+		}
+		log.Info().Msg("Synthetic capturer initialized")
+		r.started = true
+		return nil
+	}
+	if r.remote != nil {
+		if err := r.remote.Start(); err != nil {
+			return fmt.Errorf("failed to start remote capturer: %w", err)
+		}
+		r.started = true
+		return nil
+	}
+
+	// Registered capturers come first; a failing one is skipped so the
+	// built-in capturers still work
+	for _, name := range registeredCapturerNames() {
+		c, err := newRegisteredCapturer(name)
+		if err != nil {
+			log.Warn().Err(err).Msg("Registered capturer not available")
+			continue
+		}
+		r.registered = append(r.registered, namedCapturer{name: name, Capturer: c})
+		log.Info().Str("capturer", name).Msg("Registered capturer initialized")
+	}
+
+	// Windows captures through the platform's own API
+	if platform, err := NewPlatformCapturer(); err == nil {
+		if err := platform.Start(); err != nil {
+			return fmt.Errorf("failed to start %s capturer: %w", platform.Name(), err)
+		}
+		r.platform = platform
+		log.Info().Str("capturer", platform.Name()).Msg("Platform capturer initialized")
+		r.started = true
+		return nil
+	}
+
 	// Try to initialize X11 capturer
 	x11, err := NewX11Capturer()
 	if err != nil {
@@ -48,8 +194,8 @@ func (r *Router) Start() error {
 		}
 	}
 
-	// Try to initialize PipeWire capturer (uses subprocess for stability)
-	pw, err := pipewire.NewCapturer()
+	// Try to initialize PipeWire capturer (in-process, or a GStreamer subprocess)
+	pw, err := pipewire.NewCapturer(r.pipewireConfig)
 	if err != nil {
 		log.Warn().Err(err).Msg("PipeWire capturer not available")
 	} else {
@@ -57,11 +203,11 @@ func (r *Router) Start() error {
 			log.Warn().Err(err).Msg("Failed to start PipeWire capturer (user may need to grant permission)")
 		} else {
 			r.pipewireCapturer = pw
-			log.Info().Msg("PipeWire capturer initialized (subprocess mode)")
+			log.Info().Str("mode", pw.Mode()).Int("monitors", pw.Streams()).Msg("PipeWire capturer initialized")
 		}
 	}
 
-	if r.x11Capturer == nil && r.pipewireCapturer == nil {
+	if r.x11Capturer == nil && r.pipewireCapturer == nil && len(r.registered) == 0 {
 		return fmt.Errorf("no capture backends available")
 	}
 
@@ -84,18 +230,102 @@ func (r *Router) Stop() error {
 		r.pipewireCapturer = nil
 	}
 
+	if r.platform != nil {
+		r.platform.Stop()
+		r.platform = nil
+	}
+
+	if r.synthetic != nil {
+		r.synthetic.Stop()
+	}
+
+	if r.remote != nil {
+		r.remote.Stop()
+	}
+
+	for _, c := range r.registered {
+		c.Stop()
+	}
+	r.registered = nil
+
 	r.started = false
 	return nil
 }
 
-// CaptureWindow captures a window using the most appropriate capturer
-func (r *Router) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
+// Restart stops and re-initializes all capturers, recovering from capture
+// sessions that died or froze (e.g. after a GPU suspend/resume)
+func (r *Router) Restart() error {
+	logger.WithComponent("capture-router").Info().Msg("Restarting capturers")
+	if err := r.Stop(); err != nil {
+		return fmt.Errorf("failed to stop capturers: %w", err)
+	}
+	if err := r.Start(); err != nil {
+		return fmt.Errorf("failed to restart capturers: %w", err)
+	}
+	return nil
+}
+
+// SessionLost reports whether a capturer lost its display server connection
+// or portal session, or a Restart failed, so another Restart is needed
+func (r *Router) SessionLost() bool {
 	r.mu.RLock()
 	x11 := r.x11Capturer
 	pw := r.pipewireCapturer
+	platform := r.platform
+	started := r.started
 	r.mu.RUnlock()
 
-	log := logger.WithComponent("capture-router")
+	if !started {
+		return true
+	}
+	if x11 != nil && isClosed(x11.Disconnected()) {
+		return true
+	}
+	if platform != nil && isClosed(platform.Disconnected()) {
+		return true
+	}
+	return pw != nil && isClosed(pw.Disconnected())
+}
+
+// windowCapturer captures single windows
+type windowCapturer interface {
+	CaptureWindow(window *config.WindowInfo) (*image.RGBA, error)
+}
+
+// capturerFor picks the capturer for a window, returning its name, or nil
+// if none is available
+func (r *Router) capturerFor(window *config.WindowInfo) (string, windowCapturer) {
+	r.mu.RLock()
+	x11 := r.x11Capturer
+	pw := r.pipewireCapturer
+	platform := r.platform
+	synthetic := r.synthetic
+	remote := r.remote
+	registered := r.registered
+	r.mu.RUnlock()
+
+	if synthetic != nil {
+		return "synthetic", synthetic
+	}
+	if remote != nil {
+		return remote.Name(), remote
+	}
+
+	// An override or route rule wins while its capturer can take the window
+	if requested, _, ok := r.requestedCapturer(window); ok {
+		if c := namedWindowCapturer(requested, x11, pw, registered); c != nil && c.CanCapture(window) {
+			return requested, c
+		}
+	}
+
+	for _, c := range registered {
+		if c.CanCapture(window) {
+			return c.name, c
+		}
+	}
+	if platform != nil {
+		return platform.Name(), platform
+	}
 
 	// Route based on window type
 	// 1. For XWayland windows (not native Wayland), prefer X11 if available
@@ -103,28 +333,75 @@ func (r *Router) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
 	// 3. Fall back to whatever is available
 
 	if !window.IsNativeWayland && x11 != nil && x11.CanCapture(window) {
-		log.Debug().
-			Uint32("id", window.ID).
-			Str("class", window.Class).
-			Msg("Using X11 capturer for XWayland window")
-		return x11.CaptureWindow(window)
+		return "x11", x11
 	}
 
 	if pw != nil && pw.CanCapture(window) {
-		return pw.CaptureWindow(window)
+		return "pipewire", pw
 	}
 
 	// Try X11 as last resort
 	if x11 != nil {
-		log.Debug().
+		return "x11", x11
+	}
+
+	return "", nil
+}
+
+// routableCapturer is a capturer a window can be routed to
+type routableCapturer interface {
+	windowCapturer
+	CanCapture(window *config.WindowInfo) bool
+}
+
+// namedWindowCapturer returns the running capturer with the given name, or
+// nil
+func namedWindowCapturer(name string, x11 *X11Capturer, pw *pipewire.Capturer, registered []namedCapturer) routableCapturer {
+	switch name {
+	case "x11":
+		if x11 != nil {
+			return x11
+		}
+	case "pipewire":
+		if pw != nil {
+			return pw
+		}
+	default:
+		for _, c := range registered {
+			if c.name == name {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// CapturerName returns the name of the capturer CaptureWindow uses for a
+// window ("x11", "pipewire", "dxgi", "synthetic", "remote" or a
+// registered capturer's name), or "none"
+func (r *Router) CapturerName(window *config.WindowInfo) string {
+	if name, capturer := r.capturerFor(window); capturer != nil {
+		return name
+	}
+	return "none"
+}
+
+// CaptureWindow captures a window using the most appropriate capturer
+func (r *Router) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
+	name, capturer := r.capturerFor(window)
+	if capturer == nil {
+		return nil, fmt.Errorf("no capturer available for window %s (native_wayland=%v, id=%d)",
+			window.Class, window.IsNativeWayland, window.ID)
+	}
+
+	if name == "x11" {
+		logger.WithComponent("capture-router").Debug().
 			Uint32("id", window.ID).
 			Str("class", window.Class).
-			Msg("Falling back to X11 capturer")
-		return x11.CaptureWindow(window)
+			Bool("native_wayland", window.IsNativeWayland).
+			Msg("Using X11 capturer")
 	}
-
-	return nil, fmt.Errorf("no capturer available for window %s (native_wayland=%v, id=%d)",
-		window.Class, window.IsNativeWayland, window.ID)
+	return capturer.CaptureWindow(window)
 }
 
 // CaptureRegion captures a region of the screen
@@ -132,8 +409,21 @@ func (r *Router) CaptureRegion(x, y, width, height int) (*image.RGBA, error) {
 	r.mu.RLock()
 	x11 := r.x11Capturer
 	pw := r.pipewireCapturer
+	platform := r.platform
+	synthetic := r.synthetic
+	remote := r.remote
 	r.mu.RUnlock()
 
+	if synthetic != nil {
+		return synthetic.CaptureRegion(x, y, width, height)
+	}
+	if remote != nil {
+		return remote.CaptureRegion(x, y, width, height)
+	}
+	if platform != nil {
+		return platform.CaptureRegion(x, y, width, height)
+	}
+
 	// Prefer PipeWire for region capture (more reliable on Wayland)
 	if pw != nil {
 		return pw.CaptureRegion(x, y, width, height)
@@ -160,6 +450,16 @@ func (r *Router) GetPipeWireCapturer() *pipewire.Capturer {
 	return r.pipewireCapturer
 }
 
+// PortalMonitors returns the monitor map of the PipeWire capturer, which
+// windows are cropped by, or nil without PipeWire capture
+func (r *Router) PortalMonitors() []pipewire.Monitor {
+	pw := r.GetPipeWireCapturer()
+	if pw == nil {
+		return nil
+	}
+	return pw.Monitors()
+}
+
 // HasPipeWire returns true if PipeWire capture is available
 func (r *Router) HasPipeWire() bool {
 	r.mu.RLock()
@@ -174,13 +474,53 @@ func (r *Router) HasX11() bool {
 	return r.x11Capturer != nil
 }
 
+// HasPlatform returns true if a Windows capturer is in use
+func (r *Router) HasPlatform() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.platform != nil
+}
+
+// HasSynthetic returns true if the router is in synthetic (demo) mode
+func (r *Router) HasSynthetic() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.synthetic != nil
+}
+
+// HasRemote returns true if the router captures through a capture agent
+func (r *Router) HasRemote() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.remote != nil
+}
+
 // CanCapture checks if any capturer can handle the window
 func (r *Router) CanCapture(window *config.WindowInfo) bool {
 	r.mu.RLock()
 	x11 := r.x11Capturer
 	pw := r.pipewireCapturer
+	platform := r.platform
+	synthetic := r.synthetic
+	remote := r.remote
+	registered := r.registered
 	r.mu.RUnlock()
 
+	if synthetic != nil {
+		return synthetic.CanCapture(window)
+	}
+	if remote != nil {
+		return remote.CanCapture(window)
+	}
+	for _, c := range registered {
+		if c.CanCapture(window) {
+			return true
+		}
+	}
+	if platform != nil {
+		return platform.CanCapture(window)
+	}
+
 	if x11 != nil && x11.CanCapture(window) {
 		return true
 	}
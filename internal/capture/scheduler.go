@@ -0,0 +1,300 @@
+package capture
+
+import (
+	"image"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+const (
+	// maxWorkerFailures is how many captures in a row may fail before a
+	// worker assumes its window closed and stops
+	maxWorkerFailures = 20
+
+	// defaultWorkerFPS applies when a window is tracked without a cadence
+	defaultWorkerFPS = 10
+)
+
+// CaptureFunc captures one frame of a window. The caller owns the result.
+type CaptureFunc func(window *config.WindowInfo) (*image.RGBA, error)
+
+// WorkerStatus reports a capture worker for /api/health
+type WorkerStatus struct {
+	WindowID    uint32    `json:"window_id"`
+	Class       string    `json:"class"`
+	FPS         int       `json:"fps"`
+	Frames      uint64    `json:"frames"`
+	Failures    int       `json:"failures"` // Consecutive failed captures
+	LastCapture time.Time `json:"last_capture,omitempty"`
+	CaptureMs   float64   `json:"capture_ms"` // Duration of the last capture
+}
+
+// Scheduler keeps one capture worker per tracked window. Each worker
+// captures at its own cadence into a latest-frame slot, so a compositor
+// showing several windows reads their newest frames without capturing them
+// one after another in the stream tick.
+//
+// A worker stops when its window is untracked, or on its own once capture
+// keeps failing because the window closed.
+type Scheduler struct {
+	capture CaptureFunc
+
+	mu      sync.Mutex
+	workers map[uint32]*worker
+}
+
+// worker captures one window into its latest-frame slot
+type worker struct {
+	scheduler *Scheduler
+	stop      chan struct{}
+
+	mu          sync.Mutex
+	window      *config.WindowInfo
+	fps         int
+	latest      *image.RGBA
+	frames      uint64
+	failures    int
+	lastCapture time.Time
+	captureTime time.Duration
+	retune      chan struct{} // Signals a cadence change
+}
+
+// NewScheduler creates a scheduler capturing windows with capture
+func NewScheduler(capture CaptureFunc) *Scheduler {
+	return &Scheduler{
+		capture: capture,
+		workers: make(map[uint32]*worker),
+	}
+}
+
+// Track starts capturing a window fps times a second, or updates the window
+// info and cadence of a window already tracked
+func (s *Scheduler) Track(window *config.WindowInfo, fps int) {
+	if fps <= 0 {
+		fps = defaultWorkerFPS
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if w, exists := s.workers[window.ID]; exists {
+		w.mu.Lock()
+		w.window = window
+		changed := w.fps != fps
+		w.fps = fps
+		w.mu.Unlock()
+		if changed {
+			select {
+			case w.retune <- struct{}{}:
+			default:
+			}
+		}
+		return
+	}
+
+	w := &worker{
+		scheduler: s,
+		stop:      make(chan struct{}),
+		window:    window,
+		fps:       fps,
+		retune:    make(chan struct{}, 1),
+	}
+	s.workers[window.ID] = w
+	go w.run()
+
+	logger.WithComponent("capture-scheduler").Debug().
+		Uint32("window_id", window.ID).
+		Str("class", window.Class).
+		Int("fps", fps).
+		Msg("Capture worker started")
+}
+
+// Untrack stops capturing a window and releases its latest frame
+func (s *Scheduler) Untrack(windowID uint32) {
+	s.mu.Lock()
+	w, exists := s.workers[windowID]
+	delete(s.workers, windowID)
+	s.mu.Unlock()
+
+	if exists {
+		close(w.stop)
+	}
+}
+
+// Retain untracks every window whose ID isn't in windowIDs, e.g. after
+// listing the windows that still exist
+func (s *Scheduler) Retain(windowIDs []uint32) {
+	keep := make(map[uint32]bool, len(windowIDs))
+	for _, id := range windowIDs {
+		keep[id] = true
+	}
+
+	s.mu.Lock()
+	var gone []uint32
+	for id := range s.workers {
+		if !keep[id] {
+			gone = append(gone, id)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, id := range gone {
+		s.Untrack(id)
+	}
+}
+
+// Latest returns a copy of the newest frame of a window and when it was
+// captured. The caller owns the copy and releases it with framepool.Put.
+// It returns false if the window isn't tracked or has no frame yet.
+func (s *Scheduler) Latest(windowID uint32) (*image.RGBA, time.Time, bool) {
+	s.mu.Lock()
+	w, exists := s.workers[windowID]
+	s.mu.Unlock()
+	if !exists {
+		return nil, time.Time{}, false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.latest == nil {
+		return nil, time.Time{}, false
+	}
+	return framepool.Clone(w.latest), w.lastCapture, true
+}
+
+// Workers returns the status of every capture worker, by window ID
+func (s *Scheduler) Workers() []WorkerStatus {
+	s.mu.Lock()
+	workers := make([]*worker, 0, len(s.workers))
+	for _, w := range s.workers {
+		workers = append(workers, w)
+	}
+	s.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(workers))
+	for _, w := range workers {
+		statuses = append(statuses, w.status())
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].WindowID < statuses[j].WindowID
+	})
+	return statuses
+}
+
+// Stop stops every worker
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	workers := s.workers
+	s.workers = make(map[uint32]*worker)
+	s.mu.Unlock()
+
+	for _, w := range workers {
+		close(w.stop)
+	}
+}
+
+// run captures at the worker's cadence until stopped
+func (w *worker) run() {
+	defer w.release()
+
+	ticker := time.NewTicker(w.interval())
+	defer ticker.Stop()
+
+	for {
+		if !w.captureOnce() {
+			w.scheduler.remove(w)
+			return
+		}
+
+		select {
+		case <-w.stop:
+			return
+		case <-w.retune:
+			ticker.Reset(w.interval())
+		case <-ticker.C:
+		}
+	}
+}
+
+// captureOnce captures a frame into the latest slot. It returns false once
+// the window looks closed.
+func (w *worker) captureOnce() bool {
+	w.mu.Lock()
+	window := w.window
+	w.mu.Unlock()
+
+	start := time.Now()
+	img, err := w.scheduler.capture(window)
+	elapsed := time.Since(start)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.captureTime = elapsed
+	if err != nil || img == nil {
+		w.failures++
+		if w.failures < maxWorkerFailures {
+			return true
+		}
+		logger.WithComponent("capture-scheduler").Info().
+			Err(err).
+			Uint32("window_id", window.ID).
+			Str("class", window.Class).
+			Msg("Capture worker stopped, window appears closed")
+		return false
+	}
+
+	framepool.Put(w.latest)
+	w.latest = img
+	w.lastCapture = start
+	w.frames++
+	w.failures = 0
+	return true
+}
+
+func (w *worker) interval() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Second / time.Duration(w.fps)
+}
+
+// release returns the latest frame to the pool
+func (w *worker) release() {
+	w.mu.Lock()
+	framepool.Put(w.latest)
+	w.latest = nil
+	w.mu.Unlock()
+}
+
+// remove drops a worker that stopped on its own
+func (s *Scheduler) remove(w *worker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.mu.Lock()
+	id := w.window.ID
+	w.mu.Unlock()
+	if s.workers[id] == w {
+		delete(s.workers, id)
+	}
+}
+
+func (w *worker) status() WorkerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return WorkerStatus{
+		WindowID:    w.window.ID,
+		Class:       w.window.Class,
+		FPS:         w.fps,
+		Frames:      w.frames,
+		Failures:    w.failures,
+		LastCapture: w.lastCapture,
+		CaptureMs:   float64(w.captureTime.Microseconds()) / 1000,
+	}
+}
@@ -0,0 +1,181 @@
+package capture
+
+import (
+	"hash/fnv"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+const (
+	// watchdogSampleStep samples every Nth pixel in each direction when
+	// hashing, which is plenty to notice any real change in content
+	watchdogSampleStep = 8
+
+	// darkThreshold is the brightest channel value still counted as black
+	darkThreshold = 16
+
+	// maxRestartBackoff caps the delay between restart attempts
+	maxRestartBackoff = 5 * time.Minute
+)
+
+// WatchdogStatus reports the watchdog state for /api/health
+type WatchdogStatus struct {
+	Stalled     bool      `json:"stalled"`
+	Restarting  bool      `json:"restarting"`
+	Restarts    int       `json:"restarts"` // Attempts since frames last changed
+	LastChange  time.Time `json:"last_change"`
+	LastRestart time.Time `json:"last_restart,omitempty"`
+}
+
+// Watchdog notices when capture silently stops delivering new frames (for
+// example black or frozen frames after a GPU suspend/resume) and asks for
+// a capturer restart.
+//
+// A window whose content simply doesn't change looks the same as a frozen
+// capture, so after a restart an identical, non-black frame is taken as
+// static content and the watchdog stays quiet until the frame changes.
+type Watchdog struct {
+	stallAfter time.Duration
+
+	mu          sync.Mutex
+	windowID    uint32
+	lastHash    uint64
+	lastChange  time.Time
+	stalled     bool
+	static      bool
+	restarting  bool
+	restarts    int
+	lastRestart time.Time
+}
+
+// NewWatchdog creates a watchdog that reports a stall after stallAfter
+// without a changed frame
+func NewWatchdog(stallAfter time.Duration) *Watchdog {
+	return &Watchdog{stallAfter: stallAfter}
+}
+
+// Observe records the result of a capture attempt for a window (img is nil
+// when capture failed). It reports whether capture is stalled and whether
+// the caller should restart the capturers now; after a restart the caller
+// must call RestartDone.
+func (w *Watchdog) Observe(windowID uint32, img *image.RGBA, now time.Time) (stalled, restart bool) {
+	var hash uint64
+	dark := true
+	if img != nil {
+		hash, dark = frameSignature(img)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// New window: start over
+	if windowID != w.windowID || w.lastChange.IsZero() {
+		w.reset(windowID, hash, now)
+		return false, false
+	}
+
+	if img != nil && hash != w.lastHash {
+		if w.stalled {
+			logger.WithComponent("capture-watchdog").Info().
+				Int("restarts", w.restarts).
+				Msg("Capture recovered, frames are changing again")
+		}
+		w.reset(windowID, hash, now)
+		return false, false
+	}
+
+	if w.static || now.Sub(w.lastChange) < w.stallAfter {
+		return false, false
+	}
+
+	if w.restarting {
+		return true, false
+	}
+
+	// Restarted, and the capture still returns the same picture: the window
+	// content is static, not frozen
+	if w.restarts > 0 && img != nil && !dark {
+		logger.WithComponent("capture-watchdog").Info().
+			Msg("Frame unchanged after capture restart, treating window content as static")
+		w.static = true
+		w.stalled = false
+		return false, false
+	}
+
+	if !w.stalled {
+		logger.WithComponent("capture-watchdog").Warn().
+			Dur("unchanged_for", now.Sub(w.lastChange)).
+			Bool("capture_failed", img == nil).
+			Bool("dark", dark).
+			Msg("Capture stalled, restarting capturers")
+	}
+	w.stalled = true
+
+	backoff := w.stallAfter << w.restarts
+	if backoff <= 0 || backoff > maxRestartBackoff {
+		backoff = maxRestartBackoff
+	}
+	if w.restarts == 0 || now.Sub(w.lastRestart) >= backoff {
+		w.restarting = true
+		w.restarts++
+		w.lastRestart = now
+		return true, true
+	}
+	return true, false
+}
+
+// RestartDone records that a restart requested by Observe has finished
+func (w *Watchdog) RestartDone() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.restarting = false
+}
+
+// Status returns a snapshot of the watchdog state
+func (w *Watchdog) Status() WatchdogStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return WatchdogStatus{
+		Stalled:     w.stalled,
+		Restarting:  w.restarting,
+		Restarts:    w.restarts,
+		LastChange:  w.lastChange,
+		LastRestart: w.lastRestart,
+	}
+}
+
+// reset starts tracking a fresh frame. Called with mu held.
+func (w *Watchdog) reset(windowID uint32, hash uint64, now time.Time) {
+	w.windowID = windowID
+	w.lastHash = hash
+	w.lastChange = now
+	w.stalled = false
+	w.static = false
+	w.restarts = 0
+}
+
+// frameSignature hashes a sample of the frame's pixels and reports whether
+// every sampled pixel is black
+func frameSignature(img *image.RGBA) (uint64, bool) {
+	h := fnv.New64a()
+	dark := true
+	bounds := img.Bounds()
+	row := make([]byte, 0, (bounds.Dx()/watchdogSampleStep+1)*4)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += watchdogSampleStep {
+		row = row[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x += watchdogSampleStep {
+			i := img.PixOffset(x, y)
+			px := img.Pix[i : i+4]
+			row = append(row, px...)
+			if dark && (px[0] > darkThreshold || px[1] > darkThreshold || px[2] > darkThreshold) {
+				dark = false
+			}
+		}
+		h.Write(row)
+	}
+	return h.Sum64(), dark
+}
@@ -3,14 +3,15 @@ package capture
 import (
 	"fmt"
 	"image"
-	"image/color"
 	"sync"
 
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/composite"
 	"github.com/BurntSushi/xgb/xproto"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
 )
 
 // X11Capturer captures windows using X11/XWayland
@@ -19,6 +20,7 @@ type X11Capturer struct {
 	root             xproto.Window
 	screen           *xproto.ScreenInfo
 	compositeEnabled bool
+	lost             <-chan struct{} // Closed when the X connection dies
 	mu               sync.Mutex
 }
 
@@ -37,6 +39,7 @@ func NewX11Capturer() (*X11Capturer, error) {
 		conn:   conn,
 		root:   root,
 		screen: screen,
+		lost:   MonitorX11Conn(conn, nil),
 	}
 
 	return c, nil
@@ -62,10 +65,17 @@ func (c *X11Capturer) Start() error {
 
 // Stop closes the X11 connection
 func (c *X11Capturer) Stop() error {
-	c.conn.Close()
+	if !isClosed(c.lost) {
+		c.conn.Close()
+	}
 	return nil
 }
 
+// Disconnected returns a channel that is closed when the X connection is lost
+func (c *X11Capturer) Disconnected() <-chan struct{} {
+	return c.lost
+}
+
 // Name returns the capturer name
 func (c *X11Capturer) Name() string {
 	return "X11"
@@ -91,6 +101,9 @@ func (c *X11Capturer) CaptureWindow(window *config.WindowInfo) (*image.RGBA, err
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if isClosed(c.lost) {
+		return nil, fmt.Errorf("X server connection lost")
+	}
 	if !c.CanCapture(window) {
 		return nil, fmt.Errorf("cannot capture window: native Wayland or invalid ID")
 	}
@@ -147,6 +160,10 @@ func (c *X11Capturer) CaptureRegion(x, y, width, height int) (*image.RGBA, error
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	if isClosed(c.lost) {
+		return nil, fmt.Errorf("X server connection lost")
+	}
+
 	// Get image data from root window
 	reply, err := xproto.GetImage(
 		c.conn,
@@ -261,24 +278,11 @@ func (c *X11Capturer) captureWindowDrawable(win xproto.Window, geom *xproto.GetG
 
 // convertImageData converts X11 image data to RGBA
 func (c *X11Capturer) convertImageData(data []byte, width, height int) *image.RGBA {
-	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img := framepool.GetZeroed(width, height)
 	depth := int(c.screen.RootDepth)
 
 	if depth == 24 || depth == 32 {
-		for y := 0; y < height; y++ {
-			for x := 0; x < width; x++ {
-				i := (y*width + x) * 4
-				if i+3 < len(data) {
-					// BGRA to RGBA
-					img.Set(x, y, color.RGBA{
-						R: data[i+2],
-						G: data[i+1],
-						B: data[i],
-						A: 255,
-					})
-				}
-			}
-		}
+		pixconv.BGRAToRGBA(img, data)
 	}
 
 	return img
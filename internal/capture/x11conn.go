@@ -0,0 +1,57 @@
+package capture
+
+import (
+	"github.com/BurntSushi/xgb"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// MonitorX11Conn reads events from conn until the X server connection is
+// lost (server reset after suspend/resume, display hotplug, Xwayland
+// restart) and returns a channel that is closed at that point.
+//
+// Events are forwarded to events if it is non-nil, and dropped when the
+// receiver falls behind. The caller must not read events from conn itself.
+// Once the channel is closed, conn must not be used or closed again: xgb
+// panics on requests to a dead connection.
+func MonitorX11Conn(conn *xgb.Conn, events chan<- xgb.Event) <-chan struct{} {
+	lost := make(chan struct{})
+
+	go func() {
+		defer close(lost)
+		for {
+			ev, err := conn.WaitForEvent()
+			if ev == nil && err == nil {
+				// Both nil means the connection was closed
+				logger.WithComponent("x11-conn").Debug().Msg("X server connection closed")
+				return
+			}
+			if err != nil {
+				// X errors for unchecked requests (e.g. BadWindow for a
+				// window that just closed) are expected
+				continue
+			}
+			if events == nil {
+				continue
+			}
+			select {
+			case events <- ev:
+			default:
+			}
+		}
+	}()
+
+	return lost
+}
+
+// isClosed reports whether ch has been closed
+func isClosed(ch <-chan struct{}) bool {
+	if ch == nil {
+		return false
+	}
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// maxConfigBackups is how many config backups are kept; older ones are deleted
+const maxConfigBackups = 20
+
+// GetBackupDir returns the directory holding timestamped config backups
+func (m *Manager) GetBackupDir() string {
+	return filepath.Join(m.GetConfigDir(), "backups")
+}
+
+// backupConfig copies the config file to the backup directory before it is
+// replaced with data. Nothing is written if there is no file yet or its
+// contents are unchanged.
+func (m *Manager) backupConfig(data []byte) error {
+	current, err := os.ReadFile(m.configPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if bytes.Equal(current, data) {
+		return nil
+	}
+
+	dir := m.GetBackupDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	name := "config-" + time.Now().Format("20060102-150405.000") + ".yaml"
+	if err := os.WriteFile(filepath.Join(dir, name), current, 0644); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return pruneBackups(dir)
+}
+
+// pruneBackups deletes the oldest backups beyond maxConfigBackups
+func pruneBackups(dir string) error {
+	backups, err := filepath.Glob(filepath.Join(dir, "config-*.yaml"))
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	// Timestamped names sort oldest first
+	sort.Strings(backups)
+	for len(backups) > maxConfigBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
@@ -0,0 +1,485 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/hwjpeg"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config format version written by this build.
+//
+// Versions:
+//  1. Allowlists at the top level (before profiles)
+//  2. Allowlists and placeholders per profile
+//  3. Command widgets name an entry of overlay.commands
+const CurrentVersion = 3
+
+// migrations upgrade a config from version N to N+1, keyed by N
+var migrations = map[int]func(cfg *Config){
+	1: migrateV1Profiles,
+	2: migrateV2NamedCommands,
+}
+
+// validBackends mirrors window.BackendNames, which can't be imported here
+var validBackends = map[string]bool{"": true, "auto": true, "x11": true, "kwin": true, "hyprland": true, "windows": true, "synthetic": true, "remote": true}
+
+var validBackendsMu sync.RWMutex
+
+// RegisterBackendName accepts a window backend registered through
+// window.RegisterBackend in config files
+func RegisterBackendName(name string) {
+	validBackendsMu.Lock()
+	defer validBackendsMu.Unlock()
+	validBackends[name] = true
+}
+
+// isValidBackend reports whether name is a built-in or registered backend
+func isValidBackend(name string) bool {
+	validBackendsMu.RLock()
+	defer validBackendsMu.RUnlock()
+	return validBackends[name]
+}
+
+// validCapturers lists the capturers capture.routes may name: the Linux
+// window capturers, plus those registered through capture.RegisterCapturer
+var validCapturers = map[string]bool{"x11": true, "pipewire": true}
+
+// RegisterCapturerName accepts a capturer registered through
+// capture.RegisterCapturer in capture.routes
+func RegisterCapturerName(name string) {
+	validBackendsMu.Lock()
+	defer validBackendsMu.Unlock()
+	validCapturers[name] = true
+}
+
+// IsValidCapturer reports whether windows can be routed to the named
+// capturer
+func IsValidCapturer(name string) bool {
+	validBackendsMu.RLock()
+	defer validBackendsMu.RUnlock()
+	return validCapturers[name]
+}
+
+// sessionNamePattern keeps session names usable as a URL path segment and
+// directory name
+var sessionNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+var validLogLevels = map[string]bool{"": true, "debug": true, "info": true, "warn": true, "error": true}
+
+// parseConfig decodes a config file of any supported version, migrates it to
+// the current version, and fills in defaults for settings it doesn't have.
+// It returns the version the file was in.
+func (m *Manager) parseConfig(data []byte) (*Config, int, error) {
+	// Decode over the defaults so settings added since the file was written
+	// keep their default values instead of zero values
+	cfg := m.getDefaults()
+	cfg.Version = 0
+	cfg.Profiles = nil
+	cfg.ActiveProfileID = ""
+	cfg.Overlay.Widgets = nil
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	fromVersion := cfg.Version
+	if fromVersion == 0 {
+		// Unversioned files predate the version field; profiles tell them apart
+		fromVersion = 1
+		if len(cfg.Profiles) > 0 {
+			fromVersion = 2
+		}
+	}
+	if fromVersion > CurrentVersion {
+		return nil, 0, fmt.Errorf("config version %d is newer than this build supports (%d)", fromVersion, CurrentVersion)
+	}
+
+	for v := fromVersion; v < CurrentVersion; v++ {
+		logger.WithComponent("config").Info().
+			Int("from", v).
+			Int("to", v+1).
+			Msg("Migrating config format")
+		migrations[v](cfg)
+	}
+	cfg.Version = CurrentVersion
+
+	normalizeConfig(cfg)
+	return cfg, fromVersion, nil
+}
+
+// migrateV1Profiles moves the top-level allowlists into a Default profile
+func migrateV1Profiles(cfg *Config) {
+	// Migrate old single placeholder path to slice format first
+	if cfg.PlaceholderImagePath != "" && len(cfg.PlaceholderImagePaths) == 0 {
+		cfg.PlaceholderImagePaths = []string{cfg.PlaceholderImagePath}
+	}
+
+	cfg.Profiles = []Profile{{
+		ID:                     "default",
+		Name:                   "Default",
+		AllowlistPatterns:      cfg.AllowlistPatterns,
+		AllowlistTitlePatterns: cfg.AllowlistTitlePatterns,
+		AllowlistedApps:        cfg.AllowlistedApps,
+		AllowlistURLRules:      cfg.AllowlistURLRules,
+		BrowserWindowClasses:   cfg.BrowserWindowClasses,
+		BrowserBlockedClasses:  cfg.BrowserBlockedClasses,
+		PlaceholderImagePaths:  cfg.PlaceholderImagePaths,
+	}}
+	cfg.ActiveProfileID = "default"
+
+	// Clear legacy fields (they're now in the profile)
+	cfg.AllowlistPatterns = nil
+	cfg.AllowlistTitlePatterns = nil
+	cfg.AllowlistedApps = nil
+	cfg.AllowlistURLRules = nil
+	cfg.BrowserWindowClasses = nil
+	cfg.BrowserBlockedClasses = nil
+	cfg.PlaceholderImagePath = ""
+	cfg.PlaceholderImagePaths = nil
+
+	logger.WithComponent("config").Info().
+		Str("profile_id", "default").
+		Msg("Migration complete - created Default profile from existing settings")
+}
+
+// migrateV2NamedCommands moves the shell command of each command widget into
+// overlay.commands, named after the widget, and points the widget at it. A
+// working directory becomes a cd ahead of the command.
+func migrateV2NamedCommands(cfg *Config) {
+	for _, widget := range cfg.Overlay.Widgets {
+		if widgetType, _ := widget["type"].(string); widgetType != "command" {
+			continue
+		}
+		id, _ := widget["id"].(string)
+		command, _ := widget["command"].(string)
+		if id == "" || strings.TrimSpace(command) == "" {
+			continue
+		}
+		if dir, _ := widget["dir"].(string); dir != "" {
+			command = "cd '" + strings.ReplaceAll(dir, "'", `'\''`) + "' && " + command
+		}
+		if cfg.Overlay.Commands == nil {
+			cfg.Overlay.Commands = make(map[string]string)
+		}
+		cfg.Overlay.Commands[id] = command
+		widget["command"] = id
+		delete(widget, "dir")
+
+		logger.WithComponent("config").Info().
+			Str("widget", id).
+			Msg("Moved command widget's command into overlay.commands")
+	}
+}
+
+// normalizeConfig replaces nil slices with empty ones and makes sure an
+// active profile is set
+func normalizeConfig(cfg *Config) {
+	if cfg.Overlay.Widgets == nil {
+		cfg.Overlay.Widgets = []map[string]interface{}{}
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = []Profile{}
+	}
+
+	// Ensure active profile ID is set
+	if cfg.ActiveProfileID == "" && len(cfg.Profiles) > 0 {
+		cfg.ActiveProfileID = cfg.Profiles[0].ID
+	}
+
+	for i := range cfg.Profiles {
+		p := &cfg.Profiles[i]
+		if p.AllowlistPatterns == nil {
+			p.AllowlistPatterns = []string{}
+		}
+		if p.AllowlistTitlePatterns == nil {
+			p.AllowlistTitlePatterns = []string{}
+		}
+		if p.AllowlistedApps == nil {
+			p.AllowlistedApps = []string{}
+		}
+		if p.AllowlistURLRules == nil {
+			p.AllowlistURLRules = []UrlRule{}
+		}
+		if p.BrowserWindowClasses == nil {
+			p.BrowserWindowClasses = []string{}
+		}
+		if p.BrowserBlockedClasses == nil {
+			p.BrowserBlockedClasses = []string{}
+		}
+		if p.PlaceholderImagePaths == nil {
+			p.PlaceholderImagePaths = []string{}
+		}
+	}
+}
+
+// CompilePattern compiles an allowlist pattern. Errors name the problem,
+// with a hint for Perl syntax that Go's RE2 engine doesn't support.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err == nil {
+		return re, nil
+	}
+
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) && (syntaxErr.Code == syntax.ErrInvalidPerlOp || syntaxErr.Code == syntax.ErrInvalidEscape) {
+		return nil, fmt.Errorf("invalid regex pattern: %w (lookarounds and backreferences aren't supported; patterns use RE2 syntax)", err)
+	}
+	return nil, fmt.Errorf("invalid regex pattern: %w", err)
+}
+
+// Validate checks a migrated config for values the application can't run
+// with. It is applied to imported configs before they replace the current one.
+func (c *Config) Validate() error {
+	if c.ServerPort < 1 || c.ServerPort > 65535 {
+		return fmt.Errorf("invalid server_port: %d", c.ServerPort)
+	}
+	if !validLogLevels[c.LogLevel] {
+		return fmt.Errorf("invalid log_level: %s (use: debug, info, warn, error)", c.LogLevel)
+	}
+	if !isValidBackend(c.Backend) {
+		return fmt.Errorf("invalid backend: %s", c.Backend)
+	}
+
+	vd := c.VirtualDisplay
+	if vd.Width <= 0 || vd.Height <= 0 {
+		return fmt.Errorf("invalid virtual_display size: %dx%d", vd.Width, vd.Height)
+	}
+	if vd.FPS < 1 || vd.FPS > 60 {
+		return fmt.Errorf("invalid virtual_display.fps: %d (use 1-60)", vd.FPS)
+	}
+	if c.Bandwidth.MaxMbps < 0 || c.Bandwidth.ClientMaxMbps < 0 {
+		return fmt.Errorf("invalid bandwidth limit: use 0 for unlimited")
+	}
+	if c.API.RateLimit < 0 || c.API.RateBurst < 0 {
+		return fmt.Errorf("invalid API rate limit: use 0 for unlimited")
+	}
+	if c.FocusStats.RetentionDays < 0 {
+		return fmt.Errorf("invalid focus_stats.retention_days: %d (use 0 to keep all days)", c.FocusStats.RetentionDays)
+	}
+	if aw := c.ActivityWatch; aw.Enabled {
+		u, err := url.Parse(aw.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid activitywatch.url: %s (use an http or https URL)", aw.URL)
+		}
+	}
+	for _, bucket := range []string{c.ActivityWatch.WindowBucket, c.ActivityWatch.StreamBucket} {
+		if strings.ContainsAny(bucket, "/?#") {
+			return fmt.Errorf("invalid activitywatch bucket name: %s", bucket)
+		}
+	}
+	if approval := c.AllowlistApproval; approval.Enabled {
+		if hash, err := hex.DecodeString(approval.SecretSHA256); err != nil || len(hash) != sha256.Size {
+			return fmt.Errorf("invalid allowlist_approval.secret_sha256: set an approver secret to turn on approval")
+		}
+	}
+	if webhook := c.AllowlistApproval.WebhookURL; webhook != "" {
+		u, err := url.Parse(webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid allowlist_approval.webhook_url: %s (use an http or https URL)", webhook)
+		}
+	}
+	dmabuf := c.Capture.DMABuf
+	if dmabuf.PreviewScale < 0 || dmabuf.PreviewScale > 8 {
+		return fmt.Errorf("invalid capture.dmabuf.preview_scale: %d (use 1-8)", dmabuf.PreviewScale)
+	}
+	if err := ValidatePipelineTemplate(c.Capture.PipelineTemplate); err != nil {
+		return fmt.Errorf("invalid capture.pipeline_template: %w", err)
+	}
+	if c.Capture.FallbackTTLSeconds < 0 {
+		return fmt.Errorf("invalid capture.fallback_ttl_seconds: %d", c.Capture.FallbackTTLSeconds)
+	}
+	for class, fps := range c.Capture.AppFPS {
+		if class == "" || fps < 1 || fps > 60 {
+			return fmt.Errorf("invalid capture.app_fps entry: %q: %d (use a window class and 1-60)", class, fps)
+		}
+	}
+	for class, capturer := range c.Capture.Routes {
+		if class == "" || !IsValidCapturer(capturer) {
+			return fmt.Errorf("invalid capture.routes entry: %q: %q (use a window class and x11, pipewire or a registered capturer)", class, capturer)
+		}
+	}
+	governor := c.Capture.Governor
+	if governor.CPUBudgetPercent <= 0 {
+		return fmt.Errorf("invalid capture.governor.cpu_budget_percent: %g", governor.CPUBudgetPercent)
+	}
+	if governor.MinFPS < 1 || governor.MinFPS > 60 {
+		return fmt.Errorf("invalid capture.governor.min_fps: %d (use 1-60)", governor.MinFPS)
+	}
+	for _, cidr := range c.StreamAccess.AllowedCIDRs {
+		if _, err := netip.ParsePrefix(cidr); err != nil {
+			return fmt.Errorf("invalid stream_access.allowed_cidrs entry: %s", cidr)
+		}
+	}
+	if publicURL := c.StreamAccess.PublicURL; publicURL != "" {
+		u, err := url.Parse(publicURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid stream_access.public_url: %s (use an http or https URL)", publicURL)
+		}
+	}
+	if c.Watermark.Opacity < 0 || c.Watermark.Opacity > 1 {
+		return fmt.Errorf("invalid watermark.opacity: %g (use 0-1)", c.Watermark.Opacity)
+	}
+	for _, desktops := range [][]int{c.Desktops.StreamDesktops, c.Desktops.PrivateDesktops} {
+		for _, desktop := range desktops {
+			if desktop < 1 {
+				return fmt.Errorf("invalid desktop number: %d (desktops are numbered from 1)", desktop)
+			}
+		}
+	}
+	if c.Startup.Window != "" {
+		if _, err := CompilePattern(c.Startup.Window); err != nil {
+			return fmt.Errorf("invalid startup.window: %w", err)
+		}
+	}
+	if c.Startup.WindowWaitSeconds < 0 {
+		return fmt.Errorf("invalid startup.window_wait_seconds: %d", c.Startup.WindowWaitSeconds)
+	}
+	if c.Startup.ZoomScale != 0 && (c.Startup.ZoomScale < 1 || c.Startup.ZoomScale > 4) {
+		return fmt.Errorf("invalid startup.zoom_scale: %g (use 1-4)", c.Startup.ZoomScale)
+	}
+	for _, offset := range []float64{c.Startup.ZoomX, c.Startup.ZoomY} {
+		if offset < 0 || offset > 1 {
+			return fmt.Errorf("invalid startup zoom offset: %g (use 0-1)", offset)
+		}
+	}
+
+	if c.LowPower.MaxFPS < 0 || c.LowPower.MaxFPS > 60 {
+		return fmt.Errorf("invalid low_power.max_fps: %d (use 1-60, or 0 for no cap)", c.LowPower.MaxFPS)
+	}
+	if err := hwjpeg.ValidateDevice(c.LowPower.HardwareJPEG); err != nil {
+		return fmt.Errorf("invalid low_power.hardware_jpeg: %w", err)
+	}
+
+	if err := c.Placeholder.Validate(); err != nil {
+		return err
+	}
+	if err := c.StreamFilters.Validate(); err != nil {
+		return err
+	}
+	if err := c.PointerHighlight.Validate(); err != nil {
+		return err
+	}
+
+	sceneNames := make(map[string]bool)
+	for i, scene := range c.Scenes {
+		name := strings.ToLower(scene.Name)
+		if name == "" {
+			return fmt.Errorf("scene %d is missing its name", i)
+		}
+		if sceneNames[name] {
+			return fmt.Errorf("duplicate scene name: %s", scene.Name)
+		}
+		sceneNames[name] = true
+
+		if scene.Window != "" {
+			if _, err := CompilePattern(scene.Window); err != nil {
+				return fmt.Errorf("invalid window of scene %s: %w", scene.Name, err)
+			}
+		}
+		if scene.ZoomScale != 0 && (scene.ZoomScale < 1 || scene.ZoomScale > 4) {
+			return fmt.Errorf("invalid zoom_scale of scene %s: %g (use 1-4)", scene.Name, scene.ZoomScale)
+		}
+		if scene.ZoomX < 0 || scene.ZoomX > 1 || scene.ZoomY < 0 || scene.ZoomY > 1 {
+			return fmt.Errorf("invalid zoom offset of scene %s (use 0-1)", scene.Name)
+		}
+		if !ValidSceneTransition(scene.Transition) {
+			return fmt.Errorf("invalid transition of scene %s: %s (use cut, zoom or standby)", scene.Name, scene.Transition)
+		}
+		if scene.TransitionDuration < 0 {
+			return fmt.Errorf("invalid transition_ms of scene %s: %d", scene.Name, scene.TransitionDuration)
+		}
+	}
+
+	for name, command := range c.Overlay.Commands {
+		if strings.TrimSpace(name) == "" || strings.TrimSpace(command) == "" {
+			return fmt.Errorf("invalid overlay.commands entry %q: names and commands can't be empty", name)
+		}
+	}
+
+	widgetIDs := make(map[string]bool)
+	for i, widget := range c.Overlay.Widgets {
+		id, _ := widget["id"].(string)
+		if widgetType, _ := widget["type"].(string); widgetType == "" || id == "" {
+			return fmt.Errorf("overlay widget %d is missing its type or id", i)
+		}
+		if widgetIDs[id] {
+			return fmt.Errorf("duplicate overlay widget id: %s", id)
+		}
+		widgetIDs[id] = true
+	}
+
+	if len(c.Profiles) == 0 {
+		return fmt.Errorf("config has no profiles")
+	}
+	profileIDs := make(map[string]bool)
+	for _, p := range c.Profiles {
+		if p.ID == "" {
+			return fmt.Errorf("profile %q has no id", p.Name)
+		}
+		if profileIDs[p.ID] {
+			return fmt.Errorf("duplicate profile id: %s", p.ID)
+		}
+		profileIDs[p.ID] = true
+
+		for _, patterns := range [][]string{p.AllowlistPatterns, p.AllowlistTitlePatterns} {
+			for _, pattern := range patterns {
+				if _, err := CompilePattern(pattern); err != nil {
+					return fmt.Errorf("invalid pattern in profile %s: %w", p.ID, err)
+				}
+			}
+		}
+	}
+	if !profileIDs[c.ActiveProfileID] {
+		return fmt.Errorf("active profile not found: %s", c.ActiveProfileID)
+	}
+
+	for _, pattern := range c.PIIGuard.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pii_guard pattern: %w", err)
+		}
+	}
+
+	if err := ValidateSessions(c.Sessions, c.ServerPort); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ValidateSessions checks additional session definitions. serverPort is the
+// port of the daemon serving them, which sessions can't reuse.
+func ValidateSessions(sessions []SessionConfig, serverPort int) error {
+	sessionNames := make(map[string]bool)
+	for _, session := range sessions {
+		if !sessionNamePattern.MatchString(session.Name) {
+			return fmt.Errorf("invalid session name: %q (use lowercase letters, digits, - and _)", session.Name)
+		}
+		if sessionNames[session.Name] {
+			return fmt.Errorf("duplicate session name: %s", session.Name)
+		}
+		sessionNames[session.Name] = true
+
+		if session.Display == "" && session.WaylandDisplay == "" && session.Backend != "synthetic" {
+			return fmt.Errorf("session %s needs a display or wayland_display", session.Name)
+		}
+		if !isValidBackend(session.Backend) {
+			return fmt.Errorf("invalid backend for session %s: %s", session.Name, session.Backend)
+		}
+		if session.Port < 0 || session.Port > 65535 || (session.Port != 0 && session.Port == serverPort) {
+			return fmt.Errorf("invalid port for session %s: %d", session.Name, session.Port)
+		}
+	}
+
+	return nil
+}
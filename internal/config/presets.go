@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Preset names
+const (
+	PresetDefault  = "default"
+	PresetLowPower = "low-power"
+)
+
+// Preset is a named set of settings applied over the current configuration.
+// Settings a preset doesn't touch (allowlists, profiles, overlays) are kept.
+type Preset struct {
+	Name        string
+	Description string
+	apply       func(cfg, defaults *Config)
+}
+
+// Presets lists the built-in presets
+var Presets = []Preset{
+	{
+		Name:        PresetDefault,
+		Description: "Restore the stream, capture and low-power settings changed by other presets",
+		apply: func(cfg, defaults *Config) {
+			cfg.VirtualDisplay.Width = defaults.VirtualDisplay.Width
+			cfg.VirtualDisplay.Height = defaults.VirtualDisplay.Height
+			cfg.VirtualDisplay.FPS = defaults.VirtualDisplay.FPS
+			cfg.Capture.Governor = defaults.Capture.Governor
+			cfg.Capture.SuspendWithoutViewers = defaults.Capture.SuspendWithoutViewers
+			cfg.LowPower = defaults.LowPower
+		},
+	},
+	{
+		Name:        PresetLowPower,
+		Description: "Small ARM boards (e.g. Raspberry Pi): 720p at 5 FPS, load governor on, hardware JPEG if available, costly extras off",
+		apply: func(cfg, defaults *Config) {
+			cfg.VirtualDisplay.Width = 1280
+			cfg.VirtualDisplay.Height = 720
+			cfg.VirtualDisplay.FPS = 5
+			cfg.Capture.Governor = GovernorConfig{
+				Enabled:          true,
+				CPUBudgetPercent: 50,
+				MinFPS:           2,
+			}
+			cfg.Capture.SuspendWithoutViewers = true
+			cfg.Capture.GPUCompose = false
+			cfg.Capture.DMABuf.Enabled = false
+			cfg.Capture.ColorManagement.Enabled = false
+			cfg.PIIGuard.Enabled = false
+			cfg.LowPower.Enabled = true
+			cfg.LowPower.MaxFPS = defaults.LowPower.MaxFPS
+			if cfg.LowPower.HardwareJPEG == "" {
+				cfg.LowPower.HardwareJPEG = defaults.LowPower.HardwareJPEG
+			}
+		},
+	},
+}
+
+// PresetNames returns the names of the built-in presets
+func PresetNames() []string {
+	names := make([]string, len(Presets))
+	for i, preset := range Presets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// ApplyPreset applies a built-in preset to the configuration and saves it
+func (m *Manager) ApplyPreset(name string) error {
+	for _, preset := range Presets {
+		if preset.Name != name {
+			continue
+		}
+		cfg := m.Get()
+		preset.apply(cfg, m.getDefaults())
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("preset %s produced an invalid configuration: %w", name, err)
+		}
+		return m.Update(cfg)
+	}
+	return fmt.Errorf("unknown preset: %s (use: %s)", name, strings.Join(PresetNames(), ", "))
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"image"
-	"image/color"
 	"image/draw"
 	"image/png"
 	"sync"
@@ -14,6 +13,7 @@ import (
 	"github.com/BurntSushi/xgb/xproto"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
 )
 
 // WindowCapturer interface for capturing window screenshots
@@ -314,20 +314,7 @@ func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply
 	}
 
 	if depth == 24 || depth == 32 {
-		for y := 0; y < int(geom.Height); y++ {
-			for x := 0; x < int(geom.Width); x++ {
-				i := (y*int(geom.Width) + x) * 4
-				if i+3 < len(data) {
-					// BGRA to RGBA
-					img.Set(x, y, color.RGBA{
-						R: data[i+2],
-						G: data[i+1],
-						B: data[i],
-						A: 255,
-					})
-				}
-			}
-		}
+		pixconv.BGRAToRGBA(img, data)
 	} else {
 		logger.WithComponent("display").Warn().
 			Int("depth", depth).
@@ -376,25 +363,7 @@ func (m *Manager) renderImage(img *image.RGBA) error {
 
 // scaleImage performs simple nearest-neighbor scaling
 func (m *Manager) scaleImage(dst *image.RGBA, dstRect image.Rectangle, src *image.RGBA, srcRect image.Rectangle) {
-	dstWidth := dstRect.Dx()
-	dstHeight := dstRect.Dy()
-	srcWidth := srcRect.Dx()
-	srcHeight := srcRect.Dy()
-
-	for dy := 0; dy < dstHeight; dy++ {
-		for dx := 0; dx < dstWidth; dx++ {
-			// Map destination pixel to source pixel
-			sx := (dx * srcWidth) / dstWidth
-			sy := (dy * srcHeight) / dstHeight
-
-			srcX := srcRect.Min.X + sx
-			srcY := srcRect.Min.Y + sy
-			dstX := dstRect.Min.X + dx
-			dstY := dstRect.Min.Y + dy
-
-			dst.Set(dstX, dstY, src.At(srcX, srcY))
-		}
-	}
+	pixconv.ScaleNearest(dst, dstRect, src, srcRect)
 }
 
 // putImage sends an image to the X server to be displayed
@@ -449,34 +418,12 @@ func (m *Manager) putImage(img *image.RGBA) error {
 	data := make([]byte, stride*imgHeight)
 
 	// Convert RGBA to X11 format with proper padding
-	for y := 0; y < imgHeight; y++ {
-		dstRowStart := y * stride
-		for x := 0; x < imgWidth; x++ {
-			srcIdx := (y*imgWidth + x) * 4
-			dstIdx := dstRowStart + x*bytesPerPixel
-
-			if bytesPerPixel == 4 {
-				// 32-bit or 24-bit depth with 32bpp: BGRx format
-				// Byte order matches X11 visual masks: 0xff (B), 0xff00 (G), 0xff0000 (R)
-				data[dstIdx] = img.Pix[srcIdx+2]     // B (byte 0)
-				data[dstIdx+1] = img.Pix[srcIdx+1]   // G (byte 1)
-				data[dstIdx+2] = img.Pix[srcIdx]     // R (byte 2)
-				if depth == 32 {
-					data[dstIdx+3] = img.Pix[srcIdx+3] // A (byte 3) - only for depth 32
-				} else {
-					data[dstIdx+3] = 0 // Padding byte for depth 24
-				}
-			} else if bytesPerPixel == 3 {
-				// 24-bit: BGR
-				data[dstIdx] = img.Pix[srcIdx+2]     // B
-				data[dstIdx+1] = img.Pix[srcIdx+1]   // G
-				data[dstIdx+2] = img.Pix[srcIdx]     // R
-			} else {
-				return fmt.Errorf("unsupported bytes per pixel: %d", bytesPerPixel)
-			}
-		}
-		// Padding bytes are already zero-initialized
+	// Byte order matches X11 visual masks: 0xff (B), 0xff00 (G), 0xff0000 (R);
+	// alpha is only kept for depth 32. Padding bytes are already zero-initialized.
+	if bytesPerPixel != 3 && bytesPerPixel != 4 {
+		return fmt.Errorf("unsupported bytes per pixel: %d", bytesPerPixel)
 	}
+	pixconv.RGBAToBGRX(data, stride, bytesPerPixel, img, depth == 32)
 
 	// Try creating a fresh GC just for this putImage call
 	testGc, err := xproto.NewGcontextId(m.conn)
@@ -0,0 +1,207 @@
+// Package dnd turns on the desktop's "Do Not Disturb" mode while the stream
+// is live so notifications don't pop up on the shared screen.
+package dnd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/godbus/dbus/v5"
+)
+
+// Suppression methods
+const (
+	MethodKDE   = "kde"   // org.freedesktop.Notifications.Inhibit (Plasma)
+	MethodGNOME = "gnome" // org.gnome.desktop.notifications show-banners
+)
+
+const (
+	notificationsService   = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+
+	gnomeNotificationsSchema = "org.gnome.desktop.notifications"
+	gnomeShowBannersKey      = "show-banners"
+)
+
+// Status reports the suppressor state for /api/health
+type Status struct {
+	Enabled   bool   `json:"enabled"`
+	Live      bool   `json:"live"`             // Stream is live (streaming with clients)
+	Active    bool   `json:"active"`           // Notifications are currently suppressed
+	Method    string `json:"method,omitempty"` // kde or gnome while active
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Suppressor enables Do Not Disturb when the stream goes live and restores
+// the previous state when it stops
+type Suppressor struct {
+	enabled bool
+
+	mu      sync.Mutex
+	live    bool
+	active  bool
+	method  string
+	lastErr string
+
+	// KDE: the inhibition lives as long as this connection, so a crash
+	// releases it automatically
+	conn   *dbus.Conn
+	cookie uint32
+
+	// GNOME: previous show-banners value to restore
+	gnomePrevious string
+}
+
+// NewSuppressor creates a suppressor; when disabled it only tracks state
+func NewSuppressor(enabled bool) *Suppressor {
+	return &Suppressor{enabled: enabled}
+}
+
+// SetLive updates whether the stream is live, suppressing or restoring
+// notifications on transitions
+func (s *Suppressor) SetLive(live bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.live = live
+	if !s.enabled {
+		return
+	}
+
+	log := logger.WithComponent("dnd")
+	switch {
+	case live && !s.active:
+		if err := s.suppress(); err != nil {
+			s.lastErr = err.Error()
+			log.Warn().Err(err).Msg("Failed to enable Do Not Disturb")
+			return
+		}
+		s.lastErr = ""
+		log.Info().Str("method", s.method).Msg("Do Not Disturb enabled while live")
+	case !live && s.active:
+		if err := s.restore(); err != nil {
+			s.lastErr = err.Error()
+			log.Warn().Err(err).Msg("Failed to restore notifications")
+			return
+		}
+		log.Info().Msg("Notifications restored")
+	}
+}
+
+// Close restores notifications if they are suppressed
+func (s *Suppressor) Close() {
+	s.SetLive(false)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// Status returns a snapshot of the suppressor state
+func (s *Suppressor) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{
+		Enabled:   s.enabled,
+		Live:      s.live,
+		Active:    s.active,
+		LastError: s.lastErr,
+	}
+	if s.active {
+		status.Method = s.method
+	}
+	return status
+}
+
+// suppress tries KDE inhibition, then GNOME banners. Called with mu held.
+func (s *Suppressor) suppress() error {
+	kdeErr := s.inhibitKDE()
+	if kdeErr == nil {
+		s.active = true
+		s.method = MethodKDE
+		return nil
+	}
+
+	gnomeErr := s.disableGNOMEBanners()
+	if gnomeErr == nil {
+		s.active = true
+		s.method = MethodGNOME
+		return nil
+	}
+
+	return fmt.Errorf("no supported notification server (kde: %v; gnome: %v)", kdeErr, gnomeErr)
+}
+
+// restore undoes suppress. Called with mu held.
+func (s *Suppressor) restore() error {
+	var err error
+	switch s.method {
+	case MethodKDE:
+		err = s.conn.Object(notificationsService, notificationsPath).
+			Call(notificationsInterface+".UnInhibit", 0, s.cookie).Err
+		if err != nil {
+			// Dropping the connection releases the inhibition anyway
+			s.conn.Close()
+			s.conn = nil
+			err = nil
+		}
+	case MethodGNOME:
+		err = exec.Command("gsettings", "set", gnomeNotificationsSchema, gnomeShowBannersKey, s.gnomePrevious).Run()
+		if err != nil {
+			err = fmt.Errorf("failed to restore GNOME notification banners: %w", err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	s.active = false
+	s.method = ""
+	return nil
+}
+
+// inhibitKDE calls the Plasma-specific Inhibit extension of the
+// notifications spec on a private session bus connection
+func (s *Suppressor) inhibitKDE() error {
+	if s.conn == nil {
+		conn, err := dbus.ConnectSessionBus()
+		if err != nil {
+			return fmt.Errorf("failed to connect to session bus: %w", err)
+		}
+		s.conn = conn
+	}
+
+	obj := s.conn.Object(notificationsService, notificationsPath)
+	var cookie uint32
+	err := obj.Call(notificationsInterface+".Inhibit", 0,
+		"focusstreamer", "Screen sharing is live", map[string]dbus.Variant{}).Store(&cookie)
+	if err != nil {
+		return fmt.Errorf("notification inhibition not supported: %w", err)
+	}
+
+	s.cookie = cookie
+	return nil
+}
+
+// disableGNOMEBanners turns off GNOME notification banners, remembering the
+// previous value
+func (s *Suppressor) disableGNOMEBanners() error {
+	output, err := exec.Command("gsettings", "get", gnomeNotificationsSchema, gnomeShowBannersKey).Output()
+	if err != nil {
+		return fmt.Errorf("gsettings unavailable: %w", err)
+	}
+	s.gnomePrevious = strings.TrimSpace(string(output))
+
+	if err := exec.Command("gsettings", "set", gnomeNotificationsSchema, gnomeShowBannersKey, "false").Run(); err != nil {
+		return fmt.Errorf("failed to disable GNOME notification banners: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+// Package eventhub fans the typed events in pkg/events out to the channels
+// publishing them: the /api/events/ws WebSocket and the D-Bus Event signal.
+package eventhub
+
+import (
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further ones are dropped for it
+const subscriberBuffer = 64
+
+// Hub passes each published event to every subscriber
+type Hub struct {
+	mu   sync.Mutex
+	subs map[chan *events.Event]struct{}
+}
+
+// New creates a hub without subscribers
+func New() *Hub {
+	return &Hub{subs: make(map[chan *events.Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every event published from now on.
+// Events are shared between subscribers and mustn't be modified.
+func (h *Hub) Subscribe() chan *events.Event {
+	ch := make(chan *events.Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe stops and closes a channel from Subscribe
+func (h *Hub) Unsubscribe(ch chan *events.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[ch]; ok {
+		delete(h.subs, ch)
+		close(ch)
+	}
+}
+
+// Publish passes an event to every subscriber without blocking; a
+// subscriber that has fallen behind misses it
+func (h *Hub) Publish(event *events.Event) {
+	if event.Time == nil {
+		event.Time = timestamppb.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// ConfigUpdated returns the event announcing a saved configuration
+func ConfigUpdated(configMgr *config.Manager) *events.Event {
+	updated := &events.ConfigUpdated{ActiveProfileId: configMgr.GetActiveProfileID()}
+	if profile := configMgr.GetActiveProfile(); profile != nil {
+		updated.ActiveProfileName = profile.Name
+	}
+	return &events.Event{Payload: &events.Event_ConfigUpdated{ConfigUpdated: updated}}
+}
@@ -0,0 +1,233 @@
+// Package focusstats tracks how long each application has focus, whether
+// or not it is streamed, and keeps the daily totals in the config directory
+// so they add up across restarts. The totals are reported per day or week
+// at /api/stats/focus and on the /report page.
+package focusstats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Report periods
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+const (
+	// FileName is the store's file in the config directory
+	FileName = "focus-stats.json"
+
+	// dayLayout keys the daily totals, in local time
+	dayLayout = "2006-01-02"
+
+	// maxDays bounds the days a report covers
+	maxDays = 366
+)
+
+// AppTime is how long an application had focus
+type AppTime struct {
+	Class   string  `json:"class"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Bucket is one day or week of a report
+type Bucket struct {
+	Start   string    `json:"start"` // First day, YYYY-MM-DD
+	Seconds float64   `json:"seconds"`
+	Apps    []AppTime `json:"apps"` // Longest first
+}
+
+// Report is focus time per application over the last days or weeks,
+// ending today
+type Report struct {
+	Period  string    `json:"period"`
+	From    string    `json:"from"` // First day, YYYY-MM-DD
+	To      string    `json:"to"`   // Today
+	Seconds float64   `json:"seconds"`
+	Buckets []Bucket  `json:"buckets"` // Oldest first
+	Totals  []AppTime `json:"totals"`  // Longest first
+}
+
+// storeFile is the store's file format
+type storeFile struct {
+	Days map[string]map[string]float64 `json:"days"` // Day -> class -> seconds
+}
+
+// Store holds focus seconds per application and day
+type Store struct {
+	path          string
+	retentionDays int
+
+	mu    sync.Mutex
+	days  map[string]map[string]float64
+	dirty bool
+}
+
+// Open loads the store at path, starting empty if it doesn't exist yet.
+// Days older than retentionDays are dropped when saving; 0 keeps them all.
+func Open(path string, retentionDays int) (*Store, error) {
+	s := &Store{
+		path:          path,
+		retentionDays: retentionDays,
+		days:          make(map[string]map[string]float64),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read focus stats: %w", err)
+	}
+	var file storeFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse focus stats %s: %w", path, err)
+	}
+	if file.Days != nil {
+		s.days = file.Days
+	}
+	return s, nil
+}
+
+// Add counts the time from start to end toward class, split at midnight
+// between the days it spans
+func (s *Store) Add(class string, start, end time.Time) {
+	if class == "" || !end.After(start) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for start.Before(end) {
+		y, m, d := start.Date()
+		midnight := time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+		until := end
+		if midnight.Before(end) {
+			until = midnight
+		}
+
+		day := start.Format(dayLayout)
+		if s.days[day] == nil {
+			s.days[day] = make(map[string]float64)
+		}
+		s.days[day][class] += until.Sub(start).Seconds()
+		start = until
+	}
+	s.dirty = true
+}
+
+// Save writes the store if anything was added since the last save
+func (s *Store) Save(now time.Time) error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	if s.retentionDays > 0 {
+		y, m, d := now.Date()
+		oldest := time.Date(y, m, d-s.retentionDays+1, 0, 0, 0, 0, now.Location()).Format(dayLayout)
+		for day := range s.days {
+			if day < oldest {
+				delete(s.days, day)
+			}
+		}
+	}
+	data, err := json.Marshal(storeFile{Days: s.days})
+	s.dirty = false
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// Write to a temp file and rename so a crash mid-write can't leave
+	// truncated stats behind
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write focus stats: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write focus stats: %w", err)
+	}
+	return nil
+}
+
+// Report returns the focus time of the last count days or weeks up to now.
+// Weeks start on Monday.
+func (s *Store) Report(period string, count int, now time.Time) (Report, error) {
+	if period != PeriodDay && period != PeriodWeek {
+		return Report{}, fmt.Errorf("period must be %s or %s", PeriodDay, PeriodWeek)
+	}
+	bucketDays := 1
+	if period == PeriodWeek {
+		bucketDays = 7
+	}
+	if count < 1 || count*bucketDays > maxDays {
+		return Report{}, fmt.Errorf("count must be between 1 and %d for period %s", maxDays/bucketDays, period)
+	}
+
+	y, m, d := now.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+	first := today
+	if period == PeriodWeek {
+		first = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	}
+	first = first.AddDate(0, 0, -(count-1)*bucketDays)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := Report{
+		Period:  period,
+		From:    first.Format(dayLayout),
+		To:      today.Format(dayLayout),
+		Buckets: make([]Bucket, 0, count),
+	}
+	totals := make(map[string]float64)
+	for i := 0; i < count; i++ {
+		start := first.AddDate(0, 0, i*bucketDays)
+		apps := make(map[string]float64)
+		for j := 0; j < bucketDays; j++ {
+			for class, seconds := range s.days[start.AddDate(0, 0, j).Format(dayLayout)] {
+				apps[class] += seconds
+			}
+		}
+
+		bucket := Bucket{Start: start.Format(dayLayout), Apps: sortedApps(apps)}
+		for class, seconds := range apps {
+			bucket.Seconds += seconds
+			totals[class] += seconds
+		}
+		report.Seconds += bucket.Seconds
+		report.Buckets = append(report.Buckets, bucket)
+	}
+	report.Totals = sortedApps(totals)
+	return report, nil
+}
+
+// sortedApps returns per-class seconds longest first
+func sortedApps(seconds map[string]float64) []AppTime {
+	apps := make([]AppTime, 0, len(seconds))
+	for class, s := range seconds {
+		apps = append(apps, AppTime{Class: class, Seconds: s})
+	}
+	sort.Slice(apps, func(i, j int) bool {
+		if apps[i].Seconds != apps[j].Seconds {
+			return apps[i].Seconds > apps[j].Seconds
+		}
+		return apps[i].Class < apps[j].Class
+	})
+	return apps
+}
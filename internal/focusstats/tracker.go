@@ -0,0 +1,86 @@
+package focusstats
+
+import (
+	"context"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+const (
+	// tickInterval is how often the focused application's time is counted,
+	// so reports lag it by at most this much
+	tickInterval = 15 * time.Second
+
+	// saveInterval is how often the store is written while running
+	saveInterval = 5 * time.Minute
+
+	// maxGap is the longest stretch counted at once; a longer one means the
+	// machine was suspended, and isn't counted
+	maxGap = 4 * tickInterval
+)
+
+// FocusSource reports the focused window and its changes, e.g. the window
+// manager
+type FocusSource interface {
+	Subscribe() chan *config.WindowInfo
+	Unsubscribe(ch chan *config.WindowInfo)
+	GetCurrentWindow() *config.WindowInfo
+}
+
+// Track counts the time each window class has focus into store until ctx
+// is done, then saves it
+func Track(ctx context.Context, source FocusSource, store *Store) {
+	log := logger.WithComponent("focusstats")
+	updates := source.Subscribe()
+	defer source.Unsubscribe(updates)
+
+	tick := time.NewTicker(tickInterval)
+	defer tick.Stop()
+	save := time.NewTicker(saveInterval)
+	defer save.Stop()
+
+	// Wall clock times, so a suspend shows up as a gap
+	class := classOf(source.GetCurrentWindow())
+	since := time.Now().Round(0)
+
+	count := func(next string) {
+		now := time.Now().Round(0)
+		if now.Sub(since) <= maxGap {
+			store.Add(class, since, now)
+		}
+		class, since = next, now
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			count(class)
+			if err := store.Save(time.Now()); err != nil {
+				log.Warn().Err(err).Msg("Failed to save focus stats")
+			}
+			return
+		case window, ok := <-updates:
+			if !ok {
+				return
+			}
+			count(classOf(window))
+		case <-tick.C:
+			// Updates are dropped when the channel is full; the current
+			// window keeps the class right
+			count(classOf(source.GetCurrentWindow()))
+		case <-save.C:
+			if err := store.Save(time.Now()); err != nil {
+				log.Warn().Err(err).Msg("Failed to save focus stats")
+			}
+		}
+	}
+}
+
+func classOf(window *config.WindowInfo) string {
+	if window == nil {
+		return ""
+	}
+	return window.Class
+}
@@ -0,0 +1,134 @@
+// Package framepool provides pooled RGBA frames and byte buffers shared by
+// capture, window.Manager, and outputs to reduce allocation churn at stream FPS.
+//
+// Ownership rules:
+//   - A frame returned by Get/GetZeroed is owned by the caller.
+//   - Ownership is transferred by returning the frame (e.g. from a capturer).
+//   - The owner releases a frame with Put once nothing references it anymore.
+//   - Frames must not be read or written after Put.
+//   - Never Put a frame that is cached or shared (e.g. the placeholder cache).
+//
+// Output.WriteFrame implementations do not take ownership and must not
+// retain the frame after returning.
+package framepool
+
+import (
+	"bytes"
+	"image"
+	"sync"
+	"sync/atomic"
+)
+
+// maxSizes bounds the number of distinct frame sizes pooled at once.
+// Window crops vary in size, so stale sizes are dropped when exceeded.
+const maxSizes = 16
+
+// maxBufferCap prevents pathologically large buffers from being pooled
+const maxBufferCap = 64 << 20
+
+var (
+	poolsMu sync.RWMutex
+	pools   = make(map[image.Point]*sync.Pool)
+
+	bufferPool = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+
+	hits   uint64
+	misses uint64
+)
+
+// poolFor returns the pool for frames of the given size
+func poolFor(size image.Point) *sync.Pool {
+	poolsMu.RLock()
+	p, ok := pools[size]
+	poolsMu.RUnlock()
+	if ok {
+		return p
+	}
+
+	poolsMu.Lock()
+	defer poolsMu.Unlock()
+
+	if p, ok := pools[size]; ok {
+		return p
+	}
+	if len(pools) >= maxSizes {
+		pools = make(map[image.Point]*sync.Pool)
+	}
+	p = &sync.Pool{}
+	pools[size] = p
+	return p
+}
+
+// Get returns a width x height frame with undefined contents.
+// Use it when every pixel will be overwritten.
+func Get(width, height int) *image.RGBA {
+	if width <= 0 || height <= 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	if v := poolFor(image.Point{X: width, Y: height}).Get(); v != nil {
+		atomic.AddUint64(&hits, 1)
+		return v.(*image.RGBA)
+	}
+
+	atomic.AddUint64(&misses, 1)
+	return image.NewRGBA(image.Rect(0, 0, width, height))
+}
+
+// GetZeroed returns a width x height frame cleared to transparent black
+func GetZeroed(width, height int) *image.RGBA {
+	img := Get(width, height)
+	clear(img.Pix)
+	return img
+}
+
+// Put returns a frame to the pool. Frames that aren't tightly packed with
+// a zero origin (e.g. SubImage results) are ignored.
+func Put(img *image.RGBA) {
+	if img == nil {
+		return
+	}
+
+	b := img.Bounds()
+	if b.Min != (image.Point{}) || b.Dx() <= 0 || b.Dy() <= 0 || img.Stride != b.Dx()*4 || len(img.Pix) != img.Stride*b.Dy() {
+		return
+	}
+
+	poolFor(b.Size()).Put(img)
+}
+
+// GetBuffer returns an empty byte buffer from the pool
+func GetBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// PutBuffer returns a byte buffer to the pool. The buffer's bytes must not
+// be referenced after this call.
+func PutBuffer(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxBufferCap {
+		return
+	}
+	bufferPool.Put(buf)
+}
+
+// Stats returns the number of frame requests served from the pool (hits)
+// and the number that required a new allocation (misses)
+func Stats() (uint64, uint64) {
+	return atomic.LoadUint64(&hits), atomic.LoadUint64(&misses)
+}
+
+// Clone returns a pooled copy of src with a zero origin
+func Clone(src *image.RGBA) *image.RGBA {
+	b := src.Bounds()
+	dst := Get(b.Dx(), b.Dy())
+	rowBytes := b.Dx() * 4
+	for y := 0; y < b.Dy(); y++ {
+		srcStart := src.PixOffset(b.Min.X, b.Min.Y+y)
+		copy(dst.Pix[y*dst.Stride:y*dst.Stride+rowBytes], src.Pix[srcStart:srcStart+rowBytes])
+	}
+	return dst
+}
@@ -0,0 +1,357 @@
+//go:build gpu && cgo
+
+package gpu
+
+/*
+#cgo LDFLAGS: -lEGL -lGLESv2
+#include <stdlib.h>
+#include <string.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+#include <GLES3/gl3.h>
+
+// openDisplay opens an EGL display without a window system, preferring a
+// hardware device over Mesa's software one
+static EGLDisplay openDisplay(void) {
+	PFNEGLQUERYDEVICESEXTPROC queryDevices =
+		(PFNEGLQUERYDEVICESEXTPROC)eglGetProcAddress("eglQueryDevicesEXT");
+	PFNEGLQUERYDEVICESTRINGEXTPROC queryDeviceString =
+		(PFNEGLQUERYDEVICESTRINGEXTPROC)eglGetProcAddress("eglQueryDeviceStringEXT");
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC)eglGetProcAddress("eglGetPlatformDisplayEXT");
+
+	if (queryDevices && queryDeviceString && getPlatformDisplay) {
+		EGLDeviceEXT devices[16];
+		EGLint count = 0;
+		if (queryDevices(16, devices, &count) && count > 0) {
+			for (int pass = 0; pass < 2; pass++) {
+				for (int i = 0; i < count; i++) {
+					const char *ext = queryDeviceString(devices[i], EGL_EXTENSIONS);
+					int hardware = ext && strstr(ext, "EGL_EXT_device_drm") != NULL;
+					if (pass == 0 && !hardware) {
+						continue;
+					}
+					EGLDisplay display = getPlatformDisplay(EGL_PLATFORM_DEVICE_EXT, devices[i], NULL);
+					if (display != EGL_NO_DISPLAY && eglInitialize(display, NULL, NULL)) {
+						return display;
+					}
+				}
+			}
+		}
+	}
+
+	EGLDisplay display = eglGetDisplay(EGL_DEFAULT_DISPLAY);
+	if (display != EGL_NO_DISPLAY && eglInitialize(display, NULL, NULL)) {
+		return display;
+	}
+	return EGL_NO_DISPLAY;
+}
+
+// createContext creates a surfaceless OpenGL ES 3 context and makes it
+// current on the calling thread
+static EGLContext createContext(EGLDisplay display) {
+	if (!eglBindAPI(EGL_OPENGL_ES_API)) {
+		return EGL_NO_CONTEXT;
+	}
+
+	EGLint configAttribs[] = {
+		EGL_RENDERABLE_TYPE, EGL_OPENGL_ES3_BIT,
+		EGL_SURFACE_TYPE, 0,
+		EGL_NONE,
+	};
+	EGLConfig config;
+	EGLint numConfigs = 0;
+	if (!eglChooseConfig(display, configAttribs, &config, 1, &numConfigs) || numConfigs == 0) {
+		return EGL_NO_CONTEXT;
+	}
+
+	EGLint contextAttribs[] = {EGL_CONTEXT_MAJOR_VERSION, 3, EGL_NONE};
+	EGLContext context = eglCreateContext(display, config, EGL_NO_CONTEXT, contextAttribs);
+	if (context == EGL_NO_CONTEXT) {
+		return EGL_NO_CONTEXT;
+	}
+	if (!eglMakeCurrent(display, EGL_NO_SURFACE, EGL_NO_SURFACE, context)) {
+		eglDestroyContext(display, context);
+		return EGL_NO_CONTEXT;
+	}
+	return context;
+}
+
+static GLuint compileShader(GLenum type, const char *source) {
+	GLuint shader = glCreateShader(type);
+	glShaderSource(shader, 1, &source, NULL);
+	glCompileShader(shader);
+	GLint ok = 0;
+	glGetShaderiv(shader, GL_COMPILE_STATUS, &ok);
+	if (!ok) {
+		glDeleteShader(shader);
+		return 0;
+	}
+	return shader;
+}
+
+// Draws a quad from gl_VertexID, so no vertex buffers are needed. Image row
+// 0 is uploaded as texture row 0 and read back from framebuffer row 0, so
+// nothing is flipped.
+static const char *vertexSource =
+	"#version 300 es\n"
+	"uniform vec4 dstRect;\n"
+	"out highp vec2 uv;\n"
+	"void main() {\n"
+	"  vec2 pos = vec2(float(gl_VertexID & 1), float(gl_VertexID >> 1));\n"
+	"  uv = pos;\n"
+	"  gl_Position = vec4(dstRect.xy + pos * dstRect.zw, 0.0, 1.0);\n"
+	"}\n";
+
+static const char *fragmentSource =
+	"#version 300 es\n"
+	"precision highp float;\n"
+	"uniform sampler2D tex;\n"
+	"in highp vec2 uv;\n"
+	"out vec4 color;\n"
+	"void main() {\n"
+	"  color = texture(tex, uv);\n"
+	"}\n";
+
+static GLuint createProgram(void) {
+	GLuint vs = compileShader(GL_VERTEX_SHADER, vertexSource);
+	GLuint fs = compileShader(GL_FRAGMENT_SHADER, fragmentSource);
+	if (!vs || !fs) {
+		return 0;
+	}
+	GLuint program = glCreateProgram();
+	glAttachShader(program, vs);
+	glAttachShader(program, fs);
+	glLinkProgram(program);
+	glDeleteShader(vs);
+	glDeleteShader(fs);
+	GLint ok = 0;
+	glGetProgramiv(program, GL_LINK_STATUS, &ok);
+	if (!ok) {
+		glDeleteProgram(program);
+		return 0;
+	}
+	return program;
+}
+
+static GLuint createTexture(void) {
+	GLuint tex;
+	glGenTextures(1, &tex);
+	glBindTexture(GL_TEXTURE_2D, tex);
+	glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_MIN_FILTER, GL_LINEAR);
+	glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_MAG_FILTER, GL_LINEAR);
+	glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_WRAP_S, GL_CLAMP_TO_EDGE);
+	glTexParameteri(GL_TEXTURE_2D, GL_TEXTURE_WRAP_T, GL_CLAMP_TO_EDGE);
+	return tex;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// eglCompositor owns an EGL context. GL contexts are bound to an OS thread,
+// so all GL calls run on one locked goroutine.
+type eglCompositor struct {
+	calls    chan func()
+	renderer string
+
+	closeOnce sync.Once
+	done      chan struct{}
+
+	// Used on the GL thread only
+	display   C.EGLDisplay
+	context   C.EGLContext
+	program   C.GLuint
+	dstRectID C.GLint
+	srcTex    C.GLuint
+	dstTex    C.GLuint
+	fbo       C.GLuint
+	srcSize   image.Point
+	dstSize   image.Point
+}
+
+// NewCompositor creates an offscreen GPU context for scaling frames
+func NewCompositor() (Scaler, error) {
+	c := &eglCompositor{
+		calls: make(chan func()),
+		done:  make(chan struct{}),
+	}
+
+	initErr := make(chan error, 1)
+	go c.run(initErr)
+	if err := <-initErr; err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// run initializes the context and serves GL calls until closed
+func (c *eglCompositor) run(initErr chan<- error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := c.init(); err != nil {
+		c.destroy()
+		initErr <- err
+		return
+	}
+	initErr <- nil
+
+	for {
+		select {
+		case call := <-c.calls:
+			call()
+		case <-c.done:
+			c.destroy()
+			return
+		}
+	}
+}
+
+func (c *eglCompositor) init() error {
+	c.display = C.openDisplay()
+	if c.display == 0 {
+		return fmt.Errorf("no EGL display available")
+	}
+	c.context = C.createContext(c.display)
+	if c.context == nil {
+		return fmt.Errorf("failed to create OpenGL ES 3 context (EGL error 0x%x)", int(C.eglGetError()))
+	}
+
+	c.program = C.createProgram()
+	if c.program == 0 {
+		return fmt.Errorf("failed to build shaders")
+	}
+	C.glUseProgram(c.program)
+
+	dstRect := C.CString("dstRect")
+	defer C.free(unsafe.Pointer(dstRect))
+	c.dstRectID = C.glGetUniformLocation(c.program, dstRect)
+
+	c.srcTex = C.createTexture()
+	c.dstTex = C.createTexture()
+	C.glGenFramebuffers(1, &c.fbo)
+
+	c.renderer = C.GoString((*C.char)(unsafe.Pointer(C.glGetString(C.GL_RENDERER))))
+	if err := glError("initialization"); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *eglCompositor) destroy() {
+	if c.context != nil {
+		if c.fbo != 0 {
+			C.glDeleteFramebuffers(1, &c.fbo)
+		}
+		textures := [2]C.GLuint{c.srcTex, c.dstTex}
+		C.glDeleteTextures(2, &textures[0])
+		if c.program != 0 {
+			C.glDeleteProgram(c.program)
+		}
+		C.eglMakeCurrent(c.display, nil, nil, nil)
+		C.eglDestroyContext(c.display, c.context)
+	}
+	if c.display != 0 {
+		C.eglTerminate(c.display)
+	}
+}
+
+// do runs fn on the GL thread
+func (c *eglCompositor) do(fn func() error) error {
+	result := make(chan error, 1)
+	select {
+	case c.calls <- func() { result <- fn() }:
+		return <-result
+	case <-c.done:
+		return fmt.Errorf("GPU compositor closed")
+	}
+}
+
+// Scale scales the sr region of src into the dr region of dst
+func (c *eglCompositor) Scale(dst *image.RGBA, dr image.Rectangle, src *image.RGBA, sr image.Rectangle) error {
+	sr = sr.Intersect(src.Bounds())
+	if sr.Empty() || dst.Bounds().Empty() {
+		return fmt.Errorf("nothing to scale")
+	}
+	return c.do(func() error {
+		return c.scale(dst, dr, src, sr)
+	})
+}
+
+func (c *eglCompositor) scale(dst *image.RGBA, dr image.Rectangle, src *image.RGBA, sr image.Rectangle) error {
+	// Upload only the source region; the row length skips the rest
+	C.glBindTexture(C.GL_TEXTURE_2D, c.srcTex)
+	C.glPixelStorei(C.GL_UNPACK_ROW_LENGTH, C.GLint(src.Stride/4))
+	srcPix := unsafe.Pointer(&src.Pix[src.PixOffset(sr.Min.X, sr.Min.Y)])
+	if size := sr.Size(); size != c.srcSize {
+		C.glTexImage2D(C.GL_TEXTURE_2D, 0, C.GL_RGBA8, C.GLsizei(size.X), C.GLsizei(size.Y), 0, C.GL_RGBA, C.GL_UNSIGNED_BYTE, srcPix)
+		c.srcSize = size
+	} else {
+		C.glTexSubImage2D(C.GL_TEXTURE_2D, 0, 0, 0, C.GLsizei(size.X), C.GLsizei(size.Y), C.GL_RGBA, C.GL_UNSIGNED_BYTE, srcPix)
+	}
+	C.glPixelStorei(C.GL_UNPACK_ROW_LENGTH, 0)
+
+	// Render into a texture the size of dst
+	bounds := dst.Bounds()
+	C.glBindFramebuffer(C.GL_FRAMEBUFFER, c.fbo)
+	if size := bounds.Size(); size != c.dstSize {
+		C.glBindTexture(C.GL_TEXTURE_2D, c.dstTex)
+		C.glTexImage2D(C.GL_TEXTURE_2D, 0, C.GL_RGBA8, C.GLsizei(size.X), C.GLsizei(size.Y), 0, C.GL_RGBA, C.GL_UNSIGNED_BYTE, nil)
+		C.glFramebufferTexture2D(C.GL_FRAMEBUFFER, C.GL_COLOR_ATTACHMENT0, C.GL_TEXTURE_2D, c.dstTex, 0)
+		if status := C.glCheckFramebufferStatus(C.GL_FRAMEBUFFER); status != C.GL_FRAMEBUFFER_COMPLETE {
+			c.dstSize = image.Point{}
+			return fmt.Errorf("framebuffer incomplete (0x%x)", int(status))
+		}
+		c.dstSize = size
+	}
+
+	width, height := float32(bounds.Dx()), float32(bounds.Dy())
+	dr = dr.Sub(bounds.Min)
+	C.glViewport(0, 0, C.GLsizei(bounds.Dx()), C.GLsizei(bounds.Dy()))
+	C.glClearColor(0, 0, 0, 0)
+	C.glClear(C.GL_COLOR_BUFFER_BIT)
+
+	C.glUseProgram(c.program)
+	C.glUniform4f(c.dstRectID,
+		C.GLfloat(2*float32(dr.Min.X)/width-1),
+		C.GLfloat(2*float32(dr.Min.Y)/height-1),
+		C.GLfloat(2*float32(dr.Dx())/width),
+		C.GLfloat(2*float32(dr.Dy())/height))
+	C.glActiveTexture(C.GL_TEXTURE0)
+	C.glBindTexture(C.GL_TEXTURE_2D, c.srcTex)
+	C.glDrawArrays(C.GL_TRIANGLE_STRIP, 0, 4)
+
+	// Read back into dst
+	C.glPixelStorei(C.GL_PACK_ROW_LENGTH, C.GLint(dst.Stride/4))
+	dstPix := unsafe.Pointer(&dst.Pix[dst.PixOffset(bounds.Min.X, bounds.Min.Y)])
+	C.glReadPixels(0, 0, C.GLsizei(bounds.Dx()), C.GLsizei(bounds.Dy()), C.GL_RGBA, C.GL_UNSIGNED_BYTE, dstPix)
+	C.glPixelStorei(C.GL_PACK_ROW_LENGTH, 0)
+	C.glBindFramebuffer(C.GL_FRAMEBUFFER, 0)
+
+	return glError("scale")
+}
+
+// Renderer names the GPU
+func (c *eglCompositor) Renderer() string {
+	return c.renderer
+}
+
+// Close releases the GPU context
+func (c *eglCompositor) Close() error {
+	c.closeOnce.Do(func() { close(c.done) })
+	return nil
+}
+
+func glError(op string) error {
+	if code := C.glGetError(); code != C.GL_NO_ERROR {
+		return fmt.Errorf("OpenGL error 0x%x during %s", int(code), op)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+// Package gpu scales frames on the GPU through an offscreen EGL/OpenGL ES 3
+// context, for the zoom path that costs the most CPU per frame at high
+// resolutions. GPU support needs cgo with the EGL and GLESv2 libraries and is
+// only compiled in with the gpu build tag:
+//
+//	go build -tags gpu ./cmd/focusstreamer
+//
+// Without it, NewCompositor returns an error and callers use the CPU path.
+package gpu
+
+import "image"
+
+// Scaler scales part of one image into another
+type Scaler interface {
+	// Scale scales the sr region of src into the dr region of dst, with
+	// bilinear filtering. All of dst is replaced: the area outside dr is
+	// cleared to transparent black.
+	Scale(dst *image.RGBA, dr image.Rectangle, src *image.RGBA, sr image.Rectangle) error
+
+	// Renderer names the GPU doing the work, e.g. "Mesa Intel(R) UHD Graphics"
+	Renderer() string
+
+	// Close releases the GPU context
+	Close() error
+}
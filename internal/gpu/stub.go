@@ -0,0 +1,10 @@
+//go:build !gpu || !cgo
+
+package gpu
+
+import "fmt"
+
+// NewCompositor reports that GPU support wasn't compiled in
+func NewCompositor() (Scaler, error) {
+	return nil, fmt.Errorf("built without GPU support (rebuild with -tags gpu)")
+}
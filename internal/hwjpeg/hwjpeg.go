@@ -0,0 +1,30 @@
+// Package hwjpeg encodes stream frames as JPEG on a hardware encoder
+// exposed as a V4L2 memory-to-memory device, such as the bcm2835 codec of
+// the Raspberry Pi 4 (/dev/video31). Frames are handed over as raw pixels
+// and the encoded JPEG is read back, freeing the CPU on small boards.
+// The Raspberry Pi 5 has no hardware JPEG or H.264 encoder and no
+// /dev/video31, so there the software encoder stays in use.
+package hwjpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Device settings accepted by Open
+const (
+	DeviceOff  = "off"  // Software encoding only
+	DeviceAuto = "auto" // First V4L2 JPEG encoder found, if any
+)
+
+// ValidateDevice checks a device setting: "off", "auto", or a device path
+func ValidateDevice(device string) error {
+	switch {
+	case device == "", device == DeviceOff, device == DeviceAuto:
+		return nil
+	case strings.HasPrefix(device, "/dev/"):
+		return nil
+	default:
+		return fmt.Errorf("%q is not off, auto or a device path such as /dev/video31", device)
+	}
+}
@@ -0,0 +1,473 @@
+//go:build linux && (arm64 || amd64)
+
+package hwjpeg
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+)
+
+// V4L2 definitions from linux/videodev2.h. The structs match the 64-bit
+// kernel ABI, hence the build constraint.
+const (
+	v4l2CapVideoM2M   = 0x00008000
+	v4l2CapDeviceCaps = 0x80000000
+
+	v4l2BufTypeCapture = 1
+	v4l2BufTypeOutput  = 2
+	v4l2MemoryMMAP     = 1
+	v4l2FieldNone      = 1
+
+	v4l2CIDJPEGQuality = 0x009d0903 // V4L2_CID_JPEG_COMPRESSION_QUALITY
+)
+
+// Pixel formats, as fourcc codes
+var (
+	pixFmtJPEG  = fourcc("JPEG")
+	pixFmtMJPEG = fourcc("MJPG")
+	pixFmtABGR  = fourcc("AR24") // B, G, R, A in memory
+	pixFmtXBGR  = fourcc("XR24") // B, G, R, X in memory
+	pixFmtRGBA  = fourcc("AB24") // R, G, B, A in memory
+	pixFmtRGB24 = fourcc("RGB3")
+)
+
+// encodeTimeout bounds the wait for one encoded frame
+const encodeTimeout = time.Second
+
+func fourcc(s string) uint32 {
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
+
+type v4l2Capability struct {
+	Driver       [16]byte
+	Card         [32]byte
+	BusInfo      [32]byte
+	Version      uint32
+	Capabilities uint32
+	DeviceCaps   uint32
+	Reserved     [3]uint32
+}
+
+type v4l2FmtDesc struct {
+	Index       uint32
+	Type        uint32
+	Flags       uint32
+	Description [32]byte
+	PixelFormat uint32
+	MbusCode    uint32
+	Reserved    [3]uint32
+}
+
+type v4l2PixFormat struct {
+	Width        uint32
+	Height       uint32
+	PixelFormat  uint32
+	Field        uint32
+	BytesPerLine uint32
+	SizeImage    uint32
+	ColorSpace   uint32
+	Priv         uint32
+	Flags        uint32
+	YCbCrEnc     uint32
+	Quantization uint32
+	XferFunc     uint32
+}
+
+// v4l2Format holds the pix member of the format union, which is 8-byte
+// aligned and 200 bytes long
+type v4l2Format struct {
+	Type uint32
+	_    uint32
+	Pix  v4l2PixFormat
+	_    [200 - 48]byte
+}
+
+type v4l2RequestBuffers struct {
+	Count        uint32
+	Type         uint32
+	Memory       uint32
+	Capabilities uint32
+	Flags        uint8
+	Reserved     [3]uint8
+}
+
+type v4l2Buffer struct {
+	Index     uint32
+	Type      uint32
+	BytesUsed uint32
+	Flags     uint32
+	Field     uint32
+	_         uint32
+	Timestamp syscall.Timeval
+	Timecode  [16]byte
+	Sequence  uint32
+	Memory    uint32
+	Offset    uint32 // Union of offset, userptr, planes and fd
+	_         uint32
+	Length    uint32
+	Reserved2 uint32
+	RequestFD int32
+	_         uint32
+}
+
+type v4l2Control struct {
+	ID    uint32
+	Value int32
+}
+
+// ioctl request codes, sized from the structs above
+var (
+	vidiocQueryCap  = ioctlCode(2, 0, unsafe.Sizeof(v4l2Capability{}))
+	vidiocEnumFmt   = ioctlCode(3, 2, unsafe.Sizeof(v4l2FmtDesc{}))
+	vidiocSFmt      = ioctlCode(3, 5, unsafe.Sizeof(v4l2Format{}))
+	vidiocReqBufs   = ioctlCode(3, 8, unsafe.Sizeof(v4l2RequestBuffers{}))
+	vidiocQueryBuf  = ioctlCode(3, 9, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocQBuf      = ioctlCode(3, 15, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocDQBuf     = ioctlCode(3, 17, unsafe.Sizeof(v4l2Buffer{}))
+	vidiocStreamOn  = ioctlCode(1, 18, unsafe.Sizeof(int32(0)))
+	vidiocStreamOff = ioctlCode(1, 19, unsafe.Sizeof(int32(0)))
+	vidiocSCtrl     = ioctlCode(3, 28, unsafe.Sizeof(v4l2Control{}))
+)
+
+// ioctlCode builds a request code: direction (1 write, 2 read, 3 both),
+// type 'V', number and argument size
+func ioctlCode(dir, nr, size uintptr) uintptr {
+	return dir<<30 | size<<16 | 'V'<<8 | nr
+}
+
+func ioctl(fd int, request uintptr, arg unsafe.Pointer) error {
+	for {
+		_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(arg))
+		switch errno {
+		case 0:
+			return nil
+		case syscall.EINTR:
+			continue
+		default:
+			return errno
+		}
+	}
+}
+
+// Find returns the first V4L2 memory-to-memory device that encodes JPEG
+func Find() (string, error) {
+	devices, _ := filepath.Glob("/dev/video*")
+	sort.Strings(devices)
+	for _, device := range devices {
+		fd, err := syscall.Open(device, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+		if err != nil {
+			continue
+		}
+		_, ok := probe(fd)
+		syscall.Close(fd)
+		if ok {
+			return device, nil
+		}
+	}
+	return "", fmt.Errorf("no V4L2 JPEG encoder found")
+}
+
+// probe reports whether a device is a JPEG encoder, and the raw formats
+// it accepts
+func probe(fd int) ([]uint32, bool) {
+	var capability v4l2Capability
+	if err := ioctl(fd, vidiocQueryCap, unsafe.Pointer(&capability)); err != nil {
+		return nil, false
+	}
+	caps := capability.Capabilities
+	if caps&v4l2CapDeviceCaps != 0 {
+		caps = capability.DeviceCaps
+	}
+	if caps&v4l2CapVideoM2M == 0 {
+		return nil, false
+	}
+
+	encodesJPEG := false
+	for _, format := range enumFormats(fd, v4l2BufTypeCapture) {
+		if format == pixFmtJPEG || format == pixFmtMJPEG {
+			encodesJPEG = true
+		}
+	}
+	if !encodesJPEG {
+		return nil, false
+	}
+	return enumFormats(fd, v4l2BufTypeOutput), true
+}
+
+// enumFormats lists the pixel formats of one side of the device
+func enumFormats(fd int, bufType uint32) []uint32 {
+	var formats []uint32
+	for i := uint32(0); ; i++ {
+		desc := v4l2FmtDesc{Index: i, Type: bufType}
+		if err := ioctl(fd, vidiocEnumFmt, unsafe.Pointer(&desc)); err != nil {
+			return formats
+		}
+		formats = append(formats, desc.PixelFormat)
+	}
+}
+
+// mappedBuffer is a driver buffer mapped into memory
+type mappedBuffer struct {
+	data []byte
+}
+
+// Encoder encodes frames on a V4L2 JPEG encoder. One frame is encoded at a
+// time; concurrent callers wait their turn.
+type Encoder struct {
+	mu      sync.Mutex
+	device  string
+	fd      int
+	inputs  []uint32 // Raw formats the device accepts
+	input   uint32   // Raw format in use
+	stride  int
+	width   int
+	height  int
+	quality int
+	output  *mappedBuffer // Raw frame, to the device
+	capture *mappedBuffer // Encoded JPEG, from the device
+}
+
+// Open opens a V4L2 JPEG encoder. device is a path, or DeviceAuto to use
+// the first encoder found.
+func Open(device string) (*Encoder, error) {
+	if device == "" || device == DeviceAuto {
+		found, err := Find()
+		if err != nil {
+			return nil, err
+		}
+		device = found
+	}
+
+	fd, err := syscall.Open(device, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", device, err)
+	}
+	inputs, ok := probe(fd)
+	if !ok {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("%s is not a V4L2 JPEG encoder", device)
+	}
+	e := &Encoder{device: device, fd: fd, inputs: inputs}
+	if e.input = e.pickInput(); e.input == 0 {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("%s accepts no RGB input format", device)
+	}
+	return e, nil
+}
+
+// pickInput chooses the raw format cheapest to convert RGBA frames to
+func (e *Encoder) pickInput() uint32 {
+	for _, preferred := range []uint32{pixFmtRGBA, pixFmtABGR, pixFmtXBGR, pixFmtRGB24} {
+		for _, format := range e.inputs {
+			if format == preferred {
+				return format
+			}
+		}
+	}
+	return 0
+}
+
+// Device returns the device path
+func (e *Encoder) Device() string {
+	return e.device
+}
+
+// Encode writes img as JPEG at the given quality (1-100)
+func (e *Encoder) Encode(w io.Writer, img *image.RGBA, quality int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.fd < 0 {
+		return fmt.Errorf("encoder closed")
+	}
+	bounds := img.Bounds()
+	if bounds.Dx() != e.width || bounds.Dy() != e.height {
+		if err := e.configure(bounds.Dx(), bounds.Dy()); err != nil {
+			return err
+		}
+	}
+	if quality != e.quality {
+		ctrl := v4l2Control{ID: v4l2CIDJPEGQuality, Value: int32(quality)}
+		if err := ioctl(e.fd, vidiocSCtrl, unsafe.Pointer(&ctrl)); err == nil {
+			e.quality = quality
+		}
+	}
+
+	e.fill(img)
+
+	out := v4l2Buffer{Type: v4l2BufTypeOutput, Memory: v4l2MemoryMMAP, BytesUsed: uint32(len(e.output.data)), Field: v4l2FieldNone}
+	if err := ioctl(e.fd, vidiocQBuf, unsafe.Pointer(&out)); err != nil {
+		return fmt.Errorf("failed to queue frame: %w", err)
+	}
+
+	encoded := v4l2Buffer{Type: v4l2BufTypeCapture, Memory: v4l2MemoryMMAP}
+	if err := e.dequeue(&encoded); err != nil {
+		return fmt.Errorf("failed to encode frame: %w", err)
+	}
+	done := v4l2Buffer{Type: v4l2BufTypeOutput, Memory: v4l2MemoryMMAP}
+	e.dequeue(&done)
+
+	_, err := w.Write(e.capture.data[:min(int(encoded.BytesUsed), len(e.capture.data))])
+
+	// Hand the capture buffer back for the next frame
+	requeue := v4l2Buffer{Type: v4l2BufTypeCapture, Memory: v4l2MemoryMMAP}
+	if qerr := ioctl(e.fd, vidiocQBuf, unsafe.Pointer(&requeue)); qerr != nil && err == nil {
+		err = fmt.Errorf("failed to requeue capture buffer: %w", qerr)
+	}
+	return err
+}
+
+// dequeue waits for the device to return a buffer
+func (e *Encoder) dequeue(buf *v4l2Buffer) error {
+	deadline := time.Now().Add(encodeTimeout)
+	for {
+		err := ioctl(e.fd, vidiocDQBuf, unsafe.Pointer(buf))
+		if err != syscall.EAGAIN {
+			return err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timed out")
+		}
+
+		var readable syscall.FdSet
+		readable.Bits[e.fd/64] |= 1 << (uint(e.fd) % 64)
+		timeout := syscall.NsecToTimeval(remaining.Nanoseconds())
+		if _, err := syscall.Select(e.fd+1, &readable, nil, nil, &timeout); err != nil && err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// fill converts a frame into the output buffer
+func (e *Encoder) fill(img *image.RGBA) {
+	bounds := img.Bounds()
+	rowBytes := bounds.Dx() * 4
+	pixconv.ParallelRows(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			start := img.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			src := img.Pix[start : start+rowBytes]
+			dst := e.output.data[y*e.stride:]
+			switch e.input {
+			case pixFmtRGBA:
+				copy(dst, src)
+			case pixFmtABGR, pixFmtXBGR:
+				pixconv.SwapRedBlue(dst[:rowBytes], src, false)
+			case pixFmtRGB24:
+				for i, j := 0, 0; i < len(src); i, j = i+4, j+3 {
+					dst[j], dst[j+1], dst[j+2] = src[i], src[i+1], src[i+2]
+				}
+			}
+		}
+	})
+}
+
+// configure sets the frame size, (re)allocating one buffer per side
+func (e *Encoder) configure(width, height int) error {
+	e.teardown()
+
+	raw := v4l2Format{Type: v4l2BufTypeOutput}
+	raw.Pix = v4l2PixFormat{Width: uint32(width), Height: uint32(height), PixelFormat: e.input, Field: v4l2FieldNone}
+	if err := ioctl(e.fd, vidiocSFmt, unsafe.Pointer(&raw)); err != nil {
+		return fmt.Errorf("failed to set input format: %w", err)
+	}
+	if int(raw.Pix.Width) != width || int(raw.Pix.Height) != height {
+		return fmt.Errorf("encoder doesn't support %dx%d frames (offered %dx%d)", width, height, raw.Pix.Width, raw.Pix.Height)
+	}
+	e.stride = int(raw.Pix.BytesPerLine)
+
+	encoded := v4l2Format{Type: v4l2BufTypeCapture}
+	encoded.Pix = v4l2PixFormat{Width: uint32(width), Height: uint32(height), PixelFormat: pixFmtJPEG, Field: v4l2FieldNone}
+	if err := ioctl(e.fd, vidiocSFmt, unsafe.Pointer(&encoded)); err != nil {
+		encoded.Pix.PixelFormat = pixFmtMJPEG
+		if err := ioctl(e.fd, vidiocSFmt, unsafe.Pointer(&encoded)); err != nil {
+			return fmt.Errorf("failed to set JPEG format: %w", err)
+		}
+	}
+
+	var err error
+	if e.output, err = e.allocate(v4l2BufTypeOutput); err != nil {
+		return err
+	}
+	if e.capture, err = e.allocate(v4l2BufTypeCapture); err != nil {
+		return err
+	}
+
+	requeue := v4l2Buffer{Type: v4l2BufTypeCapture, Memory: v4l2MemoryMMAP}
+	if err := ioctl(e.fd, vidiocQBuf, unsafe.Pointer(&requeue)); err != nil {
+		return fmt.Errorf("failed to queue capture buffer: %w", err)
+	}
+	for _, bufType := range []int32{v4l2BufTypeOutput, v4l2BufTypeCapture} {
+		if err := ioctl(e.fd, vidiocStreamOn, unsafe.Pointer(&bufType)); err != nil {
+			return fmt.Errorf("failed to start streaming: %w", err)
+		}
+	}
+
+	e.width, e.height = width, height
+	return nil
+}
+
+// allocate requests and maps a single buffer
+func (e *Encoder) allocate(bufType uint32) (*mappedBuffer, error) {
+	req := v4l2RequestBuffers{Count: 1, Type: bufType, Memory: v4l2MemoryMMAP}
+	if err := ioctl(e.fd, vidiocReqBufs, unsafe.Pointer(&req)); err != nil {
+		return nil, fmt.Errorf("failed to request buffers: %w", err)
+	}
+	if req.Count < 1 {
+		return nil, fmt.Errorf("device allocated no buffers")
+	}
+
+	buf := v4l2Buffer{Index: 0, Type: bufType, Memory: v4l2MemoryMMAP}
+	if err := ioctl(e.fd, vidiocQueryBuf, unsafe.Pointer(&buf)); err != nil {
+		return nil, fmt.Errorf("failed to query buffer: %w", err)
+	}
+	data, err := syscall.Mmap(e.fd, int64(buf.Offset), int(buf.Length), syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, fmt.Errorf("failed to map buffer: %w", err)
+	}
+	return &mappedBuffer{data: data}, nil
+}
+
+// teardown stops streaming and frees the buffers
+func (e *Encoder) teardown() {
+	if e.output == nil && e.capture == nil {
+		return
+	}
+	for _, bufType := range []int32{v4l2BufTypeOutput, v4l2BufTypeCapture} {
+		ioctl(e.fd, vidiocStreamOff, unsafe.Pointer(&bufType))
+	}
+	for _, mapped := range []*mappedBuffer{e.output, e.capture} {
+		if mapped != nil {
+			syscall.Munmap(mapped.data)
+		}
+	}
+	for _, bufType := range []uint32{v4l2BufTypeOutput, v4l2BufTypeCapture} {
+		req := v4l2RequestBuffers{Count: 0, Type: bufType, Memory: v4l2MemoryMMAP}
+		ioctl(e.fd, vidiocReqBufs, unsafe.Pointer(&req))
+	}
+	e.output, e.capture = nil, nil
+	e.width, e.height = 0, 0
+}
+
+// Close releases the device
+func (e *Encoder) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.fd < 0 {
+		return nil
+	}
+	e.teardown()
+	err := syscall.Close(e.fd)
+	e.fd = -1
+	return err
+}
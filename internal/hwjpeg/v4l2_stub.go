@@ -0,0 +1,29 @@
+//go:build !linux || !(arm64 || amd64)
+
+package hwjpeg
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+// Encoder is unavailable off 64-bit Linux
+type Encoder struct{}
+
+// Find reports that V4L2 encoders are only supported on 64-bit Linux
+func Find() (string, error) {
+	return "", fmt.Errorf("V4L2 JPEG encoding is only supported on 64-bit Linux")
+}
+
+// Open reports that V4L2 encoders are only supported on 64-bit Linux
+func Open(device string) (*Encoder, error) {
+	_, err := Find()
+	return nil, err
+}
+
+func (e *Encoder) Device() string { return "" }
+func (e *Encoder) Encode(w io.Writer, img *image.RGBA, quality int) error {
+	return fmt.Errorf("not supported")
+}
+func (e *Encoder) Close() error { return nil }
@@ -0,0 +1,78 @@
+// Package imgenc encodes snapshots of the stream and of windows (thumbnails
+// and screenshots) as JPEG, PNG, or WebP, and picks the format for an HTTP
+// request. WebP cuts the size of the thumbnails the control page polls, but
+// needs cgo with libwebp and is only compiled in with the webp build tag:
+//
+//	go build -tags webp ./cmd/focusstreamer
+//
+// Without it, requests asking for WebP through the Accept header get the
+// endpoint's default format instead.
+package imgenc
+
+import (
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Formats
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+	WebP = "webp"
+)
+
+// WebPSupported reports whether WebP encoding was compiled in
+func WebPSupported() bool {
+	return webpSupported
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	return "image/" + format
+}
+
+// Encode writes img in format. quality (1-100) applies to JPEG and WebP.
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case JPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case PNG:
+		return png.Encode(w, img)
+	case WebP:
+		return encodeWebP(w, img, quality)
+	default:
+		return fmt.Errorf("unknown image format: %s", format)
+	}
+}
+
+// Negotiate picks the format for a request: the format query parameter
+// (jpeg, png, or webp) if set, else WebP if the Accept header lists
+// image/webp and WebP support is compiled in, else def. It returns an error
+// for an unknown format parameter, or webp without WebP support.
+func Negotiate(r *http.Request, def string) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		switch format {
+		case JPEG, PNG:
+			return format, nil
+		case "jpg":
+			return JPEG, nil
+		case WebP:
+			if !webpSupported {
+				return "", fmt.Errorf("built without WebP support (rebuild with -tags webp)")
+			}
+			return WebP, nil
+		default:
+			return "", fmt.Errorf("unknown image format: %s (use jpeg, png, or webp)", format)
+		}
+	}
+
+	if webpSupported && strings.Contains(r.Header.Get("Accept"), ContentType(WebP)) {
+		return WebP, nil
+	}
+	return def, nil
+}
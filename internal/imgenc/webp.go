@@ -0,0 +1,46 @@
+//go:build webp && cgo
+
+package imgenc
+
+// #cgo pkg-config: libwebp
+// #include <stdlib.h>
+// #include <webp/encode.h>
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"unsafe"
+)
+
+const webpSupported = true
+
+// encodeWebP encodes img as lossy WebP through libwebp
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	rgba, ok := img.(*image.RGBA)
+	if !ok || rgba.Rect.Min != (image.Point{}) {
+		rgba = image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	}
+
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if width == 0 || height == 0 {
+		return fmt.Errorf("cannot encode an empty image")
+	}
+
+	var out *C.uint8_t
+	size := C.WebPEncodeRGBA(
+		(*C.uint8_t)(unsafe.Pointer(&rgba.Pix[0])),
+		C.int(width), C.int(height), C.int(rgba.Stride),
+		C.float(quality), &out,
+	)
+	if size == 0 {
+		return fmt.Errorf("WebP encoding failed")
+	}
+	defer C.WebPFree(unsafe.Pointer(out))
+
+	_, err := w.Write(C.GoBytes(unsafe.Pointer(out), C.int(size)))
+	return err
+}
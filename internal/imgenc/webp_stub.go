@@ -0,0 +1,16 @@
+//go:build !webp || !cgo
+
+package imgenc
+
+import (
+	"fmt"
+	"image"
+	"io"
+)
+
+const webpSupported = false
+
+// encodeWebP reports that WebP support wasn't compiled in
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	return fmt.Errorf("built without WebP support (rebuild with -tags webp)")
+}
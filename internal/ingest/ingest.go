@@ -0,0 +1,262 @@
+// Package ingest takes frames pushed by other programs, such as a phone
+// camera app or a custom renderer, and keeps the newest frame of each named
+// source. The stream can show a source in place of the focused window; it
+// is then handled like a window of class WindowClass, so allowlisting,
+// standby, overlays and the outputs all apply.
+package ingest
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+)
+
+// WindowClass is the window class sources are shown and allowlisted as,
+// with the source name as the title
+const WindowClass = "external"
+
+// Frame formats accepted by Decode
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatRGBA = "rgba" // Raw 8-bit RGBA rows, top to bottom, no padding
+)
+
+const (
+	// MaxSources bounds how many sources are kept at once
+	MaxSources = 16
+
+	// MaxFrameSize bounds the width and height of a frame
+	MaxFrameSize = 8192
+
+	// StaleAfter is how long a source stays live without a new frame
+	StaleAfter = 5 * time.Second
+
+	// forgetAfter is how long an idle source is kept before it is removed
+	forgetAfter = 10 * time.Minute
+
+	// firstWindowID is the pseudo window ID of the first source. Higher IDs
+	// are never handed out by X11, KWin or the other backends in practice.
+	firstWindowID uint32 = 0xFFFF0000
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// ValidName reports whether name can name a source: 1-64 letters, digits,
+// dots, dashes and underscores
+func ValidName(name string) bool {
+	return validName.MatchString(name)
+}
+
+// SourceStatus describes a source
+type SourceStatus struct {
+	Name      string    `json:"name"`
+	WindowID  uint32    `json:"window_id"`
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	Frames    uint64    `json:"frames"`
+	LastFrame time.Time `json:"last_frame"`
+	Live      bool      `json:"live"` // A frame arrived within StaleAfter
+}
+
+type source struct {
+	name    string
+	id      uint32
+	img     *image.RGBA
+	updated time.Time
+	frames  uint64
+}
+
+// Sources holds the newest frame of each external source
+type Sources struct {
+	mu      sync.Mutex
+	sources map[string]*source
+	nextID  uint32
+}
+
+// NewSources creates an empty source set
+func NewSources() *Sources {
+	return &Sources{sources: make(map[string]*source), nextID: firstWindowID}
+}
+
+// Push makes img the newest frame of the named source, adding the source if
+// needed. Sources takes ownership of img, even on error.
+func (s *Sources) Push(name string, img *image.RGBA) error {
+	if !ValidName(name) {
+		framepool.Put(img)
+		return fmt.Errorf("invalid source name %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+
+	src, ok := s.sources[name]
+	if !ok {
+		if len(s.sources) >= MaxSources {
+			framepool.Put(img)
+			return fmt.Errorf("too many external sources (at most %d)", MaxSources)
+		}
+		src = &source{name: name, id: s.nextID}
+		s.nextID++
+		s.sources[name] = src
+	}
+	framepool.Put(src.img)
+	src.img = img
+	src.updated = now
+	src.frames++
+	return nil
+}
+
+// Remove forgets a source, reporting whether it existed
+func (s *Sources) Remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.sources[name]
+	if !ok {
+		return false
+	}
+	framepool.Put(src.img)
+	delete(s.sources, name)
+	return true
+}
+
+// Window returns the pseudo window of a live source, or nil if the source
+// is unknown or has gone quiet
+func (s *Sources) Window(name string) *config.WindowInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.sources[name]
+	if !ok || time.Since(src.updated) > StaleAfter {
+		return nil
+	}
+	bounds := src.img.Bounds()
+	return &config.WindowInfo{
+		ID:       src.id,
+		Title:    src.name,
+		Class:    WindowClass,
+		Geometry: config.Geometry{Width: bounds.Dx(), Height: bounds.Dy()},
+		Desktop:  -1,
+	}
+}
+
+// Status describes every source, by name
+func (s *Sources) Status() []SourceStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.pruneLocked(now)
+
+	status := make([]SourceStatus, 0, len(s.sources))
+	for _, src := range s.sources {
+		bounds := src.img.Bounds()
+		status = append(status, SourceStatus{
+			Name:      src.name,
+			WindowID:  src.id,
+			Width:     bounds.Dx(),
+			Height:    bounds.Dy(),
+			Frames:    src.frames,
+			LastFrame: src.updated,
+			Live:      now.Sub(src.updated) <= StaleAfter,
+		})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Name < status[j].Name })
+	return status
+}
+
+// CaptureWindow returns a copy of the newest frame of a source's pseudo
+// window. The caller releases it with framepool.Put.
+func (s *Sources) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, src := range s.sources {
+		if src.id != window.ID {
+			continue
+		}
+		if time.Since(src.updated) > StaleAfter {
+			return nil, fmt.Errorf("external source %s sent no frame for %s", src.name, StaleAfter)
+		}
+		return framepool.Clone(src.img), nil
+	}
+	return nil, fmt.Errorf("no external source with window ID %d", window.ID)
+}
+
+// pruneLocked removes sources idle for longer than forgetAfter
+func (s *Sources) pruneLocked(now time.Time) {
+	for name, src := range s.sources {
+		if now.Sub(src.updated) > forgetAfter {
+			framepool.Put(src.img)
+			delete(s.sources, name)
+		}
+	}
+}
+
+// IsExternal reports whether a window is the pseudo window of a source
+func IsExternal(window *config.WindowInfo) bool {
+	return window != nil && window.Class == WindowClass && window.ID >= firstWindowID
+}
+
+// Decode decodes a frame in one of the Format constants into a pooled
+// image. Raw RGBA frames need their width and height; the others carry
+// their own.
+func Decode(format string, data []byte, width, height int) (*image.RGBA, error) {
+	switch format {
+	case FormatRGBA:
+		if width <= 0 || height <= 0 || width > MaxFrameSize || height > MaxFrameSize {
+			return nil, fmt.Errorf("frame size %dx%d out of range (1-%d)", width, height, MaxFrameSize)
+		}
+		if len(data) != width*height*4 {
+			return nil, fmt.Errorf("raw RGBA frame is %d bytes, expected %d for %dx%d", len(data), width*height*4, width, height)
+		}
+		img := framepool.Get(width, height)
+		copy(img.Pix, data)
+		return img, nil
+
+	case FormatJPEG, FormatPNG:
+		var (
+			cfg image.Config
+			err error
+		)
+		if format == FormatJPEG {
+			cfg, err = jpeg.DecodeConfig(bytes.NewReader(data))
+		} else {
+			cfg, err = png.DecodeConfig(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s frame: %w", format, err)
+		}
+		if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxFrameSize || cfg.Height > MaxFrameSize {
+			return nil, fmt.Errorf("frame size %dx%d out of range (1-%d)", cfg.Width, cfg.Height, MaxFrameSize)
+		}
+
+		var decoded image.Image
+		if format == FormatJPEG {
+			decoded, err = jpeg.Decode(bytes.NewReader(data))
+		} else {
+			decoded, err = png.Decode(bytes.NewReader(data))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s frame: %w", format, err)
+		}
+		img := framepool.Get(cfg.Width, cfg.Height)
+		draw.Draw(img, img.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+		return img, nil
+	}
+	return nil, fmt.Errorf("unsupported frame format %q (use jpeg, png or rgba)", format)
+}
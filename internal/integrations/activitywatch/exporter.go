@@ -0,0 +1,269 @@
+// Package activitywatch forwards focus changes and stream segments to an
+// ActivityWatch server (https://activitywatch.net) through its REST API, so
+// they show up next to the rest of an existing time-tracking setup. It
+// sends heartbeats, which the server merges into events:
+//
+//	focusstreamer-window_<hostname>  {"app": "firefox", "title": "..."}
+//	focusstreamer-stream_<hostname>  {"status": "live", "app": "firefox", "title": "..."}
+//	                                 {"status": "standby", "reason": "not_allowlisted"}
+//
+// Heartbeats sent while the server is down are lost.
+package activitywatch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+)
+
+const (
+	clientName = "focusstreamer"
+
+	// Bucket event types; the window bucket uses the one aw-watcher-window
+	// does, so ActivityWatch's views treat it as window activity
+	eventTypeWindow = "currentwindow"
+	eventTypeStream = "app.focusstreamer.stream"
+
+	// heartbeatInterval is how often the focused window and the stream are
+	// reported while nothing changes
+	heartbeatInterval = 10 * time.Second
+
+	// pulsetime is how far apart heartbeats with the same data may be and
+	// still be merged into one event
+	pulsetime = heartbeatInterval + 5*time.Second
+
+	requestTimeout = 5 * time.Second
+)
+
+// windowData is a window bucket event
+type windowData struct {
+	App   string `json:"app"`
+	Title string `json:"title"`
+}
+
+// streamData is a stream bucket event
+type streamData struct {
+	Status string `json:"status"` // live or standby
+	App    string `json:"app,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Reason string `json:"reason,omitempty"` // Why standby was shown
+}
+
+// Exporter sends heartbeats for the focused window and the stream
+type Exporter struct {
+	windowMgr    *window.Manager
+	client       *http.Client
+	baseURL      string
+	hostname     string
+	windowBucket string
+	streamBucket string
+
+	// Only Run's goroutine touches these
+	bucketsReady bool
+	failing      bool
+	lastWindow   *windowData
+	streamSent   time.Time // End of the last stream heartbeat
+	streamStart  time.Time // Start of the last segment sent
+}
+
+// NewExporter creates an exporter for the ActivityWatch server and buckets
+// in cfg; empty bucket names default to focusstreamer-window_<hostname>
+// and focusstreamer-stream_<hostname>
+func NewExporter(cfg config.ActivityWatchConfig, windowMgr *window.Manager) *Exporter {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	e := &Exporter{
+		windowMgr:    windowMgr,
+		client:       &http.Client{Timeout: requestTimeout},
+		baseURL:      strings.TrimSuffix(cfg.URL, "/"),
+		hostname:     hostname,
+		windowBucket: cfg.WindowBucket,
+		streamBucket: cfg.StreamBucket,
+	}
+	if e.windowBucket == "" {
+		e.windowBucket = "focusstreamer-window_" + hostname
+	}
+	if e.streamBucket == "" {
+		e.streamBucket = "focusstreamer-stream_" + hostname
+	}
+	return e
+}
+
+// Run sends heartbeats until ctx is done
+func (e *Exporter) Run(ctx context.Context) {
+	logger.WithComponent("activitywatch").Info().
+		Str("url", e.baseURL).
+		Str("window_bucket", e.windowBucket).
+		Str("stream_bucket", e.streamBucket).
+		Msg("Exporting to ActivityWatch")
+
+	updates := e.windowMgr.Subscribe()
+	defer e.windowMgr.Unsubscribe(updates)
+
+	tick := time.NewTicker(heartbeatInterval)
+	defer tick.Stop()
+
+	e.report(ctx, e.windowMgr.GetCurrentWindow())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case window, ok := <-updates:
+			if !ok {
+				return
+			}
+			e.report(ctx, window)
+		case <-tick.C:
+			// Updates are dropped when the channel is full; the current
+			// window keeps the bucket right
+			e.report(ctx, e.windowMgr.GetCurrentWindow())
+		}
+	}
+}
+
+// report sends heartbeats for the focused window and the stream segments
+// since the last report
+func (e *Exporter) report(ctx context.Context, focused *config.WindowInfo) {
+	var err error
+	if !e.bucketsReady {
+		err = e.createBuckets(ctx)
+		e.bucketsReady = err == nil
+	}
+	if err == nil {
+		err = e.reportWindow(ctx, focused, time.Now())
+	}
+	if err == nil {
+		err = e.reportStream(ctx)
+	}
+	if ctx.Err() != nil {
+		return // Shutting down
+	}
+	e.setFailing(err)
+}
+
+// reportWindow extends the last window's event up to now and then starts
+// or extends the focused one's
+func (e *Exporter) reportWindow(ctx context.Context, focused *config.WindowInfo, now time.Time) error {
+	var data *windowData
+	if focused != nil && focused.Class != "" {
+		data = &windowData{App: focused.Class, Title: focused.Title}
+	}
+	if e.lastWindow != nil && (data == nil || *data != *e.lastWindow) {
+		if err := e.heartbeat(ctx, e.windowBucket, now, e.lastWindow); err != nil {
+			return err
+		}
+	}
+	e.lastWindow = data
+	if data == nil {
+		return nil
+	}
+	return e.heartbeat(ctx, e.windowBucket, now, data)
+}
+
+// reportStream sends the start and latest end of each stream segment that
+// changed since the last report
+func (e *Exporter) reportStream(ctx context.Context) error {
+	for _, segment := range e.windowMgr.TimelineSince(e.streamSent) {
+		data := streamData{Status: "live", App: segment.Class, Title: segment.Title}
+		if segment.Standby {
+			data = streamData{Status: "standby", Reason: string(segment.Reason)}
+		}
+		if segment.Start.After(e.streamStart) {
+			if err := e.heartbeat(ctx, e.streamBucket, segment.Start, data); err != nil {
+				return err
+			}
+			e.streamStart = segment.Start
+		}
+		if segment.End.After(segment.Start) {
+			if err := e.heartbeat(ctx, e.streamBucket, segment.End, data); err != nil {
+				return err
+			}
+		}
+		e.streamSent = segment.End
+	}
+	return nil
+}
+
+// createBuckets creates both buckets; the server answers 304 Not Modified
+// for one that exists
+func (e *Exporter) createBuckets(ctx context.Context) error {
+	for bucket, eventType := range map[string]string{e.windowBucket: eventTypeWindow, e.streamBucket: eventTypeStream} {
+		err := e.post(ctx, "/api/0/buckets/"+url.PathEscape(bucket), map[string]string{
+			"client":   clientName,
+			"type":     eventType,
+			"hostname": e.hostname,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create bucket %s: %w", bucket, err)
+		}
+	}
+	return nil
+}
+
+// heartbeat sends data as of timestamp, merged into the bucket's last event
+// when the data matches and it is within pulsetime
+func (e *Exporter) heartbeat(ctx context.Context, bucket string, timestamp time.Time, data interface{}) error {
+	path := fmt.Sprintf("/api/0/buckets/%s/heartbeat?pulsetime=%g", url.PathEscape(bucket), pulsetime.Seconds())
+	err := e.post(ctx, path, map[string]interface{}{
+		"timestamp": timestamp.UTC().Format(time.RFC3339Nano),
+		"duration":  0,
+		"data":      data,
+	})
+	if err != nil {
+		// The bucket may have been deleted; create it again next time
+		e.bucketsReady = false
+		return fmt.Errorf("failed to send heartbeat to %s: %w", bucket, err)
+	}
+	return nil
+}
+
+func (e *Exporter) post(ctx context.Context, path string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("server answered %s", resp.Status)
+	}
+	return nil
+}
+
+// setFailing logs when exporting starts failing and when it recovers,
+// rather than every failed heartbeat
+func (e *Exporter) setFailing(err error) {
+	log := logger.WithComponent("activitywatch")
+	switch {
+	case err != nil && !e.failing:
+		log.Warn().Err(err).Str("url", e.baseURL).Msg("ActivityWatch export failing, retrying")
+	case err != nil:
+		log.Debug().Err(err).Msg("ActivityWatch export still failing")
+	case e.failing:
+		log.Info().Str("url", e.baseURL).Msg("ActivityWatch export recovered")
+	}
+	e.failing = err != nil
+}
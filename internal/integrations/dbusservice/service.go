@@ -0,0 +1,306 @@
+// Package dbusservice exports FocusStreamer controls on the session bus as
+// org.focusstreamer, so desktop shortcuts, KRunner, and scripts can drive the
+// streamer without HTTP. Try it with:
+//
+//	busctl --user call org.focusstreamer /org/focusstreamer org.focusstreamer ToggleStandby
+//	busctl --user call org.focusstreamer /org/focusstreamer org.focusstreamer Panic
+//	busctl --user call org.focusstreamer /org/focusstreamer org.focusstreamer GetState
+//	dbus-monitor "type='signal',interface='org.focusstreamer'"
+//
+// The Event signal carries each typed event as a serialized
+// focusstreamer.events.v1.Event (pkg/events/events.proto).
+package dbusservice
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/eventhub"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// BusName is the well-known name owned on the session bus
+	BusName = "org.focusstreamer"
+
+	objectPath = "/org/focusstreamer"
+	iface      = "org.focusstreamer"
+
+	errNotFound = iface + ".Error.NotFound"
+)
+
+const introspectXML = `
+<node>
+	<interface name="` + iface + `">
+		<method name="ToggleStandby">
+			<arg name="standby" type="b" direction="out"/>
+		</method>
+		<method name="Panic"/>
+		<method name="Rearm"/>
+		<method name="SetProfile">
+			<arg name="profile" type="s" direction="in"/>
+		</method>
+		<method name="GetState">
+			<arg name="state" type="a{sv}" direction="out"/>
+		</method>
+		<signal name="StateChanged">
+			<arg name="state" type="a{sv}"/>
+		</signal>
+		<signal name="SharedWindowChanged">
+			<arg name="window_id" type="u"/>
+			<arg name="class" type="s"/>
+			<arg name="title" type="s"/>
+		</signal>
+		<signal name="Event">
+			<arg name="event" type="ay"/>
+		</signal>
+	</interface>` + introspect.IntrospectDataString + `</node>`
+
+// State is the streamer state reported by GetState and StateChanged
+type State struct {
+	Standby           bool
+	Panicked          bool
+	OnAir             bool
+	ProfileID         string
+	ProfileName       string
+	SharedWindowID    uint32 // 0 while the placeholder is shown
+	SharedWindowClass string
+}
+
+// Service is the org.focusstreamer object
+type Service struct {
+	conn      *dbus.Conn
+	windowMgr *window.Manager
+	configMgr *config.Manager
+
+	// Called after SetProfile, like the API's profile change callback
+	onProfileChange func(profileID string)
+
+	mu        sync.Mutex
+	lastState State
+
+	// Hub and subscription ForwardEvents emits Event signals from
+	hub    *eventhub.Hub
+	events chan *events.Event
+}
+
+// NewService connects to the session bus, exports the service, and claims
+// org.focusstreamer. It fails if there is no session bus or another
+// instance owns the name.
+func NewService(windowMgr *window.Manager, configMgr *config.Manager) (*Service, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+
+	s := &Service{
+		conn:      conn,
+		windowMgr: windowMgr,
+		configMgr: configMgr,
+	}
+	s.lastState = s.state()
+
+	if err := conn.Export(s, objectPath, iface); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to export %s: %w", iface, err)
+	}
+	if err := conn.Export(introspect.Introspectable(introspectXML), objectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to export introspection: %w", err)
+	}
+
+	reply, err := conn.RequestName(BusName, dbus.NameFlagDoNotQueue)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to request %s: %w", BusName, err)
+	}
+	if reply != dbus.RequestNameReplyPrimaryOwner {
+		conn.Close()
+		return nil, fmt.Errorf("%s is already owned by another process", BusName)
+	}
+
+	windowMgr.SetOnStateChangeCallback(s.publishState)
+	windowMgr.SetOnSharedWindowCallback(s.publishSharedWindow)
+
+	logger.WithComponent("dbus").Info().Str("name", BusName).Msg("D-Bus service exported")
+	return s, nil
+}
+
+// Conn returns the bus connection, for integrations exporting more objects
+// under the same name
+func (s *Service) Conn() *dbus.Conn {
+	return s.conn
+}
+
+// SetOnProfileChange sets a callback invoked after SetProfile switches profiles
+func (s *Service) SetOnProfileChange(callback func(profileID string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onProfileChange = callback
+}
+
+// ForwardEvents emits each event published to hub as an Event signal,
+// until Close
+func (s *Service) ForwardEvents(hub *eventhub.Hub) {
+	ch := hub.Subscribe()
+	s.mu.Lock()
+	s.hub, s.events = hub, ch
+	s.mu.Unlock()
+
+	go func() {
+		for event := range ch {
+			s.publishEvent(event)
+		}
+	}()
+}
+
+// Close releases the bus name and connection
+func (s *Service) Close() {
+	s.windowMgr.SetOnStateChangeCallback(nil)
+	s.windowMgr.SetOnSharedWindowCallback(nil)
+	s.mu.Lock()
+	if s.hub != nil {
+		s.hub.Unsubscribe(s.events)
+	}
+	s.mu.Unlock()
+	s.conn.Close()
+}
+
+// ToggleStandby toggles standby and returns the new state
+func (s *Service) ToggleStandby() (bool, *dbus.Error) {
+	return s.windowMgr.ToggleForceStandby(), nil
+}
+
+// Panic blanks the stream until Rearm
+func (s *Service) Panic() *dbus.Error {
+	s.windowMgr.Panic()
+	return nil
+}
+
+// Rearm resumes streaming after Panic
+func (s *Service) Rearm() *dbus.Error {
+	s.windowMgr.Rearm()
+	return nil
+}
+
+// SetProfile activates a profile by ID or (case-insensitive) name
+func (s *Service) SetProfile(profile string) *dbus.Error {
+	if _, err := s.SwitchProfile(profile); err != nil {
+		return dbus.NewError(errNotFound, []interface{}{err.Error()})
+	}
+	return nil
+}
+
+// GetState returns the current state as a dictionary
+func (s *Service) GetState() (map[string]dbus.Variant, *dbus.Error) {
+	return s.state().variants(), nil
+}
+
+// SwitchProfile activates a profile by ID or (case-insensitive) name and
+// returns it
+func (s *Service) SwitchProfile(profile string) (*config.Profile, error) {
+	var match *config.Profile
+	for _, p := range s.configMgr.ListProfiles() {
+		if p.ID == profile || strings.EqualFold(p.Name, profile) {
+			match = &p
+			break
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("profile not found: %s", profile)
+	}
+
+	if err := s.configMgr.SetActiveProfile(match.ID); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	callback := s.onProfileChange
+	s.mu.Unlock()
+	if callback != nil {
+		callback(match.ID)
+	}
+	return match, nil
+}
+
+// state reads the current state
+func (s *Service) state() State {
+	state := State{
+		Standby:   s.windowMgr.GetForceStandby(),
+		Panicked:  s.windowMgr.IsPanicked(),
+		OnAir:     s.windowMgr.IsOnAir(),
+		ProfileID: s.configMgr.GetActiveProfileID(),
+	}
+	if profile := s.configMgr.GetActiveProfile(); profile != nil {
+		state.ProfileName = profile.Name
+	}
+	if shared := s.windowMgr.GetSharedWindow(); shared != nil {
+		state.SharedWindowID = shared.ID
+		state.SharedWindowClass = shared.Class
+	}
+	return state
+}
+
+// variants converts the state to an a{sv} dictionary
+func (st State) variants() map[string]dbus.Variant {
+	return map[string]dbus.Variant{
+		"standby":             dbus.MakeVariant(st.Standby),
+		"panicked":            dbus.MakeVariant(st.Panicked),
+		"on_air":              dbus.MakeVariant(st.OnAir),
+		"profile_id":          dbus.MakeVariant(st.ProfileID),
+		"profile_name":        dbus.MakeVariant(st.ProfileName),
+		"shared_window_id":    dbus.MakeVariant(st.SharedWindowID),
+		"shared_window_class": dbus.MakeVariant(st.SharedWindowClass),
+	}
+}
+
+// publishState emits StateChanged if the state differs from the last one sent
+func (s *Service) publishState() {
+	state := s.state()
+
+	s.mu.Lock()
+	changed := state != s.lastState
+	s.lastState = state
+	s.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if err := s.conn.Emit(objectPath, iface+".StateChanged", state.variants()); err != nil {
+		logger.WithComponent("dbus").Debug().Err(err).Msg("Failed to emit StateChanged")
+	}
+}
+
+// publishSharedWindow emits SharedWindowChanged, with an empty window while
+// the placeholder is shown, followed by the updated state
+func (s *Service) publishSharedWindow(win *config.WindowInfo) {
+	var id uint32
+	var class, title string
+	if win != nil {
+		id, class, title = win.ID, win.Class, win.Title
+	}
+
+	if err := s.conn.Emit(objectPath, iface+".SharedWindowChanged", id, class, title); err != nil {
+		logger.WithComponent("dbus").Debug().Err(err).Msg("Failed to emit SharedWindowChanged")
+	}
+	s.publishState()
+}
+
+// publishEvent emits Event with the serialized event
+func (s *Service) publishEvent(event *events.Event) {
+	data, err := proto.Marshal(event)
+	if err != nil {
+		logger.WithComponent("dbus").Debug().Err(err).Msg("Failed to encode event")
+		return
+	}
+	if err := s.conn.Emit(objectPath, iface+".Event", data); err != nil {
+		logger.WithComponent("dbus").Debug().Err(err).Msg("Failed to emit Event")
+	}
+}
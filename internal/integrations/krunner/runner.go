@@ -0,0 +1,337 @@
+// Package krunner implements the KDE KRunner D-Bus runner interface
+// (org.kde.krunner1), so FocusStreamer can be controlled from KRunner:
+//
+//	fs standby          toggle standby
+//	fs allow firefox    allowlist a running application
+//	fs profile work     switch profile
+//
+// The runner is exported on the org.focusstreamer connection at /krunner;
+// Plasma finds it through the desktop file from DesktopFile.
+package krunner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/approval"
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/integrations/dbusservice"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/window"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	// ObjectPath is where the runner is exported
+	ObjectPath = "/krunner"
+
+	iface = "org.kde.krunner1"
+
+	// Queries must start with this keyword
+	keyword = "fs"
+
+	icon = "video-display"
+
+	errFailed = "org.focusstreamer.Error.Failed"
+)
+
+// KRunner match types (KRunner::QueryMatch::Type)
+const (
+	matchPossible = 30
+	matchExact    = 100
+)
+
+const introspectXML = `
+<node>
+	<interface name="` + iface + `">
+		<method name="Actions">
+			<arg name="matches" type="a(sss)" direction="out"/>
+		</method>
+		<method name="Match">
+			<arg name="query" type="s" direction="in"/>
+			<arg name="matches" type="a(sssida{sv})" direction="out"/>
+		</method>
+		<method name="Run">
+			<arg name="matchId" type="s" direction="in"/>
+			<arg name="actionId" type="s" direction="in"/>
+		</method>
+	</interface>` + introspect.IntrospectDataString + `</node>`
+
+// Match is a KRunner result: (id, text, icon, type, relevance, properties)
+type Match struct {
+	ID         string
+	Text       string
+	Icon       string
+	Type       int32
+	Relevance  float64
+	Properties map[string]dbus.Variant
+}
+
+// Action is a KRunner match action: (id, text, icon)
+type Action struct {
+	ID   string
+	Text string
+	Icon string
+}
+
+// Runner answers KRunner queries
+type Runner struct {
+	service   *dbusservice.Service
+	windowMgr *window.Manager
+	configMgr *config.Manager
+	approvals *approval.Queue // Holds "allow" while allowlist_approval is on
+}
+
+// Export registers the runner on the D-Bus service's connection
+func Export(service *dbusservice.Service, windowMgr *window.Manager, configMgr *config.Manager, approvals *approval.Queue) (*Runner, error) {
+	r := &Runner{
+		service:   service,
+		windowMgr: windowMgr,
+		configMgr: configMgr,
+		approvals: approvals,
+	}
+
+	conn := service.Conn()
+	if err := conn.Export(r, ObjectPath, iface); err != nil {
+		return nil, fmt.Errorf("failed to export KRunner runner: %w", err)
+	}
+	if err := conn.Export(introspect.Introspectable(introspectXML), ObjectPath, "org.freedesktop.DBus.Introspectable"); err != nil {
+		return nil, fmt.Errorf("failed to export KRunner introspection: %w", err)
+	}
+
+	logger.WithComponent("krunner").Info().Str("path", ObjectPath).Msg("KRunner runner exported")
+	return r, nil
+}
+
+// Actions returns the actions offered on every match (none)
+func (r *Runner) Actions() ([]Action, *dbus.Error) {
+	return []Action{}, nil
+}
+
+// Match returns the results for a KRunner query
+func (r *Runner) Match(query string) ([]Match, *dbus.Error) {
+	words := strings.Fields(query)
+	if len(words) == 0 || !strings.EqualFold(words[0], keyword) {
+		return []Match{}, nil
+	}
+
+	command := ""
+	if len(words) > 1 {
+		command = strings.ToLower(words[1])
+	}
+	arg := ""
+	if len(words) > 2 {
+		arg = strings.Join(words[2:], " ")
+	}
+
+	var matches []Match
+	if strings.HasPrefix("standby", command) {
+		matches = append(matches, r.standbyMatch(command == "standby"))
+	}
+	if command != "" && strings.HasPrefix("allow", command) {
+		matches = append(matches, r.allowMatches(arg)...)
+	}
+	if command != "" && strings.HasPrefix("profile", command) {
+		matches = append(matches, r.profileMatches(arg)...)
+	}
+
+	if matches == nil {
+		matches = []Match{}
+	}
+	return matches, nil
+}
+
+// Run executes a match returned by Match
+func (r *Runner) Run(matchID, actionID string) *dbus.Error {
+	log := logger.WithComponent("krunner")
+	action, arg, _ := strings.Cut(matchID, ":")
+
+	switch action {
+	case "standby":
+		standby := r.windowMgr.ToggleForceStandby()
+		log.Info().Bool("standby", standby).Msg("Standby toggled from KRunner")
+
+	case "allow":
+		if r.approvals != nil && r.approvals.Enabled() {
+			entry, err := r.approvals.Submit(approval.Entry{Kind: approval.KindApp, Value: arg, Source: "krunner"})
+			if err != nil {
+				return dbus.NewError(errFailed, []interface{}{err.Error()})
+			}
+			log.Info().Str("app_class", arg).Str("id", entry.ID).Msg("Allowlisting from KRunner waits for approval")
+			break
+		}
+		if err := r.configMgr.AddAllowlistedApp(arg); err != nil {
+			return dbus.NewError(errFailed, []interface{}{err.Error()})
+		}
+		log.Info().Str("app_class", arg).Msg("Application allowlisted from KRunner")
+
+	case "profile":
+		profile, err := r.service.SwitchProfile(arg)
+		if err != nil {
+			return dbus.NewError(errFailed, []interface{}{err.Error()})
+		}
+		log.Info().Str("profile_id", profile.ID).Msg("Profile switched from KRunner")
+
+	default:
+		return dbus.NewError(errFailed, []interface{}{"unknown match: " + matchID})
+	}
+	return nil
+}
+
+// standbyMatch offers toggling standby, describing what it will do
+func (r *Runner) standbyMatch(exact bool) Match {
+	text := "FocusStreamer: Enter standby"
+	if r.windowMgr.GetForceStandby() {
+		text = "FocusStreamer: Leave standby"
+	}
+
+	matchType := int32(matchPossible)
+	if exact {
+		matchType = matchExact
+	}
+	return Match{
+		ID:        "standby",
+		Text:      text,
+		Icon:      icon,
+		Type:      matchType,
+		Relevance: 1,
+		Properties: map[string]dbus.Variant{
+			"subtext": dbus.MakeVariant("Show the placeholder instead of any window"),
+		},
+	}
+}
+
+// allowMatches offers allowlisting running applications whose class or name
+// contains the query, best matches first
+func (r *Runner) allowMatches(query string) []Match {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	apps, err := r.windowMgr.GetApplications()
+	if err != nil {
+		logger.WithComponent("krunner").Debug().Err(err).Msg("Failed to list applications")
+	}
+
+	var matches []Match
+	seen := make(map[string]bool)
+	for _, app := range apps {
+		class := app.WindowClass
+		if class == "" || seen[class] || r.configMgr.IsAllowlisted(class) {
+			continue
+		}
+		relevance := matchRelevance(query, strings.ToLower(class), strings.ToLower(app.Name))
+		if relevance == 0 {
+			continue
+		}
+		seen[class] = true
+
+		matchType := int32(matchPossible)
+		if relevance == 1 {
+			matchType = matchExact
+		}
+		matches = append(matches, Match{
+			ID:        "allow:" + class,
+			Text:      "FocusStreamer: Allow " + class,
+			Icon:      icon,
+			Type:      matchType,
+			Relevance: relevance,
+			Properties: map[string]dbus.Variant{
+				"subtext": dbus.MakeVariant(app.Name),
+			},
+		})
+	}
+
+	// A class that isn't running yet can still be allowlisted by name
+	if query != "" && !seen[query] && !r.configMgr.IsAllowlisted(query) {
+		matches = append(matches, Match{
+			ID:        "allow:" + query,
+			Text:      "FocusStreamer: Allow " + query,
+			Icon:      icon,
+			Type:      matchPossible,
+			Relevance: 0.1,
+			Properties: map[string]dbus.Variant{
+				"subtext": dbus.MakeVariant("Window class (not running)"),
+			},
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Relevance > matches[j].Relevance
+	})
+	return matches
+}
+
+// profileMatches offers switching to profiles whose name contains the query
+func (r *Runner) profileMatches(query string) []Match {
+	query = strings.ToLower(strings.TrimSpace(query))
+	activeID := r.configMgr.GetActiveProfileID()
+
+	var matches []Match
+	for _, profile := range r.configMgr.ListProfiles() {
+		if profile.ID == activeID {
+			continue
+		}
+		relevance := matchRelevance(query, strings.ToLower(profile.ID), strings.ToLower(profile.Name))
+		if relevance == 0 {
+			continue
+		}
+
+		matchType := int32(matchPossible)
+		if relevance == 1 {
+			matchType = matchExact
+		}
+		matches = append(matches, Match{
+			ID:        "profile:" + profile.ID,
+			Text:      "FocusStreamer: Switch to profile " + profile.Name,
+			Icon:      icon,
+			Type:      matchType,
+			Relevance: relevance,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Relevance > matches[j].Relevance
+	})
+	return matches
+}
+
+// matchRelevance scores how well query matches any of the candidates:
+// 1 for an exact match, 0.8 for a prefix, 0.5 for a substring, 0.3 for
+// everything when the query is empty, and 0 for no match
+func matchRelevance(query string, candidates ...string) float64 {
+	if query == "" {
+		return 0.3
+	}
+
+	best := 0.0
+	for _, candidate := range candidates {
+		switch {
+		case candidate == query:
+			return 1
+		case strings.HasPrefix(candidate, query):
+			best = max(best, 0.8)
+		case strings.Contains(candidate, query):
+			best = max(best, 0.5)
+		}
+	}
+	return best
+}
+
+// DesktopFile returns the Plasma runner registration, installed to
+// ~/.local/share/krunner/dbusplugins/
+func DesktopFile() string {
+	return `[Desktop Entry]
+Name=FocusStreamer
+Comment=Control FocusStreamer: fs standby, fs allow <app>, fs profile <name>
+Icon=` + icon + `
+X-KDE-ServiceTypes=Plasma/Runner
+Type=Service
+X-KDE-PluginInfo-Name=focusstreamer
+X-KDE-PluginInfo-EnabledByDefault=true
+X-Plasma-API=DBus
+X-Plasma-API-Minimum-Version=2.0
+X-Plasma-DBusRunner-Service=` + dbusservice.BusName + `
+X-Plasma-DBusRunner-Path=` + ObjectPath + `
+`
+}
@@ -39,6 +39,12 @@ const (
 
 // Init initializes the global logger with the specified level and output
 func Init(level string, pretty bool) {
+	InitWithOutput(level, pretty, os.Stdout)
+}
+
+// InitWithOutput initializes the global logger writing to out instead of
+// stdout (e.g. for the native messaging host, where stdout is the protocol)
+func InitWithOutput(level string, pretty bool, out io.Writer) {
 	// Parse log level
 	var zlLevel zerolog.Level
 	switch strings.ToLower(level) {
@@ -58,10 +64,10 @@ func Init(level string, pretty bool) {
 	zerolog.SetGlobalLevel(zlLevel)
 
 	// Configure output
-	var output io.Writer = os.Stdout
+	var output io.Writer = out
 	if pretty {
 		output = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
+			Out:        out,
 			TimeFormat: time.RFC3339,
 			NoColor:    false,
 		}
@@ -0,0 +1,236 @@
+// Package onair announces when the stream goes live (viewers connected and
+// not in standby) so users can drive an on-air lamp or similar.
+package onair
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus object emitting the StateChanged(b on_air) signal. Listen with:
+//
+//	dbus-monitor "type='signal',interface='org.focusstreamer.OnAir'"
+const (
+	dbusPath      = "/org/focusstreamer/OnAir"
+	dbusInterface = "org.focusstreamer.OnAir"
+	dbusSignal    = dbusInterface + ".StateChanged"
+
+	defaultDebounce = time.Second
+	hookTimeout     = 10 * time.Second
+)
+
+// Indicator debounces on-air transitions and announces them over D-Bus and
+// the configured command and HTTP hooks
+type Indicator struct {
+	cfg      config.OnAirConfig
+	debounce time.Duration
+	conn     *dbus.Conn
+
+	mu        sync.Mutex
+	pending   bool // State waiting out the debounce
+	announced bool // Last announced state
+	timer     *time.Timer
+	closed    bool
+
+	// Hooks run one at a time in order on this goroutine
+	hooks chan bool
+	done  chan struct{}
+}
+
+// NewIndicator creates an indicator. D-Bus is optional; without a session
+// bus only the hooks run.
+func NewIndicator(cfg config.OnAirConfig) *Indicator {
+	log := logger.WithComponent("onair")
+
+	ind := &Indicator{
+		cfg:      cfg,
+		debounce: time.Duration(cfg.DebounceMs) * time.Millisecond,
+		hooks:    make(chan bool, 16),
+		done:     make(chan struct{}),
+	}
+	if cfg.DebounceMs <= 0 {
+		ind.debounce = defaultDebounce
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		log.Warn().Err(err).Msg("Session bus unavailable, on-air D-Bus signal disabled")
+	} else {
+		ind.conn = conn
+		if err := conn.Export(ind, dbusPath, dbusInterface); err != nil {
+			log.Warn().Err(err).Msg("Failed to export on-air D-Bus object")
+		}
+	}
+
+	go ind.runHooks()
+	return ind
+}
+
+// IsOnAir is exported on D-Bus so clients can query the current state
+func (ind *Indicator) IsOnAir() (bool, *dbus.Error) {
+	ind.mu.Lock()
+	defer ind.mu.Unlock()
+	return ind.announced, nil
+}
+
+// Set reports the current on-air state. It is announced once it has been
+// stable for the debounce period, so brief focus switches don't flicker the lamp.
+func (ind *Indicator) Set(onAir bool) {
+	ind.mu.Lock()
+	defer ind.mu.Unlock()
+
+	if ind.closed {
+		return
+	}
+	ind.pending = onAir
+	if ind.timer != nil {
+		ind.timer.Stop()
+		ind.timer = nil
+	}
+	if onAir == ind.announced {
+		return
+	}
+	ind.timer = time.AfterFunc(ind.debounce, ind.announce)
+}
+
+// Close announces off-air (if on air) and releases the bus connection
+func (ind *Indicator) Close() {
+	ind.mu.Lock()
+	if ind.timer != nil {
+		ind.timer.Stop()
+		ind.timer = nil
+	}
+	wasOnAir := ind.announced
+	ind.announced = false
+	ind.closed = true
+	if wasOnAir {
+		ind.queueHooks(false)
+	}
+	close(ind.hooks)
+	ind.mu.Unlock()
+
+	if wasOnAir {
+		ind.emit(false)
+	}
+	<-ind.done
+
+	if ind.conn != nil {
+		ind.conn.Close()
+	}
+}
+
+// announce publishes the pending state after the debounce
+func (ind *Indicator) announce() {
+	ind.mu.Lock()
+	onAir := ind.pending
+	if ind.closed || onAir == ind.announced {
+		ind.mu.Unlock()
+		return
+	}
+	ind.announced = onAir
+	ind.timer = nil
+	ind.queueHooks(onAir)
+	ind.mu.Unlock()
+
+	logger.WithComponent("onair").Info().Bool("on_air", onAir).Msg("On-air state changed")
+	ind.emit(onAir)
+}
+
+// queueHooks queues a transition for the hook goroutine. Called with mu held.
+func (ind *Indicator) queueHooks(onAir bool) {
+	select {
+	case ind.hooks <- onAir:
+	default:
+		logger.WithComponent("onair").Warn().Msg("On-air hook queue full, dropping transition")
+	}
+}
+
+// emit sends the D-Bus signal
+func (ind *Indicator) emit(onAir bool) {
+	if ind.conn == nil {
+		return
+	}
+	if err := ind.conn.Emit(dbusPath, dbusSignal, onAir); err != nil {
+		logger.WithComponent("onair").Warn().Err(err).Msg("Failed to emit on-air signal")
+	}
+}
+
+// runHooks runs the command and HTTP hooks for each transition in order
+func (ind *Indicator) runHooks() {
+	defer close(ind.done)
+
+	log := logger.WithComponent("onair")
+	for onAir := range ind.hooks {
+		if ind.cfg.Command != "" {
+			if err := ind.runCommand(onAir); err != nil {
+				log.Warn().Err(err).Msg("On-air command hook failed")
+			}
+		}
+		if ind.cfg.URL != "" {
+			if err := ind.postHook(onAir); err != nil {
+				log.Warn().Err(err).Msg("On-air HTTP hook failed")
+			}
+		}
+	}
+}
+
+// runCommand runs the command hook through the shell with "on" or "off" as
+// its argument and FOCUSSTREAMER_ON_AIR=1/0 in the environment
+func (ind *Indicator) runCommand(onAir bool) error {
+	state, flag := "off", "0"
+	if onAir {
+		state, flag = "on", "1"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", ind.cfg.Command+` "$1"`, "focusstreamer-onair", state)
+	cmd.Env = append(os.Environ(), "FOCUSSTREAMER_ON_AIR="+flag)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// postHook POSTs {"on_air": bool, "timestamp": ...} to the configured URL
+func (ind *Indicator) postHook(onAir bool) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"on_air":    onAir,
+		"timestamp": time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ind.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid hook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("hook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package output
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// bucketBurst is how much unused budget a client can save up, so a
+	// paused stream can't send a long burst when it resumes
+	bucketBurst = time.Second
+
+	// bitrateWindow is how often per-client bitrate is measured
+	bitrateWindow = time.Second
+)
+
+// ClientInfo reports a connected client's bandwidth use for the API
+type ClientInfo struct {
+	ID            string       `json:"id"` // Shown in the client's watermark
+	RemoteAddr    string       `json:"remote_addr"`
+	UserAgent     string       `json:"user_agent,omitempty"`
+	Format        StreamFormat `json:"format"`
+	ConnectedAt   time.Time    `json:"connected_at"`
+	BitrateBps    uint64       `json:"bitrate_bps"` // Measured over the last second
+	LimitBps      uint64       `json:"limit_bps"`   // Current budget, 0 = unlimited
+	BytesSent     uint64       `json:"bytes_sent"`
+	DroppedFrames uint64       `json:"dropped_frames"` // Client too slow to keep up
+	LimitedFrames uint64       `json:"limited_frames"` // Skipped to stay within budget
+}
+
+// tokenBucket limits the bytes queued for a client. The balance may go
+// negative after a large frame, which then skips frames until it is repaid,
+// so frames bigger than the burst still get through at the budgeted rate.
+type tokenBucket struct {
+	tokens float64 // Bytes
+	last   time.Time
+}
+
+// allow refills the bucket at rate bytes per second and reports whether a
+// frame may be sent. A zero rate means unlimited.
+func (b *tokenBucket) allow(now time.Time, rate float64) bool {
+	if rate <= 0 {
+		return true
+	}
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * rate
+	}
+	b.last = now
+
+	if burst := rate * bucketBurst.Seconds(); b.tokens > burst {
+		b.tokens = burst
+	}
+	return b.tokens >= 0
+}
+
+// take spends n bytes from the bucket
+func (b *tokenBucket) take(n int) {
+	b.tokens -= float64(n)
+}
+
+// bitrateMeter measures the bitrate actually written to a client. The HTTP
+// handler adds bytes as it writes; WriteFrame samples once per window.
+type bitrateMeter struct {
+	bytesSent uint64 // atomic, written by the client's handler
+
+	windowStart time.Time
+	windowBytes uint64
+	bitrate     uint64 // atomic, bits per second
+}
+
+// add records bytes written to the client
+func (bm *bitrateMeter) add(n int) {
+	atomic.AddUint64(&bm.bytesSent, uint64(n))
+}
+
+// sample updates the measured bitrate once a full window has passed
+func (bm *bitrateMeter) sample(now time.Time) {
+	if bm.windowStart.IsZero() {
+		bm.windowStart = now
+		return
+	}
+
+	elapsed := now.Sub(bm.windowStart)
+	if elapsed < bitrateWindow {
+		return
+	}
+
+	sent := atomic.LoadUint64(&bm.bytesSent)
+	atomic.StoreUint64(&bm.bitrate, uint64(float64(sent-bm.windowBytes)*8/elapsed.Seconds()))
+	bm.windowStart = now
+	bm.windowBytes = sent
+}
+
+// clientBudget returns the per-client limit in bits per second: the global
+// cap split evenly across clients, capped by the per-client limit. Zero
+// means unlimited.
+func clientBudget(config Config, clients int) uint64 {
+	budget := config.ClientMaxBitrate
+	if config.MaxBitrate > 0 && clients > 0 {
+		share := config.MaxBitrate / uint64(clients)
+		if budget == 0 || share < budget {
+			budget = share
+		}
+	}
+	return budget
+}
@@ -0,0 +1,150 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// StreamFormat selects how frames are encoded for a stream client
+type StreamFormat string
+
+const (
+	// StreamFormatJPEG is the default lossy format (smallest, fuzzy small text)
+	StreamFormatJPEG StreamFormat = "jpeg"
+	// StreamFormatPNG is a lossless format suited to terminals and code
+	StreamFormatPNG StreamFormat = "png"
+)
+
+// Lossless fallback tuning: if a lossless client drops this many frames
+// within the window, it is switched back to JPEG for the rest of the session
+const (
+	losslessDropThreshold = 5
+	losslessDropWindow    = 10 * time.Second
+)
+
+// streamFrame is an encoded frame queued for a client
+type streamFrame struct {
+	data        []byte
+	contentType string
+	sequence    uint64    // Frame.Sequence, sent as X-Frame-Sequence
+	timestamp   time.Time // Frame.Timestamp, sent as X-Timestamp
+}
+
+// ParseStreamFormat parses a format query parameter.
+// Accepts "jpeg"/"jpg", "png", and "lossless" (alias for png).
+func ParseStreamFormat(value string) (StreamFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "jpeg", "jpg":
+		return StreamFormatJPEG, nil
+	case "png", "lossless":
+		return StreamFormatPNG, nil
+	default:
+		return "", fmt.Errorf("unsupported stream format: %s (use jpeg or png)", value)
+	}
+}
+
+// ContentType returns the MIME type for frames in this format
+func (f StreamFormat) ContentType() string {
+	if f == StreamFormatPNG {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// pngEncoder favours speed since frames are encoded at stream FPS
+var pngEncoder = &png.Encoder{CompressionLevel: png.BestSpeed}
+
+// DefaultJPEGQuality is the JPEG quality of stream frames unless lowered
+// with SetQuality
+const DefaultJPEGQuality = 90
+
+// JPEGEncoder encodes JPEG frames off the CPU, such as a V4L2 hardware
+// encoder (see hwjpeg)
+type JPEGEncoder interface {
+	Encode(w io.Writer, img *image.RGBA, quality int) error
+	Device() string
+}
+
+// hardwareJPEG holds an optional JPEGEncoder. The first failure switches
+// back to software encoding for good, since a failing device rarely
+// recovers and retrying it would stall every frame.
+type hardwareJPEG struct {
+	mu      sync.Mutex
+	encoder JPEGEncoder
+}
+
+// set replaces the encoder; nil encodes in software
+func (h *hardwareJPEG) set(encoder JPEGEncoder) {
+	h.mu.Lock()
+	h.encoder = encoder
+	h.mu.Unlock()
+}
+
+// get returns the encoder, or nil
+func (h *hardwareJPEG) get() JPEGEncoder {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.encoder
+}
+
+// encode writes frame to buf on the encoder, reporting whether it did. buf
+// is left empty if it didn't.
+func (h *hardwareJPEG) encode(buf *bytes.Buffer, frame *image.RGBA, quality int) bool {
+	encoder := h.get()
+	if encoder == nil {
+		return false
+	}
+	err := encoder.Encode(buf, frame, quality)
+	if err == nil {
+		return true
+	}
+
+	buf.Reset()
+	h.mu.Lock()
+	if h.encoder == encoder {
+		h.encoder = nil
+		logger.WithComponent("mjpeg").Warn().Err(err).
+			Str("device", encoder.Device()).
+			Msg("Hardware JPEG encoding failed, falling back to software encoding")
+	}
+	h.mu.Unlock()
+	return false
+}
+
+// encodeFrame encodes a frame in the given format, at the given quality if
+// the format is lossy. JPEG frames go to the hardware encoder if one is
+// set. Encoding happens in a
+// pooled buffer that is pre-grown from previous frames; the result is copied
+// out once since it is shared with client goroutines.
+func encodeFrame(frame *image.RGBA, format StreamFormat, quality int, hw *hardwareJPEG) (streamFrame, error) {
+	buf := framepool.GetBuffer()
+	defer framepool.PutBuffer(buf)
+
+	switch format {
+	case StreamFormatPNG:
+		if err := pngEncoder.Encode(buf, frame); err != nil {
+			return streamFrame{}, fmt.Errorf("failed to encode PNG: %w", err)
+		}
+	default:
+		if hw.encode(buf, frame, quality) {
+			break
+		}
+		if err := jpeg.Encode(buf, frame, &jpeg.Options{Quality: quality}); err != nil {
+			return streamFrame{}, fmt.Errorf("failed to encode JPEG: %w", err)
+		}
+	}
+
+	data := make([]byte, buf.Len())
+	copy(data, buf.Bytes())
+	return streamFrame{data: data, contentType: format.ContentType()}, nil
+}
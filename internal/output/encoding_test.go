@@ -0,0 +1,96 @@
+package output
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"testing"
+)
+
+// desktopFrame draws a frame that compresses like a desktop window, as the
+// bench command does: flat panels with lines of fine, text-like detail
+func desktopFrame(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < width; x++ {
+			r, g, b := byte(30), byte(30), byte(40)
+			if x < width/5 {
+				r, g, b = 45, 45, 55
+			}
+			if y%16 >= 3 && y%16 <= 12 && (x*7+y*3)%11 < 5 && (x/48+y/16)%4 != 0 {
+				r, g, b = 212, 212, byte(160+(x*13)%90)
+			}
+			row[x*4], row[x*4+1], row[x*4+2], row[x*4+3] = r, g, b, 0xff
+		}
+	}
+	return img
+}
+
+// failingJPEG is a hardware encoder that always fails
+type failingJPEG struct{ calls int }
+
+func (f *failingJPEG) Encode(w io.Writer, img *image.RGBA, quality int) error {
+	f.calls++
+	w.Write([]byte("partial"))
+	return errors.New("device gone")
+}
+
+func (f *failingJPEG) Device() string { return "/dev/video-test" }
+
+func TestEncodeFrameFallsBackToSoftware(t *testing.T) {
+	frame := desktopFrame(64, 36)
+	hw := &hardwareJPEG{}
+	failing := &failingJPEG{}
+	hw.set(failing)
+
+	for i := 0; i < 2; i++ {
+		encoded, err := encodeFrame(frame, StreamFormatJPEG, DefaultJPEGQuality, hw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := jpeg.Decode(bytes.NewReader(encoded.data)); err != nil {
+			t.Fatalf("frame %d isn't a JPEG: %v", i, err)
+		}
+	}
+	if failing.calls != 1 {
+		t.Errorf("hardware encoder called %d times, want once before falling back", failing.calls)
+	}
+	if hw.get() != nil {
+		t.Error("hardware encoder still set after failing")
+	}
+}
+
+// BenchmarkEncodeFrame times the stream's encode step per format and
+// stream size; docs/benchmarks.md has results
+func BenchmarkEncodeFrame(b *testing.B) {
+	formats := []struct {
+		name    string
+		format  StreamFormat
+		quality int
+	}{
+		{"jpeg-q90", StreamFormatJPEG, DefaultJPEGQuality},
+		{"jpeg-q70", StreamFormatJPEG, 70},
+		{"png", StreamFormatPNG, 0},
+	}
+	for _, size := range []image.Point{{640, 360}, {1280, 720}, {1920, 1080}} {
+		frame := desktopFrame(size.X, size.Y)
+		for _, f := range formats {
+			b.Run(fmt.Sprintf("%s/%dx%d", f.name, size.X, size.Y), func(b *testing.B) {
+				hw := &hardwareJPEG{}
+				var bytesOut int
+				for b.Loop() {
+					encoded, err := encodeFrame(frame, f.format, f.quality, hw)
+					if err != nil {
+						b.Fatal(err)
+					}
+					bytesOut = len(encoded.data)
+				}
+				b.ReportMetric(float64(bytesOut)/1024, "KiB/frame")
+			})
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"image"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// Frame is a stream frame with what it shows and when it was captured, as
+// handed to overlays and outputs
+type Frame struct {
+	Image *image.RGBA // RGBA picture; outputs must not retain it, see Output.WriteFrame
+
+	// Sequence numbers the frames produced by the stream loop, from 1. Gaps
+	// mean frames were skipped before reaching this output.
+	Sequence uint64
+
+	// Timestamp is when capturing the frame's window started, or when the
+	// frame was started for the placeholder
+	Timestamp time.Time
+
+	// CaptureLatency is how long capturing the window took; zero for the
+	// placeholder
+	CaptureLatency time.Duration
+
+	Window  *config.WindowInfo // Source window, nil while showing the placeholder
+	Standby bool               // Showing the placeholder
+}
+
+// NewFrame wraps a bare image as a frame captured now, for callers without
+// metadata of their own
+func NewFrame(img *image.RGBA) *Frame {
+	return &Frame{Image: img, Timestamp: time.Now()}
+}
+
+// Age returns how long ago the frame's content was captured
+func (f *Frame) Age(now time.Time) time.Duration {
+	if f.Timestamp.IsZero() {
+		return 0
+	}
+	return now.Sub(f.Timestamp)
+}
@@ -0,0 +1,113 @@
+package output
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Translations of the viewer pages' strings, one JSON object per language
+// keyed by message ID. English is complete; other languages may leave
+// messages out, which then show in English.
+//
+//go:embed locales/*.json
+var localeFS embed.FS
+
+// defaultLocale is used when no language the browser accepts is available
+const defaultLocale = "en"
+
+// locales maps language codes to their messages
+var locales = mustLoadLocales()
+
+func mustLoadLocales() map[string]map[string]string {
+	files, err := localeFS.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+
+	locales := make(map[string]map[string]string, len(files))
+	for _, file := range files {
+		data, err := localeFS.ReadFile("locales/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic("invalid locale " + file.Name() + ": " + err.Error())
+		}
+		locales[strings.TrimSuffix(file.Name(), path.Ext(file.Name()))] = messages
+	}
+	return locales
+}
+
+// Locales returns the supported language codes
+func Locales() []string {
+	codes := make([]string, 0, len(locales))
+	for code := range locales {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// supportedLocale returns the supported language of a language tag such
+// as "de-AT", or an empty string
+func supportedLocale(tag string) string {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	if _, ok := locales[base]; ok {
+		return base
+	}
+	return ""
+}
+
+// NegotiateLocale picks the supported language the browser prefers most
+// from an Accept-Language header, e.g. "de-DE,de;q=0.9,en;q=0.8"
+func NegotiateLocale(acceptLanguage string) string {
+	best, bestQ := defaultLocale, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		// Earlier entries win ties, as browsers list languages in order
+		if locale := supportedLocale(tag); locale != "" && q > bestQ {
+			best, bestQ = locale, q
+		}
+	}
+	return best
+}
+
+// translate returns a message in a language, falling back to English and
+// then to the message ID
+func translate(locale, id string) string {
+	if message, ok := locales[locale][id]; ok {
+		return message
+	}
+	if message, ok := locales[defaultLocale][id]; ok {
+		return message
+	}
+	return id
+}
+
+// T returns a message in the page's language
+func (o ViewerOptions) T(id string) string {
+	return translate(o.Lang, id)
+}
+
+// Messages returns every message in the page's language, for page scripts
+func (o ViewerOptions) Messages() map[string]string {
+	messages := make(map[string]string, len(locales[defaultLocale]))
+	for id := range locales[defaultLocale] {
+		messages[id] = translate(o.Lang, id)
+	}
+	return messages
+}
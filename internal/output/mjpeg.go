@@ -1,24 +1,42 @@
 package output
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
 	"image"
-	"image/jpeg"
+	"math"
 	"net/http"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 )
 
 // clientStats tracks per-client connection statistics
 type clientStats struct {
-	frameChan     chan []byte
-	droppedFrames uint64
+	id            string // Random, shown in the client's watermark
+	frameChan     chan streamFrame
+	droppedFrames uint64 // atomic
 	lastSent      time.Time
 	connected     time.Time
+
+	// Encoding format for this client (a StreamFormat); lossless clients
+	// are switched back to JPEG by WriteFrame if they can't keep up, while
+	// the stats pages read it, so it is atomic
+	format          atomic.Value
+	requestedFormat StreamFormat
+	formatDrops     int
+	formatDropStart time.Time
+
+	remoteAddr    string
+	userAgent     string
+	bucket        tokenBucket
+	meter         bitrateMeter
+	limitBps      uint64 // atomic, current budget
+	limitedFrames uint64 // atomic, frames skipped to stay within budget
 }
 
 // MJPEGOutput streams frames as Motion JPEG over HTTP
@@ -28,29 +46,73 @@ type MJPEGOutput struct {
 	running bool
 	mu      sync.RWMutex
 
-	// Current frame buffer
-	frameMu      sync.RWMutex
-	currentFrame *image.RGBA
-	lastUpdate   time.Time
+	// Serve viewer pages from disk instead of the embedded templates (development)
+	templateDir string
+
+	// Last frame time (frames themselves are not retained, see Output.WriteFrame)
+	frameMu    sync.RWMutex
+	lastUpdate time.Time
 
 	// Connected clients with per-client stats
 	clientsMu sync.RWMutex
-	clients   map[chan []byte]*clientStats
+	clients   map[chan streamFrame]*clientStats
+
+	// Called with the new client count when a client connects or disconnects
+	onClientsChanged func(count int)
 
 	// Stats
 	frameCount    uint64
 	droppedFrames uint64 // Total frames dropped across all clients
 	startTime     time.Time
+
+	// Frame rate measured over the last second (only WriteFrame writes the window)
+	fpsWindowStart  time.Time
+	fpsWindowFrames int
+	fps             uint64 // atomic, math.Float64bits
+
+	// Bytes written to all clients, sampled with the frame rate
+	meter bitrateMeter
+
+	quality int64 // atomic, JPEG quality of stream frames
+
+	hwJPEG hardwareJPEG // Optional hardware JPEG encoder
 }
 
 // NewMJPEGOutput creates a new MJPEG stream output
 func NewMJPEGOutput(config Config) *MJPEGOutput {
 	return &MJPEGOutput{
 		config:  config,
-		clients: make(map[chan []byte]*clientStats),
+		clients: make(map[chan streamFrame]*clientStats),
+		quality: DefaultJPEGQuality,
 	}
 }
 
+// SetQuality sets the JPEG quality (1-100) of stream frames
+func (m *MJPEGOutput) SetQuality(quality int) {
+	atomic.StoreInt64(&m.quality, int64(min(max(quality, 1), 100)))
+}
+
+// Quality returns the JPEG quality of stream frames
+func (m *MJPEGOutput) Quality() int {
+	return int(atomic.LoadInt64(&m.quality))
+}
+
+// SetJPEGEncoder encodes JPEG frames on encoder instead of the CPU, or in
+// software again if encoder is nil. The software encoder takes over if
+// encoder fails.
+func (m *MJPEGOutput) SetJPEGEncoder(encoder JPEGEncoder) {
+	m.hwJPEG.set(encoder)
+}
+
+// JPEGEncoderDevice returns the hardware JPEG encoder's device, or an empty
+// string when encoding in software
+func (m *MJPEGOutput) JPEGEncoderDevice() string {
+	if encoder := m.hwJPEG.get(); encoder != nil {
+		return encoder.Device()
+	}
+	return ""
+}
+
 // Start initializes the MJPEG output
 // Note: The HTTP handler is registered separately via GetHTTPHandler()
 func (m *MJPEGOutput) Start() error {
@@ -85,7 +147,7 @@ func (m *MJPEGOutput) Stop() error {
 	for ch := range m.clients {
 		close(ch)
 	}
-	m.clients = make(map[chan []byte]*clientStats)
+	m.clients = make(map[chan streamFrame]*clientStats)
 	m.clientsMu.Unlock()
 
 	totalDropped := atomic.LoadUint64(&m.droppedFrames)
@@ -94,47 +156,63 @@ func (m *MJPEGOutput) Stop() error {
 }
 
 // WriteFrame sends a frame to all connected clients
-func (m *MJPEGOutput) WriteFrame(frame *image.RGBA) error {
+func (m *MJPEGOutput) WriteFrame(frame *Frame) error {
 	if !m.IsRunning() {
 		return fmt.Errorf("MJPEG output not running")
 	}
 
-	// Encode frame as JPEG
-	buf := new(bytes.Buffer)
-	if err := jpeg.Encode(buf, frame, &jpeg.Options{Quality: 90}); err != nil {
-		return fmt.Errorf("failed to encode JPEG: %w", err)
-	}
-
-	jpegData := buf.Bytes()
-
-	// Update current frame
+	// Update last frame time
 	m.frameMu.Lock()
-	m.currentFrame = frame
 	m.lastUpdate = time.Now()
 	m.frameMu.Unlock()
 
 	m.frameCount++
+	m.measureFPS()
+	m.meter.sample(time.Now())
 
-	// Broadcast to all clients with drop tracking
+	// Broadcast to all clients with drop tracking. Each format is encoded
+	// at most once per frame, and only if some client wants it.
+	encoded := make(map[StreamFormat]streamFrame, 2)
 	m.clientsMu.RLock()
 	now := time.Now()
+	budget := clientBudget(m.config, len(m.clients))
 	for ch, stats := range m.clients {
+		stats.meter.sample(now)
+		atomic.StoreUint64(&stats.limitBps, budget)
+
+		// Skip frames for clients over their bandwidth budget
+		if !stats.bucket.allow(now, float64(budget)/8) {
+			atomic.AddUint64(&stats.limitedFrames, 1)
+			continue
+		}
+
+		data, err := m.clientFrame(frame.Image, stats, now, encoded)
+		if err != nil {
+			m.clientsMu.RUnlock()
+			return err
+		}
+		data.sequence = frame.Sequence
+		data.timestamp = frame.Timestamp
+
 		select {
-		case ch <- jpegData:
+		case ch <- data:
 			// Sent successfully
 			stats.lastSent = now
+			stats.bucket.take(len(data.data))
 		default:
 			// Client is slow, skip this frame
-			stats.droppedFrames++
+			dropped := atomic.AddUint64(&stats.droppedFrames, 1)
 			atomic.AddUint64(&m.droppedFrames, 1)
 
 			// Log warning at thresholds
-			if stats.droppedFrames == 10 || stats.droppedFrames == 100 || stats.droppedFrames%1000 == 0 {
+			if dropped == 10 || dropped == 100 || dropped%1000 == 0 {
 				logger.WithComponent("mjpeg").Warn().
-					Uint64("dropped", stats.droppedFrames).
+					Uint64("dropped", dropped).
 					Dur("connected_for", now.Sub(stats.connected)).
 					Msg("Client dropping frames - possible network congestion")
 			}
+
+			m.trackFormatDrop(stats, now)
 		}
 	}
 	m.clientsMu.RUnlock()
@@ -142,6 +220,76 @@ func (m *MJPEGOutput) WriteFrame(frame *image.RGBA) error {
 	return nil
 }
 
+// clientFrame encodes the frame for one client. Without watermarks each
+// format is encoded once and shared through encoded; with watermarks every
+// client gets its own copy.
+func (m *MJPEGOutput) clientFrame(frame *image.RGBA, stats *clientStats, now time.Time, encoded map[StreamFormat]streamFrame) (streamFrame, error) {
+	if m.config.Watermark {
+		marked := watermarkFrame(frame, stats.id, now, m.config.WatermarkOpacity)
+		defer framepool.Put(marked)
+		return encodeFrame(marked, stats.streamFormat(), m.Quality(), &m.hwJPEG)
+	}
+
+	format := stats.streamFormat()
+	if data, ok := encoded[format]; ok {
+		return data, nil
+	}
+	data, err := encodeFrame(frame, format, m.Quality(), &m.hwJPEG)
+	if err != nil {
+		return streamFrame{}, err
+	}
+	encoded[format] = data
+	return data, nil
+}
+
+// measureFPS updates the measured frame rate once per second
+func (m *MJPEGOutput) measureFPS() {
+	now := time.Now()
+	m.fpsWindowFrames++
+	if m.fpsWindowStart.IsZero() {
+		m.fpsWindowStart = now
+		return
+	}
+
+	elapsed := now.Sub(m.fpsWindowStart)
+	if elapsed < time.Second {
+		return
+	}
+	atomic.StoreUint64(&m.fps, math.Float64bits(float64(m.fpsWindowFrames)/elapsed.Seconds()))
+	m.fpsWindowStart = now
+	m.fpsWindowFrames = 0
+}
+
+// trackFormatDrop switches a lossless client back to JPEG if it keeps
+// dropping frames, since PNG frames are several times larger.
+// Only called from WriteFrame, which owns the drop counts and is the only
+// writer of the format.
+func (m *MJPEGOutput) trackFormatDrop(stats *clientStats, now time.Time) {
+	if stats.streamFormat() == StreamFormatJPEG {
+		return
+	}
+
+	if now.Sub(stats.formatDropStart) > losslessDropWindow {
+		stats.formatDropStart = now
+		stats.formatDrops = 0
+	}
+	stats.formatDrops++
+
+	if stats.formatDrops >= losslessDropThreshold {
+		logger.WithComponent("mjpeg").Warn().
+			Str("requested_format", string(stats.requestedFormat)).
+			Int("drops", stats.formatDrops).
+			Dur("window", losslessDropWindow).
+			Msg("Client can't keep up with lossless stream, switching back to JPEG")
+		stats.format.Store(StreamFormatJPEG)
+	}
+}
+
+// streamFormat returns the format the client's frames are encoded in
+func (s *clientStats) streamFormat() StreamFormat {
+	return s.format.Load().(StreamFormat)
+}
+
 // Name returns the output type name
 func (m *MJPEGOutput) Name() string {
 	return "MJPEG HTTP Stream"
@@ -159,6 +307,12 @@ func (m *MJPEGOutput) IsRunning() bool {
 func (m *MJPEGOutput) GetHTTPHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Set headers for MJPEG stream
+		format, err := ParseStreamFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=frame")
 		w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
 		w.Header().Set("Pragma", "no-cache")
@@ -166,23 +320,32 @@ func (m *MJPEGOutput) GetHTTPHandler() http.HandlerFunc {
 		w.Header().Set("Connection", "close")
 
 		// Create channel for this client with larger buffer to handle network latency
-		frameChan := make(chan []byte, 10) // Buffer 10 frames to prevent drops during brief network delays
+		frameChan := make(chan streamFrame, 10) // Buffer 10 frames to prevent drops during brief network delays
 
 		// Create client stats
 		now := time.Now()
 		stats := &clientStats{
-			frameChan: frameChan,
-			connected: now,
-			lastSent:  now,
+			id:              newClientID(),
+			frameChan:       frameChan,
+			connected:       now,
+			lastSent:        now,
+			requestedFormat: format,
+			remoteAddr:      r.RemoteAddr,
+			userAgent:       r.UserAgent(),
 		}
+		stats.format.Store(format)
 
 		// Register client
 		m.clientsMu.Lock()
 		m.clients[frameChan] = stats
 		clientCount := len(m.clients)
+		onClientsChanged := m.onClientsChanged
 		m.clientsMu.Unlock()
 
-		logger.WithComponent("mjpeg").Info().Msgf("[MJPEG] New client connected (total: %d)", clientCount)
+		logger.WithComponent("mjpeg").Info().Msgf("[MJPEG] New client %s connected from %s (total: %d, format: %s)", stats.id, stats.remoteAddr, clientCount, format)
+		if onClientsChanged != nil {
+			onClientsChanged(clientCount)
+		}
 
 		// Cleanup on disconnect
 		defer func() {
@@ -190,11 +353,16 @@ func (m *MJPEGOutput) GetHTTPHandler() http.HandlerFunc {
 			clientStats := m.clients[frameChan]
 			delete(m.clients, frameChan)
 			clientCount := len(m.clients)
+			onClientsChanged := m.onClientsChanged
 			m.clientsMu.Unlock()
 
-			if clientStats != nil && clientStats.droppedFrames > 0 {
+			if onClientsChanged != nil {
+				onClientsChanged(clientCount)
+			}
+
+			if dropped := clientDrops(clientStats); dropped > 0 {
 				logger.WithComponent("mjpeg").Info().
-					Uint64("dropped_frames", clientStats.droppedFrames).
+					Uint64("dropped_frames", dropped).
 					Dur("session_duration", time.Since(clientStats.connected)).
 					Int("remaining_clients", clientCount).
 					Msg("[MJPEG] Client disconnected with frame drops")
@@ -203,15 +371,32 @@ func (m *MJPEGOutput) GetHTTPHandler() http.HandlerFunc {
 			}
 		}()
 
-		// Stream frames to client
-		for jpegData := range frameChan {
-			// Write multipart boundary
-			if _, err := fmt.Fprintf(w, "--frame\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", len(jpegData)); err != nil {
+		// Stream frames to client until it disconnects or the request is
+		// cancelled (e.g. its link token expires)
+		for {
+			var frame streamFrame
+			var ok bool
+			select {
+			case <-r.Context().Done():
+				return
+			case frame, ok = <-frameChan:
+				if !ok {
+					return
+				}
+			}
+
+			// Write multipart boundary, with the frame's sequence number and
+			// capture time (Unix microseconds) for clients measuring latency
+			if _, err := fmt.Fprintf(w, "--frame\r\nContent-Type: %s\r\nContent-Length: %d\r\nX-Frame-Sequence: %d\r\nX-Timestamp: %d\r\n\r\n",
+				frame.contentType, len(frame.data), frame.sequence, frame.timestamp.UnixMicro()); err != nil {
 				return
 			}
 
-			// Write JPEG data
-			if _, err := w.Write(jpegData); err != nil {
+			// Write frame data
+			n, err := w.Write(frame.data)
+			stats.meter.add(n)
+			m.meter.add(n)
+			if err != nil {
 				return
 			}
 
@@ -228,788 +413,6 @@ func (m *MJPEGOutput) GetHTTPHandler() http.HandlerFunc {
 	}
 }
 
-// GetViewerHandler returns an HTTP handler that displays a clean stream viewer with subtle hover nav
-func (m *MJPEGOutput) GetViewerHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		html := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>FocusStreamer</title>
-    <style>
-        * {
-            margin: 0;
-            padding: 0;
-            box-sizing: border-box;
-        }
-        body {
-            background: #000;
-            overflow: hidden;
-            display: flex;
-            justify-content: center;
-            align-items: center;
-            min-height: 100vh;
-        }
-        .stream-container {
-            position: relative;
-            display: flex;
-            justify-content: center;
-            align-items: center;
-        }
-        img {
-            width: 100vw;
-            height: 100vh;
-            object-fit: contain;
-            display: block;
-            background: #000;
-        }
-        .nav-trigger {
-            position: fixed;
-            bottom: 0;
-            left: 0;
-            width: 100px;
-            height: 100px;
-            z-index: 900;
-        }
-        .nav-menu {
-            position: fixed;
-            bottom: 16px;
-            left: 16px;
-            display: flex;
-            gap: 8px;
-            opacity: 0;
-            transform: translateY(10px);
-            transition: opacity 0.2s ease, transform 0.2s ease;
-            pointer-events: none;
-            z-index: 1000;
-        }
-        .nav-trigger:hover ~ .nav-menu,
-        .nav-menu:hover {
-            opacity: 1;
-            transform: translateY(0);
-            pointer-events: auto;
-        }
-        .nav-link {
-            display: flex;
-            align-items: center;
-            gap: 6px;
-            padding: 8px 14px;
-            background: rgba(40, 40, 40, 0.9);
-            color: #ccc;
-            text-decoration: none;
-            border-radius: 20px;
-            font-family: system-ui, -apple-system, sans-serif;
-            font-size: 13px;
-            transition: background 0.15s ease, color 0.15s ease;
-        }
-        .nav-link:hover {
-            background: rgba(60, 60, 60, 0.95);
-            color: #fff;
-        }
-        .fade-overlay {
-            position: fixed;
-            top: 0;
-            left: 0;
-            right: 0;
-            bottom: 0;
-            background: #000;
-            opacity: 0;
-            transition: opacity 250ms ease;
-            pointer-events: none;
-            z-index: 500;
-        }
-        .fade-overlay.active {
-            opacity: 1;
-        }
-    </style>
-</head>
-<body>
-    <div class="fade-overlay" id="fadeOverlay"></div>
-    <div class="stream-container">
-        <img src="/stream" alt="FocusStreamer Live Stream">
-    </div>
-    <div class="nav-trigger"></div>
-    <div class="nav-menu">
-        <a href="/settings" class="nav-link">⚙ Settings</a>
-        <a href="/control" class="nav-link">🎛 Control</a>
-    </div>
-    <script>
-        // Listen for standby state changes and trigger fade
-        let lastStandbyState = null;
-
-        async function checkStandbyState() {
-            try {
-                const response = await fetch('/api/stream/standby');
-                const data = await response.json();
-
-                if (lastStandbyState !== null && lastStandbyState !== data.enabled) {
-                    // State changed - trigger fade
-                    const overlay = document.getElementById('fadeOverlay');
-                    overlay.classList.add('active');
-                    setTimeout(() => {
-                        overlay.classList.remove('active');
-                    }, 350);
-                }
-                lastStandbyState = data.enabled;
-            } catch (err) {
-                console.error('Failed to check standby state:', err);
-            }
-        }
-
-        // Poll for standby state changes
-        setInterval(checkStandbyState, 500);
-        checkStandbyState();
-    </script>
-</body>
-</html>`
-		w.Write([]byte(html))
-	}
-}
-
-// GetControlHandler returns an HTTP handler that displays the stream with control UI
-func (m *MJPEGOutput) GetControlHandler() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		html := `<!DOCTYPE html>
-<html lang="en">
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>FocusStreamer - Control</title>
-    <style>
-        * {
-            margin: 0;
-            padding: 0;
-            box-sizing: border-box;
-        }
-        body {
-            background: #000;
-            overflow: hidden;
-            display: flex;
-            justify-content: center;
-            align-items: center;
-            min-height: 100vh;
-        }
-        body.bypass-active {
-            border: 4px solid #dc3545;
-        }
-        .stream-container {
-            position: relative;
-            display: flex;
-            justify-content: center;
-            align-items: center;
-        }
-        img {
-            width: 100vw;
-            height: 100vh;
-            object-fit: contain;
-            display: block;
-            background: #000;
-            user-select: none;
-            -webkit-user-drag: none;
-        }
-        .fade-overlay {
-            position: fixed;
-            top: 0;
-            left: 0;
-            right: 0;
-            bottom: 0;
-            background: #000;
-            opacity: 0;
-            transition: opacity 250ms ease;
-            pointer-events: none;
-            z-index: 500;
-        }
-        .fade-overlay.active {
-            opacity: 1;
-        }
-        .fab {
-            position: fixed;
-            bottom: 24px;
-            right: 24px;
-            width: 56px;
-            height: 56px;
-            border-radius: 50%;
-            border: none;
-            background: rgba(70, 130, 180, 0.9);
-            color: white;
-            font-size: 24px;
-            cursor: pointer;
-            box-shadow: 0 4px 12px rgba(0,0,0,0.4);
-            transition: all 0.2s ease;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            z-index: 1000;
-        }
-        .fab:hover {
-            transform: scale(1.1);
-            background: rgba(100, 149, 237, 0.95);
-        }
-        .fab:active {
-            transform: scale(0.95);
-        }
-        .fab.standby {
-            background: rgba(220, 80, 80, 0.9);
-        }
-        .fab.standby:hover {
-            background: rgba(240, 100, 100, 0.95);
-        }
-        .fab-bypass {
-            top: 24px;
-            bottom: auto;
-            right: 24px;
-        }
-        .fab-bypass.active {
-            background: rgba(220, 53, 69, 0.9);
-        }
-        .fab-bypass.active:hover {
-            background: rgba(240, 73, 89, 0.95);
-        }
-        .fab-bypass-tooltip {
-            top: 90px;
-            bottom: auto;
-            right: 24px;
-        }
-        .fab-tooltip {
-            position: fixed;
-            bottom: 90px;
-            right: 24px;
-            background: rgba(0,0,0,0.8);
-            color: white;
-            padding: 8px 12px;
-            border-radius: 6px;
-            font-size: 14px;
-            font-family: system-ui, sans-serif;
-            opacity: 0;
-            transition: opacity 0.2s ease;
-            pointer-events: none;
-            white-space: nowrap;
-        }
-        .fab:hover + .fab-tooltip {
-            opacity: 1;
-        }
-        .cycle-buttons {
-            position: fixed;
-            bottom: 92px;
-            right: 24px;
-            display: flex;
-            gap: 8px;
-            z-index: 1000;
-            opacity: 0;
-            transition: opacity 0.2s ease;
-            pointer-events: none;
-        }
-        .cycle-buttons.visible {
-            opacity: 1;
-            pointer-events: auto;
-        }
-        .cycle-btn {
-            width: 36px;
-            height: 36px;
-            border-radius: 50%;
-            border: none;
-            background: rgba(60, 60, 60, 0.9);
-            color: white;
-            font-size: 16px;
-            cursor: pointer;
-            display: flex;
-            align-items: center;
-            justify-content: center;
-            transition: all 0.15s ease;
-        }
-        .cycle-btn:hover {
-            background: rgba(80, 80, 80, 0.95);
-            transform: scale(1.1);
-        }
-        .cycle-btn:active {
-            transform: scale(0.95);
-        }
-        .nav-trigger {
-            position: fixed;
-            bottom: 0;
-            left: 0;
-            width: 100px;
-            height: 100px;
-            z-index: 900;
-        }
-        .nav-menu {
-            position: fixed;
-            bottom: 16px;
-            left: 16px;
-            display: flex;
-            gap: 8px;
-            opacity: 0;
-            transform: translateY(10px);
-            transition: opacity 0.2s ease, transform 0.2s ease;
-            pointer-events: none;
-            z-index: 1000;
-        }
-        .nav-trigger:hover ~ .nav-menu,
-        .nav-menu:hover {
-            opacity: 1;
-            transform: translateY(0);
-            pointer-events: auto;
-        }
-        .nav-link {
-            display: flex;
-            align-items: center;
-            gap: 6px;
-            padding: 8px 14px;
-            background: rgba(40, 40, 40, 0.9);
-            color: #ccc;
-            text-decoration: none;
-            border-radius: 20px;
-            font-family: system-ui, -apple-system, sans-serif;
-            font-size: 13px;
-            transition: background 0.15s ease, color 0.15s ease;
-        }
-        .nav-link:hover {
-            background: rgba(60, 60, 60, 0.95);
-            color: #fff;
-        }
-        .minimap {
-            position: fixed;
-            top: 16px;
-            right: 16px;
-            width: 180px;
-            background: rgba(0, 0, 0, 0.75);
-            border: 1px solid rgba(255, 255, 255, 0.2);
-            border-radius: 8px;
-            padding: 8px;
-            z-index: 1000;
-            display: none;
-            font-family: system-ui, -apple-system, sans-serif;
-        }
-        .minimap.visible {
-            display: block;
-        }
-        .minimap-header {
-            display: flex;
-            justify-content: space-between;
-            align-items: center;
-            margin-bottom: 8px;
-            color: #aaa;
-            font-size: 11px;
-        }
-        .minimap-canvas-container {
-            position: relative;
-            width: 100%;
-            background: #111;
-            border-radius: 4px;
-            overflow: hidden;
-        }
-        .minimap-canvas {
-            width: 100%;
-            display: block;
-        }
-        .minimap-viewport {
-            position: absolute;
-            border: 2px solid #4CAF50;
-            background: rgba(76, 175, 80, 0.15);
-            cursor: move;
-            box-sizing: border-box;
-        }
-        .minimap-canvas-container {
-            cursor: pointer;
-        }
-        .zoom-indicator {
-            position: fixed;
-            top: 16px;
-            left: 50%;
-            transform: translateX(-50%);
-            background: rgba(0, 0, 0, 0.7);
-            color: #fff;
-            padding: 6px 14px;
-            border-radius: 16px;
-            font-family: system-ui, -apple-system, sans-serif;
-            font-size: 13px;
-            z-index: 1000;
-            opacity: 0;
-            transition: opacity 0.2s ease;
-            pointer-events: none;
-        }
-        .zoom-indicator.visible {
-            opacity: 1;
-        }
-    </style>
-</head>
-<body>
-    <div class="stream-container" id="streamContainer">
-        <img id="streamImg" src="/stream" alt="FocusStreamer Live Stream">
-    </div>
-    <div class="fade-overlay" id="fadeOverlay"></div>
-    <div class="cycle-buttons" id="cycleButtons">
-        <button class="cycle-btn" onclick="cyclePrev()" title="Previous Image">◀</button>
-        <button class="cycle-btn" onclick="cycleNext()" title="Next Image">▶</button>
-    </div>
-    <button class="fab" id="standbyBtn" onclick="toggleStandby()" title="Toggle Standby">⏸</button>
-    <div class="fab-tooltip" id="tooltip">Toggle Standby</div>
-    <button class="fab fab-bypass" id="bypassBtn" onclick="toggleBypass()" title="Toggle Allowlist Bypass">🔓</button>
-    <div class="fab-tooltip fab-bypass-tooltip" id="bypassTooltip">Enable Bypass</div>
-    <div class="nav-trigger"></div>
-    <div class="nav-menu">
-        <a href="/" class="nav-link">📺 Stream</a>
-        <a href="/settings" class="nav-link">⚙ Settings</a>
-    </div>
-    <div class="minimap" id="minimap">
-        <div class="minimap-header">
-            <span>Zoom: <span id="zoomLevel">1.0</span>x</span>
-            <span style="color:#666;cursor:pointer" onclick="resetZoom()">Reset</span>
-        </div>
-        <div class="minimap-canvas-container">
-            <canvas id="minimapCanvas" class="minimap-canvas"></canvas>
-            <div id="minimapViewport" class="minimap-viewport"></div>
-        </div>
-    </div>
-    <div class="zoom-indicator" id="zoomIndicator">1.0x</div>
-    <script>
-        // Standby state
-        let isStandby = false;
-        let isTransitioning = false;
-
-        // Bypass state
-        let isBypass = false;
-
-        // Zoom state
-        let zoomState = { scale: 1.0, offsetX: 0.5, offsetY: 0.5 };
-        let isDragging = false;
-        let dragStart = { x: 0, y: 0 };
-        let dragStartOffset = { x: 0, y: 0 };
-        let zoomIndicatorTimeout = null;
-        let zoomUpdateTimeout = null;
-        let pendingZoomUpdate = false;
-
-        // Elements
-        const streamContainer = document.getElementById('streamContainer');
-        const streamImg = document.getElementById('streamImg');
-        const minimap = document.getElementById('minimap');
-        const minimapCanvas = document.getElementById('minimapCanvas');
-        const minimapViewport = document.getElementById('minimapViewport');
-        const zoomIndicator = document.getElementById('zoomIndicator');
-        const zoomLevelSpan = document.getElementById('zoomLevel');
-
-        // Initialize
-        fetch('/api/stream/standby')
-            .then(r => r.json())
-            .then(data => {
-                isStandby = data.enabled;
-                updateButton();
-            })
-            .catch(console.error);
-
-        fetch('/api/stream/allowlist-bypass')
-            .then(r => r.json())
-            .then(data => {
-                isBypass = data.enabled;
-                updateBypassButton();
-            })
-            .catch(console.error);
-
-        fetch('/api/stream/zoom')
-            .then(r => r.json())
-            .then(data => {
-                zoomState = data;
-                updateMinimap();
-            })
-            .catch(console.error);
-
-        // Zoom with mouse wheel (shared handler)
-        function handleWheel(e) {
-            e.preventDefault();
-
-            const delta = e.deltaY > 0 ? -0.25 : 0.25;
-            let newScale = zoomState.scale + delta;
-            newScale = Math.max(1.0, Math.min(4.0, newScale));
-
-            if (newScale !== zoomState.scale) {
-                // Adjust offset to zoom toward cursor position (only when zooming in on stream)
-                if (newScale > zoomState.scale && e.currentTarget === streamContainer) {
-                    const rect = streamImg.getBoundingClientRect();
-                    const relX = (e.clientX - rect.left) / rect.width;
-                    const relY = (e.clientY - rect.top) / rect.height;
-
-                    // Move offset toward cursor
-                    const factor = 0.1;
-                    zoomState.offsetX += (relX - 0.5) * factor;
-                    zoomState.offsetY += (relY - 0.5) * factor;
-                }
-
-                zoomState.scale = newScale;
-                updateZoom();
-            }
-        }
-        streamContainer.addEventListener('wheel', handleWheel, { passive: false });
-        minimap.addEventListener('wheel', handleWheel, { passive: false });
-
-        // Double-click to reset
-        streamContainer.addEventListener('dblclick', (e) => {
-            e.preventDefault();
-            resetZoom();
-        });
-
-        // Get minimap canvas container for drag handling
-        const minimapContainer = document.querySelector('.minimap-canvas-container');
-
-        // Clamp offset values to valid range based on current scale
-        function clampOffset() {
-            if (zoomState.scale <= 1.0) {
-                zoomState.offsetX = 0.5;
-                zoomState.offsetY = 0.5;
-                return;
-            }
-            const viewportSize = 1.0 / zoomState.scale;
-            const minOffset = viewportSize / 2;
-            const maxOffset = 1.0 - viewportSize / 2;
-            zoomState.offsetX = Math.max(minOffset, Math.min(maxOffset, zoomState.offsetX));
-            zoomState.offsetY = Math.max(minOffset, Math.min(maxOffset, zoomState.offsetY));
-        }
-
-        // Click/drag anywhere on minimap to pan - jump immediately on mousedown and follow mouse
-        minimapContainer.addEventListener('mousedown', (e) => {
-            if (e.button !== 0) return; // Left click only
-
-            const rect = minimapContainer.getBoundingClientRect();
-            const relX = (e.clientX - rect.left) / rect.width;
-            const relY = (e.clientY - rect.top) / rect.height;
-
-            // Jump to clicked position immediately
-            zoomState.offsetX = relX;
-            zoomState.offsetY = relY;
-            clampOffset();
-            updateZoom();
-
-            // Start dragging from this position
-            isDragging = true;
-            dragStart = { x: e.clientX, y: e.clientY };
-            dragStartOffset = { x: zoomState.offsetX, y: zoomState.offsetY };
-            e.preventDefault();
-        });
-
-        document.addEventListener('mousemove', (e) => {
-            if (!isDragging) return;
-
-            const rect = minimapContainer.getBoundingClientRect();
-
-            const dx = (e.clientX - dragStart.x) / rect.width;
-            const dy = (e.clientY - dragStart.y) / rect.height;
-
-            zoomState.offsetX = dragStartOffset.x + dx;
-            zoomState.offsetY = dragStartOffset.y + dy;
-            clampOffset();
-
-            updateZoom();
-        });
-
-        document.addEventListener('mouseup', () => {
-            if (isDragging) {
-                isDragging = false;
-            }
-        });
-
-        function updateZoom() {
-            // Debounce API calls to prevent overwhelming the server
-            pendingZoomUpdate = true;
-            updateMinimap(); // Update viewport rectangle immediately
-            showZoomIndicator();
-
-            if (zoomUpdateTimeout) return; // Already scheduled
-
-            zoomUpdateTimeout = setTimeout(() => {
-                zoomUpdateTimeout = null;
-                if (!pendingZoomUpdate) return;
-                pendingZoomUpdate = false;
-
-                fetch('/api/stream/zoom', {
-                    method: 'POST',
-                    headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify(zoomState)
-                })
-                .then(r => r.json())
-                .then(data => {
-                    zoomState = data;
-                    updateMinimap();
-                    fetchMinimapThumbnail(); // Fetch unzoomed thumbnail after zoom applied
-                })
-                .catch(console.error);
-            }, 50); // 50ms debounce
-        }
-
-        function resetZoom() {
-            fetch('/api/stream/zoom/reset', { method: 'POST' })
-                .then(r => r.json())
-                .then(data => {
-                    zoomState = data;
-                    updateMinimap();
-                    showZoomIndicator();
-                })
-                .catch(console.error);
-        }
-
-        function updateMinimap() {
-            const isZoomed = zoomState.scale > 1.0;
-            minimap.classList.toggle('visible', isZoomed);
-            zoomLevelSpan.textContent = zoomState.scale.toFixed(1);
-
-            if (isZoomed) {
-                // Update viewport rectangle position
-                const viewportSize = 1.0 / zoomState.scale;
-                const vpWidth = viewportSize * 100;
-                const vpHeight = viewportSize * 100;
-                const vpLeft = (zoomState.offsetX - viewportSize / 2) * 100;
-                const vpTop = (zoomState.offsetY - viewportSize / 2) * 100;
-
-                minimapViewport.style.width = vpWidth + '%';
-                minimapViewport.style.height = vpHeight + '%';
-                minimapViewport.style.left = vpLeft + '%';
-                minimapViewport.style.top = vpTop + '%';
-            }
-        }
-
-        // Fetch unzoomed thumbnail for minimap (separate from updateMinimap to avoid too many requests)
-        function fetchMinimapThumbnail() {
-            if (zoomState.scale <= 1.0) return;
-
-            const ctx = minimapCanvas.getContext('2d');
-            const img = new Image();
-            img.onload = () => {
-                minimapCanvas.width = img.width;
-                minimapCanvas.height = img.height;
-                ctx.drawImage(img, 0, 0);
-            };
-            img.src = '/api/stream/thumbnail?' + Date.now();
-        }
-
-        function showZoomIndicator() {
-            zoomIndicator.textContent = zoomState.scale.toFixed(1) + 'x';
-            zoomIndicator.classList.add('visible');
-
-            clearTimeout(zoomIndicatorTimeout);
-            zoomIndicatorTimeout = setTimeout(() => {
-                zoomIndicator.classList.remove('visible');
-            }, 1000);
-        }
-
-        // Periodically fetch unzoomed thumbnail for minimap
-        setInterval(fetchMinimapThumbnail, 500);
-
-        function toggleStandby() {
-            if (isTransitioning) return;
-            isTransitioning = true;
-
-            const overlay = document.getElementById('fadeOverlay');
-            overlay.classList.add('active');
-
-            overlay.addEventListener('transitionend', function onFadeIn(e) {
-                if (e.propertyName !== 'opacity') return;
-                overlay.removeEventListener('transitionend', onFadeIn);
-
-                fetch('/api/stream/standby', { method: 'POST' })
-                    .then(r => r.json())
-                    .then(data => {
-                        isStandby = data.enabled;
-                        updateButton();
-                        setTimeout(() => {
-                            overlay.classList.remove('active');
-                            isTransitioning = false;
-                        }, 350);
-                    })
-                    .catch(err => {
-                        console.error(err);
-                        overlay.classList.remove('active');
-                        isTransitioning = false;
-                    });
-            });
-        }
-
-        function updateButton() {
-            const btn = document.getElementById('standbyBtn');
-            const tooltip = document.getElementById('tooltip');
-            const cycleButtons = document.getElementById('cycleButtons');
-            if (isStandby) {
-                btn.classList.add('standby');
-                btn.innerHTML = '⏺';
-                tooltip.textContent = 'Resume Stream';
-                cycleButtons.classList.add('visible');
-            } else {
-                btn.classList.remove('standby');
-                btn.innerHTML = '⏸';
-                tooltip.textContent = 'Show Standby';
-                cycleButtons.classList.remove('visible');
-            }
-        }
-
-        function toggleBypass() {
-            fetch('/api/stream/allowlist-bypass', { method: 'POST' })
-                .then(r => r.json())
-                .then(data => {
-                    isBypass = data.enabled;
-                    updateBypassButton();
-                })
-                .catch(console.error);
-        }
-
-        function updateBypassButton() {
-            const btn = document.getElementById('bypassBtn');
-            const tooltip = document.getElementById('bypassTooltip');
-            if (isBypass) {
-                btn.classList.add('active');
-                btn.innerHTML = '🔒';
-                tooltip.textContent = 'Disable Bypass';
-                document.body.classList.add('bypass-active');
-            } else {
-                btn.classList.remove('active');
-                btn.innerHTML = '🔓';
-                tooltip.textContent = 'Enable Bypass';
-                document.body.classList.remove('bypass-active');
-            }
-        }
-
-        let isCycling = false;
-
-        function cyclePlaceholder(direction) {
-            if (isCycling) return;
-            isCycling = true;
-
-            const overlay = document.getElementById('fadeOverlay');
-            overlay.classList.add('active');
-
-            overlay.addEventListener('transitionend', function onFadeIn(e) {
-                if (e.propertyName !== 'opacity') return;
-                overlay.removeEventListener('transitionend', onFadeIn);
-
-                const endpoint = direction === 'next' ? '/api/stream/placeholder/next' : '/api/stream/placeholder/prev';
-                fetch(endpoint, { method: 'POST' })
-                    .then(() => {
-                        setTimeout(() => {
-                            overlay.classList.remove('active');
-                            isCycling = false;
-                        }, 350);
-                    })
-                    .catch(err => {
-                        console.error(err);
-                        overlay.classList.remove('active');
-                        isCycling = false;
-                    });
-            });
-        }
-
-        function cyclePrev() {
-            cyclePlaceholder('prev');
-        }
-
-        function cycleNext() {
-            cyclePlaceholder('next');
-        }
-    </script>
-</body>
-</html>`
-		w.Write([]byte(html))
-	}
-}
-
 // GetStatsHandler returns an HTTP handler that shows stream statistics
 func (m *MJPEGOutput) GetStatsHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1029,11 +432,15 @@ func (m *MJPEGOutput) GetStatsHandler() http.HandlerFunc {
 		var totalClientDrops uint64
 		clientDetails := make([]string, 0, clientCount)
 		for _, stats := range m.clients {
-			totalClientDrops += stats.droppedFrames
+			dropped := atomic.LoadUint64(&stats.droppedFrames)
+			totalClientDrops += dropped
 			clientDetails = append(clientDetails, fmt.Sprintf(
-				"connected %s ago, dropped %d frames",
+				"%s connected %s ago, dropped %d frames, format %s (requested %s)",
+				stats.id,
 				time.Since(stats.connected).Round(time.Second),
-				stats.droppedFrames,
+				dropped,
+				stats.streamFormat(),
+				stats.requestedFormat,
 			))
 		}
 		m.clientsMu.RUnlock()
@@ -1160,6 +567,27 @@ func (m *MJPEGOutput) GetStatsHandler() http.HandlerFunc {
 	}
 }
 
+// GetStatsJSONHandler returns an HTTP handler reporting stream stats as JSON,
+// polled by the viewer pages' FPS overlay
+func (m *MJPEGOutput) GetStatsJSONHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := m.Stats()
+		jpegEncoder := m.JPEGEncoderDevice()
+		if jpegEncoder == "" {
+			jpegEncoder = "software"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"clients":        stats.Clients,
+			"uptime_seconds": int(stats.Uptime.Seconds()),
+			"fps":            stats.FPS,
+			"bitrate_bps":    stats.BitrateBps,
+			"jpeg_encoder":   jpegEncoder,
+		})
+	}
+}
+
 // GetDroppedFrames returns the total number of dropped frames
 func (m *MJPEGOutput) GetDroppedFrames() uint64 {
 	return atomic.LoadUint64(&m.droppedFrames)
@@ -1172,9 +600,76 @@ func (m *MJPEGOutput) GetFrameCount() uint64 {
 	return m.frameCount
 }
 
+// SetOnClientsChanged sets a callback invoked with the client count whenever
+// a client connects or disconnects
+func (m *MJPEGOutput) SetOnClientsChanged(callback func(count int)) {
+	m.clientsMu.Lock()
+	m.onClientsChanged = callback
+	m.clientsMu.Unlock()
+}
+
 // GetClientCount returns the number of connected clients
 func (m *MJPEGOutput) GetClientCount() int {
 	m.clientsMu.RLock()
 	defer m.clientsMu.RUnlock()
 	return len(m.clients)
 }
+
+// Stats returns the connected client count, stream uptime and throughput
+func (m *MJPEGOutput) Stats() Stats {
+	m.mu.RLock()
+	running := m.running
+	startTime := m.startTime
+	m.mu.RUnlock()
+
+	stats := Stats{
+		Clients:    m.GetClientCount(),
+		FPS:        math.Float64frombits(atomic.LoadUint64(&m.fps)),
+		BytesSent:  atomic.LoadUint64(&m.meter.bytesSent),
+		BitrateBps: atomic.LoadUint64(&m.meter.bitrate),
+	}
+	if running && !startTime.IsZero() {
+		stats.Uptime = time.Since(startTime)
+	}
+	return stats
+}
+
+// GetClients returns bandwidth details for each connected client
+func (m *MJPEGOutput) GetClients() []ClientInfo {
+	m.clientsMu.RLock()
+	defer m.clientsMu.RUnlock()
+
+	clients := make([]ClientInfo, 0, len(m.clients))
+	for _, stats := range m.clients {
+		clients = append(clients, ClientInfo{
+			ID:            stats.id,
+			RemoteAddr:    stats.remoteAddr,
+			UserAgent:     stats.userAgent,
+			Format:        stats.streamFormat(),
+			ConnectedAt:   stats.connected,
+			BitrateBps:    atomic.LoadUint64(&stats.meter.bitrate),
+			LimitBps:      atomic.LoadUint64(&stats.limitBps),
+			BytesSent:     atomic.LoadUint64(&stats.meter.bytesSent),
+			DroppedFrames: atomic.LoadUint64(&stats.droppedFrames),
+			LimitedFrames: atomic.LoadUint64(&stats.limitedFrames),
+		})
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ConnectedAt.Before(clients[j].ConnectedAt)
+	})
+	return clients
+}
+
+// GetBandwidthLimits returns the configured total and per-client limits in
+// bits per second (0 = unlimited)
+func (m *MJPEGOutput) GetBandwidthLimits() (total, perClient uint64) {
+	return m.config.MaxBitrate, m.config.ClientMaxBitrate
+}
+
+// clientDrops returns the frames dropped for a client, or 0 for nil
+func clientDrops(stats *clientStats) uint64 {
+	if stats == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&stats.droppedFrames)
+}
@@ -0,0 +1,82 @@
+package output
+
+import (
+	"image"
+	"sync"
+	"testing"
+	"time"
+)
+
+// addTestClient registers a client the way the stream handler does, without
+// anything reading its frames
+func addTestClient(m *MJPEGOutput, format StreamFormat) *clientStats {
+	ch := make(chan streamFrame) // Unbuffered and unread: every frame is dropped
+	stats := &clientStats{
+		id:              newClientID(),
+		frameChan:       ch,
+		connected:       time.Now(),
+		requestedFormat: format,
+	}
+	stats.format.Store(format)
+
+	m.clientsMu.Lock()
+	m.clients[ch] = stats
+	m.clientsMu.Unlock()
+	return stats
+}
+
+// TestLosslessFallbackWhileStatsAreRead switches a slow lossless client back
+// to JPEG while the stats endpoints read the clients; run with -race
+func TestLosslessFallbackWhileStatsAreRead(t *testing.T) {
+	m := NewMJPEGOutput(Config{Width: 64, Height: 36, FPS: 30})
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Stop()
+
+	slow := addTestClient(m, StreamFormatPNG)
+	addTestClient(m, StreamFormatJPEG)
+
+	frame := &Frame{Image: image.NewRGBA(image.Rect(0, 0, 64, 36))}
+	const frames = 50
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, client := range m.GetClients() {
+					if client.Format != StreamFormatPNG && client.Format != StreamFormatJPEG {
+						t.Errorf("client %s has format %q", client.ID, client.Format)
+					}
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= frames; i++ {
+		frame.Sequence = uint64(i)
+		if err := m.WriteFrame(frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	if got := slow.streamFormat(); got != StreamFormatJPEG {
+		t.Errorf("slow lossless client format = %q, want %q", got, StreamFormatJPEG)
+	}
+	if got := clientDrops(slow); got != frames {
+		t.Errorf("slow client dropped %d frames, want %d", got, frames)
+	}
+	if got := m.GetDroppedFrames(); got != 2*frames {
+		t.Errorf("total dropped = %d, want %d", got, 2*frames)
+	}
+}
@@ -0,0 +1,391 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// Multiplexer is an Output that fans frames out to several sinks (MJPEG,
+// recorder, virtual camera, ...). Each sink gets its own copy of the frame
+// and its own goroutine, so a slow, failing, or panicking sink only loses
+// its own frames. A sink that is still busy when the next frame arrives
+// skips that frame.
+//
+// Sinks are started and stopped by whoever created them; disabling a sink
+// only stops feeding it.
+type Multiplexer struct {
+	mu      sync.RWMutex
+	sinks   map[string]*sink
+	running bool
+}
+
+// sink is an output registered with a Multiplexer
+type sink struct {
+	name string
+	out  Output
+
+	mu        sync.Mutex
+	enabled   bool
+	maxFPS    int
+	lastSent  time.Time
+	frames    uint64
+	throttled uint64
+	dropped   uint64
+	errors    uint64
+	failures  int // Consecutive errors
+	lastErr   string
+
+	queue  chan *Frame
+	done   chan struct{}
+	exited chan struct{} // Closed when run returns
+}
+
+// SinkStatus describes a sink registered with a Multiplexer
+type SinkStatus struct {
+	Name      string `json:"name"`
+	Type      string `json:"type"`
+	Enabled   bool   `json:"enabled"`
+	Running   bool   `json:"running"`
+	MaxFPS    int    `json:"max_fps"`   // 0 = every frame
+	Frames    uint64 `json:"frames"`    // Frames written
+	Throttled uint64 `json:"throttled"` // Frames skipped by MaxFPS
+	Dropped   uint64 `json:"dropped"`   // Frames skipped while the sink was still busy
+	Errors    uint64 `json:"errors"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// NewMultiplexer creates a multiplexer with no sinks
+func NewMultiplexer() *Multiplexer {
+	return &Multiplexer{sinks: make(map[string]*sink)}
+}
+
+// Add registers an enabled sink under name, limited to maxFPS frames per
+// second (0 = every frame)
+func (m *Multiplexer) Add(name string, out Output, maxFPS int) error {
+	if maxFPS < 0 {
+		return fmt.Errorf("max FPS must not be negative")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.sinks[name]; exists {
+		return fmt.Errorf("output %q already registered", name)
+	}
+
+	s := &sink{name: name, out: out, enabled: true, maxFPS: maxFPS}
+	m.sinks[name] = s
+	if m.running {
+		s.start()
+	}
+
+	logger.WithComponent("outputs").Info().
+		Str("output", name).
+		Str("type", out.Name()).
+		Int("max_fps", maxFPS).
+		Msg("Output registered")
+	return nil
+}
+
+// Remove unregisters a sink
+func (m *Multiplexer) Remove(name string) {
+	m.mu.Lock()
+	s, exists := m.sinks[name]
+	delete(m.sinks, name)
+	running := m.running
+	m.mu.Unlock()
+
+	if exists && running {
+		s.stop()
+	}
+}
+
+// SetEnabled starts or stops feeding frames to a sink
+func (m *Multiplexer) SetEnabled(name string, enabled bool) error {
+	s, err := m.sink(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.enabled = enabled
+	s.mu.Unlock()
+
+	logger.WithComponent("outputs").Info().
+		Str("output", name).
+		Bool("enabled", enabled).
+		Msg("Output toggled")
+	return nil
+}
+
+// SetMaxFPS limits a sink to fps frames per second (0 = every frame)
+func (m *Multiplexer) SetMaxFPS(name string, fps int) error {
+	if fps < 0 {
+		return fmt.Errorf("max FPS must not be negative")
+	}
+	s, err := m.sink(name)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.maxFPS = fps
+	s.mu.Unlock()
+	return nil
+}
+
+func (m *Multiplexer) sink(name string) (*sink, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, exists := m.sinks[name]
+	if !exists {
+		return nil, fmt.Errorf("unknown output %q", name)
+	}
+	return s, nil
+}
+
+// Sink returns the status of one sink
+func (m *Multiplexer) Sink(name string) (SinkStatus, bool) {
+	s, err := m.sink(name)
+	if err != nil {
+		return SinkStatus{}, false
+	}
+	return s.status(), true
+}
+
+// Sinks returns the status of every sink, by name
+func (m *Multiplexer) Sinks() []SinkStatus {
+	m.mu.RLock()
+	statuses := make([]SinkStatus, 0, len(m.sinks))
+	for _, s := range m.sinks {
+		statuses = append(statuses, s.status())
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// Start begins feeding the registered sinks
+func (m *Multiplexer) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return fmt.Errorf("output multiplexer already running")
+	}
+	for _, s := range m.sinks {
+		s.start()
+	}
+	m.running = true
+	return nil
+}
+
+// Stop stops feeding the sinks. The sinks themselves keep running.
+func (m *Multiplexer) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running {
+		return nil
+	}
+	for _, s := range m.sinks {
+		s.stop()
+	}
+	m.running = false
+	return nil
+}
+
+// WriteFrame queues a copy of the frame for every enabled, running sink that
+// is due a frame
+func (m *Multiplexer) WriteFrame(frame *Frame) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.running {
+		return fmt.Errorf("output multiplexer not running")
+	}
+
+	now := time.Now()
+	for _, s := range m.sinks {
+		if !s.out.IsRunning() || !s.due(now) {
+			continue
+		}
+
+		clone := *frame
+		clone.Image = framepool.Clone(frame.Image)
+		select {
+		case s.queue <- &clone:
+			s.markSent(now)
+		default:
+			framepool.Put(clone.Image)
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// Name returns the output type name
+func (m *Multiplexer) Name() string {
+	return "Output Multiplexer"
+}
+
+// IsRunning returns whether frames are being fanned out
+func (m *Multiplexer) IsRunning() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.running
+}
+
+// Stats combines the stats of the sinks that report viewers
+func (m *Multiplexer) Stats() Stats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var combined Stats
+	for _, s := range m.sinks {
+		reporter, ok := s.out.(StatsReporter)
+		if !ok {
+			continue
+		}
+		stats := reporter.Stats()
+		combined.Clients += stats.Clients
+		combined.Uptime = max(combined.Uptime, stats.Uptime)
+		combined.FPS = max(combined.FPS, stats.FPS)
+		combined.BytesSent += stats.BytesSent
+		combined.BitrateBps += stats.BitrateBps
+	}
+	return combined
+}
+
+// SetQuality sets the encoding quality of every sink that has one
+func (m *Multiplexer) SetQuality(quality int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, s := range m.sinks {
+		if setter, ok := s.out.(QualitySetter); ok {
+			setter.SetQuality(quality)
+		}
+	}
+}
+
+// due reports whether the sink takes a frame now. The frame only counts
+// against MaxFPS once markSent records that the sink's queue accepted it.
+func (s *sink) due(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.enabled {
+		return false
+	}
+	// Allow 10% early so frames arriving with jitter aren't skipped
+	if s.maxFPS > 0 && now.Sub(s.lastSent) < time.Second*9/time.Duration(10*s.maxFPS) {
+		s.throttled++
+		return false
+	}
+	return true
+}
+
+// markSent records that the sink's queue accepted a frame at now
+func (s *sink) markSent(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastSent = now
+}
+
+func (s *sink) start() {
+	s.queue = make(chan *Frame, 1)
+	s.done = make(chan struct{})
+	s.exited = make(chan struct{})
+	go s.run(s.queue, s.done, s.exited)
+}
+
+// stop ends feeding the sink, waiting for a frame being written to finish
+// so the sink can be stopped right after
+func (s *sink) stop() {
+	close(s.done)
+	<-s.exited
+}
+
+// run writes queued frames to the sink until stopped
+func (s *sink) run(queue chan *Frame, done, exited chan struct{}) {
+	defer close(exited)
+	for {
+		select {
+		case <-done:
+			select {
+			case frame := <-queue:
+				framepool.Put(frame.Image)
+			default:
+			}
+			return
+		case frame := <-queue:
+			err := s.write(frame)
+			framepool.Put(frame.Image)
+			s.record(err)
+		}
+	}
+}
+
+// write sends a frame to the sink, turning a panic into an error
+func (s *sink) write(frame *Frame) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("output panicked: %v", r)
+		}
+	}()
+	return s.out.WriteFrame(frame)
+}
+
+func (s *sink) record(err error) {
+	s.mu.Lock()
+	if err == nil {
+		s.frames++
+		s.failures = 0
+		s.mu.Unlock()
+		return
+	}
+	s.errors++
+	s.failures++
+	s.lastErr = err.Error()
+	failures := s.failures
+	s.mu.Unlock()
+
+	// Log at thresholds to avoid a line per frame
+	if failures == 1 || failures == 10 || failures%1000 == 0 {
+		logger.WithComponent("outputs").Warn().
+			Err(err).
+			Str("output", s.name).
+			Int("consecutive_failures", failures).
+			Msg("Output failed to write frame")
+	}
+}
+
+func (s *sink) status() SinkStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return SinkStatus{
+		Name:      s.name,
+		Type:      s.out.Name(),
+		Enabled:   s.enabled,
+		Running:   s.out.IsRunning(),
+		MaxFPS:    s.maxFPS,
+		Frames:    s.frames,
+		Throttled: s.throttled,
+		Dropped:   s.dropped,
+		Errors:    s.errors,
+		LastError: s.lastErr,
+	}
+}
@@ -0,0 +1,246 @@
+package output
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testSink is an Output that records frames, optionally blocking on each
+// write until released or panicking
+type testSink struct {
+	name    string
+	frames  atomic.Int64
+	writing chan struct{} // Receives once per write when set
+	release chan struct{} // Each write waits for it when set
+	panics  bool
+}
+
+func (t *testSink) Start() error    { return nil }
+func (t *testSink) Stop() error     { return nil }
+func (t *testSink) Name() string    { return t.name }
+func (t *testSink) IsRunning() bool { return true }
+
+func (t *testSink) WriteFrame(frame *Frame) error {
+	if frame.Image == nil || frame.Image.Bounds().Dx() != 16 {
+		return fmt.Errorf("frame %d has no copy of the image", frame.Sequence)
+	}
+	if t.writing != nil {
+		t.writing <- struct{}{}
+	}
+	if t.release != nil {
+		<-t.release
+	}
+	if t.panics {
+		panic("sink exploded")
+	}
+	t.frames.Add(1)
+	return nil
+}
+
+func testFrame(sequence uint64) *Frame {
+	return &Frame{Image: image.NewRGBA(image.Rect(0, 0, 16, 9)), Sequence: sequence}
+}
+
+func TestSinkDue(t *testing.T) {
+	start := time.Now()
+	tests := []struct {
+		name    string
+		maxFPS  int
+		enabled bool
+		at      []time.Duration // Frame arrival times after start
+		want    []bool
+	}{
+		{"every frame", 0, true, []time.Duration{0, time.Millisecond, 2 * time.Millisecond}, []bool{true, true, true}},
+		{"disabled", 0, false, []time.Duration{0, time.Second}, []bool{false, false}},
+		{"10 fps", 10, true, []time.Duration{0, 50 * time.Millisecond, 100 * time.Millisecond, 150 * time.Millisecond, 200 * time.Millisecond}, []bool{true, false, true, false, true}},
+		{"10 fps with early jitter", 10, true, []time.Duration{0, 91 * time.Millisecond, 170 * time.Millisecond, 185 * time.Millisecond}, []bool{true, true, false, true}},
+		{"1 fps", 1, true, []time.Duration{0, 500 * time.Millisecond, 899 * time.Millisecond, 900 * time.Millisecond}, []bool{true, false, false, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &sink{enabled: tt.enabled, maxFPS: tt.maxFPS}
+			var throttled uint64
+			for i, at := range tt.at {
+				got := s.due(start.Add(at))
+				if got != tt.want[i] {
+					t.Errorf("frame at %v: due = %v, want %v", at, got, tt.want[i])
+				}
+				if got {
+					s.markSent(start.Add(at))
+				}
+				if tt.enabled && !tt.want[i] {
+					throttled++
+				}
+			}
+			if s.throttled != throttled {
+				t.Errorf("throttled = %d, want %d", s.throttled, throttled)
+			}
+		})
+	}
+}
+
+// TestDroppedFrameDoesNotThrottle checks a frame the full queue turned away
+// doesn't count against MaxFPS, so the sink isn't starved after a drop
+func TestDroppedFrameDoesNotThrottle(t *testing.T) {
+	m := NewMultiplexer()
+	slow := &testSink{name: "slow", writing: make(chan struct{}, 1), release: make(chan struct{})}
+	if err := m.Add(slow.name, slow, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+	s, err := m.sink(slow.name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastSent := func() time.Time {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.lastSent
+	}
+
+	// The sink holds frame 1 and frame 2 waits in its queue
+	m.WriteFrame(testFrame(1))
+	<-slow.writing
+	m.WriteFrame(testFrame(2))
+	queued := lastSent()
+
+	time.Sleep(time.Millisecond)
+	m.WriteFrame(testFrame(3))
+	if got := lastSent(); !got.Equal(queued) {
+		t.Errorf("dropped frame moved lastSent from %v to %v", queued, got)
+	}
+	if status, _ := m.Sink(slow.name); status.Dropped != 1 {
+		t.Errorf("dropped = %d, want 1", status.Dropped)
+	}
+
+	close(slow.release)
+	<-slow.writing
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSlowSinkOnlyLosesItsOwnFrames blocks one sink mid-write and checks
+// the others still get every frame while it drops the rest
+func TestSlowSinkOnlyLosesItsOwnFrames(t *testing.T) {
+	m := NewMultiplexer()
+	slow := &testSink{name: "slow", writing: make(chan struct{}, 1), release: make(chan struct{})}
+	fast := &testSink{name: "fast", writing: make(chan struct{})}
+	broken := &testSink{name: "broken", panics: true}
+	for _, s := range []*testSink{slow, fast, broken} {
+		if err := m.Add(s.name, s, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	const frames = 20
+	for i := uint64(1); i <= frames; i++ {
+		if err := m.WriteFrame(testFrame(i)); err != nil {
+			t.Fatal(err)
+		}
+		<-fast.writing
+		if i == 1 {
+			<-slow.writing // The slow sink holds frame 1; frame 2 waits in its queue
+		}
+	}
+
+	// Let the slow sink finish frame 1 and take frame 2, then stop
+	close(slow.release)
+	<-slow.writing
+	deadline := time.Now().Add(5 * time.Second)
+	for slow.frames.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := make(map[string]SinkStatus)
+	for _, status := range m.Sinks() {
+		statuses[status.Name] = status
+	}
+	if got := fast.frames.Load(); got != frames || statuses["fast"].Frames != frames || statuses["fast"].Dropped != 0 {
+		t.Errorf("fast sink wrote %d frames (status %+v), want all %d", got, statuses["fast"], frames)
+	}
+	if got := slow.frames.Load(); got != 2 || statuses["slow"].Dropped != frames-2 {
+		t.Errorf("slow sink wrote %d frames and dropped %d, want 2 and %d", got, statuses["slow"].Dropped, frames-2)
+	}
+	// Stop may discard a frame still queued for the panicking sink
+	if status := statuses["broken"]; status.Frames != 0 || status.Errors == 0 || status.Errors+status.Dropped < frames-1 || !strings.Contains(status.LastError, "panicked") {
+		t.Errorf("panicking sink status %+v, want every frame an error or dropped", status)
+	}
+}
+
+// TestMultiplexerControlWhileWriting changes sinks while frames are fanned
+// out, as /api/outputs does; run with -race
+func TestMultiplexerControlWhileWriting(t *testing.T) {
+	m := NewMultiplexer()
+	sinks := []*testSink{{name: "a"}, {name: "b"}, {name: "c"}}
+	for _, s := range sinks {
+		if err := m.Add(s.name, s, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := uint64(1); i <= 500; i++ {
+			if err := m.WriteFrame(testFrame(i)); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 200 {
+			m.SetEnabled("a", i%2 == 0)
+			m.SetMaxFPS("b", i%3*30)
+			for _, status := range m.Sinks() {
+				if status.Frames+status.Errors+status.Dropped+status.Throttled > 500 {
+					t.Errorf("sink %s accounted for more frames than were written: %+v", status.Name, status)
+				}
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			name := fmt.Sprintf("extra-%d", i)
+			if err := m.Add(name, &testSink{name: name}, 0); err != nil {
+				t.Error(err)
+			}
+			m.Remove(name)
+		}
+	}()
+	wg.Wait()
+
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(m.Sinks()); got != len(sinks) {
+		t.Errorf("%d sinks left, want %d", got, len(sinks))
+	}
+	if status, _ := m.Sink("c"); status.Frames == 0 || status.Errors != 0 {
+		t.Errorf("untouched sink status %+v, want frames and no errors", status)
+	}
+}
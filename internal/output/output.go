@@ -1,7 +1,8 @@
 package output
 
 import (
-	"image"
+	"fmt"
+	"time"
 )
 
 // Output defines the interface for frame output mechanisms.
@@ -17,9 +18,10 @@ type Output interface {
 	// Stop cleanly shuts down the output
 	Stop() error
 
-	// WriteFrame sends a frame to the output
-	// The image is expected to be in RGBA format
-	WriteFrame(frame *image.RGBA) error
+	// WriteFrame sends a frame to the output, with its timestamp, sequence
+	// number and source window. Implementations must not retain the frame or
+	// its image after returning; the caller may recycle it (see framepool)
+	WriteFrame(frame *Frame) error
 
 	// Name returns a human-readable name for this output type
 	Name() string
@@ -33,4 +35,56 @@ type Config struct {
 	Width  int
 	Height int
 	FPS    int
+
+	// Bandwidth limits in bits per second (0 = unlimited). MaxBitrate is
+	// shared evenly across clients; ClientMaxBitrate caps each client.
+	MaxBitrate       uint64
+	ClientMaxBitrate uint64
+
+	// Tile each client's ID and the time over its frames at this opacity
+	// (0-1), encoding frames per client
+	Watermark        bool
+	WatermarkOpacity float64
+}
+
+// Stats is a snapshot of output activity, used to show viewers on the stream
+type Stats struct {
+	Clients int           // Connected viewers
+	Uptime  time.Duration // Time since the output started (zero when stopped)
+	FPS     float64       // Frames written over the last second
+
+	// Bytes written to all viewers, and their rate in bits per second
+	// measured over the last second
+	BytesSent  uint64
+	BitrateBps uint64
+}
+
+// String formats the stats for on-stream display, e.g. "2 viewers - up 1h05m"
+func (s Stats) String() string {
+	viewers := fmt.Sprintf("%d viewers", s.Clients)
+	if s.Clients == 1 {
+		viewers = "1 viewer"
+	}
+	if s.Uptime <= 0 {
+		return viewers
+	}
+
+	uptime := s.Uptime.Round(time.Minute)
+	hours := int(uptime.Hours())
+	minutes := int(uptime.Minutes()) % 60
+	if hours > 0 {
+		return fmt.Sprintf("%s - up %dh%02dm", viewers, hours, minutes)
+	}
+	return fmt.Sprintf("%s - up %dm", viewers, minutes)
+}
+
+// StatsReporter is implemented by outputs that track connected viewers
+type StatsReporter interface {
+	Stats() Stats
+}
+
+// QualitySetter is implemented by outputs with lossy encoding whose quality
+// (1-100) can be changed while running
+type QualitySetter interface {
+	SetQuality(quality int)
 }
@@ -0,0 +1,37 @@
+package output
+
+import (
+	"embed"
+	"html/template"
+	"os"
+)
+
+// Viewer page templates, rendered with ViewerOptions. Pages are named by file
+// (viewer.html, control.html, embed.html, timeline.html, report.html); stream.html holds
+// shared partials.
+//
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var embeddedTemplates = template.Must(template.ParseFS(templateFS, "templates/*.html"))
+
+// SetTemplateDir serves the viewer pages from dir instead of the embedded
+// copies, re-reading them on every request so edits show up on reload
+func (m *MJPEGOutput) SetTemplateDir(dir string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.templateDir = dir
+}
+
+// pageTemplates returns the embedded templates, or parses them from the
+// template directory when one is set
+func (m *MJPEGOutput) pageTemplates() (*template.Template, error) {
+	m.mu.RLock()
+	dir := m.templateDir
+	m.mu.RUnlock()
+
+	if dir == "" {
+		return embeddedTemplates, nil
+	}
+	return template.ParseFS(os.DirFS(dir), "*.html")
+}
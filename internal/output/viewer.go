@@ -0,0 +1,192 @@
+package output
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// Fit modes for the stream image (CSS object-fit)
+var viewerFits = map[string]bool{
+	"contain": true,
+	"cover":   true,
+	"fill":    true,
+	"none":    true,
+}
+
+var hexColorPattern = regexp.MustCompile(`^([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// basePathPattern accepts path prefixes like /u/alice, without a trailing slash
+var basePathPattern = regexp.MustCompile(`^(/[A-Za-z0-9._-]+)*$`)
+
+// tokenPattern accepts link tokens (base64url payload and signature)
+var tokenPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+$`)
+
+// ForwardedPrefixHeader is set by the session proxy on requests it forwards
+// under /u/<session>/, so pages can link back through the same prefix
+const ForwardedPrefixHeader = "X-Forwarded-Prefix"
+
+// ViewerOptions controls the layout of the viewer pages. They are parsed from
+// the query string, e.g. /embed?nocontrols&fit=cover&bg=000000&fps=overlay
+type ViewerOptions struct {
+	NoControls bool         // Hide the hover navigation
+	Fit        string       // CSS object-fit for the stream image
+	Background string       // Hex color without '#'
+	FPSOverlay bool         // Show the stream frame rate in a corner
+	CropBars   bool         // Crop the black bars of zoomed frames (?crop=bars)
+	Format     StreamFormat // Stream encoding (?format=png for lossless)
+	BasePath   string       // Prefix for links and requests, e.g. /u/alice
+	Token      string       // Link token passed on to the stream, for embeds without cookies
+	Lang       string       // Page language (?lang=, else negotiated from Accept-Language)
+}
+
+// ParseViewerOptions reads viewer options from a query string
+func ParseViewerOptions(query url.Values) (ViewerOptions, error) {
+	opts := ViewerOptions{
+		Fit:        "contain",
+		Background: "000000",
+	}
+
+	_, opts.NoControls = query["nocontrols"]
+
+	if fit := strings.ToLower(query.Get("fit")); fit != "" {
+		if !viewerFits[fit] {
+			return opts, fmt.Errorf("unsupported fit: %s (use contain, cover, fill, or none)", fit)
+		}
+		opts.Fit = fit
+	}
+
+	if bg := strings.TrimPrefix(query.Get("bg"), "#"); bg != "" {
+		if !hexColorPattern.MatchString(bg) {
+			return opts, fmt.Errorf("invalid background color: %s (use hex, e.g. 000000)", bg)
+		}
+		opts.Background = bg
+	}
+
+	switch fps := query.Get("fps"); fps {
+	case "":
+	case "overlay":
+		opts.FPSOverlay = true
+	default:
+		return opts, fmt.Errorf("unsupported fps option: %s (use overlay)", fps)
+	}
+
+	switch crop := query.Get("crop"); crop {
+	case "":
+	case "bars":
+		opts.CropBars = true
+	default:
+		return opts, fmt.Errorf("unsupported crop option: %s (use bars)", crop)
+	}
+
+	format, err := ParseStreamFormat(query.Get("format"))
+	if err != nil {
+		return opts, err
+	}
+	opts.Format = format
+
+	if token := query.Get("token"); token != "" {
+		if !tokenPattern.MatchString(token) {
+			return opts, fmt.Errorf("malformed token")
+		}
+		opts.Token = token
+	}
+
+	// An unsupported ?lang= falls back to the browser's languages
+	opts.Lang = supportedLocale(query.Get("lang"))
+
+	return opts, nil
+}
+
+// StreamURL returns the stream URL for the selected format
+func (o ViewerOptions) StreamURL() string {
+	query := url.Values{}
+	if o.Format == StreamFormatPNG {
+		query.Set("format", string(o.Format))
+	}
+	if o.Token != "" {
+		query.Set("token", o.Token)
+	}
+	if len(query) == 0 {
+		return o.BasePath + "/stream"
+	}
+	return o.BasePath + "/stream?" + query.Encode()
+}
+
+// BackgroundColor returns the background as a CSS color. The value was
+// validated as hex by ParseViewerOptions, so it is safe to mark as CSS.
+func (o ViewerOptions) BackgroundColor() template.CSS {
+	return template.CSS("#" + o.Background)
+}
+
+// servePage renders a viewer page template with options from the request
+func (m *MJPEGOutput) servePage(w http.ResponseWriter, r *http.Request, page string) {
+	opts, err := ParseViewerOptions(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if prefix := r.Header.Get(ForwardedPrefixHeader); basePathPattern.MatchString(prefix) {
+		opts.BasePath = prefix
+	}
+	if opts.Lang == "" {
+		opts.Lang = NegotiateLocale(r.Header.Get("Accept-Language"))
+	}
+
+	tmpl, err := m.pageTemplates()
+	if err != nil {
+		logger.WithComponent("mjpeg").Error().Err(err).Msg("Failed to load page templates")
+		http.Error(w, "failed to load page templates", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Language", opts.Lang)
+	w.Header().Add("Vary", "Accept-Language")
+	if err := tmpl.ExecuteTemplate(w, page+".html", opts); err != nil {
+		logger.WithComponent("mjpeg").Error().Err(err).Str("page", page).Msg("Failed to render page")
+	}
+}
+
+// GetViewerHandler returns an HTTP handler that displays a clean stream viewer with subtle hover nav
+func (m *MJPEGOutput) GetViewerHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.servePage(w, r, "viewer")
+	}
+}
+
+// GetControlHandler returns an HTTP handler that displays the stream with control UI
+func (m *MJPEGOutput) GetControlHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.servePage(w, r, "control")
+	}
+}
+
+// GetTimelineHandler returns an HTTP handler for the session timeline page,
+// which draws /api/timeline
+func (m *MJPEGOutput) GetTimelineHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.servePage(w, r, "timeline")
+	}
+}
+
+// GetReportHandler returns an HTTP handler for the focus report page, which
+// draws /api/stats/focus
+func (m *MJPEGOutput) GetReportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.servePage(w, r, "report")
+	}
+}
+
+// GetEmbedHandler returns an HTTP handler for a minimal page meant to be
+// embedded in an iframe (dashboards, Notion, etc.)
+func (m *MJPEGOutput) GetEmbedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.servePage(w, r, "embed")
+	}
+}
@@ -0,0 +1,61 @@
+package output
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Distance between watermark copies. Rows are staggered by half a column so
+// a crop of any part of the frame still holds a complete copy.
+const (
+	watermarkSpacingX = 320
+	watermarkSpacingY = 120
+)
+
+// newClientID returns a short random ID for a stream client, shown in its
+// watermark and listed by /api/stream/clients
+func newClientID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "00000000"
+	}
+	return hex.EncodeToString(b)
+}
+
+// watermarkFrame returns a copy of frame with the client ID and time tiled
+// across it. The caller owns the result and releases it with framepool.Put.
+func watermarkFrame(frame *image.RGBA, clientID string, now time.Time, opacity float64) *image.RGBA {
+	bounds := frame.Bounds()
+	marked := framepool.Get(bounds.Dx(), bounds.Dy())
+	draw.Draw(marked, marked.Bounds(), frame, bounds.Min, draw.Src)
+
+	alpha := uint8(opacity * 255)
+	text := clientID + " " + now.Format("2006-01-02 15:04:05")
+
+	// A dark shadow under light text keeps it faintly visible on any content
+	d := &font.Drawer{Dst: marked, Face: basicfont.Face7x13}
+	light := image.NewUniform(color.NRGBA{R: 255, G: 255, B: 255, A: alpha})
+	dark := image.NewUniform(color.NRGBA{A: alpha})
+
+	width, height := marked.Bounds().Dx(), marked.Bounds().Dy()
+	for row, y := 0, watermarkSpacingY/2; y < height; row, y = row+1, y+watermarkSpacingY {
+		for x := (row%2)*watermarkSpacingX/2 - watermarkSpacingX/2; x < width; x += watermarkSpacingX {
+			d.Src = dark
+			d.Dot = fixed.P(x+1, y+1)
+			d.DrawString(text)
+			d.Src = light
+			d.Dot = fixed.P(x, y)
+			d.DrawString(text)
+		}
+	}
+	return marked
+}
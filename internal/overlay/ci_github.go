@@ -0,0 +1,238 @@
+package overlay
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// GitHubWorkflowRun represents a simplified GitHub Actions workflow run
+type GitHubWorkflowRun struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`     // queued, in_progress, completed
+	Conclusion string `json:"conclusion"` // success, failure, cancelled, skipped, etc.
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+	Path       string `json:"path"` // Workflow file, e.g. .github/workflows/ci.yml
+}
+
+// GitHubWorkflowRunsResponse represents the GitHub API response
+type GitHubWorkflowRunsResponse struct {
+	TotalCount   int                 `json:"total_count"`
+	WorkflowRuns []GitHubWorkflowRun `json:"workflow_runs"`
+}
+
+// GitHubCheckRun is one check run of a commit
+type GitHubCheckRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`     // queued, in_progress, completed
+	Conclusion string `json:"conclusion"` // success, failure, neutral, skipped, etc.
+}
+
+// GitHubCheckRunsResponse represents the GitHub check runs API response
+type GitHubCheckRunsResponse struct {
+	TotalCount int              `json:"total_count"`
+	CheckRuns  []GitHubCheckRun `json:"check_runs"`
+}
+
+// gitHubPullRequest is the part of a pull request the widget needs
+type gitHubPullRequest struct {
+	Head struct {
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// gitHubRunsPerPage is how many recent runs are searched for the widget's
+// named workflows
+const gitHubRunsPerPage = 100
+
+// gitHubProvider reports GitHub Actions workflow runs and pull request
+// checks
+type gitHubProvider struct {
+	ciClient
+	owner  string
+	repo   string
+	branch string // Optional: filter by branch
+	token  string // Optional: GitHub token for private repos
+
+	// Optional: a row per named workflow (by name or file, e.g. ci.yml)
+	// and a summary of a pull request's checks
+	workflows []string
+	pr        int
+	prChecks  *CIChecks // Last fetched, shown again if a fetch fails
+}
+
+// Name returns the provider name
+func (p *gitHubProvider) Name() string {
+	return "github"
+}
+
+// Label returns owner/repo, with the branch if set
+func (p *gitHubProvider) Label() string {
+	label := fmt.Sprintf("%s/%s", p.owner, p.repo)
+	if p.branch != "" {
+		label = fmt.Sprintf("%s:%s", label, p.branch)
+	}
+	return label
+}
+
+// UpdateConfig applies the GitHub fields of the widget config
+func (p *gitHubProvider) UpdateConfig(config map[string]interface{}) error {
+	if owner, ok := config["owner"].(string); ok {
+		p.owner = owner
+	}
+
+	if repo, ok := config["repo"].(string); ok {
+		p.repo = repo
+	}
+
+	if branch, ok := config["branch"].(string); ok {
+		p.branch = branch
+	}
+
+	if token, ok := config["token"].(string); ok {
+		p.token = token
+	}
+
+	if workflows, ok := configStrings(config, "workflows"); ok {
+		p.workflows = workflows
+	}
+
+	if pr, ok := config["pr"].(float64); ok {
+		p.pr = int(pr)
+	} else if pr, ok := config["pr"].(int); ok {
+		p.pr = pr
+	}
+
+	return nil
+}
+
+// GetConfig returns the GitHub fields of the widget config (not the token)
+func (p *gitHubProvider) GetConfig() map[string]interface{} {
+	config := map[string]interface{}{
+		"owner":  p.owner,
+		"repo":   p.repo,
+		"branch": p.branch,
+	}
+	if len(p.workflows) > 0 {
+		config["workflows"] = p.workflows
+	}
+	if p.pr > 0 {
+		config["pr"] = p.pr
+	}
+	return config
+}
+
+// Validate requires the owner and repo
+func (p *gitHubProvider) Validate() error {
+	if p.owner == "" || p.repo == "" {
+		return fmt.Errorf("github widget requires 'owner' and 'repo' fields")
+	}
+	return nil
+}
+
+// Fetch fetches the latest workflow runs, and the pull request's checks if
+// one is set, from the GitHub API
+func (p *gitHubProvider) Fetch() (CIStatus, error) {
+	// One page of recent runs covers the named workflows
+	perPage := 1
+	if len(p.workflows) > 0 {
+		perPage = gitHubRunsPerPage
+	}
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runs?per_page=%d", p.owner, p.repo, perPage)
+	if p.branch != "" {
+		url += fmt.Sprintf("&branch=%s", p.branch)
+	}
+
+	var apiResp GitHubWorkflowRunsResponse
+	if err := p.getJSON(url, p.header(), &apiResp); err != nil {
+		return CIStatus{}, err
+	}
+
+	var status CIStatus
+	if len(apiResp.WorkflowRuns) > 0 {
+		run := apiResp.WorkflowRuns[0]
+		status.Latest = CIRun{Name: run.Name, Status: run.Status, Conclusion: run.Conclusion}
+	} else {
+		status.Latest = CIRun{Status: "no_runs"}
+	}
+
+	for _, name := range p.workflows {
+		status.Runs = append(status.Runs, latestWorkflowRun(apiResp.WorkflowRuns, name))
+	}
+
+	if p.pr > 0 {
+		checks, err := p.fetchPRChecks()
+		if err != nil {
+			logger.WithComponent("overlay").Info().Msgf("[CIWidget] Failed to fetch PR #%d checks: %v", p.pr, err)
+
+			// Keep showing the last checks of the same pull request
+			checks = &CIChecks{Label: p.prLabel(), Unknown: true}
+			if p.prChecks != nil && p.prChecks.Label == checks.Label {
+				checks = p.prChecks
+			}
+		}
+		p.prChecks = checks
+		status.Checks = checks
+	}
+
+	return status, nil
+}
+
+// latestWorkflowRun finds the newest run of a workflow, by name or by file
+// (e.g. ci.yml). Runs are listed newest first.
+func latestWorkflowRun(runs []GitHubWorkflowRun, workflow string) CIRun {
+	for _, run := range runs {
+		if strings.EqualFold(run.Name, workflow) || path.Base(run.Path) == workflow {
+			return CIRun{Name: workflow, Status: run.Status, Conclusion: run.Conclusion}
+		}
+	}
+	return CIRun{Name: workflow}
+}
+
+// fetchPRChecks counts the check runs of the pull request's head commit
+func (p *gitHubProvider) fetchPRChecks() (*CIChecks, error) {
+	var pr gitHubPullRequest
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", p.owner, p.repo, p.pr)
+	if err := p.getJSON(url, p.header(), &pr); err != nil {
+		return nil, err
+	}
+
+	var checkRuns GitHubCheckRunsResponse
+	url = fmt.Sprintf("https://api.github.com/repos/%s/%s/commits/%s/check-runs?per_page=100", p.owner, p.repo, pr.Head.SHA)
+	if err := p.getJSON(url, p.header(), &checkRuns); err != nil {
+		return nil, err
+	}
+
+	checks := &CIChecks{Label: p.prLabel()}
+	for _, check := range checkRuns.CheckRuns {
+		switch {
+		case check.Status != "completed":
+			checks.Pending++
+		case check.Conclusion == "success" || check.Conclusion == "neutral" || check.Conclusion == "skipped":
+			checks.Passed++
+		default:
+			checks.Failed++
+		}
+	}
+	return checks, nil
+}
+
+// prLabel names the pull request in the checks row
+func (p *gitHubProvider) prLabel() string {
+	return fmt.Sprintf("PR #%d", p.pr)
+}
+
+// header returns the GitHub API request headers
+func (p *gitHubProvider) header() http.Header {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github.v3+json")
+	if p.token != "" {
+		header.Set("Authorization", fmt.Sprintf("token %s", p.token))
+	}
+	return header
+}
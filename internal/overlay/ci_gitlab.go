@@ -0,0 +1,183 @@
+package overlay
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitLabPipeline is the part of a GitLab pipeline the widget needs
+type gitLabPipeline struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+}
+
+// gitLabJob is the part of a GitLab pipeline job the widget needs
+type gitLabJob struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// gitLabProvider reports the latest GitLab pipeline of a project
+type gitLabProvider struct {
+	ciClient
+	baseURL string // Default https://gitlab.com
+	project string // Numeric ID or path, e.g. group/project
+	ref     string // Optional: filter by branch or tag
+	token   string // Optional: access token for private projects
+
+	// Optional: a row per named job of the latest pipeline
+	jobs []string
+}
+
+// Name returns the provider name
+func (p *gitLabProvider) Name() string {
+	return "gitlab"
+}
+
+// Label returns the project, with the ref if set
+func (p *gitLabProvider) Label() string {
+	if p.ref != "" {
+		return fmt.Sprintf("%s:%s", p.project, p.ref)
+	}
+	return p.project
+}
+
+// UpdateConfig applies the GitLab fields of the widget config
+func (p *gitLabProvider) UpdateConfig(config map[string]interface{}) error {
+	if baseURL, ok := config["base_url"].(string); ok {
+		p.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+
+	switch project := config["project"].(type) {
+	case string:
+		p.project = project
+	case float64:
+		p.project = fmt.Sprintf("%d", int64(project))
+	case int:
+		p.project = fmt.Sprintf("%d", project)
+	}
+
+	if ref, ok := config["ref"].(string); ok {
+		p.ref = ref
+	} else if branch, ok := config["branch"].(string); ok {
+		p.ref = branch
+	}
+
+	if token, ok := config["token"].(string); ok {
+		p.token = token
+	}
+
+	if jobs, ok := configStrings(config, "jobs"); ok {
+		p.jobs = jobs
+	}
+
+	return nil
+}
+
+// GetConfig returns the GitLab fields of the widget config (not the token)
+func (p *gitLabProvider) GetConfig() map[string]interface{} {
+	config := map[string]interface{}{
+		"base_url": p.apiBase(),
+		"project":  p.project,
+		"ref":      p.ref,
+	}
+	if len(p.jobs) > 0 {
+		config["jobs"] = p.jobs
+	}
+	return config
+}
+
+// Validate requires the project
+func (p *gitLabProvider) Validate() error {
+	if p.project == "" {
+		return fmt.Errorf("gitlab provider requires a 'project' field (ID or path)")
+	}
+	return nil
+}
+
+// Fetch fetches the latest pipeline, and its named jobs, from the GitLab
+// API
+func (p *gitLabProvider) Fetch() (CIStatus, error) {
+	base := fmt.Sprintf("%s/api/v4/projects/%s", p.apiBase(), url.PathEscape(p.project))
+
+	query := url.Values{"per_page": {"1"}}
+	if p.ref != "" {
+		query.Set("ref", p.ref)
+	}
+	var pipelines []gitLabPipeline
+	if err := p.getJSON(base+"/pipelines?"+query.Encode(), p.header(), &pipelines); err != nil {
+		return CIStatus{}, err
+	}
+
+	var status CIStatus
+	if len(pipelines) == 0 {
+		status.Latest = CIRun{Status: "no_runs"}
+		for _, name := range p.jobs {
+			status.Runs = append(status.Runs, CIRun{Name: name})
+		}
+		return status, nil
+	}
+
+	pipeline := pipelines[0]
+	status.Latest = gitLabRun(pipeline.Status)
+
+	if len(p.jobs) > 0 {
+		var jobs []gitLabJob
+		jobsURL := fmt.Sprintf("%s/pipelines/%d/jobs?per_page=100", base, pipeline.ID)
+		if err := p.getJSON(jobsURL, p.header(), &jobs); err != nil {
+			return CIStatus{}, err
+		}
+		for _, name := range p.jobs {
+			run := CIRun{}
+			for _, job := range jobs {
+				if strings.EqualFold(job.Name, name) {
+					run = gitLabRun(job.Status)
+					break
+				}
+			}
+			run.Name = name
+			status.Runs = append(status.Runs, run)
+		}
+	}
+
+	return status, nil
+}
+
+// gitLabRun maps a GitLab pipeline or job status onto CIRun
+func gitLabRun(status string) CIRun {
+	switch status {
+	case "running":
+		return CIRun{Status: "in_progress"}
+	case "created", "waiting_for_resource", "preparing", "pending", "scheduled":
+		return CIRun{Status: "queued"}
+	case "success":
+		return CIRun{Status: "completed", Conclusion: "success"}
+	case "failed":
+		return CIRun{Status: "completed", Conclusion: "failure"}
+	case "canceled":
+		return CIRun{Status: "completed", Conclusion: "cancelled"}
+	default:
+		// skipped, manual
+		return CIRun{Status: "completed", Conclusion: status}
+	}
+}
+
+// apiBase returns the GitLab instance URL
+func (p *gitLabProvider) apiBase() string {
+	if p.baseURL == "" {
+		return "https://gitlab.com"
+	}
+	return p.baseURL
+}
+
+// header returns the GitLab API request headers
+func (p *gitLabProvider) header() http.Header {
+	header := http.Header{}
+	if p.token != "" {
+		header.Set("PRIVATE-TOKEN", p.token)
+	}
+	return header
+}
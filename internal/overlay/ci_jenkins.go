@@ -0,0 +1,230 @@
+package overlay
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// jenkinsBuild is the part of a Jenkins build the widget needs
+type jenkinsBuild struct {
+	Number   int     `json:"number"`
+	Building bool    `json:"building"`
+	Result   *string `json:"result"` // SUCCESS, FAILURE, UNSTABLE, ABORTED, NOT_BUILT; null while building
+}
+
+// jenkinsCrumb is a CSRF crumb from the Jenkins crumb issuer
+type jenkinsCrumb struct {
+	Crumb             string `json:"crumb"`
+	CrumbRequestField string `json:"crumbRequestField"`
+}
+
+// jenkinsProvider reports the last build of Jenkins jobs
+type jenkinsProvider struct {
+	ciClient
+	url   string // Jenkins URL, e.g. https://ci.example.com
+	job   string // Job shown as the latest run; folders separated by /
+	user  string // Optional: user for API token authentication
+	token string // Optional: API token (or password) of user
+
+	// Send a crumb from the crumb issuer with every request, for servers
+	// that require one even for reads with password authentication
+	crumb       bool
+	crumbHeader http.Header
+
+	// Optional: a row per job, after the single job if both are set
+	jobs []string
+}
+
+// Name returns the provider name
+func (p *jenkinsProvider) Name() string {
+	return "jenkins"
+}
+
+// Label returns the job name, or the Jenkins host if there is a row per
+// job
+func (p *jenkinsProvider) Label() string {
+	if p.job != "" && len(p.jobs) == 0 {
+		return p.job
+	}
+	return strings.TrimPrefix(strings.TrimPrefix(p.url, "https://"), "http://")
+}
+
+// UpdateConfig applies the Jenkins fields of the widget config
+func (p *jenkinsProvider) UpdateConfig(config map[string]interface{}) error {
+	if jenkinsURL, ok := config["url"].(string); ok {
+		p.url = strings.TrimSuffix(jenkinsURL, "/")
+	}
+
+	if job, ok := config["job"].(string); ok {
+		p.job = strings.Trim(job, "/")
+	}
+
+	if jobs, ok := configStrings(config, "jobs"); ok {
+		p.jobs = jobs
+	}
+
+	if user, ok := config["user"].(string); ok {
+		p.user = user
+	}
+
+	if token, ok := config["token"].(string); ok {
+		p.token = token
+	}
+
+	if crumb, ok := config["crumb"].(bool); ok {
+		p.crumb = crumb
+	}
+	p.crumbHeader = nil
+
+	return nil
+}
+
+// GetConfig returns the Jenkins fields of the widget config (not the token)
+func (p *jenkinsProvider) GetConfig() map[string]interface{} {
+	config := map[string]interface{}{
+		"url":  p.url,
+		"job":  p.job,
+		"user": p.user,
+	}
+	if len(p.jobs) > 0 {
+		config["jobs"] = p.jobs
+	}
+	if p.crumb {
+		config["crumb"] = true
+	}
+	return config
+}
+
+// Validate requires the URL and a job
+func (p *jenkinsProvider) Validate() error {
+	if p.url == "" {
+		return fmt.Errorf("jenkins provider requires a 'url' field")
+	}
+	if p.job == "" && len(p.jobs) == 0 {
+		return fmt.Errorf("jenkins provider requires a 'job' or 'jobs' field")
+	}
+	return nil
+}
+
+// Fetch fetches the last build of the job, or of each of the jobs
+func (p *jenkinsProvider) Fetch() (CIStatus, error) {
+	if p.crumb && p.crumbHeader == nil {
+		if err := p.fetchCrumb(); err != nil {
+			return CIStatus{}, fmt.Errorf("failed to get Jenkins crumb: %w", err)
+		}
+	}
+
+	status, err := p.fetchBuilds()
+	if err != nil {
+		// The session may have expired; get a new crumb next time
+		p.crumbHeader = nil
+		return CIStatus{}, err
+	}
+	return status, nil
+}
+
+// fetchBuilds fetches the last build of every configured job. With jobs,
+// the single job gets the first row.
+func (p *jenkinsProvider) fetchBuilds() (CIStatus, error) {
+	var status CIStatus
+	if len(p.jobs) == 0 {
+		run, err := p.lastBuild(p.job)
+		if err != nil {
+			return CIStatus{}, err
+		}
+		status.Latest = run
+		return status, nil
+	}
+
+	jobs := p.jobs
+	if p.job != "" {
+		jobs = append([]string{p.job}, jobs...)
+	}
+	for _, job := range jobs {
+		run, err := p.lastBuild(job)
+		if err != nil {
+			return CIStatus{}, err
+		}
+		run.Name = job
+		status.Runs = append(status.Runs, run)
+	}
+
+	return status, nil
+}
+
+// lastBuild fetches the last build of a job
+func (p *jenkinsProvider) lastBuild(job string) (CIRun, error) {
+	var build jenkinsBuild
+	buildURL := p.url + jenkinsJobPath(job) + "/lastBuild/api/json?tree=number,building,result"
+	if err := p.getJSON(buildURL, p.header(), &build); err != nil {
+		return CIRun{}, err
+	}
+
+	switch {
+	case build.Building:
+		return CIRun{Status: "in_progress"}, nil
+	case build.Result == nil:
+		return CIRun{Status: "queued"}, nil
+	}
+
+	switch *build.Result {
+	case "SUCCESS":
+		return CIRun{Status: "completed", Conclusion: "success"}, nil
+	case "FAILURE":
+		return CIRun{Status: "completed", Conclusion: "failure"}, nil
+	case "ABORTED":
+		return CIRun{Status: "completed", Conclusion: "cancelled"}, nil
+	default:
+		// UNSTABLE, NOT_BUILT
+		return CIRun{Status: "completed", Conclusion: strings.ToLower(*build.Result)}, nil
+	}
+}
+
+// fetchCrumb gets a crumb from the crumb issuer. Crumbs are tied to the
+// session, so the client keeps cookies from then on.
+func (p *jenkinsProvider) fetchCrumb() error {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	p.ciClient.mu.Lock()
+	p.ciClient.client = &http.Client{Timeout: 10 * time.Second, Jar: jar}
+	p.ciClient.mu.Unlock()
+
+	var crumb jenkinsCrumb
+	if err := p.getJSON(p.url+"/crumbIssuer/api/json", p.header(), &crumb); err != nil {
+		return err
+	}
+	p.crumbHeader = http.Header{}
+	p.crumbHeader.Set(crumb.CrumbRequestField, crumb.Crumb)
+	return nil
+}
+
+// jenkinsJobPath turns a job name with folders (team/app) into its URL path
+// (/job/team/job/app)
+func jenkinsJobPath(job string) string {
+	var path strings.Builder
+	for _, part := range strings.Split(strings.Trim(job, "/"), "/") {
+		path.WriteString("/job/")
+		path.WriteString(url.PathEscape(part))
+	}
+	return path.String()
+}
+
+// header returns the Jenkins request headers: basic auth and the crumb
+func (p *jenkinsProvider) header() http.Header {
+	header := http.Header{}
+	if p.user != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.user + ":" + p.token))
+		header.Set("Authorization", "Basic "+credentials)
+	}
+	for key, values := range p.crumbHeader {
+		header[key] = values
+	}
+	return header
+}
@@ -0,0 +1,506 @@
+package overlay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"maps"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// CIProvider fetches build status from a CI service for the CI widget
+type CIProvider interface {
+	// Name is the provider's value of the widget's provider field
+	Name() string
+
+	// Label is the widget's first row, naming the project and branch
+	Label() string
+
+	// UpdateConfig applies the provider's fields of the widget config
+	UpdateConfig(config map[string]interface{}) error
+
+	// GetConfig returns the provider's fields of the widget config
+	GetConfig() map[string]interface{}
+
+	// Validate reports missing required fields
+	Validate() error
+
+	// Fetch returns the current build status
+	Fetch() (CIStatus, error)
+}
+
+// CIRun is a workflow run, pipeline, job or build. Providers map their
+// states onto GitHub's: status is queued, in_progress or completed, and a
+// completed run has a conclusion such as success, failure or cancelled.
+type CIRun struct {
+	Name       string `json:"name,omitempty"`
+	Status     string `json:"status"` // Empty if there are no recent runs
+	Conclusion string `json:"conclusion"`
+}
+
+// CIChecks counts the checks of a pull or merge request
+type CIChecks struct {
+	Label   string `json:"label"`  // e.g. PR #42
+	Passed  int    `json:"passed"` // Including neutral and skipped
+	Failed  int    `json:"failed"`
+	Pending int    `json:"pending"`
+	Unknown bool   `json:"unknown,omitempty"` // Not fetched yet
+}
+
+// CIStatus is what a provider reports for the widget to draw: the latest
+// run, or a row per named run and the checks of a pull request
+type CIStatus struct {
+	Latest CIRun     `json:"latest"`
+	Runs   []CIRun   `json:"runs,omitempty"`
+	Checks *CIChecks `json:"checks,omitempty"`
+}
+
+// ciProviders creates providers by name
+var ciProviders = map[string]func() CIProvider{
+	"github":  func() CIProvider { return &gitHubProvider{} },
+	"gitlab":  func() CIProvider { return &gitLabProvider{} },
+	"jenkins": func() CIProvider { return &jenkinsProvider{} },
+}
+
+// CIWidget displays build status from a CI provider
+type CIWidget struct {
+	*BaseWidget
+	widgetType   string // "ci", or "github-actions" for widgets created before providers
+	provider     CIProvider
+	status       CIStatus
+	lastUpdate   time.Time
+	pollInterval time.Duration
+	mu           sync.RWMutex
+	stopChan     chan struct{}
+	bgColor      color.RGBA
+	padding      int
+}
+
+// NewCIWidget creates a CI status widget. The provider field picks the
+// provider (github by default).
+func NewCIWidget(id string, config map[string]interface{}) (*CIWidget, error) {
+	return newCIWidget("ci", id, config)
+}
+
+// NewGitHubWidget creates a GitHub Actions status widget
+func NewGitHubWidget(id string, config map[string]interface{}) (*CIWidget, error) {
+	config = maps.Clone(config)
+	config["provider"] = "github"
+	return newCIWidget("github-actions", id, config)
+}
+
+func newCIWidget(widgetType, id string, config map[string]interface{}) (*CIWidget, error) {
+	w := &CIWidget{
+		BaseWidget:   NewBaseWidget(id, 0, 0, 1.0),
+		widgetType:   widgetType,
+		provider:     &gitHubProvider{},
+		pollInterval: 60 * time.Second,            // Poll every 60 seconds by default
+		bgColor:      color.RGBA{30, 30, 40, 220}, // Semi-transparent dark background
+		padding:      8,
+		stopChan:     make(chan struct{}),
+	}
+
+	if err := w.UpdateConfig(config); err != nil {
+		return nil, err
+	}
+
+	// Validate required fields
+	if err := w.provider.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Start polling in background
+	go w.pollStatus()
+
+	return w, nil
+}
+
+// Type returns the widget type
+func (w *CIWidget) Type() string {
+	return w.widgetType
+}
+
+// ciRow is one line of the widget
+type ciRow struct {
+	text  string
+	color color.RGBA
+}
+
+// Colors of the widget's status rows
+var (
+	ciGreen  = color.RGBA{46, 160, 67, 255}
+	ciRed    = color.RGBA{203, 36, 49, 255}
+	ciYellow = color.RGBA{219, 154, 4, 255}
+	ciGray   = color.RGBA{158, 158, 158, 255}
+)
+
+// Render draws the CI status widget: the latest run, or one row per named
+// run and a summary of the pull request's checks
+func (w *CIWidget) Render(img *image.RGBA) error {
+	if !w.IsEnabled() {
+		return nil
+	}
+
+	w.mu.RLock()
+	label := w.provider.Label()
+	status := w.status
+	w.mu.RUnlock()
+
+	rows := []ciRow{{text: label, color: color.RGBA{200, 200, 200, 255}}}
+	if len(status.Runs) == 0 && status.Checks == nil {
+		symbol, text, statusColor := runStatusStyle(status.Latest.Status, status.Latest.Conclusion)
+		rows = append(rows, ciRow{text: symbol + " " + text, color: statusColor})
+	}
+	for _, run := range status.Runs {
+		symbol, _, statusColor := runStatusStyle(run.Status, run.Conclusion)
+		rows = append(rows, ciRow{text: symbol + " " + run.Name, color: statusColor})
+	}
+	if status.Checks != nil {
+		rows = append(rows, checksRow(status.Checks))
+	}
+
+	// Measure text
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face}
+
+	maxWidth := 0
+	for _, row := range rows {
+		maxWidth = max(maxWidth, d.MeasureString(row.text).Ceil())
+	}
+
+	// Calculate widget dimensions, one line of text per row
+	widgetWidth := maxWidth + w.padding*2
+	widgetHeight := 13*len(rows) + w.padding*(len(rows)+1)
+
+	// Draw background
+	DrawRectangle(img, w.x, w.y, widgetWidth, widgetHeight, image.NewUniform(color.NRGBA(w.bgColor)), w.opacity)
+
+	for i, row := range rows {
+		rowDrawer := &font.Drawer{
+			Dst:  img,
+			Src:  textSource(row.color, w.opacity),
+			Face: face,
+			Dot:  fixed.P(w.x+w.padding, w.y+(w.padding+13)*(i+1)),
+		}
+		rowDrawer.DrawString(row.text)
+	}
+
+	return nil
+}
+
+// runStatusStyle returns the symbol, label and color for a run or check
+func runStatusStyle(status, conclusion string) (string, string, color.RGBA) {
+	switch status {
+	case "completed":
+		switch conclusion {
+		case "success":
+			return "✓", "Passing", ciGreen
+		case "failure", "timed_out":
+			return "✗", "Failing", ciRed
+		case "cancelled":
+			return "○", "Cancelled", ciGray
+		default:
+			return "○", conclusion, ciGray
+		}
+	case "in_progress":
+		return "●", "Running", ciYellow
+	case "queued", "waiting", "pending", "requested":
+		return "○", "Queued", ciGray
+	default:
+		return "?", "Unknown", ciGray
+	}
+}
+
+// checksRow summarizes a pull request's checks, colored by the worst state
+func checksRow(checks *CIChecks) ciRow {
+	if checks.Unknown {
+		return ciRow{text: "? " + checks.Label, color: ciGray}
+	}
+
+	text := fmt.Sprintf("%s: %d passed", checks.Label, checks.Passed)
+	if checks.Failed > 0 {
+		text += fmt.Sprintf(", %d failed", checks.Failed)
+	}
+	if checks.Pending > 0 {
+		text += fmt.Sprintf(", %d pending", checks.Pending)
+	}
+
+	switch {
+	case checks.Failed > 0:
+		return ciRow{text: "✗ " + text, color: ciRed}
+	case checks.Pending > 0:
+		return ciRow{text: "● " + text, color: ciYellow}
+	case checks.Passed > 0:
+		return ciRow{text: "✓ " + text, color: ciGreen}
+	default:
+		return ciRow{text: fmt.Sprintf("○ %s: no checks", checks.Label), color: ciGray}
+	}
+}
+
+// GetConfig returns the widget configuration
+func (w *CIWidget) GetConfig() map[string]interface{} {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	config := map[string]interface{}{
+		"id":            w.id,
+		"type":          w.Type(),
+		"enabled":       w.enabled,
+		"x":             w.x,
+		"y":             w.y,
+		"opacity":       w.opacity,
+		"provider":      w.provider.Name(),
+		"poll_interval": int(w.pollInterval.Seconds()),
+		"status":        w.status.Latest.Status,
+		"conclusion":    w.status.Latest.Conclusion,
+	}
+	for key, value := range w.provider.GetConfig() {
+		config[key] = value
+	}
+	if len(w.status.Runs) > 0 {
+		config["runs"] = w.status.Runs
+	}
+	if w.status.Checks != nil {
+		config["checks"] = w.status.Checks
+	}
+
+	if !w.lastUpdate.IsZero() {
+		config["last_update"] = w.lastUpdate.Format(time.RFC3339)
+	}
+	w.rulesConfig(config)
+
+	return config
+}
+
+// UpdateConfig updates the widget configuration. Changing the provider
+// starts from a new provider configured with the same map.
+func (w *CIWidget) UpdateConfig(config map[string]interface{}) error {
+	if err := w.updateRules(config); err != nil {
+		return err
+	}
+
+	if name, ok := config["provider"].(string); ok {
+		name = strings.ToLower(name)
+		newProvider, ok := ciProviders[name]
+		if !ok {
+			return fmt.Errorf("unknown CI provider: %s (use github, gitlab or jenkins)", name)
+		}
+		w.mu.Lock()
+		if w.provider.Name() != name {
+			w.provider = newProvider()
+			w.status = CIStatus{}
+		}
+		w.mu.Unlock()
+	}
+
+	w.mu.Lock()
+	err := w.provider.UpdateConfig(config)
+	w.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if x, ok := config["x"].(float64); ok {
+		w.x = int(x)
+	} else if x, ok := config["x"].(int); ok {
+		w.x = x
+	}
+
+	if y, ok := config["y"].(float64); ok {
+		w.y = int(y)
+	} else if y, ok := config["y"].(int); ok {
+		w.y = y
+	}
+
+	if opacity, ok := config["opacity"].(float64); ok {
+		w.SetOpacity(opacity)
+	}
+
+	if enabled, ok := config["enabled"].(bool); ok {
+		w.SetEnabled(enabled)
+	}
+
+	if interval, ok := config["poll_interval"].(float64); ok {
+		w.pollInterval = time.Duration(interval) * time.Second
+	} else if interval, ok := config["poll_interval"].(int); ok {
+		w.pollInterval = time.Duration(interval) * time.Second
+	}
+
+	w.Invalidate()
+	return nil
+}
+
+// pollStatus polls the provider for build status
+func (w *CIWidget) pollStatus() {
+	// Initial fetch
+	if err := w.fetchStatus(); err != nil {
+		logger.WithComponent("overlay").Info().Msgf("[CIWidget %s] Initial fetch failed: %v", w.id, err)
+	}
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopChan:
+			return
+		case <-ticker.C:
+			if err := w.fetchStatus(); err != nil {
+				logger.WithComponent("overlay").Info().Msgf("[CIWidget %s] Failed to fetch status: %v", w.id, err)
+			}
+		}
+	}
+}
+
+// fetchStatus fetches the current status from the provider
+func (w *CIWidget) fetchStatus() error {
+	w.mu.RLock()
+	provider := w.provider
+	w.mu.RUnlock()
+
+	status, err := provider.Fetch()
+	if err != nil {
+		return err
+	}
+
+	// Update status
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The provider was replaced while fetching
+	if w.provider != provider {
+		return nil
+	}
+
+	if fmt.Sprint(status) != fmt.Sprint(w.status) {
+		w.Invalidate()
+		logger.WithComponent("overlay").Info().Msgf("[CIWidget %s] Updated status: %s/%s", w.id, status.Latest.Status, status.Latest.Conclusion)
+	}
+	w.status = status
+	w.lastUpdate = time.Now()
+
+	return nil
+}
+
+// Stop stops the background polling
+func (w *CIWidget) Stop() {
+	close(w.stopChan)
+}
+
+// ciCachedResponse is a response kept for conditional requests. GitHub
+// doesn't count 304 Not Modified responses against the rate limit.
+type ciCachedResponse struct {
+	etag string
+	body []byte
+}
+
+// maxCICachedResponses bounds a provider's ETag cache, which gains an entry
+// for every commit of a pull request that is checked
+const maxCICachedResponses = 16
+
+// ciClient makes a provider's API requests, conditional on the ETag of the
+// last response to the same URL
+type ciClient struct {
+	client    *http.Client
+	mu        sync.Mutex
+	responses map[string]ciCachedResponse // By URL
+}
+
+// getJSON fetches a URL with the given headers and decodes the JSON
+// response, reusing the last response when the server answers 304 Not
+// Modified
+func (c *ciClient) getJSON(url string, header http.Header, v interface{}) error {
+	// Create request
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	c.mu.Lock()
+	if c.client == nil {
+		c.client = &http.Client{Timeout: 10 * time.Second}
+	}
+	client := c.client
+	cached, haveCached := c.responses[url]
+	c.mu.Unlock()
+	if haveCached {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+
+	// Make request
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && haveCached:
+		body = cached.body
+	case resp.StatusCode == http.StatusOK:
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			c.mu.Lock()
+			if c.responses == nil || len(c.responses) >= maxCICachedResponses {
+				c.responses = make(map[string]ciCachedResponse)
+			}
+			c.responses[url] = ciCachedResponse{etag: etag, body: body}
+			c.mu.Unlock()
+		}
+	default:
+		return fmt.Errorf("%s returned status %d", req.URL.Host, resp.StatusCode)
+	}
+
+	// Parse response
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// configStrings reads a list of strings from a widget config field, given
+// as an array or a comma-separated string. ok is false if the field is
+// absent.
+func configStrings(config map[string]interface{}, key string) ([]string, bool) {
+	var values []string
+	switch field := config[key].(type) {
+	case []interface{}:
+		for _, value := range field {
+			if s, ok := value.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	case []string:
+		values = field
+	case string:
+		for _, s := range strings.Split(field, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	default:
+		return nil, false
+	}
+	return values, true
+}
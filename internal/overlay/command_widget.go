@@ -0,0 +1,382 @@
+package overlay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// Limits of the command widget's settings
+const (
+	commandMaxLines    = 20
+	commandMaxBytes    = 64 * 1024
+	commandMaxLineLen  = 200 // Characters; longer lines are cut
+	commandMinInterval = time.Second
+)
+
+// ansiEscape matches terminal escape sequences (colors, cursor movement)
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b[@-_]`)
+
+// commandEnvKeys are the only environment variables commands get, so tokens
+// and session details in the server's environment don't reach them
+var commandEnvKeys = []string{"PATH", "HOME", "LANG", "TZ"}
+
+// CommandWidget runs a shell command on an interval and displays the first
+// lines of its output. The widget names one of the commands in
+// overlay.commands, which only run if the overlay manager allows them
+// (overlay.allow_commands). It reuses TextWidget's styling.
+type CommandWidget struct {
+	*TextWidget
+
+	mu       sync.RWMutex
+	commands map[string]string // overlay.commands; not modified
+	name     string            // Entry of commands
+	command  string            // Run with sh -c
+	interval time.Duration
+	timeout  time.Duration
+	maxLines int
+	maxBytes int
+	lines    []string
+	failed   bool // Last run failed without output; lines holds the error
+	lastRun  time.Time
+	stopChan chan struct{}
+	runNow   chan struct{}
+}
+
+// NewCommandWidget creates a command widget running the entry of commands
+// named by config["command"] and starts running it
+func NewCommandWidget(id string, config map[string]interface{}, commands map[string]string) (*CommandWidget, error) {
+	text, err := NewTextWidget(id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &CommandWidget{
+		TextWidget: text,
+		commands:   commands,
+		interval:   10 * time.Second,
+		timeout:    5 * time.Second,
+		maxLines:   1,
+		maxBytes:   4096,
+		stopChan:   make(chan struct{}),
+		runNow:     make(chan struct{}, 1),
+	}
+	if err := w.UpdateConfig(config); err != nil {
+		return nil, err
+	}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+
+	go w.poll()
+
+	return w, nil
+}
+
+// Type returns the widget type
+func (w *CommandWidget) Type() string {
+	return "command"
+}
+
+// Render draws the output lines, or the error in red if the command failed
+func (w *CommandWidget) Render(img *image.RGBA) error {
+	if !w.IsEnabled() {
+		return nil
+	}
+
+	w.mu.RLock()
+	lines := w.lines
+	failed := w.failed
+	w.mu.RUnlock()
+	if len(lines) == 0 {
+		return nil
+	}
+
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face}
+	maxWidth := 0
+	for _, line := range lines {
+		maxWidth = max(maxWidth, d.MeasureString(line).Ceil())
+	}
+
+	widgetWidth := maxWidth + w.padding*2
+	widgetHeight := w.fontSize*len(lines) + w.padding*(len(lines)+1)
+	if w.bgColor != nil {
+		DrawRectangle(img, w.x, w.y, widgetWidth, widgetHeight, image.NewUniform(color.NRGBA(*w.bgColor)), w.opacity)
+	}
+
+	textColor := w.textColor
+	if failed {
+		textColor = ciRed
+	}
+	src := textSource(textColor, w.opacity)
+	for i, line := range lines {
+		lineDrawer := &font.Drawer{
+			Dst:  img,
+			Src:  src,
+			Face: face,
+			Dot:  fixed.P(w.x+w.padding, w.y+(w.padding+w.fontSize)*(i+1)),
+		}
+		lineDrawer.DrawString(line)
+	}
+
+	return nil
+}
+
+// GetConfig returns the widget configuration (the text is generated)
+func (w *CommandWidget) GetConfig() map[string]interface{} {
+	config := w.TextWidget.GetConfig()
+	config["type"] = w.Type()
+	delete(config, "text")
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	config["command"] = w.name
+	config["interval"] = int(w.interval.Seconds())
+	config["timeout"] = int(w.timeout.Seconds())
+	config["max_lines"] = w.maxLines
+	config["max_bytes"] = w.maxBytes
+	if !w.lastRun.IsZero() {
+		config["last_run"] = w.lastRun.Format(time.RFC3339)
+	}
+	return config
+}
+
+// UpdateConfig updates the widget configuration. A changed command runs
+// at once. Commands are picked by name; a shell command line is refused
+// unless overlay.commands has an entry by that name.
+func (w *CommandWidget) UpdateConfig(config map[string]interface{}) error {
+	name, setName := config["command"].(string)
+	command, found := w.commands[name]
+	if setName && !found {
+		return fmt.Errorf("unknown command %q (command widgets name an entry of overlay.commands)", name)
+	}
+	if _, ok := config["dir"]; ok {
+		return fmt.Errorf("command widgets don't take a 'dir'; cd in the overlay.commands entry instead")
+	}
+
+	if err := w.TextWidget.UpdateConfig(config); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := false
+	if setName && name != w.name {
+		w.name, w.command = name, command
+		changed = true
+	}
+
+	if interval := getInt(config["interval"]); interval > 0 {
+		w.interval = max(time.Duration(interval)*time.Second, commandMinInterval)
+	}
+
+	if timeout := getInt(config["timeout"]); timeout > 0 {
+		w.timeout = time.Duration(timeout) * time.Second
+	}
+
+	if maxLines := getInt(config["max_lines"]); maxLines > 0 {
+		w.maxLines = min(maxLines, commandMaxLines)
+	}
+
+	if maxBytes := getInt(config["max_bytes"]); maxBytes > 0 {
+		w.maxBytes = min(maxBytes, commandMaxBytes)
+	}
+
+	if changed {
+		select {
+		case w.runNow <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Validate requires a command
+func (w *CommandWidget) Validate() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.name == "" {
+		return fmt.Errorf("command widget requires a 'command' field naming an entry of overlay.commands")
+	}
+	return nil
+}
+
+// Stop stops running the command
+func (w *CommandWidget) Stop() {
+	close(w.stopChan)
+}
+
+// poll runs the command every interval, or at once when it changes. Setting
+// the command in NewCommandWidget queues the first run.
+func (w *CommandWidget) poll() {
+	w.mu.RLock()
+	interval := w.interval
+	w.mu.RUnlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stopChan:
+			return
+		case <-ticker.C:
+		case <-w.runNow:
+		}
+
+		w.mu.RLock()
+		if w.interval != interval {
+			interval = w.interval
+			ticker.Reset(interval)
+		}
+		w.mu.RUnlock()
+
+		w.run()
+	}
+}
+
+// run runs the command once and stores its output lines
+func (w *CommandWidget) run() {
+	w.mu.RLock()
+	command, timeout := w.command, w.timeout
+	maxLines, maxBytes := w.maxLines, w.maxBytes
+	w.mu.RUnlock()
+
+	stdout, err := runCommand(command, timeout, maxBytes)
+	lines := outputLines(stdout, maxLines)
+	failed := false
+	if err != nil {
+		logger.WithComponent("overlay").Info().Msgf("[CommandWidget %s] Command failed: %v", w.id, err)
+		// Show the output of commands that exit non-zero, such as grep -c
+		// with no matches; otherwise the error
+		if len(lines) == 0 {
+			lines = []string{cleanLine(err.Error())}
+			failed = true
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if command != w.command {
+		// Replaced while running; the new command is already queued
+		return
+	}
+	if failed != w.failed || !slices.Equal(lines, w.lines) {
+		w.Invalidate()
+	}
+	w.lines = lines
+	w.failed = failed
+	w.lastRun = time.Now()
+}
+
+// runCommand runs command with sh -c and returns up to maxBytes of its
+// stdout. The command runs in its own process group, which is killed when
+// the timeout expires so background children don't outlive it. It starts
+// in the temp directory with only commandEnvKeys set, under resource limits
+// where the platform has them (see commandLimits).
+func runCommand(command string, timeout time.Duration, maxBytes int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stdout := &cappedBuffer{limit: maxBytes}
+	stderr := &cappedBuffer{limit: 512}
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", commandLimits(timeout)+command)
+	cmd.Dir = os.TempDir()
+	cmd.Env = commandEnv()
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	killProcessGroupOnCancel(cmd)
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return stdout.Bytes(), fmt.Errorf("timed out after %v", timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return stdout.Bytes(), fmt.Errorf("%w: %s", err, msg)
+		}
+		return stdout.Bytes(), err
+	}
+	return stdout.Bytes(), nil
+}
+
+// commandEnv returns the commandEnvKeys set in the server's environment
+func commandEnv() []string {
+	var env []string
+	for _, key := range commandEnvKeys {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// cappedBuffer keeps the first limit bytes written and discards the rest,
+// without failing the writer
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+// Write keeps what fits under the limit
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// outputLines returns the first non-empty lines of output, cleaned up for
+// display
+func outputLines(output []byte, maxLines int) []string {
+	var lines []string
+	for _, line := range strings.Split(string(output), "\n") {
+		line = cleanLine(line)
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == maxLines {
+			break
+		}
+	}
+	return lines
+}
+
+// cleanLine strips escape sequences and control characters, expands tabs
+// and cuts long lines
+func cleanLine(line string) string {
+	line = ansiEscape.ReplaceAllString(line, "")
+	line = strings.ReplaceAll(line, "\t", "    ")
+	line = strings.Map(func(r rune) rune {
+		if r < ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, line)
+	line = strings.TrimRight(line, " ")
+	if runes := []rune(line); len(runes) > commandMaxLineLen {
+		line = string(runes[:commandMaxLineLen])
+	}
+	return line
+}
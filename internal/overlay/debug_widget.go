@@ -0,0 +1,76 @@
+package overlay
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+// DebugWidget displays the metadata of the frame it is drawn on: sequence
+// number, source window, capture latency and how old the frame is by the
+// time overlays render. Its text changes every frame, so the overlay layer
+// is rendered again on every frame while it is visible.
+type DebugWidget struct {
+	*TextWidget
+	frame func() (output.Frame, bool)
+}
+
+// NewDebugWidget creates a debug widget reading from the given frame source
+func NewDebugWidget(id string, config map[string]interface{}, frame func() (output.Frame, bool)) (*DebugWidget, error) {
+	text, err := NewTextWidget(id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DebugWidget{
+		TextWidget: text,
+		frame:      frame,
+	}, nil
+}
+
+// Type returns the widget type
+func (w *DebugWidget) Type() string {
+	return "debug"
+}
+
+// Revision refreshes the text from the current frame
+func (w *DebugWidget) Revision() uint64 {
+	if frame, ok := w.frame(); ok {
+		w.SetText(formatFrameInfo(frame, time.Now()))
+	} else {
+		w.SetText("")
+	}
+	return w.TextWidget.Revision()
+}
+
+// Render draws the current frame's metadata
+func (w *DebugWidget) Render(img *image.RGBA) error {
+	if _, ok := w.frame(); !ok {
+		return nil
+	}
+	return w.TextWidget.Render(img)
+}
+
+// GetConfig returns the widget configuration (the text is generated)
+func (w *DebugWidget) GetConfig() map[string]interface{} {
+	config := w.TextWidget.GetConfig()
+	config["type"] = w.Type()
+	delete(config, "text")
+	return config
+}
+
+// formatFrameInfo formats frame metadata for the debug widget, e.g.
+// "#1042 firefox | capture 8ms | age 14ms"
+func formatFrameInfo(frame output.Frame, now time.Time) string {
+	source := "standby"
+	if frame.Window != nil {
+		source = frame.Window.Class
+	}
+	text := fmt.Sprintf("#%d %s", frame.Sequence, source)
+	if frame.CaptureLatency > 0 {
+		text += fmt.Sprintf(" | capture %dms", frame.CaptureLatency.Milliseconds())
+	}
+	return text + fmt.Sprintf(" | age %dms", frame.Age(now).Milliseconds())
+}
@@ -3,9 +3,15 @@ package overlay
 import (
 	"fmt"
 	"image"
+	"image/draw"
+	"maps"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
 )
 
 // Manager handles overlay widgets and rendering
@@ -13,13 +19,45 @@ type Manager struct {
 	widgets map[string]Widget
 	mu      sync.RWMutex
 	enabled bool
+
+	// Command widgets run shell commands, so they must be enabled explicitly
+	allowCommands bool
+	commands      map[string]string // overlay.commands, by name
+
+	// Widget groups toggled off together
+	disabledGroups map[string]bool
+
+	// Stream stats for the viewers and network widgets. Separate lock since
+	// widgets read it while Render holds mu.
+	statsMu       sync.RWMutex
+	statsReporter output.StatsReporter
+
+	// Metadata of the frame being rendered, without its image, for the
+	// debug widget
+	frameMu sync.RWMutex
+	frame   output.Frame
+
+	// Widgets pre-rendered into one frame-sized layer, rendered again only
+	// when the widget set, a widget's revision, or the frame size changes
+	layerMu     sync.Mutex
+	layer       *image.RGBA
+	layerKey    []layerEntry
+	layerBounds image.Rectangle // Part of the layer with visible pixels
+}
+
+// layerEntry is one widget's part of the overlay layer's cache key
+type layerEntry struct {
+	id       string
+	revision uint64
+	visible  bool
 }
 
 // NewManager creates a new overlay manager
 func NewManager() *Manager {
 	return &Manager{
-		widgets: make(map[string]Widget),
-		enabled: true,
+		widgets:        make(map[string]Widget),
+		enabled:        true,
+		disabledGroups: make(map[string]bool),
 	}
 }
 
@@ -48,8 +86,8 @@ func (m *Manager) RemoveWidget(id string) error {
 	}
 
 	// Clean up widget resources if needed
-	if ghWidget, ok := widget.(*GitHubWidget); ok {
-		ghWidget.Stop()
+	if stopper, ok := widget.(interface{ Stop() }); ok {
+		stopper.Stop()
 	}
 
 	delete(m.widgets, id)
@@ -104,6 +142,105 @@ func (m *Manager) SetEnabled(enabled bool) {
 	logger.WithComponent("overlay").Info().Msgf("[Overlay] Overlay %s", map[bool]string{true: "enabled", false: "disabled"}[enabled])
 }
 
+// SetAllowCommands sets whether command widgets, which run shell commands,
+// may be created
+func (m *Manager) SetAllowCommands(allow bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.allowCommands = allow
+}
+
+// SetCommands sets the shell commands command widgets may run, by name.
+// Widgets only name one, so the commands themselves only come from here.
+func (m *Manager) SetCommands(commands map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commands = maps.Clone(commands)
+}
+
+// SetGroupEnabled shows or hides every widget in a group
+func (m *Manager) SetGroupEnabled(group string, enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled {
+		delete(m.disabledGroups, group)
+	} else {
+		m.disabledGroups[group] = true
+	}
+	logger.WithComponent("overlay").Info().Msgf("[Overlay] Group %s %s", group, map[bool]string{true: "enabled", false: "disabled"}[enabled])
+}
+
+// SetDisabledGroups replaces the set of hidden groups
+func (m *Manager) SetDisabledGroups(groups []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disabledGroups = make(map[string]bool, len(groups))
+	for _, group := range groups {
+		m.disabledGroups[group] = true
+	}
+}
+
+// ShowOnlyGroups shows the given groups and hides every other group that
+// has widgets
+func (m *Manager) ShowOnlyGroups(groups []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, widget := range m.widgets {
+		if grouped, ok := widget.(Grouped); ok && grouped.Group() != "" {
+			m.disabledGroups[grouped.Group()] = true
+		}
+	}
+	for _, group := range groups {
+		delete(m.disabledGroups, group)
+	}
+	logger.WithComponent("overlay").Info().Msgf("[Overlay] Showing only groups: %v", groups)
+}
+
+// DisabledGroups returns the hidden groups, sorted
+func (m *Manager) DisabledGroups() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	groups := make([]string, 0, len(m.disabledGroups))
+	for group := range m.disabledGroups {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+// GetGroups returns the groups of the current widgets and the hidden groups
+// that have no widgets yet, sorted by name
+func (m *Manager) GetGroups() []GroupInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byName := make(map[string]*GroupInfo)
+	add := func(name string) *GroupInfo {
+		if byName[name] == nil {
+			byName[name] = &GroupInfo{Name: name, Enabled: !m.disabledGroups[name], Widgets: []string{}}
+		}
+		return byName[name]
+	}
+	for id, widget := range m.widgets {
+		if grouped, ok := widget.(Grouped); ok && grouped.Group() != "" {
+			group := add(grouped.Group())
+			group.Widgets = append(group.Widgets, id)
+		}
+	}
+	for name := range m.disabledGroups {
+		add(name)
+	}
+
+	groups := make([]GroupInfo, 0, len(byName))
+	for _, group := range byName {
+		sort.Strings(group.Widgets)
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
 // IsEnabled returns whether the overlay is enabled
 func (m *Manager) IsEnabled() bool {
 	m.mu.RLock()
@@ -111,32 +248,142 @@ func (m *Manager) IsEnabled() bool {
 	return m.enabled
 }
 
-// Render renders all enabled widgets onto the provided image
-func (m *Manager) Render(img *image.RGBA) error {
+// SetStatsReporter sets the source of stream stats for viewers and network
+// widgets
+func (m *Manager) SetStatsReporter(reporter output.StatsReporter) {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+	m.statsReporter = reporter
+}
+
+// stats returns the current stream stats, or false if no reporter is set
+func (m *Manager) stats() (output.Stats, bool) {
+	m.statsMu.RLock()
+	reporter := m.statsReporter
+	m.statsMu.RUnlock()
+
+	if reporter == nil {
+		return output.Stats{}, false
+	}
+	return reporter.Stats(), true
+}
+
+// frameInfo returns the metadata of the frame being rendered, or false
+// before the first frame
+func (m *Manager) frameInfo() (output.Frame, bool) {
+	m.frameMu.RLock()
+	defer m.frameMu.RUnlock()
+	return m.frame, m.frame.Sequence > 0
+}
+
+// Render renders the visible widgets onto the frame's image: those that are
+// enabled, not in a disabled group, and whose visibility rules pass for the
+// stream state. Widgets are drawn into a cached layer that is only rendered
+// again when one of them changes; every frame just composites the layer.
+func (m *Manager) Render(frame *output.Frame, state State) error {
 	if !m.IsEnabled() {
 		return nil
 	}
+	img := frame.Image
+
+	info := *frame
+	info.Image = nil
+	m.frameMu.Lock()
+	m.frame = info
+	m.frameMu.Unlock()
 
 	m.mu.RLock()
 	widgets := make([]Widget, 0, len(m.widgets))
 	for _, widget := range m.widgets {
 		widgets = append(widgets, widget)
 	}
+	disabledGroups := maps.Clone(m.disabledGroups)
 	m.mu.RUnlock()
 
-	// Render each widget (widgets are rendered in arbitrary order for now)
+	// Widgets are drawn in ID order so the layer is the same every time
 	// TODO: Add z-index support for layer ordering in Phase 2
-	for _, widget := range widgets {
-		if widget.IsEnabled() {
-			if err := widget.Render(img); err != nil {
-				logger.WithComponent("overlay").Info().Msgf("[Overlay] Failed to render widget %s: %v", widget.ID(), err)
-			}
+	sort.Slice(widgets, func(i, j int) bool { return widgets[i].ID() < widgets[j].ID() })
+
+	now := time.Now()
+	key := make([]layerEntry, len(widgets))
+	visible := make([]Widget, 0, len(widgets))
+	for i, widget := range widgets {
+		key[i] = layerEntry{id: widget.ID(), revision: widget.Revision(), visible: widgetVisible(widget, disabledGroups, state, now)}
+		if key[i].visible {
+			visible = append(visible, widget)
 		}
 	}
 
+	m.layerMu.Lock()
+	defer m.layerMu.Unlock()
+
+	if m.layer == nil || m.layer.Bounds() != img.Bounds() || !slices.Equal(key, m.layerKey) {
+		m.renderLayer(visible, img.Bounds())
+		m.layerKey = key
+	}
+	if !m.layerBounds.Empty() {
+		draw.Draw(img, m.layerBounds, m.layer, m.layerBounds.Min, draw.Over)
+	}
+
 	return nil
 }
 
+// widgetVisible evaluates a widget's group and visibility rules
+func widgetVisible(widget Widget, disabledGroups map[string]bool, state State, now time.Time) bool {
+	if !widget.IsEnabled() {
+		return false
+	}
+
+	grouped, ok := widget.(Grouped)
+	if !ok {
+		// Widgets without rules are only hidden while paused
+		return !state.Paused
+	}
+	if disabledGroups[grouped.Group()] {
+		return false
+	}
+	return grouped.Visibility().Visible(state, now)
+}
+
+// renderLayer draws the visible widgets into the overlay layer, a single
+// premultiplied-alpha image that frames are composited with using draw.Over.
+// The caller holds layerMu.
+func (m *Manager) renderLayer(widgets []Widget, bounds image.Rectangle) {
+	if m.layer == nil || m.layer.Bounds() != bounds {
+		m.layer = image.NewRGBA(bounds)
+	} else {
+		clear(m.layer.Pix)
+	}
+
+	for _, widget := range widgets {
+		if err := widget.Render(m.layer); err != nil {
+			logger.WithComponent("overlay").Info().Msgf("[Overlay] Failed to render widget %s: %v", widget.ID(), err)
+		}
+	}
+
+	m.layerBounds = visibleBounds(m.layer)
+}
+
+// visibleBounds returns the bounds of a layer's non-transparent pixels
+func visibleBounds(layer *image.RGBA) image.Rectangle {
+	bounds := layer.Bounds()
+	minX, minY, maxX, maxY := bounds.Max.X, bounds.Max.Y, bounds.Min.X, bounds.Min.Y
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		row := layer.Pix[(y-bounds.Min.Y)*layer.Stride:]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if row[(x-bounds.Min.X)*4+3] == 0 {
+				continue
+			}
+			minX, minY = min(minX, x), min(minY, y)
+			maxX, maxY = max(maxX, x+1), max(maxY, y+1)
+		}
+	}
+	if maxX <= minX {
+		return image.Rectangle{}
+	}
+	return image.Rectangle{Min: image.Pt(minX, minY), Max: image.Pt(maxX, maxY)}
+}
+
 // CreateWidget creates a new widget instance from configuration
 func (m *Manager) CreateWidget(widgetType string, id string, config map[string]interface{}) (Widget, error) {
 	var widget Widget
@@ -147,6 +394,24 @@ func (m *Manager) CreateWidget(widgetType string, id string, config map[string]i
 		widget, err = NewTextWidget(id, config)
 	case "github-actions":
 		widget, err = NewGitHubWidget(id, config)
+	case "ci":
+		widget, err = NewCIWidget(id, config)
+	case "viewers":
+		widget, err = NewViewersWidget(id, config, m.stats)
+	case "network":
+		widget, err = NewNetworkWidget(id, config, m.stats)
+	case "sysstats":
+		widget, err = NewSysStatsWidget(id, config)
+	case "debug":
+		widget, err = NewDebugWidget(id, config, m.frameInfo)
+	case "command":
+		m.mu.RLock()
+		allowed, commands := m.allowCommands, m.commands
+		m.mu.RUnlock()
+		if !allowed {
+			return nil, fmt.Errorf("command widgets are disabled (set overlay.allow_commands to run shell commands)")
+		}
+		widget, err = NewCommandWidget(id, config, commands)
 	default:
 		return nil, fmt.Errorf("unknown widget type: %s", widgetType)
 	}
@@ -207,8 +472,8 @@ func (m *Manager) Clear() {
 
 	// Clean up resources for each widget
 	for _, widget := range m.widgets {
-		if ghWidget, ok := widget.(*GitHubWidget); ok {
-			ghWidget.Stop()
+		if stopper, ok := widget.(interface{ Stop() }); ok {
+			stopper.Stop()
 		}
 	}
 
@@ -243,6 +508,8 @@ func (m *Manager) GetAvailableWidgetTypes() []map[string]interface{} {
 				"repo":          "string (required) - GitHub repo name",
 				"branch":        "string (optional) - Filter by branch",
 				"token":         "string (optional) - GitHub token for private repos",
+				"workflows":     "[]string (optional) - Workflow names or files, one status row each",
+				"pr":            "int (optional) - Pull request whose checks to summarize",
 				"x":             "int (position)",
 				"y":             "int (position)",
 				"opacity":       "float (0.0-1.0)",
@@ -250,5 +517,112 @@ func (m *Manager) GetAvailableWidgetTypes() []map[string]interface{} {
 				"poll_interval": "int (seconds, default: 60)",
 			},
 		},
+		{
+			"type":        "ci",
+			"name":        "CI Status",
+			"description": "Display CI/CD status from GitHub Actions, GitLab pipelines or Jenkins jobs",
+			"config_schema": map[string]interface{}{
+				"provider":      "string - github (default), gitlab or jenkins",
+				"owner":         "string (github) - Repo owner",
+				"repo":          "string (github) - Repo name",
+				"branch":        "string (github, optional) - Filter by branch",
+				"workflows":     "[]string (github, optional) - Workflow names or files, one status row each",
+				"pr":            "int (github, optional) - Pull request whose checks to summarize",
+				"base_url":      "string (gitlab, optional) - GitLab instance, default https://gitlab.com",
+				"project":       "string (gitlab) - Project ID or path",
+				"ref":           "string (gitlab, optional) - Filter by branch or tag",
+				"url":           "string (jenkins) - Jenkins URL",
+				"job":           "string (jenkins) - Job name, folders separated by /",
+				"jobs":          "[]string (gitlab/jenkins, optional) - Jobs, one status row each",
+				"user":          "string (jenkins, optional) - User for API token auth",
+				"crumb":         "bool (jenkins, optional) - Send a CSRF crumb with requests",
+				"token":         "string (optional) - Access token (GitHub, GitLab) or API token (Jenkins)",
+				"x":             "int (position)",
+				"y":             "int (position)",
+				"opacity":       "float (0.0-1.0)",
+				"enabled":       "bool",
+				"poll_interval": "int (seconds, default: 60)",
+			},
+		},
+		{
+			"type":        "viewers",
+			"name":        "Viewer Count",
+			"description": "Display connected viewers and stream uptime",
+			"config_schema": map[string]interface{}{
+				"x":          "int (position)",
+				"y":          "int (position)",
+				"opacity":    "float (0.0-1.0)",
+				"enabled":    "bool",
+				"color":      "object {r, g, b, a}",
+				"background": "object {r, g, b, a} (optional)",
+				"padding":    "int",
+			},
+		},
+		{
+			"type":        "network",
+			"name":        "Network Speed",
+			"description": "Display the stream's outbound bitrate and network interface throughput",
+			"config_schema": map[string]interface{}{
+				"interface":  "string (optional) - Interface to show, e.g. eth0, or auto for the default route's",
+				"x":          "int (position)",
+				"y":          "int (position)",
+				"opacity":    "float (0.0-1.0)",
+				"enabled":    "bool",
+				"color":      "object {r, g, b, a}",
+				"background": "object {r, g, b, a} (optional)",
+				"padding":    "int",
+			},
+		},
+		{
+			"type":        "sysstats",
+			"name":        "System Stats",
+			"description": "Display CPU usage, memory, load average and temperature",
+			"config_schema": map[string]interface{}{
+				"style":      "string - text (default) or bars",
+				"fields":     "[]string - cpu, mem, load, temp (default: all)",
+				"sensor":     "string (optional) - hwmon driver for temp, e.g. coretemp, k10temp",
+				"interval":   "int (seconds, default: 2)",
+				"x":          "int (position)",
+				"y":          "int (position)",
+				"opacity":    "float (0.0-1.0)",
+				"enabled":    "bool",
+				"color":      "object {r, g, b, a}",
+				"background": "object {r, g, b, a} (optional)",
+				"padding":    "int",
+			},
+		},
+		{
+			"type":        "debug",
+			"name":        "Frame Debug",
+			"description": "Display each frame's sequence number, source window, capture latency and age",
+			"config_schema": map[string]interface{}{
+				"x":          "int (position)",
+				"y":          "int (position)",
+				"opacity":    "float (0.0-1.0)",
+				"enabled":    "bool",
+				"color":      "object {r, g, b, a}",
+				"background": "object {r, g, b, a} (optional)",
+				"padding":    "int",
+			},
+		},
+		{
+			"type":        "command",
+			"name":        "Shell Command",
+			"description": "Display the output of a shell command from overlay.commands run on an interval (requires overlay.allow_commands)",
+			"config_schema": map[string]interface{}{
+				"command":    "string (required) - Name of an entry of overlay.commands",
+				"interval":   "int (seconds, default: 10)",
+				"timeout":    "int (seconds, default: 5)",
+				"max_lines":  "int (default: 1, max: 20)",
+				"max_bytes":  "int (stdout kept, default: 4096, max: 65536)",
+				"x":          "int (position)",
+				"y":          "int (position)",
+				"opacity":    "float (0.0-1.0)",
+				"enabled":    "bool",
+				"color":      "object {r, g, b, a}",
+				"background": "object {r, g, b, a} (optional)",
+				"padding":    "int",
+			},
+		},
 	}
 }
@@ -0,0 +1,191 @@
+package overlay
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+// nicSampleInterval is how often NIC counters are read
+const nicSampleInterval = time.Second
+
+// NetworkWidget displays the stream's outbound bitrate and, optionally, the
+// throughput of a network interface, e.g.
+// "Stream 4.2 Mbps | eth0 up 5.1 Mbps down 120 kbps". It reuses TextWidget's
+// styling and replaces the text on every render.
+type NetworkWidget struct {
+	*TextWidget
+	stats func() (output.Stats, bool)
+	iface string // Interface name, "auto" for the default route's, or empty
+
+	// NIC counters, only touched from Revision (the render goroutine)
+	nicName    string
+	nicRx      uint64
+	nicTx      uint64
+	nicSampled time.Time
+	nicRxBps   uint64
+	nicTxBps   uint64
+}
+
+// NewNetworkWidget creates a network widget reading stream throughput from
+// the given stats source
+func NewNetworkWidget(id string, config map[string]interface{}, stats func() (output.Stats, bool)) (*NetworkWidget, error) {
+	text, err := NewTextWidget(id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &NetworkWidget{
+		TextWidget: text,
+		stats:      stats,
+	}
+	if err := w.UpdateConfig(config); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Type returns the widget type
+func (w *NetworkWidget) Type() string {
+	return "network"
+}
+
+// Revision refreshes the text from the current stats, so the widget counts
+// as changed when a shown rate changes
+func (w *NetworkWidget) Revision() uint64 {
+	var parts []string
+	if stats, ok := w.stats(); ok {
+		parts = append(parts, "Stream "+formatBitrate(stats.BitrateBps))
+	}
+
+	if w.iface != "" {
+		w.sampleNIC(time.Now())
+		if !w.nicSampled.IsZero() {
+			parts = append(parts, fmt.Sprintf("%s up %s down %s", w.nicName, formatBitrate(w.nicTxBps), formatBitrate(w.nicRxBps)))
+		}
+	}
+
+	w.SetText(strings.Join(parts, " | "))
+	return w.TextWidget.Revision()
+}
+
+// GetConfig returns the widget configuration (the text is generated)
+func (w *NetworkWidget) GetConfig() map[string]interface{} {
+	config := w.TextWidget.GetConfig()
+	config["type"] = w.Type()
+	delete(config, "text")
+	if w.iface != "" {
+		config["interface"] = w.iface
+	}
+	return config
+}
+
+// UpdateConfig updates the widget configuration
+func (w *NetworkWidget) UpdateConfig(config map[string]interface{}) error {
+	if err := w.TextWidget.UpdateConfig(config); err != nil {
+		return err
+	}
+
+	if iface, ok := config["interface"].(string); ok {
+		w.iface = iface
+	}
+
+	return nil
+}
+
+// Validate accepts any configuration; the text is generated
+func (w *NetworkWidget) Validate() error {
+	return nil
+}
+
+// sampleNIC updates the interface's rates once per sample interval. The
+// first sample of an interface only records its counters.
+func (w *NetworkWidget) sampleNIC(now time.Time) {
+	if !w.nicSampled.IsZero() && now.Sub(w.nicSampled) < nicSampleInterval {
+		return
+	}
+
+	name := w.iface
+	if name == "auto" {
+		name = defaultRouteInterface()
+	}
+	rx, tx, err := readNICCounters(name)
+	if err != nil {
+		w.nicSampled = time.Time{}
+		return
+	}
+
+	if !w.nicSampled.IsZero() && name == w.nicName {
+		elapsed := now.Sub(w.nicSampled).Seconds()
+		// Counters reset when an interface goes down and up again
+		if rx >= w.nicRx && tx >= w.nicTx {
+			w.nicRxBps = uint64(float64(rx-w.nicRx) * 8 / elapsed)
+			w.nicTxBps = uint64(float64(tx-w.nicTx) * 8 / elapsed)
+		}
+	} else {
+		w.nicRxBps, w.nicTxBps = 0, 0
+	}
+	w.nicName, w.nicRx, w.nicTx, w.nicSampled = name, rx, tx, now
+}
+
+// formatBitrate formats bits per second, e.g. "850 kbps" or "4.2 Mbps"
+func formatBitrate(bps uint64) string {
+	if bps >= 1_000_000 {
+		return fmt.Sprintf("%.1f Mbps", float64(bps)/1_000_000)
+	}
+	return fmt.Sprintf("%d kbps", bps/1000)
+}
+
+// readNICCounters returns the received and transmitted bytes of an
+// interface from /proc/net/dev
+func readNICCounters(name string) (uint64, uint64, error) {
+	f, err := os.Open("/proc/net/dev")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read /proc/net/dev: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		iface, counters, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || strings.TrimSpace(iface) != name {
+			continue
+		}
+		// Receive: bytes packets errs drop fifo frame compressed multicast,
+		// then transmit: bytes ...
+		fields := strings.Fields(counters)
+		if len(fields) < 9 {
+			return 0, 0, fmt.Errorf("unexpected /proc/net/dev format")
+		}
+		rx, _ := strconv.ParseUint(fields[0], 10, 64)
+		tx, _ := strconv.ParseUint(fields[8], 10, 64)
+		return rx, tx, nil
+	}
+	return 0, 0, fmt.Errorf("interface %q not found", name)
+}
+
+// defaultRouteInterface returns the interface of the IPv4 default route from
+// /proc/net/route, or an empty string if there is none
+func defaultRouteInterface() string {
+	f, err := os.Open("/proc/net/route")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Iface Destination Gateway ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[1] == "00000000" {
+			return fields[0]
+		}
+	}
+	return ""
+}
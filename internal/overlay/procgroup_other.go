@@ -0,0 +1,9 @@
+//go:build !unix
+
+package overlay
+
+import "os/exec"
+
+// killProcessGroupOnCancel leaves the default cancellation, which kills
+// only the command itself, on platforms without process groups
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,23 @@
+package overlay
+
+import (
+	"fmt"
+	"time"
+)
+
+// Resource limits of commands run by command widgets
+const (
+	commandMaxMemoryKB  = 2 * 1024 * 1024 // Address space
+	commandMaxFileSize  = 20 * 1024       // Blocks of 512 or 1024 bytes, depending on the shell
+	commandMaxOpenFiles = 64
+)
+
+// commandLimits returns a shell prefix that caps the CPU time, address
+// space, file size and open files of a command and everything it starts.
+// ulimit without -S sets the hard limit too, so the command can't raise
+// them again.
+func commandLimits(timeout time.Duration) string {
+	cpuSeconds := int(timeout.Seconds()) + 1
+	return fmt.Sprintf("ulimit -t %d && ulimit -v %d && ulimit -f %d && ulimit -n %d || exit 126\n",
+		cpuSeconds, commandMaxMemoryKB, commandMaxFileSize, commandMaxOpenFiles)
+}
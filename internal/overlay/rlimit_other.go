@@ -0,0 +1,11 @@
+//go:build !linux
+
+package overlay
+
+import "time"
+
+// commandLimits adds no resource limits where ulimit's options differ
+// between shells; the timeout still applies
+func commandLimits(timeout time.Duration) string {
+	return ""
+}
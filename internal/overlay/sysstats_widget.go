@@ -0,0 +1,446 @@
+package overlay
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// System stats the sysstats widget can show
+const (
+	SysStatCPU    = "cpu"
+	SysStatMemory = "mem"
+	SysStatLoad   = "load"
+	SysStatTemp   = "temp"
+)
+
+// sysStatsBarWidth is the width of each bar in the bars style
+const sysStatsBarWidth = 80
+
+// hwmonPreferred are CPU temperature drivers, tried in order when no sensor
+// is configured
+var hwmonPreferred = []string{"coretemp", "k10temp", "zenpower", "cpu_thermal", "soc_thermal", "acpitz"}
+
+// sysStats is one sample of machine load
+type sysStats struct {
+	CPUPercent float64
+	MemUsed    uint64 // Bytes
+	MemTotal   uint64
+	Load1      float64
+	TempC      float64
+	HasTemp    bool
+}
+
+// SysStatsWidget displays CPU usage, memory, load average and temperature
+// from /proc and hwmon, as one line of text or a block of bars. It reuses
+// TextWidget's styling.
+type SysStatsWidget struct {
+	*TextWidget
+	style  string   // "text" or "bars"
+	fields []string // SysStat constants, in display order
+
+	mu       sync.RWMutex
+	stats    sysStats
+	sensor   string // hwmon driver name; empty picks a CPU sensor
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// NewSysStatsWidget creates a system stats widget and starts sampling
+func NewSysStatsWidget(id string, config map[string]interface{}) (*SysStatsWidget, error) {
+	text, err := NewTextWidget(id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &SysStatsWidget{
+		TextWidget: text,
+		style:      "text",
+		fields:     []string{SysStatCPU, SysStatMemory, SysStatLoad, SysStatTemp},
+		interval:   2 * time.Second,
+		stopChan:   make(chan struct{}),
+	}
+	if err := w.UpdateConfig(config); err != nil {
+		return nil, err
+	}
+
+	go w.sample()
+
+	return w, nil
+}
+
+// Type returns the widget type
+func (w *SysStatsWidget) Type() string {
+	return "sysstats"
+}
+
+// Revision refreshes the text from the latest sample, so the widget counts
+// as changed when a shown value changes
+func (w *SysStatsWidget) Revision() uint64 {
+	w.mu.RLock()
+	stats := w.stats
+	w.mu.RUnlock()
+	w.SetText(w.formatStats(stats))
+	return w.TextWidget.Revision()
+}
+
+// Render draws the stats as text or bars
+func (w *SysStatsWidget) Render(img *image.RGBA) error {
+	if w.style != "bars" {
+		return w.TextWidget.Render(img)
+	}
+	if !w.IsEnabled() || w.text == "" {
+		return nil
+	}
+
+	w.mu.RLock()
+	stats := w.stats
+	w.mu.RUnlock()
+
+	type bar struct {
+		label    string
+		fraction float64
+		value    string
+	}
+	var bars []bar
+	for _, field := range w.fields {
+		switch field {
+		case SysStatCPU:
+			bars = append(bars, bar{"CPU", stats.CPUPercent / 100, fmt.Sprintf("%.0f%%", stats.CPUPercent)})
+		case SysStatMemory:
+			if stats.MemTotal > 0 {
+				bars = append(bars, bar{"RAM", float64(stats.MemUsed) / float64(stats.MemTotal), formatGiB(stats.MemUsed, stats.MemTotal)})
+			}
+		case SysStatLoad:
+			bars = append(bars, bar{"Load", stats.Load1 / float64(runtime.NumCPU()), fmt.Sprintf("%.2f", stats.Load1)})
+		case SysStatTemp:
+			if stats.HasTemp {
+				bars = append(bars, bar{"Temp", stats.TempC / 100, fmt.Sprintf("%.0fC", stats.TempC)})
+			}
+		}
+	}
+
+	face := basicfont.Face7x13
+	d := &font.Drawer{Face: face}
+	labelWidth, valueWidth := 0, 0
+	for _, b := range bars {
+		labelWidth = max(labelWidth, d.MeasureString(b.label).Ceil())
+		valueWidth = max(valueWidth, d.MeasureString(b.value).Ceil())
+	}
+
+	gap := w.padding
+	widgetWidth := w.padding*2 + labelWidth + gap + sysStatsBarWidth + gap + valueWidth
+	widgetHeight := len(bars)*w.fontSize + (len(bars)+1)*w.padding
+	if w.bgColor != nil {
+		DrawRectangle(img, w.x, w.y, widgetWidth, widgetHeight, image.NewUniform(color.NRGBA(*w.bgColor)), w.opacity)
+	}
+
+	textSrc := textSource(w.textColor, w.opacity)
+	for i, b := range bars {
+		top := w.y + w.padding + i*(w.fontSize+w.padding)
+		x := w.x + w.padding
+
+		labelDrawer := &font.Drawer{Dst: img, Src: textSrc, Face: face, Dot: fixed.P(x, top+w.fontSize-2)}
+		labelDrawer.DrawString(b.label)
+		x += labelWidth + gap
+
+		// Track, then the filled part colored by how loaded it is
+		barTop := top + w.fontSize/4
+		barHeight := w.fontSize / 2
+		DrawRectangle(img, x, barTop, sysStatsBarWidth, barHeight, image.NewUniform(color.NRGBA{255, 255, 255, 60}), w.opacity)
+		fraction := min(max(b.fraction, 0), 1)
+		if filled := int(fraction * sysStatsBarWidth); filled > 0 {
+			DrawRectangle(img, x, barTop, filled, barHeight, image.NewUniform(color.NRGBA(loadColor(fraction))), w.opacity)
+		}
+		x += sysStatsBarWidth + gap
+
+		valueDrawer := &font.Drawer{Dst: img, Src: textSrc, Face: face, Dot: fixed.P(x, top+w.fontSize-2)}
+		valueDrawer.DrawString(b.value)
+	}
+
+	return nil
+}
+
+// loadColor is green when lightly loaded, yellow, then red
+func loadColor(fraction float64) color.RGBA {
+	switch {
+	case fraction >= 0.85:
+		return ciRed
+	case fraction >= 0.6:
+		return ciYellow
+	default:
+		return ciGreen
+	}
+}
+
+// GetConfig returns the widget configuration (the text is generated)
+func (w *SysStatsWidget) GetConfig() map[string]interface{} {
+	config := w.TextWidget.GetConfig()
+	config["type"] = w.Type()
+	delete(config, "text")
+	config["style"] = w.style
+	config["fields"] = w.fields
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	config["interval"] = int(w.interval.Seconds())
+	if w.sensor != "" {
+		config["sensor"] = w.sensor
+	}
+	return config
+}
+
+// UpdateConfig updates the widget configuration
+func (w *SysStatsWidget) UpdateConfig(config map[string]interface{}) error {
+	if err := w.TextWidget.UpdateConfig(config); err != nil {
+		return err
+	}
+
+	if style, ok := config["style"].(string); ok {
+		if style != "text" && style != "bars" {
+			return fmt.Errorf("invalid sysstats style: %s (use text or bars)", style)
+		}
+		w.style = style
+	}
+
+	if fields, ok := configStrings(config, "fields"); ok {
+		for _, field := range fields {
+			if !slices.Contains([]string{SysStatCPU, SysStatMemory, SysStatLoad, SysStatTemp}, field) {
+				return fmt.Errorf("invalid sysstats field: %s (use cpu, mem, load, temp)", field)
+			}
+		}
+		w.fields = fields
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if sensor, ok := config["sensor"].(string); ok {
+		w.sensor = sensor
+	}
+
+	if interval := getInt(config["interval"]); interval > 0 {
+		w.interval = time.Duration(interval) * time.Second
+	}
+
+	return nil
+}
+
+// Validate ensures the widget configuration is valid
+func (w *SysStatsWidget) Validate() error {
+	if len(w.fields) == 0 {
+		return fmt.Errorf("sysstats widget requires at least one field")
+	}
+	return nil
+}
+
+// Stop stops sampling
+func (w *SysStatsWidget) Stop() {
+	close(w.stopChan)
+}
+
+// sample reads the stats every interval until stopped. CPU usage is
+// measured between samples, so the first sample shows 0%.
+func (w *SysStatsWidget) sample() {
+	busy, total, _ := readCPUTimes()
+
+	w.mu.RLock()
+	interval := w.interval
+	w.mu.RUnlock()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		w.mu.RLock()
+		sensor := w.sensor
+		if w.interval != interval {
+			interval = w.interval
+			ticker.Reset(interval)
+		}
+		w.mu.RUnlock()
+
+		stats := sysStats{}
+		if nextBusy, nextTotal, err := readCPUTimes(); err == nil {
+			if nextTotal > total {
+				stats.CPUPercent = 100 * float64(nextBusy-busy) / float64(nextTotal-total)
+			}
+			busy, total = nextBusy, nextTotal
+		}
+		stats.MemUsed, stats.MemTotal = readMemory()
+		stats.Load1 = readLoad()
+		stats.TempC, stats.HasTemp = readTemperature(sensor)
+
+		w.mu.Lock()
+		w.stats = stats
+		w.mu.Unlock()
+
+		select {
+		case <-w.stopChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// formatStats renders the stats as one line, e.g.
+// "CPU 23% | RAM 5.1/15.6G | Load 1.24 | 54C". The bars style uses it for
+// change detection only.
+func (w *SysStatsWidget) formatStats(stats sysStats) string {
+	var parts []string
+	for _, field := range w.fields {
+		switch field {
+		case SysStatCPU:
+			parts = append(parts, fmt.Sprintf("CPU %.0f%%", stats.CPUPercent))
+		case SysStatMemory:
+			if stats.MemTotal > 0 {
+				parts = append(parts, "RAM "+formatGiB(stats.MemUsed, stats.MemTotal))
+			}
+		case SysStatLoad:
+			parts = append(parts, fmt.Sprintf("Load %.2f", stats.Load1))
+		case SysStatTemp:
+			if stats.HasTemp {
+				parts = append(parts, fmt.Sprintf("%.0fC", stats.TempC))
+			}
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// formatGiB formats used/total memory in GiB
+func formatGiB(used, total uint64) string {
+	const gib = 1 << 30
+	return fmt.Sprintf("%.1f/%.1fG", float64(used)/gib, float64(total)/gib)
+}
+
+// readCPUTimes returns the busy and total jiffies of all CPUs from
+// /proc/stat
+func readCPUTimes() (uint64, uint64, error) {
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read /proc/stat: %w", err)
+	}
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, fmt.Errorf("unexpected /proc/stat format")
+	}
+
+	var busy, total uint64
+	// user nice system idle iowait irq softirq steal; guest time is already
+	// counted in user
+	for i, field := range fields[1:min(len(fields), 9)] {
+		value, _ := strconv.ParseUint(field, 10, 64)
+		total += value
+		if i != 3 && i != 4 {
+			busy += value
+		}
+	}
+	return busy, total, nil
+}
+
+// readMemory returns used (total minus available) and total memory from
+// /proc/meminfo
+func readMemory() (uint64, uint64) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+
+	var total, available uint64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, _ := strconv.ParseUint(fields[1], 10, 64)
+		switch fields[0] {
+		case "MemTotal:":
+			total = value * 1024
+		case "MemAvailable:":
+			available = value * 1024
+		}
+	}
+	if available > total {
+		return 0, total
+	}
+	return total - available, total
+}
+
+// readLoad returns the one-minute load average from /proc/loadavg
+func readLoad() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, _ := strconv.ParseFloat(fields[0], 64)
+	return load
+}
+
+// readTemperature returns the hottest temperature of a hwmon device: the
+// one whose driver is named sensor, or else the first CPU sensor found
+func readTemperature(sensor string) (float64, bool) {
+	dirs, _ := filepath.Glob("/sys/class/hwmon/hwmon*")
+
+	names := make(map[string]string, len(dirs)) // Driver name by directory
+	for _, dir := range dirs {
+		name, err := os.ReadFile(filepath.Join(dir, "name"))
+		if err == nil {
+			names[dir] = strings.TrimSpace(string(name))
+		}
+	}
+
+	candidates := []string{sensor}
+	if sensor == "" {
+		candidates = hwmonPreferred
+	}
+	for _, want := range candidates {
+		for _, dir := range dirs {
+			if names[dir] != want {
+				continue
+			}
+			if temp, ok := hottestInput(dir); ok {
+				return temp, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// hottestInput returns the highest temp*_input of a hwmon device in °C
+func hottestInput(dir string) (float64, bool) {
+	inputs, _ := filepath.Glob(filepath.Join(dir, "temp*_input"))
+	hottest, found := 0.0, false
+	for _, input := range inputs {
+		data, err := os.ReadFile(input)
+		if err != nil {
+			continue
+		}
+		millidegrees, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil {
+			continue
+		}
+		hottest = max(hottest, float64(millidegrees)/1000)
+		found = true
+	}
+	return hottest, found
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-	"image/draw"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
@@ -43,7 +42,7 @@ func (w *TextWidget) Type() string {
 	return "text"
 }
 
-// Render draws the text widget
+// Render draws the text widget. Colors are straight alpha.
 func (w *TextWidget) Render(img *image.RGBA) error {
 	if !w.IsEnabled() || w.text == "" {
 		return nil
@@ -53,14 +52,8 @@ func (w *TextWidget) Render(img *image.RGBA) error {
 	face := basicfont.Face7x13
 
 	// Measure text dimensions
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(w.textColor),
-		Face: face,
-	}
-
-	textWidth := d.MeasureString(w.text)
-	textWidthPx := int(textWidth >> 6) // Convert from fixed.Int26_6 to pixels
+	d := &font.Drawer{Face: face}
+	textWidthPx := d.MeasureString(w.text).Ceil()
 
 	// Calculate widget dimensions with padding
 	widgetWidth := textWidthPx + w.padding*2
@@ -68,28 +61,19 @@ func (w *TextWidget) Render(img *image.RGBA) error {
 
 	// Draw background if configured
 	if w.bgColor != nil {
-		bgImg := image.NewRGBA(image.Rect(0, 0, widgetWidth, widgetHeight))
-		draw.Draw(bgImg, bgImg.Bounds(), &image.Uniform{*w.bgColor}, image.Point{}, draw.Src)
-		BlendImage(img, bgImg, w.x, w.y, w.opacity)
+		DrawRectangle(img, w.x, w.y, widgetWidth, widgetHeight, image.NewUniform(color.NRGBA(*w.bgColor)), w.opacity)
 	}
 
-	// Draw text
-	textX := w.x + w.padding
-	textY := w.y + w.padding + w.fontSize
-
-	// Create a temporary image for the text with alpha
-	textImg := image.NewRGBA(image.Rect(0, 0, textWidthPx, w.fontSize))
+	// Draw text straight onto the image; glyph edges are blended over
+	// whatever is below them
 	textDrawer := &font.Drawer{
-		Dst:  textImg,
-		Src:  image.NewUniform(w.textColor),
+		Dst:  img,
+		Src:  textSource(w.textColor, w.opacity),
 		Face: face,
-		Dot:  fixed.Point26_6{X: 0, Y: fixed.I(w.fontSize)},
+		Dot:  fixed.P(w.x+w.padding, w.y+w.padding+w.fontSize),
 	}
 	textDrawer.DrawString(w.text)
 
-	// Blend text onto image with opacity
-	BlendImage(img, textImg, textX, textY-w.fontSize, w.opacity)
-
 	return nil
 }
 
@@ -120,12 +104,17 @@ func (w *TextWidget) GetConfig() map[string]interface{} {
 			"a": w.bgColor.A,
 		}
 	}
+	w.rulesConfig(config)
 
 	return config
 }
 
 // UpdateConfig updates the widget configuration
 func (w *TextWidget) UpdateConfig(config map[string]interface{}) error {
+	if err := w.updateRules(config); err != nil {
+		return err
+	}
+
 	if text, ok := config["text"].(string); ok {
 		w.text = text
 	}
@@ -174,6 +163,7 @@ func (w *TextWidget) UpdateConfig(config map[string]interface{}) error {
 		w.bgColor = &color.RGBA{R: r, G: g, B: b, A: a}
 	}
 
+	w.Invalidate()
 	return nil
 }
 
@@ -193,7 +183,10 @@ func getInt(v interface{}) int {
 
 // SetText updates the text content
 func (w *TextWidget) SetText(text string) {
-	w.text = text
+	if text != w.text {
+		w.text = text
+		w.Invalidate()
+	}
 }
 
 // GetText returns the current text
@@ -204,11 +197,13 @@ func (w *TextWidget) GetText() string {
 // SetColor sets the text color
 func (w *TextWidget) SetColor(c color.RGBA) {
 	w.textColor = c
+	w.Invalidate()
 }
 
 // SetBackground sets the background color (nil for transparent)
 func (w *TextWidget) SetBackground(c *color.RGBA) {
 	w.bgColor = c
+	w.Invalidate()
 }
 
 // Validate ensures the widget configuration is valid
@@ -0,0 +1,59 @@
+package overlay
+
+import (
+	"image"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+// ViewersWidget displays the live viewer count and stream uptime. It reuses
+// TextWidget's styling and replaces the text on every render.
+type ViewersWidget struct {
+	*TextWidget
+	stats func() (output.Stats, bool)
+}
+
+// NewViewersWidget creates a viewers widget reading from the given stats source
+func NewViewersWidget(id string, config map[string]interface{}, stats func() (output.Stats, bool)) (*ViewersWidget, error) {
+	text, err := NewTextWidget(id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ViewersWidget{
+		TextWidget: text,
+		stats:      stats,
+	}, nil
+}
+
+// Type returns the widget type
+func (w *ViewersWidget) Type() string {
+	return "viewers"
+}
+
+// Revision refreshes the text from the current stats, so the widget counts
+// as changed when the viewer count or uptime minute changes
+func (w *ViewersWidget) Revision() uint64 {
+	if stats, ok := w.stats(); ok {
+		w.SetText(stats.String())
+	} else {
+		w.SetText("")
+	}
+	return w.TextWidget.Revision()
+}
+
+// Render draws the current viewer count and uptime
+func (w *ViewersWidget) Render(img *image.RGBA) error {
+	if _, ok := w.stats(); !ok {
+		return nil
+	}
+	return w.TextWidget.Render(img)
+}
+
+// GetConfig returns the widget configuration (the text is generated)
+func (w *ViewersWidget) GetConfig() map[string]interface{} {
+	config := w.TextWidget.GetConfig()
+	config["type"] = w.Type()
+	delete(config, "text")
+	return config
+}
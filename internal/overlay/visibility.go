@@ -0,0 +1,163 @@
+package overlay
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// State is what the stream shows, for evaluating widget visibility rules
+type State struct {
+	Standby bool // Showing the standby placeholder
+	Paused  bool // Forced standby or a blocked desktop: only standby-only widgets draw
+	Zoomed  bool // The stream is zoomed in
+}
+
+// Values of the standby and zoomed visibility rules
+const (
+	VisibilityOnly = "only" // Show the widget only in this state
+	VisibilityHide = "hide" // Hide the widget in this state
+)
+
+// weekdays are the day names of visibility rules, indexed by time.Weekday
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// Visibility holds a widget's rules for when it is drawn. All rules must
+// pass; the zero value always shows the widget.
+type Visibility struct {
+	Standby string   // "only" or "hide" on the standby placeholder
+	Zoomed  string   // "only" or "hide" while zoomed in
+	Hours   string   // Local time range, e.g. "09:00-17:00"; wraps past midnight
+	Days    []string // Days of the week, e.g. ["mon", "tue"]
+
+	start, end int // Hours in minutes since midnight
+}
+
+// parseVisibility reads visibility rules from a widget config's
+// "visibility" object
+func parseVisibility(config map[string]interface{}) (Visibility, error) {
+	var v Visibility
+
+	for _, rule := range []struct {
+		key   string
+		value *string
+	}{{"standby", &v.Standby}, {"zoomed", &v.Zoomed}} {
+		value, _ := config[rule.key].(string)
+		if value != "" && value != VisibilityOnly && value != VisibilityHide {
+			return Visibility{}, fmt.Errorf("invalid visibility.%s: %s (use only or hide)", rule.key, value)
+		}
+		*rule.value = value
+	}
+
+	if hours, ok := config["hours"].(string); ok && hours != "" {
+		from, to, ok := strings.Cut(hours, "-")
+		start, err := parseClock(from)
+		if err == nil && ok {
+			v.end, err = parseClock(to)
+		}
+		if err != nil || !ok {
+			return Visibility{}, fmt.Errorf("invalid visibility.hours: %s (use HH:MM-HH:MM)", hours)
+		}
+		v.Hours, v.start = hours, start
+	}
+
+	if days, ok := configStrings(config, "days"); ok {
+		for _, day := range days {
+			day = strings.ToLower(day)
+			if !slices.Contains(weekdays, day) {
+				return Visibility{}, fmt.Errorf("invalid visibility day: %s (use mon, tue, ... sun)", day)
+			}
+			v.Days = append(v.Days, day)
+		}
+	}
+
+	return v, nil
+}
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(clock))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// IsZero reports whether there are no rules
+func (v Visibility) IsZero() bool {
+	return v.Standby == "" && v.Zoomed == "" && v.Hours == "" && len(v.Days) == 0
+}
+
+// Config returns the rules as a widget config "visibility" object
+func (v Visibility) Config() map[string]interface{} {
+	config := map[string]interface{}{}
+	if v.Standby != "" {
+		config["standby"] = v.Standby
+	}
+	if v.Zoomed != "" {
+		config["zoomed"] = v.Zoomed
+	}
+	if v.Hours != "" {
+		config["hours"] = v.Hours
+	}
+	if len(v.Days) > 0 {
+		config["days"] = v.Days
+	}
+	return config
+}
+
+// Visible evaluates the rules for the stream state at the given time. While
+// paused, only widgets shown only on standby are drawn.
+func (v Visibility) Visible(state State, now time.Time) bool {
+	if state.Paused && v.Standby != VisibilityOnly {
+		return false
+	}
+	if !ruleAllows(v.Standby, state.Standby) || !ruleAllows(v.Zoomed, state.Zoomed) {
+		return false
+	}
+
+	if v.Hours != "" {
+		minute := now.Hour()*60 + now.Minute()
+		if v.start <= v.end {
+			if minute < v.start || minute >= v.end {
+				return false
+			}
+		} else if minute < v.start && minute >= v.end {
+			return false
+		}
+	}
+
+	if len(v.Days) > 0 && !slices.Contains(v.Days, weekdays[now.Weekday()]) {
+		return false
+	}
+
+	return true
+}
+
+// ruleAllows applies a standby or zoomed rule to whether the stream is in
+// that state
+func ruleAllows(rule string, active bool) bool {
+	switch rule {
+	case VisibilityOnly:
+		return active
+	case VisibilityHide:
+		return !active
+	default:
+		return true
+	}
+}
+
+// GroupInfo reports a widget group for the API
+type GroupInfo struct {
+	Name    string   `json:"name"`
+	Enabled bool     `json:"enabled"`
+	Widgets []string `json:"widgets"`
+}
+
+// Grouped is implemented by widgets that belong to a group and have
+// visibility rules. BaseWidget implements it.
+type Grouped interface {
+	Group() string
+	Visibility() Visibility
+}
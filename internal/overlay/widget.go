@@ -4,6 +4,8 @@ import (
 	"image"
 	"image/color"
 	"image/draw"
+	"strings"
+	"sync/atomic"
 )
 
 // Widget represents a renderable overlay widget
@@ -28,6 +30,10 @@ type Widget interface {
 
 	// SetEnabled sets whether the widget should be rendered
 	SetEnabled(enabled bool)
+
+	// Revision changes whenever the widget would render differently. The
+	// manager reuses its cached overlay layer until a revision changes.
+	Revision() uint64
 }
 
 // BaseWidget provides common functionality for all widgets
@@ -37,6 +43,12 @@ type BaseWidget struct {
 	x       int
 	y       int
 	opacity float64 // 0.0 to 1.0
+
+	// Optional: group toggled together, and rules for when to draw
+	group      string
+	visibility Visibility
+
+	revision atomic.Uint64
 }
 
 // NewBaseWidget creates a new base widget
@@ -55,6 +67,17 @@ func (w *BaseWidget) ID() string {
 	return w.id
 }
 
+// Revision returns the widget's revision, see Widget.Revision
+func (w *BaseWidget) Revision() uint64 {
+	return w.revision.Load()
+}
+
+// Invalidate marks the widget as changed, so the overlay layer is rendered
+// again
+func (w *BaseWidget) Invalidate() {
+	w.revision.Add(1)
+}
+
 // IsEnabled returns whether the widget should be rendered
 func (w *BaseWidget) IsEnabled() bool {
 	return w.enabled
@@ -63,6 +86,45 @@ func (w *BaseWidget) IsEnabled() bool {
 // SetEnabled sets whether the widget should be rendered
 func (w *BaseWidget) SetEnabled(enabled bool) {
 	w.enabled = enabled
+	w.Invalidate()
+}
+
+// Group returns the widget's group, empty if it has none
+func (w *BaseWidget) Group() string {
+	return w.group
+}
+
+// Visibility returns the widget's visibility rules
+func (w *BaseWidget) Visibility() Visibility {
+	return w.visibility
+}
+
+// updateRules applies the "group" and "visibility" fields of a widget
+// config. A visibility object replaces all rules.
+func (w *BaseWidget) updateRules(config map[string]interface{}) error {
+	if rules, ok := config["visibility"].(map[string]interface{}); ok {
+		visibility, err := parseVisibility(rules)
+		if err != nil {
+			return err
+		}
+		w.visibility = visibility
+	}
+
+	if group, ok := config["group"].(string); ok {
+		w.group = strings.TrimSpace(group)
+	}
+
+	return nil
+}
+
+// rulesConfig adds the group and visibility rules to a widget config
+func (w *BaseWidget) rulesConfig(config map[string]interface{}) {
+	if w.group != "" {
+		config["group"] = w.group
+	}
+	if !w.visibility.IsZero() {
+		config["visibility"] = w.visibility.Config()
+	}
 }
 
 // GetPosition returns the widget's position
@@ -74,6 +136,7 @@ func (w *BaseWidget) GetPosition() (int, int) {
 func (w *BaseWidget) SetPosition(x, y int) {
 	w.x = x
 	w.y = y
+	w.Invalidate()
 }
 
 // GetOpacity returns the widget's opacity
@@ -90,62 +153,43 @@ func (w *BaseWidget) SetOpacity(opacity float64) {
 		opacity = 1.0
 	}
 	w.opacity = opacity
+	w.Invalidate()
 }
 
-// BlendImage blends a source image onto a destination image at the given position
-// with the specified opacity
+// BlendImage composites src over dst at the given position with the given
+// opacity. Both images hold premultiplied alpha, as image.RGBA does; draw
+// straight-alpha colors into src as color.NRGBA.
 func BlendImage(dst *image.RGBA, src image.Image, x, y int, opacity float64) {
-	srcBounds := src.Bounds()
-	dstBounds := dst.Bounds()
-
-	// Calculate intersection to handle clipping
-	for sy := srcBounds.Min.Y; sy < srcBounds.Max.Y; sy++ {
-		dy := y + (sy - srcBounds.Min.Y)
-		if dy < dstBounds.Min.Y || dy >= dstBounds.Max.Y {
-			continue
-		}
-
-		for sx := srcBounds.Min.X; sx < srcBounds.Max.X; sx++ {
-			dx := x + (sx - srcBounds.Min.X)
-			if dx < dstBounds.Min.X || dx >= dstBounds.Max.X {
-				continue
-			}
-
-			// Get source pixel
-			srcColor := src.At(sx, sy)
-			sr, sg, sb, sa := srcColor.RGBA()
-
-			// Apply opacity to source alpha
-			alpha := float64(sa) * opacity / 65535.0
-
-			if alpha > 0 {
-				// Get destination pixel
-				dstColor := dst.At(dx, dy)
-				dr, dg, db, da := dstColor.RGBA()
-
-				// Alpha blending
-				outAlpha := alpha + float64(da)/65535.0*(1-alpha)
-				if outAlpha > 0 {
-					outR := uint8((float64(sr)*alpha + float64(dr)/65535.0*float64(da)/65535.0*(1-alpha)) / outAlpha / 256)
-					outG := uint8((float64(sg)*alpha + float64(dg)/65535.0*float64(da)/65535.0*(1-alpha)) / outAlpha / 256)
-					outB := uint8((float64(sb)*alpha + float64(db)/65535.0*float64(da)/65535.0*(1-alpha)) / outAlpha / 256)
-					outA := uint8(outAlpha * 255)
-
-					dst.SetRGBA(dx, dy, color.RGBA{R: outR, G: outG, B: outB, A: outA})
-				}
-			}
-		}
+	if opacity <= 0 {
+		return
 	}
+
+	srcBounds := src.Bounds()
+	rect := image.Rectangle{Min: image.Pt(x, y), Max: image.Pt(x, y).Add(srcBounds.Size())}
+	draw.DrawMask(dst, rect, src, srcBounds.Min, opacityMask(opacity), image.Point{}, draw.Over)
 }
 
 // DrawRectangle draws a filled rectangle with the specified color and opacity
 func DrawRectangle(dst *image.RGBA, x, y, width, height int, color image.Image, opacity float64) {
+	if opacity <= 0 {
+		return
+	}
+
 	rect := image.Rect(x, y, x+width, y+height)
+	draw.DrawMask(dst, rect, color, image.Point{}, opacityMask(opacity), image.Point{}, draw.Over)
+}
 
-	// Create a temporary image for the rectangle
-	tmp := image.NewRGBA(rect)
-	draw.Draw(tmp, rect, color, image.Point{}, draw.Src)
+// opacityMask returns the draw mask for an opacity, nil (no mask) if opaque
+func opacityMask(opacity float64) image.Image {
+	if opacity >= 1 {
+		return nil
+	}
+	return image.NewUniform(color.Alpha{A: uint8(opacity*0xff + 0.5)})
+}
 
-	// Blend onto destination with opacity
-	BlendImage(dst, tmp, x, y, opacity)
+// textSource returns the source for drawing text in a straight-alpha color
+// at the given opacity
+func textSource(c color.RGBA, opacity float64) image.Image {
+	c.A = uint8(float64(c.A)*min(max(opacity, 0), 1) + 0.5)
+	return image.NewUniform(color.NRGBA(c))
 }
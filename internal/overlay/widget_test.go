@@ -0,0 +1,166 @@
+package overlay
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// solidImage returns a 40x20 image filled with c
+func solidImage(c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+// premultipliedImage returns a 2x2 image of a straight-alpha color, stored
+// premultiplied as image.RGBA holds it
+func premultipliedImage(c color.NRGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+var (
+	black = color.RGBA{0, 0, 0, 255}
+	white = color.RGBA{255, 255, 255, 255}
+	blue  = color.RGBA{0, 0, 255, 255}
+)
+
+// Expected pixels are the straight-alpha blend, result = src*a + dst*(1-a),
+// stored premultiplied; treating premultiplied values as straight (or the
+// reverse) makes them too dark or too bright
+func TestDrawRectangle(t *testing.T) {
+	tests := []struct {
+		name    string
+		dst     *image.RGBA
+		color   color.NRGBA
+		opacity float64
+		want    color.RGBA
+	}{
+		{"opaque color at half opacity", solidImage(blue), color.NRGBA{255, 0, 0, 255}, 0.5, color.RGBA{128, 0, 127, 255}},
+		{"half transparent color", solidImage(black), color.NRGBA{255, 255, 255, 128}, 1, color.RGBA{128, 128, 128, 255}},
+		{"half transparent color at half opacity", solidImage(black), color.NRGBA{255, 255, 255, 128}, 0.5, color.RGBA{64, 64, 64, 255}},
+		{"over transparent", image.NewRGBA(image.Rect(0, 0, 40, 20)), color.NRGBA{255, 0, 0, 255}, 0.5, color.RGBA{128, 0, 0, 128}},
+		{"opaque", solidImage(blue), color.NRGBA{10, 20, 30, 255}, 1, color.RGBA{10, 20, 30, 255}},
+		{"invisible", solidImage(blue), color.NRGBA{255, 0, 0, 255}, 0, blue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outside := tt.dst.RGBAAt(10, 10)
+			DrawRectangle(tt.dst, 0, 0, 4, 4, image.NewUniform(tt.color), tt.opacity)
+
+			if got := tt.dst.RGBAAt(1, 1); got != tt.want {
+				t.Errorf("inside = %v, want %v", got, tt.want)
+			}
+			if got := tt.dst.RGBAAt(10, 10); got != outside {
+				t.Errorf("outside = %v, want it unchanged at %v", got, outside)
+			}
+		})
+	}
+}
+
+func TestBlendImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		dst     *image.RGBA
+		src     image.Image
+		opacity float64
+		want    color.RGBA
+	}{
+		{"half transparent image at half opacity", solidImage(white), premultipliedImage(color.NRGBA{0, 255, 0, 128}), 0.5, color.RGBA{191, 255, 191, 255}},
+		{"opaque image at quarter opacity", solidImage(black), premultipliedImage(color.NRGBA{200, 100, 50, 255}), 0.25, color.RGBA{50, 25, 12, 255}},
+		{"half transparent image", solidImage(black), premultipliedImage(color.NRGBA{255, 255, 255, 128}), 1, color.RGBA{128, 128, 128, 255}},
+		{"opaque image", solidImage(black), premultipliedImage(color.NRGBA{200, 100, 50, 255}), 1, color.RGBA{200, 100, 50, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			BlendImage(tt.dst, tt.src, 3, 3, tt.opacity)
+
+			for _, p := range []image.Point{{3, 3}, {4, 4}} {
+				if got := tt.dst.RGBAAt(p.X, p.Y); got != tt.want {
+					t.Errorf("at %v = %v, want %v", p, got, tt.want)
+				}
+			}
+			if got, want := tt.dst.RGBAAt(2, 2), tt.dst.RGBAAt(10, 10); got != want {
+				t.Errorf("outside = %v, want it unchanged at %v", got, want)
+			}
+		})
+	}
+}
+
+// TestTextEdges draws text color through partial coverage, as font.Drawer
+// does for anti-aliased glyph edges
+func TestTextEdges(t *testing.T) {
+	tests := []struct {
+		name     string
+		color    color.RGBA
+		opacity  float64
+		coverage uint8
+		want     color.RGBA
+	}{
+		{"full coverage", color.RGBA{255, 255, 255, 255}, 1, 255, color.RGBA{255, 255, 255, 255}},
+		{"half coverage", color.RGBA{255, 255, 255, 255}, 1, 128, color.RGBA{128, 128, 128, 255}},
+		{"half coverage at half opacity", color.RGBA{255, 255, 255, 255}, 0.5, 128, color.RGBA{64, 64, 64, 255}},
+		{"half transparent color", color.RGBA{255, 0, 0, 128}, 1, 255, color.RGBA{128, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := solidImage(black)
+			mask := image.NewUniform(color.Alpha{A: tt.coverage})
+			draw.DrawMask(dst, image.Rect(0, 0, 1, 1), textSource(tt.color, tt.opacity), image.Point{}, mask, image.Point{}, draw.Over)
+
+			if got := dst.RGBAAt(0, 0); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestTextWidgetOverColor renders half-opaque white text on a half-opaque
+// navy background over an orange frame
+func TestTextWidgetOverColor(t *testing.T) {
+	w, err := NewTextWidget("text", map[string]interface{}{
+		"text":       "I",
+		"opacity":    0.5,
+		"background": map[string]interface{}{"r": 0, "g": 0, "b": 128, "a": 255},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frame := color.RGBA{200, 100, 0, 255}
+	background := color.RGBA{99, 49, 64, 255} // Navy over orange, half each
+	text := color.RGBA{177, 152, 160, 255}    // White over that, half each
+
+	img := solidImage(frame)
+	if err := w.Render(img); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[color.RGBA]int)
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 40; x++ {
+			counts[img.RGBAAt(x, y)]++
+		}
+	}
+
+	// basicfont glyphs are fully covered or not at all, so only these
+	// three colors may appear
+	want := map[color.RGBA]int{
+		frame:      460,
+		background: 323,
+		text:       17,
+	}
+	for c, n := range counts {
+		if want[c] != n {
+			t.Errorf("%d pixels of %v, want %d", n, c, want[c])
+		}
+	}
+	for c, n := range want {
+		if counts[c] == 0 {
+			t.Errorf("no pixels of %v, want %d", c, n)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pii
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Detector finds one kind of sensitive text in OCR output
+type Detector struct {
+	Name  string
+	match func(text string) bool
+}
+
+// Match reports whether text contains this kind of sensitive data
+func (d Detector) Match(text string) bool {
+	return d.match(text)
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// AWS access key IDs (long-term AKIA, temporary ASIA)
+	awsKeyPattern = regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)
+	// GitHub personal, OAuth, user-to-server, server-to-server, and refresh tokens
+	githubTokenPattern = regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b|\bgithub_pat_[A-Za-z0-9_]{22,}\b`)
+	// Runs of 13-19 digits, optionally grouped by spaces or dashes
+	cardCandidatePattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+)
+
+// DefaultDetectors returns the built-in detectors: email addresses, AWS
+// access keys, GitHub tokens, and Luhn-valid card numbers
+func DefaultDetectors() []Detector {
+	return []Detector{
+		{Name: "email", match: emailPattern.MatchString},
+		{Name: "aws_access_key", match: awsKeyPattern.MatchString},
+		{Name: "github_token", match: githubTokenPattern.MatchString},
+		{Name: "credit_card", match: containsCardNumber},
+	}
+}
+
+// PatternDetector builds a detector from a user-supplied regex
+func PatternDetector(pattern string) (Detector, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Detector{}, fmt.Errorf("invalid PII pattern %q: %w", pattern, err)
+	}
+	return Detector{Name: "pattern:" + pattern, match: re.MatchString}, nil
+}
+
+// containsCardNumber looks for digit runs that pass the Luhn checksum, which
+// rules out most phone numbers, timestamps, and IDs
+func containsCardNumber(text string) bool {
+	for _, candidate := range cardCandidatePattern.FindAllString(text, -1) {
+		digits := strings.NewReplacer(" ", "", "-", "").Replace(candidate)
+		if len(digits) >= 13 && len(digits) <= 19 && luhnValid(digits) {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether a digit string passes the Luhn checksum
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
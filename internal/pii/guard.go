@@ -0,0 +1,239 @@
+// Package pii implements an optional OCR-based guard that blanks the stream
+// when sensitive text (email addresses, API keys, card numbers) is on screen.
+package pii
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	xdraw "golang.org/x/image/draw"
+)
+
+// Defaults for zero config values
+const (
+	defaultInterval  = 3 * time.Second
+	defaultMaxWidth  = 1600
+	defaultTesseract = "tesseract"
+	ocrTimeout       = 15 * time.Second
+)
+
+// Status reports the guard state for the API
+type Status struct {
+	Enabled    bool      `json:"enabled"`
+	Blanked    bool      `json:"blanked"`  // Stream is currently blanked
+	Detected   bool      `json:"detected"` // Last scan found sensitive text
+	Override   bool      `json:"override"` // User chose to show the stream anyway
+	Matches    []string  `json:"matches"`  // Detector names from the last scan (never the text itself)
+	LastScan   time.Time `json:"last_scan"`
+	LastError  string    `json:"last_error,omitempty"`
+	Scans      uint64    `json:"scans"`
+	Detections uint64    `json:"detections"`
+}
+
+// Guard periodically OCRs downscaled frames in the background and reports
+// whether the stream should be blanked. Scans never block the stream loop:
+// a frame is only sampled when the interval has elapsed and no scan is
+// running, so sensitive text can be visible for up to one interval plus the
+// OCR time before the guard reacts.
+type Guard struct {
+	tesseract string
+	interval  time.Duration
+	maxWidth  int
+	detectors []Detector
+
+	mu         sync.Mutex
+	scanning   bool
+	lastSample time.Time
+	detected   bool
+	override   bool
+	matches    []string
+	lastScan   time.Time
+	lastErr    string
+	scans      uint64
+	detections uint64
+}
+
+// NewGuard creates a guard from config. It fails if tesseract is not
+// installed or a custom pattern does not compile.
+func NewGuard(cfg config.PIIGuardConfig) (*Guard, error) {
+	tesseract := cfg.TesseractPath
+	if tesseract == "" {
+		tesseract = defaultTesseract
+	}
+	path, err := exec.LookPath(tesseract)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract not found (install tesseract-ocr or set pii_guard.tesseract_path): %w", err)
+	}
+
+	g := &Guard{
+		tesseract: path,
+		interval:  time.Duration(cfg.IntervalSeconds) * time.Second,
+		maxWidth:  cfg.MaxWidth,
+		detectors: DefaultDetectors(),
+	}
+	if g.interval <= 0 {
+		g.interval = defaultInterval
+	}
+	if g.maxWidth <= 0 {
+		g.maxWidth = defaultMaxWidth
+	}
+
+	for _, pattern := range cfg.Patterns {
+		detector, err := PatternDetector(pattern)
+		if err != nil {
+			return nil, err
+		}
+		g.detectors = append(g.detectors, detector)
+	}
+
+	logger.WithComponent("pii-guard").Info().
+		Str("tesseract", path).
+		Dur("interval", g.interval).
+		Int("detectors", len(g.detectors)).
+		Msg("PII guard enabled")
+	return g, nil
+}
+
+// Submit offers a frame for scanning. If a scan is due, a downscaled copy is
+// taken (the caller keeps ownership of img) and OCR runs in the background.
+func (g *Guard) Submit(img *image.RGBA) {
+	g.mu.Lock()
+	if g.scanning || time.Since(g.lastSample) < g.interval {
+		g.mu.Unlock()
+		return
+	}
+	g.scanning = true
+	g.lastSample = time.Now()
+	g.mu.Unlock()
+
+	sample := g.downscale(img)
+	go g.scan(sample)
+}
+
+// Blanked reports whether the stream should be replaced with the placeholder
+func (g *Guard) Blanked() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.detected && !g.override
+}
+
+// SetOverride shows the stream despite a detection. The override lasts until
+// a scan comes back clean, so the next sensitive text blanks again.
+func (g *Guard) SetOverride(override bool) {
+	g.mu.Lock()
+	g.override = override
+	g.mu.Unlock()
+
+	logger.WithComponent("pii-guard").Info().
+		Bool("override", override).
+		Msg("PII guard override changed")
+}
+
+// Status returns a snapshot of the guard state
+func (g *Guard) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	matches := make([]string, len(g.matches))
+	copy(matches, g.matches)
+	return Status{
+		Enabled:    true,
+		Blanked:    g.detected && !g.override,
+		Detected:   g.detected,
+		Override:   g.override,
+		Matches:    matches,
+		LastScan:   g.lastScan,
+		LastError:  g.lastErr,
+		Scans:      g.scans,
+		Detections: g.detections,
+	}
+}
+
+// downscale copies img, shrinking it to at most maxWidth pixels wide
+func (g *Guard) downscale(img *image.RGBA) *image.RGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > g.maxWidth {
+		height = height * g.maxWidth / width
+		width = g.maxWidth
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), img, bounds, xdraw.Src, nil)
+	return dst
+}
+
+// scan runs OCR on a sample and updates the detection state
+func (g *Guard) scan(sample *image.RGBA) {
+	log := logger.WithComponent("pii-guard")
+
+	text, err := g.ocr(sample)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.scanning = false
+	g.lastScan = time.Now()
+	g.scans++
+
+	if err != nil {
+		// Keep the previous verdict; a flaky OCR run shouldn't unblank
+		g.lastErr = err.Error()
+		log.Warn().Err(err).Msg("OCR failed")
+		return
+	}
+	g.lastErr = ""
+
+	var matches []string
+	for _, d := range g.detectors {
+		if d.Match(text) {
+			matches = append(matches, d.Name)
+		}
+	}
+
+	wasDetected := g.detected
+	g.matches = matches
+	g.detected = len(matches) > 0
+
+	switch {
+	case g.detected && !wasDetected:
+		g.detections++
+		log.Warn().
+			Strs("matches", matches).
+			Bool("override", g.override).
+			Msg("Sensitive text on screen, blanking stream")
+	case !g.detected && wasDetected:
+		g.override = false
+		log.Info().Msg("Screen clear of sensitive text, resuming stream")
+	}
+}
+
+// ocr runs tesseract on a PNG-encoded sample, returning the recognized text
+func (g *Guard) ocr(sample *image.RGBA) (string, error) {
+	var input bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestSpeed}).Encode(&input, sample); err != nil {
+		return "", fmt.Errorf("failed to encode OCR sample: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ocrTimeout)
+	defer cancel()
+
+	// --psm 11: sparse text, which suits arbitrary screen content
+	cmd := exec.CommandContext(ctx, g.tesseract, "stdin", "stdout", "--psm", "11")
+	cmd.Stdin = &input
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed: %w (%s)", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return string(output), nil
+}
@@ -0,0 +1,217 @@
+// Package pixconv provides fast row-wise pixel format conversions shared by
+// the capture, compose, and display paths. Work is split across goroutines
+// sized to GOMAXPROCS for frames large enough to benefit.
+//
+// Channel swaps work on two pixels per 64-bit word rather than byte by
+// byte. This is portable Go, not SIMD: there is no NEON or SSE path, so
+// arm64 runs the same scalar loop as every other architecture.
+package pixconv
+
+import (
+	"encoding/binary"
+	"image"
+	"runtime"
+	"sync"
+)
+
+// minRowsPerWorker avoids goroutine overhead dominating on small images
+const minRowsPerWorker = 32
+
+// ParallelRows calls fn for disjoint [y0, y1) row ranges covering
+// [0, height), in parallel across up to GOMAXPROCS workers
+func ParallelRows(height int, fn func(y0, y1 int)) {
+	if height <= 0 {
+		return
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if maxWorkers := height / minRowsPerWorker; maxWorkers < workers {
+		workers = maxWorkers
+	}
+	if workers <= 1 {
+		fn(0, height)
+		return
+	}
+
+	rowsPerWorker := (height + workers - 1) / workers
+	var wg sync.WaitGroup
+	for y0 := 0; y0 < height; y0 += rowsPerWorker {
+		y1 := y0 + rowsPerWorker
+		if y1 > height {
+			y1 = height
+		}
+		wg.Add(1)
+		go func(y0, y1 int) {
+			defer wg.Done()
+			fn(y0, y1)
+		}(y0, y1)
+	}
+	wg.Wait()
+}
+
+// BGRAToRGBA converts tightly packed 32-bit BGRx/BGRA data (X11 ZPixmap at
+// depth 24/32) into dst, forcing alpha to opaque. Rows missing from a short
+// src buffer are left untouched.
+func BGRAToRGBA(dst *image.RGBA, src []byte) {
+	bounds := dst.Bounds()
+	width := bounds.Dx()
+	rowBytes := width * 4
+	if rowBytes == 0 {
+		return
+	}
+
+	rows := bounds.Dy()
+	if available := len(src) / rowBytes; available < rows {
+		rows = available
+	}
+
+	ParallelRows(rows, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			s := src[y*rowBytes : (y+1)*rowBytes]
+			dstStart := dst.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			SwapRedBlue(dst.Pix[dstStart:dstStart+rowBytes], s, false)
+		}
+	})
+}
+
+// StridedToRGBA converts 32-bit rows src, srcStride bytes apart, into dst,
+// forcing alpha to opaque. The rows are BGRx/BGRA when bgr is set and
+// RGBx/RGBA otherwise, as PipeWire delivers them. Rows missing from a short
+// src buffer are left untouched.
+func StridedToRGBA(dst *image.RGBA, src []byte, srcStride int, bgr bool) {
+	bounds := dst.Bounds()
+	rowBytes := bounds.Dx() * 4
+	if rowBytes == 0 || srcStride < rowBytes {
+		return
+	}
+
+	rows := bounds.Dy()
+	if len(src) < rowBytes {
+		return
+	}
+	if available := (len(src)-rowBytes)/srcStride + 1; available < rows {
+		rows = available
+	}
+
+	ParallelRows(rows, func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			s := src[y*srcStride : y*srcStride+rowBytes]
+			dstStart := dst.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			d := dst.Pix[dstStart : dstStart+rowBytes]
+			if bgr {
+				SwapRedBlue(d, s, false)
+			} else {
+				copyOpaque(d, s)
+			}
+		}
+	})
+}
+
+// Masks selecting the channels of two little-endian 32-bit pixels in a word
+const (
+	swapOuter = 0x000000ff000000ff // Bytes 0 and 2 swap
+	swapInner = 0x0000ff000000ff00 // Byte 1 stays
+	swapAlpha = 0xff000000ff000000 // Byte 3
+)
+
+// SwapRedBlue converts a row of 32-bit pixels between BGRA and RGBA order
+// (the same swap both ways). The fourth byte is copied when keepAlpha is
+// set and made opaque otherwise. dst and src may be the same slice.
+func SwapRedBlue(dst, src []byte, keepAlpha bool) {
+	swapRedBlue(dst, src, keepAlpha, 0xff)
+}
+
+// swapRedBlue swaps the first and third byte of each pixel, setting the
+// fourth to fill unless keepAlpha is set
+func swapRedBlue(dst, src []byte, keepAlpha bool, fill byte) {
+	n := min(len(dst), len(src)) &^ 3
+	dst, src = dst[:n], src[:n]
+
+	alphaMask, fillBits := uint64(0), uint64(fill)*0x0100000001<<24
+	if keepAlpha {
+		alphaMask, fillBits = swapAlpha, 0
+	}
+	pairs := n &^ 7
+	for i := 0; i < pairs; i += 8 {
+		v := binary.LittleEndian.Uint64(src[i : i+8])
+		v = (v>>16)&swapOuter | v&swapInner | (v&swapOuter)<<16 | v&alphaMask | fillBits
+		binary.LittleEndian.PutUint64(dst[i:i+8], v)
+	}
+	if pairs < n {
+		i := pairs
+		r, g, b, a := src[i+2], src[i+1], src[i], src[i+3]
+		if !keepAlpha {
+			a = fill
+		}
+		dst[i], dst[i+1], dst[i+2], dst[i+3] = r, g, b, a
+	}
+}
+
+// copyOpaque copies a row of 32-bit pixels, setting the fourth byte of each
+// to 0xff, since the x formats leave it undefined
+func copyOpaque(dst, src []byte) {
+	n := copy(dst, src) &^ 3
+	pairs := n &^ 7
+	for i := 0; i < pairs; i += 8 {
+		binary.LittleEndian.PutUint64(dst[i:i+8], binary.LittleEndian.Uint64(dst[i:i+8])|swapAlpha)
+	}
+	if pairs < n {
+		dst[pairs+3] = 0xff
+	}
+}
+
+// RGBAToBGRX converts src into X11 ZPixmap data with the given bytes per
+// pixel (3 or 4) and destination stride. When keepAlpha is false the fourth
+// byte is zeroed (depth 24 padding). Padding bytes at row ends are left as-is.
+func RGBAToBGRX(dst []byte, dstStride, bytesPerPixel int, src *image.RGBA, keepAlpha bool) {
+	bounds := src.Bounds()
+	width := bounds.Dx()
+
+	ParallelRows(bounds.Dy(), func(y0, y1 int) {
+		for y := y0; y < y1; y++ {
+			srcStart := src.PixOffset(bounds.Min.X, bounds.Min.Y+y)
+			s := src.Pix[srcStart : srcStart+width*4]
+			d := dst[y*dstStride : y*dstStride+width*bytesPerPixel]
+
+			if bytesPerPixel == 4 {
+				swapRedBlue(d, s, keepAlpha, 0)
+			} else {
+				for i, j := 0, 0; i < len(s); i, j = i+4, j+3 {
+					d[j] = s[i+2]
+					d[j+1] = s[i+1]
+					d[j+2] = s[i]
+				}
+			}
+		}
+	})
+}
+
+// ScaleNearest scales srcRect of src into dstRect of dst using
+// nearest-neighbor sampling
+func ScaleNearest(dst *image.RGBA, dstRect image.Rectangle, src *image.RGBA, srcRect image.Rectangle) {
+	dstWidth := dstRect.Dx()
+	dstHeight := dstRect.Dy()
+	srcWidth := srcRect.Dx()
+	srcHeight := srcRect.Dy()
+	if dstWidth <= 0 || dstHeight <= 0 || srcWidth <= 0 || srcHeight <= 0 {
+		return
+	}
+
+	// Precompute source byte offsets for each destination column
+	srcCols := make([]int, dstWidth)
+	for dx := range srcCols {
+		srcCols[dx] = (srcRect.Min.X + dx*srcWidth/dstWidth - src.Rect.Min.X) * 4
+	}
+
+	ParallelRows(dstHeight, func(y0, y1 int) {
+		for dy := y0; dy < y1; dy++ {
+			sy := srcRect.Min.Y + dy*srcHeight/dstHeight
+			srcRow := src.Pix[(sy-src.Rect.Min.Y)*src.Stride:]
+			dstStart := dst.PixOffset(dstRect.Min.X, dstRect.Min.Y+dy)
+			d := dst.Pix[dstStart : dstStart+dstWidth*4]
+			for dx, so := range srcCols {
+				copy(d[dx*4:dx*4+4], srcRow[so:so+4])
+			}
+		}
+	})
+}
@@ -0,0 +1,251 @@
+package pixconv
+
+import (
+	"bytes"
+	"image"
+	"math/rand"
+	"sync/atomic"
+	"testing"
+)
+
+func randomBytes(n int, seed int64) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(b)
+	return b
+}
+
+func randomRGBA(rect image.Rectangle, seed int64) *image.RGBA {
+	img := image.NewRGBA(rect)
+	copy(img.Pix, randomBytes(len(img.Pix), seed))
+	return img
+}
+
+// bgraToRGBASerial is the byte-by-byte reference for BGRAToRGBA
+func bgraToRGBASerial(dst *image.RGBA, src []byte) {
+	bounds := dst.Bounds()
+	rowBytes := bounds.Dx() * 4
+	for y := 0; y < bounds.Dy(); y++ {
+		if (y+1)*rowBytes > len(src) {
+			return
+		}
+		for x := 0; x < bounds.Dx(); x++ {
+			s := src[y*rowBytes+x*4:]
+			d := dst.Pix[dst.PixOffset(bounds.Min.X+x, bounds.Min.Y+y):]
+			d[0], d[1], d[2], d[3] = s[2], s[1], s[0], 0xff
+		}
+	}
+}
+
+// rgbaToBGRXSerial is the byte-by-byte reference for RGBAToBGRX
+func rgbaToBGRXSerial(dst []byte, dstStride, bytesPerPixel int, src *image.RGBA, keepAlpha bool) {
+	bounds := src.Bounds()
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			s := src.Pix[src.PixOffset(bounds.Min.X+x, bounds.Min.Y+y):]
+			d := dst[y*dstStride+x*bytesPerPixel:]
+			d[0], d[1], d[2] = s[2], s[1], s[0]
+			if bytesPerPixel == 4 {
+				d[3] = 0
+				if keepAlpha {
+					d[3] = s[3]
+				}
+			}
+		}
+	}
+}
+
+// scaleNearestSerial is the pixel-by-pixel reference for ScaleNearest
+func scaleNearestSerial(dst *image.RGBA, dstRect image.Rectangle, src *image.RGBA, srcRect image.Rectangle) {
+	if dstRect.Empty() || srcRect.Empty() {
+		return
+	}
+	for dy := 0; dy < dstRect.Dy(); dy++ {
+		sy := srcRect.Min.Y + dy*srcRect.Dy()/dstRect.Dy()
+		for dx := 0; dx < dstRect.Dx(); dx++ {
+			sx := srcRect.Min.X + dx*srcRect.Dx()/dstRect.Dx()
+			dst.SetRGBA(dstRect.Min.X+dx, dstRect.Min.Y+dy, src.RGBAAt(sx, sy))
+		}
+	}
+}
+
+func TestParallelRowsCoversEveryRowOnce(t *testing.T) {
+	for _, height := range []int{-1, 0, 1, minRowsPerWorker - 1, minRowsPerWorker * 3, 1080, 1081} {
+		counts := make([]int32, max(height, 0))
+		ParallelRows(height, func(y0, y1 int) {
+			for y := y0; y < y1; y++ {
+				atomic.AddInt32(&counts[y], 1)
+			}
+		})
+		for y, n := range counts {
+			if n != 1 {
+				t.Fatalf("height %d: row %d visited %d times", height, y, n)
+			}
+		}
+	}
+}
+
+func TestBGRAToRGBA(t *testing.T) {
+	tests := []struct {
+		name    string
+		rect    image.Rectangle
+		srcRows int // Rows of source data; fewer than the image leaves the rest untouched
+	}{
+		{"1080p", image.Rect(0, 0, 1920, 1080), 1080},
+		{"odd width", image.Rect(0, 0, 1281, 721), 721},
+		{"offset bounds", image.Rect(7, 3, 647, 483), 480},
+		{"short src", image.Rect(0, 0, 640, 480), 100},
+		{"empty src", image.Rect(0, 0, 640, 480), 0},
+		{"zero width", image.Rect(0, 0, 0, 480), 0},
+		{"zero height", image.Rect(0, 0, 640, 0), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := randomBytes(tt.rect.Dx()*4*tt.srcRows, 1)
+			// A trailing partial row must be ignored too
+			if len(src) > 0 {
+				src = append(src, 1, 2, 3)
+			}
+
+			got := randomRGBA(tt.rect, 2)
+			want := randomRGBA(tt.rect, 2)
+			BGRAToRGBA(got, src)
+			bgraToRGBASerial(want, src)
+
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Fatal("parallel conversion differs from serial reference")
+			}
+		})
+	}
+}
+
+func TestStridedToRGBA(t *testing.T) {
+	tests := []struct {
+		name    string
+		rect    image.Rectangle
+		stride  int
+		srcRows int
+		bgr     bool
+	}{
+		{"bgr packed", image.Rect(0, 0, 1280, 720), 1280 * 4, 720, true},
+		{"rgb packed", image.Rect(0, 0, 1280, 720), 1280 * 4, 720, false},
+		{"bgr padded odd width", image.Rect(0, 0, 1281, 721), 1281*4 + 60, 721, true},
+		{"rgb padded odd width", image.Rect(0, 0, 1281, 721), 1281*4 + 60, 721, false},
+		{"offset bounds", image.Rect(7, 3, 647, 483), 640*4 + 256, 480, false},
+		{"short src", image.Rect(0, 0, 640, 480), 640 * 4, 100, true},
+		{"stride too small", image.Rect(0, 0, 640, 480), 639 * 4, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The last row needn't carry its stride padding
+			var src []byte
+			if tt.srcRows > 0 {
+				src = randomBytes(tt.stride*(tt.srcRows-1)+tt.rect.Dx()*4, 1)
+			}
+
+			got := randomRGBA(tt.rect, 2)
+			want := randomRGBA(tt.rect, 2)
+			StridedToRGBA(got, src, tt.stride, tt.bgr)
+			for y := 0; y < tt.srcRows; y++ {
+				for x := 0; x < tt.rect.Dx(); x++ {
+					s := src[y*tt.stride+x*4:]
+					d := want.Pix[want.PixOffset(tt.rect.Min.X+x, tt.rect.Min.Y+y):]
+					d[0], d[1], d[2], d[3] = s[0], s[1], s[2], 0xff
+					if tt.bgr {
+						d[0], d[2] = s[2], s[0]
+					}
+				}
+			}
+
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Fatal("conversion differs from per-pixel reference")
+			}
+		})
+	}
+}
+
+func TestRGBAToBGRX(t *testing.T) {
+	tests := []struct {
+		name          string
+		rect          image.Rectangle
+		bytesPerPixel int
+		keepAlpha     bool
+	}{
+		{"depth 24", image.Rect(0, 0, 1920, 1080), 4, false},
+		{"depth 32", image.Rect(0, 0, 1920, 1080), 4, true},
+		{"24 bpp", image.Rect(0, 0, 1280, 720), 3, false},
+		{"odd width", image.Rect(0, 0, 333, 257), 4, false},
+		{"offset bounds", image.Rect(5, 9, 645, 489), 4, true},
+		{"zero width", image.Rect(0, 0, 0, 480), 4, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := randomRGBA(tt.rect, 3)
+			// Stride with padding, which must be left as-is
+			stride := tt.rect.Dx()*tt.bytesPerPixel + 8
+			got := randomBytes(stride*tt.rect.Dy(), 4)
+			want := randomBytes(stride*tt.rect.Dy(), 4)
+
+			RGBAToBGRX(got, stride, tt.bytesPerPixel, src, tt.keepAlpha)
+			rgbaToBGRXSerial(want, stride, tt.bytesPerPixel, src, tt.keepAlpha)
+
+			if !bytes.Equal(got, want) {
+				t.Fatal("parallel conversion differs from serial reference")
+			}
+		})
+	}
+}
+
+func TestScaleNearest(t *testing.T) {
+	tests := []struct {
+		name    string
+		srcRect image.Rectangle
+		dstRect image.Rectangle
+	}{
+		{"downscale", image.Rect(0, 0, 2560, 1440), image.Rect(0, 0, 1280, 720)},
+		{"upscale", image.Rect(0, 0, 320, 180), image.Rect(0, 0, 1920, 1080)},
+		{"crop into offset", image.Rect(100, 50, 740, 410), image.Rect(10, 20, 1290, 740)},
+		{"zero width", image.Rect(0, 0, 0, 100), image.Rect(0, 0, 640, 480)},
+		{"zero destination", image.Rect(0, 0, 640, 480), image.Rect(0, 0, 0, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := randomRGBA(image.Rect(0, 0, 2560, 1440), 5)
+			got := randomRGBA(image.Rect(0, 0, 1920, 1080), 6)
+			want := randomRGBA(image.Rect(0, 0, 1920, 1080), 6)
+
+			ScaleNearest(got, tt.dstRect, src, tt.srcRect)
+			scaleNearestSerial(want, tt.dstRect, src, tt.srcRect)
+
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Fatal("parallel scaling differs from serial reference")
+			}
+		})
+	}
+}
+
+func BenchmarkBGRAToRGBA(b *testing.B) {
+	dst := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	src := randomBytes(len(dst.Pix), 1)
+	b.SetBytes(int64(len(src)))
+	for b.Loop() {
+		BGRAToRGBA(dst, src)
+	}
+}
+
+func BenchmarkRGBAToBGRX(b *testing.B) {
+	src := randomRGBA(image.Rect(0, 0, 1920, 1080), 1)
+	dst := make([]byte, len(src.Pix))
+	b.SetBytes(int64(len(src.Pix)))
+	for b.Loop() {
+		RGBAToBGRX(dst, src.Stride, 4, src, false)
+	}
+}
+
+func BenchmarkScaleNearest(b *testing.B) {
+	src := randomRGBA(image.Rect(0, 0, 2560, 1440), 1)
+	dst := image.NewRGBA(image.Rect(0, 0, 1920, 1080))
+	b.SetBytes(int64(len(dst.Pix)))
+	for b.Loop() {
+		ScaleNearest(dst, dst.Rect, src, src.Rect)
+	}
+}
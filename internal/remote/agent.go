@@ -0,0 +1,289 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// windowListInterval is how often the agent resends the window list,
+	// picking up windows opened and closed without a focus change
+	windowListInterval = 5 * time.Second
+
+	// Reconnect backoff bounds
+	minReconnectDelay = time.Second
+	maxReconnectDelay = 30 * time.Second
+)
+
+// Source is what the agent forwards: the desktop's windows and captures of
+// them. window.Manager implements it.
+type Source interface {
+	ListWindows() ([]*config.WindowInfo, error)
+	GetCurrentWindow() *config.WindowInfo
+	Subscribe() chan *config.WindowInfo
+	Unsubscribe(ch chan *config.WindowInfo)
+	ActivateWindow(windowID uint32) error
+	CaptureWindowImage(windowID uint32) (*image.RGBA, error)
+}
+
+// AgentOptions configure an Agent
+type AgentOptions struct {
+	Server  string // Server URL, e.g. ws://nas:8080 (http and https are accepted too)
+	Token   string // Link token with the ingest scope, for servers on other machines
+	Quality int    // JPEG quality of forwarded frames (1-100)
+	Backend string // Local window backend name, reported to the server
+}
+
+// Agent forwards windows and frames from this desktop to a server
+type Agent struct {
+	source  Source
+	opts    AgentOptions
+	url     string
+	writeMu sync.Mutex
+}
+
+// NewAgent creates an agent forwarding from source
+func NewAgent(source Source, opts AgentOptions) (*Agent, error) {
+	target, err := AgentURL(opts.Server, opts.Token)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Quality < 1 || opts.Quality > 100 {
+		return nil, fmt.Errorf("JPEG quality must be 1-100")
+	}
+	return &Agent{source: source, opts: opts, url: target}, nil
+}
+
+// AgentURL builds the agent endpoint URL from a server URL
+func AgentURL(server, token string) (string, error) {
+	if !strings.Contains(server, "://") {
+		server = "ws://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", fmt.Errorf("invalid server URL: %w", err)
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("invalid server URL scheme %q (use ws, wss, http or https)", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("server URL %q has no host", server)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + AgentPath
+	if token != "" {
+		query := u.Query()
+		query.Set("token", token)
+		u.RawQuery = query.Encode()
+	}
+	return u.String(), nil
+}
+
+// Run connects to the server and forwards until ctx is done, reconnecting
+// with backoff when the connection drops
+func (a *Agent) Run(ctx context.Context) error {
+	log := logger.WithComponent("agent")
+	delay := minReconnectDelay
+	for {
+		connected, err := a.session(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if connected {
+			delay = minReconnectDelay
+		}
+		log.Warn().Err(err).Dur("retry_in", delay).Msg("Disconnected from server")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxReconnectDelay)
+	}
+}
+
+// session runs one connection, reporting whether it got connected
+func (a *Agent) session(ctx context.Context) (bool, error) {
+	log := logger.WithComponent("agent")
+
+	ws, resp, err := websocket.DefaultDialer.DialContext(ctx, a.url, nil)
+	if err != nil {
+		if resp != nil {
+			return false, fmt.Errorf("failed to connect: %w (HTTP %s)", err, resp.Status)
+		}
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer ws.Close()
+	ws.SetReadLimit(1 << 20)
+
+	host, _ := os.Hostname()
+	if err := a.send(ws, Message{Type: MsgHello, Version: ProtocolVersion, Host: host, Backend: a.opts.Backend}); err != nil {
+		return false, err
+	}
+	log.Info().Str("server", a.opts.Server).Msg("Connected to server")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		ws.Close()
+	}()
+
+	// Report windows and focus changes
+	updates := a.source.Subscribe()
+	defer a.source.Unsubscribe(updates)
+	go a.reportWindows(ctx, ws, updates)
+
+	// Capture what the server asks for
+	requests := make(chan Message, 1)
+	go a.captureLoop(ctx, ws, requests)
+
+	for {
+		var msg Message
+		if err := ws.ReadJSON(&msg); err != nil {
+			return true, err
+		}
+		switch msg.Type {
+		case MsgCapture:
+			select {
+			case <-requests: // Replace a request not picked up yet
+			default:
+			}
+			requests <- msg
+		case MsgActivate:
+			if err := a.source.ActivateWindow(msg.WindowID); err != nil {
+				a.send(ws, Message{Type: MsgError, WindowID: msg.WindowID, Error: err.Error()})
+			}
+		case MsgError:
+			return true, fmt.Errorf("server error: %s", msg.Error)
+		}
+	}
+}
+
+// reportWindows sends the window list and focus, then updates until ctx is
+// done
+func (a *Agent) reportWindows(ctx context.Context, ws *websocket.Conn, updates chan *config.WindowInfo) {
+	sendList := func() {
+		windows, err := a.source.ListWindows()
+		if err != nil {
+			logger.WithComponent("agent").Debug().Err(err).Msg("Failed to list windows")
+			return
+		}
+		a.send(ws, Message{Type: MsgWindows, Windows: windows})
+	}
+
+	sendList()
+	if focused := a.source.GetCurrentWindow(); focused != nil {
+		a.send(ws, Message{Type: MsgFocus, Window: focused})
+	}
+
+	ticker := time.NewTicker(windowListInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case window := <-updates:
+			if window == nil {
+				continue
+			}
+			sendList()
+			a.send(ws, Message{Type: MsgFocus, Window: window})
+		case <-ticker.C:
+			sendList()
+		}
+	}
+}
+
+// captureLoop captures the requested windows at the requested rate
+func (a *Agent) captureLoop(ctx context.Context, ws *websocket.Conn, requests chan Message) {
+	var (
+		ids    []uint32
+		ticker *time.Ticker
+		tick   <-chan time.Time
+	)
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case req := <-requests:
+			ids = req.WindowIDs
+			if ticker != nil {
+				ticker.Stop()
+				ticker, tick = nil, nil
+			}
+			if len(ids) > 0 {
+				ticker = time.NewTicker(time.Second / time.Duration(max(req.FPS, 1)))
+				tick = ticker.C
+				a.captureAll(ws, ids) // Don't wait a frame interval for the first frame
+			}
+		case <-tick:
+			a.captureAll(ws, ids)
+		}
+	}
+}
+
+// captureAll captures and sends one frame of each window
+func (a *Agent) captureAll(ws *websocket.Conn, ids []uint32) {
+	buf := framepool.GetBuffer()
+	defer framepool.PutBuffer(buf)
+
+	for _, id := range ids {
+		img, err := a.source.CaptureWindowImage(id)
+		if err != nil {
+			a.send(ws, Message{Type: MsgError, WindowID: id, Error: err.Error()})
+			continue
+		}
+		buf.Reset()
+		buf.Write(encodeFrameHeader(nil, id))
+		err = jpeg.Encode(buf, img, &jpeg.Options{Quality: a.opts.Quality})
+		framepool.Put(img)
+		if err != nil {
+			continue
+		}
+		if err := a.sendFrame(ws, buf); err != nil {
+			return
+		}
+	}
+}
+
+// send writes a control message
+func (a *Agent) send(ws *websocket.Conn, msg Message) error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	ws.SetWriteDeadline(time.Now().Add(agentWriteTimeout))
+	return ws.WriteJSON(msg)
+}
+
+// sendFrame writes a frame message
+func (a *Agent) sendFrame(ws *websocket.Conn, frame *bytes.Buffer) error {
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	ws.SetWriteDeadline(time.Now().Add(agentWriteTimeout))
+	return ws.WriteMessage(websocket.BinaryMessage, frame.Bytes())
+}
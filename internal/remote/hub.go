@@ -0,0 +1,457 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// frameWait bounds how long a capture waits for the agent's first frame
+	// of a window
+	frameWait = 2 * time.Second
+
+	// frameMaxAge is how old the last frame of a window may be before a
+	// capture waits for a new one
+	frameMaxAge = 2 * time.Second
+
+	// wantTTL is how long a window is captured after it was last asked for
+	wantTTL = 5 * time.Second
+
+	// maxFrameMessage and maxFrameSize bound frames from the agent
+	maxFrameMessage = 32 << 20
+	maxFrameSize    = 8192
+
+	// agentWriteTimeout bounds a write to the agent
+	agentWriteTimeout = 10 * time.Second
+)
+
+// remoteFrame is the last frame of a window
+type remoteFrame struct {
+	img      *image.RGBA
+	received time.Time
+}
+
+// agentConn is a connected agent. Writes go through out so they never
+// block the hub.
+type agentConn struct {
+	ws          *websocket.Conn
+	out         chan Message
+	done        chan struct{}
+	host        string
+	backend     string
+	connectedAt time.Time
+}
+
+// send queues a message for the agent, dropping it if the agent is gone
+func (c *agentConn) send(msg Message) {
+	select {
+	case c.out <- msg:
+	case <-c.done:
+	}
+}
+
+// writeLoop writes queued messages until the connection closes
+func (c *agentConn) writeLoop() {
+	for {
+		select {
+		case msg := <-c.out:
+			c.ws.SetWriteDeadline(time.Now().Add(agentWriteTimeout))
+			if err := c.ws.WriteJSON(msg); err != nil {
+				c.ws.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// AgentStatus describes the connected agent
+type AgentStatus struct {
+	Connected   bool      `json:"connected"`
+	Host        string    `json:"host,omitempty"`
+	Backend     string    `json:"backend,omitempty"`
+	ConnectedAt time.Time `json:"connected_at,omitempty"`
+	Windows     int       `json:"windows"`
+	Capturing   []uint32  `json:"capturing"`
+	Frames      uint64    `json:"frames"`
+}
+
+// Hub is the server side of agent mode. It holds the windows and frames
+// reported by the connected agent and serves them as a window backend (see
+// window.RemoteBackend) and a capturer. One agent is connected at a time; a
+// new one replaces it.
+type Hub struct {
+	mu       sync.Mutex
+	agent    *agentConn
+	windows  []*config.WindowInfo
+	focused  *config.WindowInfo
+	frames   map[uint32]remoteFrame
+	received uint64
+	signal   chan struct{} // Closed and replaced when a frame arrives
+
+	wanted    map[uint32]time.Time // Windows being captured, by when last asked for
+	capturing []uint32             // Window IDs the agent was last asked to capture
+	fps       int
+
+	onFocus func(*config.WindowInfo)
+}
+
+// NewHub creates a hub for agents streaming at fps
+func NewHub(fps int) *Hub {
+	return &Hub{
+		frames: make(map[uint32]remoteFrame),
+		wanted: make(map[uint32]time.Time),
+		signal: make(chan struct{}),
+		fps:    max(fps, 1),
+	}
+}
+
+// ServeAgent runs an upgraded agent connection until it closes
+func (h *Hub) ServeAgent(ws *websocket.Conn) {
+	log := logger.WithComponent("remote")
+	defer ws.Close()
+	ws.SetReadLimit(maxFrameMessage)
+
+	var hello Message
+	if err := ws.ReadJSON(&hello); err != nil || hello.Type != MsgHello {
+		log.Warn().Err(err).Msg("Agent did not say hello")
+		return
+	}
+	if hello.Version != ProtocolVersion {
+		ws.WriteJSON(Message{Type: MsgError, Error: fmt.Sprintf("protocol version %d not supported (server speaks %d)", hello.Version, ProtocolVersion)})
+		log.Warn().Int("version", hello.Version).Str("host", hello.Host).Msg("Agent protocol version mismatch")
+		return
+	}
+
+	conn := &agentConn{
+		ws:          ws,
+		out:         make(chan Message, 16),
+		done:        make(chan struct{}),
+		host:        hello.Host,
+		backend:     hello.Backend,
+		connectedAt: time.Now(),
+	}
+	go conn.writeLoop()
+	h.attach(conn)
+	defer h.detach(conn)
+	log.Info().Str("host", conn.host).Str("backend", conn.backend).Msg("Capture agent connected")
+
+	stopPrune := make(chan struct{})
+	defer close(stopPrune)
+	go h.pruneLoop(stopPrune)
+
+	for {
+		kind, data, err := ws.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Warn().Err(err).Str("host", conn.host).Msg("Capture agent connection lost")
+			} else {
+				log.Info().Str("host", conn.host).Msg("Capture agent disconnected")
+			}
+			return
+		}
+		switch kind {
+		case websocket.BinaryMessage:
+			if err := h.receiveFrame(data); err != nil {
+				log.Debug().Err(err).Msg("Dropped frame from agent")
+			}
+		case websocket.TextMessage:
+			h.receiveMessage(data)
+		}
+	}
+}
+
+// attach makes conn the connected agent, closing any previous one
+func (h *Hub) attach(conn *agentConn) {
+	h.mu.Lock()
+	previous := h.agent
+	h.agent = conn
+	h.capturing = nil
+	h.mu.Unlock()
+
+	if previous != nil {
+		previous.ws.Close()
+	}
+}
+
+// detach forgets conn's windows and frames if it is still the connected agent
+func (h *Hub) detach(conn *agentConn) {
+	close(conn.done)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.agent != conn {
+		return
+	}
+	h.agent = nil
+	h.windows = nil
+	h.capturing = nil
+	for id, frame := range h.frames {
+		framepool.Put(frame.img)
+		delete(h.frames, id)
+	}
+	clear(h.wanted)
+}
+
+// receiveMessage handles a control message from the agent
+func (h *Hub) receiveMessage(data []byte) {
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		logger.WithComponent("remote").Debug().Err(err).Msg("Invalid message from agent")
+		return
+	}
+
+	switch msg.Type {
+	case MsgWindows:
+		h.mu.Lock()
+		h.windows = msg.Windows
+		h.mu.Unlock()
+	case MsgFocus:
+		if msg.Window == nil {
+			return
+		}
+		h.mu.Lock()
+		h.focused = msg.Window
+		callback := h.onFocus
+		h.mu.Unlock()
+		if callback != nil {
+			window := *msg.Window
+			callback(&window)
+		}
+	case MsgError:
+		logger.WithComponent("remote").Debug().Uint32("window_id", msg.WindowID).Str("error", msg.Error).Msg("Agent reported an error")
+	}
+}
+
+// receiveFrame decodes a frame from the agent into the window's last frame
+func (h *Hub) receiveFrame(data []byte) error {
+	id, jpegData, err := decodeFrameHeader(data)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	_, wanted := h.wanted[id]
+	h.mu.Unlock()
+	if !wanted {
+		return fmt.Errorf("frame for window %d, which isn't being captured", id)
+	}
+
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(jpegData))
+	if err != nil {
+		return fmt.Errorf("invalid JPEG: %w", err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxFrameSize || cfg.Height > maxFrameSize {
+		return fmt.Errorf("frame size %dx%d out of range", cfg.Width, cfg.Height)
+	}
+	decoded, err := jpeg.Decode(bytes.NewReader(jpegData))
+	if err != nil {
+		return fmt.Errorf("invalid JPEG: %w", err)
+	}
+	img := framepool.Get(cfg.Width, cfg.Height)
+	draw.Draw(img, img.Bounds(), decoded, decoded.Bounds().Min, draw.Src)
+
+	h.mu.Lock()
+	if previous, ok := h.frames[id]; ok {
+		framepool.Put(previous.img)
+	}
+	h.frames[id] = remoteFrame{img: img, received: time.Now()}
+	h.received++
+	close(h.signal)
+	h.signal = make(chan struct{})
+	h.mu.Unlock()
+	return nil
+}
+
+// pruneLoop stops capturing windows that are no longer asked for
+func (h *Hub) pruneLoop(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.mu.Lock()
+			h.updateCaptureLocked()
+			h.mu.Unlock()
+		}
+	}
+}
+
+// updateCaptureLocked drops windows not asked for within wantTTL and tells
+// the agent when the set of captured windows changed
+func (h *Hub) updateCaptureLocked() {
+	now := time.Now()
+	for id, asked := range h.wanted {
+		if now.Sub(asked) > wantTTL {
+			delete(h.wanted, id)
+			if frame, ok := h.frames[id]; ok {
+				framepool.Put(frame.img)
+				delete(h.frames, id)
+			}
+		}
+	}
+
+	ids := make([]uint32, 0, len(h.wanted))
+	for id := range h.wanted {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	if h.agent == nil || slices.Equal(ids, h.capturing) {
+		return
+	}
+	h.capturing = ids
+	h.agent.send(Message{Type: MsgCapture, WindowIDs: ids, FPS: h.fps})
+}
+
+// Status describes the connected agent
+func (h *Hub) Status() AgentStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	status := AgentStatus{
+		Windows:   len(h.windows),
+		Capturing: append([]uint32{}, h.capturing...),
+		Frames:    h.received,
+	}
+	if h.agent != nil {
+		status.Connected = true
+		status.Host = h.agent.host
+		status.Backend = h.agent.backend
+		status.ConnectedAt = h.agent.connectedAt
+	}
+	return status
+}
+
+// Windows returns copies of the agent's windows
+func (h *Hub) Windows() []*config.WindowInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	windows := make([]*config.WindowInfo, 0, len(h.windows))
+	for _, w := range h.windows {
+		window := *w
+		windows = append(windows, &window)
+	}
+	return windows
+}
+
+// Focused returns a copy of the agent's focused window, or nil before the
+// agent reported one
+func (h *Hub) Focused() *config.WindowInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.focused == nil {
+		return nil
+	}
+	window := *h.focused
+	return &window
+}
+
+// OnFocus sets the callback for focus changes on the agent; nil removes it
+func (h *Hub) OnFocus(callback func(*config.WindowInfo)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onFocus = callback
+}
+
+// Activate asks the agent to focus and raise a window
+func (h *Hub) Activate(windowID uint32) error {
+	h.mu.Lock()
+	agent := h.agent
+	h.mu.Unlock()
+
+	if agent == nil {
+		return fmt.Errorf("no capture agent connected")
+	}
+	agent.send(Message{Type: MsgActivate, WindowID: windowID})
+	return nil
+}
+
+// Start is a no-op; agents connect through ServeAgent
+func (h *Hub) Start() error {
+	return nil
+}
+
+// Stop disconnects the agent
+func (h *Hub) Stop() error {
+	h.mu.Lock()
+	agent := h.agent
+	h.mu.Unlock()
+
+	if agent != nil {
+		agent.ws.Close()
+	}
+	return nil
+}
+
+// Name returns the capturer name
+func (h *Hub) Name() string {
+	return "remote"
+}
+
+// IsAvailable reports whether an agent is connected
+func (h *Hub) IsAvailable() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.agent != nil
+}
+
+// CanCapture reports whether an agent is connected to capture the window
+func (h *Hub) CanCapture(window *config.WindowInfo) bool {
+	return window != nil && h.IsAvailable()
+}
+
+// CaptureWindow returns the agent's latest frame of a window, asking the
+// agent to capture it and waiting for a frame if there is no recent one.
+// The caller owns the returned (pooled) frame.
+func (h *Hub) CaptureWindow(window *config.WindowInfo) (*image.RGBA, error) {
+	deadline := time.Now().Add(frameWait)
+	for {
+		h.mu.Lock()
+		if h.agent == nil {
+			h.mu.Unlock()
+			return nil, fmt.Errorf("no capture agent connected")
+		}
+		h.wanted[window.ID] = time.Now()
+		h.updateCaptureLocked()
+		frame, ok := h.frames[window.ID]
+		if ok && time.Since(frame.received) < frameMaxAge {
+			img := framepool.Clone(frame.img)
+			h.mu.Unlock()
+			return img, nil
+		}
+		signal := h.signal
+		h.mu.Unlock()
+
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			return nil, fmt.Errorf("no frame from the capture agent for window %d", window.ID)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-signal:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// CaptureRegion is not supported; agents capture whole windows
+func (h *Hub) CaptureRegion(x, y, width, height int) (*image.RGBA, error) {
+	return nil, fmt.Errorf("region capture is not supported in agent mode")
+}
@@ -0,0 +1,65 @@
+// Package remote splits FocusStreamer into a capture agent and a server.
+// The agent runs on the desktop with the windows; it reports the open and
+// focused windows to the server and captures the windows the server asks
+// for. The server (e.g. on a NAS) runs the remote window backend, so
+// allowlisting, standby, overlays and the outputs all happen there.
+//
+// The two talk over one WebSocket at AgentPath. Control messages are JSON
+// text messages (Message). Frames are binary messages: the window ID as a
+// 4-byte big-endian integer followed by a JPEG. Only windows the server
+// asks for are captured, so windows that are never shared don't leave the
+// desktop.
+package remote
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// AgentPath is the server endpoint agents connect to
+const AgentPath = "/api/agent/ws"
+
+// ProtocolVersion is bumped on incompatible protocol changes
+const ProtocolVersion = 1
+
+// Message types
+const (
+	MsgHello    = "hello"    // Agent: first message, with Version, Host and Backend
+	MsgWindows  = "windows"  // Agent: the open windows, front to back
+	MsgFocus    = "focus"    // Agent: Window gained focus
+	MsgCapture  = "capture"  // Server: capture WindowIDs at FPS; none stops capturing
+	MsgActivate = "activate" // Server: focus and raise WindowID
+	MsgError    = "error"    // Either side: Error describes a problem (capturing WindowID, if set)
+)
+
+// Message is a control message
+type Message struct {
+	Type      string               `json:"type"`
+	Version   int                  `json:"version,omitempty"`
+	Host      string               `json:"host,omitempty"`
+	Backend   string               `json:"backend,omitempty"`
+	Windows   []*config.WindowInfo `json:"windows,omitempty"`
+	Window    *config.WindowInfo   `json:"window,omitempty"`
+	WindowID  uint32               `json:"window_id,omitempty"`
+	WindowIDs []uint32             `json:"window_ids,omitempty"`
+	FPS       int                  `json:"fps,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// frameHeaderSize is the size of the window ID before a frame's JPEG
+const frameHeaderSize = 4
+
+// encodeFrameHeader prefixes a frame message with the window ID
+func encodeFrameHeader(dst []byte, windowID uint32) []byte {
+	return binary.BigEndian.AppendUint32(dst, windowID)
+}
+
+// decodeFrameHeader splits a frame message into window ID and JPEG
+func decodeFrameHeader(data []byte) (uint32, []byte, error) {
+	if len(data) <= frameHeaderSize {
+		return 0, nil, fmt.Errorf("frame message too short (%d bytes)", len(data))
+	}
+	return binary.BigEndian.Uint32(data), data[frameHeaderSize:], nil
+}
@@ -0,0 +1,12 @@
+package session
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setParentDeathSignal has the kernel send the worker SIGTERM when the
+// daemon exits
+func setParentDeathSignal(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package session
+
+import "os/exec"
+
+// setParentDeathSignal is a no-op where the kernel can't signal children
+// of an exited parent; workers are still stopped on a clean shutdown
+func setParentDeathSignal(cmd *exec.Cmd) {}
@@ -0,0 +1,198 @@
+package session
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+const (
+	// EnvSessionName is set in the environment of worker processes. A worker
+	// serves only its own session and never starts workers of its own.
+	EnvSessionName = "FOCUSSTREAMER_SESSION"
+
+	// PrimaryName is the session served by the daemon process itself
+	PrimaryName = "default"
+
+	// PathPrefix is where sessions are served: /u/<name>/stream, /u/<name>/api/...
+	PathPrefix = "/u/"
+)
+
+// Info describes a session for /api/sessions
+type Info struct {
+	Name           string    `json:"name"`
+	Primary        bool      `json:"primary"` // Served by the daemon process rather than a worker
+	Display        string    `json:"display,omitempty"`
+	WaylandDisplay string    `json:"wayland_display,omitempty"`
+	Backend        string    `json:"backend"`
+	ConfigPath     string    `json:"config_path"`
+	Running        bool      `json:"running"`
+	PID            int       `json:"pid,omitempty"`
+	Port           int       `json:"port,omitempty"`
+	Restarts       int       `json:"restarts"`
+	StartedAt      time.Time `json:"started_at,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	BasePath       string    `json:"base_path"`
+	StreamURL      string    `json:"stream_url"`
+}
+
+// Manager runs a worker process for each additional session and routes
+// requests under /u/<name>/ to the process serving that session
+type Manager struct {
+	primary    Info
+	executable string
+
+	mu      sync.RWMutex
+	local   http.Handler
+	workers map[string]*worker
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewManager creates a session manager. primary describes the session served
+// by this process; sessions are the additional ones to run as workers.
+func NewManager(configMgr *config.Manager, primary Info, sessions []config.SessionConfig) (*Manager, error) {
+	if err := config.ValidateSessions(sessions, configMgr.GetPort()); err != nil {
+		return nil, err
+	}
+
+	m := &Manager{
+		primary:  primary,
+		workers:  make(map[string]*worker),
+		stopChan: make(chan struct{}),
+	}
+	m.primary.Primary = true
+	m.primary.Running = true
+	m.primary.PID = os.Getpid()
+	m.primary.StartedAt = time.Now()
+	m.primary.ConfigPath = configMgr.GetConfigPath()
+
+	if len(sessions) == 0 {
+		return m, nil
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate executable: %w", err)
+	}
+	m.executable = executable
+
+	for _, cfg := range sessions {
+		if cfg.Name == primary.Name {
+			return nil, fmt.Errorf("session name %s is reserved for this process", cfg.Name)
+		}
+		m.workers[cfg.Name] = newWorker(cfg, configMgr.GetSessionConfigPath(cfg.Name))
+	}
+
+	return m, nil
+}
+
+// SetLocalHandler sets the handler for requests to the primary session
+func (m *Manager) SetLocalHandler(handler http.Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.local = handler
+}
+
+// Start launches the worker processes
+func (m *Manager) Start() {
+	for _, w := range m.workers {
+		m.wg.Add(1)
+		go func(w *worker) {
+			defer m.wg.Done()
+			w.run(m.executable, m.stopChan)
+		}(w)
+	}
+}
+
+// Stop terminates the worker processes and waits for them to exit
+func (m *Manager) Stop() {
+	select {
+	case <-m.stopChan:
+		return
+	default:
+	}
+	close(m.stopChan)
+	m.wg.Wait()
+}
+
+// List returns the primary session followed by the workers, by name
+func (m *Manager) List() []Info {
+	sessions := []Info{withURLs(m.primary)}
+
+	names := make([]string, 0, len(m.workers))
+	for name := range m.workers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sessions = append(sessions, withURLs(m.workers[name].info()))
+	}
+	return sessions
+}
+
+// ServeHTTP serves /u/<name>/... by forwarding the rest of the path to the
+// session, with the X-Forwarded-Prefix header set so its pages link back
+// through the prefix
+func (m *Manager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+	if rest == "" && !strings.HasSuffix(r.URL.Path, "/") {
+		// /u/<name> is the session's viewer page, served at /u/<name>/
+		target := r.URL.Path + "/"
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+
+	var handler http.Handler
+	if name == m.primary.Name {
+		m.mu.RLock()
+		handler = m.local
+		m.mu.RUnlock()
+	} else if wk, ok := m.workers[name]; ok {
+		handler = wk.handler()
+		if handler == nil {
+			http.Error(w, fmt.Sprintf("session %s is not running", name), http.StatusServiceUnavailable)
+			return
+		}
+	}
+	if handler == nil {
+		http.Error(w, fmt.Sprintf("session not found: %s", name), http.StatusNotFound)
+		return
+	}
+
+	out := r.Clone(r.Context())
+	out.URL.Path = "/" + rest
+	out.URL.RawPath = ""
+	out.Header.Set(output.ForwardedPrefixHeader, PathPrefix+name)
+	handler.ServeHTTP(w, out)
+}
+
+// withURLs fills in where a session is served
+func withURLs(info Info) Info {
+	info.BasePath = PathPrefix + info.Name
+	info.StreamURL = info.BasePath + "/stream"
+	return info
+}
+
+// freePort asks the kernel for an unused local port
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
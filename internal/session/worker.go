@@ -0,0 +1,259 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+const (
+	// Delay before restarting a worker that exited, doubling up to
+	// maxRestartBackoff while it keeps failing (e.g. its X server isn't up)
+	minRestartBackoff = time.Second
+	maxRestartBackoff = time.Minute
+
+	// A worker that ran this long is considered healthy and resets the backoff
+	healthyRunTime = 30 * time.Second
+
+	// stopTimeout is how long a worker gets to shut down before it is killed
+	stopTimeout = 5 * time.Second
+)
+
+// worker runs `focusstreamer serve` for one additional session, bound to
+// that session's display, and restarts it when it exits
+type worker struct {
+	cfg        config.SessionConfig
+	configPath string
+
+	mu        sync.RWMutex
+	cmd       *exec.Cmd
+	port      int
+	proxy     *httputil.ReverseProxy
+	restarts  int
+	startedAt time.Time
+	lastError string
+}
+
+func newWorker(cfg config.SessionConfig, configPath string) *worker {
+	return &worker{
+		cfg:        cfg,
+		configPath: configPath,
+	}
+}
+
+// run keeps the worker process running until stop is closed
+func (w *worker) run(executable string, stop <-chan struct{}) {
+	log := logger.WithComponent("session").With().Str("session", w.cfg.Name).Logger()
+	backoff := minRestartBackoff
+
+	for {
+		started := time.Now()
+		err := w.runOnce(executable, stop)
+
+		select {
+		case <-stop:
+			log.Info().Msg("Session worker stopped")
+			return
+		default:
+		}
+
+		if time.Since(started) > healthyRunTime {
+			backoff = minRestartBackoff
+		}
+
+		w.mu.Lock()
+		w.restarts++
+		if err != nil {
+			w.lastError = err.Error()
+		}
+		w.mu.Unlock()
+
+		log.Warn().Err(err).Dur("restart_in", backoff).Msg("Session worker exited")
+
+		select {
+		case <-stop:
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRestartBackoff)
+	}
+}
+
+// runOnce starts the worker process and waits for it to exit, or stops it
+// when stop is closed
+func (w *worker) runOnce(executable string, stop <-chan struct{}) error {
+	log := logger.WithComponent("session").With().Str("session", w.cfg.Name).Logger()
+
+	port := w.cfg.Port
+	if port == 0 {
+		var err error
+		if port, err = freePort(); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"serve", "--config", w.configPath, "--port", strconv.Itoa(port)}
+	if w.cfg.Backend != "" {
+		args = append(args, "--backend", w.cfg.Backend)
+	}
+
+	cmd := exec.Command(executable, args...)
+	cmd.Env = w.environ()
+	// Take the worker down with us if the daemon dies without stopping it
+	setParentDeathSignal(cmd)
+
+	// Wait copies the output into the pipe until the worker exits
+	outR, outW := io.Pipe()
+	defer outW.Close()
+	cmd.Stdout = outW
+	cmd.Stderr = outW
+	go relayOutput(w.cfg.Name, outR)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start session worker: %w", err)
+	}
+
+	target := &url.URL{Scheme: "http", Host: "127.0.0.1:" + strconv.Itoa(port)}
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(target)
+			// Rewrite drops inbound forwarding headers; keep the prefix set
+			// by Manager.ServeHTTP
+			pr.Out.Header.Set(output.ForwardedPrefixHeader, pr.In.Header.Get(output.ForwardedPrefixHeader))
+		},
+		FlushInterval: -1, // Stream MJPEG frames as they are written
+	}
+
+	w.mu.Lock()
+	w.cmd = cmd
+	w.port = port
+	w.proxy = proxy
+	w.startedAt = time.Now()
+	w.mu.Unlock()
+
+	log.Info().
+		Int("pid", cmd.Process.Pid).
+		Int("port", port).
+		Str("display", w.cfg.Display).
+		Str("wayland_display", w.cfg.WaylandDisplay).
+		Msg("Session worker started")
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-exited:
+	case <-stop:
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case err = <-exited:
+		case <-time.After(stopTimeout):
+			cmd.Process.Kill()
+			err = <-exited
+		}
+	}
+
+	w.mu.Lock()
+	w.cmd = nil
+	w.proxy = nil
+	w.mu.Unlock()
+
+	if err == nil {
+		err = fmt.Errorf("session worker exited")
+	}
+	return err
+}
+
+// environ returns the worker environment: ours, bound to the session's
+// display. Display variables are always overridden so an X session doesn't
+// pick up our Wayland compositor and vice versa.
+func (w *worker) environ() []string {
+	sessionType := "x11"
+	if w.cfg.WaylandDisplay != "" {
+		sessionType = "wayland"
+	}
+
+	overrides := map[string]string{
+		"DISPLAY":                     w.cfg.Display,
+		"WAYLAND_DISPLAY":             w.cfg.WaylandDisplay,
+		"XDG_SESSION_TYPE":            sessionType,
+		"HYPRLAND_INSTANCE_SIGNATURE": "",
+		EnvSessionName:                w.cfg.Name,
+	}
+	for key, value := range w.cfg.Env {
+		overrides[key] = value
+	}
+
+	env := make([]string, 0, len(os.Environ())+len(overrides))
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, ok := overrides[key]; !ok {
+			env = append(env, kv)
+		}
+	}
+	for key, value := range overrides {
+		if value != "" {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// handler returns the proxy to the running worker, or nil if it isn't running
+func (w *worker) handler() http.Handler {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.proxy == nil {
+		return nil
+	}
+	return w.proxy
+}
+
+// info reports the worker state
+func (w *worker) info() Info {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	info := Info{
+		Name:           w.cfg.Name,
+		Display:        w.cfg.Display,
+		WaylandDisplay: w.cfg.WaylandDisplay,
+		Backend:        w.cfg.Backend,
+		ConfigPath:     w.configPath,
+		Running:        w.cmd != nil,
+		Port:           w.port,
+		Restarts:       w.restarts,
+		StartedAt:      w.startedAt,
+		LastError:      w.lastError,
+	}
+	if info.Backend == "" {
+		info.Backend = "auto"
+	}
+	if w.cmd != nil {
+		info.PID = w.cmd.Process.Pid
+	}
+	return info
+}
+
+// relayOutput copies worker output to our stderr, tagged with the session
+func relayOutput(name string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fmt.Fprintf(os.Stderr, "[%s] %s\n", name, scanner.Text())
+	}
+}
@@ -0,0 +1,184 @@
+// Package sharealert sends a desktop notification when an application is
+// shown on the stream for the first time, with an action to stop sharing.
+// It is a safety net for allowlist patterns that match more than intended.
+package sharealert
+
+import (
+	"fmt"
+	"html"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsService   = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+
+	actionStop = "stop"
+
+	// Critical urgency, so the alert shows while Do Not Disturb is on (see
+	// suppress_notifications), which is exactly when the stream is live
+	urgencyCritical = byte(2)
+
+	expireTimeoutMs = 15000
+)
+
+// Notifier shows share alerts over org.freedesktop.Notifications and calls
+// stop when the user picks "Stop sharing" on one
+type Notifier struct {
+	conn    *dbus.Conn
+	stop    func()
+	signals chan *dbus.Signal
+
+	mu     sync.Mutex
+	alerts chan *config.WindowInfo // Windows to announce, sent on run's goroutine
+	closed bool
+
+	// Notification IDs still shown, with their window class; only run
+	// touches it
+	pending map[uint32]string
+
+	done chan struct{}
+}
+
+// NewNotifier connects to the session bus and starts listening for
+// notification actions
+func NewNotifier(stop func()) (*Notifier, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", err)
+	}
+	if err := conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(notificationsPath),
+		dbus.WithMatchInterface(notificationsInterface),
+	); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to watch notification actions: %w", err)
+	}
+
+	n := &Notifier{
+		conn:    conn,
+		stop:    stop,
+		signals: make(chan *dbus.Signal, 16),
+		alerts:  make(chan *config.WindowInfo, 16),
+		pending: make(map[uint32]string),
+		done:    make(chan struct{}),
+	}
+	conn.Signal(n.signals)
+	go n.run()
+	return n, nil
+}
+
+// Shared announces a window whose class is on the stream for the first
+// time. It doesn't block; the notification is sent on another goroutine.
+func (n *Notifier) Shared(window *config.WindowInfo) {
+	if window == nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.closed {
+		return
+	}
+	select {
+	case n.alerts <- window:
+	default:
+		logger.WithComponent("sharealert").Warn().Str("class", window.Class).Msg("Share alert queue full, dropping alert")
+	}
+}
+
+// Close stops listening for actions and releases the bus connection
+func (n *Notifier) Close() {
+	n.mu.Lock()
+	if !n.closed {
+		n.closed = true
+		close(n.alerts)
+	}
+	n.mu.Unlock()
+
+	<-n.done
+	n.conn.RemoveSignal(n.signals)
+	n.conn.Close()
+}
+
+// run sends queued alerts and handles actions on shown ones until Close
+func (n *Notifier) run() {
+	defer close(n.done)
+
+	log := logger.WithComponent("sharealert")
+	for {
+		select {
+		case window, ok := <-n.alerts:
+			if !ok {
+				return
+			}
+			id, err := n.notify(window)
+			if err != nil {
+				log.Warn().Err(err).Str("class", window.Class).Msg("Failed to send share alert")
+				continue
+			}
+			n.pending[id] = window.Class
+			log.Info().Str("class", window.Class).Uint32("notification", id).Msg("New application on the stream")
+
+		case signal, ok := <-n.signals:
+			if !ok {
+				return
+			}
+			n.handleSignal(signal)
+		}
+	}
+}
+
+// notify shows the alert for a window and returns its notification ID
+func (n *Notifier) notify(window *config.WindowInfo) (uint32, error) {
+	body := fmt.Sprintf("%s is now on the stream", html.EscapeString(window.Class))
+	if window.Title != "" {
+		body = fmt.Sprintf("%s is now on the stream: %s", html.EscapeString(window.Class), html.EscapeString(window.Title))
+	}
+	hints := map[string]dbus.Variant{
+		"urgency":       dbus.MakeVariant(urgencyCritical),
+		"desktop-entry": dbus.MakeVariant("focusstreamer"),
+	}
+
+	var id uint32
+	err := n.conn.Object(notificationsService, notificationsPath).
+		Call(notificationsInterface+".Notify", 0,
+			"FocusStreamer", uint32(0), "video-display", "Sharing a new application", body,
+			[]string{actionStop, "Stop sharing"}, hints, int32(expireTimeoutMs)).
+		Store(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to send notification: %w", err)
+	}
+	return id, nil
+}
+
+// handleSignal stops sharing when "Stop sharing" is picked on one of our
+// notifications, and forgets notifications once they close
+func (n *Notifier) handleSignal(signal *dbus.Signal) {
+	if len(signal.Body) < 2 {
+		return
+	}
+	id, ok := signal.Body[0].(uint32)
+	if !ok {
+		return
+	}
+	class, ours := n.pending[id]
+	if !ours {
+		return
+	}
+
+	switch signal.Name {
+	case notificationsInterface + ".ActionInvoked":
+		if action, _ := signal.Body[1].(string); action == actionStop {
+			logger.WithComponent("sharealert").Info().Str("class", class).Msg("Stop sharing picked on share alert, switching to standby")
+			n.stop()
+		}
+	case notificationsInterface + ".NotificationClosed":
+		delete(n.pending, id)
+	}
+}
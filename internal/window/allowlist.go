@@ -0,0 +1,178 @@
+package window
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// Rules reported by AllowlistMatch
+const (
+	AllowlistRuleApp            = "app"             // Class is in allowlisted_apps
+	AllowlistRulePattern        = "pattern"         // An allowlist pattern matched the class or title
+	AllowlistRuleTitlePattern   = "title_pattern"   // A title-only pattern matched the title
+	AllowlistRuleURL            = "url_rule"        // A URL rule matched the browser's active tab
+	AllowlistRuleBrowserBlocked = "browser_blocked" // The browser class is blocked
+	AllowlistRuleSelf           = "self"            // One of FocusStreamer's own windows
+	AllowlistRuleBypass         = "bypass"          // The allowlist bypass is on
+)
+
+// AllowlistMatch explains an allowlist decision: which rule decided it and
+// whether the window would be streamed
+type AllowlistMatch struct {
+	Source  config.AllowlistSource `json:"source"`
+	Rule    string                 `json:"rule,omitempty"`  // One of the AllowlistRule constants; empty if nothing matched
+	Value   string                 `json:"value,omitempty"` // The app class, pattern, or URL pattern that decided
+	Field   string                 `json:"field,omitempty"` // What a pattern matched: class or title
+	Streams bool                   `json:"streams"`
+	Reason  string                 `json:"reason"`
+}
+
+// TestAllowlist reports how the allowlist of the active profile treats a
+// window with the given class and title, without the window having to
+// exist. For browser classes, urlValue stands in for the active tab;
+// without it the tab last reported by the extension is used.
+func (m *Manager) TestAllowlist(class, title, urlValue string) AllowlistMatch {
+	window := &config.WindowInfo{Class: class, Title: title}
+
+	if m.IsSelfWindow(window) {
+		return AllowlistMatch{
+			Rule:   AllowlistRuleSelf,
+			Reason: "FocusStreamer's own windows are never streamed (see capture.allow_self_capture)",
+		}
+	}
+
+	match := m.matchAllowlist(window, urlValue)
+	match.Streams = match.Source != config.AllowlistSourceNone
+
+	switch match.Rule {
+	case AllowlistRuleApp:
+		match.Reason = fmt.Sprintf("class %q is allowlisted", match.Value)
+	case AllowlistRulePattern, AllowlistRuleTitlePattern:
+		match.Reason = fmt.Sprintf("pattern %q matches the %s", match.Value, match.Field)
+	case AllowlistRuleURL:
+		match.Reason = fmt.Sprintf("URL rule %q matches the active tab", match.Value)
+	case AllowlistRuleBrowserBlocked:
+		match.Reason = fmt.Sprintf("browser %q is blocked", match.Value)
+	default:
+		match.Reason = "no allowlist rule matches"
+	}
+
+	if !match.Streams && m.GetAllowlistBypass() {
+		match.Rule = AllowlistRuleBypass
+		match.Streams = true
+		match.Reason += ", but the allowlist bypass is on"
+	}
+	return match
+}
+
+// PatternMatch is an open window that an allowlist pattern matches
+type PatternMatch struct {
+	ID    uint32 `json:"id"`
+	Class string `json:"class"`
+	Title string `json:"title"`
+	Field string `json:"field"` // What the pattern matched: class or title
+}
+
+// WindowsMatchingPattern lists the open windows an allowlist pattern would
+// match (by class or title, as allowlist patterns do) and how many windows
+// are open, so a pattern's effect can be previewed before it's saved
+func (m *Manager) WindowsMatchingPattern(re *regexp.Regexp) ([]PatternMatch, int, error) {
+	windows, err := m.registryWindows()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list windows: %w", err)
+	}
+
+	matches := make([]PatternMatch, 0)
+	for _, window := range windows {
+		field := ""
+		if re.MatchString(window.Class) {
+			field = "class"
+		} else if re.MatchString(window.Title) {
+			field = "title"
+		}
+		if field != "" {
+			matches = append(matches, PatternMatch{
+				ID:    window.ID,
+				Class: window.Class,
+				Title: window.Title,
+				Field: field,
+			})
+		}
+	}
+	return matches, len(windows), nil
+}
+
+// matchAllowlist finds the allowlist rule that matches a window. Explicit
+// apps take priority over patterns, then title-only patterns. Browser windows
+// are matched by URL rules against urlValue, or the extension's active tab
+// if urlValue is empty.
+func (m *Manager) matchAllowlist(window *config.WindowInfo, urlValue string) AllowlistMatch {
+	if window == nil {
+		return AllowlistMatch{}
+	}
+
+	if m.isBrowserWindow(window.Class) {
+		return m.matchBrowserAllowlist(window, urlValue)
+	}
+
+	cfg := m.configMgr.Get()
+
+	// Normalize class to lowercase for comparison
+	normalizedClass := strings.ToLower(window.Class)
+
+	// Check exact match in allowlisted apps first (explicit takes priority)
+	for _, app := range cfg.AllowlistedApps {
+		if app == normalizedClass {
+			return AllowlistMatch{Source: config.AllowlistSourceExplicit, Rule: AllowlistRuleApp, Value: app}
+		}
+	}
+
+	// Check pattern matching (matches against both class and title)
+	for _, pattern := range cfg.AllowlistPatterns {
+		if matched, err := regexp.MatchString(pattern, window.Class); err == nil && matched {
+			return AllowlistMatch{Source: config.AllowlistSourcePattern, Rule: AllowlistRulePattern, Value: pattern, Field: "class"}
+		}
+		if matched, err := regexp.MatchString(pattern, window.Title); err == nil && matched {
+			return AllowlistMatch{Source: config.AllowlistSourcePattern, Rule: AllowlistRulePattern, Value: pattern, Field: "title"}
+		}
+	}
+
+	// Check title-only patterns (matches against title only)
+	for _, pattern := range cfg.AllowlistTitlePatterns {
+		if matched, err := regexp.MatchString(pattern, window.Title); err == nil && matched {
+			return AllowlistMatch{Source: config.AllowlistSourcePattern, Rule: AllowlistRuleTitlePattern, Value: pattern, Field: "title"}
+		}
+	}
+
+	return AllowlistMatch{}
+}
+
+// matchBrowserAllowlist matches a browser window by the URL of its tab
+func (m *Manager) matchBrowserAllowlist(window *config.WindowInfo, urlValue string) AllowlistMatch {
+	if m.configMgr.IsBrowserBlocked(window.Class) {
+		return AllowlistMatch{Rule: AllowlistRuleBrowserBlocked, Value: strings.ToLower(window.Class)}
+	}
+
+	if urlValue == "" {
+		ctx, ok := m.GetBrowserContext(window.Class)
+		if !ok || !m.isBrowserContextFresh(ctx) {
+			return AllowlistMatch{}
+		}
+
+		// The extension reports the active tab of the last focused browser
+		// window; if this window's title doesn't contain that tab's title, it is
+		// a different browser window whose tab we don't know
+		if ctx.Title != "" && window.Title != "" && !strings.Contains(window.Title, ctx.Title) {
+			return AllowlistMatch{}
+		}
+		urlValue = ctx.URL
+	}
+
+	if rule, ok := m.matchingURLRule(urlValue); ok {
+		return AllowlistMatch{Source: config.AllowlistSourceURL, Rule: AllowlistRuleURL, Value: rule.Pattern}
+	}
+	return AllowlistMatch{}
+}
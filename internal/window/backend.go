@@ -30,4 +30,123 @@ type Backend interface {
 
 	// Name returns the backend name (e.g., "x11", "kwin")
 	Name() string
+
+	// Capabilities reports which features the backend supports
+	Capabilities() Capabilities
+}
+
+// Capabilities describes what a backend supports, so the manager and the UI
+// can turn features on or off up front instead of failing at runtime
+type Capabilities struct {
+	// Window geometry is in screen coordinates, exact enough to crop a
+	// window out of a monitor-wide capture
+	AccurateGeometry bool `json:"accurate_geometry"`
+
+	// Virtual desktops are reported, so desktops rules apply
+	Desktops bool `json:"desktops"`
+
+	// Windows are captured on their own rather than cropped out of their
+	// monitor, so they can be captured while covered
+	NativeCapture bool `json:"native_capture"`
+
+	// Windows can be focused on request (WindowActivator)
+	Activation bool `json:"activation"`
+
+	// Focus changes are pushed as they happen rather than polled
+	FocusEvents bool `json:"focus_events"`
+
+	// Title, geometry and close of the shared window are pushed as they
+	// happen (TitleWatcher, GeometryWatcher, and CloseWatcher or window
+	// events)
+	TitleEvents    bool `json:"title_events"`
+	GeometryEvents bool `json:"geometry_events"`
+	CloseEvents    bool `json:"close_events"`
+
+	// Windows opening, changing and closing are pushed (WindowEventWatcher)
+	WindowEvents bool `json:"window_events"`
+
+	// The mouse pointer position is reported (PointerTracker)
+	Pointer bool `json:"pointer"`
+}
+
+// TitleWatcher is implemented by backends that can report title changes of
+// a window other than the focused one (the window being shared) without
+// waiting for the focus poll
+type TitleWatcher interface {
+	// WatchTitle subscribes to title changes of one window, replacing any
+	// previous subscription. A windowID of 0 unsubscribes.
+	WatchTitle(windowID uint32, callback func(windowID uint32, title string)) error
+}
+
+// GeometryWatcher is implemented by backends that can report moves and
+// resizes of the window being shared as they happen, so crops follow the
+// window without waiting for the focus poll
+type GeometryWatcher interface {
+	// WatchGeometry subscribes to geometry changes of one window, replacing
+	// any previous subscription. A windowID of 0 unsubscribes.
+	WatchGeometry(windowID uint32, callback func(windowID uint32, geometry config.Geometry)) error
+}
+
+// CloseWatcher is implemented by backends that can report the window being
+// shared closing as it happens, so the stream hands off to the next window
+// instead of sending stale frames until a capture fails
+type CloseWatcher interface {
+	// WatchClose subscribes to the closing of one window, replacing any
+	// previous subscription. A windowID of 0 unsubscribes.
+	WatchClose(windowID uint32, callback func(windowID uint32)) error
+}
+
+// PointerTracker is implemented by backends that can report where the mouse
+// pointer is, for pointer highlighting
+type PointerTracker interface {
+	// PointerPosition returns the pointer position relative to a window's
+	// top-left corner, in the units of its geometry, and whether a mouse
+	// button is held (false where the backend can't tell)
+	PointerPosition(window *config.WindowInfo) (x, y int, pressed bool, err error)
+}
+
+// WindowActivator is implemented by backends that can focus and raise a
+// window on request, e.g. to switch the shared window from another device
+type WindowActivator interface {
+	// ActivateWindow focuses and raises a window, switching to its desktop
+	// and un-minimizing it where the window manager supports that
+	ActivateWindow(windowID uint32) error
+}
+
+// ConnectionMonitor is implemented by backends that can tell when their
+// display server connection is lost (X server reset after suspend/resume,
+// D-Bus session restart, compositor socket closed). A lost backend cannot
+// recover; the manager replaces it with a freshly connected one.
+type ConnectionMonitor interface {
+	// Disconnected returns a channel that is closed when the connection is lost
+	Disconnected() <-chan struct{}
+}
+
+// WindowEventType is what a WindowEvent reports
+type WindowEventType int
+
+const (
+	WindowOpened       WindowEventType = iota // A window opened; Window is set
+	WindowChanged                             // A window changed; Window holds its new state
+	WindowTitleChanged                        // A window's title changed; Title is set
+	WindowClosed                              // A window closed; only ID is set
+	WindowsResync                             // Windows changed in a way the event can't describe; enumerate again
+)
+
+// WindowEvent reports a change to the open windows
+type WindowEvent struct {
+	Type   WindowEventType
+	ID     uint32
+	Window *config.WindowInfo
+	Title  string
+}
+
+// WindowEventWatcher is implemented by backends that report windows
+// opening, closing and changing as it happens, so the manager's window
+// registry stays current without enumerating windows on every read
+type WindowEventWatcher interface {
+	// WatchWindows subscribes to window events, replacing any previous
+	// subscription. A nil callback unsubscribes. Events are only delivered
+	// while WatchFocus is running.
+	WatchWindows(callback func(WindowEvent)) error
 }
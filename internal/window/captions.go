@@ -0,0 +1,259 @@
+package window
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// DefaultCaptionDuration applies when a caption is posted without one
+	DefaultCaptionDuration = 5 * time.Second
+
+	// MaxCaptionDuration bounds how long one caption stays on the stream
+	MaxCaptionDuration = time.Minute
+
+	// MaxCaptionLength bounds a caption's text, in characters
+	MaxCaptionLength = 120
+
+	// maxQueuedCaptions bounds the captions waiting to be shown
+	maxQueuedCaptions = 20
+
+	// maxCaptionHistory bounds the captions kept for GetCaptions; the oldest
+	// are dropped first
+	maxCaptionHistory = 200
+
+	// captionFade is how long a caption takes to fade in and out
+	captionFade = 400 * time.Millisecond
+)
+
+// ErrCaptionQueueFull is returned when too many captions are waiting
+var ErrCaptionQueueFull = errors.New("too many captions queued")
+
+// Caption is a short message shown along the bottom of the stream for a
+// while, e.g. "back in 5"
+type Caption struct {
+	ID       uint64        `json:"id"`
+	Text     string        `json:"text"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"duration_seconds"`
+	Source   string        `json:"source,omitempty"` // Who posted it, e.g. the client address
+	Posted   time.Time     `json:"posted"`
+	Shown    *time.Time    `json:"shown,omitempty"` // When it first went on the stream
+}
+
+// CaptionState is the caption on the stream, the ones waiting behind it and
+// the ones posted since the server started
+type CaptionState struct {
+	Current *Caption  `json:"current"`
+	Queued  []Caption `json:"queued"`
+	History []Caption `json:"history"` // Oldest first
+}
+
+// captionQueue shows captions one after another
+type captionQueue struct {
+	mu      sync.Mutex
+	nextID  uint64
+	current *Caption
+	queued  []Caption
+	history []Caption
+}
+
+// add queues a caption behind the ones already waiting
+func (q *captionQueue) add(text string, duration time.Duration, source string, now time.Time) (Caption, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return Caption{}, fmt.Errorf("caption text is empty")
+	}
+	if n := utf8.RuneCountInString(text); n > MaxCaptionLength {
+		return Caption{}, fmt.Errorf("caption is %d characters, at most %d are allowed", n, MaxCaptionLength)
+	}
+	if duration == 0 {
+		duration = DefaultCaptionDuration
+	}
+	if duration < time.Second || duration > MaxCaptionDuration {
+		return Caption{}, fmt.Errorf("caption duration must be between 1s and %s", MaxCaptionDuration)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.queued) >= maxQueuedCaptions {
+		return Caption{}, ErrCaptionQueueFull
+	}
+
+	q.nextID++
+	c := Caption{ID: q.nextID, Text: text, Duration: duration, Seconds: duration.Seconds(), Source: source, Posted: now}
+	q.queued = append(q.queued, c)
+
+	if len(q.history) >= maxCaptionHistory {
+		q.history = append(q.history[:0], q.history[1:]...)
+	}
+	q.history = append(q.history, c)
+	return c, nil
+}
+
+// active returns the caption to draw at now and its opacity, moving on to
+// the next queued caption once the current one has run its course
+func (q *captionQueue) active(now time.Time) (Caption, float64, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.current != nil && now.Sub(*q.current.Shown) >= q.current.Duration {
+		q.current = nil
+	}
+	if q.current == nil {
+		if len(q.queued) == 0 {
+			return Caption{}, 0, false
+		}
+		c := q.queued[0]
+		q.queued = q.queued[1:]
+		shown := now
+		c.Shown = &shown
+		q.current = &c
+		q.markShown(c)
+
+		logger.WithComponent("annotations").Info().
+			Uint64("id", c.ID).
+			Str("text", c.Text).
+			Msg("Caption shown")
+	}
+
+	c := *q.current
+	elapsed := now.Sub(*c.Shown)
+	fade := min(captionFade, c.Duration/4)
+	alpha := 1.0
+	switch {
+	case elapsed < fade:
+		alpha = float64(elapsed) / float64(fade)
+	case c.Duration-elapsed < fade:
+		alpha = float64(c.Duration-elapsed) / float64(fade)
+	}
+	return c, alpha, true
+}
+
+// markShown records when a caption went on the stream in the history
+func (q *captionQueue) markShown(c Caption) {
+	for i := len(q.history) - 1; i >= 0; i-- {
+		if q.history[i].ID == c.ID {
+			q.history[i].Shown = c.Shown
+			return
+		}
+	}
+}
+
+// clear drops the current and queued captions
+func (q *captionQueue) clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.current = nil
+	q.queued = nil
+}
+
+// snapshot returns copies of the current, queued and past captions
+func (q *captionQueue) snapshot() CaptionState {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state := CaptionState{
+		Queued:  append([]Caption{}, q.queued...),
+		History: append([]Caption{}, q.history...),
+	}
+	if q.current != nil {
+		c := *q.current
+		state.Current = &c
+	}
+	return state
+}
+
+// AddCaption queues a caption to be shown on the stream for duration (zero
+// for DefaultCaptionDuration). Captions are shown one at a time, in the order
+// they were posted, and every one is logged.
+func (m *Manager) AddCaption(text string, duration time.Duration, source string) (Caption, error) {
+	c, err := m.captions.add(text, duration, source, time.Now())
+	if err != nil {
+		return Caption{}, err
+	}
+
+	logger.WithComponent("annotations").Info().
+		Uint64("id", c.ID).
+		Str("text", c.Text).
+		Dur("duration", c.Duration).
+		Str("source", c.Source).
+		Msg("Caption posted")
+
+	m.requestFrame()
+	return c, nil
+}
+
+// ClearCaptions removes the caption on the stream and the queued ones
+func (m *Manager) ClearCaptions() {
+	m.captions.clear()
+	logger.WithComponent("annotations").Info().Msg("Captions cleared")
+	m.requestFrame()
+}
+
+// GetCaptions returns the current, queued and past captions
+func (m *Manager) GetCaptions() CaptionState {
+	return m.captions.snapshot()
+}
+
+// captionStage draws the active caption along the bottom of the frame
+func (m *Manager) captionStage(f *Frame) error {
+	drawActiveCaption(f.Image, &m.captions)
+	return nil
+}
+
+// drawActiveCaption draws the caption active now, if any
+func drawActiveCaption(img *image.RGBA, q *captionQueue) {
+	c, alpha, ok := q.active(time.Now())
+	if !ok || alpha <= 0 {
+		return
+	}
+	drawCaption(img, c.Text, alpha)
+}
+
+// drawCaption draws text centered in a dark box near the bottom of the image,
+// scaled up so it stays legible at stream resolutions
+func drawCaption(img *image.RGBA, text string, alpha float64) {
+	const padding = 6
+	bounds := img.Bounds()
+	face := basicfont.Face7x13
+
+	d := &font.Drawer{Face: face}
+	width := d.MeasureString(text).Ceil() + 2*padding
+	height := face.Height + 2*padding
+
+	// Render the caption at 1x with premultiplied colors faded by alpha,
+	// then scale it onto the frame
+	a := uint8(alpha * 255)
+	box := image.NewRGBA(image.Rect(0, 0, width, height))
+	bg := uint8(alpha * 180)
+	for i := 0; i < len(box.Pix); i += 4 {
+		box.Pix[i+3] = bg
+	}
+	d.Dst = box
+	d.Src = image.NewUniform(color.RGBA{a, a, a, a})
+	d.Dot = fixed.P(padding, padding+face.Ascent)
+	d.DrawString(text)
+
+	scale := max(1, bounds.Dy()/360)
+	for scale > 1 && width*scale > bounds.Dx() {
+		scale--
+	}
+	dw, dh := width*scale, height*scale
+	x := bounds.Min.X + (bounds.Dx()-dw)/2
+	y := bounds.Max.Y - dh - bounds.Dy()/12
+	xdraw.NearestNeighbor.Scale(img, image.Rect(x, y, x+dw, y+dh), box, box.Bounds(), xdraw.Over, nil)
+}
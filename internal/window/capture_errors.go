@@ -0,0 +1,158 @@
+package window
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	// captureErrorThreshold is how many frames in a row must fail to
+	// capture before the stream shows the capture error card instead of
+	// the placeholder
+	captureErrorThreshold = 3
+
+	// captureFailureWindow is the period CaptureFailures.Recent counts over
+	captureFailureWindow = 5 * time.Minute
+)
+
+// Capture paths reported by CaptureFailures, besides the capture router's
+// capturer names ("x11", "pipewire", "synthetic")
+const captureX11Direct = "x11-direct" // Direct X11 capture after the router failed
+
+// CaptureFailures reports the capture failures of one capture backend
+type CaptureFailures struct {
+	Backend     string    `json:"backend"`
+	Recent      int       `json:"recent"` // In the last five minutes
+	Total       uint64    `json:"total"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// captureFailureLog keeps rolling capture failure counts per backend
+type captureFailureLog struct {
+	mu       sync.Mutex
+	backends map[string]*backendFailures
+}
+
+type backendFailures struct {
+	times       []time.Time // Failures within captureFailureWindow, oldest first
+	total       uint64
+	lastError   string
+	lastFailure time.Time
+}
+
+func newCaptureFailureLog() *captureFailureLog {
+	return &captureFailureLog{backends: make(map[string]*backendFailures)}
+}
+
+// record counts a failure of a backend
+func (l *captureFailureLog) record(backend string, err error, now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b, ok := l.backends[backend]
+	if !ok {
+		b = &backendFailures{}
+		l.backends[backend] = b
+	}
+	b.trim(now)
+	b.times = append(b.times, now)
+	b.total++
+	b.lastFailure = now
+	if err != nil {
+		b.lastError = err.Error()
+	}
+}
+
+// trim drops failures older than captureFailureWindow
+func (b *backendFailures) trim(now time.Time) {
+	cutoff := now.Add(-captureFailureWindow)
+	i := sort.Search(len(b.times), func(i int) bool { return b.times[i].After(cutoff) })
+	b.times = b.times[i:]
+}
+
+// snapshot returns the counts of every backend that has failed, by name
+func (l *captureFailureLog) snapshot(now time.Time) []CaptureFailures {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	failures := make([]CaptureFailures, 0, len(l.backends))
+	for name, b := range l.backends {
+		b.trim(now)
+		failures = append(failures, CaptureFailures{
+			Backend:     name,
+			Recent:      len(b.times),
+			Total:       b.total,
+			LastError:   b.lastError,
+			LastFailure: b.lastFailure,
+		})
+	}
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Backend < failures[j].Backend })
+	return failures
+}
+
+// GetCaptureFailures returns rolling capture failure counts per backend
+func (m *Manager) GetCaptureFailures() []CaptureFailures {
+	return m.captureFailures.snapshot(time.Now())
+}
+
+// showCaptureError switches a frame to the capture error card, shown
+// instead of the placeholder while capture keeps failing so viewers (and
+// the streamer) can tell a problem from an intentional pause
+func (m *Manager) showCaptureError(f *Frame) {
+	f.Standby = true
+	f.StandbyReason = StandbyCaptureFailed
+	f.Window = nil
+
+	cfg := m.configMgr.Get()
+	f.Replace(drawCaptureErrorCard(cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height))
+}
+
+// drawCaptureErrorCard draws the "technical difficulties" card: a red
+// bordered box centered on a dark background
+func drawCaptureErrorCard(width, height int) *image.RGBA {
+	img := framepool.Get(width, height)
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{25, 18, 20, 255}}, image.Point{}, draw.Src)
+
+	lines := []string{
+		"TECHNICAL DIFFICULTIES",
+		"",
+		"The shared window can't be captured right now.",
+		"The stream will resume automatically.",
+	}
+	const lineHeight = 18
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.RGBA{230, 225, 225, 255}),
+		Face: basicfont.Face7x13,
+	}
+	boxWidth := 0
+	for _, line := range lines {
+		boxWidth = max(boxWidth, d.MeasureString(line).Ceil())
+	}
+	boxWidth += 8 * standbyStatsPadding
+	boxHeight := len(lines)*lineHeight + 6*standbyStatsPadding
+
+	box := image.Rect((width-boxWidth)/2, (height-boxHeight)/2, (width+boxWidth)/2, (height+boxHeight)/2)
+	draw.Draw(img, box, &image.Uniform{color.RGBA{180, 40, 40, 255}}, image.Point{}, draw.Src)
+	draw.Draw(img, box.Inset(2), &image.Uniform{color.RGBA{40, 24, 26, 255}}, image.Point{}, draw.Src)
+
+	for i, line := range lines {
+		d.Dot = fixed.Point26_6{
+			X: (fixed.I(width) - d.MeasureString(line)) / 2,
+			Y: fixed.I(box.Min.Y + 3*standbyStatsPadding + 13 + i*lineHeight - 3),
+		}
+		d.DrawString(line)
+	}
+	return img
+}
@@ -0,0 +1,21 @@
+package window
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime returns the user and kernel CPU time used by the process
+func processCPUTime() time.Duration {
+	var creation, exit, kernel, user syscall.Filetime
+	process, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0
+	}
+	if err := syscall.GetProcessTimes(process, &creation, &exit, &kernel, &user); err != nil {
+		return 0
+	}
+	// Filetimes count 100ns intervals
+	ticks := func(ft syscall.Filetime) int64 { return int64(ft.HighDateTime)<<32 | int64(ft.LowDateTime) }
+	return time.Duration((ticks(kernel) + ticks(user)) * 100)
+}
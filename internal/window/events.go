@@ -0,0 +1,97 @@
+package window
+
+import (
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventStandbyReasons maps standby reasons to their protobuf enum
+var eventStandbyReasons = map[StandbyReason]events.StandbyReason{
+	StandbyNone:             events.StandbyReason_STANDBY_REASON_NONE,
+	StandbyManual:           events.StandbyReason_STANDBY_REASON_MANUAL,
+	StandbyDesktop:          events.StandbyReason_STANDBY_REASON_DESKTOP,
+	StandbyPanic:            events.StandbyReason_STANDBY_REASON_PANIC,
+	StandbyNoWindow:         events.StandbyReason_STANDBY_REASON_NO_WINDOW,
+	StandbyNotAllowlisted:   events.StandbyReason_STANDBY_REASON_NOT_ALLOWLISTED,
+	StandbyCaptureFailed:    events.StandbyReason_STANDBY_REASON_CAPTURE_FAILED,
+	StandbySensitiveContent: events.StandbyReason_STANDBY_REASON_SENSITIVE_CONTENT,
+}
+
+// SetOnEventCallback sets a callback invoked with each focus, standby, zoom
+// and capture error event. It is called on the stream loop and mustn't
+// block.
+func (m *Manager) SetOnEventCallback(callback func(event *events.Event)) {
+	m.streamMu.Lock()
+	m.eventCallback = callback
+	m.streamMu.Unlock()
+}
+
+// publishEvent stamps an event and passes it to the event callback, if set
+func (m *Manager) publishEvent(event *events.Event) {
+	m.streamMu.Lock()
+	callback := m.eventCallback
+	m.streamMu.Unlock()
+
+	if callback == nil {
+		return
+	}
+	event.Time = timestamppb.Now()
+	callback(event)
+}
+
+func (m *Manager) publishFocusChanged(window *config.WindowInfo) {
+	m.publishEvent(&events.Event{Payload: &events.Event_FocusChanged{
+		FocusChanged: &events.FocusChanged{Window: eventWindow(window)},
+	}})
+}
+
+// publishStandbyChanged publishes a standby change when the stream switched
+// between a window and the placeholder, to another window, or to another
+// standby reason since before, and a capture error when it switched to the
+// capture error reason
+func (m *Manager) publishStandbyChanged(before streamState, standby bool, reason StandbyReason, shared *config.WindowInfo) {
+	if standby == before.wasInStandby && reason == before.standbyReason &&
+		windowID(shared) == windowID(before.sharedWindow) {
+		return
+	}
+
+	m.publishEvent(&events.Event{Payload: &events.Event_StandbyChanged{
+		StandbyChanged: &events.StandbyChanged{
+			Standby:      standby,
+			Reason:       eventStandbyReasons[reason],
+			SharedWindow: eventWindow(shared),
+		},
+	}})
+
+	if reason == StandbyCaptureFailed && before.standbyReason != StandbyCaptureFailed {
+		// The window shown until capture failed
+		m.publishCaptureError(events.CaptureErrorSource_CAPTURE_ERROR_SOURCE_WINDOW,
+			"Capturing the shared window failed", before.sharedWindow)
+	}
+}
+
+func (m *Manager) publishZoomChanged(state ZoomState) {
+	m.publishEvent(&events.Event{Payload: &events.Event_ZoomChanged{
+		ZoomChanged: &events.ZoomChanged{Scale: state.Scale, OffsetX: state.OffsetX, OffsetY: state.OffsetY},
+	}})
+}
+
+func (m *Manager) publishCaptureError(source events.CaptureErrorSource, message string, window *config.WindowInfo) {
+	m.publishEvent(&events.Event{Payload: &events.Event_CaptureError{
+		CaptureError: &events.CaptureError{Source: source, Message: message, Window: eventWindow(window)},
+	}})
+}
+
+// eventWindow converts a window to its event form, or nil for no window
+func eventWindow(window *config.WindowInfo) *events.Window {
+	if window == nil {
+		return nil
+	}
+	return &events.Window{
+		Id:    window.ID,
+		Class: window.Class,
+		Title: window.Title,
+		Pid:   int32(window.PID),
+	}
+}
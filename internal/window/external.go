@@ -0,0 +1,61 @@
+package window
+
+import (
+	"fmt"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/ingest"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// ExternalSources returns the frames pushed by other programs (see the
+// ingest package)
+func (m *Manager) ExternalSources() *ingest.Sources {
+	return m.external
+}
+
+// SelectExternalSource streams the named external source in place of the
+// focused window, or the focused window again for "". The source is shown
+// as a window of class "external" titled with its name, so it must be
+// allowlisted like any window; until it sends frames, or once it goes
+// quiet, the placeholder is shown.
+func (m *Manager) SelectExternalSource(name string) error {
+	if name != "" && !ingest.ValidName(name) {
+		return fmt.Errorf("invalid source name %q", name)
+	}
+
+	m.streamMu.Lock()
+	m.externalSource = name
+	m.streamMu.Unlock()
+
+	logger.WithComponent("stream").Info().Str("source", name).Msg("External source selected")
+	m.notifyStateChange()
+	m.requestFrame()
+	return nil
+}
+
+// SelectedExternalSource returns the external source streamed in place of
+// the focused window, empty if none
+func (m *Manager) SelectedExternalSource() string {
+	m.streamMu.Lock()
+	defer m.streamMu.Unlock()
+	return m.externalSource
+}
+
+// selectSource returns the selected external source's window, or else the
+// window selectWindow picks
+func (m *Manager) selectSource(st streamState, currentDesktop int) (*config.WindowInfo, StandbyReason) {
+	name := m.SelectedExternalSource()
+	if name == "" {
+		return m.selectWindow(st, currentDesktop)
+	}
+
+	window := m.external.Window(name)
+	if window == nil {
+		return nil, StandbyNoWindow
+	}
+	if !m.canStream(window, st.allowlistBypass) {
+		return nil, StandbyNotAllowlisted
+	}
+	return window, StandbyNone
+}
@@ -0,0 +1,359 @@
+package window
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// Health tracking tuning: a backend that fails this many calls in a row is
+// skipped for the cooldown period (unless it is the last one in the chain)
+const (
+	fallbackFailureThreshold = 3
+	fallbackCooldown         = 30 * time.Second
+)
+
+// BackendHealth reports the call health of one backend in a fallback chain
+type BackendHealth struct {
+	Name                string    `json:"name"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	TotalFailures       uint64    `json:"total_failures"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastSuccess         time.Time `json:"last_success"`
+	CooldownUntil       time.Time `json:"cooldown_until,omitempty"`
+}
+
+// FallbackBackend implements the Backend interface by composing several
+// backends. Each call goes to the most preferred healthy backend for that
+// operation and falls back down the chain on error, so e.g. KWin can serve
+// window listing while X11 still answers focus queries.
+type FallbackBackend struct {
+	backends   []Backend // All distinct backends, for Close and Name
+	listChain  []Backend // Preference order for ListWindows
+	focusChain []Backend // Preference order for focus and desktop queries
+
+	mu      sync.Mutex
+	health  map[Backend]*BackendHealth
+	watcher Backend       // Backend currently running WatchFocus
+	lost    chan struct{} // See Disconnected
+}
+
+// NewFallbackBackend creates a fallback backend. listChain and focusChain
+// give the preference order for listing and for focus/desktop queries.
+func NewFallbackBackend(listChain, focusChain []Backend) *FallbackBackend {
+	f := &FallbackBackend{
+		listChain:  listChain,
+		focusChain: focusChain,
+		health:     make(map[Backend]*BackendHealth),
+	}
+
+	for _, chain := range [][]Backend{listChain, focusChain} {
+		for _, b := range chain {
+			if _, ok := f.health[b]; ok {
+				continue
+			}
+			f.backends = append(f.backends, b)
+			f.health[b] = &BackendHealth{Name: b.Name(), Healthy: true}
+		}
+	}
+
+	return f
+}
+
+// Connect connects all backends, returning the first error
+func (f *FallbackBackend) Connect() error {
+	for _, b := range f.backends {
+		if err := b.Connect(); err != nil {
+			return fmt.Errorf("failed to connect %s backend: %w", b.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Close closes all backends
+func (f *FallbackBackend) Close() error {
+	f.StopWatching()
+
+	var firstErr error
+	for _, b := range f.backends {
+		if err := b.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Name returns the chained backend names (e.g. "kwin+x11")
+func (f *FallbackBackend) Name() string {
+	names := make([]string, len(f.backends))
+	for i, b := range f.backends {
+		names[i] = b.Name()
+	}
+	return strings.Join(names, "+")
+}
+
+// Capabilities reports those of the backend watching focus (the first in
+// the focus chain before WatchFocus), which serves the watch, activation
+// and pointer calls, with geometry accuracy from the first listing
+// backend. Window events aren't passed through the chain.
+func (f *FallbackBackend) Capabilities() Capabilities {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.mu.Unlock()
+
+	var caps Capabilities
+	if watcher != nil {
+		caps = watcher.Capabilities()
+	} else if len(f.focusChain) > 0 {
+		caps = f.focusChain[0].Capabilities()
+	}
+	if len(f.listChain) > 0 {
+		caps.AccurateGeometry = f.listChain[0].Capabilities().AccurateGeometry
+	}
+	caps.WindowEvents = false
+	return caps
+}
+
+// ListWindows lists windows using the first healthy backend that succeeds
+func (f *FallbackBackend) ListWindows() ([]*config.WindowInfo, error) {
+	var windows []*config.WindowInfo
+	err := f.try(f.listChain, "list windows", func(b Backend) error {
+		var err error
+		windows, err = b.ListWindows()
+		return err
+	})
+	return windows, err
+}
+
+// GetFocusedWindow returns the focused window using the first healthy
+// backend that succeeds
+func (f *FallbackBackend) GetFocusedWindow() (*config.WindowInfo, error) {
+	var info *config.WindowInfo
+	err := f.try(f.focusChain, "get focused window", func(b Backend) error {
+		var err error
+		info, err = b.GetFocusedWindow()
+		return err
+	})
+	return info, err
+}
+
+// GetCurrentDesktop returns the current desktop from the first healthy
+// focus backend
+func (f *FallbackBackend) GetCurrentDesktop() int {
+	for _, b := range f.candidates(f.focusChain) {
+		return b.GetCurrentDesktop()
+	}
+	return 0
+}
+
+// WatchFocus starts focus watching on the first focus backend that accepts it
+func (f *FallbackBackend) WatchFocus(callback func(*config.WindowInfo)) error {
+	var watcher Backend
+	err := f.try(f.focusChain, "watch focus", func(b Backend) error {
+		if err := b.WatchFocus(callback); err != nil {
+			return err
+		}
+		watcher = b
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.watcher = watcher
+	f.mu.Unlock()
+
+	logger.WithComponent("fallback-backend").Info().
+		Str("backend", watcher.Name()).
+		Msg("Watching focus")
+	return nil
+}
+
+// StopWatching stops the active focus watcher
+func (f *FallbackBackend) StopWatching() {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.watcher = nil
+	f.mu.Unlock()
+
+	if watcher != nil {
+		watcher.StopWatching()
+	}
+}
+
+// WatchTitle delegates to the backend watching focus, whose window IDs the
+// caller is using
+func (f *FallbackBackend) WatchTitle(windowID uint32, callback func(windowID uint32, title string)) error {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.mu.Unlock()
+
+	titleWatcher, ok := watcher.(TitleWatcher)
+	if !ok {
+		return fmt.Errorf("active backend does not support title watching")
+	}
+	return titleWatcher.WatchTitle(windowID, callback)
+}
+
+// WatchGeometry delegates to the backend watching focus, whose window IDs
+// the caller is using
+func (f *FallbackBackend) WatchGeometry(windowID uint32, callback func(windowID uint32, geometry config.Geometry)) error {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.mu.Unlock()
+
+	geometryWatcher, ok := watcher.(GeometryWatcher)
+	if !ok {
+		return fmt.Errorf("active backend does not support geometry watching")
+	}
+	return geometryWatcher.WatchGeometry(windowID, callback)
+}
+
+// WatchClose delegates to the backend watching focus, whose window IDs the
+// caller is using
+func (f *FallbackBackend) WatchClose(windowID uint32, callback func(windowID uint32)) error {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.mu.Unlock()
+
+	closeWatcher, ok := watcher.(CloseWatcher)
+	if !ok {
+		return fmt.Errorf("active backend does not support close watching")
+	}
+	return closeWatcher.WatchClose(windowID, callback)
+}
+
+// PointerPosition delegates to the backend watching focus, whose windows
+// the caller is using
+func (f *FallbackBackend) PointerPosition(window *config.WindowInfo) (int, int, bool, error) {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.mu.Unlock()
+
+	tracker, ok := watcher.(PointerTracker)
+	if !ok {
+		return 0, 0, false, fmt.Errorf("active backend does not support pointer tracking")
+	}
+	return tracker.PointerPosition(window)
+}
+
+// ActivateWindow delegates to the backend watching focus, whose window IDs
+// the caller is using
+func (f *FallbackBackend) ActivateWindow(windowID uint32) error {
+	f.mu.Lock()
+	watcher := f.watcher
+	f.mu.Unlock()
+
+	activator, ok := watcher.(WindowActivator)
+	if !ok {
+		return fmt.Errorf("active backend does not support window activation")
+	}
+	return activator.ActivateWindow(windowID)
+}
+
+// Disconnected returns a channel that is closed when any backend in the
+// chain loses its connection, so the whole chain is rebuilt together
+func (f *FallbackBackend) Disconnected() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.lost != nil {
+		return f.lost
+	}
+
+	lost := make(chan struct{})
+	var once sync.Once
+	for _, b := range f.backends {
+		monitor, ok := b.(ConnectionMonitor)
+		if !ok {
+			continue
+		}
+		go func(done <-chan struct{}) {
+			<-done
+			once.Do(func() { close(lost) })
+		}(monitor.Disconnected())
+	}
+	f.lost = lost
+	return lost
+}
+
+// Health returns a snapshot of per-backend health
+func (f *FallbackBackend) Health() []BackendHealth {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	health := make([]BackendHealth, 0, len(f.backends))
+	for _, b := range f.backends {
+		h := *f.health[b]
+		h.Healthy = !time.Now().Before(h.CooldownUntil)
+		health = append(health, h)
+	}
+	return health
+}
+
+// try calls fn on each healthy backend in chain until one succeeds
+func (f *FallbackBackend) try(chain []Backend, op string, fn func(Backend) error) error {
+	var errs []string
+	for _, b := range f.candidates(chain) {
+		err := fn(b)
+		f.record(b, op, err)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", b.Name(), err))
+	}
+	return fmt.Errorf("all backends failed to %s: %s", op, strings.Join(errs, "; "))
+}
+
+// candidates returns the backends in chain that are not cooling down. If all
+// are cooling down, the full chain is returned so calls are never starved.
+func (f *FallbackBackend) candidates(chain []Backend) []Backend {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]Backend, 0, len(chain))
+	for _, b := range chain {
+		if now.After(f.health[b].CooldownUntil) {
+			healthy = append(healthy, b)
+		}
+	}
+	if len(healthy) == 0 {
+		return chain
+	}
+	return healthy
+}
+
+// record updates health after a call
+func (f *FallbackBackend) record(b Backend, op string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	h := f.health[b]
+	if err == nil {
+		h.ConsecutiveFailures = 0
+		h.LastSuccess = time.Now()
+		return
+	}
+
+	h.ConsecutiveFailures++
+	h.TotalFailures++
+	h.LastError = err.Error()
+
+	if h.ConsecutiveFailures >= fallbackFailureThreshold {
+		h.CooldownUntil = time.Now().Add(fallbackCooldown)
+		h.ConsecutiveFailures = 0
+		logger.WithComponent("fallback-backend").Warn().
+			Str("backend", b.Name()).
+			Str("operation", op).
+			Err(err).
+			Dur("cooldown", fallbackCooldown).
+			Msg("Backend failing repeatedly, falling back")
+	}
+}
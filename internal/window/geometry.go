@@ -0,0 +1,119 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+)
+
+// ContentRect is the part of a stream frame showing content, in frame pixels
+type ContentRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// String formats the rect as "x,y,width,height", the X-Content-Rect header
+func (r ContentRect) String() string {
+	return fmt.Sprintf("%d,%d,%d,%d", r.X, r.Y, r.Width, r.Height)
+}
+
+// StreamGeometry describes the last stream frame. Unzoomed frames are the
+// window at its own size; zoomed frames fit the crop into the virtual
+// display, leaving black bars when their aspect ratios differ. Clients can
+// crop frames to Content to drop the bars.
+type StreamGeometry struct {
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Content     ContentRect `json:"content"`
+	Letterboxed bool        `json:"letterboxed"` // Bars above and below
+	Pillarboxed bool        `json:"pillarboxed"` // Bars left and right
+}
+
+// newStreamGeometry returns the geometry of a frame whose content fills
+// content, or all of it if content is empty
+func newStreamGeometry(bounds, content image.Rectangle) StreamGeometry {
+	if content.Empty() {
+		content = bounds
+	}
+	content = content.Sub(bounds.Min)
+	return StreamGeometry{
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+		Content:     ContentRect{X: content.Min.X, Y: content.Min.Y, Width: content.Dx(), Height: content.Dy()},
+		Letterboxed: content.Dy() < bounds.Dy(),
+		Pillarboxed: content.Dx() < bounds.Dx(),
+	}
+}
+
+// GetStreamGeometry returns the geometry of the last stream frame, zero
+// before the first
+func (m *Manager) GetStreamGeometry() StreamGeometry {
+	m.streamMu.Lock()
+	defer m.streamMu.Unlock()
+	return m.geometry
+}
+
+// streamSnapshot is a copy of a stream frame handed to a Snapshot caller
+type streamSnapshot struct {
+	image    *image.RGBA
+	geometry StreamGeometry
+}
+
+// Snapshot returns a copy of the next stream frame as sent to viewers, and
+// its geometry. It wakes suspended capture for the frame. The caller
+// releases the copy with framepool.Put.
+func (m *Manager) Snapshot(ctx context.Context) (*image.RGBA, StreamGeometry, error) {
+	release := m.AcquireFrames()
+	defer release()
+
+	ch := make(chan streamSnapshot, 1)
+	m.streamMu.Lock()
+	if !m.streamRunning {
+		m.streamMu.Unlock()
+		return nil, StreamGeometry{}, fmt.Errorf("stream is not running")
+	}
+	m.snapshotWaiters = append(m.snapshotWaiters, ch)
+	m.streamMu.Unlock()
+
+	select {
+	case snap := <-ch:
+		return snap.image, snap.geometry, nil
+	case <-ctx.Done():
+		m.streamMu.Lock()
+		for i, waiter := range m.snapshotWaiters {
+			if waiter == ch {
+				m.snapshotWaiters = append(m.snapshotWaiters[:i], m.snapshotWaiters[i+1:]...)
+				break
+			}
+		}
+		m.streamMu.Unlock()
+
+		// The frame may have been handed over meanwhile
+		select {
+		case snap := <-ch:
+			framepool.Put(snap.image)
+		default:
+		}
+		return nil, StreamGeometry{}, ctx.Err()
+	}
+}
+
+// recordFrame stores the geometry of a frame about to be sent and hands
+// copies of it to waiting Snapshot callers
+func (m *Manager) recordFrame(f *Frame) {
+	geometry := newStreamGeometry(f.Image.Bounds(), f.Content)
+
+	m.streamMu.Lock()
+	m.geometry = geometry
+	waiters := m.snapshotWaiters
+	m.snapshotWaiters = nil
+	m.streamMu.Unlock()
+
+	for _, ch := range waiters {
+		ch <- streamSnapshot{image: framepool.Clone(f.Image), geometry: geometry}
+	}
+}
@@ -0,0 +1,207 @@
+package window
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+const (
+	// governorInterval is how often the load governor samples CPU usage and
+	// frame latency
+	governorInterval = 2 * time.Second
+
+	// Consecutive samples over budget before the governor steps down, and
+	// under governorRelaxFraction of it before it steps back up. Stepping up
+	// is slower so the stream doesn't oscillate around the budget.
+	governorOverSamples   = 2
+	governorUnderSamples  = 5
+	governorRelaxFraction = 0.7
+)
+
+// throttleSteps are the governor's levels, from unthrottled. JPEG quality
+// goes first since viewers notice it less than a lower frame rate.
+var throttleSteps = []struct {
+	quality  int
+	fpsScale float64
+}{
+	{output.DefaultJPEGQuality, 1},
+	{75, 1},
+	{60, 1},
+	{60, 0.75},
+	{50, 0.5},
+	{50, 0.25},
+}
+
+// ThrottleStatus is the state of the load governor (see
+// config.GovernorConfig)
+type ThrottleStatus struct {
+	Enabled       bool    `json:"enabled"`
+	Level         int     `json:"level"` // 0 is unthrottled
+	MaxLevel      int     `json:"max_level"`
+	FPS           int     `json:"fps"`
+	Quality       int     `json:"quality"`        // JPEG quality of stream frames
+	CPUPercent    float64 `json:"cpu_percent"`    // Process CPU over the last sample, percent of one core
+	FrameMs       float64 `json:"frame_ms"`       // Average time to produce a frame
+	BudgetPercent float64 `json:"budget_percent"` // capture.governor.cpu_budget_percent
+}
+
+// ThrottleStatus returns the state of the load governor
+func (m *Manager) ThrottleStatus() ThrottleStatus {
+	m.throttleMu.Lock()
+	defer m.throttleMu.Unlock()
+	return m.throttle
+}
+
+// resetThrottle puts the governor back at full quality and frame rate. The
+// caller holds streamMu.
+func (m *Manager) resetThrottle(fps int) {
+	cfg := m.configMgr.Get().Capture.Governor
+
+	m.throttleMu.Lock()
+	m.throttle = ThrottleStatus{
+		Enabled:       cfg.Enabled,
+		MaxLevel:      len(throttleSteps) - 1,
+		FPS:           fps,
+		Quality:       output.DefaultJPEGQuality,
+		BudgetPercent: cfg.CPUBudgetPercent,
+	}
+	m.throttleMu.Unlock()
+
+	if setter, ok := m.output.(output.QualitySetter); ok {
+		setter.SetQuality(output.DefaultJPEGQuality)
+	}
+}
+
+// runGovernor samples process CPU and frame latency until stop closes,
+// stepping the throttle level down while over budget and back up once load
+// drops. The config is re-read every sample, so the governor can be turned
+// on and off without restarting.
+func (m *Manager) runGovernor(ctx context.Context, baseFPS int) {
+	ticker := time.NewTicker(governorInterval)
+	defer ticker.Stop()
+
+	lastCPU := processCPUTime()
+	lastSample := time.Now()
+	over, under := 0, 0
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		cpu := processCPUTime()
+		cpuPercent := 100 * float64(cpu-lastCPU) / float64(now.Sub(lastSample))
+		lastCPU, lastSample = cpu, now
+		frameMs := m.averageFrameMs()
+
+		cfg := m.configMgr.Get().Capture.Governor
+		m.throttleMu.Lock()
+		m.throttle.Enabled = cfg.Enabled
+		m.throttle.BudgetPercent = cfg.CPUBudgetPercent
+		m.throttle.CPUPercent = math.Round(cpuPercent*10) / 10
+		m.throttle.FrameMs = math.Round(frameMs*10) / 10
+		level := m.throttle.Level
+		fps := m.throttle.FPS
+		m.throttleMu.Unlock()
+
+		if !cfg.Enabled {
+			over, under = 0, 0
+			if level != 0 {
+				m.setThrottleLevel(0, baseFPS, cfg)
+			}
+			continue
+		}
+
+		// Nothing is captured while parked, so there is nothing to measure
+		m.streamMu.Lock()
+		suspended := m.suspended
+		m.streamMu.Unlock()
+		if suspended {
+			over, under = 0, 0
+			continue
+		}
+
+		intervalMs := 1000 / float64(fps)
+		switch {
+		case cpuPercent > cfg.CPUBudgetPercent || frameMs > intervalMs:
+			over++
+			under = 0
+		case cpuPercent < cfg.CPUBudgetPercent*governorRelaxFraction && frameMs < intervalMs*governorRelaxFraction:
+			under++
+			over = 0
+		default:
+			over, under = 0, 0
+		}
+
+		if over >= governorOverSamples && level < len(throttleSteps)-1 {
+			m.setThrottleLevel(level+1, baseFPS, cfg)
+			over = 0
+		} else if under >= governorUnderSamples && level > 0 {
+			m.setThrottleLevel(level-1, baseFPS, cfg)
+			under = 0
+		}
+	}
+}
+
+// setThrottleLevel applies a throttle level's JPEG quality and frame rate
+func (m *Manager) setThrottleLevel(level, baseFPS int, cfg config.GovernorConfig) {
+	step := throttleSteps[level]
+	fps := max(min(cfg.MinFPS, baseFPS), int(math.Round(float64(baseFPS)*step.fpsScale)))
+
+	m.throttleMu.Lock()
+	previous := m.throttle.Level
+	cpuPercent := m.throttle.CPUPercent
+	frameMs := m.throttle.FrameMs
+	m.throttle.Level = level
+	m.throttle.FPS = fps
+	m.throttle.Quality = step.quality
+	m.throttleMu.Unlock()
+
+	m.streamMu.Lock()
+	out := m.output
+	m.streamMu.Unlock()
+	if setter, ok := out.(output.QualitySetter); ok {
+		setter.SetQuality(step.quality)
+	}
+
+	// Replace a retiming the stream loop hasn't picked up yet
+	select {
+	case <-m.fpsChange:
+	default:
+	}
+	select {
+	case m.fpsChange <- fps:
+	default:
+	}
+
+	log := logger.WithComponent("governor")
+	event := log.Info
+	if level > previous {
+		event = log.Warn
+	}
+	event().
+		Int("level", level).
+		Int("fps", fps).
+		Int("quality", step.quality).
+		Float64("cpu_percent", cpuPercent).
+		Float64("frame_ms", frameMs).
+		Msg("Stream throttle changed")
+}
+
+// averageFrameMs is the moving average time to produce a frame, summed
+// over the pipeline stages
+func (m *Manager) averageFrameMs() float64 {
+	var total float64
+	for _, stage := range m.pipeline.Stats() {
+		total += stage.AvgMs
+	}
+	return total
+}
@@ -0,0 +1,425 @@
+package window
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// hyprlandIPCTimeout bounds each request on the Hyprland command socket
+const hyprlandIPCTimeout = 2 * time.Second
+
+// HyprlandBackend implements the Backend interface using Hyprland's IPC
+// sockets: the command socket for queries and the event socket for focus
+// changes
+type HyprlandBackend struct {
+	commandSocket string
+	eventSocket   string
+	mu            sync.RWMutex
+	currentWindow *config.WindowInfo
+	stopChan      chan struct{}
+	watching      bool
+	eventConn     net.Conn
+	titleWatchID  uint32
+	titleCallback func(windowID uint32, title string)
+	// Window event subscription (see WatchWindows)
+	windowsCallback func(WindowEvent)
+	// Closed when the event socket drops without StopWatching
+	lost     chan struct{}
+	lostOnce sync.Once
+}
+
+// hyprlandClient is a window as reported by `hyprctl -j clients`
+type hyprlandClient struct {
+	Address   string `json:"address"`
+	Mapped    bool   `json:"mapped"`
+	Hidden    bool   `json:"hidden"`
+	At        [2]int `json:"at"`
+	Size      [2]int `json:"size"`
+	Workspace struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	} `json:"workspace"`
+	Class    string `json:"class"`
+	Title    string `json:"title"`
+	PID      int    `json:"pid"`
+	XWayland bool   `json:"xwayland"`
+}
+
+// NewHyprlandBackend creates a new Hyprland IPC backend
+func NewHyprlandBackend() (*HyprlandBackend, error) {
+	signature := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE")
+	if signature == "" {
+		return nil, fmt.Errorf("HYPRLAND_INSTANCE_SIGNATURE not set (not running under Hyprland)")
+	}
+
+	// Hyprland >= 0.40 uses $XDG_RUNTIME_DIR/hypr, older versions /tmp/hypr
+	var dirs []string
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		dirs = append(dirs, filepath.Join(runtimeDir, "hypr", signature))
+	}
+	dirs = append(dirs, filepath.Join("/tmp/hypr", signature))
+
+	for _, dir := range dirs {
+		commandSocket := filepath.Join(dir, ".socket.sock")
+		if _, err := os.Stat(commandSocket); err != nil {
+			continue
+		}
+
+		logger.WithComponent("hyprland-backend").Info().
+			Str("socket", commandSocket).
+			Msg("Connected to Hyprland IPC")
+
+		return &HyprlandBackend{
+			commandSocket: commandSocket,
+			eventSocket:   filepath.Join(dir, ".socket2.sock"),
+			stopChan:      make(chan struct{}),
+			lost:          make(chan struct{}),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Hyprland IPC socket not found for instance %s", signature)
+}
+
+// Connect is a no-op; each request opens its own socket connection
+func (b *HyprlandBackend) Connect() error {
+	return nil
+}
+
+// Close stops watching
+func (b *HyprlandBackend) Close() error {
+	b.StopWatching()
+	return nil
+}
+
+// Disconnected returns a channel that is closed when the event socket is
+// lost, e.g. because the compositor restarted
+func (b *HyprlandBackend) Disconnected() <-chan struct{} {
+	return b.lost
+}
+
+// Name returns the backend name
+func (b *HyprlandBackend) Name() string {
+	return "hyprland"
+}
+
+// Capabilities reports the Hyprland features. Windows are cropped out of
+// the PipeWire monitor stream; moves and resizes are picked up by the focus
+// poll.
+func (b *HyprlandBackend) Capabilities() Capabilities {
+	return Capabilities{
+		AccurateGeometry: true,
+		Desktops:         true,
+		Activation:       true,
+		FocusEvents:      true,
+		TitleEvents:      true,
+		CloseEvents:      true,
+		WindowEvents:     true,
+		Pointer:          true,
+	}
+}
+
+// ListWindows returns all mapped, visible windows
+func (b *HyprlandBackend) ListWindows() ([]*config.WindowInfo, error) {
+	var clients []hyprlandClient
+	if err := b.query("clients", &clients); err != nil {
+		return nil, err
+	}
+
+	windows := make([]*config.WindowInfo, 0, len(clients))
+	for _, c := range clients {
+		if !c.Mapped || c.Hidden || c.Class == "" {
+			continue
+		}
+		windows = append(windows, c.toWindowInfo())
+	}
+	return windows, nil
+}
+
+// GetFocusedWindow returns the currently focused window
+func (b *HyprlandBackend) GetFocusedWindow() (*config.WindowInfo, error) {
+	var client hyprlandClient
+	if err := b.query("activewindow", &client); err != nil {
+		return nil, err
+	}
+	if client.Address == "" {
+		return nil, fmt.Errorf("no active window")
+	}
+
+	info := client.toWindowInfo()
+	info.Focused = true
+	return info, nil
+}
+
+// GetCurrentDesktop returns the active workspace as a 0-based desktop index
+func (b *HyprlandBackend) GetCurrentDesktop() int {
+	var workspace struct {
+		ID int `json:"id"`
+	}
+	if err := b.query("activeworkspace", &workspace); err != nil {
+		return 0
+	}
+	return hyprlandDesktop(workspace.ID)
+}
+
+// WatchFocus subscribes to the Hyprland event socket and reports focus,
+// title, and workspace changes
+func (b *HyprlandBackend) WatchFocus(callback func(*config.WindowInfo)) error {
+	b.mu.Lock()
+	if b.watching {
+		b.mu.Unlock()
+		return fmt.Errorf("already watching")
+	}
+
+	conn, err := net.Dial("unix", b.eventSocket)
+	if err != nil {
+		b.mu.Unlock()
+		return fmt.Errorf("failed to connect to Hyprland event socket: %w", err)
+	}
+
+	b.watching = true
+	b.stopChan = make(chan struct{})
+	b.eventConn = conn
+	b.mu.Unlock()
+
+	go b.watchEvents(conn, callback)
+	return nil
+}
+
+// watchEvents reads events until the connection is closed
+func (b *HyprlandBackend) watchEvents(conn net.Conn, callback func(*config.WindowInfo)) {
+	log := logger.WithComponent("hyprland-backend")
+
+	checkFocus := func() {
+		info, err := b.GetFocusedWindow()
+		if err != nil {
+			log.Debug().Err(err).Msg("Failed to get focused window")
+			return
+		}
+
+		b.mu.Lock()
+		changed := b.currentWindow == nil ||
+			b.currentWindow.ID != info.ID ||
+			b.currentWindow.Title != info.Title ||
+			b.currentWindow.Geometry != info.Geometry
+		if changed {
+			b.currentWindow = info
+		}
+		b.mu.Unlock()
+
+		if changed {
+			callback(info)
+		}
+	}
+
+	checkFocus()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		// Events are "name>>data". Titles in them are raw bytes, which may
+		// not be valid UTF-8.
+		line := strings.ToValidUTF8(scanner.Text(), "\uFFFD")
+		event, data, _ := strings.Cut(line, ">>")
+		if event == "windowtitlev2" {
+			b.handleTitleEvent(data)
+		}
+		b.handleWindowEvent(event, data)
+		switch event {
+		case "activewindowv2", "windowtitle", "windowtitlev2", "workspace", "workspacev2",
+			"movewindow", "movewindowv2", "closewindow", "fullscreen":
+			checkFocus()
+		}
+	}
+
+	select {
+	case <-b.stopChan:
+	default:
+		log.Warn().Err(scanner.Err()).Msg("Hyprland event socket closed")
+		b.lostOnce.Do(func() { close(b.lost) })
+	}
+}
+
+// WatchTitle reports title changes of one window from the event socket
+func (b *HyprlandBackend) WatchTitle(windowID uint32, callback func(windowID uint32, title string)) error {
+	b.mu.Lock()
+	b.titleWatchID = windowID
+	b.titleCallback = callback
+	b.mu.Unlock()
+	return nil
+}
+
+// handleTitleEvent handles "windowtitlev2>>ADDRESS,TITLE" (address without 0x)
+func (b *HyprlandBackend) handleTitleEvent(data string) {
+	address, title, ok := strings.Cut(data, ",")
+	if !ok {
+		return
+	}
+	id := hashStringToUint32("0x" + address)
+
+	b.mu.RLock()
+	watched := b.titleWatchID
+	callback := b.titleCallback
+	b.mu.RUnlock()
+
+	if watched != 0 && id == watched && callback != nil {
+		callback(id, title)
+	}
+}
+
+// WatchWindows reports windows opening, closing and changing title from the
+// event socket. Opening and moving between workspaces only name the
+// window, so they ask for a re-enumeration (one hyprctl call).
+func (b *HyprlandBackend) WatchWindows(callback func(WindowEvent)) error {
+	b.mu.Lock()
+	b.windowsCallback = callback
+	b.mu.Unlock()
+	return nil
+}
+
+// handleWindowEvent forwards window events to the WatchWindows subscriber.
+// Addresses in event data lack the 0x prefix.
+func (b *HyprlandBackend) handleWindowEvent(event, data string) {
+	b.mu.RLock()
+	callback := b.windowsCallback
+	b.mu.RUnlock()
+	if callback == nil {
+		return
+	}
+
+	address, rest, _ := strings.Cut(data, ",")
+	switch event {
+	case "openwindow", "movewindowv2":
+		callback(WindowEvent{Type: WindowsResync})
+	case "closewindow":
+		callback(WindowEvent{Type: WindowClosed, ID: hashStringToUint32("0x" + address)})
+	case "windowtitlev2":
+		callback(WindowEvent{Type: WindowTitleChanged, ID: hashStringToUint32("0x" + address), Title: rest})
+	}
+}
+
+// StopWatching stops the event loop
+func (b *HyprlandBackend) StopWatching() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.watching {
+		close(b.stopChan)
+		b.eventConn.Close()
+		b.watching = false
+	}
+}
+
+// ActivateWindow focuses a window with the focuswindow dispatcher, which
+// also switches to its workspace
+func (b *HyprlandBackend) ActivateWindow(windowID uint32) error {
+	var clients []hyprlandClient
+	if err := b.query("clients", &clients); err != nil {
+		return err
+	}
+
+	for _, c := range clients {
+		if hashStringToUint32(c.Address) == windowID {
+			return b.dispatch("focuswindow address:" + c.Address)
+		}
+	}
+	return fmt.Errorf("window %d not found", windowID)
+}
+
+// PointerPosition asks for the cursor position in layout coordinates, the
+// ones window geometry uses. Hyprland doesn't report mouse buttons.
+func (b *HyprlandBackend) PointerPosition(window *config.WindowInfo) (int, int, bool, error) {
+	var pos struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	if err := b.query("cursorpos", &pos); err != nil {
+		return 0, 0, false, err
+	}
+	return pos.X - window.Geometry.X, pos.Y - window.Geometry.Y, false, nil
+}
+
+// dispatch runs a dispatcher on the command socket, which replies "ok" on success
+func (b *HyprlandBackend) dispatch(args string) error {
+	conn, err := net.DialTimeout("unix", b.commandSocket, hyprlandIPCTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Hyprland IPC: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(hyprlandIPCTimeout))
+	if _, err := conn.Write([]byte("dispatch " + args)); err != nil {
+		return fmt.Errorf("failed to send Hyprland dispatch %q: %w", args, err)
+	}
+
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read Hyprland reply for %q: %w", args, err)
+	}
+	if reply := strings.TrimSpace(string(data)); reply != "ok" {
+		return fmt.Errorf("Hyprland dispatch %q failed: %s", args, reply)
+	}
+	return nil
+}
+
+// query sends a JSON request on the command socket and decodes the reply
+func (b *HyprlandBackend) query(command string, v interface{}) error {
+	conn, err := net.DialTimeout("unix", b.commandSocket, hyprlandIPCTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Hyprland IPC: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(hyprlandIPCTimeout))
+	if _, err := conn.Write([]byte("j/" + command)); err != nil {
+		return fmt.Errorf("failed to send Hyprland request %q: %w", command, err)
+	}
+
+	data, err := io.ReadAll(conn)
+	if err != nil {
+		return fmt.Errorf("failed to read Hyprland reply for %q: %w", command, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse Hyprland reply for %q: %w", command, err)
+	}
+	return nil
+}
+
+// toWindowInfo converts a Hyprland client to WindowInfo. Hyprland does not
+// expose X11 window IDs, so every window is treated as native Wayland and
+// captured through PipeWire; the ID is derived from the client address.
+func (c hyprlandClient) toWindowInfo() *config.WindowInfo {
+	return &config.WindowInfo{
+		ID:    hashStringToUint32(c.Address),
+		Title: c.Title,
+		Class: strings.ToLower(c.Class),
+		PID:   c.PID,
+		Geometry: config.Geometry{
+			X:      c.At[0],
+			Y:      c.At[1],
+			Width:  c.Size[0],
+			Height: c.Size[1],
+		},
+		IsNativeWayland: true,
+		Desktop:         hyprlandDesktop(c.Workspace.ID),
+	}
+}
+
+// hyprlandDesktop maps 1-based workspace IDs to 0-based desktop indices.
+// Special workspaces (negative IDs) map to -1 (visible on all desktops).
+func hyprlandDesktop(workspaceID int) int {
+	if workspaceID <= 0 {
+		return -1
+	}
+	return workspaceID - 1
+}
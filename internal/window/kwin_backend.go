@@ -1,41 +1,42 @@
 package window
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"os/exec"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/BurntSushi/xgb"
-	"github.com/BurntSushi/xgb/xproto"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 	"github.com/godbus/dbus/v5"
 )
 
-// KWinBackend implements the Backend interface using KWin's D-Bus interface
-// and kdotool for window enumeration
+// KWinBackend implements the Backend interface using KWin's D-Bus interface.
+// A persistent KWin script pushes window events over D-Bus; if it cannot be
+// installed, one-shot scripts are run per query, with WindowsRunner and
+// object properties as further fallbacks.
 type KWinBackend struct {
 	conn          *dbus.Conn
 	mu            sync.RWMutex
 	currentWindow *config.WindowInfo
 	stopChan      chan struct{}
 	watching      bool
-	useKdotool    bool
 	// Map from hashed ID to original UUID string (for KWin 6)
-	windowUUIDs   map[uint32]string
-	uuidMu        sync.RWMutex
-	// X11 connection for active window detection (XWayland fallback)
-	x11Conn       *xgb.Conn
-	x11Root       xproto.Window
-	activeAtom    xproto.Atom
-	// Cache for KWin script-based active window detection
-	cachedActiveUUID     string
-	cachedActiveUUIDTime time.Time
+	windowUUIDs map[uint32]string
+	uuidMu      sync.RWMutex
+	// Receives results from one-shot KWin scripts (nil if export failed)
+	scriptReceiver *kwinScriptReceiver
+	// Live window list pushed by the persistent events script (nil if not running)
+	events           *kwinEventReceiver
+	eventsScriptPath string
+	// Title change subscription (see WatchTitle)
+	titleWatchID  uint32
+	titleCallback func(windowID uint32, title string)
+	// Geometry change subscription (see WatchGeometry)
+	geometryWatchID  uint32
+	geometryCallback func(windowID uint32, geometry config.Geometry)
+	// Window event subscription (see WatchWindows)
+	windowsCallback func(WindowEvent)
 	// Channel for desktop change events to trigger immediate focus check
 	desktopChangeChan chan struct{}
 }
@@ -78,46 +79,22 @@ func NewKWinBackend() (*KWinBackend, error) {
 		return nil, fmt.Errorf("KWin service not found on D-Bus")
 	}
 
-	// Check if kdotool is available (preferred method for window enumeration)
-	useKdotool := false
-	if _, err := exec.LookPath("kdotool"); err == nil {
-		useKdotool = true
-		logger.WithComponent("kwin-backend").Info().Msg("Using kdotool for window enumeration")
-	} else {
-		logger.WithComponent("kwin-backend").Info().Msg("kdotool not found, using qdbus fallback")
-	}
-
 	logger.WithComponent("kwin-backend").Info().Msg("Connected to KWin D-Bus service")
 
-	// Initialize X11 connection for active window detection (via XWayland)
-	var x11Conn *xgb.Conn
-	var x11Root xproto.Window
-	var activeAtom xproto.Atom
-
-	x11Conn, err = xgb.NewConn()
-	if err != nil {
-		logger.WithComponent("kwin-backend").Warn().Err(err).Msg("Failed to connect to X11 for active window detection")
-	} else {
-		setup := xproto.Setup(x11Conn)
-		x11Root = setup.DefaultScreen(x11Conn).Root
-
-		// Get _NET_ACTIVE_WINDOW atom
-		atomReply, err := xproto.InternAtom(x11Conn, false, uint16(len("_NET_ACTIVE_WINDOW")), "_NET_ACTIVE_WINDOW").Reply()
-		if err == nil {
-			activeAtom = atomReply.Atom
-			logger.WithComponent("kwin-backend").Debug().Msg("X11 active window detection initialized")
-		}
-	}
-
-	return &KWinBackend{
+	b := &KWinBackend{
 		conn:        conn,
 		stopChan:    make(chan struct{}),
-		useKdotool:  useKdotool,
 		windowUUIDs: make(map[uint32]string),
-		x11Conn:     x11Conn,
-		x11Root:     x11Root,
-		activeAtom:  activeAtom,
-	}, nil
+	}
+
+	if err := b.exportScriptReceiver(); err != nil {
+		logger.WithComponent("kwin-backend").Warn().Err(err).Msg("KWin scripting unavailable, using WindowsRunner for enumeration")
+		b.scriptReceiver = nil
+	} else if err := b.startEventScript(); err != nil {
+		logger.WithComponent("kwin-backend").Warn().Err(err).Msg("KWin events script unavailable, querying per call")
+	}
+
+	return b, nil
 }
 
 // Connect establishes connection (already done in NewKWinBackend)
@@ -125,147 +102,83 @@ func (b *KWinBackend) Connect() error {
 	return nil
 }
 
-// Close closes the D-Bus and X11 connections
+// Close closes the D-Bus connection
 func (b *KWinBackend) Close() error {
 	b.StopWatching()
-	if b.x11Conn != nil {
-		b.x11Conn.Close()
-	}
+	b.stopEventScript()
 	return b.conn.Close()
 }
 
+// Disconnected returns a channel that is closed when the session bus
+// connection is lost
+func (b *KWinBackend) Disconnected() <-chan struct{} {
+	return b.conn.Context().Done()
+}
+
 // Name returns the backend name
 func (b *KWinBackend) Name() string {
 	return "kwin"
 }
 
+// Capabilities reports the KWin features. Windows are cropped out of the
+// PipeWire monitor stream, and KWin has no pointer query.
+func (b *KWinBackend) Capabilities() Capabilities {
+	return Capabilities{
+		AccurateGeometry: true,
+		Desktops:         true,
+		Activation:       true,
+		FocusEvents:      true,
+		TitleEvents:      true,
+		GeometryEvents:   true,
+		CloseEvents:      true,
+		WindowEvents:     true,
+	}
+}
+
 // ListWindows returns all visible windows
 func (b *KWinBackend) ListWindows() ([]*config.WindowInfo, error) {
-	if b.useKdotool {
-		return b.listWindowsKdotool()
+	if b.events != nil {
+		return b.listWindowsFromEvents(), nil
 	}
-	return b.listWindowsQdbus()
+	if b.scriptReceiver != nil {
+		windows, err := b.listWindowsViaScript()
+		if err == nil {
+			return windows, nil
+		}
+		logger.WithComponent("kwin-backend").Debug().Err(err).Msg("Script enumeration failed, falling back to WindowsRunner")
+	}
+	return b.listWindowsDBus()
 }
 
-// listWindowsKdotool uses kdotool to enumerate windows
-func (b *KWinBackend) listWindowsKdotool() ([]*config.WindowInfo, error) {
-	log := logger.WithComponent("kwin-backend")
-
-	// Get list of window IDs
-	cmd := exec.Command("kdotool", "search", "--name", ".")
-	output, err := cmd.Output()
+// listWindowsViaScript enumerates normal windows with a KWin script
+func (b *KWinBackend) listWindowsViaScript() ([]*config.WindowInfo, error) {
+	scriptWindows, err := b.queryWindowsViaScript(false)
 	if err != nil {
-		return nil, fmt.Errorf("kdotool search failed: %w", err)
+		return nil, err
 	}
+	return b.normalWindowInfos(scriptWindows), nil
+}
 
-	windows := make([]*config.WindowInfo, 0)
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		// kdotool returns window IDs as strings (UUIDs)
-		windowID := line
-
-		// Get window info
-		info, err := b.getWindowInfoKdotool(windowID)
-		if err != nil {
-			log.Debug().Str("windowID", windowID).Err(err).Msg("Failed to get window info")
+// normalWindowInfos converts script-reported windows, skipping panels,
+// popups, and other non-taskbar windows
+func (b *KWinBackend) normalWindowInfos(scriptWindows []kwinScriptWindow) []*config.WindowInfo {
+	desktopIndex := b.desktopIndexMap()
+	windows := make([]*config.WindowInfo, 0, len(scriptWindows))
+	for _, w := range scriptWindows {
+		if !w.NormalWindow || w.SkipTaskbar {
 			continue
 		}
-
+		info := w.toWindowInfo(desktopIndex)
 		if info.Title == "" && info.Class == "" {
 			continue
 		}
-
 		windows = append(windows, info)
 	}
-	return windows, nil
-}
-
-// getWindowInfoKdotool gets info for a single window via kdotool
-func (b *KWinBackend) getWindowInfoKdotool(windowID string) (*config.WindowInfo, error) {
-	// Get window name
-	nameCmd := exec.Command("kdotool", "getwindowname", windowID)
-	nameOutput, _ := nameCmd.Output()
-	name := strings.TrimSpace(string(nameOutput))
-
-	// Get window class (kdotool getwindowclassname)
-	classCmd := exec.Command("kdotool", "getwindowclassname", windowID)
-	classOutput, _ := classCmd.Output()
-	class := strings.TrimSpace(string(classOutput))
-
-	// Get window PID
-	pidCmd := exec.Command("kdotool", "getwindowpid", windowID)
-	pidOutput, _ := pidCmd.Output()
-	pid, _ := strconv.Atoi(strings.TrimSpace(string(pidOutput)))
-
-	// Get window geometry via kdotool
-	geomCmd := exec.Command("kdotool", "getwindowgeometry", windowID)
-	geomOutput, _ := geomCmd.Output()
-	geometry := b.parseKdotoolGeometry(string(geomOutput))
-
-	// Generate numeric ID from string ID
-	id := hashStringToUint32(windowID)
-
-	// Try to get X11 window ID for XWayland detection
-	isNativeWayland := true
-	windowPath := "/org/kde/KWin/Window/" + windowID
-	if xid, err := b.getWindowXID(windowPath); err == nil && xid > 0 && xid != 0x200000 {
-		id = xid // Use actual X11 ID for capture
-		isNativeWayland = false
-	}
-
-	// Get window desktop
-	desktop := b.getWindowDesktopFromDBus(windowPath)
-
-	return &config.WindowInfo{
-		ID:              id,
-		Title:           name,
-		Class:           class,
-		PID:             pid,
-		Focused:         false,
-		Geometry:        geometry,
-		IsNativeWayland: isNativeWayland,
-		Desktop:         desktop,
-	}, nil
-}
-
-// parseKdotoolGeometry parses geometry output from kdotool
-func (b *KWinBackend) parseKdotoolGeometry(output string) config.Geometry {
-	geometry := config.Geometry{}
-
-	// kdotool output format: "Window <id>\n  Position: X,Y\n  Geometry: WxH"
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "Position:") {
-			posStr := strings.TrimPrefix(line, "Position:")
-			posStr = strings.TrimSpace(posStr)
-			parts := strings.Split(posStr, ",")
-			if len(parts) >= 2 {
-				geometry.X, _ = strconv.Atoi(strings.TrimSpace(parts[0]))
-				geometry.Y, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
-			}
-		} else if strings.HasPrefix(line, "Geometry:") {
-			geomStr := strings.TrimPrefix(line, "Geometry:")
-			geomStr = strings.TrimSpace(geomStr)
-			parts := strings.Split(geomStr, "x")
-			if len(parts) >= 2 {
-				geometry.Width, _ = strconv.Atoi(strings.TrimSpace(parts[0]))
-				geometry.Height, _ = strconv.Atoi(strings.TrimSpace(parts[1]))
-			}
-		}
-	}
-
-	return geometry
+	return windows
 }
 
-// listWindowsQdbus uses D-Bus to enumerate windows (fallback)
-func (b *KWinBackend) listWindowsQdbus() ([]*config.WindowInfo, error) {
+// listWindowsDBus enumerates windows via WindowsRunner, then introspection
+func (b *KWinBackend) listWindowsDBus() ([]*config.WindowInfo, error) {
 	// Try WindowsRunner API first (most reliable on KDE6)
 	windows, err := b.listWindowsViaRunner()
 	if err == nil && len(windows) > 0 {
@@ -273,52 +186,7 @@ func (b *KWinBackend) listWindowsQdbus() ([]*config.WindowInfo, error) {
 	}
 
 	// Try D-Bus introspection
-	windows, err = b.listWindowsDBusIntrospect()
-	if err == nil && len(windows) > 0 {
-		return windows, nil
-	}
-
-	// Try qdbus6/qdbus command as fallback
-	qdbusCmd := "qdbus6"
-	if _, err := exec.LookPath("qdbus6"); err != nil {
-		qdbusCmd = "qdbus"
-		if _, err := exec.LookPath("qdbus"); err != nil {
-			return b.listWindowsWmctrl()
-		}
-	}
-
-	// List all objects under org.kde.KWin
-	cmd := exec.Command(qdbusCmd, "org.kde.KWin")
-	output, err := cmd.Output()
-	if err != nil {
-		return b.listWindowsWmctrl()
-	}
-
-	// Parse output - look for window/client paths
-	windowPaths := []string{}
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// KWin6 uses /org/kde/KWin/Window/<uuid> paths
-		if strings.Contains(line, "/Window/") || strings.Contains(line, "/Client/") {
-			windowPaths = append(windowPaths, line)
-		}
-	}
-
-	windows = make([]*config.WindowInfo, 0)
-	for _, path := range windowPaths {
-		info, err := b.getWindowInfoFromDBus(path)
-		if err != nil {
-			continue
-		}
-		if info.Title == "" && info.Class == "" {
-			continue
-		}
-		info.Focused = false
-		windows = append(windows, info)
-	}
-
-	return windows, nil
+	return b.listWindowsDBusIntrospect()
 }
 
 // KRunnerMatch represents a match returned by the KRunner interface
@@ -630,257 +498,38 @@ func (b *KWinBackend) introspectContainer(containerPath string) ([]string, error
 	return paths, nil
 }
 
-// listWindowsWmctrl uses wmctrl as a last resort fallback
-func (b *KWinBackend) listWindowsWmctrl() ([]*config.WindowInfo, error) {
-	cmd := exec.Command("wmctrl", "-l", "-p")
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("wmctrl failed: %w", err)
-	}
-
-	windows := make([]*config.WindowInfo, 0)
-	scanner := bufio.NewScanner(strings.NewReader(string(output)))
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		fields := strings.Fields(line)
-		if len(fields) < 4 {
-			continue
-		}
-
-		// wmctrl format: WindowID Desktop PID Hostname Title...
-		windowIDStr := fields[0]
-		pid, _ := strconv.Atoi(fields[2])
-		title := strings.Join(fields[4:], " ")
-
-		// Convert hex window ID to uint32
-		windowID, _ := strconv.ParseUint(strings.TrimPrefix(windowIDStr, "0x"), 16, 32)
-
-		info := &config.WindowInfo{
-			ID:      uint32(windowID),
-			Title:   title,
-			PID:     pid,
-			Focused: false,
-		}
-
-		// Try to get class via xprop (wmctrl doesn't provide it)
-		classCmd := exec.Command("xprop", "-id", windowIDStr, "WM_CLASS")
-		if classOutput, err := classCmd.Output(); err == nil {
-			// Parse: WM_CLASS(STRING) = "instance", "class"
-			if parts := strings.Split(string(classOutput), "\""); len(parts) >= 4 {
-				info.Class = parts[3]
-			}
-		}
-
-		if info.Title == "" && info.Class == "" {
-			continue
-		}
-
-		windows = append(windows, info)
-	}
-
-	return windows, nil
-}
-
 // GetFocusedWindow returns the currently focused window
 func (b *KWinBackend) GetFocusedWindow() (*config.WindowInfo, error) {
-	if b.useKdotool {
-		return b.getFocusedWindowKdotool()
+	if b.events != nil {
+		return b.focusedWindowFromEvents()
+	}
+	if b.scriptReceiver != nil {
+		info, err := b.getFocusedWindowViaScript()
+		if err == nil {
+			return info, nil
+		}
+		logger.WithComponent("kwin-backend").Debug().Err(err).Msg("Script active window query failed, falling back to D-Bus properties")
 	}
-	return b.getFocusedWindowQdbus()
+	return b.getFocusedWindowDBus()
 }
 
-// getFocusedWindowKdotool gets the active window via kdotool
-func (b *KWinBackend) getFocusedWindowKdotool() (*config.WindowInfo, error) {
-	cmd := exec.Command("kdotool", "getactivewindow")
-	output, err := cmd.Output()
+// getFocusedWindowViaScript asks a one-shot KWin script for the active window
+func (b *KWinBackend) getFocusedWindowViaScript() (*config.WindowInfo, error) {
+	scriptWindows, err := b.queryWindowsViaScript(true)
 	if err != nil {
-		return nil, fmt.Errorf("kdotool getactivewindow failed: %w", err)
+		return nil, err
 	}
-
-	windowID := strings.TrimSpace(string(output))
-	if windowID == "" {
+	if len(scriptWindows) == 0 {
 		return nil, fmt.Errorf("no active window")
 	}
 
-	info, err := b.getWindowInfoKdotool(windowID)
-	if err != nil {
-		return nil, err
-	}
-
+	info := scriptWindows[0].toWindowInfo(b.desktopIndexMap())
 	info.Focused = true
 	return info, nil
 }
 
-// getActiveWindowUUIDViaQdbus tries to get the active window UUID via qdbus command
-func (b *KWinBackend) getActiveWindowUUIDViaQdbus() string {
-	// Try qdbus6 first, then qdbus
-	qdbusCmd := "qdbus6"
-	if _, err := exec.LookPath("qdbus6"); err != nil {
-		qdbusCmd = "qdbus"
-		if _, err := exec.LookPath("qdbus"); err != nil {
-			return ""
-		}
-	}
-
-	// KDE6: Use KWin scripting to get active window (activeClient method doesn't exist)
-	uuid := b.getActiveWindowViaKWinScript(qdbusCmd)
-	if uuid != "" {
-		return uuid
-	}
-
-	// Fallback: Try legacy KDE5 approach with activeClient property
-	cmd := exec.Command(qdbusCmd, "org.kde.KWin", "/KWin", "org.kde.KWin.activeClient")
-	output, err := cmd.Output()
-	if err == nil {
-		result := strings.TrimSpace(string(output))
-		if strings.Contains(result, "/Window/") {
-			parts := strings.Split(result, "/Window/")
-			if len(parts) > 1 {
-				return parts[1]
-			}
-		}
-		if len(result) > 0 && result != "/" {
-			return result
-		}
-	}
-
-	return ""
-}
-
-// getActiveWindowViaKWinScript uses KWin scripting to get the active window UUID
-// This is needed for KDE6 which doesn't expose activeClient via D-Bus properties
-// Results are cached for 200ms to avoid excessive overhead
-func (b *KWinBackend) getActiveWindowViaKWinScript(qdbusCmd string) string {
-	log := logger.WithComponent("kwin-backend")
-
-	// Check cache first (valid for 200ms)
-	b.uuidMu.RLock()
-	if time.Since(b.cachedActiveUUIDTime) < 200*time.Millisecond && b.cachedActiveUUID != "" {
-		uuid := b.cachedActiveUUID
-		b.uuidMu.RUnlock()
-		return uuid
-	}
-	b.uuidMu.RUnlock()
-
-	// Create a temporary script
-	scriptContent := `var win = workspace.activeWindow || workspace.activeClient;
-if (win) {
-    print("FOCUSSTREAMER_ACTIVE:" + win.internalId);
-} else {
-    print("FOCUSSTREAMER_ACTIVE:none");
-}`
-
-	// Write script to temp file
-	scriptPath := "/tmp/focusstreamer_active_probe.js"
-	if err := os.WriteFile(scriptPath, []byte(scriptContent), 0644); err != nil {
-		log.Debug().Err(err).Msg("Failed to write KWin script")
-		return ""
-	}
-	defer os.Remove(scriptPath)
-
-	scriptName := fmt.Sprintf("focusstreamer_probe_%d", time.Now().UnixNano())
-
-	// Load the script
-	loadCmd := exec.Command(qdbusCmd, "org.kde.KWin", "/Scripting", "org.kde.kwin.Scripting.loadScript", scriptPath, scriptName)
-	if _, err := loadCmd.Output(); err != nil {
-		log.Debug().Err(err).Msg("Failed to load KWin script")
-		return ""
-	}
-
-	// Start the scripting engine
-	startCmd := exec.Command(qdbusCmd, "org.kde.KWin", "/Scripting", "org.kde.kwin.Scripting.start")
-	startCmd.Run()
-
-	// Give script time to execute (reduced from 100ms)
-	time.Sleep(50 * time.Millisecond)
-
-	// Unload the script
-	unloadCmd := exec.Command(qdbusCmd, "org.kde.KWin", "/Scripting", "org.kde.kwin.Scripting.unloadScript", scriptName)
-	unloadCmd.Run()
-
-	// Read from journal to get the script output
-	journalCmd := exec.Command("journalctl", "--user", "-u", "plasma-kwin_wayland.service", "-n", "10", "--no-pager", "-o", "cat")
-	journalOutput, err := journalCmd.Output()
-	if err != nil {
-		// Try alternative: journalctl for kwin_x11 or generic
-		journalCmd = exec.Command("journalctl", "--user", "-n", "10", "--no-pager", "-o", "cat")
-		journalOutput, _ = journalCmd.Output()
-	}
-
-	// Parse the output for our marker
-	lines := strings.Split(string(journalOutput), "\n")
-	for i := len(lines) - 1; i >= 0; i-- {
-		line := lines[i]
-		if strings.Contains(line, "FOCUSSTREAMER_ACTIVE:") {
-			parts := strings.Split(line, "FOCUSSTREAMER_ACTIVE:")
-			if len(parts) > 1 {
-				uuid := strings.TrimSpace(parts[1])
-				// Remove braces if present
-				uuid = strings.Trim(uuid, "{}")
-				if uuid != "" && uuid != "none" {
-					// Cache the result
-					b.uuidMu.Lock()
-					b.cachedActiveUUID = uuid
-					b.cachedActiveUUIDTime = time.Now()
-					b.uuidMu.Unlock()
-					return uuid
-				}
-			}
-		}
-	}
-
-	return ""
-}
-
-// getX11ActiveWindow returns the X11 window ID of the currently active window
-func (b *KWinBackend) getX11ActiveWindow() (uint32, error) {
-	if b.x11Conn == nil || b.activeAtom == 0 {
-		return 0, fmt.Errorf("X11 not initialized")
-	}
-
-	// Get _NET_ACTIVE_WINDOW property from root window
-	reply, err := xproto.GetProperty(b.x11Conn, false, b.x11Root, b.activeAtom, xproto.AtomWindow, 0, 1).Reply()
-	if err != nil {
-		return 0, fmt.Errorf("failed to get _NET_ACTIVE_WINDOW: %w", err)
-	}
-
-	if reply.ValueLen == 0 {
-		return 0, fmt.Errorf("no active window")
-	}
-
-	// Parse the window ID (32-bit value)
-	windowID := uint32(reply.Value[0]) | uint32(reply.Value[1])<<8 | uint32(reply.Value[2])<<16 | uint32(reply.Value[3])<<24
-	return windowID, nil
-}
-
-// getFocusedWindowQdbus gets the active window via qdbus/D-Bus
-func (b *KWinBackend) getFocusedWindowQdbus() (*config.WindowInfo, error) {
-	// First, try X11 active window detection (works for XWayland apps)
-	x11ActiveID, err := b.getX11ActiveWindow()
-	if err == nil && x11ActiveID > 0 && x11ActiveID != 0x200000 {
-		// x11ActiveID 0x200000 is a placeholder on Wayland, ignore it
-
-		// Get all windows and find one matching this X11 ID
-		windows, err := b.listWindowsViaRunner()
-		if err == nil {
-			for _, win := range windows {
-				// Check if this window's ID matches the X11 active window
-				if win.ID == x11ActiveID {
-					win.Focused = true
-					return win, nil
-				}
-			}
-		}
-
-		// Try to get window info directly via X11
-		info, err := b.getWindowInfoFromX11(x11ActiveID)
-		if err == nil {
-			info.Focused = true
-			return info, nil
-		}
-	}
-
+// getFocusedWindowDBus gets the active window from D-Bus properties
+func (b *KWinBackend) getFocusedWindowDBus() (*config.WindowInfo, error) {
 	// Try getting activeWindow path directly from D-Bus (legacy KWin 5)
 	obj := b.conn.Object(kwinService, kwinPath)
 
@@ -907,22 +556,6 @@ func (b *KWinBackend) getFocusedWindowQdbus() (*config.WindowInfo, error) {
 		return nil, fmt.Errorf("failed to list windows: %w", err)
 	}
 
-	// Try qdbus to get active window UUID directly (KDE6)
-	activeUUID := b.getActiveWindowUUIDViaQdbus()
-	if activeUUID != "" {
-		for _, win := range windows {
-			b.uuidMu.RLock()
-			rawID := b.windowUUIDs[win.ID]
-			b.uuidMu.RUnlock()
-
-			// Check if this window's UUID matches
-			if strings.Contains(rawID, activeUUID) {
-				win.Focused = true
-				return win, nil
-			}
-		}
-	}
-
 	// Find window marked as active by querying each window's active property
 	for _, win := range windows {
 		b.uuidMu.RLock()
@@ -964,72 +597,6 @@ func (b *KWinBackend) getFocusedWindowQdbus() (*config.WindowInfo, error) {
 	return nil, fmt.Errorf("no active window found")
 }
 
-// getWindowInfoFromX11 gets window info directly from X11
-func (b *KWinBackend) getWindowInfoFromX11(windowID uint32) (*config.WindowInfo, error) {
-	if b.x11Conn == nil {
-		return nil, fmt.Errorf("X11 not initialized")
-	}
-
-	info := &config.WindowInfo{
-		ID:      windowID,
-		Focused: false,
-	}
-
-	win := xproto.Window(windowID)
-
-	// Get WM_NAME
-	wmNameAtom, _ := xproto.InternAtom(b.x11Conn, false, uint16(len("WM_NAME")), "WM_NAME").Reply()
-	if wmNameAtom != nil {
-		nameReply, err := xproto.GetProperty(b.x11Conn, false, win, wmNameAtom.Atom, xproto.AtomString, 0, 256).Reply()
-		if err == nil && nameReply.ValueLen > 0 {
-			info.Title = string(nameReply.Value)
-		}
-	}
-
-	// Try _NET_WM_NAME for UTF-8 names
-	if info.Title == "" {
-		netWmNameAtom, _ := xproto.InternAtom(b.x11Conn, false, uint16(len("_NET_WM_NAME")), "_NET_WM_NAME").Reply()
-		utf8Atom, _ := xproto.InternAtom(b.x11Conn, false, uint16(len("UTF8_STRING")), "UTF8_STRING").Reply()
-		if netWmNameAtom != nil && utf8Atom != nil {
-			nameReply, err := xproto.GetProperty(b.x11Conn, false, win, netWmNameAtom.Atom, utf8Atom.Atom, 0, 256).Reply()
-			if err == nil && nameReply.ValueLen > 0 {
-				info.Title = string(nameReply.Value)
-			}
-		}
-	}
-
-	// Get WM_CLASS
-	wmClassAtom, _ := xproto.InternAtom(b.x11Conn, false, uint16(len("WM_CLASS")), "WM_CLASS").Reply()
-	if wmClassAtom != nil {
-		classReply, err := xproto.GetProperty(b.x11Conn, false, win, wmClassAtom.Atom, xproto.AtomString, 0, 256).Reply()
-		if err == nil && classReply.ValueLen > 0 {
-			// WM_CLASS is two null-terminated strings: instance and class
-			classData := string(classReply.Value)
-			parts := strings.Split(classData, "\x00")
-			if len(parts) >= 2 && parts[1] != "" {
-				info.Class = strings.ToLower(parts[1])
-			} else if len(parts) >= 1 && parts[0] != "" {
-				info.Class = strings.ToLower(parts[0])
-			}
-		}
-	}
-
-	// Get _NET_WM_PID
-	pidAtom, _ := xproto.InternAtom(b.x11Conn, false, uint16(len("_NET_WM_PID")), "_NET_WM_PID").Reply()
-	cardinalAtom, _ := xproto.InternAtom(b.x11Conn, false, uint16(len("CARDINAL")), "CARDINAL").Reply()
-	if pidAtom != nil && cardinalAtom != nil {
-		pidReply, err := xproto.GetProperty(b.x11Conn, false, win, pidAtom.Atom, cardinalAtom.Atom, 0, 1).Reply()
-		if err == nil && pidReply.ValueLen > 0 {
-			info.PID = int(pidReply.Value[0]) | int(pidReply.Value[1])<<8 | int(pidReply.Value[2])<<16 | int(pidReply.Value[3])<<24
-		}
-	}
-
-	return info, nil
-}
-
-// Note: KWin scripting API (getFocusedWindowViaScript) was removed as it doesn't work on KDE6
-// The runScript method no longer exists in org.kde.kwin.Scripting interface
-
 // getWindowInfoFromDBus gets window info from a KWin client D-Bus path
 func (b *KWinBackend) getWindowInfoFromDBus(clientPath string) (*config.WindowInfo, error) {
 	// clientPath looks like "/org/kde/KWin/Window/<uuid>" or "/org/kde/KWin/Client/<id>"
@@ -1216,6 +783,12 @@ func (b *KWinBackend) watchFocusLoop(callback func(*config.WindowInfo)) {
 		}
 	}
 
+	// Events script pushes focus and caption changes; nil channel if not running
+	var eventChan <-chan struct{}
+	if b.events != nil {
+		eventChan = b.events.notify
+	}
+
 	for {
 		select {
 		case <-b.stopChan:
@@ -1224,6 +797,9 @@ func (b *KWinBackend) watchFocusLoop(callback func(*config.WindowInfo)) {
 			// Desktop switched - immediate focus re-evaluation
 			log.Debug().Msg("Processing desktop change event")
 			checkFocus()
+		case <-eventChan:
+			// Focus or focused window title/geometry changed
+			checkFocus()
 		case <-ticker.C:
 			// Regular polling
 			checkFocus()
@@ -1260,51 +836,43 @@ func (b *KWinBackend) GetCurrentDesktop() int {
 	return b.desktopUUIDToIndex(currentUUID)
 }
 
-// desktopUUIDToIndex converts a desktop UUID to its index number
-func (b *KWinBackend) desktopUUIDToIndex(uuid string) int {
+// desktopIndexMap returns desktop UUID -> index for all virtual desktops
+func (b *KWinBackend) desktopIndexMap() map[string]int {
+	index := make(map[string]int)
+
 	obj := b.conn.Object(kwinService, virtualDesktopManagerPath)
 	desktopsProp, err := obj.GetProperty(virtualDesktopManagerInterface + ".desktops")
 	if err != nil {
-		return 0
+		return index
 	}
 
 	// desktops is a list of (uint32 index, string uuid, string name)
-	desktops, ok := desktopsProp.Value().([][]interface{})
-	if !ok {
-		// Try alternative format: []struct
-		if v := desktopsProp.Value(); v != nil {
-			// Use reflection to iterate
-			switch dv := v.(type) {
-			case []interface{}:
-				for _, d := range dv {
-					if tuple, ok := d.([]interface{}); ok && len(tuple) >= 2 {
-						if idx, ok := tuple[0].(uint32); ok {
-							if desktopUUID, ok := tuple[1].(string); ok {
-								if desktopUUID == uuid {
-									return int(idx)
-								}
-							}
-						}
-					}
-				}
-			}
+	var desktops []interface{}
+	switch v := desktopsProp.Value().(type) {
+	case [][]interface{}:
+		for _, d := range v {
+			desktops = append(desktops, d)
 		}
-		return 0
+	case []interface{}:
+		desktops = v
 	}
 
 	for _, d := range desktops {
-		if len(d) >= 2 {
-			if idx, ok := d[0].(uint32); ok {
-				if desktopUUID, ok := d[1].(string); ok {
-					if desktopUUID == uuid {
-						return int(idx)
-					}
-				}
+		if tuple, ok := d.([]interface{}); ok && len(tuple) >= 2 {
+			idx, idxOK := tuple[0].(uint32)
+			uuid, uuidOK := tuple[1].(string)
+			if idxOK && uuidOK {
+				index[uuid] = int(idx)
 			}
 		}
 	}
 
-	return 0
+	return index
+}
+
+// desktopUUIDToIndex converts a desktop UUID to its index number
+func (b *KWinBackend) desktopUUIDToIndex(uuid string) int {
+	return b.desktopIndexMap()[uuid]
 }
 
 // getWindowDesktopFromDBus gets the desktop number for a window via D-Bus
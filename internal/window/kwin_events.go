@@ -0,0 +1,397 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/godbus/dbus/v5"
+)
+
+// Persistent KWin helper script that pushes window events to FocusStreamer
+// over the org.focusstreamer.Events D-Bus interface
+const (
+	kwinEventsPath       = "/org/focusstreamer/Events"
+	kwinEventsInterface  = "org.focusstreamer.Events"
+	kwinEventsScriptName = "focusstreamer_events"
+	kwinEventsTimeout    = 2 * time.Second
+)
+
+// kwinEventsJS subscribes to workspace and per-window signals. It is
+// formatted with the fsWindow helper, then our bus name, path, and interface.
+const kwinEventsJS = `%s
+var fsService = %q, fsPath = %q, fsIface = %q;
+function fsSend(method, arg) {
+    callDBus(fsService, fsPath, fsIface, method, arg);
+}
+function fsActive() {
+    return workspace.activeWindow || workspace.activeClient;
+}
+function fsSendWindow(method, w) {
+    fsSend(method, JSON.stringify(fsWindow(w, fsActive())));
+}
+function fsWatch(w) {
+    w.captionChanged.connect(function() { fsSendWindow("WindowChanged", w); });
+    if (w.frameGeometryChanged) {
+        w.frameGeometryChanged.connect(function() { fsSendWindow("WindowChanged", w); });
+    }
+    if (w.desktopsChanged) {
+        w.desktopsChanged.connect(function() { fsSendWindow("WindowChanged", w); });
+    }
+}
+var fsList = workspace.windowList ? workspace.windowList() : workspace.clientList();
+var fsAll = [];
+for (var i = 0; i < fsList.length; i++) {
+    fsWatch(fsList[i]);
+    fsAll.push(fsWindow(fsList[i], fsActive()));
+}
+(workspace.windowAdded || workspace.clientAdded).connect(function(w) {
+    fsWatch(w);
+    fsSendWindow("WindowAdded", w);
+});
+(workspace.windowRemoved || workspace.clientRemoved).connect(function(w) {
+    fsSend("WindowRemoved", String(w.internalId));
+});
+(workspace.windowActivated || workspace.clientActivated).connect(function(w) {
+    fsSend("WindowActivated", w ? JSON.stringify(fsWindow(w, w)) : "");
+});
+fsSend("Snapshot", JSON.stringify(fsAll));
+`
+
+// kwinEventReceiver is exported as org.focusstreamer.Events and keeps a
+// live window list fed by the helper script
+type kwinEventReceiver struct {
+	mu       sync.RWMutex
+	windows  map[string]kwinScriptWindow
+	activeID string
+	ready    chan struct{} // Closed on first snapshot
+	readyMu  sync.Once
+	notify   chan struct{}          // Signalled when focus or the focused window changes
+	onChange func(kwinScriptWindow) // Called for every window update
+	onRemove func(kwinScriptWindow) // Called for every closed window
+}
+
+func newKWinEventReceiver() *kwinEventReceiver {
+	return &kwinEventReceiver{
+		windows: make(map[string]kwinScriptWindow),
+		ready:   make(chan struct{}),
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Snapshot replaces the window list (sent once when the script starts)
+func (r *kwinEventReceiver) Snapshot(payload string) *dbus.Error {
+	var windows []kwinScriptWindow
+	if err := json.Unmarshal([]byte(payload), &windows); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+
+	r.mu.Lock()
+	r.windows = make(map[string]kwinScriptWindow, len(windows))
+	r.activeID = ""
+	for _, w := range windows {
+		id := kwinWindowKey(w.ID)
+		r.windows[id] = w
+		if w.Active {
+			r.activeID = id
+		}
+	}
+	r.mu.Unlock()
+
+	r.readyMu.Do(func() { close(r.ready) })
+	r.signal()
+	return nil
+}
+
+// WindowActivated records the newly active window (empty payload for none)
+func (r *kwinEventReceiver) WindowActivated(payload string) *dbus.Error {
+	r.mu.Lock()
+	if payload == "" {
+		r.activeID = ""
+	} else {
+		var w kwinScriptWindow
+		if err := json.Unmarshal([]byte(payload), &w); err != nil {
+			r.mu.Unlock()
+			return dbus.MakeFailedError(err)
+		}
+		id := kwinWindowKey(w.ID)
+		r.windows[id] = w
+		r.activeID = id
+	}
+	r.mu.Unlock()
+
+	r.signal()
+	return nil
+}
+
+// WindowAdded records a new window
+func (r *kwinEventReceiver) WindowAdded(payload string) *dbus.Error {
+	return r.upsert(payload)
+}
+
+// WindowChanged records a caption, geometry, or desktop change
+func (r *kwinEventReceiver) WindowChanged(payload string) *dbus.Error {
+	return r.upsert(payload)
+}
+
+// WindowRemoved drops a closed window
+func (r *kwinEventReceiver) WindowRemoved(id string) *dbus.Error {
+	key := kwinWindowKey(id)
+
+	r.mu.Lock()
+	removed, known := r.windows[key]
+	delete(r.windows, key)
+	wasActive := r.activeID == key
+	if wasActive {
+		r.activeID = ""
+	}
+	r.mu.Unlock()
+
+	if wasActive {
+		r.signal()
+	}
+	if known && r.onRemove != nil {
+		r.onRemove(removed)
+	}
+	return nil
+}
+
+// upsert stores a window and signals if it is the focused one
+func (r *kwinEventReceiver) upsert(payload string) *dbus.Error {
+	var w kwinScriptWindow
+	if err := json.Unmarshal([]byte(payload), &w); err != nil {
+		return dbus.MakeFailedError(err)
+	}
+	key := kwinWindowKey(w.ID)
+
+	r.mu.Lock()
+	r.windows[key] = w
+	isActive := r.activeID == key
+	r.mu.Unlock()
+
+	if isActive {
+		r.signal()
+	}
+	if r.onChange != nil {
+		r.onChange(w)
+	}
+	return nil
+}
+
+// signal wakes the focus loop without blocking the D-Bus handler
+func (r *kwinEventReceiver) signal() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+// list returns a copy of all known windows
+func (r *kwinEventReceiver) list() []kwinScriptWindow {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	windows := make([]kwinScriptWindow, 0, len(r.windows))
+	for _, w := range r.windows {
+		windows = append(windows, w)
+	}
+	return windows
+}
+
+// active returns the focused window, if any
+func (r *kwinEventReceiver) active() (kwinScriptWindow, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.windows[r.activeID]
+	if ok {
+		w.Active = true
+	}
+	return w, ok
+}
+
+// kwinWindowKey normalizes internalId strings (KWin may include braces)
+func kwinWindowKey(id string) string {
+	return strings.Trim(id, "{}")
+}
+
+// startEventScript exports the events interface and installs the
+// persistent helper script, waiting for its initial snapshot
+func (b *KWinBackend) startEventScript() error {
+	names := b.conn.Names()
+	if len(names) == 0 {
+		return fmt.Errorf("no unique D-Bus name")
+	}
+
+	receiver := newKWinEventReceiver()
+	receiver.onChange = b.handleWindowChanged
+	receiver.onRemove = b.handleWindowRemoved
+	if err := b.conn.Export(receiver, kwinEventsPath, kwinEventsInterface); err != nil {
+		return fmt.Errorf("failed to export events interface: %w", err)
+	}
+
+	// The file must outlive loadScript, so it lives until Close
+	scriptPath := filepath.Join(os.TempDir(), fmt.Sprintf("focusstreamer-kwin-events-%d.js", os.Getpid()))
+	script := fmt.Sprintf(kwinEventsJS, kwinScriptWindowJS, names[0], kwinEventsPath, kwinEventsInterface)
+	if err := os.WriteFile(scriptPath, []byte(script), 0644); err != nil {
+		b.conn.Export(nil, kwinEventsPath, kwinEventsInterface)
+		return fmt.Errorf("failed to write events script: %w", err)
+	}
+
+	scripting := b.conn.Object(kwinService, kwinScriptingPath)
+
+	// Unload a stale copy left by a previous run (it would report to a dead bus name)
+	scripting.Call(kwinScriptingInterface+".unloadScript", 0, kwinEventsScriptName)
+
+	cleanup := func() {
+		scripting.Call(kwinScriptingInterface+".unloadScript", 0, kwinEventsScriptName)
+		b.conn.Export(nil, kwinEventsPath, kwinEventsInterface)
+		os.Remove(scriptPath)
+	}
+
+	var id int32
+	if err := scripting.Call(kwinScriptingInterface+".loadScript", 0, scriptPath, kwinEventsScriptName).Store(&id); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to load events script: %w", err)
+	}
+	if id < 0 {
+		cleanup()
+		return fmt.Errorf("KWin rejected events script")
+	}
+	if err := scripting.Call(kwinScriptingInterface+".start", 0).Err; err != nil {
+		cleanup()
+		return fmt.Errorf("failed to start events script: %w", err)
+	}
+
+	select {
+	case <-receiver.ready:
+	case <-time.After(kwinEventsTimeout):
+		cleanup()
+		return fmt.Errorf("timed out waiting for events script snapshot")
+	}
+
+	b.events = receiver
+	b.eventsScriptPath = scriptPath
+
+	logger.WithComponent("kwin-backend").Info().
+		Int("windows", len(receiver.list())).
+		Msg("KWin events script installed")
+	return nil
+}
+
+// stopEventScript unloads the helper script
+func (b *KWinBackend) stopEventScript() {
+	if b.events == nil {
+		return
+	}
+
+	b.conn.Object(kwinService, kwinScriptingPath).Call(kwinScriptingInterface+".unloadScript", 0, kwinEventsScriptName)
+	b.conn.Export(nil, kwinEventsPath, kwinEventsInterface)
+	os.Remove(b.eventsScriptPath)
+	b.events = nil
+}
+
+// listWindowsFromEvents returns normal windows from the event-fed list
+func (b *KWinBackend) listWindowsFromEvents() []*config.WindowInfo {
+	return b.normalWindowInfos(b.events.list())
+}
+
+// WatchTitle reports caption changes of one window via the events script
+func (b *KWinBackend) WatchTitle(windowID uint32, callback func(windowID uint32, title string)) error {
+	if b.events == nil {
+		return fmt.Errorf("title watching requires the KWin events script")
+	}
+
+	b.mu.Lock()
+	b.titleWatchID = windowID
+	b.titleCallback = callback
+	b.mu.Unlock()
+	return nil
+}
+
+// WatchGeometry reports frame geometry changes of one window via the events
+// script
+func (b *KWinBackend) WatchGeometry(windowID uint32, callback func(windowID uint32, geometry config.Geometry)) error {
+	if b.events == nil {
+		return fmt.Errorf("geometry watching requires the KWin events script")
+	}
+
+	b.mu.Lock()
+	b.geometryWatchID = windowID
+	b.geometryCallback = callback
+	b.mu.Unlock()
+	return nil
+}
+
+// WatchWindows reports windows opening, closing and changing via the
+// events script
+func (b *KWinBackend) WatchWindows(callback func(WindowEvent)) error {
+	if b.events == nil {
+		return fmt.Errorf("window events require the KWin events script")
+	}
+
+	b.mu.Lock()
+	b.windowsCallback = callback
+	b.mu.Unlock()
+	return nil
+}
+
+// handleWindowChanged forwards caption and geometry changes of the watched
+// windows, and every added or changed window to the WatchWindows subscriber
+func (b *KWinBackend) handleWindowChanged(w kwinScriptWindow) {
+	info := w.toWindowInfo(nil)
+
+	b.mu.RLock()
+	watched := b.titleWatchID
+	callback := b.titleCallback
+	geometryWatched := b.geometryWatchID
+	geometryCallback := b.geometryCallback
+	windowsCallback := b.windowsCallback
+	b.mu.RUnlock()
+
+	if windowsCallback != nil {
+		// Converted again for its desktop index; windows that stopped
+		// being normal taskbar windows leave the list
+		if normal := b.normalWindowInfos([]kwinScriptWindow{w}); len(normal) > 0 {
+			windowsCallback(WindowEvent{Type: WindowChanged, ID: normal[0].ID, Window: normal[0]})
+		} else {
+			windowsCallback(WindowEvent{Type: WindowClosed, ID: info.ID})
+		}
+	}
+
+	if watched != 0 && info.ID == watched && callback != nil {
+		callback(info.ID, info.Title)
+	}
+	if geometryWatched != 0 && info.ID == geometryWatched && geometryCallback != nil {
+		geometryCallback(info.ID, info.Geometry)
+	}
+}
+
+// handleWindowRemoved reports a closed window to the WatchWindows subscriber
+func (b *KWinBackend) handleWindowRemoved(w kwinScriptWindow) {
+	b.mu.RLock()
+	callback := b.windowsCallback
+	b.mu.RUnlock()
+
+	if callback != nil {
+		callback(WindowEvent{Type: WindowClosed, ID: w.toWindowInfo(nil).ID})
+	}
+}
+
+// focusedWindowFromEvents returns the focused window from the event-fed list
+func (b *KWinBackend) focusedWindowFromEvents() (*config.WindowInfo, error) {
+	w, ok := b.events.active()
+	if !ok {
+		return nil, fmt.Errorf("no active window")
+	}
+
+	info := w.toWindowInfo(b.desktopIndexMap())
+	info.Focused = true
+	return info, nil
+}
@@ -0,0 +1,277 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// kwinPayload encodes windows as the events script sends them
+func kwinPayload(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// kwinCall is one call from the events script
+type kwinCall struct {
+	method  string // Snapshot, WindowActivated, WindowAdded, WindowChanged or WindowRemoved
+	payload string
+}
+
+func (c kwinCall) send(r *kwinEventReceiver) *dbus.Error {
+	switch c.method {
+	case "Snapshot":
+		return r.Snapshot(c.payload)
+	case "WindowActivated":
+		return r.WindowActivated(c.payload)
+	case "WindowAdded":
+		return r.WindowAdded(c.payload)
+	case "WindowChanged":
+		return r.WindowChanged(c.payload)
+	case "WindowRemoved":
+		return r.WindowRemoved(c.payload)
+	}
+	return dbus.MakeFailedError(fmt.Errorf("unknown method %s", c.method))
+}
+
+func TestKWinEventReceiver(t *testing.T) {
+	editor := kwinScriptWindow{ID: "{aaa}", Caption: "main.go", ResourceClass: "code", NormalWindow: true}
+	term := kwinScriptWindow{ID: "{bbb}", Caption: "bash", ResourceClass: "konsole", NormalWindow: true, Active: true}
+	renamed := term
+	renamed.Caption = "vim"
+	browser := kwinScriptWindow{ID: "ccc", Caption: "Docs", ResourceClass: "firefox", NormalWindow: true}
+
+	snapshot := kwinCall{"Snapshot", kwinPayload(t, []kwinScriptWindow{editor, term})}
+
+	tests := []struct {
+		name        string
+		calls       []kwinCall
+		wantErr     bool     // The last call fails
+		wantWindows []string // Captions, sorted
+		wantActive  string   // Caption of the focused window, empty for none
+		wantSignal  bool     // The last call woke the focus loop
+		wantChanged []string // Captions passed to onChange
+		wantRemoved []string // Captions passed to onRemove
+	}{
+		{
+			name:        "snapshot",
+			calls:       []kwinCall{snapshot},
+			wantWindows: []string{"bash", "main.go"},
+			wantActive:  "bash",
+			wantSignal:  true,
+		},
+		{
+			name:        "activate new window",
+			calls:       []kwinCall{snapshot, {"WindowActivated", kwinPayload(t, browser)}},
+			wantWindows: []string{"Docs", "bash", "main.go"},
+			wantActive:  "Docs",
+			wantSignal:  true,
+		},
+		{
+			name:        "activate nothing",
+			calls:       []kwinCall{snapshot, {"WindowActivated", ""}},
+			wantWindows: []string{"bash", "main.go"},
+			wantSignal:  true,
+		},
+		{
+			name:        "focused window renamed",
+			calls:       []kwinCall{snapshot, {"WindowChanged", kwinPayload(t, renamed)}},
+			wantWindows: []string{"main.go", "vim"},
+			wantActive:  "vim",
+			wantSignal:  true,
+			wantChanged: []string{"vim"},
+		},
+		{
+			name:        "other window added",
+			calls:       []kwinCall{snapshot, {"WindowAdded", kwinPayload(t, browser)}},
+			wantWindows: []string{"Docs", "bash", "main.go"},
+			wantActive:  "bash",
+			wantChanged: []string{"Docs"},
+		},
+		{
+			name:        "focused window removed by bare ID",
+			calls:       []kwinCall{snapshot, {"WindowRemoved", "bbb"}},
+			wantWindows: []string{"main.go"},
+			wantSignal:  true,
+			wantRemoved: []string{"bash"},
+		},
+		{
+			name:        "other window removed",
+			calls:       []kwinCall{snapshot, {"WindowRemoved", "{aaa}"}},
+			wantWindows: []string{"bash"},
+			wantActive:  "bash",
+			wantRemoved: []string{"main.go"},
+		},
+		{
+			name:        "unknown window removed",
+			calls:       []kwinCall{snapshot, {"WindowRemoved", "{zzz}"}},
+			wantWindows: []string{"bash", "main.go"},
+			wantActive:  "bash",
+		},
+		{
+			name:        "new snapshot replaces the list",
+			calls:       []kwinCall{snapshot, {"Snapshot", kwinPayload(t, []kwinScriptWindow{browser})}},
+			wantWindows: []string{"Docs"},
+			wantSignal:  true,
+		},
+		{
+			name:        "bad payload leaves the list",
+			calls:       []kwinCall{snapshot, {"WindowChanged", "{not json"}},
+			wantErr:     true,
+			wantWindows: []string{"bash", "main.go"},
+			wantActive:  "bash",
+		},
+		{
+			name:        "bad activation payload",
+			calls:       []kwinCall{snapshot, {"WindowActivated", "["}},
+			wantErr:     true,
+			wantWindows: []string{"bash", "main.go"},
+			wantActive:  "bash",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newKWinEventReceiver()
+			var changed, removed []string
+			r.onChange = func(w kwinScriptWindow) { changed = append(changed, w.Caption) }
+			r.onRemove = func(w kwinScriptWindow) { removed = append(removed, w.Caption) }
+
+			for i, call := range tt.calls {
+				last := i == len(tt.calls)-1
+				if last {
+					// Drain earlier signals so only the last call's shows
+					select {
+					case <-r.notify:
+					default:
+					}
+				}
+				err := call.send(r)
+				if err != nil && !(last && tt.wantErr) {
+					t.Fatalf("%s: %v", call.method, err)
+				}
+				if last && tt.wantErr && err == nil {
+					t.Fatalf("%s succeeded, want an error", call.method)
+				}
+			}
+
+			select {
+			case <-r.ready:
+			default:
+				t.Error("not ready after a snapshot")
+			}
+
+			var windows []string
+			for _, w := range r.list() {
+				windows = append(windows, w.Caption)
+			}
+			sort.Strings(windows)
+			if strings.Join(windows, ",") != strings.Join(tt.wantWindows, ",") {
+				t.Errorf("windows = %v, want %v", windows, tt.wantWindows)
+			}
+
+			active, ok := r.active()
+			if ok != (tt.wantActive != "") || active.Caption != tt.wantActive {
+				t.Errorf("active = %q (%v), want %q", active.Caption, ok, tt.wantActive)
+			}
+			if ok && !active.Active {
+				t.Error("focused window not marked active")
+			}
+
+			signalled := false
+			select {
+			case <-r.notify:
+				signalled = true
+			default:
+			}
+			if signalled != tt.wantSignal {
+				t.Errorf("signalled = %v, want %v", signalled, tt.wantSignal)
+			}
+			if strings.Join(changed, ",") != strings.Join(tt.wantChanged, ",") {
+				t.Errorf("onChange got %v, want %v", changed, tt.wantChanged)
+			}
+			if strings.Join(removed, ",") != strings.Join(tt.wantRemoved, ",") {
+				t.Errorf("onRemove got %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// TestKWinEventReceiverConcurrentCalls delivers script calls from several
+// goroutines, as godbus dispatches them, while the focus loop reads; run
+// with -race
+func TestKWinEventReceiverConcurrentCalls(t *testing.T) {
+	r := newKWinEventReceiver()
+	var changes, removals atomic.Int64
+	r.onChange = func(kwinScriptWindow) { changes.Add(1) }
+	r.onRemove = func(kwinScriptWindow) { removals.Add(1) }
+
+	const senders, windows = 4, 200
+	var wg sync.WaitGroup
+	for s := range senders {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := r.Snapshot("[]"); err != nil {
+				t.Error(err)
+			}
+			for i := range windows {
+				w := kwinScriptWindow{ID: fmt.Sprintf("{%d-%d}", s, i), Caption: "window", NormalWindow: true}
+				payload := kwinPayload(t, w)
+				r.WindowAdded(payload)
+				r.WindowActivated(payload)
+				w.Caption = "renamed"
+				r.WindowChanged(kwinPayload(t, w))
+				r.WindowRemoved(w.ID)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	readers.Add(1)
+	go func() {
+		defer readers.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-r.notify:
+			default:
+			}
+			for _, w := range r.list() {
+				if w.Caption != "window" && w.Caption != "renamed" {
+					t.Errorf("window %s has caption %q", w.ID, w.Caption)
+				}
+			}
+			r.active()
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if got := len(r.list()); got != 0 {
+		t.Errorf("%d windows left after every window was removed", got)
+	}
+	if _, ok := r.active(); ok {
+		t.Error("active window left after every window was removed")
+	}
+	if got, want := changes.Load(), int64(2*senders*windows); got != want {
+		t.Errorf("onChange called %d times, want %d", got, want)
+	}
+	// A snapshot from another sender can clear a window before its removal
+	if got := removals.Load(); got == 0 || got > senders*windows {
+		t.Errorf("onRemove called %d times, want 1-%d", got, senders*windows)
+	}
+}
@@ -0,0 +1,323 @@
+package window
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus object exported so one-shot KWin scripts can hand results back over
+// the session bus (instead of print() + journal scraping)
+const (
+	kwinScriptResultPath      = "/org/focusstreamer/KWinScript"
+	kwinScriptResultInterface = "org.focusstreamer.KWinScript"
+	kwinScriptingPath         = "/Scripting"
+	kwinScriptingInterface    = "org.kde.kwin.Scripting"
+	kwinScriptTimeout         = time.Second
+)
+
+// kwinScriptWindowJS serializes a KWin 5 client or KWin 6 window. Wayland
+// windows have no X11 windowId; desktops is a list of VirtualDesktop
+// objects on KWin 6 and an int on KWin 5.
+const kwinScriptWindowJS = `function fsWindow(w, active) {
+    var g = w.frameGeometry || w.geometry;
+    var desktops = [];
+    if (w.desktops) {
+        for (var j = 0; j < w.desktops.length; j++) {
+            desktops.push(String(w.desktops[j].id));
+        }
+    }
+    return {
+        id: String(w.internalId),
+        caption: String(w.caption),
+        resourceClass: String(w.resourceClass),
+        resourceName: String(w.resourceName),
+        pid: w.pid || 0,
+        windowId: w.windowId || 0,
+        x: g.x, y: g.y, width: g.width, height: g.height,
+        desktops: desktops,
+        desktop: typeof w.desktop === "number" ? w.desktop : 0,
+        onAllDesktops: !!w.onAllDesktops,
+        normalWindow: !!w.normalWindow,
+        skipTaskbar: !!w.skipTaskbar,
+        active: w === active
+    };
+}
+`
+
+// kwinScriptWindow is a window as serialized by kwinScriptWindowJS
+type kwinScriptWindow struct {
+	ID            string   `json:"id"`
+	Caption       string   `json:"caption"`
+	ResourceClass string   `json:"resourceClass"`
+	ResourceName  string   `json:"resourceName"`
+	PID           int      `json:"pid"`
+	WindowID      uint32   `json:"windowId"`
+	X             float64  `json:"x"`
+	Y             float64  `json:"y"`
+	Width         float64  `json:"width"`
+	Height        float64  `json:"height"`
+	Desktops      []string `json:"desktops"`
+	Desktop       int      `json:"desktop"`
+	OnAllDesktops bool     `json:"onAllDesktops"`
+	NormalWindow  bool     `json:"normalWindow"`
+	SkipTaskbar   bool     `json:"skipTaskbar"`
+	Active        bool     `json:"active"`
+}
+
+// kwinScriptReceiver is exported on the session bus and receives Report
+// calls from scripts, routing each payload to the waiting caller
+type kwinScriptReceiver struct {
+	mu      sync.Mutex
+	pending map[string]chan string
+}
+
+// Report is called by scripts via callDBus with the script name and payload
+func (r *kwinScriptReceiver) Report(name, payload string) *dbus.Error {
+	r.mu.Lock()
+	ch, ok := r.pending[name]
+	delete(r.pending, name)
+	r.mu.Unlock()
+
+	if ok {
+		ch <- payload
+	}
+	return nil
+}
+
+// expect registers a pending result for a script name
+func (r *kwinScriptReceiver) expect(name string) chan string {
+	ch := make(chan string, 1)
+	r.mu.Lock()
+	r.pending[name] = ch
+	r.mu.Unlock()
+	return ch
+}
+
+// cancel drops a pending result that timed out
+func (r *kwinScriptReceiver) cancel(name string) {
+	r.mu.Lock()
+	delete(r.pending, name)
+	r.mu.Unlock()
+}
+
+// exportScriptReceiver exports the script result object on the connection
+func (b *KWinBackend) exportScriptReceiver() error {
+	b.scriptReceiver = &kwinScriptReceiver{pending: make(map[string]chan string)}
+	if err := b.conn.Export(b.scriptReceiver, kwinScriptResultPath, kwinScriptResultInterface); err != nil {
+		return fmt.Errorf("failed to export script result object: %w", err)
+	}
+	return nil
+}
+
+// runScript loads a one-shot KWin script whose body ends by reporting a
+// string result, runs it, and waits for the result over D-Bus. The body
+// must call report(value) exactly once.
+func (b *KWinBackend) runScript(body string) (string, error) {
+	names := b.conn.Names()
+	if len(names) == 0 {
+		return "", fmt.Errorf("no unique D-Bus name")
+	}
+
+	name := fmt.Sprintf("focusstreamer_%d", time.Now().UnixNano())
+	script := fmt.Sprintf(`%s
+function report(value) {
+    callDBus(%q, %q, %q, "Report", %q, value);
+}
+%s`, kwinScriptWindowJS, names[0], kwinScriptResultPath, kwinScriptResultInterface, name, body)
+
+	// loadScript takes a file path, so the script still goes through a temp file
+	file, err := os.CreateTemp("", "focusstreamer-kwin-*.js")
+	if err != nil {
+		return "", fmt.Errorf("failed to create script file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.WriteString(script); err != nil {
+		file.Close()
+		return "", fmt.Errorf("failed to write script file: %w", err)
+	}
+	file.Close()
+
+	result := b.scriptReceiver.expect(name)
+	defer b.scriptReceiver.cancel(name)
+
+	scripting := b.conn.Object(kwinService, kwinScriptingPath)
+	var id int32
+	if err := scripting.Call(kwinScriptingInterface+".loadScript", 0, file.Name(), name).Store(&id); err != nil {
+		return "", fmt.Errorf("failed to load KWin script: %w", err)
+	}
+	defer scripting.Call(kwinScriptingInterface+".unloadScript", 0, name)
+
+	if id < 0 {
+		return "", fmt.Errorf("KWin rejected script %s", name)
+	}
+	if err := scripting.Call(kwinScriptingInterface+".start", 0).Err; err != nil {
+		return "", fmt.Errorf("failed to start KWin script: %w", err)
+	}
+
+	select {
+	case payload := <-result:
+		return payload, nil
+	case <-time.After(kwinScriptTimeout):
+		return "", fmt.Errorf("timed out waiting for KWin script result")
+	}
+}
+
+// queryWindowsViaScript returns all windows (or only the active one) as
+// reported by a KWin script
+func (b *KWinBackend) queryWindowsViaScript(activeOnly bool) ([]kwinScriptWindow, error) {
+	body := `var active = workspace.activeWindow || workspace.activeClient;
+var list = workspace.windowList ? workspace.windowList() : workspace.clientList();
+var out = [];
+for (var i = 0; i < list.length; i++) {
+    out.push(fsWindow(list[i], active));
+}
+report(JSON.stringify(out));`
+	if activeOnly {
+		body = `var active = workspace.activeWindow || workspace.activeClient;
+report(JSON.stringify(active ? [fsWindow(active, active)] : []));`
+	}
+
+	payload, err := b.runScript(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var windows []kwinScriptWindow
+	if err := json.Unmarshal([]byte(payload), &windows); err != nil {
+		return nil, fmt.Errorf("failed to parse KWin script result: %w", err)
+	}
+	return windows, nil
+}
+
+// ActivateWindow focuses and raises a window (un-minimizing it and switching
+// to its desktop) with a KWin script, falling back to kdotool when scripts
+// aren't available
+func (b *KWinBackend) ActivateWindow(windowID uint32) error {
+	if b.scriptReceiver == nil {
+		return b.activateWindowViaKdotool(windowID)
+	}
+
+	internalID, err := b.scriptWindowInternalID(windowID)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(`var list = workspace.windowList ? workspace.windowList() : workspace.clientList();
+var result = "notfound";
+for (var i = 0; i < list.length; i++) {
+    var w = list[i];
+    if (String(w.internalId) !== %q) {
+        continue;
+    }
+    w.minimized = false;
+    if (workspace.activeWindow !== undefined) {
+        workspace.activeWindow = w;
+    } else {
+        workspace.activeClient = w;
+    }
+    result = "ok";
+    break;
+}
+report(result);`, internalID)
+
+	result, err := b.runScript(body)
+	if err != nil {
+		if kdotoolErr := b.activateWindowViaKdotool(windowID); kdotoolErr == nil {
+			return nil
+		}
+		return err
+	}
+	if result != "ok" {
+		return fmt.Errorf("window %d not found", windowID)
+	}
+	return nil
+}
+
+// scriptWindowInternalID maps a window ID to KWin's internalId
+func (b *KWinBackend) scriptWindowInternalID(windowID uint32) (string, error) {
+	scriptWindows, err := b.queryWindowsViaScript(false)
+	if err != nil {
+		return "", err
+	}
+
+	desktopIndex := b.desktopIndexMap()
+	for _, w := range scriptWindows {
+		if w.toWindowInfo(desktopIndex).ID == windowID {
+			return w.ID, nil
+		}
+	}
+	return "", fmt.Errorf("window %d not found", windowID)
+}
+
+// activateWindowViaKdotool activates a native Wayland window with kdotool,
+// matching the UUIDs it lists against window IDs
+func (b *KWinBackend) activateWindowViaKdotool(windowID uint32) error {
+	if _, err := exec.LookPath("kdotool"); err != nil {
+		return fmt.Errorf("KWin scripting unavailable and kdotool not installed")
+	}
+
+	out, err := exec.Command("kdotool", "search", ".").Output()
+	if err != nil {
+		return fmt.Errorf("kdotool search failed: %w", err)
+	}
+
+	for _, uuid := range strings.Fields(string(out)) {
+		if hashStringToUint32(strings.Trim(uuid, "{}")) != windowID {
+			continue
+		}
+		if err := exec.Command("kdotool", "windowactivate", uuid).Run(); err != nil {
+			return fmt.Errorf("kdotool windowactivate failed: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("window %d not found", windowID)
+}
+
+// toWindowInfo converts a script-reported window to WindowInfo. XWayland
+// windows keep their X11 ID so they can be captured directly.
+func (w kwinScriptWindow) toWindowInfo(desktopIndex map[string]int) *config.WindowInfo {
+	uuid := strings.Trim(w.ID, "{}")
+
+	info := &config.WindowInfo{
+		ID:              hashStringToUint32(uuid),
+		Title:           w.Caption,
+		Class:           strings.ToLower(w.ResourceClass),
+		PID:             w.PID,
+		Focused:         w.Active,
+		IsNativeWayland: true,
+		Geometry: config.Geometry{
+			X:      int(w.X),
+			Y:      int(w.Y),
+			Width:  int(w.Width),
+			Height: int(w.Height),
+		},
+	}
+	if info.Class == "" {
+		info.Class = strings.ToLower(w.ResourceName)
+	}
+
+	// 0x200000 is the XWayland placeholder, not a real client window
+	if w.WindowID > 0 && w.WindowID != 0x200000 {
+		info.ID = w.WindowID
+		info.IsNativeWayland = false
+	}
+
+	switch {
+	case w.OnAllDesktops:
+		info.Desktop = -1
+	case len(w.Desktops) > 0:
+		info.Desktop = desktopIndex[w.Desktops[0]]
+	case w.Desktop > 0:
+		info.Desktop = w.Desktop - 1 // KWin 5 desktops are 1-based
+	}
+
+	return info
+}
@@ -2,32 +2,42 @@ package window
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
 	_ "image/gif"  // Register GIF decoder
 	_ "image/jpeg" // Register JPEG decoder
-	"image/png"
 	"net/url"
 	"os"
-	"regexp"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/composite"
 	"github.com/BurntSushi/xgb/xproto"
 	"github.com/bryanchriswhite/FocusStreamer/internal/capture"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/gpu"
+	"github.com/bryanchriswhite/FocusStreamer/internal/imgenc"
+	"github.com/bryanchriswhite/FocusStreamer/internal/ingest"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 	"github.com/bryanchriswhite/FocusStreamer/internal/output"
 	"github.com/bryanchriswhite/FocusStreamer/internal/overlay"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pii"
+	"github.com/bryanchriswhite/FocusStreamer/internal/pixconv"
+	"github.com/bryanchriswhite/FocusStreamer/internal/remote"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
 	xdraw "golang.org/x/image/draw"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
+	"golang.org/x/sync/errgroup"
 )
 
 // ZoomState represents the current zoom and pan state for the stream
@@ -48,59 +58,172 @@ type BrowserContext struct {
 
 // Manager handles window detection and monitoring
 type Manager struct {
-	// Backend for window discovery (X11 or KWin)
-	backend Backend
+	// Backend for window discovery (X11 or KWin), replaced by the
+	// supervisor when its connection is lost
+	backend     Backend
+	backendName string
+	backendMu   sync.RWMutex
 
 	// Capture router for X11/PipeWire capture
 	captureRouter *capture.Router
 
-	// X11 connection for screenshot capture (needed regardless of backend)
-	conn             *xgb.Conn
-	root             xproto.Window
-	screen           *xproto.ScreenInfo
-	compositeEnabled bool
+	// Capture agent connection in agent mode (remote backend), else nil
+	remoteHub *remote.Hub
+
+	// Frames pushed by other programs, and the source streamed in place of
+	// the focused window ("", guarded by streamMu)
+	external       *ingest.Sources
+	externalSource string
+
+	// What the stream showed since start, for /api/timeline
+	timeline *timelineRecorder
+
+	// Captions posted through /api/annotations, and strokes drawn over
+	// the stream through /api/annotations/draw
+	captions    captionQueue
+	telestrator telestrator
+
+	// Click state and ripples for pointer_highlight
+	pointer pointerHighlighter
+
+	// Background capture of windows shown besides the focused one
+	captureScheduler *capture.Scheduler
+
+	// X11 connection for screenshot capture (nil without an X server)
+	x11    *x11Conn
+	x11Mu  sync.RWMutex
+	useX11 bool // Reconnect the X connection after a loss
+
+	// Reconnect state reported in /api/health
+	connStatus ConnectionStatus
+	connMu     sync.RWMutex
 
 	configMgr     *config.Manager
 	currentWindow *config.WindowInfo
 	mu            sync.RWMutex
 	listeners     []chan *config.WindowInfo
-	stopChan      chan struct{}
+
+	// Lifetime of the background tasks (supervisor, reconciliation, scene
+	// transitions), canceled by Stop, which waits for them to return
+	ctx      context.Context
+	cancel   context.CancelFunc
+	tasks    *errgroup.Group
+	tasksMu  sync.Mutex
+	stopping bool
+
+	// Stages each streamed frame passes through
+	pipeline *Pipeline
 
 	// Output for streaming frames
 	output            output.Output
 	overlayMgr        *overlay.Manager
-	streamStopChan    chan struct{}
+	streamCancel      context.CancelFunc // Ends the running stream loop and governor
+	streamTasks       *errgroup.Group
 	streamRunning     bool
 	streamMu          sync.Mutex
-	lastAllowedWindow *config.WindowInfo // Last allowlisted window to stream
+	frameRequest      chan struct{} // Requests an immediate frame outside the ticker
+	titleWatchID      uint32        // Window subscribed for title changes (stream goroutine only)
+	titleWatchBackend Backend       // Backend holding that subscription (stream goroutine only)
+
+	// Geometry change subscription for the shared window (stream goroutine only)
+	geometryWatchID      uint32
+	geometryWatchBackend Backend
+
+	// Close subscription for the shared window (stream goroutine only)
+	closeWatchID      uint32
+	closeWatchBackend Backend
+
+	// Geometry of the last frame sent, and Snapshot calls waiting for the
+	// next one
+	geometry        StreamGeometry
+	snapshotWaiters []chan streamSnapshot
+
+	// Standby, bypass, panic, zoom and fallback window state, snapshotted
+	// per frame (see stateStore)
+	state *stateStore
+
+	// Recent batch screenshots and the captures in flight (see
+	// CaptureWindowScreenshots)
+	screenshotCache map[screenshotKey]cachedScreenshot
+	screenshotMu    sync.Mutex
+	screenshotSem   chan struct{}
+
+	// Open windows for API listings and lookups, fed by backend window
+	// events where supported (see windowRegistry)
+	registry       *windowRegistry
+	registrySyncMu sync.Mutex
+
+	// Rolling capture failure counts per capture backend
+	captureFailures *captureFailureLog
 
-	// Manual standby control
-	forceStandby bool
+	// Optional OCR guard that blanks the stream when sensitive text is visible
+	piiGuard *pii.Guard
 
-	// Allowlist bypass mode - when enabled, all windows are shown regardless of allowlist
-	allowlistBypass bool
+	// Restarts capture when frames stop changing (nil when disabled)
+	watchdog *capture.Watchdog
+
+	// On-air state: stream viewers connected and not in standby
+	clientCount   int
+	onAir         bool
+	onAirCallback func(onAir bool)
+
+	// Frame consumers other than stream viewers (see AcquireFrames), and
+	// whether the stream loop is parked because there are none
+	frameDemand int
+	suspended   bool
+
+	// Load governor state; fpsChange retimes the stream loop
+	throttleMu sync.Mutex
+	throttle   ThrottleStatus
+	fpsChange  chan int
+
+	// Frame rate of the stream loop after capture.app_fps (streamMu)
+	streamFPS int
+
+	// Called when the window shown on the stream changes
+	sharedWindowCallback func(window *config.WindowInfo)
+
+	// Called when a window of a class not shown since the manager started
+	// is shown; sharedClasses holds the classes shown so far (streamMu)
+	newClassCallback func(window *config.WindowInfo)
+	sharedClasses    map[string]bool
+
+	// Called when standby, on-air, or the active profile changes
+	stateCallback func()
+
+	// Called with each typed event (see events.go)
+	eventCallback func(event *events.Event)
 
 	// Browser URL contexts keyed by window class
 	browserContexts   map[string]BrowserContext
 	browserContextMu  sync.RWMutex
 	browserContextTTL time.Duration
+	liveBrowserConns  map[string]int // Connected extension sessions per window class; contexts never expire while > 0
 
-	// Zoom and pan control
-	zoomState ZoomState
-	zoomMu    sync.RWMutex
+	// Last activated scene; sceneGen cancels the transition of a scene
+	// replaced while it runs
+	sceneMu     sync.Mutex
+	activeScene string
+	sceneGen    uint64
 
 	// Last unzoomed frame for minimap thumbnail
 	lastUnzoomedFrame *image.RGBA
 	unzoomedFrameMu   sync.RWMutex
 
 	// Cached placeholder frame
-	cachedPlaceholder     *image.RGBA
-	cachedPlaceholderPath string // Path used to generate cached placeholder
-	cachedPlaceholderSize image.Point
+	placeholder placeholderCache
 
-	// Placeholder rotation state
-	wasInStandby          bool // True if previous frame was showing placeholder
-	currentPlaceholderIdx int  // Index of currently selected placeholder (-1 = default)
+	// GPU scaler for zoomed frames (nil uses the CPU). Only the stream
+	// goroutine changes it, under streamMu.
+	scaler gpu.Scaler
+
+	// Color management converter, rebuilt when its config changes
+	colorConverter    *capture.ColorConverter
+	colorConverterKey string
+
+	// Stream filters, rebuilt when their config changes
+	imageFilter    *capture.ImageFilter
+	imageFilterKey config.StreamFiltersConfig
 
 	// Health monitoring
 	lastFrameTime        time.Time
@@ -109,37 +232,56 @@ type Manager struct {
 	healthMu             sync.RWMutex
 }
 
+// Backend names accepted by NewManagerWithBackend
+const (
+	BackendAuto      = "auto"
+	BackendX11       = "x11"
+	BackendKWin      = "kwin"
+	BackendHyprland  = "hyprland"
+	BackendWindows   = "windows"
+	BackendSynthetic = "synthetic"
+	BackendRemote    = "remote"
+)
+
+// BackendNames lists the valid backend names, including ones added with
+// RegisterBackend
+var BackendNames = []string{BackendAuto, BackendX11, BackendKWin, BackendHyprland, BackendWindows, BackendSynthetic, BackendRemote}
+
 // NewManager creates a new window manager with auto-detected backend
 func NewManager(configMgr *config.Manager) (*Manager, error) {
+	return NewManagerWithBackend(configMgr, BackendAuto)
+}
+
+// NewManagerWithBackend creates a new window manager using the named backend
+func NewManagerWithBackend(configMgr *config.Manager, backendName string) (*Manager, error) {
+	switch backendName {
+	case BackendSynthetic:
+		return newSyntheticManager(configMgr)
+	case BackendRemote:
+		return newRemoteManager(configMgr)
+	}
+
 	log := logger.WithComponent("window-manager")
 
-	// Auto-detect backend
-	backend, err := detectBackend()
+	backend, err := newBackend(backendName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to detect window backend: %w", err)
+		return nil, fmt.Errorf("failed to initialize window backend: %w", err)
 	}
 	log.Info().Str("backend", backend.Name()).Msg("Using window backend")
 
-	// Always need X11 connection for screenshot capture
-	conn, err := xgb.NewConn()
-	if err != nil {
-		backend.Close()
-		return nil, fmt.Errorf("failed to connect to X server for screenshots: %w", err)
+	// X11 connection for screenshot capture. Only the x11 backend requires
+	// it; Wayland backends without XWayland capture through PipeWire, and
+	// Windows through the platform capturer.
+	var x11 *x11Conn
+	if !usesPlatformCapture() {
+		x11, err = connectX11()
 	}
-
-	setup := xproto.Setup(conn)
-	screen := setup.DefaultScreen(conn)
-	root := screen.Root
-
-	// Initialize composite extension
-	compositeEnabled := false
-	if err := composite.Init(conn); err != nil {
-		log.Warn().
-			Err(err).
-			Msg("Composite extension not available - window screenshots may fail for obscured or off-screen windows")
-	} else {
-		compositeEnabled = true
-		log.Info().Msg("Composite extension initialized successfully")
+	if err != nil {
+		if backendName == BackendX11 {
+			backend.Close()
+			return nil, fmt.Errorf("failed to connect to X server for screenshots: %w", err)
+		}
+		log.Warn().Err(err).Msg("No X server for screenshots, relying on capture router")
 	}
 
 	// Initialize capture router
@@ -147,6 +289,10 @@ func NewManager(configMgr *config.Manager) (*Manager, error) {
 	if err != nil {
 		log.Warn().Err(err).Msg("Failed to create capture router")
 	} else {
+		captureRouter.SetPipeWireConfig(configMgr.Get().Capture)
+		captureRouter.SetRouteRules(func(class string) (string, bool) {
+			return configMgr.Get().CaptureRoute(class)
+		})
 		if err := captureRouter.Start(); err != nil {
 			log.Warn().Err(err).Msg("Failed to start capture router")
 			captureRouter = nil
@@ -154,66 +300,194 @@ func NewManager(configMgr *config.Manager) (*Manager, error) {
 			log.Info().
 				Bool("has_x11", captureRouter.HasX11()).
 				Bool("has_pipewire", captureRouter.HasPipeWire()).
+				Bool("has_platform", captureRouter.HasPlatform()).
 				Msg("Capture router initialized")
 		}
 	}
 
+	m := newManager(configMgr, backend, captureRouter)
+	m.backendName = backendName
+	m.x11 = x11
+	m.useX11 = x11 != nil
+
+	return m, nil
+}
+
+// newSyntheticManager creates a window manager with fake windows and
+// procedurally drawn frames. No display server connection is made.
+func newSyntheticManager(configMgr *config.Manager) (*Manager, error) {
+	log := logger.WithComponent("window-manager")
+
+	captureRouter := capture.NewSyntheticRouter()
+	if err := captureRouter.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start synthetic capture: %w", err)
+	}
+
+	log.Info().Msg("Using synthetic window backend (demo mode)")
+	return newManager(configMgr, NewSyntheticBackend(), captureRouter), nil
+}
+
+// newRemoteManager creates a window manager for agent mode: windows and
+// frames come from a capture agent on another machine. No display server
+// connection is made.
+func newRemoteManager(configMgr *config.Manager) (*Manager, error) {
+	hub := remote.NewHub(configMgr.Get().StreamFPS())
+	captureRouter := capture.NewRemoteRouter(hub)
+	if err := captureRouter.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start remote capture: %w", err)
+	}
+
+	logger.WithComponent("window-manager").Info().Str("endpoint", remote.AgentPath).Msg("Using remote window backend, waiting for a capture agent")
+	m := newManager(configMgr, NewRemoteBackend(hub), captureRouter)
+	m.backendName = BackendRemote
+	m.remoteHub = hub
+	return m, nil
+}
+
+// RemoteHub returns the hub capture agents connect to, or nil unless the
+// remote backend is in use
+func (m *Manager) RemoteHub() *remote.Hub {
+	return m.remoteHub
+}
+
+// newManager builds a Manager around a backend and capture router.
+// X11 fields are left unset; callers with an X connection fill them in.
+func newManager(configMgr *config.Manager, backend Backend, captureRouter *capture.Router) *Manager {
+	var watchdog *capture.Watchdog
+	if cfg := configMgr.Get().Capture.Watchdog; cfg.Enabled && captureRouter != nil {
+		stallAfter := time.Duration(cfg.StallSeconds) * time.Second
+		if stallAfter <= 0 {
+			stallAfter = defaultStallTimeout
+		}
+		watchdog = capture.NewWatchdog(stallAfter)
+	}
+
 	m := &Manager{
 		backend:           backend,
 		captureRouter:     captureRouter,
-		conn:              conn,
-		root:              root,
-		screen:            screen,
+		external:          ingest.NewSources(),
+		timeline:          newTimelineRecorder(time.Now()),
 		configMgr:         configMgr,
 		listeners:         make([]chan *config.WindowInfo, 0),
-		stopChan:          make(chan struct{}),
-		compositeEnabled:  compositeEnabled,
+		frameRequest:      make(chan struct{}, 1),
+		fpsChange:         make(chan int, 1),
 		browserContexts:   make(map[string]BrowserContext),
+		liveBrowserConns:  make(map[string]int),
 		browserContextTTL: 5 * time.Second,
-		zoomState:         ZoomState{Scale: 1.0, OffsetX: 0.5, OffsetY: 0.5},
+		screenshotCache:   make(map[screenshotKey]cachedScreenshot),
+		registry:          newWindowRegistry(),
+		captureFailures:   newCaptureFailureLog(),
+		screenshotSem:     make(chan struct{}, screenshotConcurrency),
+		state:             newStateStore(streamState{zoom: ZoomState{Scale: 1.0, OffsetX: 0.5, OffsetY: 0.5}}),
+		watchdog:          watchdog,
+		connStatus:        ConnectionStatus{Connected: true},
+	}
+	m.pipeline = m.newStreamPipeline()
+	m.captureScheduler = capture.NewScheduler(m.captureWorkerFrame)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.tasks, m.ctx = errgroup.WithContext(ctx)
+	m.cancel = cancel
+
+	if configMgr.Get().Capture.GPUCompose {
+		scaler, err := gpu.NewCompositor()
+		if err != nil {
+			logger.WithComponent("window-manager").Warn().Err(err).Msg("GPU compose unavailable, scaling on the CPU")
+		} else {
+			logger.WithComponent("window-manager").Info().Str("renderer", scaler.Renderer()).Msg("GPU compose enabled")
+			m.scaler = scaler
+		}
 	}
-
-	return m, nil
+	return m
 }
 
-// detectBackend auto-detects the appropriate window backend
+// detectBackend auto-detects the appropriate window backend. Registered
+// backends that recognize the session take precedence over the built-in ones.
 func detectBackend() (Backend, error) {
 	log := logger.WithComponent("window-manager")
 
+	if backend := detectRegisteredBackend(); backend != nil {
+		return backend, nil
+	}
+
+	switch runtime.GOOS {
+	case "windows":
+		return NewWin32Backend()
+	}
+
 	// Check if running on Wayland
 	sessionType := os.Getenv("XDG_SESSION_TYPE")
 	log.Debug().Str("XDG_SESSION_TYPE", sessionType).Msg("Detecting session type")
 
-	if sessionType == "wayland" {
-		// Try KWin backend first
+	if sessionType != "wayland" {
+		log.Info().Msg("Using X11 backend")
+		return NewX11Backend()
+	}
+
+	// Pick the compositor-native backend, then chain XWayland behind it so
+	// calls keep working if the native backend starts failing
+	var primary Backend
+	if os.Getenv("HYPRLAND_INSTANCE_SIGNATURE") != "" {
+		log.Info().Msg("Wayland session detected, trying Hyprland backend")
+		if hypr, err := NewHyprlandBackend(); err == nil {
+			primary = hypr
+		} else {
+			log.Warn().Err(err).Msg("Hyprland backend not available")
+		}
+	}
+	if primary == nil {
 		log.Info().Msg("Wayland session detected, trying KWin backend")
-		kwin, err := NewKWinBackend()
-		if err == nil {
-			return kwin, nil
+		if kwin, err := NewKWinBackend(); err == nil {
+			primary = kwin
+		} else {
+			log.Warn().Err(err).Msg("KWin backend not available, falling back to X11")
+		}
+	}
+
+	x11, err := NewX11Backend()
+	if err != nil {
+		if primary == nil {
+			return nil, err
 		}
-		log.Warn().Err(err).Msg("KWin backend not available, falling back to X11")
+		log.Warn().Err(err).Msg("XWayland not available, no fallback backend")
+		return primary, nil
+	}
+	if primary == nil {
+		log.Info().Msg("Using X11 backend")
+		return x11, nil
 	}
 
-	// Fall back to X11
-	log.Info().Msg("Using X11 backend")
-	return NewX11Backend()
+	chain := []Backend{primary, x11}
+	return NewFallbackBackend(chain, chain), nil
 }
 
-// Start begins monitoring window focus changes
-func (m *Manager) Start() error {
-	// Use backend for focus monitoring
-	err := m.backend.WatchFocus(func(info *config.WindowInfo) {
-		m.mu.Lock()
-		m.currentWindow = info
-		m.mu.Unlock()
-		m.notifyListeners(info)
-	})
-	if err != nil {
-		return fmt.Errorf("failed to start focus monitoring: %w", err)
+// usesPlatformCapture reports whether windows are captured through the
+// platform's own API instead of X11 or PipeWire
+func usesPlatformCapture() bool {
+	return runtime.GOOS == "windows"
+}
+
+// newBackend creates the named window backend
+func newBackend(name string) (Backend, error) {
+	if name == "" || name == BackendAuto {
+		return detectBackend()
+	}
+	return newRegisteredBackend(name)
+}
+
+// Start begins monitoring window focus changes. The background tasks stop
+// when ctx is canceled or Stop is called.
+func (m *Manager) Start(ctx context.Context) error {
+	context.AfterFunc(ctx, m.cancel)
+
+	backend := m.getBackend()
+	if err := m.watchFocus(backend); err != nil {
+		return err
 	}
+	m.watchWindows(backend)
 
 	// Get initial focused window
-	if info, err := m.backend.GetFocusedWindow(); err == nil {
+	if info, err := backend.GetFocusedWindow(); err == nil {
 		m.mu.Lock()
 		m.currentWindow = info
 		m.mu.Unlock()
@@ -223,18 +497,76 @@ func (m *Manager) Start() error {
 			Msg("Failed to get initial window")
 	}
 
+	m.goTask(m.superviseConnections)
+	m.goTask(m.reconcileWindows)
+
+	return nil
+}
+
+// goTask runs fn in the background until the manager's context is
+// canceled. Stop waits for it to return; once stopping, fn isn't started.
+func (m *Manager) goTask(fn func(ctx context.Context)) {
+	m.tasksMu.Lock()
+	defer m.tasksMu.Unlock()
+	if m.stopping {
+		return
+	}
+	m.tasks.Go(func() error {
+		fn(m.ctx)
+		return nil
+	})
+}
+
+// watchFocus starts focus monitoring on a backend
+func (m *Manager) watchFocus(backend Backend) error {
+	err := backend.WatchFocus(func(info *config.WindowInfo) {
+		m.mu.Lock()
+		m.currentWindow = info
+		m.mu.Unlock()
+		if !m.registry.setFocused(windowID(info)) {
+			m.registry.markStale()
+		}
+		m.notifyListeners(info)
+		m.requestFrame()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start focus monitoring: %w", err)
+	}
 	return nil
 }
 
-// Stop stops the window manager
+// Stop stops the window manager, waiting for its background tasks and the
+// stream loop to return before closing the backend and capturers
 func (m *Manager) Stop() {
-	close(m.stopChan)
-	m.backend.StopWatching()
-	m.backend.Close()
+	m.tasksMu.Lock()
+	m.stopping = true
+	m.tasksMu.Unlock()
+	m.cancel()
+	m.tasks.Wait()
+	m.StopStreaming()
+
+	m.captureScheduler.Stop()
+	if m.scaler != nil {
+		m.scaler.Close()
+	}
+	backend := m.getBackend()
+	backend.StopWatching()
+	backend.Close()
 	if m.captureRouter != nil {
 		m.captureRouter.Stop()
 	}
-	m.conn.Close()
+
+	m.x11Mu.Lock()
+	m.x11.close()
+	m.x11 = nil
+	m.x11Mu.Unlock()
+}
+
+// getBackend returns the current window backend
+func (m *Manager) getBackend() Backend {
+	m.backendMu.RLock()
+	defer m.backendMu.RUnlock()
+	return m.backend
 }
 
 // GetCurrentWindow returns the currently focused window
@@ -246,7 +578,7 @@ func (m *Manager) GetCurrentWindow() *config.WindowInfo {
 
 // ListWindows returns all visible windows via the backend
 func (m *Manager) ListWindows() ([]*config.WindowInfo, error) {
-	return m.backend.ListWindows()
+	return m.getBackend().ListWindows()
 }
 
 // IsWindowAllowlisted checks if a window is allowlisted
@@ -256,44 +588,36 @@ func (m *Manager) IsWindowAllowlisted(window *config.WindowInfo) bool {
 
 // GetWindowAllowlistSource returns why a window is allowlisted (explicit, pattern, or none)
 func (m *Manager) GetWindowAllowlistSource(window *config.WindowInfo) config.AllowlistSource {
-	if window == nil {
-		return config.AllowlistSourceNone
-	}
-
-	if m.isBrowserWindow(window.Class) {
-		return m.getBrowserAllowlistSource(window.Class)
-	}
-
-	cfg := m.configMgr.Get()
-
-	// Normalize class to lowercase for comparison
-	normalizedClass := strings.ToLower(window.Class)
+	return m.matchAllowlist(window, "").Source
+}
 
-	// Check exact match in allowlisted apps first (explicit takes priority)
-	for _, app := range cfg.AllowlistedApps {
-		if app == normalizedClass {
-			return config.AllowlistSourceExplicit
-		}
-	}
+// Titles and class of FocusStreamer's own windows: the virtual display
+// (display.Manager) and the web pages (viewer, /control, settings), which
+// browsers show as the window title
+const (
+	selfWindowClass = "focusstreamer"
+	selfTitlePrefix = "FocusStreamer"
+)
 
-	// Check pattern matching (matches against both class and title)
-	for _, pattern := range cfg.AllowlistPatterns {
-		if matched, err := regexp.MatchString(pattern, window.Class); err == nil && matched {
-			return config.AllowlistSourcePattern
-		}
-		if matched, err := regexp.MatchString(pattern, window.Title); err == nil && matched {
-			return config.AllowlistSourcePattern
-		}
+// IsSelfWindow reports whether a window belongs to FocusStreamer itself, so
+// capturing it would feed the stream back into itself. Always false when
+// capture.allow_self_capture is set.
+func (m *Manager) IsSelfWindow(window *config.WindowInfo) bool {
+	if window == nil || m.configMgr.Get().Capture.AllowSelfCapture {
+		return false
 	}
+	return window.PID == os.Getpid() ||
+		strings.EqualFold(window.Class, selfWindowClass) ||
+		strings.HasPrefix(window.Title, selfTitlePrefix)
+}
 
-	// Check title-only patterns (matches against title only)
-	for _, pattern := range cfg.AllowlistTitlePatterns {
-		if matched, err := regexp.MatchString(pattern, window.Title); err == nil && matched {
-			return config.AllowlistSourcePattern
-		}
+// canStream reports whether a window may be shown on the stream: allowlisted
+// (or the allowlist bypassed) and not one of FocusStreamer's own windows
+func (m *Manager) canStream(window *config.WindowInfo, bypass bool) bool {
+	if m.IsSelfWindow(window) {
+		return false
 	}
-
-	return config.AllowlistSourceNone
+	return bypass || m.IsWindowAllowlisted(window)
 }
 
 // UpdateBrowserContext updates the active browser URL context.
@@ -317,6 +641,34 @@ func (m *Manager) UpdateBrowserContext(windowClass, urlValue, title string) {
 	m.browserContextMu.Lock()
 	m.browserContexts[normalized] = ctx
 	m.browserContextMu.Unlock()
+
+	// Tab switches should cut over immediately, not on the next tick
+	m.requestFrame()
+}
+
+// SetBrowserContextLive marks a window class as having a connected extension
+// session (WebSocket or native messaging). While connected, the extension
+// pushes every tab change, so its context does not expire with the TTL.
+func (m *Manager) SetBrowserContextLive(windowClass string, live bool) {
+	normalized := strings.ToLower(windowClass)
+	if normalized == "" {
+		return
+	}
+
+	m.browserContextMu.Lock()
+	if live {
+		m.liveBrowserConns[normalized]++
+	} else if m.liveBrowserConns[normalized] > 1 {
+		m.liveBrowserConns[normalized]--
+	} else {
+		delete(m.liveBrowserConns, normalized)
+	}
+	m.browserContextMu.Unlock()
+}
+
+// IsURLAllowlisted reports whether a URL matches any URL allowlist rule
+func (m *Manager) IsURLAllowlisted(urlValue string) bool {
+	return m.isURLAllowlisted(urlValue)
 }
 
 // GetBrowserContext returns the current browser context for a window class.
@@ -353,38 +705,34 @@ func (m *Manager) hasBrowserContext(windowClass string) bool {
 	return ok
 }
 
-func (m *Manager) getBrowserAllowlistSource(windowClass string) config.AllowlistSource {
-	if m.configMgr.IsBrowserBlocked(windowClass) {
-		return config.AllowlistSourceNone
-	}
-
-	ctx, ok := m.GetBrowserContext(windowClass)
-	if !ok || !m.isBrowserContextFresh(ctx) {
-		return config.AllowlistSourceNone
-	}
-
-	if m.isURLAllowlisted(ctx.URL) {
-		return config.AllowlistSourceURL
-	}
-
-	return config.AllowlistSourceNone
-}
-
 func (m *Manager) isBrowserContextFresh(ctx BrowserContext) bool {
 	if ctx.UpdatedAt.IsZero() {
 		return false
 	}
+
+	m.browserContextMu.RLock()
+	live := m.liveBrowserConns[ctx.WindowClass] > 0
+	m.browserContextMu.RUnlock()
+	if live {
+		return true
+	}
 	return time.Since(ctx.UpdatedAt) <= m.browserContextTTL
 }
 
 func (m *Manager) isURLAllowlisted(urlValue string) bool {
+	_, ok := m.matchingURLRule(urlValue)
+	return ok
+}
+
+// matchingURLRule returns the first URL allowlist rule matching a URL
+func (m *Manager) matchingURLRule(urlValue string) (config.UrlRule, bool) {
 	cfg := m.configMgr.Get()
 	for _, rule := range cfg.AllowlistURLRules {
 		if matchURLRule(urlValue, rule) {
-			return true
+			return rule, true
 		}
 	}
-	return false
+	return config.UrlRule{}, false
 }
 
 func matchURLRule(urlValue string, rule config.UrlRule) bool {
@@ -466,6 +814,8 @@ func (m *Manager) Unsubscribe(ch chan *config.WindowInfo) {
 
 // notifyListeners notifies all listeners of window changes
 func (m *Manager) notifyListeners(window *config.WindowInfo) {
+	m.publishFocusChanged(window)
+
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
@@ -496,8 +846,20 @@ func (m *Manager) checkWindowState(window *config.WindowInfo) WindowState {
 
 	log := logger.WithComponent("window-state")
 
+	// Without an X connection (synthetic and non-X11 backends, or lost
+	// until the supervisor reconnects), trust the backend's list. Look the
+	// window up by ID: another window of its class doesn't keep a closed
+	// one alive.
+	x := m.x11Conn()
+	if x == nil {
+		if _, err := m.FindWindowByID(window.ID); err != nil {
+			return WindowStateInvalid
+		}
+		return WindowStateCapturable
+	}
+
 	// Check window attributes via X11 - single call for both existence and map state
-	attrs, err := xproto.GetWindowAttributes(m.conn, xproto.Window(window.ID)).Reply()
+	attrs, err := xproto.GetWindowAttributes(x.conn, xproto.Window(window.ID)).Reply()
 	if err != nil {
 		// On Wayland, X11 window attributes may fail even for valid windows
 		// This is handled via class-based recovery in the streaming loop
@@ -505,7 +867,7 @@ func (m *Manager) checkWindowState(window *config.WindowInfo) WindowState {
 	}
 
 	// Check geometry to ensure window has reasonable size
-	geom, err := xproto.GetGeometry(m.conn, xproto.Drawable(window.ID)).Reply()
+	geom, err := xproto.GetGeometry(x.conn, xproto.Drawable(window.ID)).Reply()
 	if err != nil {
 		// On Wayland, X11 geometry queries may fail even for valid windows
 		return WindowStateInvalid
@@ -536,8 +898,20 @@ func (m *Manager) checkWindowState(window *config.WindowInfo) WindowState {
 	return WindowStateValid
 }
 
-// FindWindowByClass finds the first window with the given class
+// FindWindowByClass finds the first window with the given class. It looks
+// the class up in the window registry when backend events keep that
+// current, and enumerates windows otherwise.
 func (m *Manager) FindWindowByClass(windowClass string) (*config.WindowInfo, error) {
+	if m.registry.isLive() {
+		if err := m.syncWindowRegistry(false); err != nil {
+			return nil, err
+		}
+		if window, ok := m.registry.firstOfClass(windowClass); ok {
+			return window, nil
+		}
+		return nil, fmt.Errorf("window not found: %s", windowClass)
+	}
+
 	windows, err := m.ListWindows()
 	if err != nil {
 		return nil, err
@@ -552,12 +926,137 @@ func (m *Manager) FindWindowByClass(windowClass string) (*config.WindowInfo, err
 	return nil, fmt.Errorf("window not found: %s", windowClass)
 }
 
-// CaptureWindowScreenshot captures a screenshot of a window by ID and returns PNG data
+// FindWindowByID finds a window by ID in the window registry
+func (m *Manager) FindWindowByID(windowID uint32) (*config.WindowInfo, error) {
+	if err := m.syncWindowRegistry(false); err != nil {
+		return nil, err
+	}
+	if window, ok := m.registry.get(windowID); ok {
+		return window, nil
+	}
+	return nil, fmt.Errorf("window not found: %d", windowID)
+}
+
+// CanActivateWindows reports whether the backend can focus windows on request
+func (m *Manager) CanActivateWindows() bool {
+	backend := m.getBackend()
+	_, ok := backend.(WindowActivator)
+	return ok && backend.Capabilities().Activation
+}
+
+// BackendInfo describes the window backend in use
+type BackendInfo struct {
+	Name         string          `json:"name"`
+	Capabilities Capabilities    `json:"capabilities"`
+	Chain        []BackendHealth `json:"chain,omitempty"` // Per-backend health for fallback chains
+}
+
+// GetBackendInfo returns the window backend's name and capabilities
+func (m *Manager) GetBackendInfo() BackendInfo {
+	backend := m.getBackend()
+	info := BackendInfo{
+		Name:         backend.Name(),
+		Capabilities: backend.Capabilities(),
+	}
+	if fallback, ok := backend.(*FallbackBackend); ok {
+		info.Chain = fallback.Health()
+	}
+	return info
+}
+
+// ActivateWindow focuses and raises a window through the backend. The focus
+// watcher then picks it up, so the stream switches as if the user had
+// clicked it.
+func (m *Manager) ActivateWindow(windowID uint32) error {
+	backend := m.getBackend()
+	activator, ok := backend.(WindowActivator)
+	if !ok {
+		return fmt.Errorf("%s backend does not support window activation", backend.Name())
+	}
+
+	if err := activator.ActivateWindow(windowID); err != nil {
+		return fmt.Errorf("failed to activate window: %w", err)
+	}
+
+	logger.WithComponent("window-manager").Info().
+		Uint32("window_id", windowID).
+		Str("backend", backend.Name()).
+		Msg("Window activated")
+	return nil
+}
+
+// CaptureWindowScreenshot captures a screenshot of a window by ID and returns
+// PNG data. It captures through the capture router (X11, PipeWire, or KWin
+// ScreenShot2), so native Wayland windows work, and falls back to raw X11
+// capture for windows the router can't capture.
 func (m *Manager) CaptureWindowScreenshot(windowID uint32) ([]byte, error) {
+	return m.CaptureWindowScreenshotAs(windowID, imgenc.PNG)
+}
+
+// CaptureWindowScreenshotAs is CaptureWindowScreenshot encoding in an
+// imgenc format
+func (m *Manager) CaptureWindowScreenshotAs(windowID uint32, format string) ([]byte, error) {
+	img, err := m.CaptureWindowImage(windowID)
+	if err != nil {
+		return nil, err
+	}
+	defer framepool.Put(img)
+
+	var buf bytes.Buffer
+	if err := imgenc.Encode(&buf, img, format, screenshotQuality); err != nil {
+		return nil, fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// CaptureWindowImage captures a window by ID like CaptureWindowScreenshot,
+// without encoding it. The caller owns the returned (pooled) frame.
+func (m *Manager) CaptureWindowImage(windowID uint32) (*image.RGBA, error) {
+	window, err := m.FindWindowByID(windowID)
+	if err == nil {
+		return m.captureScreenshotImage(window)
+	}
+	if x := m.x11Conn(); x != nil {
+		// Not listed by the backend, but X11 can still capture it by ID
+		return m.captureScreenshotViaX11(x, windowID)
+	}
+	return nil, err
+}
+
+// captureScreenshotImage captures a window through the capture router,
+// falling back to raw X11. The caller owns the returned (pooled) frame.
+func (m *Manager) captureScreenshotImage(window *config.WindowInfo) (*image.RGBA, error) {
+	var routerErr error
+	if m.captureRouter != nil && m.captureRouter.CanCapture(window) {
+		img, err := m.captureRouter.CaptureWindow(window)
+		if err == nil {
+			return img, nil
+		}
+		routerErr = err
+		logger.WithComponent("window").Debug().
+			Uint32("window_id", window.ID).
+			Str("class", window.Class).
+			Err(err).
+			Msg("Screenshot via capture router failed, trying X11")
+	}
+
+	x := m.x11Conn()
+	if x == nil || window.IsNativeWayland {
+		if routerErr != nil {
+			return nil, fmt.Errorf("failed to capture window: %w", routerErr)
+		}
+		return nil, fmt.Errorf("no capture backend available for window %d", window.ID)
+	}
+	return m.captureScreenshotViaX11(x, window.ID)
+}
+
+// captureScreenshotViaX11 captures a screenshot with raw X11 calls, falling
+// back to a child window when the window itself can't be captured
+func (m *Manager) captureScreenshotViaX11(x *x11Conn, windowID uint32) (*image.RGBA, error) {
 	win := xproto.Window(windowID)
 
 	// Check window attributes first
-	attrs, err := xproto.GetWindowAttributes(m.conn, win).Reply()
+	attrs, err := xproto.GetWindowAttributes(x.conn, win).Reply()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get window attributes: %w", err)
 	}
@@ -575,7 +1074,7 @@ func (m *Manager) CaptureWindowScreenshot(windowID uint32) ([]byte, error) {
 			Msg("Window not directly capturable, searching for child windows")
 
 		// Try to find a child window that can be captured
-		childWin, err := m.findCapturableChild(win)
+		childWin, err := m.findCapturableChild(x, win)
 		if err != nil {
 			return nil, fmt.Errorf("no capturable window found: %w", err)
 		}
@@ -586,7 +1085,7 @@ func (m *Manager) CaptureWindowScreenshot(windowID uint32) ([]byte, error) {
 		win = childWin
 
 		// Get attributes of child window
-		attrs, err = xproto.GetWindowAttributes(m.conn, win).Reply()
+		attrs, err = xproto.GetWindowAttributes(x.conn, win).Reply()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get child window attributes: %w", err)
 		}
@@ -598,7 +1097,7 @@ func (m *Manager) CaptureWindowScreenshot(windowID uint32) ([]byte, error) {
 	}
 
 	// Get window geometry
-	geom, err := xproto.GetGeometry(m.conn, xproto.Drawable(win)).Reply()
+	geom, err := xproto.GetGeometry(x.conn, xproto.Drawable(win)).Reply()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get window geometry: %w", err)
 	}
@@ -612,24 +1111,17 @@ func (m *Manager) CaptureWindowScreenshot(windowID uint32) ([]byte, error) {
 		Msg("Window geometry")
 
 	// Capture window image
-	img, err := m.captureWindow(win, geom)
+	img, err := m.captureWindow(x, win, geom)
 	if err != nil {
 		return nil, fmt.Errorf("failed to capture window: %w", err)
 	}
-
-	// Encode as PNG
-	var buf bytes.Buffer
-	if err := png.Encode(&buf, img); err != nil {
-		return nil, fmt.Errorf("failed to encode PNG: %w", err)
-	}
-
-	return buf.Bytes(), nil
+	return img, nil
 }
 
 // findCapturableChild recursively searches for a capturable child window
-func (m *Manager) findCapturableChild(parent xproto.Window) (xproto.Window, error) {
+func (m *Manager) findCapturableChild(x *x11Conn, parent xproto.Window) (xproto.Window, error) {
 	// Query child windows
-	tree, err := xproto.QueryTree(m.conn, parent).Reply()
+	tree, err := xproto.QueryTree(x.conn, parent).Reply()
 	if err != nil {
 		return 0, fmt.Errorf("failed to query tree: %w", err)
 	}
@@ -641,7 +1133,7 @@ func (m *Manager) findCapturableChild(parent xproto.Window) (xproto.Window, erro
 
 	// Search through children for a capturable window
 	for _, child := range tree.Children {
-		attrs, err := xproto.GetWindowAttributes(m.conn, child).Reply()
+		attrs, err := xproto.GetWindowAttributes(x.conn, child).Reply()
 		if err != nil {
 			logger.WithComponent("window").Debug().
 				Uint32("child_id", uint32(child)).
@@ -650,7 +1142,7 @@ func (m *Manager) findCapturableChild(parent xproto.Window) (xproto.Window, erro
 			continue
 		}
 
-		geom, err := xproto.GetGeometry(m.conn, xproto.Drawable(child)).Reply()
+		geom, err := xproto.GetGeometry(x.conn, xproto.Drawable(child)).Reply()
 		if err != nil {
 			logger.WithComponent("window").Debug().
 				Uint32("child_id", uint32(child)).
@@ -695,7 +1187,7 @@ func (m *Manager) findCapturableChild(parent xproto.Window) (xproto.Window, erro
 		}
 
 		// Recursively search this child's children
-		if grandchild, err := m.findCapturableChild(child); err == nil {
+		if grandchild, err := m.findCapturableChild(x, child); err == nil {
 			return grandchild, nil
 		}
 	}
@@ -704,14 +1196,14 @@ func (m *Manager) findCapturableChild(parent xproto.Window) (xproto.Window, erro
 }
 
 // captureWindow captures a window's content as an image
-func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply) (*image.RGBA, error) {
+func (m *Manager) captureWindow(x *x11Conn, win xproto.Window, geom *xproto.GetGeometryReply) (*image.RGBA, error) {
 	var drawable xproto.Drawable
 
 	// Use Composite extension if available for more reliable capture
-	if m.compositeEnabled {
+	if x.compositeEnabled {
 		// Redirect window to off-screen buffer for compositing
 		// Use CompositeRedirectAutomatic (0) for temporary redirection
-		err := composite.RedirectWindowChecked(m.conn, win, composite.RedirectAutomatic).Check()
+		err := composite.RedirectWindowChecked(x.conn, win, composite.RedirectAutomatic).Check()
 		if err != nil {
 			logger.WithComponent("window").Warn().
 				Err(err).
@@ -720,10 +1212,10 @@ func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply
 			drawable = xproto.Drawable(win)
 		} else {
 			// Ensure we unredirect when done
-			defer composite.UnredirectWindow(m.conn, win, composite.RedirectAutomatic)
+			defer composite.UnredirectWindow(x.conn, win, composite.RedirectAutomatic)
 
 			// Create a pixmap ID and associate it with the window's off-screen buffer
-			pixmap, err := xproto.NewPixmapId(m.conn)
+			pixmap, err := xproto.NewPixmapId(x.conn)
 			if err != nil {
 				logger.WithComponent("window").Warn().
 					Err(err).
@@ -732,7 +1224,7 @@ func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply
 				drawable = xproto.Drawable(win)
 			} else {
 				// Associate the pixmap with the window's off-screen buffer
-				err = composite.NameWindowPixmapChecked(m.conn, win, pixmap).Check()
+				err = composite.NameWindowPixmapChecked(x.conn, win, pixmap).Check()
 				if err != nil {
 					logger.WithComponent("window").Warn().
 						Err(err).
@@ -745,7 +1237,7 @@ func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply
 						Uint32("window_id", uint32(win)).
 						Msg("Using Composite pixmap for window capture")
 					// Free pixmap when done
-					defer xproto.FreePixmap(m.conn, pixmap)
+					defer xproto.FreePixmap(x.conn, pixmap)
 				}
 			}
 		}
@@ -755,7 +1247,7 @@ func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply
 
 	// Get window image data
 	reply, err := xproto.GetImage(
-		m.conn,
+		x.conn,
 		xproto.ImageFormatZPixmap,
 		drawable,
 		0, 0,
@@ -768,35 +1260,23 @@ func (m *Manager) captureWindow(win xproto.Window, geom *xproto.GetGeometryReply
 	}
 
 	// Convert to RGBA image
-	img := image.NewRGBA(image.Rect(0, 0, int(geom.Width), int(geom.Height)))
+	img := framepool.GetZeroed(int(geom.Width), int(geom.Height))
 
 	// Parse image data (assuming 32-bit BGRA format)
 	data := reply.Data
-	depth := int(m.screen.RootDepth)
+	depth := int(x.screen.RootDepth)
 
 	if depth == 24 || depth == 32 {
-		for y := 0; y < int(geom.Height); y++ {
-			for x := 0; x < int(geom.Width); x++ {
-				i := (y*int(geom.Width) + x) * 4
-				if i+3 < len(data) {
-					// BGRA to RGBA
-					img.Set(x, y, color.RGBA{
-						R: data[i+2],
-						G: data[i+1],
-						B: data[i],
-						A: 255,
-					})
-				}
-			}
-		}
+		pixconv.BGRAToRGBA(img, data)
 	}
 
 	return img, nil
 }
 
-// GetApplications returns a list of unique applications
+// GetApplications returns a list of unique applications, sorted by name,
+// from the window registry
 func (m *Manager) GetApplications() ([]config.Application, error) {
-	windows, err := m.ListWindows()
+	windows, err := m.registryWindows()
 	if err != nil {
 		return nil, err
 	}
@@ -838,6 +1318,12 @@ func (m *Manager) GetApplications() ([]config.Application, error) {
 				AllowlistSource: allowlistSource,
 			}
 		}
+
+		app := appMap[win.Class]
+		app.Windows++
+		if !slices.Contains(app.Desktops, win.Desktop) {
+			app.Desktops = append(app.Desktops, win.Desktop)
+		}
 	}
 
 	// Update display names with extracted names
@@ -850,8 +1336,16 @@ func (m *Manager) GetApplications() ([]config.Application, error) {
 	// Convert map to slice
 	apps := make([]config.Application, 0, len(appMap))
 	for _, app := range appMap {
+		slices.Sort(app.Desktops)
 		apps = append(apps, *app)
 	}
+	sort.Slice(apps, func(i, j int) bool {
+		a, b := strings.ToLower(apps[i].Name), strings.ToLower(apps[j].Name)
+		if a != b {
+			return a < b
+		}
+		return apps[i].ID < apps[j].ID
+	})
 
 	return apps, nil
 }
@@ -883,10 +1377,26 @@ func (m *Manager) StartStreaming(fps int) error {
 		return fmt.Errorf("no output configured")
 	}
 
-	m.streamStopChan = make(chan struct{})
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.streamCancel = cancel
+	m.streamTasks = &errgroup.Group{}
 	m.streamRunning = true
 
-	go m.streamLoop(fps)
+	// Start unthrottled, dropping any retiming left from a previous run
+	select {
+	case <-m.fpsChange:
+	default:
+	}
+	m.resetThrottle(fps)
+
+	m.streamTasks.Go(func() error {
+		m.streamLoop(ctx, fps)
+		return nil
+	})
+	m.streamTasks.Go(func() error {
+		m.runGovernor(ctx, fps)
+		return nil
+	})
 
 	logger.WithComponent("window").Info().
 		Int("fps", fps).
@@ -894,492 +1404,694 @@ func (m *Manager) StartStreaming(fps int) error {
 	return nil
 }
 
-// StopStreaming stops the continuous capture and streaming
+// StopStreaming stops the continuous capture and streaming. It returns once
+// the frame in flight, if any, has reached the output.
 func (m *Manager) StopStreaming() {
 	m.streamMu.Lock()
-	defer m.streamMu.Unlock()
-
 	if !m.streamRunning {
+		m.streamMu.Unlock()
 		return
 	}
 
-	close(m.streamStopChan)
+	m.streamCancel()
+	tasks := m.streamTasks
 	m.streamRunning = false
+	m.streamMu.Unlock()
+
+	tasks.Wait()
+
+	m.updateOnAir()
 	logger.WithComponent("window").Info().Msg("Stopped streaming")
 }
 
-// streamLoop continuously captures and streams the focused window
-func (m *Manager) streamLoop(fps int) {
-	ticker := time.NewTicker(time.Second / time.Duration(fps))
+// streamLoop continuously captures and streams the focused window, parking
+// while nothing consumes frames. The frame rate follows the load governor
+// and the shared window's capture.app_fps entry.
+func (m *Manager) streamLoop(ctx context.Context, fps int) {
+	baseFPS, governorFPS := fps, fps
+	m.setStreamFPS(fps)
+	interval := time.Second / time.Duration(fps)
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for {
-		select {
-		case <-m.streamStopChan:
+	// retime applies the frame rate for the window now shared
+	retime := func() {
+		next := m.sharedFrameRate(baseFPS, governorFPS)
+		if next == fps {
 			return
-		case <-ticker.C:
-			m.captureAndStream()
 		}
+		logger.WithComponent("stream").Debug().
+			Int("from", fps).
+			Int("to", next).
+			Msg("Stream frame rate changed")
+		fps = next
+		m.setStreamFPS(fps)
+		interval = time.Second / time.Duration(fps)
+		ticker.Reset(interval)
 	}
-}
 
-// captureState holds a consistent snapshot of state needed for frame capture
-type captureState struct {
-	forceStandby      bool
-	wasInStandby      bool
-	allowlistBypass   bool
-	lastAllowedWindow *config.WindowInfo
-	currentWindow     *config.WindowInfo
+	for {
+		if m.shouldSuspend() {
+			ticker.Stop()
+			if !m.parkStream(ctx) {
+				return
+			}
+			ticker.Reset(interval)
+			m.captureAndStream()
+			retime()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.captureAndStream()
+			retime()
+		case <-m.frameRequest:
+			// Re-evaluate immediately (e.g. the shared window's title changed)
+			m.captureAndStream()
+			retime()
+		case governorFPS = <-m.fpsChange:
+			// The load governor changed the frame rate
+			retime()
+		}
+	}
 }
 
-// getCaptureState returns a consistent snapshot of capture-related state
-func (m *Manager) getCaptureState() captureState {
-	m.streamMu.Lock()
-	state := captureState{
-		forceStandby:      m.forceStandby,
-		wasInStandby:      m.wasInStandby,
-		allowlistBypass:   m.allowlistBypass,
-		lastAllowedWindow: m.lastAllowedWindow,
+// sharedFrameRate returns the FPS for the window now shared: its
+// capture.app_fps entry, scaled down as far as the load governor has
+// throttled baseFPS to governorFPS, or governorFPS without one
+func (m *Manager) sharedFrameRate(baseFPS, governorFPS int) int {
+	shared := m.GetSharedWindow()
+	if shared == nil {
+		return governorFPS
+	}
+	fps, ok := m.configMgr.Get().AppStreamFPS(shared.Class)
+	if !ok {
+		return governorFPS
 	}
+	if governorFPS < baseFPS {
+		fps = max(1, fps*governorFPS/baseFPS)
+	}
+	return fps
+}
+
+// setStreamFPS records the stream loop's frame rate
+func (m *Manager) setStreamFPS(fps int) {
+	m.streamMu.Lock()
+	m.streamFPS = fps
 	m.streamMu.Unlock()
+}
 
-	m.mu.RLock()
-	state.currentWindow = m.currentWindow
-	m.mu.RUnlock()
+// shouldSuspend reports whether capture may stop: suspension is enabled and
+// neither a stream viewer nor another frame consumer is connected
+func (m *Manager) shouldSuspend() bool {
+	if !m.configMgr.Get().Capture.SuspendWithoutViewers {
+		return false
+	}
+	m.streamMu.Lock()
+	defer m.streamMu.Unlock()
+	return m.clientCount == 0 && m.frameDemand == 0
+}
 
-	return state
+// parkStream blocks the stream loop until frames are wanted again. It
+// returns false if streaming stops meanwhile.
+func (m *Manager) parkStream(ctx context.Context) bool {
+	log := logger.WithComponent("stream")
+	m.setSuspended(true)
+	log.Info().Msg("No viewers, capture suspended")
+
+	for m.shouldSuspend() {
+		select {
+		case <-ctx.Done():
+			m.setSuspended(false)
+			return false
+		case <-m.frameRequest:
+		}
+	}
+
+	m.setSuspended(false)
+	log.Info().Msg("Viewer connected, capture resumed")
+	return true
 }
 
-// updateCaptureState updates the capture state after processing
-func (m *Manager) updateCaptureState(wasInStandby bool, lastAllowed *config.WindowInfo) {
+// setSuspended records whether the stream loop is parked
+func (m *Manager) setSuspended(suspended bool) {
 	m.streamMu.Lock()
-	m.wasInStandby = wasInStandby
-	if lastAllowed != nil {
-		m.lastAllowedWindow = lastAllowed
+	m.suspended = suspended
+	m.streamMu.Unlock()
+
+	if !suspended {
+		// The pause isn't a stall
+		m.healthMu.Lock()
+		m.lastFrameTime = time.Time{}
+		m.healthMu.Unlock()
 	}
+}
+
+// AcquireFrames keeps frames coming while no stream viewer is connected,
+// for consumers of stream frames such as the minimap thumbnail. Call the
+// returned function to release it.
+func (m *Manager) AcquireFrames() func() {
+	m.streamMu.Lock()
+	m.frameDemand++
 	m.streamMu.Unlock()
+	m.requestFrame()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.streamMu.Lock()
+			m.frameDemand--
+			m.streamMu.Unlock()
+		})
+	}
+}
+
+// requestFrame asks the stream loop to capture a frame now instead of
+// waiting for the next tick
+func (m *Manager) requestFrame() {
+	select {
+	case m.frameRequest <- struct{}{}:
+	default:
+	}
+}
+
+// updateTitleWatch subscribes to title changes of the window being shared so
+// title-based allowlist rules are re-evaluated as soon as the title changes,
+// not only on focus changes
+func (m *Manager) updateTitleWatch(window *config.WindowInfo) {
+	var id uint32
+	if window != nil {
+		id = window.ID
+	}
+	backend := m.getBackend()
+	if id == m.titleWatchID && backend == m.titleWatchBackend {
+		return
+	}
+	m.titleWatchID = id
+	m.titleWatchBackend = backend
+
+	titleWatcher, ok := backend.(TitleWatcher)
+	if !ok || !backend.Capabilities().TitleEvents {
+		return
+	}
+	if err := titleWatcher.WatchTitle(id, m.onTitleChanged); err != nil {
+		logger.WithComponent("stream").Debug().
+			Err(err).
+			Uint32("window_id", id).
+			Msg("Title watching unavailable")
+	}
+}
+
+// onTitleChanged updates the stored window info for a retitled window and
+// triggers an immediate frame so allowlisting is re-evaluated
+func (m *Manager) onTitleChanged(windowID uint32, title string) {
+	var current *config.WindowInfo
+	m.mu.Lock()
+	if m.currentWindow != nil && m.currentWindow.ID == windowID && m.currentWindow.Title != title {
+		updated := *m.currentWindow
+		updated.Title = title
+		m.currentWindow = &updated
+		current = &updated
+	}
+	m.mu.Unlock()
+
+	m.state.update(func(st *streamState) {
+		if st.lastAllowedWindow != nil && st.lastAllowedWindow.ID == windowID && st.lastAllowedWindow.Title != title {
+			updated := *st.lastAllowedWindow
+			updated.Title = title
+			st.lastAllowedWindow = &updated
+		}
+	})
+
+	logger.WithComponent("stream").Debug().
+		Uint32("window_id", windowID).
+		Str("title", title).
+		Msg("Shared window title changed")
+
+	if current != nil {
+		m.notifyListeners(current)
+	}
+	m.requestFrame()
+}
+
+// updateGeometryWatch subscribes to moves and resizes of the window being
+// shared so the next frame crops to its new geometry, not only once the
+// focus poll notices
+func (m *Manager) updateGeometryWatch(window *config.WindowInfo) {
+	var id uint32
+	if window != nil {
+		id = window.ID
+	}
+	backend := m.getBackend()
+	if id == m.geometryWatchID && backend == m.geometryWatchBackend {
+		return
+	}
+	m.geometryWatchID = id
+	m.geometryWatchBackend = backend
+
+	geometryWatcher, ok := backend.(GeometryWatcher)
+	if !ok || !backend.Capabilities().GeometryEvents {
+		return
+	}
+	if err := geometryWatcher.WatchGeometry(id, m.onGeometryChanged); err != nil {
+		logger.WithComponent("stream").Debug().
+			Err(err).
+			Uint32("window_id", id).
+			Msg("Geometry watching unavailable")
+	}
+}
+
+// onGeometryChanged updates the stored geometry of a moved or resized window.
+// No frame is requested: a drag reports many changes, and the next tick
+// picks up the latest one.
+func (m *Manager) onGeometryChanged(windowID uint32, geometry config.Geometry) {
+	m.mu.Lock()
+	if m.currentWindow != nil && m.currentWindow.ID == windowID && m.currentWindow.Geometry != geometry {
+		updated := *m.currentWindow
+		updated.Geometry = geometry
+		m.currentWindow = &updated
+	}
+	m.mu.Unlock()
+
+	m.state.update(func(st *streamState) {
+		if st.lastAllowedWindow != nil && st.lastAllowedWindow.ID == windowID && st.lastAllowedWindow.Geometry != geometry {
+			updated := *st.lastAllowedWindow
+			updated.Geometry = geometry
+			st.lastAllowedWindow = &updated
+		}
+	})
+}
+
+// updateCloseWatch subscribes to the closing of the window being shared so
+// the stream hands off as soon as it closes, on backends that don't report
+// window events (see watchWindows)
+func (m *Manager) updateCloseWatch(window *config.WindowInfo) {
+	var id uint32
+	if window != nil {
+		id = window.ID
+	}
+	backend := m.getBackend()
+	if id == m.closeWatchID && backend == m.closeWatchBackend {
+		return
+	}
+	m.closeWatchID = id
+	m.closeWatchBackend = backend
+
+	closeWatcher, ok := backend.(CloseWatcher)
+	if !ok || !backend.Capabilities().CloseEvents {
+		return
+	}
+	if err := closeWatcher.WatchClose(id, m.onWindowClosed); err != nil {
+		logger.WithComponent("stream").Debug().
+			Err(err).
+			Uint32("window_id", id).
+			Msg("Close watching unavailable")
+	}
+}
+
+// onWindowClosed forgets a closed window as the focused and last allowed
+// window and, if it was being shared, triggers an immediate frame so the
+// stream switches to the next allowed window or the placeholder instead of
+// showing stale frames until capture fails
+func (m *Manager) onWindowClosed(windowID uint32) {
+	m.mu.Lock()
+	if m.currentWindow != nil && m.currentWindow.ID == windowID {
+		m.currentWindow = nil // The focus watch reports the next one
+	}
+	m.mu.Unlock()
+
+	if m.captureRouter != nil {
+		m.captureRouter.SetWindowRoute(windowID, "auto")
+	}
+
+	_, st := m.state.update(func(st *streamState) { st.forgetWindow(windowID) })
+	shared := st.sharedWindow != nil && st.sharedWindow.ID == windowID
+
+	if !shared {
+		return
+	}
+	logger.WithComponent("stream").Info().
+		Uint32("window_id", windowID).
+		Msg("Shared window closed, handing off")
+	m.requestFrame()
 }
 
 // clearLastAllowedWindow clears the last allowed window
 func (m *Manager) clearLastAllowedWindow() {
-	m.streamMu.Lock()
-	m.lastAllowedWindow = nil
-	m.streamMu.Unlock()
+	m.state.update(func(st *streamState) { st.lastAllowedWindow = nil })
 }
 
-// captureAndStream captures the current focused window and sends it to the output
+// captureAndStream runs one frame through the stream pipeline (see
+// newStreamPipeline)
 func (m *Manager) captureAndStream() {
-	log := logger.WithComponent("stream")
+	m.checkFrameInterval()
+
+	f := &Frame{state: m.state.snapshot()}
+	m.pipeline.Run(f)
 
-	// Track frame timing for health monitoring
+	// Update wasInStandby for next frame's transition detection
+	m.setStandbyState(f.Standby, f.StandbyReason, f.Window)
+}
+
+// checkFrameInterval records the frame time for health monitoring and warns
+// when frames are arriving much slower than the configured FPS
+func (m *Manager) checkFrameInterval() {
 	frameStart := time.Now()
 	m.healthMu.Lock()
 	lastFrame := m.lastFrameTime
 	m.lastFrameTime = frameStart
 	m.healthMu.Unlock()
 
+	if lastFrame.IsZero() {
+		return
+	}
+
 	// Warn if frame interval is too long (>3x expected interval)
 	// Rate-limit to once per 10 seconds to avoid log spam
-	if !lastFrame.IsZero() {
-		interval := frameStart.Sub(lastFrame)
-		// Calculate threshold based on actual FPS setting
-		cfg := m.configMgr.Get()
-		fps := cfg.VirtualDisplay.FPS
-		if fps <= 0 {
-			fps = 10 // default
+	interval := frameStart.Sub(lastFrame)
+	// Calculate threshold based on the stream loop's frame rate
+	m.streamMu.Lock()
+	fps := m.streamFPS
+	m.streamMu.Unlock()
+	if fps <= 0 {
+		fps = 10 // default
+	}
+	expectedInterval := time.Second / time.Duration(fps)
+	threshold := expectedInterval * 3 // 3x expected = real stall
+
+	if interval > threshold {
+		m.healthMu.Lock()
+		lastWarn := m.lastFrameIntervalWarn
+		if frameStart.Sub(lastWarn) > 10*time.Second {
+			m.lastFrameIntervalWarn = frameStart
+			m.healthMu.Unlock()
+			logger.WithComponent("stream").Warn().
+				Dur("interval", interval).
+				Dur("threshold", threshold).
+				Int("fps", fps).
+				Msg("Frame interval exceeds threshold - possible stall")
+		} else {
+			m.healthMu.Unlock()
 		}
-		expectedInterval := time.Second / time.Duration(fps)
-		threshold := expectedInterval * 3 // 3x expected = real stall
-
-		if interval > threshold {
-			m.healthMu.Lock()
-			lastWarn := m.lastFrameIntervalWarn
-			if frameStart.Sub(lastWarn) > 10*time.Second {
-				m.lastFrameIntervalWarn = frameStart
-				m.healthMu.Unlock()
-				log.Warn().
-					Dur("interval", interval).
-					Dur("threshold", threshold).
-					Int("fps", fps).
-					Msg("Frame interval exceeds threshold - possible stall")
-			} else {
-				m.healthMu.Unlock()
-			}
+	}
+}
+
+// setStandbyState records whether the last frame was the placeholder and
+// why, or which window it showed otherwise, and re-evaluates the on-air state
+func (m *Manager) setStandbyState(showingStandby bool, reason StandbyReason, shared *config.WindowInfo) {
+	before, _ := m.state.update(func(st *streamState) {
+		st.wasInStandby = showingStandby
+		st.standbyReason = reason
+		st.sharedWindow = shared
+	})
+	changed := windowID(shared) != windowID(before.sharedWindow)
+
+	m.streamMu.Lock()
+	callback := m.sharedWindowCallback
+	var newClassCallback func(window *config.WindowInfo)
+	if changed && shared != nil && !m.sharedClasses[shared.Class] {
+		if m.sharedClasses == nil {
+			m.sharedClasses = make(map[string]bool)
 		}
+		m.sharedClasses[shared.Class] = true
+		newClassCallback = m.newClassCallback
 	}
+	m.streamMu.Unlock()
+
+	if changed && callback != nil {
+		callback(shared)
+	}
+	if newClassCallback != nil {
+		newClassCallback(shared)
+	}
+	m.publishStandbyChanged(before, showingStandby, reason, shared)
+	m.timeline.record(time.Now(), showingStandby, reason, shared)
+	m.updateOnAir()
+}
+
+// windowID returns the window's ID, or 0 for no window
+func windowID(window *config.WindowInfo) uint32 {
+	if window == nil {
+		return 0
+	}
+	return window.ID
+}
+
+// GetSharedWindow returns the window shown on the stream, or nil while the
+// placeholder is shown
+func (m *Manager) GetSharedWindow() *config.WindowInfo {
+	return m.state.snapshot().sharedWindow
+}
 
-	// Track whether this frame shows standby/placeholder
-	showingStandby := false
+// SetOnSharedWindowCallback sets a callback invoked when the window shown on
+// the stream changes, with nil when the placeholder replaces it
+func (m *Manager) SetOnSharedWindowCallback(callback func(window *config.WindowInfo)) {
+	m.streamMu.Lock()
+	m.sharedWindowCallback = callback
+	m.streamMu.Unlock()
+}
 
-	// Check if force standby is enabled
+// SetOnNewClassSharedCallback sets a callback invoked when a window is
+// shown on the stream whose class hasn't been shown since the manager
+// started. It is called on the stream loop and mustn't block.
+func (m *Manager) SetOnNewClassSharedCallback(callback func(window *config.WindowInfo)) {
 	m.streamMu.Lock()
-	forceStandby := m.forceStandby
-	wasInStandby := m.wasInStandby
+	m.newClassCallback = callback
 	m.streamMu.Unlock()
+}
 
-	if forceStandby {
-		showingStandby = true
-		// Detect transition TO standby for rotation
-		if !wasInStandby {
-			m.rotatePlaceholder()
-		}
-		if m.output != nil {
-			cfg := m.configMgr.Get()
-			placeholder := m.createPlaceholderFrame(cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height)
-			m.output.WriteFrame(placeholder)
-		}
-		// Update wasInStandby before returning
-		m.streamMu.Lock()
-		m.wasInStandby = showingStandby
-		m.streamMu.Unlock()
-		return
+// SetOnStateChangeCallback sets a callback invoked when standby, on-air, or
+// the active profile changes
+func (m *Manager) SetOnStateChangeCallback(callback func()) {
+	m.streamMu.Lock()
+	m.stateCallback = callback
+	m.streamMu.Unlock()
+}
+
+// notifyStateChange invokes the state change callback, if set
+func (m *Manager) notifyStateChange() {
+	m.streamMu.Lock()
+	callback := m.stateCallback
+	m.streamMu.Unlock()
+
+	if callback != nil {
+		callback()
 	}
+}
 
-	// Get current window
-	m.mu.RLock()
-	currentWin := m.currentWindow
-	m.mu.RUnlock()
-
-	// Get current desktop once for all checks
-	currentDesktop := m.backend.GetCurrentDesktop()
-
-	// Check if window is on current desktop
-	// Desktop -1 means window is on all desktops (sticky)
-	if currentWin != nil {
-		if currentWin.Desktop != -1 && currentWin.Desktop != currentDesktop {
-			log.Debug().
-				Int("window_desktop", currentWin.Desktop).
-				Int("current_desktop", currentDesktop).
-				Str("window_class", currentWin.Class).
-				Msg("Window not on current desktop, treating as unfocused")
-			currentWin = nil
-		}
+// SetClientCount records the number of connected stream viewers
+func (m *Manager) SetClientCount(count int) {
+	m.streamMu.Lock()
+	m.clientCount = count
+	m.streamMu.Unlock()
+	m.updateOnAir()
+
+	// Wake a parked stream loop
+	if count > 0 {
+		m.requestFrame()
 	}
+}
 
-	var windowToCapture *config.WindowInfo
-	var usePlaceholder bool
+// SetOnAirCallback sets a callback invoked when the on-air state changes
+func (m *Manager) SetOnAirCallback(callback func(onAir bool)) {
+	m.streamMu.Lock()
+	m.onAirCallback = callback
+	m.streamMu.Unlock()
+}
 
-	// Check allowlist bypass mode
+// IsOnAir reports whether viewers are connected and seeing a real window
+// (streaming, at least one client, not showing the standby placeholder)
+func (m *Manager) IsOnAir() bool {
 	m.streamMu.Lock()
-	bypassEnabled := m.allowlistBypass
-	lastAllowed := m.lastAllowedWindow
+	defer m.streamMu.Unlock()
+	return m.onAir
+}
+
+// updateOnAir recomputes the on-air state and notifies on change
+func (m *Manager) updateOnAir() {
+	inStandby := m.state.snapshot().wasInStandby
+
+	m.streamMu.Lock()
+	onAir := m.streamRunning && m.clientCount > 0 && !inStandby
+	changed := onAir != m.onAir
+	m.onAir = onAir
+	callback := m.onAirCallback
 	m.streamMu.Unlock()
 
-	if currentWin == nil {
-		// No window focused (or not on current desktop) - try to use last allowed window
-		if lastAllowed != nil {
-			// Check window state in a single X11 call
-			state := m.checkWindowState(lastAllowed)
-
-			if state == WindowStateInvalid {
-				// Window ID might be stale - try to find window by class before giving up
-				refreshedWin, err := m.FindWindowByClass(lastAllowed.Class)
-				refreshedOnCurrentDesktop := refreshedWin != nil && (refreshedWin.Desktop == -1 || refreshedWin.Desktop == currentDesktop)
-				if err == nil && refreshedOnCurrentDesktop && (bypassEnabled || m.IsWindowAllowlisted(refreshedWin)) {
-					// Found the window by class on current desktop - try to capture it
-					// On Wayland, X11 state checks may fail but capture can still work via PipeWire
-					// Only log when window ID actually changes to avoid spam
-					if refreshedWin.ID != lastAllowed.ID {
-						log.Debug().
-							Uint32("old_window_id", lastAllowed.ID).
-							Uint32("new_window_id", refreshedWin.ID).
-							Str("window_class", lastAllowed.Class).
-							Msg("Recovered window by class with new ID")
-					}
-					m.streamMu.Lock()
-					m.lastAllowedWindow = refreshedWin
-					m.streamMu.Unlock()
-					windowToCapture = refreshedWin
-				} else {
-					if err == nil && !refreshedOnCurrentDesktop {
-						log.Debug().
-							Uint32("window_id", refreshedWin.ID).
-							Str("window_class", refreshedWin.Class).
-							Int("window_desktop", refreshedWin.Desktop).
-							Int("current_desktop", currentDesktop).
-							Msg("Recovered window by class but not on current desktop")
-					} else {
-						log.Debug().
-							Uint32("window_id", lastAllowed.ID).
-							Str("window_class", lastAllowed.Class).
-							Msg("Last allowed window no longer valid (closed)")
-					}
-					m.streamMu.Lock()
-					m.lastAllowedWindow = nil
-					m.streamMu.Unlock()
-					usePlaceholder = true
-				}
-			} else {
-				lastAllowedOnCurrentDesktop := lastAllowed.Desktop == -1 || lastAllowed.Desktop == currentDesktop
-
-				if lastAllowedOnCurrentDesktop && (bypassEnabled || m.IsWindowAllowlisted(lastAllowed)) {
-					if state == WindowStateCapturable {
-						windowToCapture = lastAllowed
-					} else {
-						// Window exists but not capturable (obscured/minimized) - show placeholder
-						// but keep lastAllowedWindow for when it becomes capturable again
-						usePlaceholder = true
-					}
-				} else {
-					// Last allowed window no longer valid for capture (wrong desktop or not allowlisted)
-					if !lastAllowedOnCurrentDesktop {
-						log.Debug().
-							Int("window_desktop", lastAllowed.Desktop).
-							Int("current_desktop", currentDesktop).
-							Str("window_class", lastAllowed.Class).
-							Msg("Last allowed window not on current desktop")
-					}
-					m.streamMu.Lock()
-					m.lastAllowedWindow = nil
-					m.streamMu.Unlock()
-					usePlaceholder = true
-				}
-			}
-		} else {
-			usePlaceholder = true
-		}
-	} else {
-		// Check if current window is allowlisted (or bypass is enabled)
-		isAllowlisted := bypassEnabled || m.IsWindowAllowlisted(currentWin)
-		if isAllowlisted {
-			// Current window is allowlisted - use it and save as last allowed
-			windowToCapture = currentWin
-			m.streamMu.Lock()
-			m.lastAllowedWindow = currentWin
-			m.streamMu.Unlock()
-		} else {
-			// Current window is not allowlisted - use last allowed window if available
-			if lastAllowed != nil {
-				// Check if same window (e.g., browser tab changed to non-matching title)
-				if lastAllowed.ID == currentWin.ID {
-					log.Debug().
-						Uint32("current_id", currentWin.ID).
-						Str("current_class", currentWin.Class).
-						Msg("Current window same as lastAllowed but no longer allowlisted")
-					m.streamMu.Lock()
-					m.lastAllowedWindow = nil
-					m.streamMu.Unlock()
-					usePlaceholder = true
-				} else {
-					// Check window state in a single X11 call
-					state := m.checkWindowState(lastAllowed)
-
-					if state == WindowStateInvalid {
-						// Window ID might be stale - try to find window by class before giving up
-						refreshedWin, err := m.FindWindowByClass(lastAllowed.Class)
-						refreshedOnCurrentDesktop := refreshedWin != nil && (refreshedWin.Desktop == -1 || refreshedWin.Desktop == currentDesktop)
-						if err == nil && refreshedOnCurrentDesktop && (bypassEnabled || m.IsWindowAllowlisted(refreshedWin)) {
-							// Found the window by class on current desktop - try to capture it
-							// On Wayland, X11 state checks may fail but capture can still work via PipeWire
-							// Only log when window ID actually changes to avoid spam
-							if refreshedWin.ID != lastAllowed.ID {
-								log.Debug().
-									Uint32("old_window_id", lastAllowed.ID).
-									Uint32("new_window_id", refreshedWin.ID).
-									Str("window_class", lastAllowed.Class).
-									Msg("Recovered window by class with new ID")
-							}
-							m.streamMu.Lock()
-							m.lastAllowedWindow = refreshedWin
-							m.streamMu.Unlock()
-							windowToCapture = refreshedWin
-						} else {
-							if err == nil && !refreshedOnCurrentDesktop {
-								log.Debug().
-									Uint32("window_id", refreshedWin.ID).
-									Str("window_class", refreshedWin.Class).
-									Int("window_desktop", refreshedWin.Desktop).
-									Int("current_desktop", currentDesktop).
-									Msg("Recovered window by class but not on current desktop")
-							} else {
-								log.Debug().
-									Uint32("window_id", lastAllowed.ID).
-									Str("window_class", lastAllowed.Class).
-									Msg("Last allowed window no longer valid (closed)")
-							}
-							m.streamMu.Lock()
-							m.lastAllowedWindow = nil
-							m.streamMu.Unlock()
-							usePlaceholder = true
-						}
-					} else {
-						lastAllowedOnCurrentDesktop := lastAllowed.Desktop == -1 || lastAllowed.Desktop == currentDesktop
-						lastAllowedStillAllowlisted := bypassEnabled || m.IsWindowAllowlisted(lastAllowed)
-
-						if lastAllowedOnCurrentDesktop && lastAllowedStillAllowlisted {
-							if state == WindowStateCapturable {
-								windowToCapture = lastAllowed
-							} else {
-								// Window exists but not capturable - show placeholder but keep reference
-								log.Debug().
-									Uint32("window_id", lastAllowed.ID).
-									Str("window_class", lastAllowed.Class).
-									Int("state", int(state)).
-									Msg("Last allowed window not capturable (obscured/minimized)")
-								usePlaceholder = true
-							}
-						} else {
-							log.Debug().
-								Uint32("window_id", lastAllowed.ID).
-								Str("window_class", lastAllowed.Class).
-								Bool("on_current_desktop", lastAllowedOnCurrentDesktop).
-								Bool("still_allowlisted", lastAllowedStillAllowlisted).
-								Int("window_desktop", lastAllowed.Desktop).
-								Int("current_desktop", currentDesktop).
-								Msg("Last allowed window no longer valid for fallback")
-							m.streamMu.Lock()
-							m.lastAllowedWindow = nil
-							m.streamMu.Unlock()
-							usePlaceholder = true
-						}
-					}
-				}
-			} else {
-				// No allowlisted window yet - show placeholder
-				usePlaceholder = true
-			}
-		}
+	if changed && callback != nil {
+		callback(onAir)
+	}
+	if changed {
+		m.notifyStateChange()
 	}
+}
 
-	var img *image.RGBA
+// applyColorManagement converts a captured frame from the configured
+// monitor color space to sRGB in place. The converter is cached and only
+// rebuilt when the color management config changes.
+func (m *Manager) applyColorManagement(img *image.RGBA) {
+	cm := m.configMgr.Get().Capture.ColorManagement
+	if !cm.Enabled {
+		return
+	}
 
-	if usePlaceholder {
-		showingStandby = true
-		// Detect transition TO standby for rotation
-		if !wasInStandby {
-			m.rotatePlaceholder()
+	key := cm.SourceColorSpace + "|" + cm.ICCProfile
+	if key != m.colorConverterKey {
+		converter, err := capture.NewColorConverter(cm.SourceColorSpace, cm.ICCProfile)
+		if err != nil {
+			logger.WithComponent("stream").Warn().
+				Err(err).
+				Str("source_color_space", cm.SourceColorSpace).
+				Str("icc_profile", cm.ICCProfile).
+				Msg("Failed to build color converter, disabling conversion")
 		}
-		// Create and send placeholder frame
-		cfg := m.configMgr.Get()
-		img = m.createPlaceholderFrame(cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height)
-	} else {
-		var err error
+		m.colorConverter = converter
+		m.colorConverterKey = key
+	}
 
-		// Try capture router first (supports both X11 and PipeWire)
-		if m.captureRouter != nil && m.captureRouter.CanCapture(windowToCapture) {
-			img, err = m.captureRouter.CaptureWindow(windowToCapture)
-			if err != nil {
-				log.Debug().
-					Uint32("id", windowToCapture.ID).
-					Str("class", windowToCapture.Class).
-					Bool("native_wayland", windowToCapture.IsNativeWayland).
-					Err(err).
-					Msg("Capture router failed, trying fallback")
-			}
-		}
+	m.colorConverter.Apply(img)
+}
 
-		// Fallback to direct X11 capture if router failed or unavailable
-		if img == nil && !windowToCapture.IsNativeWayland {
-			geom, err := xproto.GetGeometry(m.conn, xproto.Drawable(windowToCapture.ID)).Reply()
-			if err != nil {
-				log.Debug().
-					Uint32("id", windowToCapture.ID).
-					Str("class", windowToCapture.Class).
-					Err(err).
-					Msg("Failed to get window geometry")
-			} else {
-				img, err = m.captureWindow(xproto.Window(windowToCapture.ID), geom)
-				if err != nil {
-					log.Debug().
-						Uint32("id", windowToCapture.ID).
-						Str("class", windowToCapture.Class).
-						Err(err).
-						Msg("Direct X11 capture failed")
-				}
-			}
-		}
+// applyStreamFilters applies the configured brightness, contrast, gamma and
+// saturation adjustments in place. The filter is cached and only rebuilt
+// when stream_filters changes.
+func (m *Manager) applyStreamFilters(img *image.RGBA) {
+	filters := m.configMgr.Get().StreamFilters
+	if filters != m.imageFilterKey {
+		m.imageFilter = capture.NewImageFilter(filters)
+		m.imageFilterKey = filters
+	}
+	m.imageFilter.Apply(img)
+}
 
-		// If capture failed, clear lastAllowedWindow and send placeholder
-		if img == nil {
-			// Track consecutive failures for health monitoring
-			m.healthMu.Lock()
-			m.consecutiveFailures++
-			failures := m.consecutiveFailures
-			m.healthMu.Unlock()
+// checkCaptureWatchdog feeds a capture result (nil on failure) to the
+// watchdog and restarts the capturers in the background when it asks to.
+// It reports whether capture is stalled.
+func (m *Manager) checkCaptureWatchdog(windowID uint32, img *image.RGBA) bool {
+	if m.watchdog == nil {
+		return false
+	}
 
-			// Log warning at thresholds
-			if failures == 5 || failures%50 == 0 {
-				log.Warn().
-					Int("consecutive_failures", failures).
-					Str("window_class", windowToCapture.Class).
-					Uint32("window_id", windowToCapture.ID).
-					Msg("Consecutive capture failures - window may be closed or inaccessible")
+	stalled, restart := m.watchdog.Observe(windowID, img, time.Now())
+	if restart {
+		m.publishCaptureError(events.CaptureErrorSource_CAPTURE_ERROR_SOURCE_STALLED,
+			"Capture stalled, restarting", &config.WindowInfo{ID: windowID})
+		go func() {
+			defer m.watchdog.RestartDone()
+			if err := m.captureRouter.Restart(); err != nil {
+				logger.WithComponent("capture-watchdog").Error().Err(err).Msg("Capture restart failed")
+				m.publishCaptureError(events.CaptureErrorSource_CAPTURE_ERROR_SOURCE_STALLED,
+					fmt.Sprintf("Capture restart failed: %v", err), nil)
 			}
+		}()
+	}
+	return stalled
+}
 
-			showingStandby = true
-			// Detect transition TO standby for rotation
-			if !wasInStandby {
-				m.rotatePlaceholder()
-			}
-			m.streamMu.Lock()
-			m.lastAllowedWindow = nil
-			m.streamMu.Unlock()
+// drawStallBanner draws a "capture stalled" banner across the top of a frame
+// so viewers know the picture is frozen rather than idle
+func drawStallBanner(img *image.RGBA) {
+	const text = "Capture stalled - recovering..."
+	bounds := img.Bounds()
 
-			cfg := m.configMgr.Get()
-			img = m.createPlaceholderFrame(cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height)
-		} else {
-			// Reset consecutive failures on successful capture
-			m.healthMu.Lock()
-			m.consecutiveFailures = 0
-			m.healthMu.Unlock()
-		}
+	bandHeight := 13 + 2*standbyStatsPadding
+	band := image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Max.X, bounds.Min.Y+bandHeight)
+	draw.Draw(img, band, &image.Uniform{color.RGBA{180, 40, 40, 255}}, image.Point{}, draw.Src)
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.RGBA{255, 255, 255, 255}),
+		Face: basicfont.Face7x13,
 	}
+	textWidth := d.MeasureString(text)
+	d.Dot = fixed.Point26_6{
+		X: fixed.I(bounds.Min.X) + (fixed.I(bounds.Dx())-textWidth)/2,
+		Y: fixed.I(bounds.Min.Y + bandHeight - standbyStatsPadding - 3),
+	}
+	d.DrawString(text)
+}
+
+const (
+	// standbyStatsPadding is the padding around the viewer stats line on standby frames
+	standbyStatsPadding = 6
 
-	// Store unzoomed frame for minimap thumbnail
-	m.unzoomedFrameMu.Lock()
-	m.lastUnzoomedFrame = img
-	m.unzoomedFrameMu.Unlock()
+	// defaultStallTimeout applies when capture.watchdog.stall_seconds is unset
+	defaultStallTimeout = 30 * time.Second
+)
 
-	// Apply zoom/pan transformation if active
-	img = m.applyZoom(img)
+// standbyFrame returns a copy of the placeholder (so overlays don't draw onto
+// the cache) with the viewer count and stream uptime along the bottom, so a
+// paused streamer can see whether anyone is still watching, and the standby
+// reason above them if enabled
+func (m *Manager) standbyFrame(width, height int, reason StandbyReason) *image.RGBA {
+	img := framepool.Clone(m.createPlaceholderFrame(width, height))
 
-	// Apply overlay rendering if overlay manager is set
-	if m.overlayMgr != nil {
-		if err := m.overlayMgr.Render(img); err != nil {
-			logger.WithComponent("stream").Error().
-				Err(err).
-				Msg("Failed to render overlay")
-		}
+	cfg := m.configMgr.Get()
+	if cfg.Placeholder.ShowReason {
+		drawStandbyCaption(img, reason.Caption(), themeColor(cfg.Placeholder.TextColor, color.RGBA{150, 150, 160, 255}))
 	}
 
-	// Send to output at native resolution - browser will scale to fit viewport
-	if err := m.output.WriteFrame(img); err != nil {
-		logger.WithComponent("stream").Error().
-			Err(err).
-			Msg("Failed to write frame to output")
+	if cfg.Overlay.HideStandbyStats {
+		return img
+	}
+	reporter, ok := m.output.(output.StatsReporter)
+	if !ok {
+		return img
 	}
 
-	// Update wasInStandby for next frame's transition detection
-	m.streamMu.Lock()
-	m.wasInStandby = showingStandby
-	m.streamMu.Unlock()
+	text := reporter.Stats().String()
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.RGBA{150, 150, 160, 255}),
+		Face: basicfont.Face7x13,
+	}
+	textWidth := d.MeasureString(text)
+
+	// Dark band behind the text keeps it readable on custom placeholder images
+	bandHeight := 13 + 2*standbyStatsPadding
+	band := image.Rect(0, height-bandHeight, width, height)
+	draw.Draw(img, band, &image.Uniform{color.RGBA{20, 20, 30, 255}}, image.Point{}, draw.Src)
+
+	d.Dot = fixed.Point26_6{
+		X: (fixed.I(width) - textWidth) / 2,
+		Y: fixed.I(height - standbyStatsPadding - 3), // Baseline sits above the font's descent
+	}
+	d.DrawString(text)
+	return img
 }
 
-// createPlaceholderFrame creates a placeholder frame with a large centered target symbol
-// when no allowlisted window has been focused yet
+// createPlaceholderFrame creates a placeholder frame: the current custom
+// placeholder image, or the default one themed by the placeholder config (a
+// large centered target symbol over the message)
 func (m *Manager) createPlaceholderFrame(width, height int) *image.RGBA {
 	// Get the current placeholder path based on index
 	paths := m.configMgr.GetPlaceholderImagePaths()
-	m.streamMu.Lock()
-	idx := m.currentPlaceholderIdx
-	m.streamMu.Unlock()
+	st := m.state.snapshot()
+	idx := st.placeholderIdx
 
 	var currentPath string
 	if idx >= 0 && idx < len(paths) {
 		currentPath = paths[idx]
 	}
 
+	// The default placeholder is redrawn when its theme changes, and each
+	// minute while it counts down to the next stream
+	theme := m.configMgr.Get().Placeholder
+	var nextLine string
+	if currentPath == "" {
+		nextLine = nextStreamLine(theme.NextStream, time.Now())
+	}
+
 	// Check if we can use cached placeholder
-	m.streamMu.Lock()
-	if m.cachedPlaceholder != nil &&
-		m.cachedPlaceholderPath == currentPath &&
-		m.cachedPlaceholderSize.X == width &&
-		m.cachedPlaceholderSize.Y == height {
-		cached := m.cachedPlaceholder
-		m.streamMu.Unlock()
+	key := placeholderKey{gen: st.placeholderGen, path: currentPath, size: image.Point{X: width, Y: height}}
+	if currentPath == "" {
+		key.theme, key.next = theme, nextLine
+	}
+	if cached := m.placeholder.get(key); cached != nil {
 		return cached
 	}
-	m.streamMu.Unlock()
 
 	log := logger.WithComponent("placeholder")
 	log.Debug().Msg("Generating new placeholder frame")
@@ -1389,67 +2101,108 @@ func (m *Manager) createPlaceholderFrame(width, height int) *image.RGBA {
 		if customImg, err := m.loadAndResizeImage(currentPath, width, height); err == nil {
 			log.Debug().Str("path", currentPath).Int("index", idx).Msg("Using custom placeholder image")
 			// Cache it
-			m.streamMu.Lock()
-			m.cachedPlaceholder = customImg
-			m.cachedPlaceholderPath = currentPath
-			m.cachedPlaceholderSize = image.Point{X: width, Y: height}
-			m.streamMu.Unlock()
+			m.placeholder.put(key, customImg)
 			return customImg
 		} else {
 			log.Warn().Err(err).Str("path", currentPath).Msg("Failed to load custom placeholder, using default")
 		}
 	}
 
-	// Default placeholder: Create blank canvas with dark background
+	img := drawDefaultPlaceholder(width, height, theme, nextLine)
+
+	// Cache the default placeholder (empty path)
+	key.path, key.theme, key.next = "", theme, nextLine
+	m.placeholder.put(key, img)
+
+	return img
+}
+
+// drawDefaultPlaceholder draws the default placeholder: a target symbol
+// (unless hidden) over the message lines and the next stream line
+func drawDefaultPlaceholder(width, height int, theme config.PlaceholderConfig, nextLine string) *image.RGBA {
 	img := image.NewRGBA(image.Rect(0, 0, width, height))
-	bgColor := color.RGBA{20, 20, 30, 255} // Dark blue-gray background
+	bgColor := themeColor(theme.Background, color.RGBA{20, 20, 30, 255}) // Dark blue-gray background
 	draw.Draw(img, img.Bounds(), &image.Uniform{bgColor}, image.Point{}, draw.Src)
 
-	// Draw a large target/circle symbol at ~1/4 size
 	centerX := width / 2
 	centerY := height / 2
 	targetSize := width / 4 // Target takes up 1/4 of width
 
-	// Draw concentric circles to create a target symbol
-	circleColor1 := color.RGBA{70, 130, 180, 255}  // Steel blue
-	circleColor2 := color.RGBA{100, 149, 237, 255} // Cornflower blue
+	lines := strings.Split(theme.Message, "\n")
+	if nextLine != "" {
+		lines = append(lines, "", nextLine)
+	}
+	const lineHeight = 18
 
-	// Draw outer circle
-	drawCircle(img, centerX, centerY, targetSize/2, circleColor1)
-	// Draw middle circle
-	drawCircle(img, centerX, centerY, targetSize/3, circleColor2)
-	// Draw inner circle
-	drawCircle(img, centerX, centerY, targetSize/6, circleColor1)
-	// Draw center dot
-	drawCircle(img, centerX, centerY, targetSize/12, color.RGBA{255, 255, 255, 255})
+	// Text starts below the target, or is centered without one
+	textY := centerY + targetSize/2 + 40
+	if theme.HideTarget {
+		textY = centerY - (len(lines)-1)*lineHeight/2
+	} else {
+		// Draw concentric circles to create a target symbol
+		circleColor1 := themeColor(theme.TargetColor, color.RGBA{70, 130, 180, 255})  // Steel blue
+		circleColor2 := themeColor(theme.AccentColor, color.RGBA{100, 149, 237, 255}) // Cornflower blue
 
-	// Add text below the target
-	text := "Waiting for allowlisted window..."
-	textColor := color.RGBA{150, 150, 160, 255}
+		// Draw outer circle
+		drawCircle(img, centerX, centerY, targetSize/2, circleColor1)
+		// Draw middle circle
+		drawCircle(img, centerX, centerY, targetSize/3, circleColor2)
+		// Draw inner circle
+		drawCircle(img, centerX, centerY, targetSize/6, circleColor1)
+		// Draw center dot
+		drawCircle(img, centerX, centerY, targetSize/12, color.RGBA{255, 255, 255, 255})
+	}
 
-	// Calculate text position (centered below target)
 	d := &font.Drawer{
 		Dst:  img,
-		Src:  image.NewUniform(textColor),
+		Src:  image.NewUniform(themeColor(theme.TextColor, color.RGBA{150, 150, 160, 255})),
 		Face: basicfont.Face7x13,
-		Dot:  fixed.Point26_6{},
+	}
+	for i, line := range lines {
+		textWidth := d.MeasureString(line)
+		d.Dot = fixed.Point26_6{
+			X: (fixed.I(width) - textWidth) / 2,
+			Y: fixed.I(textY + i*lineHeight),
+		}
+		d.DrawString(line)
 	}
 
-	textWidth := d.MeasureString(text)
-	textX := (fixed.I(width) - textWidth) / 2
-	textY := fixed.I(centerY + targetSize/2 + 40)
-
-	d.Dot = fixed.Point26_6{X: textX, Y: textY}
-	d.DrawString(text)
+	return img
+}
 
-	// Cache the default placeholder
-	m.streamMu.Lock()
-	m.cachedPlaceholder = img
-	m.cachedPlaceholderPath = "" // Empty path means default placeholder
-	m.cachedPlaceholderSize = image.Point{X: width, Y: height}
-	m.streamMu.Unlock()
+// themeColor parses a placeholder theme color, falling back to the
+// built-in color if it is invalid
+func themeColor(hex string, fallback color.RGBA) color.RGBA {
+	if c, err := config.ParseHexColor(hex); err == nil {
+		return c
+	}
+	return fallback
+}
 
-	return img
+// nextStreamLine describes the next scheduled stream (an RFC 3339 time),
+// e.g. "Next stream: Mon 14:00 (in 2h 5m)", or returns an empty string if
+// it is unset, invalid or past
+func nextStreamLine(nextStream string, now time.Time) string {
+	if nextStream == "" {
+		return ""
+	}
+	at, err := time.Parse(time.RFC3339, nextStream)
+	if err != nil || !at.After(now) {
+		return ""
+	}
+
+	// Round up, so the countdown never shows 0m before the time
+	minutes := int(at.Sub(now).Minutes()) + 1
+	var in string
+	switch days, hours := minutes/(24*60), minutes/60%24; {
+	case days > 0:
+		in = fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		in = fmt.Sprintf("%dh %dm", hours, minutes%60)
+	default:
+		in = fmt.Sprintf("%dm", minutes)
+	}
+	return fmt.Sprintf("Next stream: %s (in %s)", at.Local().Format("Mon 15:04"), in)
 }
 
 // loadAndResizeImage loads an image from disk and resizes it to fit the given dimensions
@@ -1514,28 +2267,52 @@ func drawCircle(img *image.RGBA, cx, cy, radius int, col color.Color) {
 	}
 }
 
-// SetForceStandby sets the force standby mode
-func (m *Manager) SetForceStandby(enabled bool) {
+// SetPIIGuard sets the OCR guard that blanks the stream on sensitive text
+func (m *Manager) SetPIIGuard(guard *pii.Guard) {
 	m.streamMu.Lock()
-	m.forceStandby = enabled
+	m.piiGuard = guard
 	m.streamMu.Unlock()
+}
+
+// GetPIIGuard returns the PII guard, or nil if it is disabled
+func (m *Manager) GetPIIGuard() *pii.Guard {
+	m.streamMu.Lock()
+	defer m.streamMu.Unlock()
+	return m.piiGuard
+}
+
+// isPIIBlanked submits a captured frame to the PII guard and reports
+// whether it must be replaced with the placeholder
+func (m *Manager) isPIIBlanked(img *image.RGBA) bool {
+	guard := m.GetPIIGuard()
+	if guard == nil {
+		return false
+	}
+	guard.Submit(img)
+	return guard.Blanked()
+}
+
+// SetForceStandby sets the force standby mode
+func (m *Manager) SetForceStandby(enabled bool) {
+	m.state.update(func(st *streamState) { st.forceStandby = enabled })
 	logger.WithComponent("stream").Info().Bool("enabled", enabled).Msg("Force standby mode changed")
+	m.notifyStateChange()
+}
+
+// IsDesktopBlocked reports whether the current desktop isn't streamed
+func (m *Manager) IsDesktopBlocked() bool {
+	return m.state.snapshot().desktopBlocked
 }
 
 // GetForceStandby returns the current force standby state
 func (m *Manager) GetForceStandby() bool {
-	m.streamMu.Lock()
-	defer m.streamMu.Unlock()
-	return m.forceStandby
+	return m.state.snapshot().forceStandby
 }
 
 // ToggleForceStandby toggles the force standby mode and returns the new state
 func (m *Manager) ToggleForceStandby() bool {
-	m.streamMu.Lock()
-	wasInStandby := m.wasInStandby
-	m.forceStandby = !m.forceStandby
-	newState := m.forceStandby
-	m.streamMu.Unlock()
+	_, st := m.state.update(func(st *streamState) { st.forceStandby = !st.forceStandby })
+	wasInStandby, newState := st.wasInStandby, st.forceStandby
 
 	// If turning ON standby and we weren't already showing placeholder, rotate
 	if newState && !wasInStandby {
@@ -1543,6 +2320,7 @@ func (m *Manager) ToggleForceStandby() bool {
 	}
 
 	logger.WithComponent("stream").Info().Bool("enabled", newState).Msg("Force standby mode toggled")
+	m.notifyStateChange()
 	return newState
 }
 
@@ -1554,25 +2332,19 @@ func (m *Manager) rotatePlaceholder() {
 
 // SetAllowlistBypass sets the allowlist bypass mode
 func (m *Manager) SetAllowlistBypass(enabled bool) {
-	m.streamMu.Lock()
-	m.allowlistBypass = enabled
-	m.streamMu.Unlock()
+	m.state.update(func(st *streamState) { st.allowlistBypass = enabled })
 	logger.WithComponent("stream").Info().Bool("enabled", enabled).Msg("Allowlist bypass mode changed")
 }
 
 // GetAllowlistBypass returns the current allowlist bypass state
 func (m *Manager) GetAllowlistBypass() bool {
-	m.streamMu.Lock()
-	defer m.streamMu.Unlock()
-	return m.allowlistBypass
+	return m.state.snapshot().allowlistBypass
 }
 
 // ToggleAllowlistBypass toggles the allowlist bypass mode and returns the new state
 func (m *Manager) ToggleAllowlistBypass() bool {
-	m.streamMu.Lock()
-	m.allowlistBypass = !m.allowlistBypass
-	newState := m.allowlistBypass
-	m.streamMu.Unlock()
+	_, st := m.state.update(func(st *streamState) { st.allowlistBypass = !st.allowlistBypass })
+	newState := st.allowlistBypass
 
 	logger.WithComponent("stream").Info().Bool("enabled", newState).Msg("Allowlist bypass mode toggled")
 	return newState
@@ -1584,32 +2356,31 @@ func (m *Manager) CyclePlaceholder(direction int) {
 	log := logger.WithComponent("placeholder")
 
 	if len(paths) == 0 {
-		m.streamMu.Lock()
-		m.currentPlaceholderIdx = -1
-		m.cachedPlaceholder = nil // Invalidate cache
-		m.streamMu.Unlock()
+		m.state.update(func(st *streamState) {
+			st.placeholderIdx = -1
+			st.placeholderGen++ // Invalidate cache
+		})
 		log.Debug().Msg("No placeholder images configured, using default")
 		return
 	}
 
 	if len(paths) == 1 {
-		m.streamMu.Lock()
-		if m.currentPlaceholderIdx != 0 {
-			m.currentPlaceholderIdx = 0
-			m.cachedPlaceholder = nil // Invalidate cache
-		}
-		m.streamMu.Unlock()
+		m.state.update(func(st *streamState) {
+			if st.placeholderIdx != 0 {
+				st.placeholderIdx = 0
+				st.placeholderGen++ // Invalidate cache
+			}
+		})
 		log.Debug().Str("path", paths[0]).Msg("Single placeholder image, no cycling needed")
 		return
 	}
 
 	// Cycle in the given direction
-	m.streamMu.Lock()
-	currentIdx := m.currentPlaceholderIdx
-	newIdx := (currentIdx + direction + len(paths)) % len(paths)
-	m.currentPlaceholderIdx = newIdx
-	m.cachedPlaceholder = nil // Invalidate cache to force reload
-	m.streamMu.Unlock()
+	_, st := m.state.update(func(st *streamState) {
+		st.placeholderIdx = (st.placeholderIdx + direction + len(paths)) % len(paths)
+		st.placeholderGen++ // Invalidate cache to force reload
+	})
+	newIdx := st.placeholderIdx
 
 	log.Debug().
 		Int("new_index", newIdx).
@@ -1620,16 +2391,11 @@ func (m *Manager) CyclePlaceholder(direction int) {
 
 // GetZoomState returns the current zoom state
 func (m *Manager) GetZoomState() ZoomState {
-	m.zoomMu.RLock()
-	defer m.zoomMu.RUnlock()
-	return m.zoomState
+	return m.state.snapshot().zoom
 }
 
 // SetZoomState sets the zoom state with validation
 func (m *Manager) SetZoomState(state ZoomState) ZoomState {
-	m.zoomMu.Lock()
-	defer m.zoomMu.Unlock()
-
 	// Clamp scale between 1.0 and 4.0
 	if state.Scale < 1.0 {
 		state.Scale = 1.0
@@ -1662,8 +2428,11 @@ func (m *Manager) SetZoomState(state ZoomState) ZoomState {
 		state.OffsetY = 0.5
 	}
 
-	m.zoomState = state
-	return m.zoomState
+	before, _ := m.state.update(func(st *streamState) { st.zoom = state })
+	if before.zoom != state {
+		m.publishZoomChanged(state)
+	}
+	return state
 }
 
 // ResetZoom resets the zoom to default (no zoom)
@@ -1673,10 +2442,12 @@ func (m *Manager) ResetZoom() ZoomState {
 
 // GetThumbnail returns a scaled-down unzoomed thumbnail of the current stream frame
 func (m *Manager) GetThumbnail(maxWidth int) *image.RGBA {
+	// Hold the read lock while scaling: the stream loop recycles the frame
+	// once it is replaced
 	m.unzoomedFrameMu.RLock()
-	src := m.lastUnzoomedFrame
-	m.unzoomedFrameMu.RUnlock()
+	defer m.unzoomedFrameMu.RUnlock()
 
+	src := m.lastUnzoomedFrame
 	if src == nil {
 		return nil
 	}
@@ -1696,15 +2467,14 @@ func (m *Manager) GetThumbnail(maxWidth int) *image.RGBA {
 	return dst
 }
 
-// applyZoom applies the current zoom/pan state to an image
-func (m *Manager) applyZoom(img *image.RGBA) *image.RGBA {
-	m.zoomMu.RLock()
-	state := m.zoomState
-	m.zoomMu.RUnlock()
+// applyZoom applies a zoom/pan state to an image.
+// When zoomed, the result is a new pooled frame owned by the caller, along
+// with the part of it the crop was scaled into.
+func (m *Manager) applyZoom(img *image.RGBA, state ZoomState) (*image.RGBA, image.Rectangle) {
 
 	// No zoom needed if scale is 1.0
 	if state.Scale <= 1.0 {
-		return img
+		return img, image.Rectangle{}
 	}
 
 	bounds := img.Bounds()
@@ -1746,7 +2516,7 @@ func (m *Manager) applyZoom(img *image.RGBA) *image.RGBA {
 	cfg := m.configMgr.Get()
 	dstWidth := cfg.VirtualDisplay.Width
 	dstHeight := cfg.VirtualDisplay.Height
-	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	dst := framepool.GetZeroed(dstWidth, dstHeight)
 
 	// Calculate scaling to maintain aspect ratio (letterbox if needed)
 	cropWidth := cropRect.Dx()
@@ -1771,9 +2541,22 @@ func (m *Manager) applyZoom(img *image.RGBA) *image.RGBA {
 	scaledRect := image.Rect(offsetX, offsetY, offsetX+scaledWidth, offsetY+scaledHeight)
 
 	// Scale the cropped region to the centered rectangle (maintains aspect ratio)
+	if m.scaler != nil {
+		err := m.scaler.Scale(dst, scaledRect, img, cropRect)
+		if err == nil {
+			return dst, scaledRect
+		}
+		// Stay on the CPU from here on rather than failing every frame
+		logger.WithComponent("stream").Warn().Err(err).Msg("GPU scaling failed, falling back to the CPU")
+		m.scaler.Close()
+		m.streamMu.Lock()
+		m.scaler = nil
+		m.streamMu.Unlock()
+		clear(dst.Pix)
+	}
 	xdraw.CatmullRom.Scale(dst, scaledRect, img, cropRect, xdraw.Over, nil)
 
-	return dst
+	return dst, scaledRect
 }
 
 // scaleAndLetterbox scales an image to fill the max dimensions while maintaining aspect ratio
@@ -1815,11 +2598,29 @@ func (m *Manager) scaleAndLetterbox(src *image.RGBA, out output.Output) *image.R
 
 // HealthStatus contains streaming health information
 type HealthStatus struct {
-	LastFrameTime       time.Time `json:"last_frame_time"`
-	FrameAge            string    `json:"frame_age"`
-	ConsecutiveFailures int       `json:"consecutive_failures"`
-	IsHealthy           bool      `json:"is_healthy"`
-	StreamRunning       bool      `json:"stream_running"`
+	LastFrameTime       time.Time       `json:"last_frame_time"`
+	FrameAge            string          `json:"frame_age"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	IsHealthy           bool            `json:"is_healthy"`
+	StreamRunning       bool            `json:"stream_running"`
+	FramePoolHits       uint64          `json:"frame_pool_hits"`
+	FramePoolMisses     uint64          `json:"frame_pool_misses"`
+	Backend             string          `json:"backend"`
+	Backends            []BackendHealth `json:"backends,omitempty"` // Per-backend health for fallback chains
+	Connection          ConnectionStatus `json:"connection"`
+	Clients             int             `json:"clients"`
+	OnAir               bool            `json:"on_air"`
+	Suspended           bool            `json:"suspended"` // Capture parked without viewers
+	FPS                 int             `json:"fps"`       // Stream loop frame rate, after capture.app_fps
+	Throttle            ThrottleStatus  `json:"throttle"`  // Load governor
+
+	CaptureFailures []CaptureFailures `json:"capture_failures"` // Per capture backend, rolling over five minutes
+
+	Watchdog *capture.WatchdogStatus `json:"watchdog,omitempty"` // Nil when the watchdog is disabled
+	Pipeline []StageStats            `json:"pipeline"`           // Per-stage frame timing
+
+	CaptureWorkers []capture.WorkerStatus `json:"capture_workers"` // Background window capture
+	Compose        string                 `json:"compose"`         // "cpu", or the GPU renderer scaling zoomed frames
 }
 
 // GetHealthStatus returns the current health status of the stream
@@ -1831,6 +2632,11 @@ func (m *Manager) GetHealthStatus() HealthStatus {
 
 	m.streamMu.Lock()
 	running := m.streamRunning
+	clients := m.clientCount
+	onAir := m.onAir
+	suspended := m.suspended
+	fps := m.streamFPS
+	scaler := m.scaler
 	m.streamMu.Unlock()
 
 	var frameAge string
@@ -1841,15 +2647,46 @@ func (m *Manager) GetHealthStatus() HealthStatus {
 	}
 
 	// Consider unhealthy if: not running, >5 consecutive failures, or frame age > 1s
-	isHealthy := running && failures < 5 && (lastFrame.IsZero() || time.Since(lastFrame) < time.Second)
+	// (frames aren't expected while suspended)
+	isHealthy := running && failures < 5 && (suspended || lastFrame.IsZero() || time.Since(lastFrame) < time.Second)
+
+	poolHits, poolMisses := framepool.Stats()
 
-	return HealthStatus{
+	backend := m.getBackend()
+	var backends []BackendHealth
+	if fallback, ok := backend.(*FallbackBackend); ok {
+		backends = fallback.Health()
+	}
+
+	status := HealthStatus{
 		LastFrameTime:       lastFrame,
 		FrameAge:            frameAge,
 		ConsecutiveFailures: failures,
 		IsHealthy:           isHealthy,
 		StreamRunning:       running,
-	}
+		FramePoolHits:       poolHits,
+		FramePoolMisses:     poolMisses,
+		Backend:             backend.Name(),
+		Backends:            backends,
+		Connection:          m.GetConnectionStatus(),
+		Clients:             clients,
+		OnAir:               onAir,
+		Suspended:           suspended,
+		FPS:                 fps,
+		Throttle:            m.ThrottleStatus(),
+		Pipeline:            m.pipeline.Stats(),
+		CaptureWorkers:      m.captureScheduler.Workers(),
+		CaptureFailures:     m.GetCaptureFailures(),
+		Compose:             "cpu",
+	}
+	if scaler != nil {
+		status.Compose = scaler.Renderer()
+	}
+	if m.watchdog != nil {
+		watchdog := m.watchdog.Status()
+		status.Watchdog = &watchdog
+	}
+	return status
 }
 
 // OnProfileChanged should be called when the active profile changes.
@@ -1859,15 +2696,13 @@ func (m *Manager) OnProfileChanged(profileID string) {
 		Str("profile_id", profileID).
 		Msg("Profile changed, invalidating caches")
 
-	// Clear cached placeholder image
-	m.streamMu.Lock()
-	m.cachedPlaceholder = nil
-	m.cachedPlaceholderPath = ""
-	m.currentPlaceholderIdx = 0
-	m.streamMu.Unlock()
+	// Clear the cached placeholder image, and the last allowed window since
+	// the allowlist may have changed
+	m.state.update(func(st *streamState) {
+		st.placeholderIdx = 0
+		st.placeholderGen++
+		st.lastAllowedWindow = nil
+	})
 
-	// Clear the last allowed window since allowlist may have changed
-	m.streamMu.Lock()
-	m.lastAllowedWindow = nil
-	m.streamMu.Unlock()
+	m.notifyStateChange()
 }
@@ -0,0 +1,77 @@
+package window
+
+import (
+	"image"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// Panic blanks the stream at once: frames go black instead of showing the
+// placeholder, the last allowed window is forgotten, and overlays are
+// turned off. The stream stays black until Rearm, whatever gets focused.
+// It returns false if the stream was already panicked.
+func (m *Manager) Panic() bool {
+	overlaysEnabled := m.overlayMgr != nil && m.overlayMgr.IsEnabled()
+	before, _ := m.state.update(func(st *streamState) {
+		if st.panicked {
+			return
+		}
+		st.panicked = true
+		st.panicOverlays = overlaysEnabled
+		st.lastAllowedWindow = nil
+	})
+	if before.panicked {
+		return false
+	}
+
+	if m.overlayMgr != nil {
+		m.overlayMgr.SetEnabled(false)
+	}
+
+	// The minimap thumbnail would otherwise keep showing the last frame
+	m.unzoomedFrameMu.Lock()
+	framepool.Put(m.lastUnzoomedFrame)
+	m.lastUnzoomedFrame = nil
+	m.unzoomedFrameMu.Unlock()
+
+	m.requestFrame()
+	logger.WithComponent("stream").Warn().Msg("Panic: stream blanked until re-armed")
+	m.notifyStateChange()
+	return true
+}
+
+// Rearm ends a panic, restoring the overlays and resuming normal
+// streaming. It returns false if the stream wasn't panicked.
+func (m *Manager) Rearm() bool {
+	before, _ := m.state.update(func(st *streamState) {
+		st.panicked = false
+		st.panicOverlays = false
+	})
+	if !before.panicked {
+		return false
+	}
+
+	if m.overlayMgr != nil && before.panicOverlays {
+		m.overlayMgr.SetEnabled(true)
+	}
+
+	m.requestFrame()
+	logger.WithComponent("stream").Info().Msg("Stream re-armed after panic")
+	m.notifyStateChange()
+	return true
+}
+
+// IsPanicked reports whether the stream is blanked by Panic
+func (m *Manager) IsPanicked() bool {
+	return m.state.snapshot().panicked
+}
+
+// blackFrame returns an opaque black pooled frame
+func blackFrame(width, height int) *image.RGBA {
+	img := framepool.GetZeroed(width, height)
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 0xff
+	}
+	return img
+}
@@ -0,0 +1,234 @@
+package window
+
+import (
+	"image"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/output"
+)
+
+// StageKind orders the stages of the stream pipeline. Stages run by kind,
+// and in the order they were added within a kind.
+type StageKind int
+
+const (
+	// StageSource decides what the frame shows and produces its image: the
+	// allowlisted window or the placeholder. Allowlist rules are applied here
+	// because a window that may not be streamed must never be captured.
+	StageSource StageKind = iota
+	// StagePolicy may replace captured content, e.g. blanking it for PII
+	StagePolicy
+	// StageTransform changes the picture (color conversion, zoom)
+	StageTransform
+	// StageOverlay draws on top of the final picture
+	StageOverlay
+	// StageSink delivers the frame
+	StageSink
+)
+
+var stageKindNames = [...]string{"source", "policy", "transform", "overlay", "sink"}
+
+func (k StageKind) String() string {
+	if k < 0 || int(k) >= len(stageKindNames) {
+		return "unknown"
+	}
+	return stageKindNames[k]
+}
+
+// FrameStage is one step of the stream pipeline. Process runs on the stream
+// goroutine once per frame; an error is logged and the frame continues
+// through the remaining stages.
+type FrameStage interface {
+	Name() string
+	Kind() StageKind
+	Process(f *Frame) error
+}
+
+// Frame is a frame moving through the stream pipeline
+type Frame struct {
+	Image         *image.RGBA        // Pooled image, owned by the pipeline
+	Window        *config.WindowInfo // Window shown, nil while showing the placeholder
+	Standby       bool               // Showing the placeholder
+	StandbyReason StandbyReason      // Why the placeholder is shown
+	Stalled       bool               // The capture watchdog sees frozen frames
+	Final         bool               // Sent as is: transform and overlay stages are skipped
+	Content       image.Rectangle    // Part of Image showing content when zoomed; empty means all of it
+
+	Sequence       uint64        // Numbers the frames run through the pipeline, from 1
+	Timestamp      time.Time     // When capture started, or the frame did for the placeholder
+	CaptureLatency time.Duration // Time the capture took; zero for the placeholder
+
+	desktop  int         // Current virtual desktop
+	state    streamState // Snapshot of the stream state taken when the frame started
+	retained *image.RGBA // Image kept by a stage past the end of the frame
+}
+
+// Replace swaps in a new image, releasing the previous one to the frame pool
+// unless a stage retained it
+func (f *Frame) Replace(img *image.RGBA) {
+	if f.Image != img && f.Image != f.retained {
+		framepool.Put(f.Image)
+	}
+	f.Image = img
+}
+
+// Retain takes ownership of the current image away from the pipeline, so it
+// isn't released when replaced or when the frame ends
+func (f *Frame) Retain() {
+	f.retained = f.Image
+}
+
+// Output returns the frame as handed to overlays and outputs
+func (f *Frame) Output() *output.Frame {
+	return &output.Frame{
+		Image:          f.Image,
+		Sequence:       f.Sequence,
+		Timestamp:      f.Timestamp,
+		CaptureLatency: f.CaptureLatency,
+		Window:         f.Window,
+		Standby:        f.Standby,
+	}
+}
+
+// release returns the frame's image to the pool once every stage has run
+func (f *Frame) release() {
+	if f.Image != f.retained {
+		framepool.Put(f.Image)
+	}
+	f.Image = nil
+}
+
+// StageStats reports how long a pipeline stage takes
+type StageStats struct {
+	Name   string  `json:"name"`
+	Kind   string  `json:"kind"`
+	Runs   uint64  `json:"runs"`
+	Errors uint64  `json:"errors"`
+	LastMs float64 `json:"last_ms"`
+	AvgMs  float64 `json:"avg_ms"` // Moving average over roughly the last 20 frames
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// stageAverageWeight is the weight of the newest sample in StageStats.AvgMs
+const stageAverageWeight = 0.1
+
+type pipelineStage struct {
+	stage FrameStage
+	stats StageStats
+}
+
+// Pipeline runs frames through its stages and times each one
+type Pipeline struct {
+	mu     sync.Mutex
+	stages []*pipelineStage
+	seq    atomic.Uint64
+}
+
+// NewPipeline returns a pipeline of the given stages
+func NewPipeline(stages ...FrameStage) *Pipeline {
+	p := &Pipeline{}
+	for _, stage := range stages {
+		p.Add(stage)
+	}
+	return p
+}
+
+// Add inserts a stage after the existing stages of its kind
+func (p *Pipeline) Add(stage FrameStage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	i := 0
+	for i < len(p.stages) && p.stages[i].stage.Kind() <= stage.Kind() {
+		i++
+	}
+
+	// Copy so a frame in flight keeps the slice it started with
+	stages := make([]*pipelineStage, 0, len(p.stages)+1)
+	stages = append(stages, p.stages[:i]...)
+	stages = append(stages, &pipelineStage{
+		stage: stage,
+		stats: StageStats{Name: stage.Name(), Kind: stage.Kind().String()},
+	})
+	stages = append(stages, p.stages[i:]...)
+	p.stages = stages
+}
+
+// Run numbers and timestamps a frame, passes it through every stage, then
+// releases its image
+func (p *Pipeline) Run(f *Frame) {
+	f.Sequence = p.seq.Add(1)
+	f.Timestamp = time.Now()
+
+	p.mu.Lock()
+	stages := p.stages
+	p.mu.Unlock()
+
+	for _, s := range stages {
+		kind := s.stage.Kind()
+		if f.Final && (kind == StageTransform || kind == StageOverlay) {
+			continue
+		}
+
+		start := time.Now()
+		err := s.stage.Process(f)
+		p.record(s, time.Since(start), err)
+
+		if err != nil {
+			logger.WithComponent("stream").Error().
+				Err(err).
+				Str("stage", s.stats.Name).
+				Msg("Frame stage failed")
+		}
+	}
+
+	f.release()
+}
+
+func (p *Pipeline) record(s *pipelineStage, elapsed time.Duration, err error) {
+	ms := float64(elapsed.Microseconds()) / 1000
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := &s.stats
+	if stats.Runs == 0 {
+		stats.AvgMs = ms
+	} else {
+		stats.AvgMs += stageAverageWeight * (ms - stats.AvgMs)
+	}
+	stats.Runs++
+	stats.LastMs = ms
+	stats.MaxMs = max(stats.MaxMs, ms)
+	if err != nil {
+		stats.Errors++
+	}
+}
+
+// Stats returns the timing of every stage, in pipeline order
+func (p *Pipeline) Stats() []StageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]StageStats, len(p.stages))
+	for i, s := range p.stages {
+		stats[i] = s.stats
+	}
+	return stats
+}
+
+// stageFunc is a FrameStage backed by a function
+type stageFunc struct {
+	name    string
+	kind    StageKind
+	process func(f *Frame) error
+}
+
+func (s stageFunc) Name() string           { return s.name }
+func (s stageFunc) Kind() StageKind        { return s.kind }
+func (s stageFunc) Process(f *Frame) error { return s.process(f) }
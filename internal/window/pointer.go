@@ -0,0 +1,159 @@
+package window
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// rippleDuration is how long a click ripple takes to expand and fade out
+const rippleDuration = 600 * time.Millisecond
+
+// pointerRipple is a click ripple, at a position relative to the window
+// (0-1) so it stays put in the frame
+type pointerRipple struct {
+	x, y    float64
+	started time.Time
+}
+
+// pointerHighlighter remembers the button state between frames to spot
+// clicks, and the ripples still expanding
+type pointerHighlighter struct {
+	mu        sync.Mutex
+	pressed   bool
+	ripples   []pointerRipple
+	lastError string
+}
+
+// press records the button state at a position and starts a ripple when a
+// button went down since the last frame; it returns the live ripples
+func (p *pointerHighlighter) press(x, y float64, pressed bool, now time.Time) []pointerRipple {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pressed && !p.pressed {
+		p.ripples = append(p.ripples, pointerRipple{x: x, y: y, started: now})
+	}
+	p.pressed = pressed
+
+	live := p.ripples[:0]
+	for _, r := range p.ripples {
+		if now.Sub(r.started) < rippleDuration {
+			live = append(live, r)
+		}
+	}
+	p.ripples = live
+	return append([]pointerRipple{}, live...)
+}
+
+// logError logs a pointer query failure once, until a different one occurs
+func (p *pointerHighlighter) logError(err error) {
+	p.mu.Lock()
+	repeated := p.lastError == err.Error()
+	p.lastError = err.Error()
+	p.mu.Unlock()
+
+	if !repeated {
+		logger.WithComponent("stream").Debug().Err(err).Msg("Pointer position unavailable, not highlighting it")
+	}
+}
+
+// pointerStage draws a halo around the mouse pointer, and ripples where it
+// clicked, on shared windows with pointer_highlight enabled. It runs before
+// zoom so the halo stays on the pointer at any zoom level.
+func (m *Manager) pointerStage(f *Frame) error {
+	cfg := m.configMgr.Get().PointerHighlight
+	if !cfg.Enabled || f.Standby || f.Window == nil || f.Window.Geometry.Width <= 0 || f.Window.Geometry.Height <= 0 {
+		return nil
+	}
+	backend := m.getBackend()
+	tracker, ok := backend.(PointerTracker)
+	if !ok || !backend.Capabilities().Pointer {
+		return nil
+	}
+
+	x, y, pressed, err := tracker.PointerPosition(f.Window)
+	if err != nil {
+		m.pointer.logError(err)
+		return nil
+	}
+
+	geometry := f.Window.Geometry
+	relX := float64(x) / float64(geometry.Width)
+	relY := float64(y) / float64(geometry.Height)
+	ripples := m.pointer.press(relX, relY, pressed && cfg.ClickRipples, time.Now())
+
+	// The radius is given for a 720p stream; zooming enlarges the frame
+	// afterwards, so shrink it by the zoom scale
+	bounds := f.Image.Bounds()
+	radius := float64(cfg.Radius) * float64(bounds.Dy()) / 720 / max(1, f.state.zoom.Scale)
+	halo := themeColor(cfg.Color, color.RGBA{255, 214, 10, 255})
+
+	now := time.Now()
+	for _, r := range ripples {
+		progress := float64(now.Sub(r.started)) / float64(rippleDuration)
+		cx := float64(bounds.Min.X) + r.x*float64(bounds.Dx())
+		cy := float64(bounds.Min.Y) + r.y*float64(bounds.Dy())
+		drawRing(f.Image, cx, cy, radius*(1+2*progress), max(2, radius/6), halo, 1-progress)
+	}
+
+	if relX < 0 || relX > 1 || relY < 0 || relY > 1 {
+		return nil // Pointer outside the shared window
+	}
+	cx := float64(bounds.Min.X) + relX*float64(bounds.Dx())
+	cy := float64(bounds.Min.Y) + relY*float64(bounds.Dy())
+	fill := 0.35
+	if pressed {
+		fill = 0.55
+	}
+	drawDisc(f.Image, cx, cy, radius, halo, fill)
+	// A dark outline keeps the halo visible on light backgrounds
+	drawRing(f.Image, cx, cy, radius, max(2, radius/8), halo, 1)
+	drawRing(f.Image, cx, cy, radius+max(2, radius/8), max(1, radius/16), color.RGBA{0, 0, 0, 255}, 0.8)
+	return nil
+}
+
+// drawDisc blends a filled, anti-aliased circle onto img
+func drawDisc(img *image.RGBA, cx, cy, radius float64, c color.RGBA, alpha float64) {
+	blendAnnulus(img, cx, cy, radius, 0, c, alpha)
+}
+
+// drawRing blends an anti-aliased ring of the given width, centered on
+// radius, onto img
+func drawRing(img *image.RGBA, cx, cy, radius, width float64, c color.RGBA, alpha float64) {
+	blendAnnulus(img, cx, cy, radius+width/2, max(0, radius-width/2), c, alpha)
+}
+
+// blendAnnulus blends the area between the inner and outer radius onto img
+func blendAnnulus(img *image.RGBA, cx, cy, outer, inner float64, c color.RGBA, alpha float64) {
+	if alpha <= 0 {
+		return
+	}
+	r := int(math.Ceil(outer)) + 1
+	area := image.Rect(int(cx)-r, int(cy)-r, int(cx)+r+1, int(cy)+r+1).Intersect(img.Bounds())
+	if area.Empty() {
+		return
+	}
+
+	mask := image.NewAlpha(area)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			cov := min(1, outer+0.5-d)
+			if inner > 0 {
+				cov = min(cov, d-inner+0.5)
+			}
+			if cov > 0 {
+				mask.Pix[mask.PixOffset(x, y)] = uint8(min(1, cov) * 255)
+			}
+		}
+	}
+
+	src := image.NewUniform(color.NRGBA{c.R, c.G, c.B, uint8(min(1, alpha) * 255)})
+	draw.DrawMask(img, area, src, image.Point{}, mask, area.Min, draw.Over)
+}
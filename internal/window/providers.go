@@ -0,0 +1,188 @@
+package window
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture"
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// BackendDriver creates a window backend. Packages outside this one, e.g.
+// for a proprietary compositor, register drivers with RegisterBackend from
+// their init function, like database/sql drivers.
+type BackendDriver struct {
+	// New connects the backend
+	New func() (Backend, error)
+
+	// Detect reports whether the backend suits the current session. Auto
+	// detection tries registered backends whose Detect returns true before
+	// the built-in ones; nil means the backend is only used by name.
+	Detect func() bool
+
+	// Description is a short human-readable summary for provider listings
+	Description string
+
+	// Priority orders auto detection among registered backends, highest
+	// first
+	Priority int
+}
+
+// BackendProvider describes a built-in or registered window backend
+type BackendProvider struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Builtin     bool   `json:"builtin"`
+	Detectable  bool   `json:"detectable"` // Tried by auto detection
+	Priority    int    `json:"priority"`
+	Active      bool   `json:"active"` // In use, alone or in a fallback chain
+}
+
+// Providers lists the registered window backends and capturers
+type Providers struct {
+	Backends  []BackendProvider          `json:"backends"`
+	Capturers []capture.CapturerProvider `json:"capturers"`
+}
+
+// registeredBackend is a driver and whether it ships with FocusStreamer
+type registeredBackend struct {
+	BackendDriver
+	builtin bool
+}
+
+var (
+	backendDriversMu sync.RWMutex
+	backendDrivers   = make(map[string]registeredBackend)
+)
+
+func init() {
+	// Built-in backends are detected by detectBackend; synthetic and remote
+	// bring their own capture router, so NewManagerWithBackend creates them
+	builtins := map[string]BackendDriver{
+		BackendX11:       {New: func() (Backend, error) { return NewX11Backend() }, Description: "X11 and XWayland through the X server"},
+		BackendKWin:      {New: func() (Backend, error) { return NewKWinBackend() }, Description: "KDE Plasma through a KWin script"},
+		BackendHyprland:  {New: func() (Backend, error) { return NewHyprlandBackend() }, Description: "Hyprland through its IPC socket"},
+		BackendWindows:   {New: func() (Backend, error) { return NewWin32Backend() }, Description: "Windows through the Win32 API"},
+		BackendSynthetic: {Description: "Fake windows and procedurally drawn frames (demo mode)"},
+		BackendRemote:    {Description: "Windows and frames from a capture agent (agent mode)"},
+	}
+	for name, driver := range builtins {
+		backendDrivers[name] = registeredBackend{BackendDriver: driver, builtin: true}
+	}
+}
+
+// RegisterBackend makes a window backend available by name, for the
+// backend setting and --backend flag, and to auto detection if it has a
+// Detect function. It panics if the name is empty or taken or New is nil.
+func RegisterBackend(name string, driver BackendDriver) {
+	backendDriversMu.Lock()
+	defer backendDriversMu.Unlock()
+
+	if name == "" || name == BackendAuto {
+		panic("window: RegisterBackend with an invalid name: " + name)
+	}
+	if driver.New == nil {
+		panic("window: RegisterBackend " + name + " without a New function")
+	}
+	if _, dup := backendDrivers[name]; dup {
+		panic("window: RegisterBackend called twice for " + name)
+	}
+	backendDrivers[name] = registeredBackend{BackendDriver: driver}
+	BackendNames = append(BackendNames, name)
+	config.RegisterBackendName(name)
+}
+
+// newRegisteredBackend creates the named backend from its driver
+func newRegisteredBackend(name string) (Backend, error) {
+	backendDriversMu.RLock()
+	driver, ok := backendDrivers[name]
+	backendDriversMu.RUnlock()
+
+	if !ok || driver.New == nil {
+		return nil, fmt.Errorf("unknown window backend: %s (use %s)", name, strings.Join(BackendNames, ", "))
+	}
+	return driver.New()
+}
+
+// detectRegisteredBackend connects the first registered backend, by
+// priority, that recognizes the session, or returns nil if none does
+func detectRegisteredBackend() Backend {
+	log := logger.WithComponent("window-manager")
+
+	backendDriversMu.RLock()
+	type candidate struct {
+		name   string
+		driver BackendDriver
+	}
+	var candidates []candidate
+	for name, d := range backendDrivers {
+		if !d.builtin && d.Detect != nil {
+			candidates = append(candidates, candidate{name, d.BackendDriver})
+		}
+	}
+	backendDriversMu.RUnlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].driver.Priority != candidates[j].driver.Priority {
+			return candidates[i].driver.Priority > candidates[j].driver.Priority
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	for _, c := range candidates {
+		if !c.driver.Detect() {
+			continue
+		}
+		backend, err := c.driver.New()
+		if err != nil {
+			log.Warn().Err(err).Str("backend", c.name).Msg("Registered backend detected but not available")
+			continue
+		}
+		log.Info().Str("backend", c.name).Msg("Using registered backend")
+		return backend
+	}
+	return nil
+}
+
+// GetProviders lists the built-in and registered window backends and
+// capturers, marking the ones in use
+func (m *Manager) GetProviders() Providers {
+	active := make(map[string]bool)
+	backend := m.getBackend()
+	active[backend.Name()] = true
+	if fallback, ok := backend.(*FallbackBackend); ok {
+		for _, h := range fallback.Health() {
+			active[h.Name] = true
+		}
+	}
+
+	backendDriversMu.RLock()
+	providers := Providers{Backends: make([]BackendProvider, 0, len(backendDrivers))}
+	for name, d := range backendDrivers {
+		providers.Backends = append(providers.Backends, BackendProvider{
+			Name:        name,
+			Description: d.Description,
+			Builtin:     d.builtin,
+			Detectable:  (d.builtin && d.New != nil) || d.Detect != nil,
+			Priority:    d.Priority,
+			Active:      active[name],
+		})
+	}
+	backendDriversMu.RUnlock()
+
+	sort.Slice(providers.Backends, func(i, j int) bool {
+		a, b := providers.Backends[i], providers.Backends[j]
+		if a.Builtin != b.Builtin {
+			return a.Builtin
+		}
+		return a.Name < b.Name
+	})
+
+	if m.captureRouter != nil {
+		providers.Capturers = m.captureRouter.Providers()
+	}
+	return providers
+}
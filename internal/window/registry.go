@@ -0,0 +1,316 @@
+package window
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+const (
+	// windowListTTL is how long an enumerated window list is reused when
+	// the backend doesn't report window events. Enumerating shells out per
+	// window on some backends (kdotool), so a settings page polling the
+	// lists would otherwise enumerate constantly.
+	windowListTTL = 2 * time.Second
+
+	// registryReconcileInterval is how often a registry fed by window
+	// events is checked against a full enumeration, to catch missed events
+	registryReconcileInterval = 30 * time.Second
+)
+
+// windowRegistry holds the open windows by ID, in the backend's order, for
+// API listings and lookups. Backends that report window events keep it
+// current (live); otherwise it is a short-lived cache of the last
+// enumeration. Stored WindowInfos are never modified, only replaced, so
+// callers may keep them.
+type windowRegistry struct {
+	mu      sync.RWMutex
+	windows map[uint32]*config.WindowInfo
+	order   []uint32
+	byClass map[string][]uint32
+	focused uint32
+	live    bool      // Fed by backend window events
+	synced  time.Time // Last enumeration; zero when stale
+	events  uint64    // Events applied, to detect ones racing an enumeration
+}
+
+func newWindowRegistry() *windowRegistry {
+	return &windowRegistry{
+		windows: make(map[uint32]*config.WindowInfo),
+		byClass: make(map[string][]uint32),
+	}
+}
+
+// fresh reports whether reads can be served without enumerating
+func (r *windowRegistry) fresh() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return !r.synced.IsZero() && (r.live || time.Since(r.synced) < windowListTTL)
+}
+
+// isLive reports whether window events keep the registry current
+func (r *windowRegistry) isLive() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.live
+}
+
+// setLive records whether window events keep the registry current, and
+// marks it stale either way
+func (r *windowRegistry) setLive(live bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.live = live
+	r.synced = time.Time{}
+}
+
+// markStale makes the next read enumerate again
+func (r *windowRegistry) markStale() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.synced = time.Time{}
+}
+
+// eventCount returns the number of events applied so far
+func (r *windowRegistry) eventCount() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.events
+}
+
+// replace stores a full enumeration. If events were applied since it
+// started (eventsBefore), it may already be outdated, so the registry is
+// left stale for the next read to enumerate again.
+func (r *windowRegistry) replace(windows []*config.WindowInfo, eventsBefore uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.windows = make(map[uint32]*config.WindowInfo, len(windows))
+	r.order = make([]uint32, 0, len(windows))
+	r.byClass = make(map[string][]uint32)
+	r.focused = 0
+	for _, window := range windows {
+		if _, ok := r.windows[window.ID]; ok {
+			continue
+		}
+		r.windows[window.ID] = window
+		r.order = append(r.order, window.ID)
+		r.byClass[window.Class] = append(r.byClass[window.Class], window.ID)
+		if window.Focused {
+			r.focused = window.ID
+		}
+	}
+
+	if r.events == eventsBefore {
+		r.synced = time.Now()
+	} else {
+		r.synced = time.Time{}
+	}
+}
+
+// apply updates the registry from a backend window event
+func (r *windowRegistry) apply(event WindowEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events++
+
+	switch event.Type {
+	case WindowOpened, WindowChanged:
+		if event.Window == nil {
+			return
+		}
+		window := *event.Window
+		window.Focused = window.ID == r.focused
+		r.put(&window)
+	case WindowTitleChanged:
+		if old, ok := r.windows[event.ID]; ok && old.Title != event.Title {
+			window := *old
+			window.Title = event.Title
+			r.put(&window)
+		}
+	case WindowClosed:
+		r.remove(event.ID)
+	case WindowsResync:
+		r.synced = time.Time{}
+	}
+}
+
+// setFocused moves the focused flag to a window (0 for none). It reports
+// false for a window the registry doesn't know.
+func (r *windowRegistry) setFocused(id uint32) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, known := r.windows[id]; !known && id != 0 {
+		return false
+	}
+	if id == r.focused {
+		return true
+	}
+	for _, windowID := range []uint32{r.focused, id} {
+		if old, ok := r.windows[windowID]; ok {
+			window := *old
+			window.Focused = windowID == id
+			r.windows[windowID] = &window
+		}
+	}
+	r.focused = id
+	return true
+}
+
+// put stores a window, keeping its position if it is already known
+func (r *windowRegistry) put(window *config.WindowInfo) {
+	old, ok := r.windows[window.ID]
+	if !ok {
+		r.order = append(r.order, window.ID)
+	} else if old.Class != window.Class {
+		r.byClass[old.Class] = deleteID(r.byClass[old.Class], window.ID)
+	}
+	if !ok || old.Class != window.Class {
+		r.byClass[window.Class] = append(r.byClass[window.Class], window.ID)
+	}
+	r.windows[window.ID] = window
+}
+
+// remove drops a window
+func (r *windowRegistry) remove(id uint32) {
+	old, ok := r.windows[id]
+	if !ok {
+		return
+	}
+	delete(r.windows, id)
+	r.order = deleteID(r.order, id)
+	if ids := deleteID(r.byClass[old.Class], id); len(ids) > 0 {
+		r.byClass[old.Class] = ids
+	} else {
+		delete(r.byClass, old.Class)
+	}
+	if r.focused == id {
+		r.focused = 0
+	}
+}
+
+// deleteID removes an ID from a list, keeping the order
+func deleteID(ids []uint32, id uint32) []uint32 {
+	return slices.DeleteFunc(ids, func(other uint32) bool { return other == id })
+}
+
+// list returns the windows in order
+func (r *windowRegistry) list() []*config.WindowInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	windows := make([]*config.WindowInfo, 0, len(r.order))
+	for _, id := range r.order {
+		windows = append(windows, r.windows[id])
+	}
+	return windows
+}
+
+// get returns a window by ID
+func (r *windowRegistry) get(id uint32) (*config.WindowInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	window, ok := r.windows[id]
+	return window, ok
+}
+
+// firstOfClass returns the first window with the given class
+func (r *windowRegistry) firstOfClass(class string) (*config.WindowInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	ids := r.byClass[class]
+	if len(ids) == 0 {
+		return nil, false
+	}
+	return r.windows[ids[0]], true
+}
+
+// syncWindowRegistry enumerates the windows into the registry unless it is
+// fresh. Concurrent callers wait for a single enumeration.
+func (m *Manager) syncWindowRegistry(force bool) error {
+	m.registrySyncMu.Lock()
+	defer m.registrySyncMu.Unlock()
+
+	if !force && m.registry.fresh() {
+		return nil
+	}
+
+	events := m.registry.eventCount()
+	windows, err := m.ListWindows()
+	if err != nil {
+		return err
+	}
+	m.registry.replace(windows, events)
+	return nil
+}
+
+// registryWindows returns the open windows from the registry, enumerating
+// first if it is stale. The stream loop and focus tracking call ListWindows
+// directly unless the registry is live, since they need the current state.
+func (m *Manager) registryWindows() ([]*config.WindowInfo, error) {
+	if err := m.syncWindowRegistry(false); err != nil {
+		return nil, err
+	}
+	return m.registry.list(), nil
+}
+
+// InvalidateWindowList makes the next window listing enumerate windows
+// again
+func (m *Manager) InvalidateWindowList() {
+	m.registry.markStale()
+}
+
+// watchWindows subscribes the registry to a backend's window events, so it
+// stays current between reconciliations, and hands the stream off as soon
+// as the shared window closes. Without them the registry falls
+// back to caching enumerations briefly. Fallback chains don't report
+// events, since listing may move between their backends.
+func (m *Manager) watchWindows(backend Backend) {
+	log := logger.WithComponent("window")
+
+	watcher, ok := backend.(WindowEventWatcher)
+	if !ok {
+		m.registry.setLive(false)
+		return
+	}
+	err := watcher.WatchWindows(func(event WindowEvent) {
+		m.registry.apply(event)
+		if event.Type == WindowClosed {
+			m.onWindowClosed(event.ID)
+		}
+	})
+	if err != nil {
+		log.Debug().Err(err).Str("backend", backend.Name()).Msg("Window events unavailable, caching window lists briefly")
+		m.registry.setLive(false)
+		return
+	}
+	m.registry.setLive(true)
+	log.Debug().Str("backend", backend.Name()).Msg("Window registry fed by backend events")
+}
+
+// reconcileWindows periodically re-enumerates a live registry, catching
+// events a backend missed
+func (m *Manager) reconcileWindows(ctx context.Context) {
+	ticker := time.NewTicker(registryReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !m.registry.isLive() {
+			continue
+		}
+		if err := m.syncWindowRegistry(true); err != nil {
+			logger.WithComponent("window").Debug().Err(err).Msg("Failed to reconcile window registry")
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package window
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/remote"
+)
+
+// RemoteBackend implements the Backend interface with the windows reported
+// by a capture agent on another machine (see the remote package). Until an
+// agent connects there are no windows.
+type RemoteBackend struct {
+	hub      *remote.Hub
+	mu       sync.Mutex
+	watching bool
+}
+
+// NewRemoteBackend creates a backend serving a hub's agent windows
+func NewRemoteBackend(hub *remote.Hub) *RemoteBackend {
+	return &RemoteBackend{hub: hub}
+}
+
+// Connect is a no-op; agents connect to the server
+func (b *RemoteBackend) Connect() error {
+	return nil
+}
+
+// Close stops watching
+func (b *RemoteBackend) Close() error {
+	b.StopWatching()
+	return nil
+}
+
+// Name returns the backend name
+func (b *RemoteBackend) Name() string {
+	return BackendRemote
+}
+
+// Capabilities reports the remote features: the agent pushes focus changes
+// and activates windows on request. How it captures depends on its
+// platform, so native capture isn't claimed.
+func (b *RemoteBackend) Capabilities() Capabilities {
+	return Capabilities{
+		AccurateGeometry: true,
+		Desktops:         true,
+		Activation:       true,
+		FocusEvents:      true,
+	}
+}
+
+// ListWindows returns the agent's windows
+func (b *RemoteBackend) ListWindows() ([]*config.WindowInfo, error) {
+	return b.hub.Windows(), nil
+}
+
+// GetFocusedWindow returns the agent's focused window
+func (b *RemoteBackend) GetFocusedWindow() (*config.WindowInfo, error) {
+	if window := b.hub.Focused(); window != nil {
+		return window, nil
+	}
+	return nil, fmt.Errorf("no capture agent has reported a focused window")
+}
+
+// GetCurrentDesktop returns the desktop of the agent's focused window
+func (b *RemoteBackend) GetCurrentDesktop() int {
+	if window := b.hub.Focused(); window != nil {
+		return window.Desktop
+	}
+	return 0
+}
+
+// WatchFocus reports focus changes as the agent sends them
+func (b *RemoteBackend) WatchFocus(callback func(*config.WindowInfo)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.watching {
+		return fmt.Errorf("already watching")
+	}
+	b.watching = true
+	b.hub.OnFocus(callback)
+	return nil
+}
+
+// ActivateWindow asks the agent to focus and raise a window
+func (b *RemoteBackend) ActivateWindow(windowID uint32) error {
+	return b.hub.Activate(windowID)
+}
+
+// StopWatching stops reporting focus changes
+func (b *RemoteBackend) StopWatching() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.watching {
+		b.hub.OnFocus(nil)
+		b.watching = false
+	}
+}
@@ -0,0 +1,50 @@
+package window
+
+import (
+	"fmt"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture"
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// GetCaptureRoute returns the capturer requested for a window, by an
+// override or capture.routes, and the one capturing it
+func (m *Manager) GetCaptureRoute(window *config.WindowInfo) (capture.Route, error) {
+	if m.captureRouter == nil {
+		return capture.Route{}, fmt.Errorf("capture router not available")
+	}
+	return m.captureRouter.Route(window), nil
+}
+
+// SetCaptureRoute sends a window to the named capturer ahead of
+// capture.routes, e.g. when one capture path shows artifacts for an app.
+// "auto" removes the override. Overrides last until the window closes or
+// the server restarts.
+func (m *Manager) SetCaptureRoute(window *config.WindowInfo, capturer string) (capture.Route, error) {
+	if m.captureRouter == nil {
+		return capture.Route{}, fmt.Errorf("capture router not available")
+	}
+	if err := m.captureRouter.SetWindowRoute(window.ID, capturer); err != nil {
+		return capture.Route{}, err
+	}
+
+	route := m.captureRouter.Route(window)
+	logger.WithComponent("capture-router").Info().
+		Uint32("window_id", window.ID).
+		Str("class", window.Class).
+		Str("requested", route.Requested).
+		Str("source", route.Source).
+		Str("capturer", route.Capturer).
+		Msg("Capture route set")
+	return route, nil
+}
+
+// GetCaptureRouteOverrides returns the per-window capturer overrides by
+// window ID
+func (m *Manager) GetCaptureRouteOverrides() map[uint32]string {
+	if m.captureRouter == nil {
+		return map[uint32]string{}
+	}
+	return m.captureRouter.WindowRoutes()
+}
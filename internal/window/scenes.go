@@ -0,0 +1,218 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+const (
+	// defaultSceneTransition is how long zoom and standby transitions take
+	// unless the scene or request says otherwise
+	defaultSceneTransition = 500 * time.Millisecond
+
+	// maxSceneTransition caps requested transition durations
+	maxSceneTransition = 10 * time.Second
+
+	// sceneZoomStep is how often an animated zoom transition updates
+	sceneZoomStep = time.Second / 30
+)
+
+// ActiveScene returns the name of the last activated scene, empty if none
+func (m *Manager) ActiveScene() string {
+	m.sceneMu.Lock()
+	defer m.sceneMu.Unlock()
+	return m.activeScene
+}
+
+// ActivateScene applies the named scene from the config. An empty
+// transition or zero duration uses the scene's own. The profile, overlays
+// and window are applied before it returns; zoom and standby transitions
+// finish in the background, and activating another scene cancels them.
+func (m *Manager) ActivateScene(name, transition string, duration time.Duration) (config.SceneConfig, error) {
+	scene, ok := m.configMgr.Get().FindScene(name)
+	if !ok {
+		return config.SceneConfig{}, fmt.Errorf("scene not found: %s", name)
+	}
+
+	if transition == "" {
+		transition = scene.Transition
+	}
+	if !config.ValidSceneTransition(transition) {
+		return config.SceneConfig{}, fmt.Errorf("invalid transition: %s (use cut, zoom or standby)", transition)
+	}
+	if duration <= 0 {
+		duration = time.Duration(scene.TransitionDuration) * time.Millisecond
+	}
+	if duration <= 0 {
+		duration = defaultSceneTransition
+	}
+	duration = min(duration, maxSceneTransition)
+
+	// Resolve the profile first so a typo doesn't half-apply the scene
+	var profileID string
+	if scene.Profile != "" {
+		if profileID, ok = m.findProfile(scene.Profile); !ok {
+			return config.SceneConfig{}, fmt.Errorf("profile of scene %s not found: %s", scene.Name, scene.Profile)
+		}
+	}
+
+	m.sceneMu.Lock()
+	m.sceneGen++
+	gen := m.sceneGen
+	m.activeScene = scene.Name
+	m.sceneMu.Unlock()
+
+	log := logger.WithComponent("scenes")
+	log.Info().
+		Str("scene", scene.Name).
+		Str("transition", transition).
+		Dur("duration", duration).
+		Msg("Activating scene")
+
+	// Hide the switch behind the placeholder, unless the scene ends in
+	// standby anyway
+	coverWithStandby := transition == config.SceneTransitionStandby && !m.GetForceStandby()
+	if coverWithStandby {
+		m.SetForceStandby(true)
+	}
+
+	if profileID != "" && profileID != m.configMgr.GetActiveProfileID() {
+		if err := m.configMgr.SetActiveProfile(profileID); err != nil {
+			log.Warn().Err(err).Str("profile", scene.Profile).Msg("Failed to activate scene profile")
+		} else {
+			m.OnProfileChanged(profileID)
+		}
+	}
+
+	if m.overlayMgr != nil {
+		if scene.Overlays != nil {
+			m.overlayMgr.SetEnabled(*scene.Overlays)
+		}
+		if scene.Groups != nil {
+			m.overlayMgr.ShowOnlyGroups(scene.Groups)
+		}
+	}
+
+	if scene.Window != "" {
+		m.focusSceneWindow(scene)
+	}
+
+	var zoom *ZoomState
+	if scene.ZoomScale > 0 {
+		zoom = &ZoomState{Scale: scene.ZoomScale, OffsetX: scene.ZoomX, OffsetY: scene.ZoomY}
+		if zoom.OffsetX == 0 {
+			zoom.OffsetX = 0.5
+		}
+		if zoom.OffsetY == 0 {
+			zoom.OffsetY = 0.5
+		}
+	}
+
+	switch {
+	case coverWithStandby:
+		if zoom != nil {
+			m.SetZoomState(*zoom)
+		}
+		m.goTask(func(ctx context.Context) { m.endStandbyTransition(ctx, gen, scene, duration) })
+	case transition == config.SceneTransitionZoom && zoom != nil:
+		if scene.Standby != nil {
+			m.SetForceStandby(*scene.Standby)
+		}
+		from := m.GetZoomState()
+		m.goTask(func(ctx context.Context) { m.animateZoom(ctx, gen, from, *zoom, duration) })
+	default:
+		if scene.Standby != nil {
+			m.SetForceStandby(*scene.Standby)
+		}
+		if zoom != nil {
+			m.SetZoomState(*zoom)
+		}
+	}
+
+	return scene, nil
+}
+
+// focusSceneWindow focuses the first open window matching the scene's
+// window pattern, without waiting for one to open
+func (m *Manager) focusSceneWindow(scene config.SceneConfig) {
+	log := logger.WithComponent("scenes")
+
+	if !m.CanActivateWindows() {
+		log.Warn().Str("backend", m.getBackend().Name()).Msg("Backend can't focus windows, skipping scene window")
+		return
+	}
+
+	re, err := config.CompilePattern(scene.Window)
+	if err != nil {
+		log.Warn().Err(err).Str("scene", scene.Name).Msg("Invalid scene window pattern")
+		return
+	}
+
+	window := m.findMatchingWindow(re)
+	if window == nil {
+		log.Warn().Str("scene", scene.Name).Str("pattern", scene.Window).Msg("No window matches the scene window pattern")
+		return
+	}
+	if err := m.ActivateWindow(window.ID); err != nil {
+		log.Warn().Err(err).Str("class", window.Class).Msg("Failed to focus scene window")
+	}
+}
+
+// endStandbyTransition lifts the placeholder shown during a standby
+// transition, unless the scene asks for standby or was replaced
+func (m *Manager) endStandbyTransition(ctx context.Context, gen uint64, scene config.SceneConfig, duration time.Duration) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(duration):
+	}
+
+	if !m.sceneCurrent(gen) {
+		return
+	}
+	if scene.Standby == nil || !*scene.Standby {
+		m.SetForceStandby(false)
+	}
+}
+
+// animateZoom eases the zoom from one state to another, stopping early if
+// another scene is activated
+func (m *Manager) animateZoom(ctx context.Context, gen uint64, from, to ZoomState, duration time.Duration) {
+	ticker := time.NewTicker(sceneZoomStep)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !m.sceneCurrent(gen) {
+			return
+		}
+
+		t := min(float64(time.Since(start))/float64(duration), 1)
+		t = t * t * (3 - 2*t) // Smoothstep
+		m.SetZoomState(ZoomState{
+			Scale:   from.Scale + (to.Scale-from.Scale)*t,
+			OffsetX: from.OffsetX + (to.OffsetX-from.OffsetX)*t,
+			OffsetY: from.OffsetY + (to.OffsetY-from.OffsetY)*t,
+		})
+		if t >= 1 {
+			return
+		}
+	}
+}
+
+// sceneCurrent reports whether gen is still the latest scene activation
+func (m *Manager) sceneCurrent(gen uint64) bool {
+	m.sceneMu.Lock()
+	defer m.sceneMu.Unlock()
+	return m.sceneGen == gen
+}
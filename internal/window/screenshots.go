@@ -0,0 +1,160 @@
+package window
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/imgenc"
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	// screenshotCacheTTL is how long a batch screenshot is reused, so a
+	// settings page reloading its previews doesn't capture every window again
+	screenshotCacheTTL = 3 * time.Second
+
+	// screenshotConcurrency bounds captures in flight across all batches,
+	// so a page full of previews doesn't flood the X server
+	screenshotConcurrency = 3
+
+	// Quality of lossy screenshots: full-size ones and batch previews
+	screenshotQuality = 85
+	previewQuality    = 75
+)
+
+// WindowScreenshot is one window's downscaled image in a screenshot batch
+type WindowScreenshot struct {
+	ID          uint32 `json:"id"`
+	Class       string `json:"class,omitempty"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Data        []byte `json:"data,omitempty"` // Base64 in JSON
+	Error       string `json:"error,omitempty"`
+}
+
+// screenshotKey identifies a cached batch screenshot
+type screenshotKey struct {
+	windowID uint32
+	maxWidth int
+	format   string
+}
+
+// cachedScreenshot is a batch screenshot and when it was taken
+type cachedScreenshot struct {
+	shot  WindowScreenshot
+	taken time.Time
+}
+
+// CaptureWindowScreenshots captures several windows at once, each scaled
+// down to at most maxWidth pixels wide and encoded in an imgenc format, in
+// the order of windowIDs. A window
+// that can't be captured gets an Error instead of Data. Screenshots taken
+// within the last few seconds are reused.
+func (m *Manager) CaptureWindowScreenshots(windowIDs []uint32, maxWidth int, format string) ([]WindowScreenshot, error) {
+	windows, err := m.registryWindows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows: %w", err)
+	}
+	byID := make(map[uint32]*config.WindowInfo, len(windows))
+	for _, window := range windows {
+		byID[window.ID] = window
+	}
+
+	m.pruneScreenshotCache()
+
+	shots := make([]WindowScreenshot, len(windowIDs))
+	var wg sync.WaitGroup
+	for i, id := range windowIDs {
+		window, ok := byID[id]
+		if !ok {
+			shots[i] = WindowScreenshot{ID: id, Error: "window not found"}
+			continue
+		}
+
+		key := screenshotKey{windowID: id, maxWidth: maxWidth, format: format}
+		if shot, ok := m.lookupScreenshot(key); ok {
+			shots[i] = shot
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, window *config.WindowInfo) {
+			defer wg.Done()
+
+			m.screenshotSem <- struct{}{}
+			shot := m.captureBatchScreenshot(window, maxWidth, format)
+			<-m.screenshotSem
+
+			shots[i] = shot
+			if shot.Error == "" {
+				m.screenshotMu.Lock()
+				m.screenshotCache[key] = cachedScreenshot{shot: shot, taken: time.Now()}
+				m.screenshotMu.Unlock()
+			}
+		}(i, window)
+	}
+	wg.Wait()
+
+	return shots, nil
+}
+
+// captureBatchScreenshot captures, downscales and encodes one window
+func (m *Manager) captureBatchScreenshot(window *config.WindowInfo, maxWidth int, format string) WindowScreenshot {
+	shot := WindowScreenshot{ID: window.ID, Class: window.Class}
+
+	img, err := m.captureScreenshotImage(window)
+	if err != nil {
+		shot.Error = err.Error()
+		return shot
+	}
+	defer framepool.Put(img)
+
+	scaled := img
+	if bounds := img.Bounds(); maxWidth > 0 && bounds.Dx() > maxWidth {
+		height := max(1, bounds.Dy()*maxWidth/bounds.Dx())
+		scaled = framepool.Get(maxWidth, height)
+		defer framepool.Put(scaled)
+		xdraw.ApproxBiLinear.Scale(scaled, scaled.Bounds(), img, bounds, xdraw.Src, nil)
+	}
+
+	var buf bytes.Buffer
+	if err := imgenc.Encode(&buf, scaled, format, previewQuality); err != nil {
+		shot.Error = fmt.Sprintf("failed to encode screenshot: %v", err)
+		return shot
+	}
+
+	shot.Width = scaled.Bounds().Dx()
+	shot.Height = scaled.Bounds().Dy()
+	shot.ContentType = imgenc.ContentType(format)
+	shot.Data = buf.Bytes()
+	return shot
+}
+
+// lookupScreenshot returns a batch screenshot taken within screenshotCacheTTL
+func (m *Manager) lookupScreenshot(key screenshotKey) (WindowScreenshot, bool) {
+	m.screenshotMu.Lock()
+	defer m.screenshotMu.Unlock()
+
+	entry, ok := m.screenshotCache[key]
+	if !ok || time.Since(entry.taken) > screenshotCacheTTL {
+		return WindowScreenshot{}, false
+	}
+	return entry.shot, true
+}
+
+// pruneScreenshotCache drops expired batch screenshots
+func (m *Manager) pruneScreenshotCache() {
+	m.screenshotMu.Lock()
+	defer m.screenshotMu.Unlock()
+
+	for key, entry := range m.screenshotCache {
+		if time.Since(entry.taken) > screenshotCacheTTL {
+			delete(m.screenshotCache, key)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package window
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// WindowMatch is a window returned by SearchWindows, with what the streamer
+// knows about capturing it
+type WindowMatch struct {
+	config.WindowInfo
+	Score            float64                `json:"score"` // Fuzzy match score, higher is better (0 without a query)
+	Capturable       bool                   `json:"capturable"`
+	Minimized        bool                   `json:"minimized"` // Exists but isn't viewable (minimized or unmapped)
+	OnCurrentDesktop bool                   `json:"on_current_desktop"`
+	Allowlisted      bool                   `json:"allowlisted"`
+	AllowlistSource  config.AllowlistSource `json:"allowlist_source"`
+	Shared           bool                   `json:"shared"`        // Currently shown on the stream
+	SelfExcluded     bool                   `json:"self_excluded"` // FocusStreamer's own window, never streamed
+}
+
+// SearchWindows returns the individual windows whose title or class fuzzy
+// matches query, best match first. An empty query returns every window,
+// focused first and then by class and title. Windows come from the window
+// registry.
+func (m *Manager) SearchWindows(query string) ([]WindowMatch, error) {
+	windows, err := m.registryWindows()
+	if err != nil {
+		return nil, err
+	}
+
+	currentDesktop := m.getBackend().GetCurrentDesktop()
+	sharedID := windowID(m.GetSharedWindow())
+	query = strings.TrimSpace(query)
+
+	matches := make([]WindowMatch, 0, len(windows))
+	for _, w := range windows {
+		score := 0.0
+		if query != "" {
+			if score = windowScore(query, w); score == 0 {
+				continue
+			}
+		}
+
+		capturable, minimized := m.captureFlags(w)
+		selfExcluded := m.IsSelfWindow(w)
+		source := m.GetWindowAllowlistSource(w)
+		matches = append(matches, WindowMatch{
+			WindowInfo:       *w,
+			Score:            score,
+			Capturable:       capturable && !selfExcluded,
+			Minimized:        minimized,
+			OnCurrentDesktop: w.Desktop == -1 || w.Desktop == currentDesktop,
+			Allowlisted:      source != config.AllowlistSourceNone,
+			AllowlistSource:  source,
+			Shared:           w.ID != 0 && w.ID == sharedID,
+			SelfExcluded:     selfExcluded,
+		})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Focused != b.Focused {
+			return a.Focused
+		}
+		if a.Class != b.Class {
+			return strings.ToLower(a.Class) < strings.ToLower(b.Class)
+		}
+		return strings.ToLower(a.Title) < strings.ToLower(b.Title)
+	})
+	return matches, nil
+}
+
+// windowScore scores a window against a query. Each word of the query must
+// match the class or title; class matches rank slightly above title matches.
+func windowScore(query string, w *config.WindowInfo) float64 {
+	score := func(q string) float64 {
+		return max(fuzzyScore(q, w.Class)*1.1, fuzzyScore(q, w.Title))
+	}
+
+	words := strings.Fields(query)
+	if len(words) == 1 {
+		return score(query)
+	}
+
+	// A multi-word query matching as a whole (e.g. a title phrase) beats
+	// its words matching separately
+	whole := score(query)
+	total := 0.0
+	for _, word := range words {
+		s := score(word)
+		if s == 0 {
+			return whole
+		}
+		total += s
+	}
+	return max(whole, 0.9*total/float64(len(words)))
+}
+
+// captureFlags reports whether a window can be captured right now, and
+// whether it exists but is minimized
+func (m *Manager) captureFlags(w *config.WindowInfo) (capturable, minimized bool) {
+	state := m.checkWindowState(w)
+	minimized = !w.IsNativeWayland && state == WindowStateValid
+	if minimized {
+		return false, true
+	}
+	if state == WindowStateCapturable {
+		return true, false
+	}
+	return m.captureRouter != nil && m.captureRouter.CanCapture(w), false
+}
+
+// fuzzyScore scores how well query matches text, case-insensitively, from 0
+// (no match) to 1 (exact match). From best to worst: exact, prefix, substring
+// at a word start, other substring, then an in-order subsequence ("ffx" in
+// "Firefox") scored by how tightly and early its letters match.
+func fuzzyScore(query, text string) float64 {
+	q := strings.ToLower(query)
+	t := strings.ToLower(text)
+	if q == "" || t == "" {
+		return 0
+	}
+
+	switch {
+	case t == q:
+		return 1
+	case strings.HasPrefix(t, q):
+		return 0.9
+	}
+
+	if i := strings.Index(t, q); i >= 0 {
+		if isWordStart(t, i) {
+			return 0.8
+		}
+		return 0.7
+	}
+
+	// Subsequence: every query rune in order
+	qr := []rune(q)
+	tr := []rune(t)
+	qi := 0
+	first, last := -1, -1
+	wordStarts := 0
+	for ti, r := range tr {
+		if qi < len(qr) && r == qr[qi] {
+			if first < 0 {
+				first = ti
+			}
+			last = ti
+			if ti == 0 || !isWordRune(tr[ti-1]) {
+				wordStarts++
+			}
+			qi++
+		}
+	}
+	if qi < len(qr) {
+		return 0
+	}
+
+	// Tight spans, early starts, and word-start hits score higher, staying
+	// below a substring match
+	span := float64(last - first + 1)
+	tightness := float64(len(qr)) / span
+	early := 1 / (1 + float64(first)/10)
+	boundary := float64(wordStarts) / float64(len(qr))
+	return 0.6 * (0.5*tightness + 0.25*early + 0.25*boundary)
+}
+
+// isWordStart reports whether byte offset i in s starts a word
+func isWordStart(s string, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := []rune(s[:i])
+	return !isWordRune(prev[len(prev)-1])
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,525 @@
+package window
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture"
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/framepool"
+	"github.com/bryanchriswhite/FocusStreamer/internal/ingest"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/internal/overlay"
+)
+
+// newStreamPipeline builds the stream's built-in stages:
+//
+//	select → capture → pii-guard → color → pointer → zoom → sharpen → overlays → telestrator → captions → stall-banner → output
+func (m *Manager) newStreamPipeline() *Pipeline {
+	return NewPipeline(
+		stageFunc{"select", StageSource, m.selectStage},
+		stageFunc{"capture", StageSource, m.captureStage},
+		stageFunc{"pii-guard", StagePolicy, m.piiStage},
+		stageFunc{"color", StageTransform, m.colorStage},
+		stageFunc{"pointer", StageTransform, m.pointerStage},
+		stageFunc{"zoom", StageTransform, m.zoomStage},
+		stageFunc{"sharpen", StageTransform, m.sharpenStage},
+		stageFunc{"overlays", StageOverlay, m.overlayStage},
+		stageFunc{"telestrator", StageOverlay, m.telestratorStage},
+		stageFunc{"captions", StageOverlay, m.captionStage},
+		stageFunc{"stall-banner", StageOverlay, m.stallBannerStage},
+		stageFunc{"output", StageSink, m.outputStage},
+	)
+}
+
+// AddFrameStage adds a stage to the stream pipeline, after the built-in
+// stages of its kind
+func (m *Manager) AddFrameStage(stage FrameStage) {
+	m.pipeline.Add(stage)
+}
+
+// GetPipelineStats returns the timing of each stream pipeline stage
+func (m *Manager) GetPipelineStats() []StageStats {
+	return m.pipeline.Stats()
+}
+
+// showPlaceholder switches a frame to the standby placeholder for the given
+// reason, rotating placeholders on the transition into standby
+func (m *Manager) showPlaceholder(f *Frame, reason StandbyReason) {
+	if !f.Standby && !f.state.wasInStandby {
+		m.rotatePlaceholder()
+	}
+	f.Standby = true
+	f.StandbyReason = reason
+	f.Window = nil
+
+	cfg := m.configMgr.Get()
+	f.Replace(m.standbyFrame(cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height, reason))
+}
+
+// selectStage applies panic, standby and the desktop rules, then picks the
+// window to stream. Panic and forced standby frames skip the transform and
+// overlay stages; forced standby draws its standby-only widgets and captions
+// here.
+func (m *Manager) selectStage(f *Frame) error {
+	if f.state.panicked {
+		cfg := m.configMgr.Get()
+		f.Standby = true
+		f.StandbyReason = StandbyPanic
+		f.Window = nil
+		f.Final = true
+		f.Replace(blackFrame(cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height))
+		return nil
+	}
+
+	f.desktop = m.getBackend().GetCurrentDesktop()
+	desktopBlocked := !m.configMgr.Get().Desktops.AllowsDesktop(f.desktop)
+
+	forceStandby := f.state.forceStandby
+	before, _ := m.state.update(func(st *streamState) { st.desktopBlocked = desktopBlocked })
+	desktopRuleChanged := desktopBlocked != before.desktopBlocked
+
+	if desktopRuleChanged {
+		logger.WithComponent("stream").Info().
+			Int("desktop", f.desktop+1).
+			Bool("streamed", !desktopBlocked).
+			Msg("Desktop streaming rule applied")
+	}
+
+	if forceStandby || desktopBlocked {
+		reason := StandbyManual
+		if !forceStandby {
+			reason = StandbyDesktop
+		}
+		m.showPlaceholder(f, reason)
+		f.Final = true
+		// The overlay stage is skipped; widgets shown only on standby (e.g.
+		// a "be right back" group) are still drawn
+		if m.overlayMgr != nil {
+			if err := m.overlayMgr.Render(f.Output(), overlay.State{Standby: true, Paused: true}); err != nil {
+				return err
+			}
+		}
+		drawActiveCaption(f.Image, &m.captions)
+		return nil
+	}
+
+	window, reason := m.selectSource(f.state, f.desktop)
+	watched := window
+	if ingest.IsExternal(window) {
+		watched = nil // Not a window of the backend
+	}
+	m.updateTitleWatch(watched)
+	m.updateGeometryWatch(watched)
+	m.updateCloseWatch(watched)
+	if window == nil {
+		m.showPlaceholder(f, reason)
+		return nil
+	}
+	f.Window = window
+	return nil
+}
+
+// selectWindow returns the focused window if it may be streamed, otherwise
+// the last allowed window while it stays valid, or nil for the placeholder
+// and the reason it is shown. st is the frame's state snapshot.
+func (m *Manager) selectWindow(st streamState, currentDesktop int) (*config.WindowInfo, StandbyReason) {
+	log := logger.WithComponent("stream")
+
+	m.mu.RLock()
+	currentWin := m.currentWindow
+	m.mu.RUnlock()
+
+	// Check if window is on current desktop
+	// Desktop -1 means window is on all desktops (sticky)
+	if currentWin != nil && currentWin.Desktop != -1 && currentWin.Desktop != currentDesktop {
+		log.Debug().
+			Int("window_desktop", currentWin.Desktop).
+			Int("current_desktop", currentDesktop).
+			Str("window_class", currentWin.Class).
+			Msg("Window not on current desktop, treating as unfocused")
+		currentWin = nil
+	}
+
+	bypassEnabled := st.allowlistBypass
+	lastAllowed := st.lastAllowedWindow
+	lastAllowedAt := st.lastAllowedAt
+
+	// Current window is allowlisted (or bypass is enabled) and isn't one of
+	// our own windows - use it and save as last allowed
+	if currentWin != nil && m.canStream(currentWin, bypassEnabled) {
+		m.state.update(func(st *streamState) {
+			st.lastAllowedWindow = currentWin
+			st.lastAllowedAt = time.Now()
+		})
+		return currentWin, StandbyNone
+	}
+
+	// A focused window that may not be streamed is the reason for any
+	// placeholder from here on
+	reason := StandbyNoWindow
+	if currentWin != nil {
+		reason = StandbyNotAllowlisted
+	}
+
+	// No allowlisted window yet - show placeholder
+	if lastAllowed == nil {
+		return nil, reason
+	}
+
+	// Same window (e.g., browser tab changed to non-matching title)
+	if currentWin != nil && lastAllowed.ID == currentWin.ID {
+		log.Debug().
+			Uint32("current_id", currentWin.ID).
+			Str("current_class", currentWin.Class).
+			Msg("Current window same as lastAllowed but no longer allowlisted")
+		m.clearLastAllowedWindow()
+		return nil, reason
+	}
+
+	// Don't keep streaming a window left unfocused for too long
+	if ttl := time.Duration(m.configMgr.Get().Capture.FallbackTTLSeconds) * time.Second; ttl > 0 && time.Since(lastAllowedAt) > ttl {
+		log.Info().
+			Uint32("window_id", lastAllowed.ID).
+			Str("window_class", lastAllowed.Class).
+			Dur("unfocused", time.Since(lastAllowedAt).Round(time.Second)).
+			Msg("Last allowed window out of focus too long, showing placeholder")
+		m.clearLastAllowedWindow()
+		return nil, reason
+	}
+
+	if window := m.fallbackWindow(lastAllowed, currentDesktop, bypassEnabled); window != nil {
+		return window, StandbyNone
+	}
+	return nil, reason
+}
+
+// fallbackWindow returns the last allowed window if it can still be shown
+// while no allowlisted window is focused. An existing window that can't be
+// captured right now (obscured/minimized) returns nil but stays the last
+// allowed window, for when it becomes capturable again.
+func (m *Manager) fallbackWindow(lastAllowed *config.WindowInfo, currentDesktop int, bypassEnabled bool) *config.WindowInfo {
+	log := logger.WithComponent("stream")
+
+	// Check window state in a single X11 call
+	state := m.checkWindowState(lastAllowed)
+
+	if state == WindowStateInvalid {
+		// Window ID might be stale - try to find window by class before giving up
+		refreshedWin, err := m.FindWindowByClass(lastAllowed.Class)
+		refreshedOnCurrentDesktop := refreshedWin != nil && (refreshedWin.Desktop == -1 || refreshedWin.Desktop == currentDesktop)
+		if err == nil && refreshedOnCurrentDesktop && m.canStream(refreshedWin, bypassEnabled) {
+			// Found the window by class on current desktop - try to capture it
+			// On Wayland, X11 state checks may fail but capture can still work via PipeWire
+			// Only log when window ID actually changes to avoid spam
+			if refreshedWin.ID != lastAllowed.ID {
+				log.Debug().
+					Uint32("old_window_id", lastAllowed.ID).
+					Uint32("new_window_id", refreshedWin.ID).
+					Str("window_class", lastAllowed.Class).
+					Msg("Recovered window by class with new ID")
+			}
+			m.state.update(func(st *streamState) { st.lastAllowedWindow = refreshedWin })
+			return refreshedWin
+		}
+
+		if err == nil && !refreshedOnCurrentDesktop {
+			log.Debug().
+				Uint32("window_id", refreshedWin.ID).
+				Str("window_class", refreshedWin.Class).
+				Int("window_desktop", refreshedWin.Desktop).
+				Int("current_desktop", currentDesktop).
+				Msg("Recovered window by class but not on current desktop")
+		} else {
+			log.Debug().
+				Uint32("window_id", lastAllowed.ID).
+				Str("window_class", lastAllowed.Class).
+				Msg("Last allowed window no longer valid (closed)")
+		}
+		m.clearLastAllowedWindow()
+		return nil
+	}
+
+	lastAllowedOnCurrentDesktop := lastAllowed.Desktop == -1 || lastAllowed.Desktop == currentDesktop
+	lastAllowedStillAllowlisted := m.canStream(lastAllowed, bypassEnabled)
+
+	if !lastAllowedOnCurrentDesktop || !lastAllowedStillAllowlisted {
+		log.Debug().
+			Uint32("window_id", lastAllowed.ID).
+			Str("window_class", lastAllowed.Class).
+			Bool("on_current_desktop", lastAllowedOnCurrentDesktop).
+			Bool("still_allowlisted", lastAllowedStillAllowlisted).
+			Int("window_desktop", lastAllowed.Desktop).
+			Int("current_desktop", currentDesktop).
+			Msg("Last allowed window no longer valid for fallback")
+		m.clearLastAllowedWindow()
+		return nil
+	}
+
+	if state != WindowStateCapturable {
+		log.Debug().
+			Uint32("window_id", lastAllowed.ID).
+			Str("window_class", lastAllowed.Class).
+			Int("state", int(state)).
+			Msg("Last allowed window not capturable (obscured/minimized)")
+		return nil
+	}
+	return lastAllowed
+}
+
+// captureStage captures the selected window, falling back to the
+// placeholder when capture fails
+func (m *Manager) captureStage(f *Frame) error {
+	if f.Standby {
+		return nil
+	}
+
+	start := time.Now()
+	img := m.captureWindowImage(f.Window)
+	if img != nil {
+		f.Timestamp = start
+		f.CaptureLatency = time.Since(start)
+		img = m.scaleAtSource(img, f.state.zoom)
+	}
+	// An external source repeating a picture isn't a stalled capture
+	if !ingest.IsExternal(f.Window) {
+		f.Stalled = m.checkCaptureWatchdog(f.Window.ID, img)
+	}
+
+	if img == nil {
+		// Track consecutive failures for health monitoring
+		m.healthMu.Lock()
+		m.consecutiveFailures++
+		failures := m.consecutiveFailures
+		m.healthMu.Unlock()
+
+		// Log warning at thresholds
+		if failures == 5 || failures%50 == 0 {
+			logger.WithComponent("stream").Warn().
+				Int("consecutive_failures", failures).
+				Str("window_class", f.Window.Class).
+				Uint32("window_id", f.Window.ID).
+				Msg("Consecutive capture failures - window may be closed or inaccessible")
+		}
+
+		m.clearLastAllowedWindow()
+		if failures >= captureErrorThreshold {
+			m.showCaptureError(f)
+		} else {
+			m.showPlaceholder(f, StandbyCaptureFailed)
+		}
+		return nil
+	}
+
+	// Reset consecutive failures on successful capture
+	m.healthMu.Lock()
+	m.consecutiveFailures = 0
+	m.healthMu.Unlock()
+
+	f.Replace(img)
+	return nil
+}
+
+// scaleAtSource downscales a captured window larger than the virtual display
+// to fit it, with capture.scale_at_source, so the later stages and the
+// outputs handle output-sized frames. While zoomed the full resolution is
+// kept, since the zoom crop is scaled up from it.
+func (m *Manager) scaleAtSource(img *image.RGBA, zoom ZoomState) *image.RGBA {
+	cfg := m.configMgr.Get()
+	if !cfg.Capture.ScaleAtSource || zoom.Scale > 1 {
+		return img
+	}
+	return capture.Downscale(img, cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height)
+}
+
+// captureWindowImage captures a window through the capture router, falling
+// back to direct X11 capture. It returns nil if both fail. Failures are
+// counted per capture backend.
+func (m *Manager) captureWindowImage(window *config.WindowInfo) *image.RGBA {
+	log := logger.WithComponent("stream")
+	var img *image.RGBA
+	var err error
+	tried := false
+
+	if ingest.IsExternal(window) {
+		img, err = m.external.CaptureWindow(window)
+		if err != nil {
+			m.captureFailures.record(ingest.WindowClass, err, time.Now())
+			log.Debug().Str("source", window.Title).Err(err).Msg("External source capture failed")
+		}
+		return img
+	}
+
+	// Try capture router first (supports both X11 and PipeWire)
+	if m.captureRouter != nil && m.captureRouter.CanCapture(window) {
+		tried = true
+		img, err = m.captureRouter.CaptureWindow(window)
+		if err != nil {
+			m.captureFailures.record(m.captureRouter.CapturerName(window), err, time.Now())
+			log.Debug().
+				Uint32("id", window.ID).
+				Str("class", window.Class).
+				Bool("native_wayland", window.IsNativeWayland).
+				Err(err).
+				Msg("Capture router failed, trying fallback")
+		}
+	}
+
+	// Fallback to direct X11 capture if router failed or unavailable
+	if x := m.x11Conn(); img == nil && !window.IsNativeWayland && x != nil {
+		tried = true
+		geom, err := xproto.GetGeometry(x.conn, xproto.Drawable(window.ID)).Reply()
+		if err != nil {
+			m.captureFailures.record(captureX11Direct, err, time.Now())
+			log.Debug().
+				Uint32("id", window.ID).
+				Str("class", window.Class).
+				Err(err).
+				Msg("Failed to get window geometry")
+			return nil
+		}
+		img, err = m.captureWindow(x, xproto.Window(window.ID), geom)
+		if err != nil {
+			m.captureFailures.record(captureX11Direct, err, time.Now())
+			log.Debug().
+				Uint32("id", window.ID).
+				Str("class", window.Class).
+				Err(err).
+				Msg("Direct X11 capture failed")
+		}
+	}
+
+	// Count windows no capturer could even try, e.g. native Wayland
+	// windows without PipeWire
+	if !tried {
+		m.captureFailures.record("none", fmt.Errorf("no capturer available for window"), time.Now())
+	}
+	return img
+}
+
+// TrackWindowCapture captures a window in the background fps times a
+// second, for layouts showing more than the focused window. Capture stops
+// with UntrackWindowCapture, or by itself once the window closes or may no
+// longer be streamed.
+func (m *Manager) TrackWindowCapture(window *config.WindowInfo, fps int) {
+	m.captureScheduler.Track(window, fps)
+}
+
+// UntrackWindowCapture stops capturing a window in the background
+func (m *Manager) UntrackWindowCapture(windowID uint32) {
+	m.captureScheduler.Untrack(windowID)
+}
+
+// LatestWindowFrame returns a copy of the newest background capture of a
+// tracked window, or nil if there is none, the window may no longer be
+// streamed, or the stream is panicked. The caller releases the copy with
+// framepool.Put.
+func (m *Manager) LatestWindowFrame(window *config.WindowInfo) *image.RGBA {
+	if m.IsPanicked() || !m.canStream(window, m.GetAllowlistBypass()) {
+		return nil
+	}
+	img, _, ok := m.captureScheduler.Latest(window.ID)
+	if !ok {
+		return nil
+	}
+	return img
+}
+
+// captureWorkerFrame captures a tracked window for the capture scheduler,
+// refusing windows that may not be streamed
+func (m *Manager) captureWorkerFrame(window *config.WindowInfo) (*image.RGBA, error) {
+	if !m.canStream(window, m.GetAllowlistBypass()) {
+		return nil, fmt.Errorf("window %s may not be streamed", window.Class)
+	}
+	img := m.captureWindowImage(window)
+	if img == nil {
+		return nil, fmt.Errorf("failed to capture window %s", window.Class)
+	}
+	cfg := m.configMgr.Get()
+	if cfg.Capture.ScaleAtSource {
+		img = capture.Downscale(img, cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height)
+	}
+	return img, nil
+}
+
+// piiStage blanks the stream while the PII guard sees sensitive text on screen
+func (m *Manager) piiStage(f *Frame) error {
+	if !f.Standby && m.isPIIBlanked(f.Image) {
+		m.showPlaceholder(f, StandbySensitiveContent)
+	}
+	return nil
+}
+
+// colorStage converts captured frames to sRGB if color management is
+// enabled, then applies the stream filters
+func (m *Manager) colorStage(f *Frame) error {
+	if !f.Standby {
+		m.applyColorManagement(f.Image)
+		m.applyStreamFilters(f.Image)
+	}
+	return nil
+}
+
+// zoomStage keeps the unzoomed frame for the minimap thumbnail, then applies
+// the zoom/pan state
+func (m *Manager) zoomStage(f *Frame) error {
+	f.Retain()
+	m.unzoomedFrameMu.Lock()
+	prevUnzoomed := m.lastUnzoomedFrame
+	m.lastUnzoomedFrame = f.Image
+	m.unzoomedFrameMu.Unlock()
+	if prevUnzoomed != f.Image {
+		framepool.Put(prevUnzoomed)
+	}
+
+	zoomed, content := m.applyZoom(f.Image, f.state.zoom)
+	f.Replace(zoomed)
+	f.Content = content
+	return nil
+}
+
+// sharpenStage runs the zoomed or unzoomed frame through an unsharp mask
+// with stream_filters.sharpen, after all scaling and before the overlays
+// and encoding
+func (m *Manager) sharpenStage(f *Frame) error {
+	amount := m.configMgr.Get().StreamFilters.Sharpen
+	if f.Standby || amount <= 0 {
+		return nil
+	}
+	bounds := f.Image.Bounds()
+	dst := framepool.Get(bounds.Dx(), bounds.Dy())
+	capture.Sharpen(dst, f.Image, amount)
+	f.Replace(dst)
+	return nil
+}
+
+// overlayStage renders the overlay widgets whose rules pass for the frame
+func (m *Manager) overlayStage(f *Frame) error {
+	if m.overlayMgr == nil {
+		return nil
+	}
+	return m.overlayMgr.Render(f.Output(), overlay.State{
+		Standby: f.Standby,
+		Zoomed:  f.state.zoom.Scale > 1,
+	})
+}
+
+// stallBannerStage marks frames whose capture has stalled
+func (m *Manager) stallBannerStage(f *Frame) error {
+	if f.Stalled {
+		drawStallBanner(f.Image)
+	}
+	return nil
+}
+
+// outputStage sends the frame to the output at native resolution; the
+// browser scales it to fit the viewport. Outputs don't retain frames.
+func (m *Manager) outputStage(f *Frame) error {
+	m.recordFrame(f)
+	if m.output == nil {
+		return nil
+	}
+	return m.output.WriteFrame(f.Output())
+}
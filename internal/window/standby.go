@@ -0,0 +1,81 @@
+package window
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// StandbyReason says why the stream shows the placeholder (or a black
+// frame, when panicked)
+type StandbyReason string
+
+// Standby reasons reported by GetStandbyReason
+const (
+	StandbyNone             StandbyReason = ""                  // Streaming a window
+	StandbyManual           StandbyReason = "manual"            // Forced standby (API, hotkey, tray or scene)
+	StandbyDesktop          StandbyReason = "desktop"           // The current virtual desktop isn't streamed
+	StandbyPanic            StandbyReason = "panic"             // Panic button; the stream is black
+	StandbyNoWindow         StandbyReason = "no_window"         // No allowlisted window is focused or can be shown
+	StandbyNotAllowlisted   StandbyReason = "not_allowlisted"   // The focused window may not be streamed
+	StandbyCaptureFailed    StandbyReason = "capture_failed"    // Capturing the window failed
+	StandbySensitiveContent StandbyReason = "sensitive_content" // The PII guard blanked the stream
+)
+
+// standbyCaptions are shown to viewers on the placeholder. They don't name
+// the focused window, since it wasn't meant to be shared.
+var standbyCaptions = map[StandbyReason]string{
+	StandbyManual:           "Stream paused",
+	StandbyDesktop:          "Stream paused on this desktop",
+	StandbyNoWindow:         "No shared window open",
+	StandbyNotAllowlisted:   "A window that isn't shared is in focus",
+	StandbyCaptureFailed:    "Capture failed - retrying",
+	StandbySensitiveContent: "Hidden while sensitive content is on screen",
+}
+
+// Caption returns the text shown to viewers for the reason, empty if none
+func (r StandbyReason) Caption() string {
+	return standbyCaptions[r]
+}
+
+// GetStandbyReason returns why the last frame showed the placeholder, or
+// StandbyNone if it showed a window
+func (m *Manager) GetStandbyReason() StandbyReason {
+	return m.state.snapshot().standbyReason
+}
+
+// drawStandbyCaption draws a standby caption centered above the viewer
+// stats line, on a dark band so it stays readable on custom placeholder
+// images
+func drawStandbyCaption(img *image.RGBA, text string, textColor color.RGBA) {
+	if text == "" {
+		return
+	}
+	bounds := img.Bounds()
+
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(textColor),
+		Face: basicfont.Face7x13,
+	}
+	textWidth := d.MeasureString(text).Ceil()
+
+	// Leave room for the stats band below
+	bandHeight := 13 + 2*standbyStatsPadding
+	bottom := bounds.Max.Y - bandHeight - 2*standbyStatsPadding
+	band := image.Rect(
+		bounds.Min.X+(bounds.Dx()-textWidth)/2-2*standbyStatsPadding, bottom-bandHeight,
+		bounds.Min.X+(bounds.Dx()+textWidth)/2+2*standbyStatsPadding, bottom,
+	)
+	draw.Draw(img, band, &image.Uniform{color.RGBA{20, 20, 30, 255}}, image.Point{}, draw.Src)
+
+	d.Dot = fixed.Point26_6{
+		X: fixed.I(band.Min.X + 2*standbyStatsPadding),
+		Y: fixed.I(bottom - standbyStatsPadding - 3), // Baseline sits above the font's descent
+	}
+	d.DrawString(text)
+}
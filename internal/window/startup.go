@@ -0,0 +1,138 @@
+package window
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// startupPollInterval is how often RunStartupActions looks for the startup
+// window while waiting for it to open
+const startupPollInterval = time.Second
+
+// RunStartupActions applies the startup section of the config: it activates
+// the profile, sets the zoom, and focuses the first window matching the
+// window pattern, waiting up to window_wait_seconds for one to open. Call it
+// after Start; it blocks while waiting for the window, until ctx is done or
+// the manager stops. A missing profile or window is logged and the
+// remaining actions still run.
+func (m *Manager) RunStartupActions(ctx context.Context, startup config.StartupConfig) {
+	log := logger.WithComponent("startup")
+
+	if startup.Profile != "" {
+		if profileID, ok := m.findProfile(startup.Profile); !ok {
+			log.Warn().Str("profile", startup.Profile).Msg("Startup profile not found")
+		} else if err := m.configMgr.SetActiveProfile(profileID); err != nil {
+			log.Warn().Err(err).Str("profile", startup.Profile).Msg("Failed to activate startup profile")
+		} else {
+			m.OnProfileChanged(profileID)
+		}
+	}
+
+	if startup.ZoomScale > 0 {
+		zoom := ZoomState{Scale: startup.ZoomScale, OffsetX: startup.ZoomX, OffsetY: startup.ZoomY}
+		if zoom.OffsetX == 0 {
+			zoom.OffsetX = 0.5
+		}
+		if zoom.OffsetY == 0 {
+			zoom.OffsetY = 0.5
+		}
+		zoom = m.SetZoomState(zoom)
+		log.Info().
+			Float64("scale", zoom.Scale).
+			Float64("offset_x", zoom.OffsetX).
+			Float64("offset_y", zoom.OffsetY).
+			Msg("Startup zoom set")
+	}
+
+	if startup.Window != "" {
+		m.focusStartupWindow(ctx, startup.Window, time.Duration(startup.WindowWaitSeconds)*time.Second)
+	}
+}
+
+// findProfile resolves a profile by ID, or else by name ignoring case
+func (m *Manager) findProfile(idOrName string) (string, bool) {
+	profiles := m.configMgr.ListProfiles()
+	for _, p := range profiles {
+		if p.ID == idOrName {
+			return p.ID, true
+		}
+	}
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, idOrName) {
+			return p.ID, true
+		}
+	}
+	return "", false
+}
+
+// focusStartupWindow activates the first window whose class or title
+// matches pattern, polling until one opens, wait elapses, or the manager
+// stops
+func (m *Manager) focusStartupWindow(ctx context.Context, pattern string, wait time.Duration) {
+	log := logger.WithComponent("startup")
+
+	if !m.CanActivateWindows() {
+		log.Warn().Str("backend", m.getBackend().Name()).Msg("Backend can't focus windows, skipping startup window")
+		return
+	}
+
+	re, err := config.CompilePattern(pattern)
+	if err != nil {
+		log.Warn().Err(err).Msg("Invalid startup window pattern")
+		return
+	}
+
+	deadline := time.Now().Add(wait)
+	for {
+		if window := m.findMatchingWindow(re); window != nil {
+			if err := m.ActivateWindow(window.ID); err != nil {
+				log.Warn().Err(err).Str("class", window.Class).Msg("Failed to focus startup window")
+				return
+			}
+			if !m.IsWindowAllowlisted(window) {
+				log.Warn().
+					Str("class", window.Class).
+					Str("title", window.Title).
+					Msg("Startup window focused but isn't allowlisted; the placeholder is shown instead")
+			}
+			return
+		}
+
+		if !time.Now().Before(deadline) {
+			log.Warn().Str("pattern", pattern).Dur("waited", wait).Msg("No window matches the startup window pattern")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.ctx.Done():
+			return
+		case <-time.After(startupPollInterval):
+		}
+	}
+}
+
+// findMatchingWindow returns the first open window, other than our own,
+// whose class or title matches re
+func (m *Manager) findMatchingWindow(re *regexp.Regexp) *config.WindowInfo {
+	windows, err := m.registryWindows()
+	if err != nil {
+		logger.WithComponent("startup").Debug().Err(err).Msg("Failed to list windows")
+		return nil
+	}
+	for _, window := range windows {
+		if m.IsSelfWindow(window) {
+			continue
+		}
+		if re.MatchString(window.Class) || re.MatchString(window.Title) {
+			return window
+		}
+	}
+	return nil
+}
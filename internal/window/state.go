@@ -0,0 +1,114 @@
+package window
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// streamState is the stream's mutable control state: the switches the API,
+// hotkeys and scenes flip while the stream runs, the zoom, the placeholder
+// selection, the window to fall back to, and what the last frame showed.
+// It is a plain value; window pointers in it are replaced, never modified.
+type streamState struct {
+	forceStandby    bool
+	allowlistBypass bool // All windows are shown regardless of the allowlist
+	desktopBlocked  bool // The current desktop isn't streamed (see DesktopRulesConfig)
+
+	// Privacy panic: black frames until re-armed (see Panic)
+	panicked      bool
+	panicOverlays bool // Whether overlays were enabled before the panic
+
+	zoom ZoomState
+
+	// Selected placeholder (-1 = default), and a generation bumped to make
+	// the stream draw it again (see placeholderCache)
+	placeholderIdx int
+	placeholderGen uint64
+
+	lastAllowedWindow *config.WindowInfo // Last allowlisted window to stream
+	lastAllowedAt     time.Time          // When lastAllowedWindow last had focus
+
+	// What the last frame showed: the placeholder and why, or a window
+	wasInStandby  bool
+	standbyReason StandbyReason
+	sharedWindow  *config.WindowInfo
+}
+
+// stateStore holds the streamState behind one lock. Writers change it
+// through update; the stream loop takes a snapshot when a frame starts and
+// every stage decides from that copy, so API calls landing mid-frame take
+// effect on the next frame instead of tearing this one. The lock is a leaf:
+// update functions never take another lock or call out.
+type stateStore struct {
+	mu    sync.RWMutex
+	state streamState
+}
+
+// newStateStore returns a store holding the initial state
+func newStateStore(initial streamState) *stateStore {
+	return &stateStore{state: initial}
+}
+
+// snapshot returns a copy of the current state
+func (s *stateStore) snapshot() streamState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// update changes the state with fn under the lock and returns the state
+// before and after the change
+func (s *stateStore) update(fn func(st *streamState)) (before, after streamState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	before = s.state
+	fn(&s.state)
+	return before, s.state
+}
+
+// forgetWindow drops a window that closed or may no longer be shown as the
+// last allowed window
+func (st *streamState) forgetWindow(windowID uint32) {
+	if st.lastAllowedWindow != nil && st.lastAllowedWindow.ID == windowID {
+		st.lastAllowedWindow = nil
+	}
+}
+
+// placeholderCache keeps the last placeholder drawn, so the stream doesn't
+// decode or draw it again every frame. It is keyed by everything the
+// placeholder is drawn from; invalidating it means bumping
+// streamState.placeholderGen.
+type placeholderCache struct {
+	mu  sync.Mutex
+	img *image.RGBA
+	key placeholderKey
+}
+
+// placeholderKey identifies a drawn placeholder
+type placeholderKey struct {
+	gen   uint64
+	path  string // Custom image, empty for the default placeholder
+	size  image.Point
+	theme config.PlaceholderConfig // Theme the default placeholder was drawn with
+	next  string                   // Next stream line drawn on it
+}
+
+// get returns the cached placeholder drawn for key, or nil
+func (c *placeholderCache) get(key placeholderKey) *image.RGBA {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.img == nil || c.key != key {
+		return nil
+	}
+	return c.img
+}
+
+// put caches a placeholder drawn for key
+func (c *placeholderCache) put(key placeholderKey, img *image.RGBA) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.img, c.key = img, key
+}
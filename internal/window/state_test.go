@@ -0,0 +1,149 @@
+package window
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+// TestStateStoreSnapshotsAreConsistent checks that a snapshot never sees
+// half of an update: every update writes the same value to several fields
+func TestStateStoreSnapshotsAreConsistent(t *testing.T) {
+	store := newStateStore(streamState{})
+	const writers, updates = 4, 2000
+
+	var wg sync.WaitGroup
+	for range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range updates {
+				store.update(func(st *streamState) {
+					st.placeholderGen++
+					n := float64(st.placeholderGen)
+					st.zoom = ZoomState{Scale: n, OffsetX: n, OffsetY: n}
+					st.forceStandby = st.placeholderGen%2 == 1
+				})
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	for range 4 {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				st := store.snapshot()
+				n := float64(st.placeholderGen)
+				if st.zoom != (ZoomState{Scale: n, OffsetX: n, OffsetY: n}) || st.forceStandby != (st.placeholderGen%2 == 1) {
+					t.Errorf("torn snapshot: gen %d, zoom %+v, standby %v", st.placeholderGen, st.zoom, st.forceStandby)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	if got := store.snapshot().placeholderGen; got != writers*updates {
+		t.Errorf("placeholderGen = %d after %d updates", got, writers*updates)
+	}
+}
+
+// newTestManager returns a manager on the synthetic backend with a config
+// in a temporary directory and a small stream, so frames are quick under
+// the race detector
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	configMgr, err := config.NewManager(filepath.Join(t.TempDir(), "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := *configMgr.Get()
+	cfg.VirtualDisplay.Width, cfg.VirtualDisplay.Height = 320, 180
+	if err := configMgr.Update(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManagerWithBackend(configMgr, BackendSynthetic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// TestControlChangesDuringFrames runs the stream loop's per-frame path
+// while zoom, standby and placeholder changes arrive from other goroutines,
+// as API calls do; run with -race
+func TestControlChangesDuringFrames(t *testing.T) {
+	m := newTestManager(t)
+	const frames, changes = 100, 300
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range frames {
+			m.captureAndStream()
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range changes {
+			scale := 1 + float64(i%8)
+			m.SetZoomState(ZoomState{Scale: scale, OffsetX: 0.1 * float64(i%10), OffsetY: 0.5})
+		}
+	}()
+
+	toggles := 0
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range changes {
+			m.ToggleForceStandby()
+			toggles++
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range changes {
+			m.CyclePlaceholder(1 - 2*(i%2))
+			_ = m.GetZoomState()
+			_ = m.GetStandbyReason()
+		}
+	}()
+	wg.Wait()
+
+	st := m.state.snapshot()
+	if st.forceStandby != (toggles%2 == 1) {
+		t.Errorf("forceStandby = %v after %d toggles", st.forceStandby, toggles)
+	}
+	if st.zoom.Scale < 1 || st.zoom.Scale > 4 {
+		t.Errorf("zoom scale %v outside 1-4", st.zoom.Scale)
+	}
+	if st.placeholderGen == 0 {
+		t.Error("placeholder changes didn't bump placeholderGen")
+	}
+
+	// The next frame follows the final state
+	m.captureAndStream()
+	if got := m.GetStandbyReason() == StandbyManual; got != st.forceStandby {
+		t.Errorf("frame after the changes showed forced standby = %v, want %v", got, st.forceStandby)
+	}
+}
@@ -0,0 +1,298 @@
+package window
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/composite"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture"
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture/pipewire"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+	"github.com/bryanchriswhite/FocusStreamer/pkg/events"
+)
+
+const (
+	// supervisorInterval is how often connections are checked
+	supervisorInterval = 2 * time.Second
+
+	// maxReconnectBackoff caps the delay between reconnect attempts while
+	// the display server is still coming back (e.g. during resume)
+	maxReconnectBackoff = 30 * time.Second
+)
+
+// ConnectionStatus reports display server connection supervision for
+// /api/health
+type ConnectionStatus struct {
+	Connected     bool      `json:"connected"`
+	Reconnects    int       `json:"reconnects"` // Successful reconnects since start
+	LastLost      time.Time `json:"last_lost,omitempty"`
+	LastReconnect time.Time `json:"last_reconnect,omitempty"`
+	LastError     string    `json:"last_error,omitempty"` // Most recent failed attempt
+}
+
+// x11Conn is an X connection and the screen state derived from it
+type x11Conn struct {
+	conn             *xgb.Conn
+	root             xproto.Window
+	screen           *xproto.ScreenInfo
+	compositeEnabled bool
+	lost             <-chan struct{}
+}
+
+// connectX11 opens an X connection for direct screenshot capture
+func connectX11() (*x11Conn, error) {
+	log := logger.WithComponent("window-manager")
+
+	conn, err := xgb.NewConn()
+	if err != nil {
+		return nil, err
+	}
+
+	screen := xproto.Setup(conn).DefaultScreen(conn)
+	x := &x11Conn{
+		conn:   conn,
+		root:   screen.Root,
+		screen: screen,
+	}
+
+	// Initialize composite extension
+	if err := composite.Init(conn); err != nil {
+		log.Warn().
+			Err(err).
+			Msg("Composite extension not available - window screenshots may fail for obscured or off-screen windows")
+	} else {
+		x.compositeEnabled = true
+		log.Info().Msg("Composite extension initialized successfully")
+	}
+
+	x.lost = capture.MonitorX11Conn(conn, nil)
+	return x, nil
+}
+
+// isLost reports whether the X server connection has died
+func (x *x11Conn) isLost() bool {
+	select {
+	case <-x.lost:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes the connection unless it already died
+func (x *x11Conn) close() {
+	if x != nil && !x.isLost() {
+		x.conn.Close()
+	}
+}
+
+// x11Conn returns the live X connection, or nil if there is none
+func (m *Manager) x11Conn() *x11Conn {
+	m.x11Mu.RLock()
+	defer m.x11Mu.RUnlock()
+
+	if m.x11 == nil || m.x11.isLost() {
+		return nil
+	}
+	return m.x11
+}
+
+// GetConnectionStatus returns the display server connection state
+func (m *Manager) GetConnectionStatus() ConnectionStatus {
+	m.connMu.RLock()
+	defer m.connMu.RUnlock()
+	return m.connStatus
+}
+
+// GetPortalSession returns the state of the screen share portal session.
+// The PipeWire capturer reopens it by itself when the compositor closes it;
+// lostConnection only sees it once that failed.
+func (m *Manager) GetPortalSession() pipewire.SessionEvent {
+	return pipewire.CurrentSession()
+}
+
+// SubscribePortalSession returns a channel receiving portal session state
+// changes. Call UnsubscribePortalSession when done.
+func (m *Manager) SubscribePortalSession() chan pipewire.SessionEvent {
+	return pipewire.SubscribeSession()
+}
+
+// UnsubscribePortalSession stops a channel from SubscribePortalSession
+func (m *Manager) UnsubscribePortalSession(ch chan pipewire.SessionEvent) {
+	pipewire.UnsubscribeSession(ch)
+}
+
+// GetCaptureMonitors returns the monitors shared through the screen share
+// portal, with the PipeWire node and desktop area of each
+func (m *Manager) GetCaptureMonitors() []pipewire.Monitor {
+	if m.captureRouter == nil {
+		return nil
+	}
+	return m.captureRouter.PortalMonitors()
+}
+
+// superviseConnections watches the backend, X, and capture connections and
+// re-establishes them after they are lost, e.g. when the X server or portal
+// session dies across suspend/resume or a display hotplug
+func (m *Manager) superviseConnections(ctx context.Context) {
+	log := logger.WithComponent("supervisor")
+	ticker := time.NewTicker(supervisorInterval)
+	defer ticker.Stop()
+
+	var backoff time.Duration
+	var nextAttempt time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reason := m.lostConnection()
+		if reason == "" {
+			continue
+		}
+
+		now := time.Now()
+		m.connMu.Lock()
+		justLost := m.connStatus.Connected
+		if justLost {
+			m.connStatus.Connected = false
+			m.connStatus.LastLost = now
+			log.Warn().Str("lost", reason).Msg("Display connection lost, reconnecting")
+		}
+		m.connMu.Unlock()
+		if justLost {
+			m.publishCaptureError(events.CaptureErrorSource_CAPTURE_ERROR_SOURCE_DISPLAY, "Lost connection to "+reason, nil)
+		}
+
+		if now.Before(nextAttempt) {
+			continue
+		}
+
+		if err := m.reconnect(); err != nil {
+			backoff = min(max(backoff*2, supervisorInterval), maxReconnectBackoff)
+			nextAttempt = now.Add(backoff)
+
+			m.connMu.Lock()
+			m.connStatus.LastError = err.Error()
+			m.connMu.Unlock()
+
+			log.Warn().Err(err).Dur("retry_in", backoff).Msg("Reconnect failed")
+			m.publishCaptureError(events.CaptureErrorSource_CAPTURE_ERROR_SOURCE_DISPLAY, err.Error(), nil)
+			continue
+		}
+
+		backoff = 0
+		nextAttempt = time.Time{}
+
+		m.connMu.Lock()
+		m.connStatus.Connected = true
+		m.connStatus.Reconnects++
+		m.connStatus.LastReconnect = time.Now()
+		m.connStatus.LastError = ""
+		m.connMu.Unlock()
+
+		log.Info().Str("backend", m.getBackend().Name()).Msg("Display connection re-established")
+	}
+}
+
+// lostConnection returns what has lost its connection, or "" if all is well
+func (m *Manager) lostConnection() string {
+	if backendLost(m.getBackend()) {
+		return "window backend"
+	}
+	if m.useX11 && m.x11Conn() == nil {
+		return "X server"
+	}
+	if m.captureRouter != nil && m.captureRouter.SessionLost() {
+		return "capture session"
+	}
+	return ""
+}
+
+// reconnect replaces whatever lost its connection. Each step is skipped if
+// it is already healthy, so a failed attempt can simply be retried.
+func (m *Manager) reconnect() error {
+	log := logger.WithComponent("supervisor")
+
+	if old := m.getBackend(); backendLost(old) {
+		fresh, err := newBackend(m.backendName)
+		if err != nil {
+			return fmt.Errorf("failed to reconnect window backend: %w", err)
+		}
+		if err := m.watchFocus(fresh); err != nil {
+			fresh.Close()
+			return err
+		}
+
+		m.backendMu.Lock()
+		m.backend = fresh
+		m.backendMu.Unlock()
+		old.Close()
+		m.watchWindows(fresh)
+
+		log.Info().Str("backend", fresh.Name()).Msg("Window backend reconnected")
+	}
+
+	if m.useX11 && m.x11Conn() == nil {
+		x, err := connectX11()
+		if err != nil {
+			return fmt.Errorf("failed to reconnect to X server: %w", err)
+		}
+
+		m.x11Mu.Lock()
+		old := m.x11
+		m.x11 = x
+		m.x11Mu.Unlock()
+		old.close()
+
+		log.Info().Msg("X connection for screenshots reconnected")
+	}
+
+	if m.captureRouter != nil && m.captureRouter.SessionLost() {
+		if err := m.captureRouter.Restart(); err != nil {
+			return fmt.Errorf("failed to restart capture: %w", err)
+		}
+	}
+
+	m.republishState()
+	return nil
+}
+
+// republishState re-reads the focused window after a reconnect, pushes it to
+// listeners (WebSocket clients), and renders a fresh frame
+func (m *Manager) republishState() {
+	info, err := m.getBackend().GetFocusedWindow()
+	if err != nil {
+		logger.WithComponent("supervisor").Debug().Err(err).Msg("Failed to get focused window after reconnect")
+		m.requestFrame()
+		return
+	}
+
+	m.mu.Lock()
+	m.currentWindow = info
+	m.mu.Unlock()
+
+	m.notifyListeners(info)
+	m.requestFrame()
+}
+
+// backendLost reports whether a backend's connection is known to be lost
+func backendLost(backend Backend) bool {
+	monitor, ok := backend.(ConnectionMonitor)
+	if !ok {
+		return false
+	}
+
+	select {
+	case <-monitor.Disconnected():
+		return true
+	default:
+		return false
+	}
+}
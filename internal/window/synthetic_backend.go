@@ -0,0 +1,218 @@
+package window
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// syntheticFocusInterval is how long each fake window stays focused
+const syntheticFocusInterval = 5 * time.Second
+
+// SyntheticBackend implements the Backend interface with a fixed set of fake
+// windows whose focus rotates on a timer. It needs no display server, which
+// makes it useful for demos (evaluating overlays and outputs without sharing
+// a real window) and deterministic testing.
+type SyntheticBackend struct {
+	mu            sync.RWMutex
+	windows       []*config.WindowInfo
+	focusedIdx    int
+	focusInterval time.Duration
+	stopChan      chan struct{}
+	watching      bool
+	callback      func(*config.WindowInfo)
+}
+
+// NewSyntheticBackend creates a synthetic backend with a default set of windows
+func NewSyntheticBackend() *SyntheticBackend {
+	windows := []*config.WindowInfo{
+		{ID: 1, Title: "Terminal - synthetic", Class: "synthetic-terminal", PID: 1001},
+		{ID: 2, Title: "Code Editor - synthetic", Class: "synthetic-editor", PID: 1002},
+		{ID: 3, Title: "Web Browser - synthetic", Class: "synthetic-browser", PID: 1003},
+		{ID: 4, Title: "Test Card - synthetic", Class: "synthetic-testcard", PID: 1004},
+	}
+	for i, w := range windows {
+		w.Geometry = config.Geometry{X: 40 * i, Y: 30 * i, Width: 1280, Height: 720}
+		w.Desktop = 0
+	}
+	windows[0].Focused = true
+
+	return &SyntheticBackend{
+		windows:       windows,
+		focusInterval: syntheticFocusInterval,
+		stopChan:      make(chan struct{}),
+	}
+}
+
+// Connect is a no-op for the synthetic backend
+func (b *SyntheticBackend) Connect() error {
+	return nil
+}
+
+// Close stops watching
+func (b *SyntheticBackend) Close() error {
+	b.StopWatching()
+	return nil
+}
+
+// Name returns the backend name
+func (b *SyntheticBackend) Name() string {
+	return "synthetic"
+}
+
+// Capabilities reports the synthetic features; its fake windows never
+// change, so there are no title, geometry or close events to push
+func (b *SyntheticBackend) Capabilities() Capabilities {
+	return Capabilities{
+		AccurateGeometry: true,
+		NativeCapture:    true,
+		Activation:       true,
+		FocusEvents:      true,
+		WindowEvents:     true,
+		Pointer:          true,
+	}
+}
+
+// ListWindows returns copies of all fake windows
+func (b *SyntheticBackend) ListWindows() ([]*config.WindowInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	windows := make([]*config.WindowInfo, 0, len(b.windows))
+	for _, w := range b.windows {
+		info := *w
+		windows = append(windows, &info)
+	}
+	return windows, nil
+}
+
+// GetFocusedWindow returns a copy of the currently focused fake window
+func (b *SyntheticBackend) GetFocusedWindow() (*config.WindowInfo, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.windows) == 0 {
+		return nil, fmt.Errorf("no synthetic windows")
+	}
+	info := *b.windows[b.focusedIdx]
+	return &info, nil
+}
+
+// GetCurrentDesktop always returns desktop 0
+func (b *SyntheticBackend) GetCurrentDesktop() int {
+	return 0
+}
+
+// WatchFocus rotates focus through the fake windows on a timer
+func (b *SyntheticBackend) WatchFocus(callback func(*config.WindowInfo)) error {
+	b.mu.Lock()
+	if b.watching {
+		b.mu.Unlock()
+		return fmt.Errorf("already watching")
+	}
+	b.watching = true
+	b.callback = callback
+	b.stopChan = make(chan struct{})
+	stopChan := b.stopChan
+	interval := b.focusInterval
+	b.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ticker.C:
+				info := b.rotateFocus()
+				logger.WithComponent("synthetic-backend").Debug().
+					Uint32("id", info.ID).
+					Str("class", info.Class).
+					Msg("Synthetic focus changed")
+				callback(info)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// rotateFocus moves focus to the next fake window and returns a copy of it
+func (b *SyntheticBackend) rotateFocus() *config.WindowInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.windows[b.focusedIdx].Focused = false
+	b.focusedIdx = (b.focusedIdx + 1) % len(b.windows)
+	b.windows[b.focusedIdx].Focused = true
+
+	info := *b.windows[b.focusedIdx]
+	return &info
+}
+
+// ActivateWindow focuses a fake window and reports it immediately; rotation
+// continues from it on the next tick
+func (b *SyntheticBackend) ActivateWindow(windowID uint32) error {
+	b.mu.Lock()
+	idx := -1
+	for i, w := range b.windows {
+		if w.ID == windowID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		b.mu.Unlock()
+		return fmt.Errorf("no synthetic window with ID %d", windowID)
+	}
+
+	b.windows[b.focusedIdx].Focused = false
+	b.focusedIdx = idx
+	b.windows[idx].Focused = true
+	info := *b.windows[idx]
+	callback := b.callback
+	if !b.watching {
+		callback = nil
+	}
+	b.mu.Unlock()
+
+	if callback != nil {
+		callback(&info)
+	}
+	return nil
+}
+
+// PointerPosition moves a fake pointer in a figure eight over the window,
+// clicking for a moment every three seconds, so pointer highlighting can be
+// tried without a display server
+func (b *SyntheticBackend) PointerPosition(window *config.WindowInfo) (int, int, bool, error) {
+	t := float64(time.Now().UnixMilli()%12000) / 12000 * 2 * math.Pi
+	x := float64(window.Geometry.Width) * (0.5 + 0.35*math.Sin(t))
+	y := float64(window.Geometry.Height) * (0.5 + 0.3*math.Sin(2*t))
+	pressed := time.Now().UnixMilli()%3000 < 150
+	return int(x), int(y), pressed, nil
+}
+
+// WatchWindows accepts a window event subscription. The fake windows never
+// open, close or change, so no events are sent; focus changes are reported
+// by WatchFocus.
+func (b *SyntheticBackend) WatchWindows(callback func(WindowEvent)) error {
+	return nil
+}
+
+// StopWatching stops the focus rotation loop
+func (b *SyntheticBackend) StopWatching() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.watching {
+		close(b.stopChan)
+		b.watching = false
+	}
+}
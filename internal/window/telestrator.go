@@ -0,0 +1,212 @@
+package window
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+const (
+	// strokeHold is how long a stroke stays fully visible
+	strokeHold = 3 * time.Second
+
+	// strokeFade is how long a stroke then takes to fade out
+	strokeFade = time.Second
+
+	// maxStrokes bounds the strokes on the stream; the oldest are dropped
+	// first
+	maxStrokes = 500
+
+	// maxStrokePoints bounds the points of one stroke
+	maxStrokePoints = 2000
+
+	// maxStrokeWidth bounds a stroke's width, in pixels of a 720p frame
+	maxStrokeWidth = 40
+
+	// defaultStrokeWidth applies when a stroke is posted without a width
+	defaultStrokeWidth = 4
+)
+
+// StrokePoint is a point of a stroke, as a fraction of the frame's width
+// and height (0-1), so strokes land in the same place whatever the stream
+// resolution
+type StrokePoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+// Stroke is a line drawn over the stream from the control page
+type Stroke struct {
+	Points []StrokePoint `json:"points"`
+	Color  string        `json:"color,omitempty"` // Hex, e.g. "#ff3b30"; red by default
+	Width  float64       `json:"width,omitempty"` // Pixels at 720p, scaled with the frame
+}
+
+// telestratorStroke is a stroke on the stream with its parsed color
+type telestratorStroke struct {
+	Stroke
+	rgba  color.RGBA
+	added time.Time
+}
+
+// telestrator keeps the strokes drawn over the stream until they fade out
+type telestrator struct {
+	mu      sync.Mutex
+	strokes []telestratorStroke
+}
+
+// add validates a stroke and puts it on the stream
+func (t *telestrator) add(s Stroke, now time.Time) error {
+	if len(s.Points) == 0 {
+		return fmt.Errorf("stroke has no points")
+	}
+	if len(s.Points) > maxStrokePoints {
+		return fmt.Errorf("stroke has %d points, at most %d are allowed", len(s.Points), maxStrokePoints)
+	}
+	for _, p := range s.Points {
+		if p.X < 0 || p.X > 1 || p.Y < 0 || p.Y > 1 || math.IsNaN(p.X) || math.IsNaN(p.Y) {
+			return fmt.Errorf("stroke points must be between 0 and 1")
+		}
+	}
+	if s.Width == 0 {
+		s.Width = defaultStrokeWidth
+	}
+	if s.Width < 1 || s.Width > maxStrokeWidth {
+		return fmt.Errorf("stroke width must be between 1 and %d", maxStrokeWidth)
+	}
+	if s.Color == "" {
+		s.Color = "#ff3b30"
+	}
+	rgba, err := config.ParseHexColor(s.Color)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.strokes) >= maxStrokes {
+		t.strokes = append(t.strokes[:0], t.strokes[1:]...)
+	}
+	t.strokes = append(t.strokes, telestratorStroke{Stroke: s, rgba: rgba, added: now})
+	return nil
+}
+
+// clear removes every stroke
+func (t *telestrator) clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.strokes = nil
+}
+
+// visible drops the strokes that have faded out and returns the rest with
+// their opacity at now
+func (t *telestrator) visible(now time.Time) ([]telestratorStroke, []float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	kept := t.strokes[:0]
+	var alphas []float64
+	for _, s := range t.strokes {
+		age := now.Sub(s.added)
+		if age >= strokeHold+strokeFade {
+			continue
+		}
+		alpha := 1.0
+		if age > strokeHold {
+			alpha = 1 - float64(age-strokeHold)/float64(strokeFade)
+		}
+		kept = append(kept, s)
+		alphas = append(alphas, alpha)
+	}
+	t.strokes = kept
+	return append([]telestratorStroke{}, kept...), alphas
+}
+
+// AddStroke draws a stroke over the stream; it stays for a few seconds,
+// then fades out
+func (m *Manager) AddStroke(s Stroke) error {
+	if err := m.telestrator.add(s, time.Now()); err != nil {
+		return err
+	}
+	m.requestFrame()
+	return nil
+}
+
+// ClearStrokes removes every stroke from the stream
+func (m *Manager) ClearStrokes() {
+	m.telestrator.clear()
+	logger.WithComponent("annotations").Debug().Msg("Strokes cleared")
+	m.requestFrame()
+}
+
+// telestratorStage draws the strokes posted from the control page
+func (m *Manager) telestratorStage(f *Frame) error {
+	strokes, alphas := m.telestrator.visible(time.Now())
+	for i, s := range strokes {
+		drawStroke(f.Image, s, alphas[i])
+	}
+	return nil
+}
+
+// drawStroke draws a stroke as round-capped line segments. The stroke is
+// rasterized into a coverage mask first so it fades evenly where its
+// segments overlap.
+func drawStroke(img *image.RGBA, s telestratorStroke, alpha float64) {
+	bounds := img.Bounds()
+	fw, fh := float64(bounds.Dx()), float64(bounds.Dy())
+	radius := max(0.5, s.Width*fh/720/2)
+
+	pts := make([][2]float64, len(s.Points))
+	area := image.Rectangle{}
+	for i, p := range s.Points {
+		x := float64(bounds.Min.X) + p.X*fw
+		y := float64(bounds.Min.Y) + p.Y*fh
+		pts[i] = [2]float64{x, y}
+		r := int(math.Ceil(radius)) + 1
+		area = area.Union(image.Rect(int(x)-r, int(y)-r, int(x)+r+1, int(y)+r+1))
+	}
+	area = area.Intersect(bounds)
+	if area.Empty() {
+		return
+	}
+
+	mask := image.NewAlpha(area)
+	stamp := func(cx, cy float64) {
+		r := int(math.Ceil(radius)) + 1
+		for y := max(int(cy)-r, area.Min.Y); y <= min(int(cy)+r, area.Max.Y-1); y++ {
+			for x := max(int(cx)-r, area.Min.X); x <= min(int(cx)+r, area.Max.X-1); x++ {
+				d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+				cov := radius + 0.5 - d
+				if cov <= 0 {
+					continue
+				}
+				a := uint8(min(1, cov) * 255)
+				i := mask.PixOffset(x, y)
+				mask.Pix[i] = max(mask.Pix[i], a)
+			}
+		}
+	}
+
+	stamp(pts[0][0], pts[0][1])
+	step := max(0.5, radius/2)
+	for i := 1; i < len(pts); i++ {
+		x0, y0 := pts[i-1][0], pts[i-1][1]
+		dx, dy := pts[i][0]-x0, pts[i][1]-y0
+		n := int(math.Ceil(math.Hypot(dx, dy) / step))
+		for j := 1; j <= n; j++ {
+			t := float64(j) / float64(n)
+			stamp(x0+dx*t, y0+dy*t)
+		}
+	}
+
+	c := color.NRGBA{s.rgba.R, s.rgba.G, s.rgba.B, uint8(alpha * 255)}
+	draw.DrawMask(img, area, image.NewUniform(c), image.Point{}, mask, area.Min, draw.Over)
+}
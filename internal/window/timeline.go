@@ -0,0 +1,156 @@
+package window
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+)
+
+const (
+	// timelineGap is how long frames may stop (e.g. capture suspended with
+	// no viewers) before the timeline shows a gap instead of stretching the
+	// current segment over it
+	timelineGap = 5 * time.Second
+
+	// maxTimelineSegments bounds the timeline; the oldest segments are
+	// dropped first
+	maxTimelineSegments = 5000
+)
+
+// TimelineSegment is a stretch of the session in which the stream showed
+// one application, or the placeholder for one reason
+type TimelineSegment struct {
+	Start   time.Time     `json:"start"`
+	End     time.Time     `json:"end"`
+	Class   string        `json:"class,omitempty"` // Shared application, empty on standby
+	Title   string        `json:"title,omitempty"` // Latest title of its window
+	Standby bool          `json:"standby"`
+	Reason  StandbyReason `json:"reason,omitempty"` // Why the placeholder was shown
+}
+
+// Duration returns how long the segment lasted
+func (s TimelineSegment) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// TimelineTotal is how long the stream showed one application, or the
+// placeholder (Standby) in total
+type TimelineTotal struct {
+	Class   string  `json:"class,omitempty"`
+	Standby bool    `json:"standby"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Timeline is what the stream showed since the server started. Time
+// without frames (no viewers, stream stopped) is in no segment.
+type Timeline struct {
+	Started  time.Time         `json:"started"`
+	Now      time.Time         `json:"now"`
+	Segments []TimelineSegment `json:"segments"`
+	Totals   []TimelineTotal   `json:"totals"` // Longest first
+}
+
+// timelineRecorder builds the timeline from the streamed frames
+type timelineRecorder struct {
+	mu       sync.Mutex
+	started  time.Time
+	segments []TimelineSegment
+}
+
+func newTimelineRecorder(started time.Time) *timelineRecorder {
+	return &timelineRecorder{started: started}
+}
+
+// record extends the current segment with a frame, or starts a new one
+// when what is shown changed or frames stopped for a while
+func (t *timelineRecorder) record(now time.Time, standby bool, reason StandbyReason, window *config.WindowInfo) {
+	next := TimelineSegment{Start: now, End: now, Standby: standby, Reason: reason}
+	if !standby && window != nil {
+		next.Class = window.Class
+		next.Title = window.Title
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if n := len(t.segments); n > 0 {
+		last := &t.segments[n-1]
+		sameContent := last.Standby == next.Standby && last.Reason == next.Reason && last.Class == next.Class
+		if sameContent && now.Sub(last.End) <= timelineGap {
+			last.End = now
+			if next.Title != "" {
+				last.Title = next.Title
+			}
+			return
+		}
+		// Close the previous segment at this frame unless frames stopped
+		if now.Sub(last.End) <= timelineGap {
+			last.End = now
+		}
+	}
+
+	if len(t.segments) >= maxTimelineSegments {
+		t.segments = append(t.segments[:0], t.segments[1:]...)
+	}
+	t.segments = append(t.segments, next)
+}
+
+// snapshot returns the timeline, with per-application totals
+func (t *timelineRecorder) snapshot(now time.Time) Timeline {
+	t.mu.Lock()
+	segments := make([]TimelineSegment, len(t.segments))
+	copy(segments, t.segments)
+	t.mu.Unlock()
+
+	type key struct {
+		class   string
+		standby bool
+	}
+	totals := make(map[key]time.Duration)
+	for _, s := range segments {
+		totals[key{s.Class, s.Standby}] += s.Duration()
+	}
+
+	timeline := Timeline{
+		Started:  t.started,
+		Now:      now,
+		Segments: segments,
+		Totals:   make([]TimelineTotal, 0, len(totals)),
+	}
+	for k, d := range totals {
+		timeline.Totals = append(timeline.Totals, TimelineTotal{Class: k.class, Standby: k.standby, Seconds: d.Seconds()})
+	}
+	sort.Slice(timeline.Totals, func(i, j int) bool {
+		return timeline.Totals[i].Seconds > timeline.Totals[j].Seconds
+	})
+	return timeline
+}
+
+// since returns the segments that ended after the given time, oldest first
+func (t *timelineRecorder) since(after time.Time) []TimelineSegment {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	i := len(t.segments)
+	for i > 0 && t.segments[i-1].End.After(after) {
+		i--
+	}
+	segments := make([]TimelineSegment, len(t.segments)-i)
+	copy(segments, t.segments[i:])
+	return segments
+}
+
+// GetTimeline returns what the stream showed since the server started:
+// segments per shared application and standby period, and the total time
+// of each
+func (m *Manager) GetTimeline() Timeline {
+	return m.timeline.snapshot(time.Now())
+}
+
+// TimelineSince returns the timeline segments that ended after t, oldest
+// first, including the one still being streamed
+func (m *Manager) TimelineSince(t time.Time) []TimelineSegment {
+	return m.timeline.since(t)
+}
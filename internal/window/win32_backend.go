@@ -0,0 +1,335 @@
+//go:build windows
+
+package window
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"github.com/bryanchriswhite/FocusStreamer/internal/config"
+	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
+)
+
+// win32FocusPollInterval is how often the foreground window is checked
+const win32FocusPollInterval = 250 * time.Millisecond
+
+const (
+	gwlExStyle              = ^uintptr(19) // -20
+	gwOwner                 = 4
+	wsExToolWindow          = 0x80
+	swRestore               = 9
+	dwmwaExtendedFrameBound = 9
+	dwmwaCloaked            = 14
+	processQueryLimited     = 0x1000
+
+	// DPI_AWARENESS_CONTEXT_PER_MONITOR_AWARE_V2, so window bounds are in
+	// the physical pixels DXGI captures
+	dpiAwarenessPerMonitorV2 = ^uintptr(3)
+)
+
+var (
+	user32                            = syscall.NewLazyDLL("user32.dll")
+	procEnumWindows                   = user32.NewProc("EnumWindows")
+	procGetForegroundWindow           = user32.NewProc("GetForegroundWindow")
+	procSetForegroundWindow           = user32.NewProc("SetForegroundWindow")
+	procShowWindow                    = user32.NewProc("ShowWindow")
+	procIsIconic                      = user32.NewProc("IsIconic")
+	procIsWindowVisible               = user32.NewProc("IsWindowVisible")
+	procGetWindow                     = user32.NewProc("GetWindow")
+	procGetWindowLongPtrW             = user32.NewProc("GetWindowLongPtrW")
+	procGetWindowTextW                = user32.NewProc("GetWindowTextW")
+	procGetWindowTextLengthW          = user32.NewProc("GetWindowTextLengthW")
+	procGetWindowRect                 = user32.NewProc("GetWindowRect")
+	procGetWindowThreadProcessId      = user32.NewProc("GetWindowThreadProcessId")
+	procSetProcessDpiAwarenessContext = user32.NewProc("SetProcessDpiAwarenessContext")
+	dwmapi                            = syscall.NewLazyDLL("dwmapi.dll")
+	procDwmGetWindowAttribute         = dwmapi.NewProc("DwmGetWindowAttribute")
+	kernel32                          = syscall.NewLazyDLL("kernel32.dll")
+	procQueryFullProcessImageNameW    = kernel32.NewProc("QueryFullProcessImageNameW")
+)
+
+// EnumWindows collects handles through a single callback, since Go can
+// only create a limited number of them
+var (
+	enumMu       sync.Mutex
+	enumHandles  []uintptr
+	enumCallback = syscall.NewCallback(func(hwnd, _ uintptr) uintptr {
+		enumHandles = append(enumHandles, hwnd)
+		return 1 // Continue enumerating
+	})
+)
+
+// win32Rect is RECT
+type win32Rect struct {
+	Left, Top, Right, Bottom int32
+}
+
+// Win32Backend implements the Backend interface on Windows with the Win32
+// window APIs. Window IDs are HWNDs, which fit in 32 bits; the class is the
+// program's executable name without ".exe" (e.g. "firefox"), which is what
+// allowlists match.
+type Win32Backend struct {
+	mu            sync.Mutex
+	watching      bool
+	stopChan      chan struct{}
+	currentWindow *config.WindowInfo
+}
+
+// NewWin32Backend creates a Win32 window backend
+func NewWin32Backend() (Backend, error) {
+	if err := procEnumWindows.Find(); err != nil {
+		return nil, fmt.Errorf("user32 not available: %w", err)
+	}
+	b := &Win32Backend{}
+	if err := b.Connect(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Connect opts into per-monitor DPI awareness so window bounds aren't
+// scaled. Older Windows versions without it keep working at 100% scale.
+func (b *Win32Backend) Connect() error {
+	if procSetProcessDpiAwarenessContext.Find() == nil {
+		procSetProcessDpiAwarenessContext.Call(dpiAwarenessPerMonitorV2)
+	}
+	return nil
+}
+
+// Close stops watching
+func (b *Win32Backend) Close() error {
+	b.StopWatching()
+	return nil
+}
+
+// Name returns the backend name
+func (b *Win32Backend) Name() string {
+	return BackendWindows
+}
+
+// Capabilities reports the Win32 features. Focus is polled and windows are
+// cropped out of their monitor by DXGI desktop duplication.
+func (b *Win32Backend) Capabilities() Capabilities {
+	return Capabilities{
+		AccurateGeometry: true,
+		Activation:       true,
+	}
+}
+
+// ListWindows returns the visible top-level application windows, front to
+// back
+func (b *Win32Backend) ListWindows() ([]*config.WindowInfo, error) {
+	enumMu.Lock()
+	enumHandles = nil
+	r, _, err := procEnumWindows.Call(enumCallback, 0)
+	handles := enumHandles
+	enumMu.Unlock()
+	if r == 0 {
+		return nil, fmt.Errorf("failed to enumerate windows: %w", err)
+	}
+
+	foreground, _, _ := procGetForegroundWindow.Call()
+	windows := make([]*config.WindowInfo, 0, len(handles))
+	for _, hwnd := range handles {
+		if !isAppWindow(hwnd) {
+			continue
+		}
+		info := windowInfo(hwnd)
+		info.Focused = hwnd == foreground
+		windows = append(windows, info)
+	}
+	return windows, nil
+}
+
+// GetFocusedWindow returns the foreground window
+func (b *Win32Backend) GetFocusedWindow() (*config.WindowInfo, error) {
+	hwnd, _, _ := procGetForegroundWindow.Call()
+	if hwnd == 0 {
+		return nil, fmt.Errorf("no foreground window")
+	}
+	info := windowInfo(hwnd)
+	info.Focused = true
+	return info, nil
+}
+
+// GetCurrentDesktop always returns desktop 0. Windows virtual desktops
+// hide other desktops' windows from the enumeration (they are cloaked).
+func (b *Win32Backend) GetCurrentDesktop() int {
+	return 0
+}
+
+// WatchFocus polls the foreground window, reporting focus, title and
+// geometry changes
+func (b *Win32Backend) WatchFocus(callback func(*config.WindowInfo)) error {
+	b.mu.Lock()
+	if b.watching {
+		b.mu.Unlock()
+		return fmt.Errorf("already watching")
+	}
+	b.watching = true
+	b.stopChan = make(chan struct{})
+	stopChan := b.stopChan
+	b.mu.Unlock()
+
+	go b.watchFocusLoop(stopChan, callback)
+	return nil
+}
+
+// watchFocusLoop reports foreground window changes until stopped
+func (b *Win32Backend) watchFocusLoop(stopChan chan struct{}, callback func(*config.WindowInfo)) {
+	log := logger.WithComponent("win32-backend")
+	ticker := time.NewTicker(win32FocusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopChan:
+			return
+		case <-ticker.C:
+		}
+
+		info, err := b.GetFocusedWindow()
+		if err != nil {
+			log.Debug().Err(err).Msg("Failed to get focused window")
+			continue
+		}
+
+		b.mu.Lock()
+		changed := b.currentWindow == nil ||
+			b.currentWindow.ID != info.ID ||
+			b.currentWindow.Title != info.Title ||
+			b.currentWindow.Geometry != info.Geometry
+		if changed {
+			b.currentWindow = info
+		}
+		b.mu.Unlock()
+
+		if changed {
+			callback(info)
+		}
+	}
+}
+
+// ActivateWindow restores a minimized window and brings it to the
+// foreground. Windows only lets the foreground process steal focus, so this
+// may just flash the window's taskbar button.
+func (b *Win32Backend) ActivateWindow(windowID uint32) error {
+	hwnd := uintptr(windowID)
+	if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+		return fmt.Errorf("no visible window with ID %d", windowID)
+	}
+	if iconic, _, _ := procIsIconic.Call(hwnd); iconic != 0 {
+		procShowWindow.Call(hwnd, swRestore)
+	}
+	if r, _, _ := procSetForegroundWindow.Call(hwnd); r == 0 {
+		return fmt.Errorf("window %d could not be brought to the foreground", windowID)
+	}
+	return nil
+}
+
+// StopWatching stops the focus polling loop
+func (b *Win32Backend) StopWatching() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.watching {
+		close(b.stopChan)
+		b.watching = false
+	}
+}
+
+// isAppWindow reports whether a window shows up in the taskbar: visible,
+// titled, unowned, not a tool window and not cloaked (on another virtual
+// desktop, or a suspended UWP app)
+func isAppWindow(hwnd uintptr) bool {
+	if visible, _, _ := procIsWindowVisible.Call(hwnd); visible == 0 {
+		return false
+	}
+	if length, _, _ := procGetWindowTextLengthW.Call(hwnd); length == 0 {
+		return false
+	}
+	if owner, _, _ := procGetWindow.Call(hwnd, gwOwner); owner != 0 {
+		return false
+	}
+	if exStyle, _, _ := procGetWindowLongPtrW.Call(hwnd, gwlExStyle); exStyle&wsExToolWindow != 0 {
+		return false
+	}
+	var cloaked uint32
+	if procDwmGetWindowAttribute.Find() == nil {
+		r, _, _ := procDwmGetWindowAttribute.Call(hwnd, dwmwaCloaked, uintptr(unsafe.Pointer(&cloaked)), unsafe.Sizeof(cloaked))
+		if r == 0 && cloaked != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// windowInfo describes a window
+func windowInfo(hwnd uintptr) *config.WindowInfo {
+	var pid uint32
+	procGetWindowThreadProcessId.Call(hwnd, uintptr(unsafe.Pointer(&pid)))
+
+	return &config.WindowInfo{
+		ID:       uint32(hwnd),
+		Title:    windowTitle(hwnd),
+		Class:    processName(pid),
+		PID:      int(pid),
+		Geometry: windowBounds(hwnd),
+		Desktop:  0,
+	}
+}
+
+// windowTitle returns a window's title
+func windowTitle(hwnd uintptr) string {
+	length, _, _ := procGetWindowTextLengthW.Call(hwnd)
+	if length == 0 {
+		return ""
+	}
+	buf := make([]uint16, length+1)
+	procGetWindowTextW.Call(hwnd, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	return syscall.UTF16ToString(buf)
+}
+
+// windowBounds returns a window's visible bounds. GetWindowRect includes
+// the invisible resize borders of Windows 10 and later, so the DWM frame
+// bounds are preferred.
+func windowBounds(hwnd uintptr) config.Geometry {
+	var rect win32Rect
+	found := false
+	if procDwmGetWindowAttribute.Find() == nil {
+		r, _, _ := procDwmGetWindowAttribute.Call(hwnd, dwmwaExtendedFrameBound, uintptr(unsafe.Pointer(&rect)), unsafe.Sizeof(rect))
+		found = r == 0
+	}
+	if !found {
+		procGetWindowRect.Call(hwnd, uintptr(unsafe.Pointer(&rect)))
+	}
+	return config.Geometry{
+		X:      int(rect.Left),
+		Y:      int(rect.Top),
+		Width:  int(rect.Right - rect.Left),
+		Height: int(rect.Bottom - rect.Top),
+	}
+}
+
+// processName returns a process's executable name without its extension,
+// or an empty string if the process can't be queried
+func processName(pid uint32) string {
+	process, err := syscall.OpenProcess(processQueryLimited, false, pid)
+	if err != nil {
+		return ""
+	}
+	defer syscall.CloseHandle(process)
+
+	buf := make([]uint16, syscall.MAX_PATH)
+	size := uint32(len(buf))
+	if r, _, _ := procQueryFullProcessImageNameW.Call(uintptr(process), 0, uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&size))); r == 0 {
+		return ""
+	}
+	name := filepath.Base(syscall.UTF16ToString(buf[:size]))
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
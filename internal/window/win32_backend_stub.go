@@ -0,0 +1,10 @@
+//go:build !windows
+
+package window
+
+import "fmt"
+
+// NewWin32Backend reports that the Win32 backend only exists on Windows
+func NewWin32Backend() (Backend, error) {
+	return nil, fmt.Errorf("the %s backend is only available on Windows", BackendWindows)
+}
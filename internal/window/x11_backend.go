@@ -8,6 +8,7 @@ import (
 
 	"github.com/BurntSushi/xgb"
 	"github.com/BurntSushi/xgb/xproto"
+	"github.com/bryanchriswhite/FocusStreamer/internal/capture"
 	"github.com/bryanchriswhite/FocusStreamer/internal/config"
 	"github.com/bryanchriswhite/FocusStreamer/internal/logger"
 )
@@ -25,6 +26,19 @@ type X11Backend struct {
 	currentDesktopAtom xproto.Atom
 	// Channel for desktop change events to trigger immediate focus check
 	desktopChangeChan chan struct{}
+	// Title change subscription (see WatchTitle)
+	titleWindow   xproto.Window
+	titleCallback func(windowID uint32, title string)
+	netWmNameAtom xproto.Atom
+	// Geometry change subscription (see WatchGeometry)
+	geometryWindow   xproto.Window
+	geometryCallback func(windowID uint32, geometry config.Geometry)
+	// Close subscription (see WatchClose)
+	closeWindow   xproto.Window
+	closeCallback func(windowID uint32)
+	// Events read from the connection, and a channel closed when it dies
+	events chan xgb.Event
+	lost   <-chan struct{}
 }
 
 // NewX11Backend creates a new X11 backend
@@ -45,12 +59,15 @@ func NewX11Backend() (*X11Backend, error) {
 		currentDesktopAtom = atomReply.Atom
 	}
 
+	events := make(chan xgb.Event, 64)
 	return &X11Backend{
 		conn:               conn,
 		root:               root,
 		screen:             screen,
 		stopChan:           make(chan struct{}),
 		currentDesktopAtom: currentDesktopAtom,
+		events:             events,
+		lost:               capture.MonitorX11Conn(conn, events),
 	}, nil
 }
 
@@ -63,15 +80,48 @@ func (b *X11Backend) Connect() error {
 // Close closes the X11 connection
 func (b *X11Backend) Close() error {
 	b.StopWatching()
-	b.conn.Close()
+	if b.checkConn() == nil {
+		b.conn.Close()
+	}
 	return nil
 }
 
+// Disconnected returns a channel that is closed when the X connection is lost
+func (b *X11Backend) Disconnected() <-chan struct{} {
+	return b.lost
+}
+
+// checkConn returns an error once the X connection is lost. xgb panics on
+// requests to a dead connection, so public methods check before using it.
+func (b *X11Backend) checkConn() error {
+	select {
+	case <-b.lost:
+		return fmt.Errorf("X server connection lost")
+	default:
+		return nil
+	}
+}
+
 // Name returns the backend name
 func (b *X11Backend) Name() string {
 	return "x11"
 }
 
+// Capabilities reports the X11 features. Geometry is relative to the
+// window manager's frame, which doesn't matter for per-window capture.
+func (b *X11Backend) Capabilities() Capabilities {
+	return Capabilities{
+		Desktops:       true,
+		NativeCapture:  true,
+		Activation:     true,
+		FocusEvents:    true,
+		TitleEvents:    true,
+		GeometryEvents: true,
+		CloseEvents:    true,
+		Pointer:        true,
+	}
+}
+
 // GetConn returns the X11 connection (needed by Manager for screenshots)
 func (b *X11Backend) GetConn() *xgb.Conn {
 	return b.conn
@@ -91,6 +141,10 @@ func (b *X11Backend) GetScreen() *xproto.ScreenInfo {
 func (b *X11Backend) ListWindows() ([]*config.WindowInfo, error) {
 	log := logger.WithComponent("x11-backend")
 
+	if err := b.checkConn(); err != nil {
+		return nil, err
+	}
+
 	// Try EWMH _NET_CLIENT_LIST first (preferred method)
 	windows, err := b.listWindowsEWMH()
 	if err == nil && len(windows) > 0 {
@@ -231,6 +285,10 @@ func (b *X11Backend) listWindowsQueryTree() ([]*config.WindowInfo, error) {
 
 // GetFocusedWindow returns the currently focused window
 func (b *X11Backend) GetFocusedWindow() (*config.WindowInfo, error) {
+	if err := b.checkConn(); err != nil {
+		return nil, err
+	}
+
 	focusReply, err := xproto.GetInputFocus(b.conn).Reply()
 	if err != nil {
 		return nil, err
@@ -243,6 +301,10 @@ func (b *X11Backend) GetFocusedWindow() (*config.WindowInfo, error) {
 func (b *X11Backend) WatchFocus(callback func(*config.WindowInfo)) error {
 	log := logger.WithComponent("x11-backend")
 
+	if err := b.checkConn(); err != nil {
+		return err
+	}
+
 	b.mu.Lock()
 	if b.watching {
 		b.mu.Unlock()
@@ -264,48 +326,56 @@ func (b *X11Backend) WatchFocus(callback func(*config.WindowInfo)) error {
 		return fmt.Errorf("failed to set event mask: %w", err)
 	}
 
-	// Start goroutine to listen for X11 property change events
-	if b.currentDesktopAtom != 0 {
-		go b.watchDesktopEvents()
-		log.Debug().Msg("Started watching for desktop change events")
-	}
+	// Start goroutine to listen for X11 property change events (desktop
+	// switches on the root window, title and geometry changes on the
+	// watched windows)
+	go b.watchEvents()
+	log.Debug().Msg("Started watching for desktop, title and geometry change events")
 
 	go b.watchFocusLoop(callback)
 	return nil
 }
 
-// watchDesktopEvents listens for X11 PropertyNotify events on the root window
-func (b *X11Backend) watchDesktopEvents() {
+// watchEvents listens for X11 PropertyNotify, ConfigureNotify and
+// DestroyNotify events
+func (b *X11Backend) watchEvents() {
 	log := logger.WithComponent("x11-backend")
 
 	for {
+		var ev xgb.Event
 		select {
 		case <-b.stopChan:
 			return
-		default:
+		case <-b.lost:
+			log.Warn().Msg("X server connection lost, stopped watching events")
+			return
+		case ev = <-b.events:
 		}
 
-		// Poll for events with a short timeout to allow checking stopChan
-		ev, err := b.conn.PollForEvent()
-		if err != nil {
-			// Connection error - exit
-			log.Debug().Err(err).Msg("X11 event poll error")
-			return
+		if configureNotify, ok := ev.(xproto.ConfigureNotifyEvent); ok {
+			b.handleGeometryEvent(configureNotify)
+			continue
+		}
+		if destroyNotify, ok := ev.(xproto.DestroyNotifyEvent); ok {
+			b.handleDestroyEvent(destroyNotify)
+			continue
 		}
 
-		if ev == nil {
-			// No event available, sleep briefly
-			time.Sleep(50 * time.Millisecond)
+		propNotify, ok := ev.(xproto.PropertyNotifyEvent)
+		if !ok {
 			continue
 		}
 
-		// Check if this is a PropertyNotify for _NET_CURRENT_DESKTOP
-		if propNotify, ok := ev.(xproto.PropertyNotifyEvent); ok {
-			if propNotify.Atom == b.currentDesktopAtom {
+		// _NET_CURRENT_DESKTOP on the root window
+		if propNotify.Window == b.root {
+			if b.currentDesktopAtom != 0 && propNotify.Atom == b.currentDesktopAtom {
 				log.Debug().Msg("Desktop switched via X11, triggering focus re-evaluation")
 				b.triggerDesktopChange()
 			}
+			continue
 		}
+
+		b.handleTitleEvent(propNotify)
 	}
 }
 
@@ -359,6 +429,8 @@ func (b *X11Backend) watchFocusLoop(callback func(*config.WindowInfo)) {
 		select {
 		case <-b.stopChan:
 			return
+		case <-b.lost:
+			return
 		case <-b.desktopChangeChan:
 			// Desktop switched - immediate focus re-evaluation
 			log.Debug().Msg("Processing desktop change event")
@@ -383,7 +455,7 @@ func (b *X11Backend) StopWatching() {
 
 // GetCurrentDesktop returns the current virtual desktop number
 func (b *X11Backend) GetCurrentDesktop() int {
-	if b.currentDesktopAtom == 0 {
+	if b.currentDesktopAtom == 0 || b.checkConn() != nil {
 		return 0
 	}
 
@@ -446,33 +518,12 @@ func (b *X11Backend) getWindowInfo(win xproto.Window) (*config.WindowInfo, error
 	}
 
 	// Get window geometry
-	geom, err := xproto.GetGeometry(b.conn, xproto.Drawable(win)).Reply()
-	if err == nil {
-		info.Geometry = config.Geometry{
-			X:      int(geom.X),
-			Y:      int(geom.Y),
-			Width:  int(geom.Width),
-			Height: int(geom.Height),
-		}
+	if geom, err := b.getWindowGeometry(win); err == nil {
+		info.Geometry = geom
 	}
 
 	// Get window title
-	titleAtom, err := b.getAtom("_NET_WM_NAME")
-	if err == nil {
-		if title, err := b.getProperty(win, titleAtom); err == nil {
-			info.Title = title
-		}
-	}
-
-	// Try alternative title property
-	if info.Title == "" {
-		titleAtom, err = b.getAtom("WM_NAME")
-		if err == nil {
-			if title, err := b.getProperty(win, titleAtom); err == nil {
-				info.Title = title
-			}
-		}
-	}
+	info.Title = b.getWindowTitle(win)
 
 	// Get window class
 	// WM_CLASS format is: instance\0class\0 (two null-terminated strings)
@@ -515,8 +566,251 @@ func (b *X11Backend) getWindowInfo(win xproto.Window) (*config.WindowInfo, error
 	return info, nil
 }
 
+// getWindowGeometry returns the geometry of a window
+func (b *X11Backend) getWindowGeometry(win xproto.Window) (config.Geometry, error) {
+	geom, err := xproto.GetGeometry(b.conn, xproto.Drawable(win)).Reply()
+	if err != nil {
+		return config.Geometry{}, err
+	}
+	return config.Geometry{
+		X:      int(geom.X),
+		Y:      int(geom.Y),
+		Width:  int(geom.Width),
+		Height: int(geom.Height),
+	}, nil
+}
+
+// getWindowTitle returns _NET_WM_NAME, falling back to WM_NAME
+func (b *X11Backend) getWindowTitle(win xproto.Window) string {
+	for _, name := range []string{"_NET_WM_NAME", "WM_NAME"} {
+		atom, err := b.getAtom(name)
+		if err != nil {
+			continue
+		}
+		// Some clients include the terminating NUL
+		if title, err := b.getProperty(win, atom); err == nil && strings.TrimRight(title, "\x00") != "" {
+			return strings.TrimRight(title, "\x00")
+		}
+	}
+	return ""
+}
+
+// WatchTitle subscribes to PropertyNotify on a window so title changes are
+// reported immediately, replacing any previous subscription (0 unsubscribes).
+// Events are delivered by the WatchFocus event loop.
+func (b *X11Backend) WatchTitle(windowID uint32, callback func(windowID uint32, title string)) error {
+	if err := b.checkConn(); err != nil {
+		return err
+	}
+
+	netWmName, _ := b.getAtom("_NET_WM_NAME")
+
+	b.mu.Lock()
+	prev := b.titleWindow
+	b.titleWindow = xproto.Window(windowID)
+	b.titleCallback = callback
+	b.netWmNameAtom = netWmName
+	b.mu.Unlock()
+
+	if prev != 0 && prev != xproto.Window(windowID) {
+		b.selectWindowEvents(prev, false)
+	}
+	if windowID == 0 {
+		return nil
+	}
+
+	if err := b.selectWindowEvents(xproto.Window(windowID), true); err != nil {
+		return fmt.Errorf("failed to watch window title: %w", err)
+	}
+	return nil
+}
+
+// WatchGeometry subscribes to ConfigureNotify on a window so moves and
+// resizes are reported immediately, replacing any previous subscription (0
+// unsubscribes). Events are delivered by the WatchFocus event loop.
+func (b *X11Backend) WatchGeometry(windowID uint32, callback func(windowID uint32, geometry config.Geometry)) error {
+	if err := b.checkConn(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	prev := b.geometryWindow
+	b.geometryWindow = xproto.Window(windowID)
+	b.geometryCallback = callback
+	b.mu.Unlock()
+
+	if prev != 0 && prev != xproto.Window(windowID) {
+		b.selectWindowEvents(prev, false)
+	}
+	if windowID == 0 {
+		return nil
+	}
+
+	if err := b.selectWindowEvents(xproto.Window(windowID), true); err != nil {
+		return fmt.Errorf("failed to watch window geometry: %w", err)
+	}
+	return nil
+}
+
+// WatchClose subscribes to DestroyNotify on a window so its closing is
+// reported immediately, replacing any previous subscription (0 unsubscribes).
+// Events are delivered by the WatchFocus event loop.
+func (b *X11Backend) WatchClose(windowID uint32, callback func(windowID uint32)) error {
+	if err := b.checkConn(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	prev := b.closeWindow
+	b.closeWindow = xproto.Window(windowID)
+	b.closeCallback = callback
+	b.mu.Unlock()
+
+	if prev != 0 && prev != xproto.Window(windowID) {
+		b.selectWindowEvents(prev, false)
+	}
+	if windowID == 0 {
+		return nil
+	}
+
+	if err := b.selectWindowEvents(xproto.Window(windowID), true); err != nil {
+		return fmt.Errorf("failed to watch window close: %w", err)
+	}
+	return nil
+}
+
+// selectWindowEvents sets our event mask on a window to cover its title,
+// geometry and close subscriptions. Event masks are per client, so this only changes
+// our own selection. Unchecked requests suit windows that may be gone.
+func (b *X11Backend) selectWindowEvents(win xproto.Window, checked bool) error {
+	b.mu.RLock()
+	var mask uint32
+	if win == b.titleWindow {
+		mask |= xproto.EventMaskPropertyChange
+	}
+	if win == b.geometryWindow || win == b.closeWindow {
+		mask |= xproto.EventMaskStructureNotify
+	}
+	b.mu.RUnlock()
+
+	if !checked {
+		xproto.ChangeWindowAttributes(b.conn, win, xproto.CwEventMask, []uint32{mask})
+		return nil
+	}
+	return xproto.ChangeWindowAttributesChecked(b.conn, win, xproto.CwEventMask, []uint32{mask}).Check()
+}
+
+// handleTitleEvent reports a title change on the watched window
+func (b *X11Backend) handleTitleEvent(ev xproto.PropertyNotifyEvent) {
+	b.mu.RLock()
+	watched := b.titleWindow
+	callback := b.titleCallback
+	netWmName := b.netWmNameAtom
+	b.mu.RUnlock()
+
+	if ev.Window != watched || watched == 0 || callback == nil {
+		return
+	}
+	if ev.Atom != netWmName && ev.Atom != xproto.AtomWmName {
+		return
+	}
+	callback(uint32(ev.Window), b.getWindowTitle(ev.Window))
+}
+
+// handleGeometryEvent reports a move or resize of the watched window
+func (b *X11Backend) handleGeometryEvent(ev xproto.ConfigureNotifyEvent) {
+	b.mu.RLock()
+	watched := b.geometryWindow
+	callback := b.geometryCallback
+	b.mu.RUnlock()
+
+	if ev.Window != watched || watched == 0 || callback == nil {
+		return
+	}
+
+	// Query rather than trust the event: synthetic events from the window
+	// manager use root coordinates, real ones are parent-relative
+	geom, err := b.getWindowGeometry(ev.Window)
+	if err != nil {
+		return
+	}
+	callback(uint32(ev.Window), geom)
+}
+
+// handleDestroyEvent reports the watched window closing and re-checks focus
+// right away, since the window manager moves it elsewhere
+func (b *X11Backend) handleDestroyEvent(ev xproto.DestroyNotifyEvent) {
+	b.mu.Lock()
+	watched := b.closeWindow
+	callback := b.closeCallback
+	if ev.Window == watched && watched != 0 {
+		b.closeWindow = 0
+	}
+	b.mu.Unlock()
+
+	if ev.Window != watched || watched == 0 {
+		return
+	}
+	if callback != nil {
+		callback(uint32(ev.Window))
+	}
+	b.triggerDesktopChange()
+}
+
+// ActivateWindow asks the window manager to focus and raise a window with an
+// EWMH _NET_ACTIVE_WINDOW client message (source indication 2, a pager), which
+// also switches to its desktop and un-minimizes it
+func (b *X11Backend) ActivateWindow(windowID uint32) error {
+	if err := b.checkConn(); err != nil {
+		return err
+	}
+
+	activeAtom, err := b.getAtom("_NET_ACTIVE_WINDOW")
+	if err != nil {
+		return err
+	}
+
+	ev := xproto.ClientMessageEvent{
+		Format: 32,
+		Window: xproto.Window(windowID),
+		Type:   activeAtom,
+		Data:   xproto.ClientMessageDataUnionData32New([]uint32{2, uint32(xproto.TimeCurrentTime), 0, 0, 0}),
+	}
+	if err := xproto.SendEventChecked(
+		b.conn,
+		false,
+		b.root,
+		xproto.EventMaskSubstructureNotify|xproto.EventMaskSubstructureRedirect,
+		string(ev.Bytes()),
+	).Check(); err != nil {
+		return fmt.Errorf("failed to send _NET_ACTIVE_WINDOW: %w", err)
+	}
+	return nil
+}
+
+// PointerPosition queries the pointer relative to the window; the reply's
+// button mask tells whether a mouse button is held
+func (b *X11Backend) PointerPosition(window *config.WindowInfo) (int, int, bool, error) {
+	if err := b.checkConn(); err != nil {
+		return 0, 0, false, err
+	}
+
+	reply, err := xproto.QueryPointer(b.conn, xproto.Window(window.ID)).Reply()
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to query pointer: %w", err)
+	}
+	if !reply.SameScreen {
+		return 0, 0, false, fmt.Errorf("pointer is on another screen")
+	}
+	buttons := uint16(xproto.KeyButMaskButton1 | xproto.KeyButMaskButton2 | xproto.KeyButMaskButton3)
+	return int(reply.WinX), int(reply.WinY), reply.Mask&buttons != 0, nil
+}
+
 // GetWindowInfo is the public version for use by Manager
 func (b *X11Backend) GetWindowInfo(windowID uint32) (*config.WindowInfo, error) {
+	if err := b.checkConn(); err != nil {
+		return nil, err
+	}
 	return b.getWindowInfo(xproto.Window(windowID))
 }
 
@@ -529,7 +823,8 @@ func (b *X11Backend) getAtom(name string) (xproto.Atom, error) {
 	return reply.Atom, nil
 }
 
-// getProperty gets a property value as a string
+// getProperty gets a property value as a UTF-8 string (see
+// decodeTextProperty)
 func (b *X11Backend) getProperty(win xproto.Window, atom xproto.Atom) (string, error) {
 	reply, err := xproto.GetProperty(
 		b.conn,
@@ -548,5 +843,20 @@ func (b *X11Backend) getProperty(win xproto.Window, atom xproto.Atom) (string, e
 		return "", fmt.Errorf("empty property")
 	}
 
-	return string(reply.Value), nil
+	return decodeTextProperty(reply.Value, reply.Type), nil
+}
+
+// decodeTextProperty converts a text property to UTF-8. STRING properties
+// (WM_NAME of older clients, WM_CLASS) are Latin-1, which would show as
+// mojibake if taken as UTF-8. Other types (UTF8_STRING, COMPOUND_TEXT) are
+// taken as UTF-8, with invalid bytes replaced by U+FFFD.
+func decodeTextProperty(value []byte, typ xproto.Atom) string {
+	if typ == xproto.AtomString {
+		runes := make([]rune, len(value))
+		for i, c := range value {
+			runes[i] = rune(c)
+		}
+		return string(runes)
+	}
+	return strings.ToValidUTF8(string(value), "\uFFFD")
 }
@@ -0,0 +1,5 @@
+// Package events holds the typed events FocusStreamer publishes over the
+// /api/events/ws WebSocket and the org.focusstreamer Event D-Bus signal,
+// generated from events.proto. Clients in other languages generate their
+// own types from the same file.
+package events
@@ -0,0 +1,770 @@
+// Events published by FocusStreamer. Each binary WebSocket message on
+// /api/events/ws and each org.focusstreamer.Event D-Bus signal carries one
+// serialized Event.
+//
+// Regenerate events.pb.go with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative pkg/events/events.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: pkg/events/events.proto
+
+package events
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// StandbyReason says why the stream shows the placeholder
+type StandbyReason int32
+
+const (
+	StandbyReason_STANDBY_REASON_NONE              StandbyReason = 0 // Streaming a window
+	StandbyReason_STANDBY_REASON_MANUAL            StandbyReason = 1 // Forced standby (API, hotkey, tray or scene)
+	StandbyReason_STANDBY_REASON_DESKTOP           StandbyReason = 2 // The current virtual desktop isn't streamed
+	StandbyReason_STANDBY_REASON_PANIC             StandbyReason = 3 // Panic button; the stream is black
+	StandbyReason_STANDBY_REASON_NO_WINDOW         StandbyReason = 4 // No allowlisted window is focused or can be shown
+	StandbyReason_STANDBY_REASON_NOT_ALLOWLISTED   StandbyReason = 5 // The focused window may not be streamed
+	StandbyReason_STANDBY_REASON_CAPTURE_FAILED    StandbyReason = 6 // Capturing the window failed
+	StandbyReason_STANDBY_REASON_SENSITIVE_CONTENT StandbyReason = 7 // The PII guard blanked the stream
+)
+
+// Enum value maps for StandbyReason.
+var (
+	StandbyReason_name = map[int32]string{
+		0: "STANDBY_REASON_NONE",
+		1: "STANDBY_REASON_MANUAL",
+		2: "STANDBY_REASON_DESKTOP",
+		3: "STANDBY_REASON_PANIC",
+		4: "STANDBY_REASON_NO_WINDOW",
+		5: "STANDBY_REASON_NOT_ALLOWLISTED",
+		6: "STANDBY_REASON_CAPTURE_FAILED",
+		7: "STANDBY_REASON_SENSITIVE_CONTENT",
+	}
+	StandbyReason_value = map[string]int32{
+		"STANDBY_REASON_NONE":              0,
+		"STANDBY_REASON_MANUAL":            1,
+		"STANDBY_REASON_DESKTOP":           2,
+		"STANDBY_REASON_PANIC":             3,
+		"STANDBY_REASON_NO_WINDOW":         4,
+		"STANDBY_REASON_NOT_ALLOWLISTED":   5,
+		"STANDBY_REASON_CAPTURE_FAILED":    6,
+		"STANDBY_REASON_SENSITIVE_CONTENT": 7,
+	}
+)
+
+func (x StandbyReason) Enum() *StandbyReason {
+	p := new(StandbyReason)
+	*p = x
+	return p
+}
+
+func (x StandbyReason) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (StandbyReason) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_events_events_proto_enumTypes[0].Descriptor()
+}
+
+func (StandbyReason) Type() protoreflect.EnumType {
+	return &file_pkg_events_events_proto_enumTypes[0]
+}
+
+func (x StandbyReason) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use StandbyReason.Descriptor instead.
+func (StandbyReason) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{0}
+}
+
+// CaptureErrorSource says which part of capture failed
+type CaptureErrorSource int32
+
+const (
+	CaptureErrorSource_CAPTURE_ERROR_SOURCE_UNSPECIFIED CaptureErrorSource = 0
+	CaptureErrorSource_CAPTURE_ERROR_SOURCE_WINDOW      CaptureErrorSource = 1 // Capturing the shared window failed
+	CaptureErrorSource_CAPTURE_ERROR_SOURCE_STALLED     CaptureErrorSource = 2 // The watchdog saw frozen frames and restarted capture
+	CaptureErrorSource_CAPTURE_ERROR_SOURCE_DISPLAY     CaptureErrorSource = 3 // The display connection was lost or can't be restored
+)
+
+// Enum value maps for CaptureErrorSource.
+var (
+	CaptureErrorSource_name = map[int32]string{
+		0: "CAPTURE_ERROR_SOURCE_UNSPECIFIED",
+		1: "CAPTURE_ERROR_SOURCE_WINDOW",
+		2: "CAPTURE_ERROR_SOURCE_STALLED",
+		3: "CAPTURE_ERROR_SOURCE_DISPLAY",
+	}
+	CaptureErrorSource_value = map[string]int32{
+		"CAPTURE_ERROR_SOURCE_UNSPECIFIED": 0,
+		"CAPTURE_ERROR_SOURCE_WINDOW":      1,
+		"CAPTURE_ERROR_SOURCE_STALLED":     2,
+		"CAPTURE_ERROR_SOURCE_DISPLAY":     3,
+	}
+)
+
+func (x CaptureErrorSource) Enum() *CaptureErrorSource {
+	p := new(CaptureErrorSource)
+	*p = x
+	return p
+}
+
+func (x CaptureErrorSource) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CaptureErrorSource) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_events_events_proto_enumTypes[1].Descriptor()
+}
+
+func (CaptureErrorSource) Type() protoreflect.EnumType {
+	return &file_pkg_events_events_proto_enumTypes[1]
+}
+
+func (x CaptureErrorSource) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CaptureErrorSource.Descriptor instead.
+func (CaptureErrorSource) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{1}
+}
+
+// Event is the envelope every event is sent in
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// When it happened
+	Time *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*Event_FocusChanged
+	//	*Event_StandbyChanged
+	//	*Event_ZoomChanged
+	//	*Event_ConfigUpdated
+	//	*Event_CaptureError
+	Payload       isEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_pkg_events_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Event) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Event) GetPayload() isEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *Event) GetFocusChanged() *FocusChanged {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_FocusChanged); ok {
+			return x.FocusChanged
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetStandbyChanged() *StandbyChanged {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_StandbyChanged); ok {
+			return x.StandbyChanged
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetZoomChanged() *ZoomChanged {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ZoomChanged); ok {
+			return x.ZoomChanged
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetConfigUpdated() *ConfigUpdated {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_ConfigUpdated); ok {
+			return x.ConfigUpdated
+		}
+	}
+	return nil
+}
+
+func (x *Event) GetCaptureError() *CaptureError {
+	if x != nil {
+		if x, ok := x.Payload.(*Event_CaptureError); ok {
+			return x.CaptureError
+		}
+	}
+	return nil
+}
+
+type isEvent_Payload interface {
+	isEvent_Payload()
+}
+
+type Event_FocusChanged struct {
+	FocusChanged *FocusChanged `protobuf:"bytes,10,opt,name=focus_changed,json=focusChanged,proto3,oneof"`
+}
+
+type Event_StandbyChanged struct {
+	StandbyChanged *StandbyChanged `protobuf:"bytes,11,opt,name=standby_changed,json=standbyChanged,proto3,oneof"`
+}
+
+type Event_ZoomChanged struct {
+	ZoomChanged *ZoomChanged `protobuf:"bytes,12,opt,name=zoom_changed,json=zoomChanged,proto3,oneof"`
+}
+
+type Event_ConfigUpdated struct {
+	ConfigUpdated *ConfigUpdated `protobuf:"bytes,13,opt,name=config_updated,json=configUpdated,proto3,oneof"`
+}
+
+type Event_CaptureError struct {
+	CaptureError *CaptureError `protobuf:"bytes,14,opt,name=capture_error,json=captureError,proto3,oneof"`
+}
+
+func (*Event_FocusChanged) isEvent_Payload() {}
+
+func (*Event_StandbyChanged) isEvent_Payload() {}
+
+func (*Event_ZoomChanged) isEvent_Payload() {}
+
+func (*Event_ConfigUpdated) isEvent_Payload() {}
+
+func (*Event_CaptureError) isEvent_Payload() {}
+
+// Window is a desktop window
+type Window struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"` // Backend window ID, 0 for native Wayland windows
+	Class         string                 `protobuf:"bytes,2,opt,name=class,proto3" json:"class,omitempty"`
+	Title         string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Pid           int32                  `protobuf:"varint,4,opt,name=pid,proto3" json:"pid,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Window) Reset() {
+	*x = Window{}
+	mi := &file_pkg_events_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Window) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Window) ProtoMessage() {}
+
+func (x *Window) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Window.ProtoReflect.Descriptor instead.
+func (*Window) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Window) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Window) GetClass() string {
+	if x != nil {
+		return x.Class
+	}
+	return ""
+}
+
+func (x *Window) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Window) GetPid() int32 {
+	if x != nil {
+		return x.Pid
+	}
+	return 0
+}
+
+// FocusChanged is sent when another window gets focus or the focused
+// window's title changes. Window is unset when nothing has focus.
+type FocusChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Window        *Window                `protobuf:"bytes,1,opt,name=window,proto3" json:"window,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FocusChanged) Reset() {
+	*x = FocusChanged{}
+	mi := &file_pkg_events_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FocusChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FocusChanged) ProtoMessage() {}
+
+func (x *FocusChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FocusChanged.ProtoReflect.Descriptor instead.
+func (*FocusChanged) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FocusChanged) GetWindow() *Window {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+// StandbyChanged is sent when the stream switches between a window and the
+// placeholder, to another window, or to another standby reason
+type StandbyChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Standby       bool                   `protobuf:"varint,1,opt,name=standby,proto3" json:"standby,omitempty"`
+	Reason        StandbyReason          `protobuf:"varint,2,opt,name=reason,proto3,enum=focusstreamer.events.v1.StandbyReason" json:"reason,omitempty"`
+	SharedWindow  *Window                `protobuf:"bytes,3,opt,name=shared_window,json=sharedWindow,proto3" json:"shared_window,omitempty"` // Unset on standby
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StandbyChanged) Reset() {
+	*x = StandbyChanged{}
+	mi := &file_pkg_events_events_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StandbyChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StandbyChanged) ProtoMessage() {}
+
+func (x *StandbyChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StandbyChanged.ProtoReflect.Descriptor instead.
+func (*StandbyChanged) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StandbyChanged) GetStandby() bool {
+	if x != nil {
+		return x.Standby
+	}
+	return false
+}
+
+func (x *StandbyChanged) GetReason() StandbyReason {
+	if x != nil {
+		return x.Reason
+	}
+	return StandbyReason_STANDBY_REASON_NONE
+}
+
+func (x *StandbyChanged) GetSharedWindow() *Window {
+	if x != nil {
+		return x.SharedWindow
+	}
+	return nil
+}
+
+// ZoomChanged is sent when the stream's zoom or pan changes
+type ZoomChanged struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Scale         float64                `protobuf:"fixed64,1,opt,name=scale,proto3" json:"scale,omitempty"`                    // 1 to 4
+	OffsetX       float64                `protobuf:"fixed64,2,opt,name=offset_x,json=offsetX,proto3" json:"offset_x,omitempty"` // Center of the view, 0 to 1
+	OffsetY       float64                `protobuf:"fixed64,3,opt,name=offset_y,json=offsetY,proto3" json:"offset_y,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ZoomChanged) Reset() {
+	*x = ZoomChanged{}
+	mi := &file_pkg_events_events_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ZoomChanged) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ZoomChanged) ProtoMessage() {}
+
+func (x *ZoomChanged) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ZoomChanged.ProtoReflect.Descriptor instead.
+func (*ZoomChanged) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ZoomChanged) GetScale() float64 {
+	if x != nil {
+		return x.Scale
+	}
+	return 0
+}
+
+func (x *ZoomChanged) GetOffsetX() float64 {
+	if x != nil {
+		return x.OffsetX
+	}
+	return 0
+}
+
+func (x *ZoomChanged) GetOffsetY() float64 {
+	if x != nil {
+		return x.OffsetY
+	}
+	return 0
+}
+
+// ConfigUpdated is sent after the configuration is saved; fetch
+// /api/config for the new settings
+type ConfigUpdated struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	ActiveProfileId   string                 `protobuf:"bytes,1,opt,name=active_profile_id,json=activeProfileId,proto3" json:"active_profile_id,omitempty"`
+	ActiveProfileName string                 `protobuf:"bytes,2,opt,name=active_profile_name,json=activeProfileName,proto3" json:"active_profile_name,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *ConfigUpdated) Reset() {
+	*x = ConfigUpdated{}
+	mi := &file_pkg_events_events_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigUpdated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigUpdated) ProtoMessage() {}
+
+func (x *ConfigUpdated) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigUpdated.ProtoReflect.Descriptor instead.
+func (*ConfigUpdated) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ConfigUpdated) GetActiveProfileId() string {
+	if x != nil {
+		return x.ActiveProfileId
+	}
+	return ""
+}
+
+func (x *ConfigUpdated) GetActiveProfileName() string {
+	if x != nil {
+		return x.ActiveProfileName
+	}
+	return ""
+}
+
+// CaptureError is sent when capture fails or stalls
+type CaptureError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        CaptureErrorSource     `protobuf:"varint,1,opt,name=source,proto3,enum=focusstreamer.events.v1.CaptureErrorSource" json:"source,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Window        *Window                `protobuf:"bytes,3,opt,name=window,proto3" json:"window,omitempty"` // The window being captured, when known
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CaptureError) Reset() {
+	*x = CaptureError{}
+	mi := &file_pkg_events_events_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CaptureError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CaptureError) ProtoMessage() {}
+
+func (x *CaptureError) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_events_events_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CaptureError.ProtoReflect.Descriptor instead.
+func (*CaptureError) Descriptor() ([]byte, []int) {
+	return file_pkg_events_events_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CaptureError) GetSource() CaptureErrorSource {
+	if x != nil {
+		return x.Source
+	}
+	return CaptureErrorSource_CAPTURE_ERROR_SOURCE_UNSPECIFIED
+}
+
+func (x *CaptureError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CaptureError) GetWindow() *Window {
+	if x != nil {
+		return x.Window
+	}
+	return nil
+}
+
+var File_pkg_events_events_proto protoreflect.FileDescriptor
+
+const file_pkg_events_events_proto_rawDesc = "" +
+	"\n" +
+	"\x17pkg/events/events.proto\x12\x17focusstreamer.events.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xce\x03\n" +
+	"\x05Event\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12L\n" +
+	"\rfocus_changed\x18\n" +
+	" \x01(\v2%.focusstreamer.events.v1.FocusChangedH\x00R\ffocusChanged\x12R\n" +
+	"\x0fstandby_changed\x18\v \x01(\v2'.focusstreamer.events.v1.StandbyChangedH\x00R\x0estandbyChanged\x12I\n" +
+	"\fzoom_changed\x18\f \x01(\v2$.focusstreamer.events.v1.ZoomChangedH\x00R\vzoomChanged\x12O\n" +
+	"\x0econfig_updated\x18\r \x01(\v2&.focusstreamer.events.v1.ConfigUpdatedH\x00R\rconfigUpdated\x12L\n" +
+	"\rcapture_error\x18\x0e \x01(\v2%.focusstreamer.events.v1.CaptureErrorH\x00R\fcaptureErrorB\t\n" +
+	"\apayload\"V\n" +
+	"\x06Window\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x14\n" +
+	"\x05class\x18\x02 \x01(\tR\x05class\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x10\n" +
+	"\x03pid\x18\x04 \x01(\x05R\x03pid\"G\n" +
+	"\fFocusChanged\x127\n" +
+	"\x06window\x18\x01 \x01(\v2\x1f.focusstreamer.events.v1.WindowR\x06window\"\xb0\x01\n" +
+	"\x0eStandbyChanged\x12\x18\n" +
+	"\astandby\x18\x01 \x01(\bR\astandby\x12>\n" +
+	"\x06reason\x18\x02 \x01(\x0e2&.focusstreamer.events.v1.StandbyReasonR\x06reason\x12D\n" +
+	"\rshared_window\x18\x03 \x01(\v2\x1f.focusstreamer.events.v1.WindowR\fsharedWindow\"Y\n" +
+	"\vZoomChanged\x12\x14\n" +
+	"\x05scale\x18\x01 \x01(\x01R\x05scale\x12\x19\n" +
+	"\boffset_x\x18\x02 \x01(\x01R\aoffsetX\x12\x19\n" +
+	"\boffset_y\x18\x03 \x01(\x01R\aoffsetY\"k\n" +
+	"\rConfigUpdated\x12*\n" +
+	"\x11active_profile_id\x18\x01 \x01(\tR\x0factiveProfileId\x12.\n" +
+	"\x13active_profile_name\x18\x02 \x01(\tR\x11activeProfileName\"\xa6\x01\n" +
+	"\fCaptureError\x12C\n" +
+	"\x06source\x18\x01 \x01(\x0e2+.focusstreamer.events.v1.CaptureErrorSourceR\x06source\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x127\n" +
+	"\x06window\x18\x03 \x01(\v2\x1f.focusstreamer.events.v1.WindowR\x06window*\x84\x02\n" +
+	"\rStandbyReason\x12\x17\n" +
+	"\x13STANDBY_REASON_NONE\x10\x00\x12\x19\n" +
+	"\x15STANDBY_REASON_MANUAL\x10\x01\x12\x1a\n" +
+	"\x16STANDBY_REASON_DESKTOP\x10\x02\x12\x18\n" +
+	"\x14STANDBY_REASON_PANIC\x10\x03\x12\x1c\n" +
+	"\x18STANDBY_REASON_NO_WINDOW\x10\x04\x12\"\n" +
+	"\x1eSTANDBY_REASON_NOT_ALLOWLISTED\x10\x05\x12!\n" +
+	"\x1dSTANDBY_REASON_CAPTURE_FAILED\x10\x06\x12$\n" +
+	" STANDBY_REASON_SENSITIVE_CONTENT\x10\a*\x9f\x01\n" +
+	"\x12CaptureErrorSource\x12$\n" +
+	" CAPTURE_ERROR_SOURCE_UNSPECIFIED\x10\x00\x12\x1f\n" +
+	"\x1bCAPTURE_ERROR_SOURCE_WINDOW\x10\x01\x12 \n" +
+	"\x1cCAPTURE_ERROR_SOURCE_STALLED\x10\x02\x12 \n" +
+	"\x1cCAPTURE_ERROR_SOURCE_DISPLAY\x10\x03B5Z3github.com/bryanchriswhite/FocusStreamer/pkg/eventsb\x06proto3"
+
+var (
+	file_pkg_events_events_proto_rawDescOnce sync.Once
+	file_pkg_events_events_proto_rawDescData []byte
+)
+
+func file_pkg_events_events_proto_rawDescGZIP() []byte {
+	file_pkg_events_events_proto_rawDescOnce.Do(func() {
+		file_pkg_events_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_events_events_proto_rawDesc), len(file_pkg_events_events_proto_rawDesc)))
+	})
+	return file_pkg_events_events_proto_rawDescData
+}
+
+var file_pkg_events_events_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_pkg_events_events_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_pkg_events_events_proto_goTypes = []any{
+	(StandbyReason)(0),            // 0: focusstreamer.events.v1.StandbyReason
+	(CaptureErrorSource)(0),       // 1: focusstreamer.events.v1.CaptureErrorSource
+	(*Event)(nil),                 // 2: focusstreamer.events.v1.Event
+	(*Window)(nil),                // 3: focusstreamer.events.v1.Window
+	(*FocusChanged)(nil),          // 4: focusstreamer.events.v1.FocusChanged
+	(*StandbyChanged)(nil),        // 5: focusstreamer.events.v1.StandbyChanged
+	(*ZoomChanged)(nil),           // 6: focusstreamer.events.v1.ZoomChanged
+	(*ConfigUpdated)(nil),         // 7: focusstreamer.events.v1.ConfigUpdated
+	(*CaptureError)(nil),          // 8: focusstreamer.events.v1.CaptureError
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+}
+var file_pkg_events_events_proto_depIdxs = []int32{
+	9,  // 0: focusstreamer.events.v1.Event.time:type_name -> google.protobuf.Timestamp
+	4,  // 1: focusstreamer.events.v1.Event.focus_changed:type_name -> focusstreamer.events.v1.FocusChanged
+	5,  // 2: focusstreamer.events.v1.Event.standby_changed:type_name -> focusstreamer.events.v1.StandbyChanged
+	6,  // 3: focusstreamer.events.v1.Event.zoom_changed:type_name -> focusstreamer.events.v1.ZoomChanged
+	7,  // 4: focusstreamer.events.v1.Event.config_updated:type_name -> focusstreamer.events.v1.ConfigUpdated
+	8,  // 5: focusstreamer.events.v1.Event.capture_error:type_name -> focusstreamer.events.v1.CaptureError
+	3,  // 6: focusstreamer.events.v1.FocusChanged.window:type_name -> focusstreamer.events.v1.Window
+	0,  // 7: focusstreamer.events.v1.StandbyChanged.reason:type_name -> focusstreamer.events.v1.StandbyReason
+	3,  // 8: focusstreamer.events.v1.StandbyChanged.shared_window:type_name -> focusstreamer.events.v1.Window
+	1,  // 9: focusstreamer.events.v1.CaptureError.source:type_name -> focusstreamer.events.v1.CaptureErrorSource
+	3,  // 10: focusstreamer.events.v1.CaptureError.window:type_name -> focusstreamer.events.v1.Window
+	11, // [11:11] is the sub-list for method output_type
+	11, // [11:11] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_pkg_events_events_proto_init() }
+func file_pkg_events_events_proto_init() {
+	if File_pkg_events_events_proto != nil {
+		return
+	}
+	file_pkg_events_events_proto_msgTypes[0].OneofWrappers = []any{
+		(*Event_FocusChanged)(nil),
+		(*Event_StandbyChanged)(nil),
+		(*Event_ZoomChanged)(nil),
+		(*Event_ConfigUpdated)(nil),
+		(*Event_CaptureError)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_events_events_proto_rawDesc), len(file_pkg_events_events_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_events_events_proto_goTypes,
+		DependencyIndexes: file_pkg_events_events_proto_depIdxs,
+		EnumInfos:         file_pkg_events_events_proto_enumTypes,
+		MessageInfos:      file_pkg_events_events_proto_msgTypes,
+	}.Build()
+	File_pkg_events_events_proto = out.File
+	file_pkg_events_events_proto_goTypes = nil
+	file_pkg_events_events_proto_depIdxs = nil
+}
@@ -0,0 +1,24 @@
+// Package web embeds the built settings UI (web/dist) so the binary can
+// serve it from any working directory. Run `make build-frontend` before
+// building the backend to include it.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Dist returns the built UI, and false if the binary was built without it
+func Dist() (fs.FS, bool) {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	if _, err := fs.Stat(sub, "index.html"); err != nil {
+		return nil, false
+	}
+	return sub, true
+}
@@ -7,6 +7,18 @@ import PatternManager from './components/PatternManager'
 import PlaceholderUpload from './components/PlaceholderUpload'
 import ProfileSelector from './components/ProfileSelector'
 
+// API errors are problem+json documents; returns their detail, or the body
+// for anything else
+async function errorDetail(response) {
+  const text = await response.text()
+  try {
+    const problem = JSON.parse(text)
+    return problem.detail || problem.title
+  } catch {
+    return text
+  }
+}
+
 function ThemeToggle({ theme, setTheme }) {
   return (
     <div className="theme-toggle">
@@ -340,8 +352,7 @@ function App() {
     })
 
     if (!response.ok) {
-      const text = await response.text()
-      throw new Error(text || 'Upload failed')
+      throw new Error(await errorDetail(response) || 'Upload failed')
     }
 
     await fetchPlaceholderImages()
@@ -354,8 +365,7 @@ function App() {
     })
 
     if (!response.ok) {
-      const text = await response.text()
-      throw new Error(text || 'Delete failed')
+      throw new Error(await errorDetail(response) || 'Delete failed')
     }
 
     await fetchPlaceholderImages()